@@ -67,6 +67,94 @@ type (
 	Accumulator = types.Accumulator
 )
 
+// Re-export the nodes Walk visits so visitors can type-switch on them. They
+// are for inspecting built queries; build queries with the helper functions.
+type (
+	FilterCondition  = types.FilterCondition
+	FilterGroup      = types.FilterGroup
+	RangeFilter      = types.RangeFilter
+	RegexFilter      = types.RegexFilter
+	TextSearchFilter = types.TextSearchFilter
+	GeoFilter        = types.GeoFilter
+	ArrayFilter      = types.ArrayFilter
+	ValuesFilter     = types.ValuesFilter
+	ModFilter        = types.ModFilter
+	TypeFilter       = types.TypeFilter
+	ElemMatchFilter  = types.ElemMatchFilter
+	NullFilter       = types.NullFilter
+	ExistsFilter     = types.ExistsFilter
+
+	Projection      = types.Projection
+	ProjectionField = types.ProjectionField
+	SortClause      = types.SortClause
+	Document        = types.Document
+	UpdateOperation = types.UpdateOperation
+
+	MatchStage       = types.MatchStage
+	ProjectStage     = types.ProjectStage
+	GroupStage       = types.GroupStage
+	SortStage        = types.SortStage
+	LimitStage       = types.LimitStage
+	SkipStage        = types.SkipStage
+	UnwindStage      = types.UnwindStage
+	LookupStage      = types.LookupStage
+	GraphLookupStage = types.GraphLookupStage
+	AddFieldsStage   = types.AddFieldsStage
+	ReplaceRootStage = types.ReplaceRootStage
+	CountStage       = types.CountStage
+	FacetStage       = types.FacetStage
+	BucketStage      = types.BucketStage
+	SortByCountStage = types.SortByCountStage
+	SampleStage      = types.SampleStage
+	MergeStage       = types.MergeStage
+
+	FieldExpression       = types.FieldExpression
+	LiteralExpression     = types.LiteralExpression
+	OperatorExpression    = types.OperatorExpression
+	ConditionalExpression = types.ConditionalExpression
+	CompositeExpression   = types.CompositeExpression
+)
+
+// UnsupportedError is returned by renderers for AST features their provider
+// cannot express. Use errors.As to detect it and inspect what was unsupported.
+type UnsupportedError = types.UnsupportedError
+
+// Structured errors returned by schema lookups, validation, the builder, and
+// renderers. Use errors.As to extract their context, or errors.Is with the
+// matching sentinel below.
+type (
+	UnknownCollectionError = types.UnknownCollectionError
+	UnknownFieldError      = types.UnknownFieldError
+	InvalidIdentifierError = types.InvalidIdentifierError
+	LimitExceededError     = types.LimitExceededError
+	MissingFilterError     = types.MissingFilterError
+	InvalidASTError        = types.InvalidASTError
+	PolicyError            = types.PolicyError
+	PolicyViolation        = types.PolicyViolation
+)
+
+// Sentinel errors for errors.Is.
+var (
+	ErrUnknownCollection    = types.ErrUnknownCollection
+	ErrUnknownField         = types.ErrUnknownField
+	ErrInvalidIdentifier    = types.ErrInvalidIdentifier
+	ErrUnsupportedOperation = types.ErrUnsupportedOperation
+	ErrUnsupportedFilter    = types.ErrUnsupportedFilter
+	ErrLimitExceeded        = types.ErrLimitExceeded
+	ErrMissingFilter        = types.ErrMissingFilter
+	ErrInvalidAST           = types.ErrInvalidAST
+	ErrPolicyViolation      = types.ErrPolicyViolation
+)
+
+// Limits holds the complexity ceilings applied during validation.
+// Configure per instance with DOCQL.WithLimits or per query with Builder.WithLimits.
+type Limits = types.Limits
+
+// DefaultLimits returns the package-wide complexity limits.
+func DefaultLimits() Limits {
+	return types.DefaultLimits()
+}
+
 // Re-export enum types - these are safe as they're just type-safe constants.
 type (
 	// Operation represents a document database operation type.
@@ -83,6 +171,9 @@ type (
 
 	// SortOrder represents sort direction.
 	SortOrder = types.SortOrder
+
+	// SortOpts sets case-insensitive or numeric string ordering on a sort.
+	SortOpts = types.SortOpts
 )
 
 // Operation constants.
@@ -98,6 +189,9 @@ const (
 	OpAggregate  = types.OpAggregate
 	OpCount      = types.OpCount
 	OpDistinct   = types.OpDistinct
+	OpWatch      = types.OpWatch
+
+	OpTransaction = types.OpTransaction
 )
 
 // Filter operator constants.
@@ -114,6 +208,7 @@ const (
 	OpType          = types.Type
 	OpRegex         = types.Regex
 	OpText          = types.Text
+	OpMod           = types.Mod
 	OpAll           = types.All
 	OpElemMatch     = types.ElemMatch
 	OpSize          = types.Size
@@ -168,12 +263,24 @@ const (
 	AccCount    = types.AccCount
 )
 
+// $merge action constants for MergeInto.
+const (
+	MergeReplace      = types.MergeReplace
+	MergeKeepExisting = types.MergeKeepExisting
+	MergeMerge        = types.MergeMerge
+	MergeFail         = types.MergeFail
+	MergeInsert       = types.MergeInsert
+	MergeDiscard      = types.MergeDiscard
+)
+
 // Complexity limit constants.
 const (
 	MaxFilterDepth      = types.MaxFilterDepth
+	MaxFilterConditions = types.MaxFilterConditions
 	MaxBatchSize        = types.MaxBatchSize
 	MaxLimit            = types.MaxLimit
 	MaxProjectionFields = types.MaxProjectionFields
 	MaxSortFields       = types.MaxSortFields
 	MaxPipelineStages   = types.MaxPipelineStages
+	MaxQueryTimeMS      = types.MaxQueryTimeMS
 )
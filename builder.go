@@ -2,6 +2,10 @@ package docql
 
 import (
 	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/zoobzio/docql/internal/types"
 )
@@ -10,6 +14,22 @@ import (
 type Builder struct {
 	ast *types.DocumentAST
 	err error
+
+	// Soft-delete state, set by DOCQL constructors and applied at Build.
+	softDelete     *types.Field
+	includeDeleted bool
+	asSoftDelete   bool
+
+	// preserveFilter skips NormalizeFilter at Build.
+	preserveFilter bool
+
+	// filterDepth and filterLogic describe the filter clause as Build will
+	// validate it, so each Filter call only measures what it adds.
+	filterDepth int
+	filterLogic types.LogicOperator
+
+	// Render hooks, set by DOCQL constructors.
+	hooks *Hooks
 }
 
 // Find creates a new find query builder.
@@ -118,21 +138,77 @@ func Count(c types.Collection) *Builder {
 }
 
 // Distinct creates a distinct query builder.
+// A field created for a different collection is rejected.
 func Distinct(c types.Collection, field types.Field) *Builder {
-	return &Builder{
+	b := &Builder{
 		ast: &types.DocumentAST{
 			Operation:     types.OpDistinct,
 			Target:        c,
 			DistinctField: &field,
 		},
 	}
+	if field.Collection != "" && field.Collection != c.Name {
+		b.err = fmt.Errorf("distinct field '%s' belongs to collection '%s', not '%s'",
+			field.Path, field.Collection, c.Name)
+	}
+	return b
+}
+
+// Watch creates a change stream builder. Its filter matches change events
+// and addresses the changed document's fields, unless RawEvent is set.
+func Watch(c types.Collection) *Builder {
+	return &Builder{
+		ast: &types.DocumentAST{
+			Operation: types.OpWatch,
+			Target:    c,
+		},
+	}
+}
+
+// Transaction creates a builder grouping write operations that should
+// commit atomically. Execution stays with the caller; renderers describe the
+// grouping in their provider's batch or transaction format.
+func Transaction() *Builder {
+	return &Builder{
+		ast: &types.DocumentAST{
+			Operation: types.OpTransaction,
+		},
+	}
+}
+
+// Add appends a write operation to a transaction. The operation is built when
+// added, so later changes to op are not reflected in the transaction.
+func (b *Builder) Add(op *Builder) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpTransaction {
+		b.err = fmt.Errorf("Add() can only be used with TRANSACTION operations")
+		return b
+	}
+	ast, err := op.Build()
+	if err != nil {
+		b.err = fmt.Errorf("transaction operation %d: %w", len(b.ast.Operations), err)
+		return b
+	}
+	if !ast.Operation.IsWrite() {
+		b.err = fmt.Errorf("transaction operation %d: %s is not a write operation", len(b.ast.Operations), ast.Operation)
+		return b
+	}
+	b.ast.Operations = append(b.ast.Operations, ast.Clone())
+	return b
 }
 
-// Filter sets or adds to the filter clause.
+// Filter sets or adds to the filter clause. When the builder has limits, from
+// WithLimits or a DOCQL constructor, a clause nested deeper than
+// MaxFilterDepth is an error immediately rather than at Build. Only those
+// builders fail early: a builder from a package-level constructor may yet be
+// built by an instance with other limits, so its depth is checked at Build.
 func (b *Builder) Filter(f types.FilterItem) *Builder {
 	if b.err != nil {
 		return b
 	}
+	b.addFilterDepth(f, types.AND, false)
 	if b.ast.FilterClause == nil {
 		b.ast.FilterClause = f
 	} else {
@@ -144,6 +220,182 @@ func (b *Builder) Filter(f types.FilterItem) *Builder {
 	return b
 }
 
+// FilterOr combines the existing filter clause with f under OR. Like Filter,
+// it wraps what came before, so Filter(a).Filter(b).FilterOr(c) matches
+// (a AND b) OR c, and a later Filter(d) matches ((a AND b) OR c) AND d. When
+// the existing clause is already an OR group, f joins it instead of nesting.
+func (b *Builder) FilterOr(f types.FilterItem) *Builder {
+	if b.err != nil {
+		return b
+	}
+	group, joins := b.ast.FilterClause.(types.FilterGroup)
+	joins = joins && group.Logic == types.OR
+	b.addFilterDepth(f, types.OR, joins)
+	if b.ast.FilterClause == nil {
+		b.ast.FilterClause = f
+	} else if joins {
+		conditions := make([]types.FilterItem, 0, len(group.Conditions)+1)
+		b.ast.FilterClause = types.FilterGroup{
+			Logic:      types.OR,
+			Conditions: append(append(conditions, group.Conditions...), f),
+		}
+	} else {
+		b.ast.FilterClause = types.FilterGroup{
+			Logic:      types.OR,
+			Conditions: []types.FilterItem{b.ast.FilterClause, f},
+		}
+	}
+	return b
+}
+
+// OrFilter is an alias for FilterOr.
+func (b *Builder) OrFilter(f types.FilterItem) *Builder {
+	return b.FilterOr(f)
+}
+
+// FilterReplace discards the existing filter clause and sets it to f. A nil f
+// clears the filter. Filters added at Build, such as the soft-delete
+// condition, still apply.
+func (b *Builder) FilterReplace(f types.FilterItem) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.ast.FilterClause = nil
+	b.filterDepth, b.filterLogic = 0, ""
+	if f != nil {
+		b.addFilterDepth(f, types.AND, false)
+		b.ast.FilterClause = f
+	}
+	return b
+}
+
+// addFilterDepth updates the tracked depth for f being combined with the
+// existing clause under logic, and records an error when the result is too
+// deep. joins reports that f is appended to an existing group rather than
+// wrapping the clause. The clause is measured as Build will validate it:
+// chained Filter calls nest groups that normalization flattens, so they only
+// fail if still too deep once normalized. Normalization can also drop
+// duplicate conditions, which the tracked depth ignores, so an apparent
+// violation is confirmed against the normalized clause.
+func (b *Builder) addFilterDepth(f types.FilterItem, logic types.LogicOperator, joins bool) {
+	depth, fLogic := b.filterShape(f)
+	if b.ast.FilterClause != nil {
+		depth = max(b.childDepth(b.filterDepth, b.filterLogic, logic, joins), b.childDepth(depth, fLogic, logic, false))
+		fLogic = logic
+	}
+	b.filterDepth, b.filterLogic = depth, fLogic
+
+	// Without limits of its own the builder may yet be validated against an
+	// instance's, so the depth is left to Build.
+	if b.ast.Limits == nil {
+		return
+	}
+	maxDepth := b.ast.Limits.MaxFilterDepth
+	if depth <= maxDepth {
+		return
+	}
+	var clause types.FilterItem = f
+	if b.ast.FilterClause != nil {
+		clause = types.FilterGroup{Logic: logic, Conditions: []types.FilterItem{b.ast.FilterClause, f}}
+	}
+	if !b.preserveFilter {
+		clause = NormalizeFilter(clause)
+	}
+	if err := types.ValidateFilterDepth(clause, maxDepth); err != nil {
+		b.err = err
+		return
+	}
+	b.filterDepth, b.filterLogic = shapeOf(clause, !b.preserveFilter)
+}
+
+// childDepth is the depth a clause of the given depth and top-level logic
+// reaches as an operand of a logic group. Build flattens same-logic operands
+// into the group; a preserved filter is only merged where joins says so.
+func (b *Builder) childDepth(depth int, clauseLogic, logic types.LogicOperator, joins bool) int {
+	if (b.preserveFilter && joins) || (!b.preserveFilter && clauseLogic == logic) {
+		return depth
+	}
+	return depth + 1
+}
+
+// filterShape returns the depth and top-level logic of f as Build will
+// validate it.
+func (b *Builder) filterShape(f types.FilterItem) (int, types.LogicOperator) {
+	return shapeOf(f, !b.preserveFilter)
+}
+
+// shapeOf returns the depth of f and the logic of its top-level group, or ""
+// when it is not a group. When normalized is set, f is measured as
+// NormalizeFilter would leave it, without building the normalized tree:
+// same-logic AND and OR groups are flattened into their parent, and a group
+// left with one condition is replaced by it.
+func shapeOf(f types.FilterItem, normalized bool) (int, types.LogicOperator) {
+	switch filter := f.(type) {
+	case types.FilterGroup:
+		if !normalized || (filter.Logic != types.AND && filter.Logic != types.OR) {
+			depth := 0
+			for _, c := range filter.Conditions {
+				d, _ := shapeOf(c, normalized)
+				depth = max(depth, d+1)
+			}
+			return depth, filter.Logic
+		}
+		depth, count := 0, 0
+		var only types.LogicOperator
+		for _, c := range filter.Conditions {
+			d, l := shapeOf(c, normalized)
+			if l == filter.Logic {
+				depth, count = max(depth, d), count+2
+				continue
+			}
+			depth, count, only = max(depth, d+1), count+1, l
+		}
+		if count == 1 {
+			return depth - 1, only
+		}
+		return depth, filter.Logic
+	case types.ElemMatchFilter:
+		depth := 0
+		for _, c := range filter.Conditions {
+			d, l := shapeOf(c, normalized)
+			if normalized && l == types.AND {
+				depth = max(depth, d)
+			} else {
+				depth = max(depth, d+1)
+			}
+		}
+		return depth, ""
+	default:
+		return 0, ""
+	}
+}
+
+// MergeFilter ANDs other's filter clause into this builder's, as Filter does,
+// for composing queries from reusable fragments. Both builders must have the
+// same operation and collection; an error recorded on other is propagated.
+// The merged filter is copied, so later changes to other are not reflected.
+func (b *Builder) MergeFilter(other *Builder) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if other.err != nil {
+		b.err = fmt.Errorf("MergeFilter(): %w", other.err)
+		return b
+	}
+	if other.ast.Operation != b.ast.Operation {
+		b.err = fmt.Errorf("MergeFilter() requires matching operations, got %s and %s", b.ast.Operation, other.ast.Operation)
+		return b
+	}
+	if other.ast.Target.Name != b.ast.Target.Name {
+		b.err = fmt.Errorf("MergeFilter() requires matching collections, got '%s' and '%s'", b.ast.Target.Name, other.ast.Target.Name)
+		return b
+	}
+	if other.ast.FilterClause == nil {
+		return b
+	}
+	return b.Filter(other.ast.Clone().FilterClause)
+}
+
 // Where is an alias for Filter.
 func (b *Builder) Where(f types.FilterItem) *Builder {
 	return b.Filter(f)
@@ -158,11 +410,69 @@ func (b *Builder) Select(fields ...types.Field) *Builder {
 		b.err = fmt.Errorf("Select() can only be used with read operations")
 		return b
 	}
+	if b.ast.Projection != nil && b.ast.Projection.Exclude {
+		b.err = fmt.Errorf("Select() cannot be combined with Exclude()")
+		return b
+	}
 	projFields := make([]types.ProjectionField, len(fields))
 	for i, f := range fields {
 		projFields[i] = types.ProjectionField{Field: f, Include: true}
 	}
-	b.ast.Projection = &types.Projection{Fields: projFields, Exclude: false}
+	b.setProjection(&types.Projection{Fields: projFields, Exclude: false})
+	return b
+}
+
+// SelectAs includes field in results under alias, adding to any fields
+// already selected. Providers that cannot rename server-side return the
+// mapping in QueryResult.FieldAliases.
+func (b *Builder) SelectAs(field types.Field, alias string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if !b.isReadOperation() {
+		b.err = fmt.Errorf("SelectAs() can only be used with read operations")
+		return b
+	}
+	if !types.IsValidIdentifier(alias) {
+		b.err = &types.InvalidIdentifierError{Kind: "alias", Value: alias}
+		return b
+	}
+	var projFields []types.ProjectionField
+	if b.ast.Projection != nil {
+		if b.ast.Projection.Exclude {
+			b.err = fmt.Errorf("SelectAs() cannot be combined with Exclude()")
+			return b
+		}
+		projFields = slices.Clone(b.ast.Projection.Fields)
+	}
+	projFields = append(projFields, types.ProjectionField{Field: field, Include: true, Alias: alias})
+	b.setProjection(&types.Projection{Fields: projFields, Exclude: false})
+	return b
+}
+
+// SelectWithoutID includes the given fields and excludes _id, the one mixed
+// include/exclude projection document databases accept.
+func (b *Builder) SelectWithoutID(fields ...types.Field) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if !b.isReadOperation() {
+		b.err = fmt.Errorf("SelectWithoutID() can only be used with read operations")
+		return b
+	}
+	if b.ast.Projection != nil && b.ast.Projection.Exclude {
+		b.err = fmt.Errorf("SelectWithoutID() cannot be combined with Exclude()")
+		return b
+	}
+	projFields := make([]types.ProjectionField, 0, len(fields)+1)
+	projFields = append(projFields, types.ProjectionField{
+		Field:   types.Field{Path: types.IDField, Collection: b.ast.Target.Name},
+		Include: false,
+	})
+	for _, f := range fields {
+		projFields = append(projFields, types.ProjectionField{Field: f, Include: true})
+	}
+	b.setProjection(&types.Projection{Fields: projFields, Exclude: false})
 	return b
 }
 
@@ -175,16 +485,37 @@ func (b *Builder) Exclude(fields ...types.Field) *Builder {
 		b.err = fmt.Errorf("Exclude() can only be used with read operations")
 		return b
 	}
+	if b.ast.Projection != nil && !b.ast.Projection.Exclude {
+		b.err = fmt.Errorf("Exclude() cannot be combined with Select()")
+		return b
+	}
 	projFields := make([]types.ProjectionField, len(fields))
 	for i, f := range fields {
 		projFields[i] = types.ProjectionField{Field: f, Include: false}
 	}
-	b.ast.Projection = &types.Projection{Fields: projFields, Exclude: true}
+	b.setProjection(&types.Projection{Fields: projFields, Exclude: true})
 	return b
 }
 
+// setProjection stores the projection after rejecting duplicate field paths.
+func (b *Builder) setProjection(p *types.Projection) {
+	if err := p.Validate(); err != nil {
+		b.err = err
+		return
+	}
+	b.ast.Projection = p
+}
+
 // Sort adds a sort clause.
+// On an aggregate, Sort adds to a $sort stage at the current pipeline
+// position, extending the last stage when it is already a $sort.
 func (b *Builder) Sort(field types.Field, order types.SortOrder) *Builder {
+	return b.SortWith(field, order, types.SortOpts{})
+}
+
+// SortWith adds a sort clause with string comparison options, such as
+// case-insensitive or numeric ordering.
+func (b *Builder) SortWith(field types.Field, order types.SortOrder, opts types.SortOpts) *Builder {
 	if b.err != nil {
 		return b
 	}
@@ -192,13 +523,38 @@ func (b *Builder) Sort(field types.Field, order types.SortOrder) *Builder {
 		b.err = fmt.Errorf("Sort() can only be used with read operations")
 		return b
 	}
-	b.ast.SortClauses = append(b.ast.SortClauses, types.SortClause{
-		Field: field,
-		Order: order,
-	})
+	b.addSort(types.SortClause{Field: field, Order: order, Opts: opts})
 	return b
 }
 
+// addSort stores a sort clause on the AST, or in a $sort stage for aggregates
+// whose renderers only read the pipeline. Consecutive sorts share a stage so
+// that they order by each clause in turn, as they do outside an aggregate.
+func (b *Builder) addSort(s types.SortClause) {
+	if b.ast.Operation != types.OpAggregate {
+		b.ast.SortClauses = append(b.ast.SortClauses, s)
+		return
+	}
+	if n := len(b.ast.Pipeline); n > 0 {
+		if last, ok := b.ast.Pipeline[n-1].(types.SortStage); ok {
+			last.Sorts = append(slices.Clip(last.Sorts), s)
+			b.ast.Pipeline[n-1] = last
+			return
+		}
+	}
+	b.ast.Pipeline = append(b.ast.Pipeline, types.SortStage{Sorts: []types.SortClause{s}})
+}
+
+// SortAscWith adds ascending sort with string comparison options.
+func (b *Builder) SortAscWith(field types.Field, opts types.SortOpts) *Builder {
+	return b.SortWith(field, types.Ascending, opts)
+}
+
+// SortDescWith adds descending sort with string comparison options.
+func (b *Builder) SortDescWith(field types.Field, opts types.SortOpts) *Builder {
+	return b.SortWith(field, types.Descending, opts)
+}
+
 // SortAsc adds ascending sort.
 func (b *Builder) SortAsc(field types.Field) *Builder {
 	return b.Sort(field, types.Ascending)
@@ -210,6 +566,7 @@ func (b *Builder) SortDesc(field types.Field) *Builder {
 }
 
 // Skip sets the number of documents to skip.
+// On an aggregate, Skip appends a $skip stage at the current pipeline position.
 func (b *Builder) Skip(n int) *Builder {
 	if b.err != nil {
 		return b
@@ -218,11 +575,16 @@ func (b *Builder) Skip(n int) *Builder {
 		b.err = fmt.Errorf("Skip() can only be used with read operations")
 		return b
 	}
-	b.ast.Skip = &types.PaginationValue{Static: &n}
+	if err := types.ValidateSkip(n); err != nil {
+		b.err = err
+		return b
+	}
+	b.setSkip(types.PaginationValue{Static: &n})
 	return b
 }
 
 // SkipParam sets skip from a parameter.
+// On an aggregate, SkipParam appends a $skip stage at the current pipeline position.
 func (b *Builder) SkipParam(p types.Param) *Builder {
 	if b.err != nil {
 		return b
@@ -231,11 +593,12 @@ func (b *Builder) SkipParam(p types.Param) *Builder {
 		b.err = fmt.Errorf("SkipParam() can only be used with read operations")
 		return b
 	}
-	b.ast.Skip = &types.PaginationValue{Param: &p}
+	b.setSkip(types.PaginationValue{Param: &p})
 	return b
 }
 
 // Limit sets the maximum number of documents to return.
+// On an aggregate, Limit appends a $limit stage at the current pipeline position.
 func (b *Builder) Limit(n int) *Builder {
 	if b.err != nil {
 		return b
@@ -244,15 +607,16 @@ func (b *Builder) Limit(n int) *Builder {
 		b.err = fmt.Errorf("Limit() can only be used with read operations")
 		return b
 	}
-	if n > types.MaxLimit {
-		b.err = fmt.Errorf("limit exceeds maximum: %d > %d", n, types.MaxLimit)
+	if err := types.ValidateLimit(n, b.limits()); err != nil {
+		b.err = err
 		return b
 	}
-	b.ast.Limit = &types.PaginationValue{Static: &n}
+	b.setLimit(types.PaginationValue{Static: &n})
 	return b
 }
 
 // LimitParam sets limit from a parameter.
+// On an aggregate, LimitParam appends a $limit stage at the current pipeline position.
 func (b *Builder) LimitParam(p types.Param) *Builder {
 	if b.err != nil {
 		return b
@@ -261,7 +625,213 @@ func (b *Builder) LimitParam(p types.Param) *Builder {
 		b.err = fmt.Errorf("LimitParam() can only be used with read operations")
 		return b
 	}
-	b.ast.Limit = &types.PaginationValue{Param: &p}
+	b.setLimit(types.PaginationValue{Param: &p})
+	return b
+}
+
+// setSkip stores skip on the AST, or as a $skip stage for aggregates whose
+// renderers only read the pipeline.
+func (b *Builder) setSkip(v types.PaginationValue) {
+	if b.ast.Operation == types.OpAggregate {
+		b.ast.Pipeline = append(b.ast.Pipeline, types.SkipStage{Skip: v})
+		return
+	}
+	b.ast.Skip = &v
+}
+
+// setLimit stores limit on the AST, or as a $limit stage for aggregates whose
+// renderers only read the pipeline.
+func (b *Builder) setLimit(v types.PaginationValue) {
+	if b.ast.Operation == types.OpAggregate {
+		b.ast.Pipeline = append(b.ast.Pipeline, types.LimitStage{Limit: v})
+		return
+	}
+	b.ast.Limit = &v
+}
+
+// MaxTime sets a server-side execution time limit for read operations.
+// The duration is stored in milliseconds.
+func (b *Builder) MaxTime(d time.Duration) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if !b.isReadOperation() {
+		b.err = fmt.Errorf("MaxTime() can only be used with read operations")
+		return b
+	}
+	ms := int(d.Milliseconds())
+	if ms <= 0 {
+		b.err = fmt.Errorf("MaxTime() requires a duration of at least 1ms: %s", d)
+		return b
+	}
+	if limits := b.limits(); ms > limits.MaxQueryTimeMS {
+		b.err = &types.LimitExceededError{Limit: "MaxQueryTimeMS", Value: ms, Max: limits.MaxQueryTimeMS}
+		return b
+	}
+	b.ast.MaxTimeMS = &ms
+	return b
+}
+
+// MaxTimeMS sets a server-side execution time limit, in milliseconds, for an
+// aggregation. Use MaxTime for other reads.
+func (b *Builder) MaxTimeMS(ms int) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpAggregate {
+		b.err = fmt.Errorf("MaxTimeMS() can only be used with AGGREGATE; use MaxTime() for %s", b.ast.Operation)
+		return b
+	}
+	if ms <= 0 {
+		b.err = fmt.Errorf("MaxTimeMS() must be positive: %d", ms)
+		return b
+	}
+	if limits := b.limits(); ms > limits.MaxQueryTimeMS {
+		b.err = &types.LimitExceededError{Limit: "MaxQueryTimeMS", Value: ms, Max: limits.MaxQueryTimeMS}
+		return b
+	}
+	b.ast.MaxTimeMS = &ms
+	return b
+}
+
+// AllowDiskUse lets aggregation stages that exceed the server's memory limit
+// write temporary files. Renderers without the option ignore it.
+func (b *Builder) AllowDiskUse(allow bool) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpAggregate {
+		b.err = fmt.Errorf("AllowDiskUse() can only be used with AGGREGATE, got %s", b.ast.Operation)
+		return b
+	}
+	b.ast.AllowDiskUse = allow
+	return b
+}
+
+// Hint forces the query to use the named index. It applies to reads, updates,
+// and deletes; renderers without index hints ignore it.
+func (b *Builder) Hint(index string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	switch b.ast.Operation {
+	case types.OpInsert, types.OpInsertMany, types.OpTransaction:
+		b.err = fmt.Errorf("Hint() cannot be used with %s operations", b.ast.Operation)
+		return b
+	}
+	if index == "" {
+		b.err = fmt.Errorf("Hint() requires an index name")
+		return b
+	}
+	b.ast.Hint = &index
+	return b
+}
+
+// Collation sets the string comparison rules for the query's filter and sort,
+// e.g. Collation("en", 2) for case-insensitive matching. Strength is an ICU
+// comparison level from 1 to 5.
+func (b *Builder) Collation(locale string, strength int) *Builder {
+	if b.err != nil {
+		return b
+	}
+	switch b.ast.Operation {
+	case types.OpInsert, types.OpInsertMany, types.OpTransaction:
+		b.err = fmt.Errorf("Collation() cannot be used with %s operations", b.ast.Operation)
+		return b
+	}
+	if locale == "" {
+		b.err = fmt.Errorf("Collation() requires a locale")
+		return b
+	}
+	if strength < 1 || strength > 5 {
+		b.err = fmt.Errorf("Collation() strength must be between 1 and 5: %d", strength)
+		return b
+	}
+	b.ast.Collation = &types.Collation{Locale: locale, Strength: strength}
+	return b
+}
+
+// ReadConcern sets the read concern level of a read or transaction, such as
+// "majority" or "snapshot". Renderers without read concerns ignore it.
+func (b *Builder) ReadConcern(level string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation.IsWrite() {
+		b.err = fmt.Errorf("ReadConcern() cannot be used with %s operations", b.ast.Operation)
+		return b
+	}
+	if level == "" {
+		b.err = fmt.Errorf("ReadConcern() requires a level")
+		return b
+	}
+	b.ast.ReadConcern = &level
+	return b
+}
+
+// WriteConcern sets the write concern of a write or transaction: "majority",
+// an acknowledgement count such as "1", or a tag set name. Renderers without
+// write concerns ignore it.
+func (b *Builder) WriteConcern(w string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if !b.ast.Operation.IsWrite() && b.ast.Operation != types.OpTransaction {
+		b.err = fmt.Errorf("WriteConcern() cannot be used with %s operations", b.ast.Operation)
+		return b
+	}
+	if w == "" {
+		b.err = fmt.Errorf("WriteConcern() requires a value")
+		return b
+	}
+	b.ast.WriteConcern = &w
+	return b
+}
+
+// RawEvent makes a change stream's filter address change event fields, such
+// as operationType or updateDescription.updatedFields, instead of the
+// changed document's fields.
+func (b *Builder) RawEvent() *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpWatch {
+		b.err = fmt.Errorf("RawEvent() can only be used with WATCH operations")
+		return b
+	}
+	b.ast.RawEvent = true
+	return b
+}
+
+// FullDocument sets what a change stream returns as the changed document,
+// such as "updateLookup" to include the current document on updates.
+func (b *Builder) FullDocument(mode string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpWatch {
+		b.err = fmt.Errorf("FullDocument() can only be used with WATCH operations")
+		return b
+	}
+	if mode == "" {
+		b.err = fmt.Errorf("FullDocument() requires a mode")
+		return b
+	}
+	b.ast.FullDocument = &mode
+	return b
+}
+
+// ResumeAfterParam resumes a change stream after the event whose resume
+// token is bound to p.
+func (b *Builder) ResumeAfterParam(p types.Param) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpWatch {
+		b.err = fmt.Errorf("ResumeAfterParam() can only be used with WATCH operations")
+		return b
+	}
+	b.ast.ResumeAfter = &p
 	return b
 }
 
@@ -291,6 +861,53 @@ func (b *Builder) Documents(docs []types.Document) *Builder {
 	return b
 }
 
+// IndexedParam names the param for document i of a batch insert, as Build
+// namespaces params shared between documents: "email" becomes "email_0".
+func IndexedParam(base types.Param, i int) types.Param {
+	return types.Param{Name: base.Name + "_" + strconv.Itoa(i)}
+}
+
+// namespaceDocumentParams renames each param used by more than one document
+// to its IndexedParam in every document that uses it, so each document binds
+// its own values. It returns nil when no param is shared, and an error when a
+// generated name is already used by another param.
+func namespaceDocumentParams(docs []types.Document) ([]types.Document, error) {
+	uses := make(map[string]int)
+	for _, doc := range docs {
+		seen := make(map[string]bool, len(doc.Fields))
+		for _, p := range doc.Fields {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				uses[p.Name]++
+			}
+		}
+	}
+	shared := false
+	for _, n := range uses {
+		shared = shared || n > 1
+	}
+	if !shared {
+		return nil, nil
+	}
+
+	out := make([]types.Document, len(docs))
+	for i, doc := range docs {
+		fields := make(map[types.Field]types.Param, len(doc.Fields))
+		for f, p := range doc.Fields {
+			if uses[p.Name] > 1 {
+				indexed := IndexedParam(p, i)
+				if uses[indexed.Name] > 0 {
+					return nil, fmt.Errorf("INSERT_MANY document %d: cannot namespace param '%s' as '%s', which is already used", i, p.Name, indexed.Name)
+				}
+				p = indexed
+			}
+			fields[f] = p
+		}
+		out[i] = types.Document{Fields: fields}
+	}
+	return out, nil
+}
+
 // Set adds a $set update operation.
 func (b *Builder) Set(field types.Field, value types.Param) *Builder {
 	if b.err != nil {
@@ -384,7 +1001,24 @@ func (b *Builder) AddToSet(field types.Field, value types.Param) *Builder {
 	return b
 }
 
-// Upsert enables upsert mode.
+// IfNotExists makes an insert fail when a document with the same key already
+// exists, rather than overwrite it. DynamoDB renders it as a
+// ConditionExpression on the partition key; most other providers never
+// overwrite on insert.
+func (b *Builder) IfNotExists() *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpInsert {
+		b.err = fmt.Errorf("IfNotExists() can only be used with INSERT")
+		return b
+	}
+	b.ast.IfNotExists = true
+	return b
+}
+
+// Upsert enables upsert mode. With UpdateMany, MongoDB updates every matching
+// document, or inserts a single new document when nothing matches the filter.
 func (b *Builder) Upsert() *Builder {
 	if b.err != nil {
 		return b
@@ -423,6 +1057,34 @@ func (b *Builder) Project(proj types.Projection) *Builder {
 	return b
 }
 
+// ProjectComputed adds a $project pipeline stage of computed fields, each
+// set to the value of its expression.
+func (b *Builder) ProjectComputed(fields map[string]types.Expression) *Builder {
+	return b.projectStage("ProjectComputed", types.ProjectStage{Computed: fields})
+}
+
+// ProjectWith adds a $project pipeline stage that keeps the fields of proj
+// alongside computed ones. An exclusion projection may only exclude _id.
+func (b *Builder) ProjectWith(proj types.Projection, computed map[string]types.Expression) *Builder {
+	return b.projectStage("ProjectWith", types.ProjectStage{Projection: proj, Computed: computed})
+}
+
+func (b *Builder) projectStage(method string, stage types.ProjectStage) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpAggregate {
+		b.err = fmt.Errorf("%s() can only be used with AGGREGATE", method)
+		return b
+	}
+	if err := validateStageKeys(stage); err != nil {
+		b.err = err
+		return b
+	}
+	b.ast.Pipeline = append(b.ast.Pipeline, stage)
+	return b
+}
+
 // Group adds a $group pipeline stage.
 func (b *Builder) Group(id types.Expression, accumulators map[string]types.Accumulator) *Builder {
 	if b.err != nil {
@@ -432,10 +1094,64 @@ func (b *Builder) Group(id types.Expression, accumulators map[string]types.Accum
 		b.err = fmt.Errorf("Group() can only be used with AGGREGATE")
 		return b
 	}
-	b.ast.Pipeline = append(b.ast.Pipeline, types.GroupStage{
+	stage := types.GroupStage{
 		ID:           id,
 		Accumulators: accumulators,
-	})
+	}
+	if err := validateStageKeys(stage); err != nil {
+		b.err = err
+		return b
+	}
+	b.ast.Pipeline = append(b.ast.Pipeline, stage)
+	return b
+}
+
+// GroupBy adds a $group pipeline stage keyed by several expressions, rendered
+// as a compound _id such as {"_id": {"status": "$status", "region": "$region"}}.
+func (b *Builder) GroupBy(fields map[string]types.Expression, accumulators map[string]types.Accumulator) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if len(fields) == 0 {
+		b.err = fmt.Errorf("GroupBy() requires at least one field")
+		return b
+	}
+	return b.Group(types.CompositeExpression{Fields: fields}, accumulators)
+}
+
+// AddFields adds an $addFields pipeline stage.
+func (b *Builder) AddFields(fields map[string]types.Expression) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpAggregate {
+		b.err = fmt.Errorf("AddFields() can only be used with AGGREGATE")
+		return b
+	}
+	stage := types.AddFieldsStage{Fields: fields}
+	if err := validateStageKeys(stage); err != nil {
+		b.err = err
+		return b
+	}
+	b.ast.Pipeline = append(b.ast.Pipeline, stage)
+	return b
+}
+
+// Facet adds a $facet pipeline stage.
+func (b *Builder) Facet(facets map[string][]types.PipelineStage) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpAggregate {
+		b.err = fmt.Errorf("Facet() can only be used with AGGREGATE")
+		return b
+	}
+	stage := types.FacetStage{Facets: facets}
+	if err := validateStageKeys(stage); err != nil {
+		b.err = err
+		return b
+	}
+	b.ast.Pipeline = append(b.ast.Pipeline, stage)
 	return b
 }
 
@@ -457,6 +1173,43 @@ func (b *Builder) Lookup(from string, localField, foreignField types.Field, as s
 	return b
 }
 
+// GraphLookupOpts holds the optional $graphLookup settings.
+type GraphLookupOpts struct {
+	// MaxDepth bounds recursion; nil searches without limit.
+	MaxDepth *types.Param
+	// DepthField names the field recording each match's recursion depth.
+	DepthField string
+	// RestrictSearchWithMatch filters the documents the search may visit.
+	RestrictSearchWithMatch types.FilterItem
+}
+
+// GraphLookup adds a $graphLookup pipeline stage for recursive traversal.
+func (b *Builder) GraphLookup(from string, startWith types.Expression, connectFromField, connectToField types.Field, as string, opts GraphLookupOpts) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpAggregate {
+		b.err = fmt.Errorf("GraphLookup() can only be used with AGGREGATE")
+		return b
+	}
+	stage := types.GraphLookupStage{
+		From:                    from,
+		StartWith:               startWith,
+		ConnectFromField:        connectFromField,
+		ConnectToField:          connectToField,
+		As:                      as,
+		RestrictSearchWithMatch: opts.RestrictSearchWithMatch,
+	}
+	if opts.MaxDepth != nil {
+		stage.MaxDepth = &types.PaginationValue{Param: opts.MaxDepth}
+	}
+	if opts.DepthField != "" {
+		stage.DepthField = &opts.DepthField
+	}
+	b.ast.Pipeline = append(b.ast.Pipeline, stage)
+	return b
+}
+
 // Unwind adds an $unwind pipeline stage.
 func (b *Builder) Unwind(path types.Field) *Builder {
 	if b.err != nil {
@@ -470,6 +1223,73 @@ func (b *Builder) Unwind(path types.Field) *Builder {
 	return b
 }
 
+// UnwindOpts adds an $unwind pipeline stage with its options. A non-empty
+// includeArrayIndex names the field that receives each element's index;
+// preserveNullAndEmpty keeps documents whose array is missing or empty.
+func (b *Builder) UnwindOpts(path types.Field, includeArrayIndex string, preserveNullAndEmpty bool) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpAggregate {
+		b.err = fmt.Errorf("UnwindOpts() can only be used with AGGREGATE")
+		return b
+	}
+	stage := types.UnwindStage{Path: path, PreserveNullAndEmptyArrays: preserveNullAndEmpty}
+	if includeArrayIndex != "" {
+		if !types.IsValidIdentifier(includeArrayIndex) {
+			b.err = &types.InvalidIdentifierError{Kind: "$unwind includeArrayIndex", Value: includeArrayIndex}
+			return b
+		}
+		stage.IncludeArrayIndex = &includeArrayIndex
+	}
+	b.ast.Pipeline = append(b.ast.Pipeline, stage)
+	return b
+}
+
+// SortByCount adds a $sortByCount pipeline stage.
+func (b *Builder) SortByCount(expr types.Expression) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpAggregate {
+		b.err = fmt.Errorf("SortByCount() can only be used with AGGREGATE")
+		return b
+	}
+	b.ast.Pipeline = append(b.ast.Pipeline, types.SortByCountStage{Expr: expr})
+	return b
+}
+
+// Sample adds a $sample pipeline stage selecting size random documents.
+func (b *Builder) Sample(size types.Param) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpAggregate {
+		b.err = fmt.Errorf("Sample() can only be used with AGGREGATE")
+		return b
+	}
+	b.ast.Pipeline = append(b.ast.Pipeline, types.SampleStage{Size: types.PaginationValue{Param: &size}})
+	return b
+}
+
+// MergeInto adds a $merge pipeline stage writing results into collection.
+// It must be the final stage; empty actions use the server defaults.
+func (b *Builder) MergeInto(collection string, whenMatched, whenNotMatched string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpAggregate {
+		b.err = fmt.Errorf("MergeInto() can only be used with AGGREGATE")
+		return b
+	}
+	b.ast.Pipeline = append(b.ast.Pipeline, types.MergeStage{
+		Into:           collection,
+		WhenMatched:    whenMatched,
+		WhenNotMatched: whenNotMatched,
+	})
+	return b
+}
+
 // Stage adds a custom pipeline stage.
 func (b *Builder) Stage(stage types.PipelineStage) *Builder {
 	if b.err != nil {
@@ -479,19 +1299,145 @@ func (b *Builder) Stage(stage types.PipelineStage) *Builder {
 		b.err = fmt.Errorf("Stage() can only be used with AGGREGATE")
 		return b
 	}
+	if err := validateStageKeys(stage); err != nil {
+		b.err = err
+		return b
+	}
 	b.ast.Pipeline = append(b.ast.Pipeline, stage)
 	return b
 }
 
-// Build returns the constructed AST or an error.
+// Err returns the first error recorded by a chained call, or nil. Unlike
+// Build, it does not validate the AST, so it can be checked mid-chain.
+func (b *Builder) Err() error {
+	return b.err
+}
+
+// Build returns the constructed AST or an error. The filter clause is
+// normalized with NormalizeFilter unless PreserveFilter is set. The query as
+// written is validated before any soft-delete filter is added, so an
+// UpdateMany or DeleteMany still needs a filter of its own.
 func (b *Builder) Build() (*types.DocumentAST, error) {
 	if b.err != nil {
 		return nil, b.err
 	}
-	if err := b.ast.Validate(); err != nil {
+	ast := b.ast
+	if !b.preserveFilter && ast.FilterClause != nil {
+		normalized := *ast
+		normalized.FilterClause = NormalizeFilter(ast.FilterClause)
+		ast = &normalized
+	}
+	if ast.Operation == types.OpInsertMany {
+		docs, err := namespaceDocumentParams(ast.Documents)
+		if err != nil {
+			return nil, err
+		}
+		if docs != nil {
+			namespaced := *ast
+			namespaced.Documents = docs
+			ast = &namespaced
+		}
+	}
+	if err := ast.Validate(); err != nil {
 		return nil, err
 	}
-	return b.ast, nil
+	if resolved := b.resolved(ast); resolved != ast {
+		if err := resolved.Validate(); err != nil {
+			return nil, err
+		}
+		ast = resolved
+	}
+	return ast, nil
+}
+
+// PreserveFilter keeps the filter clause exactly as written instead of
+// normalizing it at Build.
+func (b *Builder) PreserveFilter() *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.preserveFilter = true
+	b.filterDepth, b.filterLogic = b.filterShape(b.ast.FilterClause)
+	return b
+}
+
+// Clone returns an independent copy of the builder, including any recorded
+// error. Changes to either builder never affect the other.
+func (b *Builder) Clone() *Builder {
+	return &Builder{
+		ast:            b.ast.Clone(),
+		err:            b.err,
+		softDelete:     b.softDelete,
+		includeDeleted: b.includeDeleted,
+		asSoftDelete:   b.asSoftDelete,
+		preserveFilter: b.preserveFilter,
+		filterDepth:    b.filterDepth,
+		filterLogic:    b.filterLogic,
+		hooks:          b.hooks,
+	}
+}
+
+// Paginate derives a page query and a matching count query from a Find
+// builder. The find variant skips (page-1)*pageSize documents and limits to
+// pageSize; the count variant keeps the filter but drops sort, projection,
+// and pagination. Pages are numbered from 1. The base builder is unchanged.
+func Paginate(base *Builder, page, pageSize int) (find *Builder, count *Builder, err error) {
+	if err := checkPaginateBase(base); err != nil {
+		return nil, nil, err
+	}
+	if page < 1 {
+		return nil, nil, fmt.Errorf("page must be at least 1, got %d", page)
+	}
+	if pageSize < 1 {
+		return nil, nil, fmt.Errorf("page size must be at least 1, got %d", pageSize)
+	}
+	if limits := base.limits(); pageSize > limits.MaxLimit {
+		return nil, nil, &types.LimitExceededError{Limit: "MaxLimit", Value: pageSize, Max: limits.MaxLimit}
+	}
+	if page-1 > math.MaxInt/pageSize {
+		return nil, nil, fmt.Errorf("page %d with page size %d overflows skip", page, pageSize)
+	}
+
+	find = base.Clone().Skip((page - 1) * pageSize).Limit(pageSize)
+	if find.err != nil {
+		return nil, nil, find.err
+	}
+	return find, countOf(base), nil
+}
+
+// PaginateParams is Paginate with the offset and page size bound at render
+// time. Renderers cannot compute (page-1)*size, so offsetParam carries the
+// number of documents to skip rather than the page number.
+func PaginateParams(base *Builder, offsetParam, sizeParam types.Param) (find *Builder, count *Builder, err error) {
+	if err := checkPaginateBase(base); err != nil {
+		return nil, nil, err
+	}
+	find = base.Clone().SkipParam(offsetParam).LimitParam(sizeParam)
+	if find.err != nil {
+		return nil, nil, find.err
+	}
+	return find, countOf(base), nil
+}
+
+func checkPaginateBase(base *Builder) error {
+	if base.err != nil {
+		return base.err
+	}
+	if base.ast.Operation != types.OpFind {
+		return fmt.Errorf("Paginate() requires a find query, got %s", base.ast.Operation)
+	}
+	return nil
+}
+
+// countOf copies base as a count query over the same filter.
+func countOf(base *Builder) *Builder {
+	count := base.Clone()
+	count.ast.Operation = types.OpCount
+	count.ast.Projection = nil
+	count.ast.SortClauses = nil
+	count.ast.Skip = nil
+	count.ast.Limit = nil
+	return count
 }
 
 // MustBuild returns the AST or panics on error.
@@ -509,7 +1455,40 @@ func (b *Builder) Render(renderer Renderer) (*types.QueryResult, error) {
 	if err != nil {
 		return nil, err
 	}
-	return renderer.Render(ast)
+	result, err := hooked(renderer, b.hooks).Render(ast)
+	if err != nil {
+		return nil, err
+	}
+	result.ParamMinimums = paramMinimums(ast)
+	return result, nil
+}
+
+// paramMinimums collects the params bound to a skip or limit, on the query
+// or in a $skip or $limit stage, with the smallest value each accepts.
+func paramMinimums(ast *types.DocumentAST) map[string]int {
+	var out map[string]int
+	add := func(v *types.PaginationValue, minimum int) {
+		if v == nil || v.Param == nil {
+			return
+		}
+		if out == nil {
+			out = make(map[string]int)
+		}
+		if current, ok := out[v.Param.Name]; !ok || minimum > current {
+			out[v.Param.Name] = minimum
+		}
+	}
+	add(ast.Skip, 0)
+	add(ast.Limit, 1)
+	for _, stage := range ast.Pipeline {
+		switch s := stage.(type) {
+		case types.SkipStage:
+			add(&s.Skip, 0)
+		case types.LimitStage:
+			add(&s.Limit, 1)
+		}
+	}
+	return out
 }
 
 // MustRender renders the query or panics on error.
@@ -521,6 +1500,37 @@ func (b *Builder) MustRender(renderer Renderer) *types.QueryResult {
 	return result
 }
 
+// RequireFilterForSingleWrites makes Update and Delete without a filter an
+// error, matching UpdateMany and DeleteMany. An unfiltered single write
+// otherwise mutates an arbitrary document.
+func (b *Builder) RequireFilterForSingleWrites() *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.ast.RequireFilter = true
+	return b
+}
+
+// WithLimits validates this query against the given limits instead of the
+// package defaults; fields left at zero keep their defaults. Call it before
+// Limit(), MaxTime(), or Filter() so their immediate checks use the same
+// ceilings; renderers re-validate with these limits too.
+func (b *Builder) WithLimits(limits types.Limits) *Builder {
+	if b.err != nil {
+		return b
+	}
+	limits = limits.WithDefaults()
+	b.ast.Limits = &limits
+	return b
+}
+
+func (b *Builder) limits() types.Limits {
+	if b.ast.Limits != nil {
+		return *b.ast.Limits
+	}
+	return types.DefaultLimits()
+}
+
 func (b *Builder) isReadOperation() bool {
 	return b.ast.Operation == types.OpFind ||
 		b.ast.Operation == types.OpFindOne ||
@@ -546,3 +1556,65 @@ func (b *Builder) addOrMergeUpdate(op types.UpdateOperator, field types.Field, v
 		Fields:   map[types.Field]types.Param{field: value},
 	})
 }
+
+// validateStageKeys checks that every user-supplied key in a map-keyed pipeline
+// stage is a valid identifier. These keys become JSON object keys in the rendered
+// query, so they must not start with "$", contain dots, or carry quoting characters.
+func validateStageKeys(stage types.PipelineStage) error {
+	switch s := stage.(type) {
+	case types.GroupStage:
+		if composite, ok := s.ID.(types.CompositeExpression); ok {
+			for name := range composite.Fields {
+				if !types.IsValidIdentifier(name) {
+					return &types.InvalidIdentifierError{Kind: "$group _id key", Value: name}
+				}
+			}
+		}
+		for name := range s.Accumulators {
+			if !types.IsValidIdentifier(name) {
+				return &types.InvalidIdentifierError{Kind: "accumulator name", Value: name}
+			}
+		}
+	case types.AddFieldsStage:
+		for name := range s.Fields {
+			if !types.IsValidIdentifier(name) {
+				return &types.InvalidIdentifierError{Kind: "$addFields key", Value: name}
+			}
+		}
+	case types.ProjectStage:
+		for name := range s.Computed {
+			if !types.IsValidIdentifier(name) {
+				return &types.InvalidIdentifierError{Kind: "computed projection key", Value: name}
+			}
+		}
+	case types.BucketStage:
+		for name := range s.Output {
+			if !types.IsValidIdentifier(name) {
+				return &types.InvalidIdentifierError{Kind: "$bucket output name", Value: name}
+			}
+		}
+	case types.LookupStage:
+		for name := range s.Let {
+			if !types.IsValidIdentifier(name) {
+				return &types.InvalidIdentifierError{Kind: "$lookup variable name", Value: name}
+			}
+		}
+		for _, sub := range s.Pipeline {
+			if err := validateStageKeys(sub); err != nil {
+				return err
+			}
+		}
+	case types.FacetStage:
+		for name, pipeline := range s.Facets {
+			if !types.IsValidIdentifier(name) {
+				return &types.InvalidIdentifierError{Kind: "facet name", Value: name}
+			}
+			for _, sub := range pipeline {
+				if err := validateStageKeys(sub); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
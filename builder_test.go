@@ -1,7 +1,12 @@
 package docql
 
 import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/zoobzio/docql/internal/types"
 )
@@ -52,6 +57,236 @@ func TestFind_WithFilter(t *testing.T) {
 	}
 }
 
+func TestFind_OrFilter(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	status := types.Field{Path: "status", Collection: "users"}
+	role := types.Field{Path: "role", Collection: "users"}
+	age := types.Field{Path: "age", Collection: "users"}
+
+	a := Eq(status, types.Param{Name: "status"})
+	b := Eq(role, types.Param{Name: "role"})
+
+	ast, err := Find(coll).Filter(a).OrFilter(b).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	group, ok := ast.FilterClause.(types.FilterGroup)
+	if !ok {
+		t.Fatalf("expected FilterGroup, got %T", ast.FilterClause)
+	}
+	if group.Logic != types.OR {
+		t.Errorf("expected OR logic, got %s", group.Logic)
+	}
+	if len(group.Conditions) != 2 || group.Conditions[0] != a || group.Conditions[1] != b {
+		t.Errorf("expected both conditions, got %v", group.Conditions)
+	}
+
+	// OrFilter wraps the existing AND clause rather than joining it.
+	c := Gt(age, types.Param{Name: "minAge"})
+	ast, err = Find(coll).Filter(a).Filter(c).OrFilter(b).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	group = ast.FilterClause.(types.FilterGroup)
+	if group.Logic != types.OR || len(group.Conditions) != 2 {
+		t.Fatalf("expected OR of two clauses, got %+v", group)
+	}
+	if inner, ok := group.Conditions[0].(types.FilterGroup); !ok || inner.Logic != types.AND {
+		t.Errorf("expected first OR branch to be the AND clause, got %v", group.Conditions[0])
+	}
+
+	// With no existing clause, OrFilter sets the filter directly.
+	ast, err = Find(coll).OrFilter(b).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.FilterClause != b {
+		t.Errorf("expected the lone condition, got %v", ast.FilterClause)
+	}
+}
+
+func TestFind_FilterOrGrouping(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	status := types.Field{Path: "status", Collection: "users"}
+	a := Eq(status, types.Param{Name: "a"})
+	b := Eq(status, types.Param{Name: "b"})
+	c := Eq(status, types.Param{Name: "c"})
+
+	// Filter(a).FilterOr(b).Filter(c) is ((a OR b) AND c), with or without
+	// normalization.
+	want := And(Or(a, b), c)
+	for _, builder := range []*Builder{
+		Find(coll).Filter(a).FilterOr(b).Filter(c),
+		Find(coll).PreserveFilter().Filter(a).FilterOr(b).Filter(c),
+	} {
+		ast, err := builder.Build()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(ast.FilterClause, want) {
+			t.Errorf("expected %v, got %v", want, ast.FilterClause)
+		}
+	}
+
+	// An existing OR clause absorbs the new condition without changing the
+	// group the caller passed in.
+	existing := Or(a, b)
+	ast, err := Find(coll).PreserveFilter().Filter(existing).FilterOr(c).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ast.FilterClause, Or(a, b, c)) {
+		t.Errorf("expected a flat OR of three, got %v", ast.FilterClause)
+	}
+	if len(existing.Conditions) != 2 {
+		t.Errorf("FilterOr changed the caller's group: %v", existing)
+	}
+}
+
+func TestFind_FilterReplace(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	status := types.Field{Path: "status", Collection: "users"}
+	a := Eq(status, types.Param{Name: "a"})
+	b := Eq(status, types.Param{Name: "b"})
+	c := Eq(status, types.Param{Name: "c"})
+
+	ast, err := Find(coll).Filter(a).FilterOr(b).FilterReplace(c).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.FilterClause != c {
+		t.Errorf("expected the replacement filter, got %v", ast.FilterClause)
+	}
+
+	ast, err = Find(coll).Filter(a).FilterReplace(nil).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.FilterClause != nil {
+		t.Errorf("expected FilterReplace(nil) to clear the filter, got %v", ast.FilterClause)
+	}
+}
+
+func TestFind_MergeFilter(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	status := types.Field{Path: "status", Collection: "users"}
+	role := types.Field{Path: "role", Collection: "users"}
+	age := types.Field{Path: "age", Collection: "users"}
+
+	a := Eq(status, types.Param{Name: "status"})
+	b := Eq(role, types.Param{Name: "role"})
+	c := Gt(age, types.Param{Name: "minAge"})
+
+	fragment := Find(coll).Filter(b).Filter(c)
+	ast, err := Find(coll).Filter(a).MergeFilter(fragment).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	group, ok := ast.FilterClause.(types.FilterGroup)
+	if !ok || group.Logic != types.AND {
+		t.Fatalf("expected AND group, got %v", ast.FilterClause)
+	}
+	if len(group.Conditions) != 3 || group.Conditions[0] != a || group.Conditions[1] != b || group.Conditions[2] != c {
+		t.Errorf("expected all three conditions, got %v", group.Conditions)
+	}
+	if params := requiredParams(ast); strings.Join(params, ",") != "minAge,role,status" {
+		t.Errorf("unexpected params: %v", params)
+	}
+
+	// The fragment is unchanged and can be merged again.
+	if fragment.ast.FilterClause.(types.FilterGroup).Conditions[0] != b {
+		t.Errorf("fragment was modified: %v", fragment.ast.FilterClause)
+	}
+
+	// Merging into an empty filter, or merging an empty one, keeps the other.
+	if ast, err = Find(coll).MergeFilter(Find(coll).Filter(a)).Build(); err != nil || ast.FilterClause != a {
+		t.Errorf("expected the merged condition alone, got %v, %v", ast, err)
+	}
+	if ast, err = Find(coll).Filter(a).MergeFilter(Find(coll)).Build(); err != nil || ast.FilterClause != a {
+		t.Errorf("expected the original condition alone, got %v, %v", ast, err)
+	}
+
+	tests := []struct {
+		name  string
+		other *Builder
+		want  string
+	}{
+		{"operation", Count(coll).Filter(b), "matching operations"},
+		{"collection", Find(types.Collection{Name: "posts"}).Filter(b), "matching collections"},
+		{"error", Insert(coll).Select(status), "MergeFilter(): Select()"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Find(coll).Filter(a).MergeFilter(tt.other).Build()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+	status := types.Field{Path: "status", Collection: "orders"}
+
+	ast, err := Watch(coll).
+		Filter(Eq(status, types.Param{Name: "status"})).
+		FullDocument("updateLookup").
+		ResumeAfterParam(types.Param{Name: "token"}).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.Operation != types.OpWatch || ast.RawEvent {
+		t.Errorf("unexpected watch AST: %+v", ast)
+	}
+	if ast.FullDocument == nil || *ast.FullDocument != "updateLookup" {
+		t.Errorf("expected fullDocument updateLookup, got %v", ast.FullDocument)
+	}
+	if ast.ResumeAfter == nil || ast.ResumeAfter.Name != "token" {
+		t.Errorf("expected resumeAfter token, got %v", ast.ResumeAfter)
+	}
+	if params := requiredParams(ast); strings.Join(params, ",") != "status,token" {
+		t.Errorf("unexpected params: %v", params)
+	}
+
+	if ast, err = Watch(coll).RawEvent().Build(); err != nil || !ast.RawEvent {
+		t.Errorf("expected raw event watch, got %v, %v", ast, err)
+	}
+
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    string
+	}{
+		{"raw event on find", Find(coll).RawEvent(), "RawEvent() can only be used with WATCH"},
+		{"full document on find", Find(coll).FullDocument("updateLookup"), "FullDocument() can only be used with WATCH"},
+		{"resume on find", Find(coll).ResumeAfterParam(types.Param{Name: "token"}), "ResumeAfterParam() can only be used with WATCH"},
+		{"empty full document", Watch(coll).FullDocument(""), "requires a mode"},
+		{"unknown full document", Watch(coll).FullDocument("sometimes"), "unknown fullDocument mode"},
+		{"select", Watch(coll).Select(status), "read operations"},
+		{"limit", Watch(coll).Limit(10), "read operations"},
+		{"stage", Watch(coll).Match(Eq(status, types.Param{Name: "status"})), "AGGREGATE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.Build()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	withPipeline := &types.DocumentAST{Operation: types.OpWatch, Target: coll, Pipeline: []types.PipelineStage{types.CountStage{FieldName: "n"}}}
+	if err := withPipeline.Validate(); err == nil || !strings.Contains(err.Error(), "pipeline") {
+		t.Errorf("expected pipeline stages to be rejected, got %v", err)
+	}
+	raw := &types.DocumentAST{Operation: types.OpFind, Target: coll, RawEvent: true}
+	if err := raw.Validate(); err == nil || !strings.Contains(err.Error(), "only valid for WATCH") {
+		t.Errorf("expected change stream options to be rejected on FIND, got %v", err)
+	}
+}
+
 func TestFind_WithSort(t *testing.T) {
 	coll := types.Collection{Name: "users"}
 	field := types.Field{Path: "createdAt", Collection: "users"}
@@ -102,6 +337,37 @@ func TestFind_LimitExceedsMax(t *testing.T) {
 	}
 }
 
+func TestFind_PaginationBoundaries(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+
+	tests := []struct {
+		name string
+		b    *Builder
+		ok   bool
+	}{
+		{"skip 0", Find(coll).Skip(0), true},
+		{"skip -1", Find(coll).Skip(-1), false},
+		{"limit 1", Find(coll).Limit(1), true},
+		{"limit max", Find(coll).Limit(types.MaxLimit), true},
+		{"limit 0", Find(coll).Limit(0), false},
+		{"limit -1", Find(coll).Limit(-1), false},
+		{"stage skip -1", Aggregate(coll).Skip(-1), false},
+		{"stage limit 0", Aggregate(coll).Limit(0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The fluent calls record the error before Build.
+			if err := tt.b.Err(); (err == nil) != tt.ok {
+				t.Errorf("Err() = %v, want ok %t", err, tt.ok)
+			}
+		})
+	}
+
+	if err := Find(coll).Limit(0).Err(); err == nil || !strings.Contains(err.Error(), "omit the limit") {
+		t.Errorf("expected Limit(0) to direct users to omit the limit, got %v", err)
+	}
+}
+
 func TestInsert(t *testing.T) {
 	coll := types.Collection{Name: "users"}
 	field := types.Field{Path: "email", Collection: "users"}
@@ -124,6 +390,22 @@ func TestInsert(t *testing.T) {
 	}
 }
 
+func TestInsert_IfNotExists(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	doc := Doc().Set(types.Field{Path: "email", Collection: "users"}, types.Param{Name: "email"}).Build()
+
+	ast, err := Insert(coll).Document(doc).IfNotExists().Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ast.IfNotExists {
+		t.Error("expected IfNotExists to be set")
+	}
+	if _, err := InsertMany(coll).Documents([]types.Document{doc}).IfNotExists().Build(); err == nil {
+		t.Error("expected IfNotExists() to be rejected for INSERT_MANY")
+	}
+}
+
 func TestInsert_RequiresDocument(t *testing.T) {
 	coll := types.Collection{Name: "users"}
 
@@ -154,6 +436,59 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdate_PathConflicts(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	f := func(path string) types.Field { return types.Field{Path: path, Collection: "users"} }
+	p := types.Param{Name: "v"}
+
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    string
+	}{
+		{
+			name:    "same path in two operators",
+			builder: Update(coll).Set(f("score"), p).Inc(f("score"), p),
+			want:    "conflicting update paths: 'score' in $set and 'score' in $inc",
+		},
+		{
+			name:    "parent and child",
+			builder: Update(coll).Set(f("address.city"), p).Unset(f("address")),
+			want:    "conflicting update paths: 'address' in $unset and 'address.city' in $set",
+		},
+		{
+			name:    "grandparent",
+			builder: UpdateMany(coll).Filter(Exists(f("address"))).Push(f("address.geo.points"), p).Unset(f("address")),
+			want:    "conflicting update paths: 'address' in $unset and 'address.geo.points' in $push",
+		},
+		{
+			name:    "merged into one operator",
+			builder: Update(coll).Set(f("address"), p).Inc(f("visits"), p).Set(f("address.zip"), p),
+			want:    "conflicting update paths: 'address' in $set and 'address.zip' in $set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.Build()
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	allowed := []*Builder{
+		Update(coll).Set(f("address.city"), p).Unset(f("address.zip")),
+		Update(coll).Set(f("address"), p).Unset(f("addressLine")),
+		Update(coll).Set(f("a-b"), p).Set(f("a.b"), p).Inc(f("ab"), p),
+		Update(coll).Set(f("status"), p).Set(f("status"), types.Param{Name: "w"}),
+	}
+	for i, b := range allowed {
+		if _, err := b.Build(); err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+		}
+	}
+}
+
 func TestUpdate_RequiresUpdateOps(t *testing.T) {
 	coll := types.Collection{Name: "users"}
 
@@ -261,6 +596,15 @@ func TestDistinct(t *testing.T) {
 	}
 }
 
+func TestDistinct_FieldFromOtherCollection(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	field := types.Field{Path: "title", Collection: "posts"}
+
+	if _, err := Distinct(coll, field).Build(); err == nil {
+		t.Error("expected error for field from another collection")
+	}
+}
+
 func TestOperationMismatch(t *testing.T) {
 	coll := types.Collection{Name: "users"}
 	field := types.Field{Path: "status", Collection: "users"}
@@ -284,3 +628,1101 @@ func TestOperationMismatch(t *testing.T) {
 		t.Error("expected error for Match() on Find")
 	}
 }
+
+func TestBuilder_Err(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	field := types.Field{Path: "status", Collection: "users"}
+	param := types.Param{Name: "value"}
+
+	b := Find(coll)
+	if b.Err() != nil {
+		t.Fatalf("expected no error on a fresh builder, got %v", b.Err())
+	}
+
+	b.Set(field, param)
+	err := b.Err()
+	if err == nil {
+		t.Fatal("expected Err() to report Set() on Find immediately")
+	}
+
+	b.Filter(Eq(field, param)).SortAsc(field).Limit(10)
+	if b.Err() != err {
+		t.Errorf("expected the first error to be kept, got %v", b.Err())
+	}
+	if b.ast.FilterClause != nil || len(b.ast.SortClauses) != 0 || b.ast.Limit != nil {
+		t.Error("expected chained calls after an error to be no-ops")
+	}
+}
+
+func TestAggregate_MapKeyValidation(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+	total := FieldExpr(types.Field{Path: "total", Collection: "orders"})
+
+	malicious := []string{
+		`x", "$where": "sleep(1000)`,
+		"$where",
+		"a.b",
+		"",
+		"drop table",
+	}
+
+	for _, key := range malicious {
+		_, err := Aggregate(coll).
+			Group(total, map[string]types.Accumulator{key: Sum(total)}).
+			Build()
+		if err == nil {
+			t.Errorf("expected Group() error for key %q", key)
+		}
+
+		_, err = Aggregate(coll).
+			AddFields(map[string]types.Expression{key: total}).
+			Build()
+		if err == nil {
+			t.Errorf("expected AddFields() error for key %q", key)
+		}
+
+		_, err = Aggregate(coll).
+			GroupBy(map[string]types.Expression{key: total}, nil).
+			Build()
+		if err == nil {
+			t.Errorf("expected GroupBy() error for key %q", key)
+		}
+
+		_, err = Aggregate(coll).
+			Facet(map[string][]types.PipelineStage{key: {types.CountStage{FieldName: "n"}}}).
+			Build()
+		if err == nil {
+			t.Errorf("expected Facet() error for key %q", key)
+		}
+
+		_, err = Aggregate(coll).
+			Facet(map[string][]types.PipelineStage{
+				"nested": {types.GroupStage{ID: total, Accumulators: map[string]types.Accumulator{key: Sum(total)}}},
+			}).
+			Build()
+		if err == nil {
+			t.Errorf("expected Facet() error for nested group key %q", key)
+		}
+
+		_, err = Aggregate(coll).
+			Stage(types.BucketStage{GroupBy: total, Output: map[string]types.Accumulator{key: Sum(total)}}).
+			Build()
+		if err == nil {
+			t.Errorf("expected Stage() error for bucket key %q", key)
+		}
+
+		_, err = Aggregate(coll).
+			Stage(types.ProjectStage{Computed: map[string]types.Expression{key: total}}).
+			Build()
+		if err == nil {
+			t.Errorf("expected Stage() error for computed projection key %q", key)
+		}
+
+		_, err = Aggregate(coll).
+			ProjectComputed(map[string]types.Expression{key: total}).
+			Build()
+		if err == nil {
+			t.Errorf("expected ProjectComputed() error for key %q", key)
+		}
+
+		_, err = Aggregate(coll).
+			Stage(types.LookupStage{From: "users", As: "user", Let: map[string]types.Expression{key: total}}).
+			Build()
+		if err == nil {
+			t.Errorf("expected Stage() error for lookup variable %q", key)
+		}
+	}
+}
+
+func TestAggregate_GroupBy(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+	status := FieldExpr(types.Field{Path: "status", Collection: "orders"})
+	region := FieldExpr(types.Field{Path: "region", Collection: "orders"})
+	total := FieldExpr(types.Field{Path: "total", Collection: "orders"})
+
+	ast, err := Aggregate(coll).
+		GroupBy(map[string]types.Expression{"status": status, "region": region}, map[string]types.Accumulator{"sum": Sum(total)}).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	group, ok := ast.Pipeline[0].(types.GroupStage)
+	if !ok {
+		t.Fatalf("expected GroupStage, got %T", ast.Pipeline[0])
+	}
+	id, ok := group.ID.(types.CompositeExpression)
+	if !ok || len(id.Fields) != 2 || id.Fields["status"] != status || id.Fields["region"] != region {
+		t.Errorf("unexpected composite _id: %#v", group.ID)
+	}
+
+	if _, err := Aggregate(coll).GroupBy(nil, nil).Build(); err == nil {
+		t.Error("expected error for GroupBy() without fields")
+	}
+	if _, err := Find(coll).GroupBy(map[string]types.Expression{"status": status}, nil).Build(); err == nil {
+		t.Error("expected error for GroupBy() on FIND")
+	}
+}
+
+func TestAggregate_ValidMapKeys(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+	total := FieldExpr(types.Field{Path: "total", Collection: "orders"})
+
+	ast, err := Aggregate(coll).
+		Group(total, map[string]types.Accumulator{"sumTotal": Sum(total)}).
+		AddFields(map[string]types.Expression{"grand_total": total}).
+		Facet(map[string][]types.PipelineStage{"counts": {types.CountStage{FieldName: "n"}}}).
+		Build()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ast.Pipeline) != 3 {
+		t.Errorf("expected 3 pipeline stages, got %d", len(ast.Pipeline))
+	}
+}
+
+func TestMaxTime(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+
+	ast, err := Find(coll).MaxTime(1500 * time.Millisecond).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.MaxTimeMS == nil || *ast.MaxTimeMS != 1500 {
+		t.Errorf("expected MaxTimeMS 1500, got %v", ast.MaxTimeMS)
+	}
+
+	ast, err = Aggregate(coll).
+		Match(Eq(types.Field{Path: "status"}, types.Param{Name: "status"})).
+		MaxTime(2 * time.Second).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.MaxTimeMS == nil || *ast.MaxTimeMS != 2000 {
+		t.Errorf("expected MaxTimeMS 2000, got %v", ast.MaxTimeMS)
+	}
+}
+
+func TestMaxTime_Invalid(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+
+	invalid := []time.Duration{0, -time.Second, time.Microsecond, time.Hour + time.Millisecond}
+	for _, d := range invalid {
+		if _, err := Find(coll).MaxTime(d).Build(); err == nil {
+			t.Errorf("expected error for MaxTime(%s)", d)
+		}
+	}
+
+	if _, err := Find(coll).MaxTime(time.Hour).Build(); err != nil {
+		t.Errorf("expected MaxTime(1h) to be accepted, got: %v", err)
+	}
+
+	field := types.Field{Path: "status"}
+	if _, err := Update(coll).Set(field, types.Param{Name: "s"}).MaxTime(time.Second).Build(); err == nil {
+		t.Error("expected error for MaxTime() on Update")
+	}
+}
+
+func TestAggregate_Options(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+	match := Eq(types.Field{Path: "status"}, types.Param{Name: "status"})
+
+	ast, err := Aggregate(coll).Match(match).AllowDiskUse(true).MaxTimeMS(5000).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ast.AllowDiskUse {
+		t.Error("expected AllowDiskUse to be set")
+	}
+	if ast.MaxTimeMS == nil || *ast.MaxTimeMS != 5000 {
+		t.Errorf("expected MaxTimeMS 5000, got %v", ast.MaxTimeMS)
+	}
+
+	if _, err := Find(coll).AllowDiskUse(true).Build(); err == nil {
+		t.Error("expected error for AllowDiskUse() on Find")
+	}
+	if _, err := Find(coll).MaxTimeMS(100).Build(); err == nil {
+		t.Error("expected error for MaxTimeMS() on Find")
+	}
+	for _, ms := range []int{0, -1, int(time.Hour.Milliseconds()) + 1} {
+		if _, err := Aggregate(coll).Match(match).MaxTimeMS(ms).Build(); err == nil {
+			t.Errorf("expected error for MaxTimeMS(%d)", ms)
+		}
+	}
+	if err := (&types.DocumentAST{Operation: types.OpFind, Target: coll, AllowDiskUse: true}).Validate(); err == nil {
+		t.Error("expected validation error for allowDiskUse on FIND")
+	}
+}
+
+func TestUpdateMany_Upsert(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	status := types.Field{Path: "status", Collection: "users"}
+
+	ast, err := UpdateMany(coll).
+		Filter(Eq(status, types.Param{Name: "oldStatus"})).
+		Set(status, types.Param{Name: "newStatus"}).
+		Upsert().
+		Build()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.Operation != types.OpUpdateMany {
+		t.Errorf("expected OpUpdateMany, got %s", ast.Operation)
+	}
+	if !ast.Upsert {
+		t.Error("expected Upsert to be true")
+	}
+}
+
+func TestAggregate_LimitSkipAppendStages(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+	status := types.Field{Path: "status", Collection: "orders"}
+
+	ast, err := Aggregate(coll).
+		Match(Eq(status, types.Param{Name: "status"})).
+		SkipParam(types.Param{Name: "offset"}).
+		Limit(10).
+		Build()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.Skip != nil || ast.Limit != nil {
+		t.Error("expected top-level Skip/Limit to remain unset for aggregates")
+	}
+	if len(ast.Pipeline) != 3 {
+		t.Fatalf("expected 3 pipeline stages, got %d", len(ast.Pipeline))
+	}
+	skip, ok := ast.Pipeline[1].(types.SkipStage)
+	if !ok || skip.Skip.Param == nil || skip.Skip.Param.Name != "offset" {
+		t.Errorf("expected $skip stage with offset param, got %#v", ast.Pipeline[1])
+	}
+	limit, ok := ast.Pipeline[2].(types.LimitStage)
+	if !ok || limit.Limit.Static == nil || *limit.Limit.Static != 10 {
+		t.Errorf("expected $limit stage of 10, got %#v", ast.Pipeline[2])
+	}
+}
+
+func TestAggregate_SortAppendsStage(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+	status := types.Field{Path: "status", Collection: "orders"}
+	total := types.Field{Path: "total", Collection: "orders"}
+	id := types.Field{Path: "_id", Collection: "orders"}
+
+	ast, err := Aggregate(coll).
+		Match(Eq(status, types.Param{Name: "status"})).
+		SortDesc(total).
+		SortAsc(id).
+		Limit(5).
+		SortAsc(status).
+		Build()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ast.SortClauses) != 0 {
+		t.Error("expected top-level SortClauses to remain unset for aggregates")
+	}
+	if len(ast.Pipeline) != 4 {
+		t.Fatalf("expected 4 pipeline stages, got %d", len(ast.Pipeline))
+	}
+	sort, ok := ast.Pipeline[1].(types.SortStage)
+	if !ok || len(sort.Sorts) != 2 || sort.Sorts[0].Field.Path != "total" || sort.Sorts[1].Field.Path != "_id" {
+		t.Errorf("expected consecutive sorts to share a $sort stage, got %#v", ast.Pipeline[1])
+	}
+	if _, ok := ast.Pipeline[2].(types.LimitStage); !ok {
+		t.Errorf("expected $limit stage, got %#v", ast.Pipeline[2])
+	}
+	sort, ok = ast.Pipeline[3].(types.SortStage)
+	if !ok || len(sort.Sorts) != 1 || sort.Sorts[0].Field.Path != "status" {
+		t.Errorf("expected a new $sort stage after $limit, got %#v", ast.Pipeline[3])
+	}
+}
+
+func TestAggregate_LimitExceedsMax(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+
+	_, err := Aggregate(coll).Limit(types.MaxLimit + 1).Build()
+	if err == nil {
+		t.Fatal("expected error for exceeding limit on aggregate")
+	}
+}
+
+func TestAggregate_AnalyticsStages(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+	status := types.Field{Path: "status", Collection: "orders"}
+
+	ast, err := Aggregate(coll).
+		Sample(types.Param{Name: "n"}).
+		SortByCount(FieldExpr(status)).
+		MergeInto("status_counts", MergeReplace, MergeInsert).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ast.Pipeline) != 3 {
+		t.Fatalf("expected 3 pipeline stages, got %d", len(ast.Pipeline))
+	}
+	for i, name := range []string{"$sample", "$sortByCount", "$merge"} {
+		if got := ast.Pipeline[i].StageName(); got != name {
+			t.Errorf("stage %d: expected %s, got %s", i, name, got)
+		}
+	}
+}
+
+func TestAggregate_MergeMustBeFinal(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+
+	_, err := Aggregate(coll).MergeInto("out", "", "").Limit(10).Build()
+	if err == nil || !strings.Contains(err.Error(), "final pipeline stage") {
+		t.Errorf("expected final-stage error, got %v", err)
+	}
+}
+
+func TestAggregate_MergeInvalidOptions(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+
+	tests := []*Builder{
+		Aggregate(coll).MergeInto("out; drop", "", ""),
+		Aggregate(coll).MergeInto("out", "overwrite", ""),
+		Aggregate(coll).MergeInto("out", "", "upsert"),
+	}
+	for i, b := range tests {
+		if _, err := b.Build(); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestAggregate_GraphLookup(t *testing.T) {
+	coll := types.Collection{Name: "employees"}
+	manager := types.Field{Path: "managerId", Collection: "employees"}
+	id := types.Field{Path: "_id", Collection: "employees"}
+	depth := types.Param{Name: "depth"}
+
+	ast, err := Aggregate(coll).
+		GraphLookup("employees", FieldExpr(manager), manager, id, "chain", GraphLookupOpts{
+			MaxDepth:   &depth,
+			DepthField: "level",
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stage, ok := ast.Pipeline[0].(types.GraphLookupStage)
+	if !ok {
+		t.Fatalf("expected GraphLookupStage, got %T", ast.Pipeline[0])
+	}
+	if stage.MaxDepth == nil || stage.MaxDepth.Param == nil || stage.MaxDepth.Param.Name != "depth" {
+		t.Errorf("expected maxDepth param, got %+v", stage.MaxDepth)
+	}
+	if stage.DepthField == nil || *stage.DepthField != "level" {
+		t.Errorf("expected depthField level, got %v", stage.DepthField)
+	}
+}
+
+func TestAggregate_GraphLookupValidation(t *testing.T) {
+	coll := types.Collection{Name: "employees"}
+	manager := types.Field{Path: "managerId", Collection: "employees"}
+	id := types.Field{Path: "_id", Collection: "employees"}
+	negative := -1
+
+	tests := map[string]types.GraphLookupStage{
+		"as name":   {From: "employees", StartWith: FieldExpr(manager), ConnectFromField: manager, ConnectToField: id, As: "$chain"},
+		"from":      {From: "emp;loyees", StartWith: FieldExpr(manager), ConnectFromField: manager, ConnectToField: id, As: "chain"},
+		"max depth": {From: "employees", StartWith: FieldExpr(manager), ConnectFromField: manager, ConnectToField: id, As: "chain", MaxDepth: &types.PaginationValue{Static: &negative}},
+	}
+	for name, stage := range tests {
+		if _, err := Aggregate(coll).Stage(stage).Build(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestAggregate_ProjectWith(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+	name := types.Field{Path: "name", Collection: "orders"}
+	price := FieldExpr(types.Field{Path: "price", Collection: "orders"})
+	computed := map[string]types.Expression{"total": price}
+
+	ast, err := Aggregate(coll).
+		ProjectWith(types.Projection{Fields: []types.ProjectionField{{Field: name, Include: true}}}, computed).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stage, ok := ast.Pipeline[0].(types.ProjectStage)
+	if !ok || len(stage.Projection.Fields) != 1 || stage.Computed["total"] != price {
+		t.Errorf("unexpected stage: %+v", ast.Pipeline[0])
+	}
+
+	// Excluding _id is the one exclusion computed fields allow.
+	id := types.Field{Path: types.IDField, Collection: "orders"}
+	exclude := func(f types.Field) types.Projection {
+		return types.Projection{Exclude: true, Fields: []types.ProjectionField{{Field: f}}}
+	}
+	if _, err := Aggregate(coll).ProjectWith(exclude(id), computed).Build(); err != nil {
+		t.Errorf("unexpected error excluding _id: %v", err)
+	}
+
+	invalid := map[string]*Builder{
+		"excluded field": Aggregate(coll).ProjectWith(exclude(name), computed),
+		"same name":      Aggregate(coll).ProjectWith(types.Projection{Fields: []types.ProjectionField{{Field: types.Field{Path: "total"}, Include: true}}}, computed),
+		"alias":          Aggregate(coll).ProjectWith(types.Projection{Fields: []types.ProjectionField{{Field: name, Include: true, Alias: "total"}}}, computed),
+		"nil expression": Aggregate(coll).ProjectComputed(map[string]types.Expression{"total": nil}),
+		"not aggregate":  Find(coll).ProjectComputed(computed),
+	}
+	for name, b := range invalid {
+		if _, err := b.Build(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestAggregate_SampleSizeMustBePositive(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+	zero := 0
+
+	_, err := Aggregate(coll).Stage(types.SampleStage{Size: types.PaginationValue{Static: &zero}}).Build()
+	if err == nil {
+		t.Error("expected error for non-positive $sample size")
+	}
+}
+
+func TestBuilder_WithLimits(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+
+	raised := types.DefaultLimits()
+	raised.MaxLimit = types.MaxLimit * 2
+
+	ast, err := Find(coll).WithLimits(raised).Limit(types.MaxLimit + 1).Build()
+	if err != nil {
+		t.Fatalf("unexpected error with raised limit: %v", err)
+	}
+	if ast.Limits == nil || ast.Limits.MaxLimit != raised.MaxLimit {
+		t.Error("expected limits to be carried on the AST")
+	}
+
+	lowered := types.DefaultLimits()
+	lowered.MaxLimit = 10
+	if _, err := Find(coll).WithLimits(lowered).Limit(11).Build(); err == nil {
+		t.Error("expected lowered limit to reject Limit(11)")
+	}
+	if _, err := Aggregate(coll).WithLimits(lowered).Limit(11).Build(); err == nil {
+		t.Error("expected lowered limit to reject aggregate Limit(11)")
+	}
+}
+
+func TestBuilder_WithLimits_PartialKeepsDefaults(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	field := types.Field{Path: "status", Collection: "users"}
+
+	ast, err := Find(coll).WithLimits(types.Limits{MaxLimit: 50}).
+		Filter(Eq(field, types.Param{Name: "status"})).
+		Limit(50).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.Limits.MaxFilterDepth != types.MaxFilterDepth {
+		t.Errorf("expected default MaxFilterDepth, got %d", ast.Limits.MaxFilterDepth)
+	}
+	if _, err := Find(coll).WithLimits(types.Limits{MaxLimit: 50}).Limit(51).Build(); err == nil {
+		t.Error("expected partial limits to apply MaxLimit")
+	}
+}
+
+func TestFilter_DepthCheckedEarly(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	field := types.Field{Path: "status", Collection: "users"}
+	status := Eq(field, types.Param{Name: "status"})
+
+	// nest alternates OR and AND over distinct conditions so normalization
+	// cannot flatten or collapse the groups.
+	nest := func(depth int) types.FilterItem {
+		f := types.FilterItem(status)
+		for i := range depth {
+			other := Eq(field, types.Param{Name: "s" + strconv.Itoa(i)})
+			if i%2 == 0 {
+				f = Or(f, other)
+			} else {
+				f = And(f, other)
+			}
+		}
+		return f
+	}
+
+	limits := types.DefaultLimits()
+	limits.MaxFilterDepth = 3
+
+	under := Find(coll).WithLimits(limits).Filter(nest(3))
+	if under.Err() != nil {
+		t.Fatalf("unexpected error at the depth limit: %v", under.Err())
+	}
+	if _, err := under.Build(); err != nil {
+		t.Errorf("Build failed at the depth limit: %v", err)
+	}
+
+	over := Find(coll).WithLimits(limits).Filter(nest(4))
+	var exceeded *types.LimitExceededError
+	if !errors.As(over.Err(), &exceeded) || exceeded.Limit != "MaxFilterDepth" {
+		t.Fatalf("expected MaxFilterDepth error from Filter, got %v", over.Err())
+	}
+	if exceeded.Value != 4 || exceeded.Max != 3 {
+		t.Errorf("expected depth 4 of max 3, got %d of %d", exceeded.Value, exceeded.Max)
+	}
+	if over.Sort(types.Field{Path: "status"}, types.Ascending).Err() != exceeded {
+		t.Error("expected later calls to keep the depth error")
+	}
+
+	// nest(3) is an OR group, so ANDing another condition onto it pushes the
+	// clause over the limit, while ORing one flattens into it.
+	if err := Find(coll).WithLimits(limits).Filter(nest(3)).Filter(status).Err(); !errors.As(err, &exceeded) {
+		t.Errorf("expected MaxFilterDepth error from a second Filter, got %v", err)
+	}
+	if err := Find(coll).WithLimits(limits).Filter(nest(3)).OrFilter(status).Err(); err != nil {
+		t.Errorf("unexpected error from OrFilter: %v", err)
+	}
+
+	// Chained Filter calls nest AND groups that Build flattens, so they are
+	// not rejected early.
+	chained := Find(coll).WithLimits(limits)
+	for i := range 6 {
+		chained.Filter(Eq(field, types.Param{Name: "c" + strconv.Itoa(i)}))
+	}
+	if _, err := chained.Build(); err != nil {
+		t.Errorf("expected chained filters to pass, got %v", err)
+	}
+}
+
+func TestFilter_DepthWithoutLimitsLeftToBuild(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	deep := nestedOr(types.MaxFilterDepth + 1)
+
+	b := Find(coll).Filter(deep)
+	if b.Err() != nil {
+		t.Fatalf("expected no error before Build without builder limits, got %v", b.Err())
+	}
+	if _, err := b.Build(); err == nil {
+		t.Error("expected Build to apply the default MaxFilterDepth")
+	}
+}
+
+func TestFilter_DepthEveryOperation(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	field := types.Field{Path: "status", Collection: "users"}
+	deep := nestedOr(types.MaxFilterDepth + 1)
+	limits := types.DefaultLimits()
+
+	builders := map[string]func() *Builder{
+		"find":        func() *Builder { return Find(coll) },
+		"count":       func() *Builder { return Count(coll) },
+		"distinct":    func() *Builder { return Distinct(coll, field) },
+		"update":      func() *Builder { return Update(coll).Set(field, types.Param{Name: "v"}) },
+		"update many": func() *Builder { return UpdateMany(coll).Set(field, types.Param{Name: "v"}) },
+		"delete":      func() *Builder { return Delete(coll) },
+		"delete many": func() *Builder { return DeleteMany(coll) },
+		"watch":       func() *Builder { return Watch(coll) },
+	}
+	for name, start := range builders {
+		t.Run(name, func(t *testing.T) {
+			var exceeded *types.LimitExceededError
+			if _, err := start().Filter(deep).Build(); !errors.As(err, &exceeded) || exceeded.Limit != "MaxFilterDepth" {
+				t.Errorf("expected MaxFilterDepth error from Build, got %v", err)
+			}
+			if err := start().WithLimits(limits).Filter(deep).Err(); !errors.As(err, &exceeded) || exceeded.Limit != "MaxFilterDepth" {
+				t.Errorf("expected MaxFilterDepth error from Filter, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFilter_TrackedDepthMatchesNormalized(t *testing.T) {
+	field := types.Field{Path: "status", Collection: "users"}
+	a := Eq(field, types.Param{Name: "a"})
+	c := Eq(field, types.Param{Name: "c"})
+	for _, f := range []types.FilterItem{
+		a,
+		And(a),
+		And(And(a, c), c),
+		Or(And(a), c),
+		And(Or(And(a, c)), c),
+		Or(Or(a, c), And(a, And(c, a))),
+		Nor(And(a, And(c))),
+		types.FilterGroup{Logic: types.NOT, Conditions: []types.FilterItem{Or(a)}},
+		types.ElemMatchFilter{Field: field, Conditions: []types.FilterItem{And(a, Or(c, a))}},
+		nestedOr(5),
+	} {
+		depth, _ := shapeOf(f, true)
+		want := 0
+		for types.ValidateFilterDepth(NormalizeFilter(f), want) != nil {
+			want++
+		}
+		if depth != want {
+			t.Errorf("shapeOf(%#v) = %d, want %d", f, depth, want)
+		}
+	}
+}
+
+// nestedOr alternates OR and AND groups depth levels deep so normalization
+// cannot flatten them.
+func nestedOr(depth int) types.FilterItem {
+	field := types.Field{Path: "status", Collection: "users"}
+	f := types.FilterItem(Eq(field, types.Param{Name: "status"}))
+	for i := range depth {
+		other := Eq(field, types.Param{Name: "s" + strconv.Itoa(i)})
+		if i%2 == 0 {
+			f = Or(f, other)
+		} else {
+			f = And(f, other)
+		}
+	}
+	return f
+}
+
+func TestProjection_SelectThenExclude(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	email := types.Field{Path: "email", Collection: "users"}
+	name := types.Field{Path: "name", Collection: "users"}
+
+	if _, err := Find(coll).Select(email).Exclude(name).Build(); err == nil {
+		t.Error("expected error combining Select and Exclude")
+	}
+	if _, err := Find(coll).Exclude(name).Select(email).Build(); err == nil {
+		t.Error("expected error combining Exclude and Select")
+	}
+}
+
+func TestProjection_DuplicateFields(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	email := types.Field{Path: "email", Collection: "users"}
+
+	if _, err := Find(coll).Select(email, email).Build(); err == nil {
+		t.Error("expected error for duplicate Select field")
+	}
+	if _, err := Find(coll).Exclude(email, email).Build(); err == nil {
+		t.Error("expected error for duplicate Exclude field")
+	}
+}
+
+func TestSelectWithoutID(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	email := types.Field{Path: "email", Collection: "users"}
+	name := types.Field{Path: "name", Collection: "users"}
+
+	ast, err := Find(coll).SelectWithoutID(email, name).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fields := ast.Projection.Fields
+	if len(fields) != 3 {
+		t.Fatalf("expected 3 projection fields, got %d", len(fields))
+	}
+	if fields[0].Field.Path != types.IDField || fields[0].Include {
+		t.Errorf("expected _id exclusion first, got %+v", fields[0])
+	}
+	if !fields[1].Include || !fields[2].Include {
+		t.Error("expected remaining fields to be included")
+	}
+
+	if _, err := Find(coll).SelectWithoutID(types.Field{Path: types.IDField}).Build(); err == nil {
+		t.Error("expected error when _id is also selected")
+	}
+}
+
+func TestSelectAs(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	id := types.Field{Path: types.IDField, Collection: "users"}
+	name := types.Field{Path: "name", Collection: "users"}
+	createdAt := types.Field{Path: "createdAt", Collection: "users"}
+
+	ast, err := Find(coll).Select(name).SelectAs(id, "id").SelectAs(createdAt, "created_at").Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fields := ast.Projection.Fields
+	if len(fields) != 3 || fields[0].Alias != "" || fields[1].Alias != "id" || fields[2].Alias != "created_at" {
+		t.Fatalf("unexpected projection: %+v", fields)
+	}
+	if !fields[1].Include || !fields[2].Include {
+		t.Error("aliased fields should be included")
+	}
+
+	var identErr *InvalidIdentifierError
+	if _, err := Find(coll).SelectAs(name, "full name").Build(); !errors.As(err, &identErr) || identErr.Kind != "alias" {
+		t.Errorf("expected invalid alias error, got %v", err)
+	}
+	if _, err := Find(coll).Exclude(name).SelectAs(id, "id").Build(); err == nil {
+		t.Error("expected error combining Exclude and SelectAs")
+	}
+	if _, err := Find(coll).SelectAs(id, "name").Select(name).Build(); err != nil {
+		t.Errorf("Select should replace the aliased projection, got %v", err)
+	}
+	if _, err := Find(coll).Select(name).SelectAs(id, "name").Build(); err == nil {
+		t.Error("expected error for alias clashing with a selected field")
+	}
+	if _, err := Insert(coll).SelectAs(id, "id").Build(); err == nil {
+		t.Error("expected error for SelectAs on a write")
+	}
+}
+
+func TestRequireFilterForSingleWrites(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	field := types.Field{Path: "status", Collection: "users"}
+	param := types.Param{Name: "status"}
+
+	if _, err := Update(coll).Set(field, param).Build(); err != nil {
+		t.Fatalf("expected unfiltered update to be allowed by default, got: %v", err)
+	}
+	if _, err := Update(coll).RequireFilterForSingleWrites().Set(field, param).Build(); err == nil {
+		t.Error("expected error for unfiltered update in safety mode")
+	}
+	if _, err := Delete(coll).RequireFilterForSingleWrites().Build(); err == nil {
+		t.Error("expected error for unfiltered delete in safety mode")
+	}
+	if _, err := Delete(coll).RequireFilterForSingleWrites().Filter(Eq(field, param)).Build(); err != nil {
+		t.Errorf("expected filtered delete to pass in safety mode, got: %v", err)
+	}
+}
+
+func TestBuilder_StructuredErrors(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+
+	_, err := Find(coll).Limit(types.DefaultLimits().MaxLimit + 1).Build()
+	var exceeded *types.LimitExceededError
+	if !errors.As(err, &exceeded) || exceeded.Limit != "MaxLimit" {
+		t.Errorf("expected *LimitExceededError for MaxLimit, got %v", err)
+	}
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Error("expected errors.Is ErrLimitExceeded")
+	}
+
+	_, err = Aggregate(coll).Group(FieldExpr(types.Field{Path: "status"}), map[string]types.Accumulator{
+		"$bad": Sum(FieldExpr(types.Field{Path: "total"})),
+	}).Build()
+	var invalid *types.InvalidIdentifierError
+	if !errors.As(err, &invalid) || invalid.Kind != "accumulator name" || invalid.Value != "$bad" {
+		t.Errorf("expected *InvalidIdentifierError for accumulator, got %v", err)
+	}
+
+	_, err = UpdateMany(coll).Set(types.Field{Path: "status"}, types.Param{Name: "s"}).Build()
+	if !errors.Is(err, ErrMissingFilter) {
+		t.Errorf("expected ErrMissingFilter, got %v", err)
+	}
+}
+
+func TestBuilder_Clone(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	original := Update(coll).
+		Filter(Eq(types.Field{Path: "_id"}, types.Param{Name: "id"})).
+		Set(types.Field{Path: "status"}, types.Param{Name: "status"})
+
+	clone := original.Clone()
+	clone.Set(types.Field{Path: "name"}, types.Param{Name: "name"})
+
+	ast, err := original.Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ast.UpdateOps[0].Fields) != 1 {
+		t.Errorf("clone mutation reached original: %v", ast.UpdateOps[0].Fields)
+	}
+
+	failed := Insert(coll).Sort(types.Field{Path: "a"}, Ascending)
+	if _, err := failed.Clone().Build(); err == nil {
+		t.Error("expected clone to carry the builder error")
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	base := Find(coll).
+		Filter(Eq(types.Field{Path: "status"}, types.Param{Name: "status"})).
+		Select(types.Field{Path: "name"}).
+		SortDesc(types.Field{Path: "createdAt"})
+
+	find, count, err := Paginate(base, 3, 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	findAST := find.MustBuild()
+	if *findAST.Skip.Static != 40 || *findAST.Limit.Static != 20 {
+		t.Errorf("expected skip 40 limit 20, got skip %v limit %v", *findAST.Skip.Static, *findAST.Limit.Static)
+	}
+	if findAST.Projection == nil || len(findAST.SortClauses) != 1 {
+		t.Error("find variant should keep projection and sort")
+	}
+
+	countAST := count.MustBuild()
+	if countAST.Operation != types.OpCount {
+		t.Errorf("expected count operation, got %s", countAST.Operation)
+	}
+	if countAST.Skip != nil || countAST.Limit != nil || countAST.SortClauses != nil || countAST.Projection != nil {
+		t.Errorf("count variant should drop pagination, sort, and projection: %+v", countAST)
+	}
+	if _, ok := countAST.FilterClause.(types.FilterCondition); !ok {
+		t.Errorf("count variant should keep the filter, got %T", countAST.FilterClause)
+	}
+
+	baseAST := base.MustBuild()
+	if baseAST.Skip != nil || baseAST.Limit != nil || baseAST.Operation != types.OpFind {
+		t.Error("Paginate should not modify the base builder")
+	}
+
+	find.Filter(Eq(types.Field{Path: "tenant"}, types.Param{Name: "tenant"}))
+	if _, ok := count.MustBuild().FilterClause.(types.FilterCondition); !ok {
+		t.Error("find and count variants should be independent")
+	}
+}
+
+func TestPaginate_Errors(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+
+	tests := []struct {
+		name     string
+		base     *Builder
+		page     int
+		pageSize int
+		want     string
+	}{
+		{"page zero", Find(coll), 0, 10, "page must be at least 1"},
+		{"page size zero", Find(coll), 1, 0, "page size must be at least 1"},
+		{"page size over max", Find(coll), 1, MaxLimit + 1, "limit exceeds maximum"},
+		{"page size over custom max", Find(coll).WithLimits(Limits{MaxLimit: 50}), 1, 51, "limit exceeds maximum"},
+		{"not a find", Count(coll), 1, 10, "requires a find query"},
+		{"find one", FindOne(coll), 1, 10, "requires a find query"},
+		{"builder error", Insert(coll).Sort(types.Field{Path: "a"}, Ascending), 1, 10, "Sort() can only be used"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Paginate(tt.base, tt.page, tt.pageSize)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	var limitErr *LimitExceededError
+	if _, _, err := Paginate(Find(coll), 1, MaxLimit+1); !errors.As(err, &limitErr) || limitErr.Limit != "MaxLimit" {
+		t.Errorf("expected *LimitExceededError, got %v", err)
+	}
+}
+
+func TestPaginateParams(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	base := Find(coll).SortAsc(types.Field{Path: "name"})
+
+	find, count, err := PaginateParams(base, types.Param{Name: "offset"}, types.Param{Name: "size"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	findAST := find.MustBuild()
+	if findAST.Skip.Param.Name != "offset" || findAST.Limit.Param.Name != "size" {
+		t.Errorf("unexpected pagination: skip %+v limit %+v", findAST.Skip, findAST.Limit)
+	}
+	countAST := count.MustBuild()
+	if countAST.Operation != types.OpCount || countAST.Skip != nil || countAST.Limit != nil || countAST.SortClauses != nil {
+		t.Errorf("unexpected count variant: %+v", countAST)
+	}
+
+	if _, _, err := PaginateParams(Aggregate(coll), types.Param{Name: "offset"}, types.Param{Name: "size"}); err == nil {
+		t.Error("expected error for non-find base")
+	}
+}
+
+func TestAggregate_UnwindOpts(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+	items := types.Field{Path: "items"}
+
+	ast, err := Aggregate(coll).UnwindOpts(items, "idx", true).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stage := ast.Pipeline[0].(types.UnwindStage)
+	if stage.IncludeArrayIndex == nil || *stage.IncludeArrayIndex != "idx" || !stage.PreserveNullAndEmptyArrays {
+		t.Errorf("unexpected stage: %+v", stage)
+	}
+
+	ast, err = Aggregate(coll).UnwindOpts(items, "", false).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stage := ast.Pipeline[0].(types.UnwindStage); stage.IncludeArrayIndex != nil {
+		t.Errorf("expected no includeArrayIndex, got %q", *stage.IncludeArrayIndex)
+	}
+
+	if _, err := Aggregate(coll).UnwindOpts(items, "$idx", false).Build(); err == nil {
+		t.Error("expected error for invalid includeArrayIndex")
+	}
+	if _, err := Find(coll).UnwindOpts(items, "idx", false).Build(); err == nil {
+		t.Error("expected error for UnwindOpts on FIND")
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	orders := types.Collection{Name: "orders"}
+	id := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+
+	update := Update(users).Filter(id).Set(types.Field{Path: "status"}, types.Param{Name: "status"})
+	ast, err := Transaction().
+		Add(Insert(orders).Document(types.Document{Fields: map[types.Field]types.Param{{Path: "userId"}: {Name: "id"}}})).
+		Add(update).
+		Add(Delete(users).Filter(id)).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ast.Operation != types.OpTransaction || len(ast.Operations) != 3 {
+		t.Fatalf("unexpected transaction: %+v", ast)
+	}
+	for i, want := range []types.Operation{types.OpInsert, types.OpUpdate, types.OpDelete} {
+		if ast.Operations[i].Operation != want {
+			t.Errorf("operation %d: expected %s, got %s", i, want, ast.Operations[i].Operation)
+		}
+	}
+
+	// Changes made after Add do not reach the transaction.
+	update.Set(types.Field{Path: "name"}, types.Param{Name: "name"})
+	if n := len(ast.Operations[1].UpdateOps[0].Fields); n != 1 {
+		t.Errorf("expected added update to be unchanged, got %d fields", n)
+	}
+}
+
+func TestTransaction_Errors(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	id := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+
+	if _, err := Transaction().Build(); err == nil {
+		t.Error("expected error for empty transaction")
+	}
+	if _, err := Transaction().Add(Find(users)).Build(); err == nil {
+		t.Error("expected error for read operation")
+	}
+	if _, err := Transaction().Add(Transaction().Add(Delete(users))).Build(); err == nil {
+		t.Error("expected error for nested transaction")
+	}
+	if _, err := Transaction().Add(UpdateMany(users)).Build(); err == nil {
+		t.Error("expected error for invalid operation")
+	}
+	if _, err := Find(users).Add(Delete(users)).Build(); err == nil {
+		t.Error("expected error for Add on FIND")
+	}
+
+	limits := types.DefaultLimits()
+	limits.MaxBatchSize = 1
+	_, err := Transaction().WithLimits(limits).Add(Delete(users).Filter(id)).Add(Delete(users).Filter(id)).Build()
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected limit exceeded error, got %v", err)
+	}
+}
+
+func TestBuilder_Hint(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+
+	ast, err := Find(coll).Hint("status_1").Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.Hint == nil || *ast.Hint != "status_1" {
+		t.Errorf("expected hint status_1, got %v", ast.Hint)
+	}
+
+	if _, err := Insert(coll).Hint("status_1").Build(); err == nil {
+		t.Error("expected error for Hint on INSERT")
+	}
+	if _, err := Find(coll).Hint("").Build(); err == nil {
+		t.Error("expected error for empty hint")
+	}
+}
+
+func TestBuilder_Collation(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+
+	ast, err := Find(coll).Collation("en", 2).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.Collation == nil || ast.Collation.Locale != "en" || ast.Collation.Strength != 2 {
+		t.Errorf("unexpected collation: %+v", ast.Collation)
+	}
+
+	if _, err := Insert(coll).Collation("en", 2).Build(); err == nil {
+		t.Error("expected error for Collation on INSERT")
+	}
+	if _, err := Find(coll).Collation("", 2).Build(); err == nil {
+		t.Error("expected error for empty locale")
+	}
+	if _, err := Find(coll).Collation("en", 6).Build(); err == nil {
+		t.Error("expected error for strength out of range")
+	}
+}
+
+func TestBuilder_Concerns(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	filter := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+
+	ast, err := Find(coll).ReadConcern("majority").Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.ReadConcern == nil || *ast.ReadConcern != "majority" {
+		t.Errorf("unexpected read concern: %v", ast.ReadConcern)
+	}
+
+	ast, err = Delete(coll).Filter(filter).WriteConcern("majority").Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ast.WriteConcern == nil || *ast.WriteConcern != "majority" {
+		t.Errorf("unexpected write concern: %v", ast.WriteConcern)
+	}
+
+	if _, err := Find(coll).WriteConcern("1").Build(); err == nil {
+		t.Error("expected error for WriteConcern on FIND")
+	}
+	if _, err := Delete(coll).Filter(filter).ReadConcern("local").Build(); err == nil {
+		t.Error("expected error for ReadConcern on DELETE")
+	}
+	if _, err := Find(coll).ReadConcern("eventual").Build(); err == nil {
+		t.Error("expected error for unknown read concern level")
+	}
+	if _, err := Transaction().Add(Delete(coll).Filter(filter).WriteConcern("1")).Build(); err == nil {
+		t.Error("expected error for write concern on a transaction operation")
+	}
+	if _, err := Transaction().Add(Delete(coll).Filter(filter)).ReadConcern("snapshot").WriteConcern("majority").Build(); err != nil {
+		t.Errorf("unexpected error for transaction concerns: %v", err)
+	}
+}
+
+func TestTypeIs(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	field := types.Field{Path: "age"}
+
+	for _, name := range types.TypeNames {
+		if _, err := Find(coll).Filter(TypeIs(field, name)).Build(); err != nil {
+			t.Errorf("TypeIs(%q): unexpected error: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"", "String", "integer", "number", "regex"} {
+		if _, err := Find(coll).Filter(TypeIs(field, name)).Build(); err == nil {
+			t.Errorf("TypeIs(%q): expected error for unknown type name", name)
+		}
+	}
+
+	nested := Or(Eq(field, types.Param{Name: "age"}), ElemMatch(types.Field{Path: "tags"}, TypeIs(field, "bogus")))
+	if _, err := DeleteMany(coll).Filter(nested).Build(); err == nil {
+		t.Error("expected error for unknown type name in a nested filter")
+	}
+	if _, err := Aggregate(coll).Match(TypeIs(field, "bogus")).Build(); err == nil {
+		t.Error("expected error for unknown type name in $match")
+	}
+}
@@ -0,0 +1,98 @@
+package docql
+
+import "github.com/zoobzio/docql/internal/types"
+
+// Complexity summarizes how expensive a query is likely to be for the
+// server, so callers can reject costly queries before they run. Counts cover
+// the whole AST, including pipeline sub-pipelines and transaction writes.
+type Complexity struct {
+	// FilterDepth is the deepest nesting of groups and $elemMatch in any
+	// filter, as MaxFilterDepth measures it: a lone condition is 0.
+	FilterDepth int
+
+	// Conditions counts the filters that are not groups, including those
+	// inside $elemMatch and pipeline stages.
+	Conditions int
+
+	// PipelineStages counts pipeline stages, including those in $facet and
+	// $lookup sub-pipelines.
+	PipelineStages int
+
+	// ProjectionFields is the width of the widest projection, counting the
+	// computed fields of a $project stage.
+	ProjectionFields int
+
+	// Score weighs the above into one number; see EstimateComplexity.
+	Score int
+}
+
+// Score weights. Stages that join, group, sort, or fan out the documents
+// flowing through them cost more than those that filter or reshape one
+// document at a time.
+const (
+	conditionWeight      = 1
+	depthWeight          = 5
+	stageWeight          = 3
+	expensiveStageWeight = 10
+	projectionWeight     = 1 // per 10 fields
+)
+
+// EstimateComplexity measures ast. The score counts 1 per condition, 5 per
+// level of filter nesting, 3 per pipeline stage plus 10 more for each
+// $lookup, $graphLookup, $group, $bucket, $facet, $sort, and $unwind, and 1
+// per 10 projected fields. A Find with a single condition scores 1.
+func EstimateComplexity(ast *types.DocumentAST) Complexity {
+	var c Complexity
+	expensive := 0
+	Walk(ast, func(node interface{}) bool {
+		switch n := node.(type) {
+		case *types.DocumentAST:
+			c.FilterDepth = max(c.FilterDepth, filterDepth(n.FilterClause))
+		case types.FilterGroup:
+		case types.FilterItem:
+			c.Conditions++
+		case types.Projection:
+			c.ProjectionFields = max(c.ProjectionFields, len(n.Fields))
+		case types.PipelineStage:
+			c.PipelineStages++
+			switch stage := n.(type) {
+			case types.MatchStage:
+				c.FilterDepth = max(c.FilterDepth, filterDepth(stage.Filter))
+			case types.GraphLookupStage:
+				c.FilterDepth = max(c.FilterDepth, filterDepth(stage.RestrictSearchWithMatch))
+				expensive++
+			case types.ProjectStage:
+				c.ProjectionFields = max(c.ProjectionFields, len(stage.Projection.Fields)+len(stage.Computed))
+			case types.LookupStage, types.GroupStage, types.BucketStage, types.FacetStage, types.SortStage, types.UnwindStage:
+				expensive++
+			}
+		}
+		return true
+	})
+	c.Score = c.Conditions*conditionWeight +
+		c.FilterDepth*depthWeight +
+		c.PipelineStages*stageWeight +
+		expensive*expensiveStageWeight +
+		c.ProjectionFields/10*projectionWeight
+	return c
+}
+
+// filterDepth returns the nesting depth of f: 0 for a condition or an empty
+// group, one more than its deepest child for a group or $elemMatch.
+func filterDepth(f types.FilterItem) int {
+	var children []types.FilterItem
+	switch filter := f.(type) {
+	case types.FilterGroup:
+		children = filter.Conditions
+	case types.ElemMatchFilter:
+		children = filter.Conditions
+	}
+	if len(children) == 0 {
+		return 0
+	}
+	depth := 0
+	for _, c := range children {
+		depth = max(depth, filterDepth(c))
+	}
+	return depth + 1
+}
@@ -0,0 +1,83 @@
+package docql_test
+
+import (
+	"testing"
+
+	"github.com/zoobzio/docql"
+)
+
+func mustBuild(t *testing.T, b *docql.Builder) *docql.DocumentAST {
+	t.Helper()
+	ast, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	return ast
+}
+
+func TestEstimateComplexity_TrivialFind(t *testing.T) {
+	instance := createTestInstance(t)
+	ast := mustBuild(t, docql.Find(instance.C("users")).Filter(instance.Eq(instance.F("users", "status"), instance.P("status"))))
+
+	got := docql.EstimateComplexity(ast)
+	want := docql.Complexity{Conditions: 1, Score: 1}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestEstimateComplexity_Nesting(t *testing.T) {
+	instance := createTestInstance(t)
+	status := instance.F("users", "status")
+	active := instance.F("users", "active")
+
+	flat := docql.And(instance.Eq(status, instance.P("a")), instance.Eq(active, instance.P("b")))
+	nested := docql.And(instance.Eq(status, instance.P("a")), docql.Or(instance.Eq(active, instance.P("b")), instance.Eq(active, instance.P("c"))))
+	deeper := docql.And(instance.Eq(status, instance.P("a")), docql.Or(instance.Eq(active, instance.P("b")), docql.And(instance.Eq(active, instance.P("c")), instance.Eq(status, instance.P("d")))))
+
+	previous := -1
+	for i, f := range []docql.FilterItem{flat, nested, deeper} {
+		c := docql.EstimateComplexity(mustBuild(t, docql.Find(instance.C("users")).Filter(f).PreserveFilter()))
+		if c.FilterDepth != i+1 {
+			t.Errorf("filter %d: expected depth %d, got %d", i, i+1, c.FilterDepth)
+		}
+		if c.Score <= previous {
+			t.Errorf("filter %d: expected score above %d, got %d", i, previous, c.Score)
+		}
+		previous = c.Score
+	}
+}
+
+func TestEstimateComplexity_PipelineLength(t *testing.T) {
+	instance := createTestInstance(t)
+	match := instance.Eq(instance.F("users", "status"), instance.P("status"))
+
+	short := docql.EstimateComplexity(mustBuild(t, docql.Aggregate(instance.C("users")).Match(match)))
+	long := docql.EstimateComplexity(mustBuild(t, docql.Aggregate(instance.C("users")).
+		Match(match).
+		Lookup("posts", instance.F("users", "_id"), instance.F("posts", "userId"), "posts").
+		Group(docql.FieldExpr(instance.F("users", "status")), map[string]docql.Accumulator{"n": docql.CountAcc()}).
+		Limit(10)))
+
+	if short.PipelineStages != 1 || long.PipelineStages != 4 {
+		t.Errorf("expected 1 and 4 stages, got %d and %d", short.PipelineStages, long.PipelineStages)
+	}
+	if long.Score <= short.Score {
+		t.Errorf("expected the longer pipeline to score higher: %d <= %d", long.Score, short.Score)
+	}
+	// $lookup and $group weigh more than $limit.
+	if want := short.Score + 3*3 + 2*10; long.Score != want {
+		t.Errorf("expected score %d, got %d", want, long.Score)
+	}
+}
+
+func TestEstimateComplexity_Projection(t *testing.T) {
+	instance := createTestInstance(t)
+	ast := mustBuild(t, docql.Find(instance.C("users")).
+		Filter(instance.Eq(instance.F("users", "status"), instance.P("status"))).
+		Select(instance.F("users", "username"), instance.F("users", "email")))
+
+	if c := docql.EstimateComplexity(ast); c.ProjectionFields != 2 {
+		t.Errorf("expected projection width 2, got %d", c.ProjectionFields)
+	}
+}
@@ -0,0 +1,110 @@
+package docql
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/zoobzio/ddml"
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// TypeOperators lists the filter operators that are meaningful for each DDML
+// field type. Paths inside array elements also accept the operators of their
+// own type through $elemMatch. Join it with a renderer's SupportsFilter to
+// find the operators a provider can apply to a field.
+var TypeOperators = map[ddml.FieldType][]types.FilterOperator{
+	ddml.TypeString:   {types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE, types.IN, types.NotIn, types.Regex, types.Exists, types.Type},
+	ddml.TypeInt:      {types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE, types.IN, types.NotIn, types.Mod, types.Exists, types.Type},
+	ddml.TypeFloat:    {types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE, types.IN, types.NotIn, types.Mod, types.Exists, types.Type},
+	ddml.TypeDate:     {types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE, types.IN, types.NotIn, types.Exists, types.Type},
+	ddml.TypeObjectID: {types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE, types.IN, types.NotIn, types.Exists, types.Type},
+	ddml.TypeBool:     {types.EQ, types.NE, types.Exists, types.Type},
+	ddml.TypeEnum:     {types.EQ, types.NE, types.IN, types.NotIn, types.Exists, types.Type},
+	ddml.TypeBinary:   {types.EQ, types.NE, types.Exists, types.Type},
+	ddml.TypeObject:   {types.EQ, types.NE, types.Exists, types.Type},
+	ddml.TypeArray:    {types.EQ, types.NE, types.IN, types.NotIn, types.All, types.ElemMatch, types.Size, types.Exists, types.Type},
+	ddml.TypeGeoPoint: {types.GeoWithin, types.GeoIntersects, types.Near, types.NearSphere, types.Exists, types.Type},
+}
+
+// CollectionInfo describes a collection and every field path it indexes.
+type CollectionInfo struct {
+	Name   string      `json:"name"`
+	Fields []FieldInfo `json:"fields"`
+}
+
+// FieldInfo describes a field path as F accepts it. Paths inside array
+// elements omit element positions, as in "orders.items.sku".
+type FieldInfo struct {
+	Path     string         `json:"path"`
+	Type     ddml.FieldType `json:"type"`
+	Required bool           `json:"required"`
+
+	// Object and Array report a nested document or array field; ElementType
+	// is the type of an array's elements. InArray marks paths inside array
+	// elements, which filters reach with $elemMatch or dotted paths.
+	Object      bool           `json:"object,omitempty"`
+	Array       bool           `json:"array,omitempty"`
+	ElementType ddml.FieldType `json:"elementType,omitempty"`
+	InArray     bool           `json:"inArray,omitempty"`
+
+	// EnumValues lists the allowed values of an enum field.
+	EnumValues []string `json:"enumValues,omitempty"`
+
+	// Operators lists the filter operators suited to the field's type.
+	Operators []types.FilterOperator `json:"operators"`
+}
+
+// Describe returns the fields of a collection, sorted by path, with their
+// types, flags, enum values, and suitable filter operators.
+func (d *DOCQL) Describe(collectionName string) (*CollectionInfo, error) {
+	collFields, ok := d.fields[collectionName]
+	if !ok {
+		return nil, &types.UnknownCollectionError{Collection: collectionName}
+	}
+
+	info := &CollectionInfo{Name: collectionName, Fields: make([]FieldInfo, 0, len(collFields))}
+	for _, path := range slices.Sorted(maps.Keys(collFields)) {
+		info.Fields = append(info.Fields, d.describeField(collectionName, path, collFields[path]))
+	}
+	return info, nil
+}
+
+// DescribeAll describes every collection in the schema, sorted by name.
+func (d *DOCQL) DescribeAll() []*CollectionInfo {
+	names := d.Collections()
+	out := make([]*CollectionInfo, len(names))
+	for i, name := range names {
+		out[i], _ = d.Describe(name)
+	}
+	return out
+}
+
+func (d *DOCQL) describeField(collectionName, path string, f *ddml.Field) FieldInfo {
+	info := FieldInfo{
+		Path:      path,
+		Type:      f.Type,
+		Required:  f.Required,
+		Object:    f.Type == ddml.TypeObject,
+		Array:     f.Type == ddml.TypeArray,
+		InArray:   d.inArray(collectionName, path),
+		Operators: slices.Clone(TypeOperators[f.Type]),
+	}
+	if info.Array && f.ArrayOf != nil {
+		info.ElementType = f.ArrayOf.Type
+	}
+	if values, err := d.EnumValues(collectionName, path); err == nil {
+		info.EnumValues = values
+	}
+	return info
+}
+
+// inArray reports whether any parent of path is an array field.
+func (d *DOCQL) inArray(collectionName, path string) bool {
+	for i := strings.LastIndexByte(path, '.'); i > 0; i = strings.LastIndexByte(path[:i], '.') {
+		if parent, ok := d.fields[collectionName][path[:i]]; ok && parent.Type == ddml.TypeArray {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package docql_test
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/zoobzio/ddml"
+	"github.com/zoobzio/docql"
+	"github.com/zoobzio/docql/pkg/firestore"
+)
+
+func describeInstance(t *testing.T) *docql.DOCQL {
+	t.Helper()
+
+	schema := ddml.NewSchema("shop")
+	schema.AddEnum(ddml.NewEnum("Status", "active", "banned"))
+
+	users := ddml.NewCollection("users")
+	users.AddField(ddml.NewField("_id", ddml.TypeObjectID).WithPrimaryKey())
+	users.AddField(ddml.NewField("email", ddml.TypeString).WithRequired())
+	users.AddField(ddml.NewField("status", ddml.TypeEnum).WithEnumRef("Status"))
+	users.AddField(ddml.NewObjectField("address").
+		AddField(ddml.NewField("city", ddml.TypeString)))
+	users.AddField(ddml.NewArrayField("orders", ddml.NewObjectField("").
+		AddField(ddml.NewField("total", ddml.TypeFloat).WithRequired())))
+	users.AddField(ddml.NewArrayField("tags", ddml.NewField("", ddml.TypeString)))
+	schema.AddCollection(users)
+
+	schema.AddCollection(ddml.NewCollection("audit").AddField(ddml.NewField("at", ddml.TypeDate)))
+
+	instance, err := docql.NewFromDDML(schema)
+	if err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	return instance
+}
+
+func TestDescribe(t *testing.T) {
+	instance := describeInstance(t)
+
+	info, err := instance.Describe("users")
+	if err != nil {
+		t.Fatalf("Describe failed: %v", err)
+	}
+
+	var paths []string
+	fields := make(map[string]docql.FieldInfo)
+	for _, f := range info.Fields {
+		paths = append(paths, f.Path)
+		fields[f.Path] = f
+	}
+	want := []string{"_id", "address", "address.city", "email", "orders", "orders.total", "status", "tags"}
+	if !slices.Equal(paths, want) {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+
+	if f := fields["address"]; !f.Object || f.Array || f.InArray {
+		t.Errorf("unexpected address info: %+v", f)
+	}
+	if f := fields["address.city"]; f.Type != ddml.TypeString || f.InArray {
+		t.Errorf("unexpected address.city info: %+v", f)
+	}
+	if f := fields["orders"]; !f.Array || f.ElementType != ddml.TypeObject || !slices.Contains(f.Operators, docql.OpElemMatch) {
+		t.Errorf("unexpected orders info: %+v", f)
+	}
+	if f := fields["orders.total"]; !f.InArray || !f.Required || f.Type != ddml.TypeFloat || !slices.Contains(f.Operators, docql.OpGT) {
+		t.Errorf("unexpected orders.total info: %+v", f)
+	}
+	if f := fields["tags"]; f.ElementType != ddml.TypeString {
+		t.Errorf("unexpected tags info: %+v", f)
+	}
+	if f := fields["status"]; !slices.Equal(f.EnumValues, []string{"active", "banned"}) || slices.Contains(f.Operators, docql.OpRegex) {
+		t.Errorf("unexpected status info: %+v", f)
+	}
+	if f := fields["email"]; !f.Required || !slices.Contains(f.Operators, docql.OpRegex) {
+		t.Errorf("unexpected email info: %+v", f)
+	}
+
+	// The table joins with renderer capabilities: Firestore cannot use $regex.
+	var supported []docql.FilterOperator
+	for _, op := range fields["email"].Operators {
+		if firestore.New().SupportsFilter(op) {
+			supported = append(supported, op)
+		}
+	}
+	if slices.Contains(supported, docql.OpRegex) || !slices.Contains(supported, docql.OpEQ) {
+		t.Errorf("unexpected Firestore operators for email: %v", supported)
+	}
+
+	if _, err := instance.Describe("missing"); !errors.Is(err, docql.ErrUnknownCollection) {
+		t.Errorf("expected unknown collection error, got %v", err)
+	}
+}
+
+func TestDescribeAll(t *testing.T) {
+	instance := describeInstance(t)
+
+	all := instance.DescribeAll()
+	if len(all) != 2 || all[0].Name != "audit" || all[1].Name != "users" {
+		t.Fatalf("unexpected collections: %+v", all)
+	}
+
+	data, err := json.Marshal(all)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	var decoded []struct {
+		Name   string `json:"name"`
+		Fields []struct {
+			Path      string   `json:"path"`
+			Type      string   `json:"type"`
+			Operators []string `json:"operators"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if at := decoded[0].Fields[0]; at.Path != "at" || at.Type != "date" || !slices.Contains(at.Operators, "$gte") {
+		t.Errorf("unexpected audit field: %+v", at)
+	}
+}
@@ -42,6 +42,16 @@ func NotIn(field types.Field, value types.Param) types.FilterCondition {
 	return types.FilterCondition{Field: field, Operator: types.NotIn, Value: value}
 }
 
+// InValues creates an IN filter over individually bound params.
+func InValues(field types.Field, params ...types.Param) types.ValuesFilter {
+	return types.ValuesFilter{Field: field, Operator: types.IN, Values: params}
+}
+
+// NinValues creates a NOT IN filter over individually bound params.
+func NinValues(field types.Field, params ...types.Param) types.ValuesFilter {
+	return types.ValuesFilter{Field: field, Operator: types.NotIn, Values: params}
+}
+
 // Exists creates a field existence filter.
 func Exists(field types.Field) types.ExistsFilter {
 	return types.ExistsFilter{Field: field, Exists: true}
@@ -52,6 +62,29 @@ func NotExists(field types.Field) types.ExistsFilter {
 	return types.ExistsFilter{Field: field, Exists: false}
 }
 
+// IsNull creates a filter matching null values. Whether a missing field also
+// matches follows the store; MongoDB, for one, matches it.
+func IsNull(field types.Field) types.NullFilter {
+	return types.NullFilter{Field: field}
+}
+
+// IsNotNull creates a filter matching values that are not null. Whether a
+// missing field also matches follows the store; DynamoDB, for one, matches it.
+func IsNotNull(field types.Field) types.NullFilter {
+	return types.NullFilter{Field: field, Negated: true}
+}
+
+// IsNullStrict creates a filter matching fields that are present and null.
+func IsNullStrict(field types.Field) types.NullFilter {
+	return types.NullFilter{Field: field, Strict: true}
+}
+
+// IsNotNullStrict creates a filter matching fields that are present and not
+// null.
+func IsNotNullStrict(field types.Field) types.NullFilter {
+	return types.NullFilter{Field: field, Negated: true, Strict: true}
+}
+
 // Regex creates a regex filter.
 func Regex(field types.Field, pattern types.Param) types.RegexFilter {
 	return types.RegexFilter{Field: field, Pattern: pattern}
@@ -93,6 +126,11 @@ func RangeExclusive(field types.Field, minVal, maxVal *types.Param) types.RangeF
 	}
 }
 
+// NotRange creates a filter matching values outside an inclusive range.
+func NotRange(field types.Field, minVal, maxVal *types.Param) types.RangeFilter {
+	return types.RangeFilter{Field: field, Min: minVal, Max: maxVal, Negated: true}
+}
+
 // Geo creates a geospatial filter.
 func Geo(field types.Field, lon, lat, radius types.Param) types.GeoFilter {
 	return types.GeoFilter{
@@ -113,6 +151,32 @@ func Size(field types.Field, value types.Param) types.ArrayFilter {
 	return types.ArrayFilter{Field: field, Operator: types.Size, Value: value}
 }
 
+// SizeLiteral creates a $size array filter matching arrays of exactly n
+// elements, with n rendered in the query rather than bound from a param.
+func SizeLiteral(field types.Field, n int) types.ArrayFilter {
+	return types.ArrayFilter{Field: field, Operator: types.Size, Literal: &n}
+}
+
+// ParamCollection returns c with its physical name bound from p at execution
+// time, for collections such as per-tenant ones whose name varies per call.
+// The query is still built and checked against c's schema, and renderers
+// that support it emit p's placeholder in place of the collection name.
+func ParamCollection(c types.Collection, p types.Param) types.Collection {
+	c.Param = &p
+	return c
+}
+
+// Mod creates a $mod filter matching field % divisor == remainder.
+func Mod(field types.Field, divisor, remainder types.Param) types.ModFilter {
+	return types.ModFilter{Field: field, Divisor: divisor, Remainder: remainder}
+}
+
+// TypeIs creates a $type filter matching values of the named BSON type, one
+// of types.TypeNames. Unknown names fail at Build.
+func TypeIs(field types.Field, bsonType string) types.TypeFilter {
+	return types.TypeFilter{Field: field, TypeName: bsonType}
+}
+
 // ElemMatch creates an $elemMatch filter.
 func ElemMatch(field types.Field, conditions ...types.FilterItem) types.ElemMatchFilter {
 	return types.ElemMatchFilter{Field: field, Conditions: conditions}
@@ -123,6 +187,17 @@ func TextSearch(search types.Param) types.TextSearchFilter {
 	return types.TextSearchFilter{Search: search}
 }
 
+// TextSearchOpts creates a text search filter with options. A nil language
+// leaves the choice to the text index's default language.
+func TextSearchOpts(search types.Param, language *types.Param, caseSensitive, diacriticSensitive bool) types.TextSearchFilter {
+	return types.TextSearchFilter{
+		Search:             search,
+		Language:           language,
+		CaseSensitive:      caseSensitive,
+		DiacriticSensitive: diacriticSensitive,
+	}
+}
+
 // Doc creates a new document for insert operations.
 func Doc() *DocumentBuilder {
 	return &DocumentBuilder{
@@ -143,6 +218,13 @@ func (db *DocumentBuilder) Set(field types.Field, value types.Param) *DocumentBu
 	return db
 }
 
+// SetIndexed adds a field bound to IndexedParam(base, i), naming the param
+// explicitly for document i of a batch insert.
+func (db *DocumentBuilder) SetIndexed(field types.Field, base types.Param, i int) *DocumentBuilder {
+	db.doc.Fields[field] = IndexedParam(base, i)
+	return db
+}
+
 // Build returns the document.
 func (db *DocumentBuilder) Build() types.Document {
 	return db.doc
@@ -188,6 +270,17 @@ func Last(expr types.Expression) types.Accumulator {
 	return types.Accumulator{Operator: types.AccLast, Expr: expr}
 }
 
+// PushAcc creates a $push accumulator collecting every value in the group.
+func PushAcc(expr types.Expression) types.Accumulator {
+	return types.Accumulator{Operator: types.AccPush, Expr: expr}
+}
+
+// AddToSetAcc creates an $addToSet accumulator collecting the group's
+// distinct values.
+func AddToSetAcc(expr types.Expression) types.Accumulator {
+	return types.Accumulator{Operator: types.AccAddToSet, Expr: expr}
+}
+
 // CountAcc creates a $count accumulator.
 func CountAcc() types.Accumulator {
 	return types.Accumulator{Operator: types.AccCount}
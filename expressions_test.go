@@ -93,6 +93,23 @@ func TestNotIn(t *testing.T) {
 	}
 }
 
+func TestInValues(t *testing.T) {
+	field := types.Field{Path: "status"}
+
+	f := InValues(field, types.Param{Name: "a"}, types.Param{Name: "b"})
+	if f.Operator != types.IN {
+		t.Errorf("Expected IN operator, got %v", f.Operator)
+	}
+	if len(f.Values) != 2 || f.Values[0].Name != "a" || f.Values[1].Name != "b" {
+		t.Errorf("Expected values [a b] in order, got %v", f.Values)
+	}
+
+	f = NinValues(field, types.Param{Name: "a"})
+	if f.Operator != types.NotIn {
+		t.Errorf("Expected NotIn operator, got %v", f.Operator)
+	}
+}
+
 func TestExists(t *testing.T) {
 	field := types.Field{Path: "email"}
 
@@ -243,6 +260,19 @@ func TestSize(t *testing.T) {
 	}
 }
 
+func TestSizeLiteral(t *testing.T) {
+	filter := SizeLiteral(types.Field{Path: "tags"}, 2)
+	if filter.Operator != types.Size {
+		t.Errorf("Expected Size operator, got %v", filter.Operator)
+	}
+	if filter.Literal == nil || *filter.Literal != 2 {
+		t.Errorf("Expected literal size 2, got %v", filter.Literal)
+	}
+	if filter.Value.Name != "" {
+		t.Errorf("Expected no param, got %q", filter.Value.Name)
+	}
+}
+
 func TestElemMatch(t *testing.T) {
 	field := types.Field{Path: "items"}
 	cond := Eq(types.Field{Path: "price"}, types.Param{Name: "minPrice"})
@@ -265,6 +295,23 @@ func TestTextSearch(t *testing.T) {
 	}
 }
 
+func TestTextSearchOpts(t *testing.T) {
+	search := types.Param{Name: "q"}
+	lang := types.Param{Name: "lang"}
+
+	filter := TextSearchOpts(search, &lang, true, false)
+	if filter.Search.Name != "q" || filter.Language == nil || filter.Language.Name != "lang" {
+		t.Errorf("Expected search 'q' in language 'lang', got %+v", filter)
+	}
+	if !filter.CaseSensitive || filter.DiacriticSensitive {
+		t.Errorf("Expected only case sensitivity, got %+v", filter)
+	}
+
+	if filter := TextSearchOpts(search, nil, false, true); filter.Language != nil || !filter.DiacriticSensitive {
+		t.Errorf("Expected no language and diacritic sensitivity, got %+v", filter)
+	}
+}
+
 func TestDoc(t *testing.T) {
 	builder := Doc()
 	if builder == nil {
@@ -381,3 +428,21 @@ func TestCountAcc(t *testing.T) {
 		t.Errorf("Expected AccCount, got %v", acc.Operator)
 	}
 }
+
+func TestPushAcc(t *testing.T) {
+	expr := FieldExpr(types.Field{Path: "sku"})
+	acc := PushAcc(expr)
+
+	if acc.Operator != types.AccPush {
+		t.Errorf("Expected AccPush, got %v", acc.Operator)
+	}
+}
+
+func TestAddToSetAcc(t *testing.T) {
+	expr := FieldExpr(types.Field{Path: "tag"})
+	acc := AddToSetAcc(expr)
+
+	if acc.Operator != types.AccAddToSet {
+		t.Errorf("Expected AccAddToSet, got %v", acc.Operator)
+	}
+}
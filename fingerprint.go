@@ -0,0 +1,467 @@
+package docql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// fingerprintBytes is the number of SHA-256 bytes kept in a fingerprint.
+const fingerprintBytes = 8
+
+// Fingerprint returns a short, stable hash identifying the structure of an AST.
+//
+// The hash covers the operation, collection, field paths, operators, param names,
+// sort, projection, update operators, and pipeline stage kinds. Static literal
+// values such as pagination counts are reduced to their presence, so queries that
+// differ only in Limit(10) vs Limit(20) share a fingerprint. Map-ordered
+// structures and AND/OR condition order are canonicalized before hashing.
+func Fingerprint(ast *types.DocumentAST) string {
+	if ast == nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(canonicalAST(ast)))
+	return hex.EncodeToString(sum[:fingerprintBytes])
+}
+
+// Fingerprint builds the AST and returns its structural fingerprint.
+func (b *Builder) Fingerprint() (string, error) {
+	ast, err := b.Build()
+	if err != nil {
+		return "", err
+	}
+	return Fingerprint(ast), nil
+}
+
+func canonicalAST(ast *types.DocumentAST) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "op=%s;coll=%s;", ast.Operation, ast.Target.Name)
+	if ast.Target.Param != nil {
+		sb.WriteString("collParam=" + ast.Target.Param.Name + ";")
+	}
+
+	if ast.FilterClause != nil {
+		sb.WriteString("filter=" + canonicalFilter(ast.FilterClause) + ";")
+	}
+
+	if ast.Projection != nil {
+		sb.WriteString("proj=" + canonicalProjection(ast.Projection) + ";")
+	}
+
+	if len(ast.SortClauses) > 0 {
+		sb.WriteString("sort=" + canonicalSorts(ast.SortClauses) + ";")
+	}
+
+	if ast.Skip != nil {
+		sb.WriteString("skip=" + canonicalPagination(*ast.Skip) + ";")
+	}
+	if ast.Limit != nil {
+		sb.WriteString("limit=" + canonicalPagination(*ast.Limit) + ";")
+	}
+
+	if len(ast.Documents) > 0 {
+		docs := make([]string, len(ast.Documents))
+		for i, doc := range ast.Documents {
+			docs[i] = canonicalFieldParams(doc.Fields)
+		}
+		sb.WriteString("docs=[" + strings.Join(docs, ",") + "];")
+	}
+
+	if len(ast.UpdateOps) > 0 {
+		ops := make([]string, len(ast.UpdateOps))
+		for i, op := range ast.UpdateOps {
+			ops[i] = string(op.Operator) + canonicalFieldParams(op.Fields)
+		}
+		slices.Sort(ops)
+		sb.WriteString("update=" + strings.Join(ops, ",") + ";")
+	}
+	if ast.Upsert {
+		sb.WriteString("upsert;")
+	}
+	if ast.IfNotExists {
+		sb.WriteString("ifNotExists;")
+	}
+
+	if len(ast.Pipeline) > 0 {
+		sb.WriteString("pipeline=" + canonicalPipeline(ast.Pipeline) + ";")
+	}
+
+	if ast.DistinctField != nil {
+		sb.WriteString("distinct=" + ast.DistinctField.Path + ";")
+	}
+
+	if ast.MaxTimeMS != nil {
+		sb.WriteString("maxTime;")
+	}
+	if ast.AllowDiskUse {
+		sb.WriteString("allowDiskUse;")
+	}
+	if ast.Hint != nil {
+		sb.WriteString("hint=" + *ast.Hint + ";")
+	}
+	if ast.Collation != nil {
+		fmt.Fprintf(&sb, "collation=%s/%d;", ast.Collation.Locale, ast.Collation.Strength)
+	}
+	if ast.ReadConcern != nil {
+		sb.WriteString("readConcern=" + *ast.ReadConcern + ";")
+	}
+	if ast.WriteConcern != nil {
+		sb.WriteString("writeConcern=" + *ast.WriteConcern + ";")
+	}
+	if ast.FullDocument != nil {
+		sb.WriteString("fullDocument=" + *ast.FullDocument + ";")
+	}
+	if ast.ResumeAfter != nil {
+		sb.WriteString("resumeAfter=" + canonicalParam(*ast.ResumeAfter) + ";")
+	}
+	if ast.RawEvent {
+		sb.WriteString("rawEvent;")
+	}
+
+	if len(ast.Operations) > 0 {
+		ops := make([]string, len(ast.Operations))
+		for i, op := range ast.Operations {
+			ops[i] = "{" + canonicalAST(op) + "}"
+		}
+		sb.WriteString("ops=[" + strings.Join(ops, ",") + "];")
+	}
+
+	return sb.String()
+}
+
+func canonicalFilter(f types.FilterItem) string {
+	switch filter := f.(type) {
+	case types.FilterCondition:
+		return fmt.Sprintf("%s %s %s", filter.Field.Path, filter.Operator, canonicalParam(filter.Value))
+
+	case types.FilterGroup:
+		children := canonicalGroupChildren(filter, filter.Logic)
+		if isCommutative(filter.Logic) {
+			slices.Sort(children)
+		}
+		return string(filter.Logic) + "(" + strings.Join(children, "|") + ")"
+
+	case types.RangeFilter:
+		var sb strings.Builder
+		sb.WriteString(filter.Field.Path + " range")
+		if filter.Min != nil {
+			op := "$gte"
+			if filter.MinExclusive {
+				op = "$gt"
+			}
+			sb.WriteString(" " + op + " " + canonicalParam(*filter.Min))
+		}
+		if filter.Max != nil {
+			op := "$lte"
+			if filter.MaxExclusive {
+				op = "$lt"
+			}
+			sb.WriteString(" " + op + " " + canonicalParam(*filter.Max))
+		}
+		if filter.Negated {
+			sb.WriteString(" $not")
+		}
+		return sb.String()
+
+	case types.RegexFilter:
+		s := filter.Field.Path + " $regex " + canonicalParam(filter.Pattern)
+		if filter.Options != nil {
+			s += " $options " + canonicalParam(*filter.Options)
+		}
+		return s
+
+	case types.TextSearchFilter:
+		s := "$text " + canonicalParam(filter.Search)
+		if filter.Language != nil {
+			s += " $language " + canonicalParam(*filter.Language)
+		}
+		if filter.CaseSensitive {
+			s += " $caseSensitive"
+		}
+		if filter.DiacriticSensitive {
+			s += " $diacriticSensitive"
+		}
+		return s
+
+	case types.GeoFilter:
+		s := fmt.Sprintf("%s %s %s,%s", filter.Field.Path, filter.Operator,
+			canonicalParam(filter.Center.Lon), canonicalParam(filter.Center.Lat))
+		if filter.Radius != nil {
+			s += " radius " + canonicalParam(*filter.Radius)
+		}
+		if filter.MaxDistance != nil {
+			s += " max " + canonicalParam(*filter.MaxDistance)
+		}
+		if filter.MinDistance != nil {
+			s += " min " + canonicalParam(*filter.MinDistance)
+		}
+		return s
+
+	case types.ArrayFilter:
+		if filter.Literal != nil {
+			return fmt.Sprintf("%s %s %d", filter.Field.Path, filter.Operator, *filter.Literal)
+		}
+		return fmt.Sprintf("%s %s %s", filter.Field.Path, filter.Operator, canonicalParam(filter.Value))
+
+	case types.ValuesFilter:
+		values := make([]string, len(filter.Values))
+		for i, v := range filter.Values {
+			values[i] = canonicalParam(v)
+		}
+		return fmt.Sprintf("%s %s [%s]", filter.Field.Path, filter.Operator, strings.Join(values, ","))
+
+	case types.ModFilter:
+		return fmt.Sprintf("%s $mod %s,%s", filter.Field.Path, canonicalParam(filter.Divisor), canonicalParam(filter.Remainder))
+
+	case types.TypeFilter:
+		return filter.Field.Path + " $type " + filter.TypeName
+
+	case types.ElemMatchFilter:
+		children := make([]string, len(filter.Conditions))
+		for i, c := range filter.Conditions {
+			children[i] = canonicalFilter(c)
+		}
+		slices.Sort(children)
+		return filter.Field.Path + " $elemMatch(" + strings.Join(children, "|") + ")"
+
+	case types.ExistsFilter:
+		return fmt.Sprintf("%s $exists %t", filter.Field.Path, filter.Exists)
+
+	case types.NullFilter:
+		return fmt.Sprintf("%s null negated=%t strict=%t", filter.Field.Path, filter.Negated, filter.Strict)
+
+	default:
+		return fmt.Sprintf("%T", f)
+	}
+}
+
+// canonicalGroupChildren flattens AND-within-AND and OR-within-OR so that
+// Filter(a).Filter(b).Filter(c) matches any other ordering of a, b, and c.
+func canonicalGroupChildren(group types.FilterGroup, logic types.LogicOperator) []string {
+	children := make([]string, 0, len(group.Conditions))
+	for _, c := range group.Conditions {
+		if nested, ok := c.(types.FilterGroup); ok && nested.Logic == logic && (logic == types.AND || logic == types.OR) {
+			children = append(children, canonicalGroupChildren(nested, logic)...)
+			continue
+		}
+		children = append(children, canonicalFilter(c))
+	}
+	return children
+}
+
+func isCommutative(logic types.LogicOperator) bool {
+	return logic == types.AND || logic == types.OR || logic == types.NOR
+}
+
+func canonicalParam(p types.Param) string {
+	return ":" + p.Name
+}
+
+func canonicalPagination(p types.PaginationValue) string {
+	if p.Param != nil {
+		return canonicalParam(*p.Param)
+	}
+	return "static"
+}
+
+func canonicalProjection(p *types.Projection) string {
+	fields := make([]string, len(p.Fields))
+	for i, f := range p.Fields {
+		s := f.Field.Path
+		if f.Include {
+			s += "+"
+		} else {
+			s += "-"
+		}
+		if f.Alias != "" {
+			s += " as " + f.Alias
+		}
+		if f.Slice != nil {
+			s += " $slice " + canonicalParam(f.Slice.Count)
+			if f.Slice.Skip != nil {
+				s += "," + canonicalParam(*f.Slice.Skip)
+			}
+		}
+		if f.ElemMatch != nil {
+			conds := make([]string, len(f.ElemMatch.Conditions))
+			for j, c := range f.ElemMatch.Conditions {
+				conds[j] = canonicalFilter(c)
+			}
+			slices.Sort(conds)
+			s += " $elemMatch(" + strings.Join(conds, "|") + ")"
+		}
+		fields[i] = s
+	}
+	slices.Sort(fields)
+	return strings.Join(fields, ",")
+}
+
+func canonicalSorts(sorts []types.SortClause) string {
+	parts := make([]string, len(sorts))
+	for i, s := range sorts {
+		parts[i] = fmt.Sprintf("%s:%d", s.Field.Path, s.Order)
+		if s.Opts.CaseInsensitive {
+			parts[i] += "/ci"
+		}
+		if s.Opts.Numeric {
+			parts[i] += "/numeric"
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func canonicalFieldParams(fields map[types.Field]types.Param) string {
+	parts := make([]string, 0, len(fields))
+	for field, value := range fields {
+		parts = append(parts, field.Path+"="+canonicalParam(value))
+	}
+	slices.Sort(parts)
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func canonicalPipeline(stages []types.PipelineStage) string {
+	parts := make([]string, len(stages))
+	for i, stage := range stages {
+		parts[i] = canonicalStage(stage)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func canonicalStage(stage types.PipelineStage) string {
+	name := stage.StageName()
+	switch s := stage.(type) {
+	case types.MatchStage:
+		return name + "(" + canonicalFilter(s.Filter) + ")"
+
+	case types.ProjectStage:
+		return name + "(" + canonicalProjection(&s.Projection) + ";" + canonicalExprs(s.Computed) + ")"
+
+	case types.GroupStage:
+		return name + "(" + canonicalExpr(s.ID) + ";" + canonicalAccumulators(s.Accumulators) + ")"
+
+	case types.SortStage:
+		return name + "(" + canonicalSorts(s.Sorts) + ")"
+
+	case types.LimitStage:
+		return name + "(" + canonicalPagination(s.Limit) + ")"
+
+	case types.SkipStage:
+		return name + "(" + canonicalPagination(s.Skip) + ")"
+
+	case types.UnwindStage:
+		out := name + "(" + s.Path.Path
+		if s.IncludeArrayIndex != nil {
+			out += ",index=" + *s.IncludeArrayIndex
+		}
+		if s.PreserveNullAndEmptyArrays {
+			out += ",preserve"
+		}
+		return out + ")"
+
+	case types.LookupStage:
+		return name + "(" + s.From + "," + s.LocalField.Path + "," + s.ForeignField.Path + "," + s.As +
+			"," + canonicalExprs(s.Let) + "," + canonicalPipeline(s.Pipeline) + ")"
+
+	case types.GraphLookupStage:
+		out := name + "(" + s.From + "," + canonicalExpr(s.StartWith) + "," + s.ConnectFromField.Path +
+			"," + s.ConnectToField.Path + "," + s.As
+		if s.MaxDepth != nil {
+			out += ",maxDepth=" + canonicalPagination(*s.MaxDepth)
+		}
+		if s.DepthField != nil {
+			out += ",depthField=" + *s.DepthField
+		}
+		if s.RestrictSearchWithMatch != nil {
+			out += ",match=" + canonicalFilter(s.RestrictSearchWithMatch)
+		}
+		return out + ")"
+
+	case types.AddFieldsStage:
+		return name + "(" + canonicalExprs(s.Fields) + ")"
+
+	case types.ReplaceRootStage:
+		return name + "(" + canonicalExpr(s.NewRoot) + ")"
+
+	case types.CountStage:
+		return name + "(" + s.FieldName + ")"
+
+	case types.FacetStage:
+		facets := make([]string, 0, len(s.Facets))
+		for _, key := range slices.Sorted(maps.Keys(s.Facets)) {
+			facets = append(facets, key+"="+canonicalPipeline(s.Facets[key]))
+		}
+		return name + "(" + strings.Join(facets, ",") + ")"
+
+	case types.BucketStage:
+		boundaries := make([]string, len(s.Boundaries))
+		for i, b := range s.Boundaries {
+			boundaries[i] = canonicalParam(b)
+		}
+		out := name + "(" + canonicalExpr(s.GroupBy) + ";" + strings.Join(boundaries, ",")
+		if s.Default != nil {
+			out += ";default=" + canonicalParam(*s.Default)
+		}
+		return out + ";" + canonicalAccumulators(s.Output) + ")"
+
+	case types.SortByCountStage:
+		return name + "(" + canonicalExpr(s.Expr) + ")"
+
+	case types.SampleStage:
+		return name + "(" + canonicalPagination(s.Size) + ")"
+
+	case types.MergeStage:
+		return name + "(" + s.Into + "," + s.WhenMatched + "," + s.WhenNotMatched + ")"
+
+	default:
+		return name
+	}
+}
+
+func canonicalExprs(exprs map[string]types.Expression) string {
+	parts := make([]string, 0, len(exprs))
+	for _, key := range slices.Sorted(maps.Keys(exprs)) {
+		parts = append(parts, key+"="+canonicalExpr(exprs[key]))
+	}
+	return strings.Join(parts, ",")
+}
+
+func canonicalAccumulators(accs map[string]types.Accumulator) string {
+	parts := make([]string, 0, len(accs))
+	for _, key := range slices.Sorted(maps.Keys(accs)) {
+		acc := accs[key]
+		parts = append(parts, key+"="+acc.Operator+"("+canonicalExpr(acc.Expr)+")")
+	}
+	return strings.Join(parts, ",")
+}
+
+func canonicalExpr(expr types.Expression) string {
+	switch e := expr.(type) {
+	case nil:
+		return "null"
+	case types.FieldExpression:
+		return "$" + e.Field.Path
+	case types.LiteralExpression:
+		return canonicalParam(e.Value)
+	case types.OperatorExpression:
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = canonicalExpr(arg)
+		}
+		return e.Operator + "(" + strings.Join(args, ",") + ")"
+	case types.ConditionalExpression:
+		return "$cond(" + canonicalExpr(e.If) + "," + canonicalExpr(e.Then) + "," + canonicalExpr(e.Else) + ")"
+	case types.CompositeExpression:
+		fields := make([]string, 0, len(e.Fields))
+		for _, key := range slices.Sorted(maps.Keys(e.Fields)) {
+			fields = append(fields, key+"="+canonicalExpr(e.Fields[key]))
+		}
+		return "{" + strings.Join(fields, ",") + "}"
+	default:
+		return fmt.Sprintf("%T", expr)
+	}
+}
@@ -0,0 +1,180 @@
+package docql
+
+import (
+	"testing"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+func TestFingerprint_OrderIndependent(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	status := types.Field{Path: "status", Collection: "users"}
+	age := types.Field{Path: "age", Collection: "users"}
+	email := types.Field{Path: "email", Collection: "users"}
+
+	a, err := Find(coll).
+		Filter(Eq(status, types.Param{Name: "status"})).
+		Filter(Gt(age, types.Param{Name: "minAge"})).
+		Filter(Exists(email)).
+		SortDesc(age).
+		LimitParam(types.Param{Name: "limit"}).
+		Fingerprint()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := Find(coll).
+		LimitParam(types.Param{Name: "limit"}).
+		SortDesc(age).
+		Filter(Exists(email)).
+		Filter(Gt(age, types.Param{Name: "minAge"})).
+		Filter(Eq(status, types.Param{Name: "status"})).
+		Fingerprint()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if a != b {
+		t.Errorf("expected equal fingerprints, got %s and %s", a, b)
+	}
+	if len(a) != 2*fingerprintBytes {
+		t.Errorf("expected %d hex characters, got %d", 2*fingerprintBytes, len(a))
+	}
+}
+
+func TestFingerprint_MapOrderIndependent(t *testing.T) {
+	coll := types.Collection{Name: "orders"}
+	total := FieldExpr(types.Field{Path: "total", Collection: "orders"})
+	status := FieldExpr(types.Field{Path: "status", Collection: "orders"})
+
+	build := func() string {
+		fp, err := Aggregate(coll).
+			Group(status, map[string]types.Accumulator{
+				"sum": Sum(total),
+				"avg": Avg(total),
+				"max": Max(total),
+				"min": Min(total),
+			}).
+			Fingerprint()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return fp
+	}
+
+	first := build()
+	for i := 0; i < 20; i++ {
+		if fp := build(); fp != first {
+			t.Fatalf("fingerprint changed between builds: %s vs %s", first, fp)
+		}
+	}
+
+	field := types.Field{Path: "a", Collection: "users"}
+	other := types.Field{Path: "b", Collection: "users"}
+	u1 := Update(types.Collection{Name: "users"}).
+		Set(field, types.Param{Name: "a"}).
+		Set(other, types.Param{Name: "b"}).
+		MustBuild()
+	u2 := Update(types.Collection{Name: "users"}).
+		Set(other, types.Param{Name: "b"}).
+		Set(field, types.Param{Name: "a"}).
+		MustBuild()
+	if Fingerprint(u1) != Fingerprint(u2) {
+		t.Error("expected update fingerprints to match regardless of Set order")
+	}
+}
+
+func TestFingerprint_IgnoresStaticPagination(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+
+	a := Fingerprint(Find(coll).Limit(10).Skip(0).MustBuild())
+	b := Fingerprint(Find(coll).Limit(20).Skip(40).MustBuild())
+
+	if a != b {
+		t.Errorf("expected static limits to collide, got %s and %s", a, b)
+	}
+}
+
+func TestFingerprint_DistinguishesStructure(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+	status := types.Field{Path: "status", Collection: "users"}
+	age := types.Field{Path: "age", Collection: "users"}
+
+	base := Fingerprint(Find(coll).Filter(Eq(status, types.Param{Name: "status"})).MustBuild())
+
+	variants := map[string]*types.DocumentAST{
+		"operator":   Find(coll).Filter(Ne(status, types.Param{Name: "status"})).MustBuild(),
+		"field":      Find(coll).Filter(Eq(age, types.Param{Name: "status"})).MustBuild(),
+		"collection": Find(types.Collection{Name: "posts"}).Filter(Eq(status, types.Param{Name: "status"})).MustBuild(),
+		"operation":  Count(coll).Filter(Eq(status, types.Param{Name: "status"})).MustBuild(),
+		"sort":       Find(coll).Filter(Eq(status, types.Param{Name: "status"})).SortAsc(age).MustBuild(),
+		"limit":      Find(coll).Filter(Eq(status, types.Param{Name: "status"})).Limit(5).MustBuild(),
+	}
+
+	for name, ast := range variants {
+		if Fingerprint(ast) == base {
+			t.Errorf("expected %s change to alter the fingerprint", name)
+		}
+	}
+}
+
+func TestFingerprint_DistinguishesStageContents(t *testing.T) {
+	field := func(path string) types.Expression { return FieldExpr(types.Field{Path: path}) }
+	param := func(name string) types.Param { return types.Param{Name: name} }
+	str := func(s string) *string { return &s }
+	limit := types.PaginationValue{Param: &types.Param{Name: "depth"}}
+	otherLimit := types.PaginationValue{Param: &types.Param{Name: "maxDepth"}}
+
+	graph := types.GraphLookupStage{From: "users", StartWith: field("managerId"), ConnectFromField: types.Field{Path: "managerId"},
+		ConnectToField: types.Field{Path: "_id"}, As: "chain"}
+	bucket := types.BucketStage{GroupBy: field("age"), Boundaries: []types.Param{param("lo"), param("hi")},
+		Output: map[string]types.Accumulator{"n": Sum(field("one"))}}
+	with := func(update func(s *types.GraphLookupStage)) types.GraphLookupStage { s := graph; update(&s); return s }
+	withBucket := func(update func(s *types.BucketStage)) types.BucketStage { s := bucket; update(&s); return s }
+
+	cases := map[string][2]types.PipelineStage{
+		"$graphLookup from":        {graph, with(func(s *types.GraphLookupStage) { s.From = "staff" })},
+		"$graphLookup startWith":   {graph, with(func(s *types.GraphLookupStage) { s.StartWith = field("reportsTo") })},
+		"$graphLookup connectFrom": {graph, with(func(s *types.GraphLookupStage) { s.ConnectFromField = types.Field{Path: "reportsTo"} })},
+		"$graphLookup connectTo":   {graph, with(func(s *types.GraphLookupStage) { s.ConnectToField = types.Field{Path: "code"} })},
+		"$graphLookup as":          {graph, with(func(s *types.GraphLookupStage) { s.As = "managers" })},
+		"$graphLookup maxDepth":    {with(func(s *types.GraphLookupStage) { s.MaxDepth = &limit }), with(func(s *types.GraphLookupStage) { s.MaxDepth = &otherLimit })},
+		"$graphLookup depthField":  {graph, with(func(s *types.GraphLookupStage) { s.DepthField = str("level") })},
+		"$graphLookup match": {graph, with(func(s *types.GraphLookupStage) {
+			s.RestrictSearchWithMatch = Eq(types.Field{Path: "active"}, param("active"))
+		})},
+		"$replaceRoot":        {types.ReplaceRootStage{NewRoot: field("profile")}, types.ReplaceRootStage{NewRoot: field("address")}},
+		"$count":              {types.CountStage{FieldName: "total"}, types.CountStage{FieldName: "n"}},
+		"$bucket groupBy":     {bucket, withBucket(func(s *types.BucketStage) { s.GroupBy = field("score") })},
+		"$bucket boundaries":  {bucket, withBucket(func(s *types.BucketStage) { s.Boundaries = []types.Param{param("lo"), param("mid"), param("hi")} })},
+		"$bucket default":     {bucket, withBucket(func(s *types.BucketStage) { s.Default = &types.Param{Name: "other"} })},
+		"$bucket output":      {bucket, withBucket(func(s *types.BucketStage) { s.Output = map[string]types.Accumulator{"n": Avg(field("one"))} })},
+		"$sortByCount":        {types.SortByCountStage{Expr: field("status")}, types.SortByCountStage{Expr: field("role")}},
+		"$sample":             {types.SampleStage{Size: limit}, types.SampleStage{Size: otherLimit}},
+		"$merge into":         {types.MergeStage{Into: "a"}, types.MergeStage{Into: "b"}},
+		"$merge whenMatched":  {types.MergeStage{Into: "a", WhenMatched: types.MergeReplace}, types.MergeStage{Into: "a", WhenMatched: types.MergeKeepExisting}},
+		"$merge whenNotMatch": {types.MergeStage{Into: "a", WhenNotMatched: types.MergeFail}, types.MergeStage{Into: "a"}},
+		"$unwind index":       {types.UnwindStage{Path: types.Field{Path: "tags"}}, types.UnwindStage{Path: types.Field{Path: "tags"}, IncludeArrayIndex: str("i")}},
+		"$unwind preserve":    {types.UnwindStage{Path: types.Field{Path: "tags"}}, types.UnwindStage{Path: types.Field{Path: "tags"}, PreserveNullAndEmptyArrays: true}},
+		"$lookup let": {
+			types.LookupStage{From: "posts", As: "posts", Let: map[string]types.Expression{"id": field("_id")}},
+			types.LookupStage{From: "posts", As: "posts", Let: map[string]types.Expression{"id": field("authorId")}},
+		},
+	}
+	for name, pair := range cases {
+		a := &types.DocumentAST{Operation: types.OpAggregate, Target: types.Collection{Name: "users"}, Pipeline: []types.PipelineStage{pair[0]}}
+		b := &types.DocumentAST{Operation: types.OpAggregate, Target: types.Collection{Name: "users"}, Pipeline: []types.PipelineStage{pair[1]}}
+		if Fingerprint(a) == Fingerprint(b) {
+			t.Errorf("%s: expected different stages to have different fingerprints", name)
+		}
+	}
+}
+
+func TestFingerprint_BuilderError(t *testing.T) {
+	coll := types.Collection{Name: "users"}
+
+	_, err := Find(coll).Set(types.Field{Path: "x"}, types.Param{Name: "x"}).Fingerprint()
+	if err == nil {
+		t.Error("expected builder error to propagate")
+	}
+}
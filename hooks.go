@@ -0,0 +1,115 @@
+package docql
+
+import (
+	"path"
+	"reflect"
+	"time"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// Hooks are callbacks around each render, for metrics and tracing. Either
+// may be nil. Both receive a copy of the AST, so they cannot change what is
+// rendered, and renderer names the provider package, such as "mongodb".
+type Hooks struct {
+	// BeforeRender runs before the inner renderer is called.
+	BeforeRender func(ast *types.DocumentAST, renderer string)
+
+	// AfterRender runs once the inner renderer returns, on success and on
+	// error, with the time the render took.
+	AfterRender func(ast *types.DocumentAST, result *types.QueryResult, err error, d time.Duration)
+}
+
+// WithHooks runs hooks around every render of builders created through the
+// instance constructors (Find, Update, Watch, and so on) and of DOCQL.Render.
+func WithHooks(hooks Hooks) Option {
+	return func(d *DOCQL) {
+		d.hooks = &hooks
+	}
+}
+
+// HookedRenderer runs Hooks around another renderer.
+type HookedRenderer struct {
+	inner Renderer
+	name  string
+	hooks Hooks
+}
+
+// NewHookedRenderer wraps inner so that hooks run around each render.
+func NewHookedRenderer(inner Renderer, hooks Hooks) *HookedRenderer {
+	return &HookedRenderer{inner: inner, name: rendererName(inner), hooks: hooks}
+}
+
+// Render calls the hooks around the inner renderer's Render.
+func (r *HookedRenderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
+	if r.hooks.BeforeRender != nil {
+		r.hooks.BeforeRender(ast.Clone(), r.name)
+	}
+	start := time.Now()
+	result, err := r.inner.Render(ast)
+	elapsed := time.Since(start)
+	if r.hooks.AfterRender != nil {
+		r.hooks.AfterRender(ast.Clone(), result, err, elapsed)
+	}
+	return result, err
+}
+
+// SupportsOperation delegates to the inner renderer.
+func (r *HookedRenderer) SupportsOperation(op types.Operation) bool {
+	return r.inner.SupportsOperation(op)
+}
+
+// SupportsFilter delegates to the inner renderer.
+func (r *HookedRenderer) SupportsFilter(op types.FilterOperator) bool {
+	return r.inner.SupportsFilter(op)
+}
+
+// SupportsUpdate delegates to the inner renderer.
+func (r *HookedRenderer) SupportsUpdate(op types.UpdateOperator) bool {
+	return r.inner.SupportsUpdate(op)
+}
+
+// SupportsPipelineStage delegates to the inner renderer.
+func (r *HookedRenderer) SupportsPipelineStage(stage string) bool {
+	return r.inner.SupportsPipelineStage(stage)
+}
+
+// CacheKey reports false, so the hooks run on every render through a
+// registry.
+func (r *HookedRenderer) CacheKey() (string, bool) {
+	return "", false
+}
+
+// rendererName returns the package name of a renderer, looking through the
+// wrappers in this package.
+func rendererName(r Renderer) string {
+	switch w := r.(type) {
+	case *HookedRenderer:
+		return w.name
+	case *PolicyRenderer:
+		return rendererName(w.inner)
+	}
+	t := reflect.TypeOf(r)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.PkgPath() == "" {
+		return t.String()
+	}
+	return path.Base(t.PkgPath())
+}
+
+// withHooks attaches the instance's hooks, if any, to b.
+func (d *DOCQL) withHooks(b *Builder) *Builder {
+	b.hooks = d.hooks
+	return b
+}
+
+// hooked wraps renderer with hooks, or returns it unchanged when there are
+// none.
+func hooked(renderer Renderer, hooks *Hooks) Renderer {
+	if hooks == nil {
+		return renderer
+	}
+	return NewHookedRenderer(renderer, *hooks)
+}
@@ -0,0 +1,139 @@
+package docql_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zoobzio/ddml"
+	"github.com/zoobzio/docql"
+	"github.com/zoobzio/docql/internal/types"
+	"github.com/zoobzio/docql/pkg/firestore"
+	"github.com/zoobzio/docql/pkg/mongodb"
+)
+
+// renderMetrics counts renders by renderer and outcome and accumulates
+// render durations, as a metrics backend would.
+type renderMetrics struct {
+	renders   map[string]int
+	errors    map[string]int
+	durations []time.Duration
+}
+
+func (m *renderMetrics) hooks() docql.Hooks {
+	m.renders = make(map[string]int)
+	m.errors = make(map[string]int)
+	return docql.Hooks{
+		BeforeRender: func(_ *types.DocumentAST, renderer string) {
+			m.renders[renderer]++
+		},
+		AfterRender: func(_ *types.DocumentAST, _ *types.QueryResult, err error, d time.Duration) {
+			m.durations = append(m.durations, d)
+			switch {
+			case err == nil:
+			case errors.Is(err, docql.ErrUnsupportedOperation):
+				m.errors["unsupported"]++
+			default:
+				m.errors["other"]++
+			}
+		},
+	}
+}
+
+func TestHookedRenderer(t *testing.T) {
+	instance := createTestInstance(t)
+	var m renderMetrics
+	hooks := m.hooks()
+
+	find := docql.Find(instance.C("users")).Filter(instance.Eq(instance.F("users", "status"), instance.P("status")))
+	agg := docql.Aggregate(instance.C("users")).Match(instance.Eq(instance.F("users", "status"), instance.P("status")))
+
+	mongo := docql.NewHookedRenderer(mongodb.New(), hooks)
+	fs := docql.NewHookedRenderer(firestore.New(), hooks)
+	if _, err := find.Render(mongo); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if _, err := find.Render(fs); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if _, err := agg.Render(fs); err == nil {
+		t.Fatal("expected firestore to reject AGGREGATE")
+	}
+
+	if m.renders["mongodb"] != 1 || m.renders["firestore"] != 2 {
+		t.Errorf("unexpected render counts: %v", m.renders)
+	}
+	if m.errors["unsupported"] != 1 || len(m.errors) != 1 {
+		t.Errorf("unexpected error counts: %v", m.errors)
+	}
+	if len(m.durations) != 3 {
+		t.Errorf("expected 3 durations, got %d", len(m.durations))
+	}
+}
+
+func TestHookedRenderer_CannotMutateAST(t *testing.T) {
+	instance := createTestInstance(t)
+	hooks := docql.Hooks{
+		BeforeRender: func(ast *types.DocumentAST, _ string) {
+			ast.Target.Name = "posts"
+			ast.FilterClause = nil
+		},
+	}
+	b := docql.Find(instance.C("users")).Filter(instance.Eq(instance.F("users", "status"), instance.P("status")))
+
+	want, err := b.Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	got, err := b.Render(docql.NewHookedRenderer(mongodb.New(), hooks))
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if got.JSON != want.JSON {
+		t.Errorf("hook changed the rendered query:\n got: %s\nwant: %s", got.JSON, want.JSON)
+	}
+}
+
+func TestHookedRenderer_NilHooks(t *testing.T) {
+	instance := createTestInstance(t)
+	r := docql.NewHookedRenderer(mongodb.New(), docql.Hooks{})
+	if _, err := docql.Find(instance.C("users")).Render(r); err != nil {
+		t.Errorf("Render failed: %v", err)
+	}
+	if !r.SupportsOperation(docql.OpAggregate) {
+		t.Error("expected SupportsOperation to delegate")
+	}
+}
+
+func TestWithHooks(t *testing.T) {
+	schema := ddml.NewSchema("test")
+	users := ddml.NewCollection("users")
+	users.AddField(ddml.NewField("status", ddml.TypeString))
+	schema.AddCollection(users)
+
+	var m renderMetrics
+	instance, err := docql.NewFromDDML(schema, docql.WithHooks(m.hooks()))
+	if err != nil {
+		t.Fatalf("NewFromDDML failed: %v", err)
+	}
+
+	b := instance.Find("users").Filter(instance.Eq(instance.F("users", "status"), instance.P("status")))
+	if _, err := b.Render(mongodb.New()); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if _, err := b.Clone().Render(mongodb.New()); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if _, err := instance.Render(docql.Find(instance.C("users")), mongodb.New()); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	// Package-level builders carry no instance hooks.
+	if _, err := docql.Find(instance.C("users")).Render(mongodb.New()); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	if m.renders["mongodb"] != 3 || len(m.durations) != 3 {
+		t.Errorf("expected 3 hooked renders, got %v and %d durations", m.renders, len(m.durations))
+	}
+}
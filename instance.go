@@ -1,8 +1,12 @@
 package docql
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/zoobzio/ddml"
 	"github.com/zoobzio/docql/internal/types"
@@ -14,10 +18,55 @@ type DOCQL struct {
 	collections map[string]*ddml.Collection
 	fields      map[string]map[string]*ddml.Field
 	enums       map[string]*ddml.Enum
+	limits      types.Limits
+	strict      bool
+
+	// Schema names that are not valid identifiers or field paths. Exact
+	// schema hits skip validation in TryC and TryF unless listed here, so
+	// these still take the slow path and are rejected.
+	invalidCollections map[string]bool
+	invalidFields      map[string]map[string]bool
+
+	// Collection name to soft-delete field, set by WithSoftDelete.
+	softDelete map[string]types.Field
+
+	// Lowercased name to canonical name, set by WithCaseInsensitiveLookup.
+	// An empty canonical name marks names that collide when folded.
+	foldedCollections map[string]string
+	foldedFields      map[string]map[string]string
+	caseInsensitive   bool
+
+	// Render hooks, set by WithHooks.
+	hooks *Hooks
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// Option configures a DOCQL instance.
+type Option func(*DOCQL)
+
+// WithStrictMode makes C, F, and P return invalid references carrying the
+// error instead of panicking. A query using an invalid reference fails when it
+// is built or validated, with the error the reference was created with; other
+// queries are unaffected. The errors are also recorded for Err.
+func WithStrictMode() Option {
+	return func(d *DOCQL) {
+		d.strict = true
+	}
+}
+
+// WithCaseInsensitiveLookup makes TryC and TryF (and so C and F) match collection
+// names and field paths ignoring case, returning the canonical schema names.
+// Names that differ only by case are ambiguous and must be given exactly.
+func WithCaseInsensitiveLookup() Option {
+	return func(d *DOCQL) {
+		d.caseInsensitive = true
+	}
 }
 
 // NewFromDDML creates a new DOCQL instance from a DDML schema.
-func NewFromDDML(schema *ddml.Schema) (*DOCQL, error) {
+func NewFromDDML(schema *ddml.Schema, opts ...Option) (*DOCQL, error) {
 	if schema == nil {
 		return nil, fmt.Errorf("schema cannot be nil")
 	}
@@ -27,17 +76,107 @@ func NewFromDDML(schema *ddml.Schema) (*DOCQL, error) {
 		collections: make(map[string]*ddml.Collection),
 		fields:      make(map[string]map[string]*ddml.Field),
 		enums:       schema.Enums,
+		limits:      types.DefaultLimits(),
 	}
 
 	for name, coll := range schema.Collections {
 		d.collections[name] = coll
 		d.fields[name] = make(map[string]*ddml.Field)
 		d.indexFields(name, "", coll.Fields)
+		if !types.IsValidIdentifier(name) {
+			if d.invalidCollections == nil {
+				d.invalidCollections = make(map[string]bool)
+			}
+			d.invalidCollections[name] = true
+		}
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if d.caseInsensitive {
+		d.foldedCollections = foldNames(d.collections)
+		d.foldedFields = make(map[string]map[string]string, len(d.fields))
+		for name, fields := range d.fields {
+			d.foldedFields[name] = foldNames(fields)
+		}
 	}
 
 	return d, nil
 }
 
+func foldNames[V any](names map[string]V) map[string]string {
+	folded := make(map[string]string, len(names))
+	for name := range names {
+		key := strings.ToLower(name)
+		if _, exists := folded[key]; exists {
+			folded[key] = ""
+			continue
+		}
+		folded[key] = name
+	}
+	return folded
+}
+
+// resolveCollection returns the canonical collection name for name.
+func (d *DOCQL) resolveCollection(name string) (string, bool) {
+	if _, ok := d.collections[name]; ok {
+		return name, true
+	}
+	if d.foldedCollections != nil {
+		if canonical := d.foldedCollections[strings.ToLower(name)]; canonical != "" {
+			return canonical, true
+		}
+	}
+	return "", false
+}
+
+// resolveField returns the canonical path for fieldPath in a canonical collection.
+func (d *DOCQL) resolveField(collectionName, fieldPath string) (string, bool) {
+	if _, ok := d.fields[collectionName][fieldPath]; ok {
+		return fieldPath, true
+	}
+	if d.foldedFields != nil {
+		if canonical := d.foldedFields[collectionName][strings.ToLower(fieldPath)]; canonical != "" {
+			return canonical, true
+		}
+	}
+	return "", false
+}
+
+// Err returns the reference errors recorded in strict mode since the last
+// call, or nil, and clears them. Queries fail on their own invalid
+// references, so Err is only needed to inspect references that were never
+// built into a query.
+func (d *DOCQL) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := errors.Join(d.errs...)
+	d.errs = nil
+	return err
+}
+
+// fail panics with err, or records it in strict mode.
+func (d *DOCQL) fail(err error) {
+	if !d.strict {
+		panic(err)
+	}
+	d.mu.Lock()
+	d.errs = append(d.errs, err)
+	d.mu.Unlock()
+}
+
+// Build builds a query under the instance: the instance's limits apply unless
+// the builder set its own.
+func (d *DOCQL) Build(b *Builder) (*types.DocumentAST, error) {
+	if b.err == nil && b.ast.Limits == nil {
+		limits := d.limits
+		b.ast.Limits = &limits
+	}
+	return b.Build()
+}
+
 func (d *DOCQL) indexFields(collName, prefix string, fields []*ddml.Field) {
 	for _, f := range fields {
 		path := f.Name
@@ -45,99 +184,209 @@ func (d *DOCQL) indexFields(collName, prefix string, fields []*ddml.Field) {
 			path = prefix + "." + f.Name
 		}
 		d.fields[collName][path] = f
+		if !types.IsValidFieldPath(path) {
+			d.markInvalidField(collName, path)
+		}
 
-		if f.Type == ddml.TypeObject && len(f.Fields) > 0 {
+		switch f.Type {
+		case ddml.TypeObject:
 			d.indexFields(collName, path, f.Fields)
+		case ddml.TypeArray:
+			d.indexArrayElement(collName, path, f.ArrayOf)
 		}
+	}
+}
 
-		if f.Type == ddml.TypeArray && f.ArrayOf != nil && f.ArrayOf.Type == ddml.TypeObject {
-			d.indexFields(collName, path, f.ArrayOf.Fields)
-		}
+func (d *DOCQL) markInvalidField(collName, path string) {
+	if d.invalidFields == nil {
+		d.invalidFields = make(map[string]map[string]bool)
+	}
+	if d.invalidFields[collName] == nil {
+		d.invalidFields[collName] = make(map[string]bool)
+	}
+	d.invalidFields[collName][path] = true
+}
+
+// indexArrayElement indexes the fields of an array's elements under the array's
+// own path, unwrapping arrays of arrays. Element positions are not part of the
+// path: users.orders[].items[].sku indexes as "orders.items.sku".
+func (d *DOCQL) indexArrayElement(collName, path string, elem *ddml.Field) {
+	for elem != nil && elem.Type == ddml.TypeArray {
+		elem = elem.ArrayOf
+	}
+	if elem != nil && elem.Type == ddml.TypeObject {
+		d.indexFields(collName, path, elem.Fields)
 	}
 }
 
+// WithLimits replaces the instance's complexity limits; fields left at zero
+// keep their defaults. The limits apply to builders the instance constructors
+// create afterwards and to queries built, validated, or rendered through the
+// instance.
+func (d *DOCQL) WithLimits(limits types.Limits) *DOCQL {
+	d.limits = limits.WithDefaults()
+	return d
+}
+
+// withLimits seeds b with the instance's limits, so the checks its methods
+// make as the query is built use them too.
+func (d *DOCQL) withLimits(b *Builder) *Builder {
+	limits := d.limits
+	b.ast.Limits = &limits
+	return b
+}
+
+// Database returns the schema name, which instance-bound collections carry
+// as their database.
+func (d *DOCQL) Database() string {
+	return d.schema.Name
+}
+
+// Limits returns the instance's complexity limits.
+func (d *DOCQL) Limits() types.Limits {
+	return d.limits
+}
+
+// Validate validates an AST against the instance's complexity limits.
+func (d *DOCQL) Validate(ast *types.DocumentAST) error {
+	return ast.ValidateWithLimits(d.limits)
+}
+
 // C creates a validated collection reference.
 func (d *DOCQL) C(name string) types.Collection {
 	c, err := d.TryC(name)
 	if err != nil {
-		panic(err)
+		d.fail(err)
+		return types.InvalidCollection(err)
 	}
 	return c
 }
 
 // TryC creates a collection reference with error handling.
 func (d *DOCQL) TryC(name string) (types.Collection, error) {
-	if !isValidIdentifier(name) {
-		return types.Collection{}, fmt.Errorf("invalid collection name: %s", name)
+	// Exact schema names were validated when the schema was indexed.
+	if _, ok := d.collections[name]; ok && !d.invalidCollections[name] {
+		return types.Collection{Name: name, Database: d.schema.Name}, nil
+	}
+	if !types.IsValidIdentifier(name) {
+		return types.Collection{}, &types.InvalidIdentifierError{Kind: "collection name", Value: name}
 	}
-	if _, ok := d.collections[name]; !ok {
-		return types.Collection{}, fmt.Errorf("collection '%s' not found in schema", name)
+	canonical, ok := d.resolveCollection(name)
+	if !ok {
+		return types.Collection{}, &types.UnknownCollectionError{Collection: name}
 	}
-	return types.Collection{Name: name}, nil
+	return types.Collection{Name: canonical, Database: d.schema.Name}, nil
+}
+
+// Distinct creates a distinct query builder for a schema-validated field.
+// Chain Filter to restrict the documents considered.
+func (d *DOCQL) Distinct(collectionName, fieldPath string) *Builder {
+	return d.withHooks(d.withLimits(d.withSoftDelete(Distinct(d.C(collectionName), d.F(collectionName, fieldPath)))))
+}
+
+// Find creates a find query builder for a schema-validated collection.
+func (d *DOCQL) Find(collectionName string) *Builder {
+	return d.withHooks(d.withLimits(d.withSoftDelete(Find(d.C(collectionName)))))
+}
+
+// FindOne creates a find-one query builder for a schema-validated collection.
+func (d *DOCQL) FindOne(collectionName string) *Builder {
+	return d.withHooks(d.withLimits(d.withSoftDelete(FindOne(d.C(collectionName)))))
+}
+
+// Count creates a count query builder for a schema-validated collection.
+func (d *DOCQL) Count(collectionName string) *Builder {
+	return d.withHooks(d.withLimits(d.withSoftDelete(Count(d.C(collectionName)))))
+}
+
+// Update creates an update query builder for a schema-validated collection.
+func (d *DOCQL) Update(collectionName string) *Builder {
+	return d.withHooks(d.withLimits(d.withSoftDelete(Update(d.C(collectionName)))))
+}
+
+// UpdateMany creates an update-many query builder for a schema-validated collection.
+func (d *DOCQL) UpdateMany(collectionName string) *Builder {
+	return d.withHooks(d.withLimits(d.withSoftDelete(UpdateMany(d.C(collectionName)))))
+}
+
+// Delete creates a delete query builder for a schema-validated collection.
+func (d *DOCQL) Delete(collectionName string) *Builder {
+	return d.withHooks(d.withLimits(d.withSoftDelete(Delete(d.C(collectionName)))))
+}
+
+// DeleteMany creates a delete-many query builder for a schema-validated collection.
+func (d *DOCQL) DeleteMany(collectionName string) *Builder {
+	return d.withHooks(d.withLimits(d.withSoftDelete(DeleteMany(d.C(collectionName)))))
+}
+
+// Watch creates a change stream builder for a schema-validated collection.
+func (d *DOCQL) Watch(collectionName string) *Builder {
+	return d.withHooks(d.withLimits(Watch(d.C(collectionName))))
 }
 
 // F creates a validated field reference.
 func (d *DOCQL) F(collectionName, fieldPath string) types.Field {
 	f, err := d.TryF(collectionName, fieldPath)
 	if err != nil {
-		panic(err)
+		d.fail(err)
+		return types.InvalidField(err)
 	}
 	return f
 }
 
 // TryF creates a field reference with error handling.
 func (d *DOCQL) TryF(collectionName, fieldPath string) (types.Field, error) {
-	if !isValidFieldPath(fieldPath) {
-		return types.Field{}, fmt.Errorf("invalid field path: %s", fieldPath)
+	// Exact schema paths were validated when the schema was indexed; only
+	// case-folded and unknown paths pay for validation.
+	if fields, ok := d.fields[collectionName]; ok {
+		if _, ok := fields[fieldPath]; ok && !d.invalidFields[collectionName][fieldPath] {
+			return types.Field{Path: fieldPath, Collection: collectionName}, nil
+		}
 	}
-	collFields, ok := d.fields[collectionName]
+	if !types.IsValidFieldPath(fieldPath) {
+		return types.Field{}, &types.InvalidIdentifierError{Kind: "field path", Value: fieldPath}
+	}
+	collection, ok := d.resolveCollection(collectionName)
 	if !ok {
-		return types.Field{}, fmt.Errorf("collection '%s' not found", collectionName)
+		return types.Field{}, &types.UnknownCollectionError{Collection: collectionName}
 	}
-	if _, ok := collFields[fieldPath]; !ok {
-		return types.Field{}, fmt.Errorf("field '%s' not found in collection '%s'",
-			fieldPath, collectionName)
+	path, ok := d.resolveField(collection, fieldPath)
+	if !ok {
+		return types.Field{}, &types.UnknownFieldError{Collection: collectionName, Fields: []string{fieldPath}}
 	}
-	return types.Field{Path: fieldPath, Collection: collectionName}, nil
+	return types.Field{Path: path, Collection: collection}, nil
 }
 
 // P creates a validated parameter reference.
 func (d *DOCQL) P(name string) types.Param {
 	p, err := d.TryP(name)
 	if err != nil {
-		panic(err)
+		d.fail(err)
+		return types.InvalidParam(err)
 	}
 	return p
 }
 
 // TryP creates a parameter with error handling.
 func (d *DOCQL) TryP(name string) (types.Param, error) {
-	if !isValidIdentifier(name) {
-		return types.Param{}, fmt.Errorf("invalid parameter name: %s", name)
+	if !types.IsValidIdentifier(name) {
+		return types.Param{}, &types.InvalidIdentifierError{Kind: "parameter name", Value: name}
 	}
 	return types.Param{Name: name}, nil
 }
 
-// Collections returns all collection names in the schema.
+// Collections returns all collection names in the schema, sorted lexicographically.
 func (d *DOCQL) Collections() []string {
-	names := make([]string, 0, len(d.collections))
-	for name := range d.collections {
-		names = append(names, name)
-	}
-	return names
+	return slices.Sorted(maps.Keys(d.collections))
 }
 
-// Fields returns all field paths for a collection.
+// Fields returns all field paths for a collection, sorted lexicographically.
 func (d *DOCQL) Fields(collectionName string) ([]string, error) {
 	collFields, ok := d.fields[collectionName]
 	if !ok {
-		return nil, fmt.Errorf("collection '%s' not found", collectionName)
-	}
-	paths := make([]string, 0, len(collFields))
-	for path := range collFields {
-		paths = append(paths, path)
+		return nil, &types.UnknownCollectionError{Collection: collectionName}
 	}
-	return paths, nil
+	return slices.Sorted(maps.Keys(collFields)), nil
 }
 
 // GetFieldType returns the DDML type for a field.
@@ -147,7 +396,7 @@ func (d *DOCQL) GetFieldType(collectionName, fieldPath string) (ddml.FieldType,
 			return field.Type, nil
 		}
 	}
-	return "", fmt.Errorf("field '%s' not found in collection '%s'", fieldPath, collectionName)
+	return "", &types.UnknownFieldError{Collection: collectionName, Fields: []string{fieldPath}}
 }
 
 // IsFieldRequired checks if a field is required.
@@ -157,7 +406,428 @@ func (d *DOCQL) IsFieldRequired(collectionName, fieldPath string) (bool, error)
 			return field.Required, nil
 		}
 	}
-	return false, fmt.Errorf("field '%s' not found in collection '%s'", fieldPath, collectionName)
+	return false, &types.UnknownFieldError{Collection: collectionName, Fields: []string{fieldPath}}
+}
+
+// ValidateInsert checks that a document supplies every required field of a collection.
+// A field is satisfied when the document sets it directly or sets any of its
+// sub-fields; required sub-fields are then checked in turn. The error lists all
+// missing paths.
+func (d *DOCQL) ValidateInsert(collectionName string, doc types.Document) error {
+	coll, ok := d.collections[collectionName]
+	if !ok {
+		return &types.UnknownCollectionError{Collection: collectionName}
+	}
+
+	present := make(map[string]bool, len(doc.Fields))
+	for field := range doc.Fields {
+		present[field.Path] = true
+	}
+
+	var missing []string
+	collectMissingFields(coll.Fields, "", present, &missing)
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required fields in collection '%s': %s",
+			collectionName, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func collectMissingFields(fields []*ddml.Field, prefix string, present map[string]bool, missing *[]string) {
+	for _, f := range fields {
+		path := f.Name
+		if prefix != "" {
+			path = prefix + "." + f.Name
+		}
+		if present[path] {
+			continue
+		}
+
+		hasChildren := false
+		for p := range present {
+			if strings.HasPrefix(p, path+".") {
+				hasChildren = true
+				break
+			}
+		}
+
+		if !hasChildren {
+			if f.Required {
+				*missing = append(*missing, path)
+			}
+			continue
+		}
+		if f.Type == ddml.TypeObject {
+			collectMissingFields(f.Fields, path, present, missing)
+		}
+	}
+}
+
+// EnumValues returns the allowed values of an enum-typed field.
+func (d *DOCQL) EnumValues(collectionName, fieldPath string) ([]string, error) {
+	collFields, ok := d.fields[collectionName]
+	if !ok {
+		return nil, &types.UnknownCollectionError{Collection: collectionName}
+	}
+	field, ok := collFields[fieldPath]
+	if !ok {
+		return nil, &types.UnknownFieldError{Collection: collectionName, Fields: []string{fieldPath}}
+	}
+	if field.Type != ddml.TypeEnum || field.EnumRef == nil {
+		return nil, fmt.Errorf("field '%s' in collection '%s' is not an enum", fieldPath, collectionName)
+	}
+	enum, ok := d.enums[*field.EnumRef]
+	if !ok {
+		return nil, fmt.Errorf("enum '%s' not found in schema", *field.EnumRef)
+	}
+	return append([]string(nil), enum.Values...), nil
+}
+
+// Render builds a query under the instance and renders it, annotating the result with the params
+// bound to enum-typed fields so a binding step can validate their values.
+func (d *DOCQL) Render(b *Builder, renderer Renderer) (*types.QueryResult, error) {
+	ast, err := d.Build(b)
+	if err != nil {
+		return nil, err
+	}
+	result, err := hooked(renderer, d.hooks).Render(ast)
+	if err != nil {
+		return nil, err
+	}
+	result.EnumParams = d.enumParams(ast)
+	result.ParamMinimums = paramMinimums(ast)
+	return result, nil
+}
+
+// enumParams collects params compared against, inserted into, or set on enum fields.
+func (d *DOCQL) enumParams(ast *types.DocumentAST) map[string][]string {
+	out := make(map[string][]string)
+	add := func(f types.Field, p types.Param) {
+		if p.Name == "" {
+			return
+		}
+		if values, err := d.EnumValues(ast.Target.Name, f.Path); err == nil {
+			out[p.Name] = values
+		}
+	}
+
+	var walk func(f types.FilterItem)
+	walk = func(f types.FilterItem) {
+		switch filter := f.(type) {
+		case types.FilterCondition:
+			switch filter.Operator {
+			case types.EQ, types.NE, types.IN, types.NotIn:
+				add(filter.Field, filter.Value)
+			}
+		case types.ValuesFilter:
+			for _, v := range filter.Values {
+				add(filter.Field, v)
+			}
+		case types.FilterGroup:
+			for _, c := range filter.Conditions {
+				walk(c)
+			}
+		}
+	}
+
+	if ast.FilterClause != nil {
+		walk(ast.FilterClause)
+	}
+	for _, stage := range ast.Pipeline {
+		if match, ok := stage.(types.MatchStage); ok {
+			walk(match.Filter)
+		}
+	}
+	for _, doc := range ast.Documents {
+		for f, p := range doc.Fields {
+			add(f, p)
+		}
+	}
+	for _, op := range ast.UpdateOps {
+		if op.Operator == types.Set || op.Operator == types.SetOnInsert {
+			for f, p := range op.Fields {
+				add(f, p)
+			}
+		}
+	}
+	for _, op := range ast.Operations {
+		maps.Copy(out, d.enumParams(op))
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// BuildChecked builds the query under the instance and verifies that every field referenced by its
+// filter, projection, sort, documents, updates, distinct field, and pipeline
+// exists in the target collection's schema. Raw types.Field values bypass F();
+// this catches them. Pipeline references are checked until a stage such as
+// $group or $project reshapes the documents, and may name fields earlier
+// stages added. The From collection of a $lookup or $graphLookup must exist,
+// and its foreign fields and sub-pipeline are checked against it. Each
+// operation of a transaction is checked against its own collection.
+func (d *DOCQL) BuildChecked(b *Builder) (*types.DocumentAST, error) {
+	ast, err := d.Build(b)
+	if err != nil {
+		return nil, err
+	}
+	if ast.Operation == types.OpTransaction {
+		for i, op := range ast.Operations {
+			if err := d.checkFields(op); err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+		}
+		return ast, nil
+	}
+	if err := d.checkFields(ast); err != nil {
+		return nil, err
+	}
+	return ast, nil
+}
+
+// checkFields verifies the field references of a single-collection AST.
+func (d *DOCQL) checkFields(ast *types.DocumentAST) error {
+	if _, ok := d.fields[ast.Target.Name]; !ok {
+		return &types.UnknownCollectionError{Collection: ast.Target.Name}
+	}
+
+	var errs []error
+	byCollection := make(map[string]*types.UnknownFieldError)
+	for _, ref := range unknownFields(ast, d.fields) {
+		if ref.Path == "" {
+			errs = append(errs, &types.UnknownCollectionError{Collection: ref.Collection})
+			continue
+		}
+		if e, ok := byCollection[ref.Collection]; ok {
+			e.Fields = append(e.Fields, ref.Path)
+			continue
+		}
+		e := &types.UnknownFieldError{Collection: ref.Collection, Fields: []string{ref.Path}}
+		byCollection[ref.Collection] = e
+		errs = append(errs, e)
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// unknownFields returns the field references of ast that are missing from the
+// schema, deduplicated, in reference order. References in the pipeline are
+// checked until a stage reshapes the documents, and those of a $lookup or
+// $graphLookup against its From collection; a From collection missing from
+// the schema is returned with an empty Path.
+func unknownFields(ast *types.DocumentAST, schema map[string]map[string]*ddml.Field) []FieldRef {
+	if ast.RawEvent {
+		trimmed := *ast
+		trimmed.FilterClause = nil
+		ast = &trimmed
+	}
+	c := &fieldChecker{schema: schema, seen: make(map[FieldRef]bool)}
+	w := &walker{visit: c.visitor(&fieldScope{collection: ast.Target.Name}), prune: true}
+	w.ast(ast)
+	if ast.DistinctField != nil {
+		c.check(*ast.DistinctField, &fieldScope{collection: ast.Target.Name})
+	}
+	return c.unknown
+}
+
+// fieldChecker accumulates field references missing from the schema.
+type fieldChecker struct {
+	schema  map[string]map[string]*ddml.Field
+	seen    map[FieldRef]bool
+	unknown []FieldRef
+}
+
+// fieldScope is what field references resolve against at one point of a
+// query: a collection, the array field of an enclosing $elemMatch, and the
+// fields earlier pipeline stages added. Once a stage reshapes the documents,
+// or when the collection is unknown, references are not checked.
+type fieldScope struct {
+	collection string
+	parent     string
+	added      map[string]bool
+	unchecked  bool
+}
+
+// with returns a copy of s whose added fields can grow independently.
+func (s *fieldScope) with() *fieldScope {
+	out := *s
+	out.added = maps.Clone(s.added)
+	return &out
+}
+
+// add records a field introduced by a pipeline stage.
+func (s *fieldScope) add(name string) {
+	if s.added == nil {
+		s.added = make(map[string]bool)
+	}
+	s.added[name] = true
+}
+
+// introduced reports whether path is, or is inside, a field added by an
+// earlier stage.
+func (s *fieldScope) introduced(path string) bool {
+	for name := range s.added {
+		if path == name || strings.HasPrefix(path, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// from returns the scope of a $lookup or $graphLookup From collection,
+// recording the collection as unknown when the schema lacks it.
+func (c *fieldChecker) from(collection string) *fieldScope {
+	if _, ok := c.schema[collection]; !ok {
+		c.record(FieldRef{Collection: collection})
+		return &fieldScope{collection: collection, unchecked: true}
+	}
+	return &fieldScope{collection: collection}
+}
+
+func (c *fieldChecker) record(ref FieldRef) {
+	if !c.seen[ref] {
+		c.seen[ref] = true
+		c.unknown = append(c.unknown, ref)
+	}
+}
+
+// check records f as unknown unless it exists in the scope's collection.
+// Inside $elemMatch the path may also be relative to the array field.
+func (c *fieldChecker) check(f types.Field, s *fieldScope) {
+	if f.Path == "" || f.Path == types.IDField || s.unchecked || s.introduced(f.Path) {
+		return
+	}
+	known := f.Collection == "" || f.Collection == s.collection
+	if known {
+		fields := c.schema[s.collection]
+		_, known = fields[f.Path]
+		if !known && s.parent != "" {
+			_, known = fields[s.parent+"."+f.Path]
+		}
+	}
+	if !known {
+		c.record(FieldRef{Collection: s.collection, Path: f.Path})
+	}
+}
+
+// visitor returns the Walk visitor checking each node under s. Stages are
+// checked whole, so the scope they leave applies to the stages after them.
+func (c *fieldChecker) visitor(s *fieldScope) func(node interface{}) bool {
+	return func(node interface{}) bool {
+		switch n := node.(type) {
+		case types.ElemMatchFilter:
+			c.check(n.Field, s)
+			inner := s.with()
+			inner.parent = n.Field.Path
+			c.filters(n.Conditions, inner)
+			return false
+		case types.FilterItem:
+			if f, ok := types.FilterField(n); ok {
+				c.check(f, s)
+			}
+		case types.ProjectionField:
+			c.check(n.Field, s)
+			if n.ElemMatch != nil {
+				inner := s.with()
+				inner.parent = n.Field.Path
+				c.filters(n.ElemMatch.Conditions, inner)
+			}
+			return false
+		case types.SortClause:
+			c.check(n.Field, s)
+		case types.Document:
+			for f := range n.Fields {
+				c.check(f, s)
+			}
+		case types.UpdateOperation:
+			for f := range n.Fields {
+				c.check(f, s)
+			}
+		case types.FieldExpression:
+			c.check(n.Field, s)
+		case types.PipelineStage:
+			c.stage(n, s)
+			return false
+		}
+		return true
+	}
+}
+
+// filters checks each of conditions under s.
+func (c *fieldChecker) filters(conditions []types.FilterItem, s *fieldScope) {
+	w := &walker{visit: c.visitor(s), prune: true}
+	for _, f := range conditions {
+		w.filter(f)
+	}
+}
+
+// stage checks a pipeline stage under s and applies its effect on the fields
+// of the documents it outputs.
+func (c *fieldChecker) stage(stage types.PipelineStage, s *fieldScope) {
+	w := &walker{visit: c.visitor(s), prune: true}
+	switch st := stage.(type) {
+	case types.MatchStage:
+		w.filter(st.Filter)
+	case types.SortStage:
+		for _, sort := range st.Sorts {
+			c.check(sort.Field, s)
+		}
+	case types.UnwindStage:
+		c.check(st.Path, s)
+		if st.IncludeArrayIndex != nil {
+			s.add(*st.IncludeArrayIndex)
+		}
+	case types.AddFieldsStage:
+		w.expressions(st.Fields)
+		for name := range st.Fields {
+			s.add(name)
+		}
+	case types.LookupStage:
+		c.check(st.LocalField, s)
+		w.expressions(st.Let)
+		foreign := c.from(st.From)
+		c.check(st.ForeignField, foreign)
+		(&walker{visit: c.visitor(foreign), prune: true}).pipeline(st.Pipeline)
+		s.add(st.As)
+	case types.GraphLookupStage:
+		w.expr(st.StartWith)
+		foreign := c.from(st.From)
+		c.check(st.ConnectFromField, foreign)
+		c.check(st.ConnectToField, foreign)
+		if st.RestrictSearchWithMatch != nil {
+			(&walker{visit: c.visitor(foreign), prune: true}).filter(st.RestrictSearchWithMatch)
+		}
+		s.add(st.As)
+	case types.FacetStage:
+		for _, name := range slices.Sorted(maps.Keys(st.Facets)) {
+			(&walker{visit: c.visitor(s.with()), prune: true}).pipeline(st.Facets[name])
+		}
+		s.unchecked = true
+	case types.ProjectStage:
+		w.projection(st.Projection)
+		w.expressions(st.Computed)
+		s.unchecked = true
+	case types.GroupStage:
+		w.expr(st.ID)
+		w.accumulators(st.Accumulators)
+		s.unchecked = true
+	case types.BucketStage:
+		w.expr(st.GroupBy)
+		w.accumulators(st.Output)
+		s.unchecked = true
+	case types.SortByCountStage:
+		w.expr(st.Expr)
+		s.unchecked = true
+	case types.ReplaceRootStage:
+		w.expr(st.NewRoot)
+		s.unchecked = true
+	case types.CountStage:
+		s.unchecked = true
+	}
 }
 
 // Filter Operator Accessors.
@@ -174,6 +844,7 @@ func (*DOCQL) OpExists() types.FilterOperator        { return types.Exists }
 func (*DOCQL) OpType() types.FilterOperator          { return types.Type }
 func (*DOCQL) OpRegex() types.FilterOperator         { return types.Regex }
 func (*DOCQL) OpText() types.FilterOperator          { return types.Text }
+func (*DOCQL) OpMod() types.FilterOperator           { return types.Mod }
 func (*DOCQL) OpAll() types.FilterOperator           { return types.All }
 func (*DOCQL) OpElemMatch() types.FilterOperator     { return types.ElemMatch }
 func (*DOCQL) OpSize() types.FilterOperator          { return types.Size }
@@ -213,17 +884,19 @@ func (*DOCQL) Desc() types.SortOrder { return types.Descending }
 
 // Operation Accessors.
 
-func (*DOCQL) OperationFind() types.Operation       { return types.OpFind }
-func (*DOCQL) OperationFindOne() types.Operation    { return types.OpFindOne }
-func (*DOCQL) OperationInsert() types.Operation     { return types.OpInsert }
-func (*DOCQL) OperationInsertMany() types.Operation { return types.OpInsertMany }
-func (*DOCQL) OperationUpdate() types.Operation     { return types.OpUpdate }
-func (*DOCQL) OperationUpdateMany() types.Operation { return types.OpUpdateMany }
-func (*DOCQL) OperationDelete() types.Operation     { return types.OpDelete }
-func (*DOCQL) OperationDeleteMany() types.Operation { return types.OpDeleteMany }
-func (*DOCQL) OperationAggregate() types.Operation  { return types.OpAggregate }
-func (*DOCQL) OperationCount() types.Operation      { return types.OpCount }
-func (*DOCQL) OperationDistinct() types.Operation   { return types.OpDistinct }
+func (*DOCQL) OperationFind() types.Operation        { return types.OpFind }
+func (*DOCQL) OperationFindOne() types.Operation     { return types.OpFindOne }
+func (*DOCQL) OperationInsert() types.Operation      { return types.OpInsert }
+func (*DOCQL) OperationInsertMany() types.Operation  { return types.OpInsertMany }
+func (*DOCQL) OperationUpdate() types.Operation      { return types.OpUpdate }
+func (*DOCQL) OperationUpdateMany() types.Operation  { return types.OpUpdateMany }
+func (*DOCQL) OperationDelete() types.Operation      { return types.OpDelete }
+func (*DOCQL) OperationDeleteMany() types.Operation  { return types.OpDeleteMany }
+func (*DOCQL) OperationAggregate() types.Operation   { return types.OpAggregate }
+func (*DOCQL) OperationCount() types.Operation       { return types.OpCount }
+func (*DOCQL) OperationDistinct() types.Operation    { return types.OpDistinct }
+func (*DOCQL) OperationWatch() types.Operation       { return types.OpWatch }
+func (*DOCQL) OperationTransaction() types.Operation { return types.OpTransaction }
 
 // Filter Condition Constructors.
 
@@ -259,6 +932,14 @@ func (d *DOCQL) Nin(field types.Field, value types.Param) types.FilterCondition
 	return types.FilterCondition{Field: field, Operator: types.NotIn, Value: value}
 }
 
+func (d *DOCQL) InValues(field types.Field, params ...types.Param) types.ValuesFilter {
+	return types.ValuesFilter{Field: field, Operator: types.IN, Values: params}
+}
+
+func (d *DOCQL) NinValues(field types.Field, params ...types.Param) types.ValuesFilter {
+	return types.ValuesFilter{Field: field, Operator: types.NotIn, Values: params}
+}
+
 func (d *DOCQL) Exists(field types.Field) types.ExistsFilter {
 	return types.ExistsFilter{Field: field, Exists: true}
 }
@@ -267,6 +948,22 @@ func (d *DOCQL) NotExists(field types.Field) types.ExistsFilter {
 	return types.ExistsFilter{Field: field, Exists: false}
 }
 
+func (d *DOCQL) IsNull(field types.Field) types.NullFilter {
+	return types.NullFilter{Field: field}
+}
+
+func (d *DOCQL) IsNotNull(field types.Field) types.NullFilter {
+	return types.NullFilter{Field: field, Negated: true}
+}
+
+func (d *DOCQL) Mod(field types.Field, divisor, remainder types.Param) types.ModFilter {
+	return types.ModFilter{Field: field, Divisor: divisor, Remainder: remainder}
+}
+
+func (d *DOCQL) TypeIs(field types.Field, bsonType string) types.TypeFilter {
+	return types.TypeFilter{Field: field, TypeName: bsonType}
+}
+
 func (d *DOCQL) Regex(field types.Field, pattern types.Param) types.RegexFilter {
 	return types.RegexFilter{Field: field, Pattern: pattern}
 }
@@ -345,81 +1042,3 @@ func (*DOCQL) Documents() []types.Document {
 func (*DOCQL) Accumulators() map[string]types.Accumulator {
 	return make(map[string]types.Accumulator)
 }
-
-// Identifier Validation.
-
-var suspiciousPatterns = []string{
-	";", "--", "/*", "*/", "'", "\"", "`", "\\",
-	" or ", " and ", "drop ", "delete ", "insert ",
-	"update ", "select ", "union ", "exec ", "execute ",
-}
-
-func isValidIdentifier(s string) bool {
-	if s == "" {
-		return false
-	}
-
-	// Explicit space rejection as defense-in-depth
-	if strings.Contains(s, " ") {
-		return false
-	}
-
-	for i, r := range s {
-		if i == 0 {
-			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && r != '_' {
-				return false
-			}
-		} else {
-			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
-				return false
-			}
-		}
-	}
-
-	lower := strings.ToLower(s)
-	for _, pattern := range suspiciousPatterns {
-		if strings.Contains(lower, pattern) {
-			return false
-		}
-	}
-
-	return true
-}
-
-func isValidFieldPath(s string) bool {
-	if s == "" {
-		return false
-	}
-
-	// Explicit space rejection as defense-in-depth
-	if strings.Contains(s, " ") {
-		return false
-	}
-
-	parts := strings.Split(s, ".")
-	for _, part := range parts {
-		if part == "" {
-			return false
-		}
-		for i, r := range part {
-			if i == 0 {
-				if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && r != '_' && r != '$' {
-					return false
-				}
-			} else {
-				if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
-					return false
-				}
-			}
-		}
-	}
-
-	lower := strings.ToLower(s)
-	for _, pattern := range suspiciousPatterns {
-		if strings.Contains(lower, pattern) {
-			return false
-		}
-	}
-
-	return true
-}
@@ -1,12 +1,24 @@
 package docql_test
 
 import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/zoobzio/ddml"
 	"github.com/zoobzio/docql"
 	"github.com/zoobzio/docql/internal/types"
+	"github.com/zoobzio/docql/pkg/arangodb"
+	"github.com/zoobzio/docql/pkg/cosmosdb"
+	"github.com/zoobzio/docql/pkg/couchdb"
+	"github.com/zoobzio/docql/pkg/dynamodb"
+	"github.com/zoobzio/docql/pkg/firestore"
+	"github.com/zoobzio/docql/pkg/kv"
 	"github.com/zoobzio/docql/pkg/mongodb"
+	"github.com/zoobzio/docql/pkg/postgres"
+	"github.com/zoobzio/docql/pkg/redisearch"
 )
 
 func createTestInstance(t *testing.T) *docql.DOCQL {
@@ -133,6 +145,33 @@ func TestTryF_InvalidField(t *testing.T) {
 	}
 }
 
+func TestTry_InvalidSchemaNames(t *testing.T) {
+	schema := ddml.NewSchema("test_db")
+	coll := ddml.NewCollection("users")
+	coll.AddField(ddml.NewField("name", ddml.TypeString))
+	coll.AddField(ddml.NewField("name;drop", ddml.TypeString))
+	coll.AddField(ddml.NewField("Select Star", ddml.TypeString))
+	schema.AddCollection(coll)
+	schema.AddCollection(ddml.NewCollection("logs--old"))
+
+	instance, err := docql.NewFromDDML(schema)
+	if err != nil {
+		t.Fatalf("Failed to create instance: %v", err)
+	}
+
+	if _, err := instance.TryF("users", "name"); err != nil {
+		t.Errorf("TryF(name): unexpected error: %v", err)
+	}
+	for _, path := range []string{"name;drop", "Select Star"} {
+		if _, err := instance.TryF("users", path); !errors.Is(err, docql.ErrInvalidIdentifier) {
+			t.Errorf("TryF(%q): expected invalid identifier error, got %v", path, err)
+		}
+	}
+	if _, err := instance.TryC("logs--old"); !errors.Is(err, docql.ErrInvalidIdentifier) {
+		t.Errorf("TryC: expected invalid identifier error, got %v", err)
+	}
+}
+
 func TestF_ValidField(t *testing.T) {
 	instance := createTestInstance(t)
 
@@ -358,3 +397,1707 @@ func TestSecurityValidation_InjectionAttempts(t *testing.T) {
 		})
 	}
 }
+
+func TestWithLimits(t *testing.T) {
+	instance := createTestInstance(t)
+
+	if instance.Limits() != docql.DefaultLimits() {
+		t.Error("Expected instance to start with default limits")
+	}
+
+	limits := docql.DefaultLimits()
+	limits.MaxLimit = 50
+	instance.WithLimits(limits)
+
+	ast := docql.Find(instance.C("users")).Limit(100).MustBuild()
+	if err := instance.Validate(ast); err == nil {
+		t.Error("Expected instance limits to reject limit 100")
+	}
+	if _, err := instance.Build(docql.Find(instance.C("users")).Limit(100)); err == nil {
+		t.Error("Expected instance Build to apply instance limits")
+	}
+
+	limits.MaxLimit = docql.MaxLimit * 2
+	instance.WithLimits(limits)
+	ast = docql.Find(instance.C("users")).WithLimits(instance.Limits()).Limit(docql.MaxLimit + 1).MustBuild()
+	if err := instance.Validate(ast); err != nil {
+		t.Errorf("Expected raised instance limits to allow limit, got: %v", err)
+	}
+}
+
+func TestValidateInsert(t *testing.T) {
+	schema := ddml.NewSchema("test_db")
+	users := ddml.NewCollection("users")
+	users.AddField(ddml.NewField("email", ddml.TypeString).WithRequired())
+	users.AddField(ddml.NewField("name", ddml.TypeString))
+	users.AddField(ddml.NewObjectField("address").
+		AddField(ddml.NewField("city", ddml.TypeString).WithRequired()).
+		AddField(ddml.NewField("zip", ddml.TypeString)))
+	schema.AddCollection(users)
+
+	instance, err := docql.NewFromDDML(schema)
+	if err != nil {
+		t.Fatalf("Failed to create instance: %v", err)
+	}
+
+	valid := docql.Doc().
+		Set(instance.F("users", "email"), instance.P("email")).
+		Build()
+	if err := instance.ValidateInsert("users", valid); err != nil {
+		t.Errorf("Expected no error when required field present, got: %v", err)
+	}
+
+	missing := docql.Doc().
+		Set(instance.F("users", "name"), instance.P("name")).
+		Build()
+	err = instance.ValidateInsert("users", missing)
+	if err == nil {
+		t.Fatal("Expected error when required email is omitted")
+	}
+	if !strings.Contains(err.Error(), "email") {
+		t.Errorf("Expected error to list email, got: %v", err)
+	}
+
+	partial := docql.Doc().
+		Set(instance.F("users", "email"), instance.P("email")).
+		Set(instance.F("users", "address.zip"), instance.P("zip")).
+		Build()
+	err = instance.ValidateInsert("users", partial)
+	if err == nil || !strings.Contains(err.Error(), "address.city") {
+		t.Errorf("Expected error listing address.city, got: %v", err)
+	}
+
+	if err := instance.ValidateInsert("missing", valid); err == nil {
+		t.Error("Expected error for unknown collection")
+	}
+}
+
+func TestBuildChecked(t *testing.T) {
+	instance := createTestInstance(t)
+	users := instance.C("users")
+
+	ast, err := instance.BuildChecked(docql.Find(users).
+		Filter(instance.Eq(instance.F("users", "status"), instance.P("status"))).
+		SortAsc(instance.F("users", "username")))
+	if err != nil {
+		t.Fatalf("Expected no error for schema fields, got: %v", err)
+	}
+	if ast.Target.Name != "users" {
+		t.Errorf("Expected target users, got %s", ast.Target.Name)
+	}
+
+	typo := types.Field{Path: "stauts", Collection: "users"}
+	_, err = instance.BuildChecked(docql.Find(users).
+		Filter(docql.Eq(typo, instance.P("status"))).
+		Select(types.Field{Path: "emial"}))
+	if err == nil {
+		t.Fatal("Expected error for typo'd field paths")
+	}
+	if !strings.Contains(err.Error(), "stauts") || !strings.Contains(err.Error(), "emial") {
+		t.Errorf("Expected error to list both unknown fields, got: %v", err)
+	}
+
+	_, err = instance.BuildChecked(docql.Update(users).
+		Filter(instance.Eq(instance.F("users", "status"), instance.P("status"))).
+		Set(instance.F("posts", "title"), instance.P("title")))
+	if err == nil {
+		t.Error("Expected error for field from another collection")
+	}
+}
+
+func TestBuildChecked_Pipeline(t *testing.T) {
+	instance := createTestInstance(t)
+	users := instance.C("users")
+	status := instance.F("users", "status")
+
+	_, err := instance.BuildChecked(docql.Aggregate(users).
+		Match(docql.Eq(types.Field{Path: "stauts"}, instance.P("status"))).
+		Lookup("posts", instance.F("users", "_id"), types.Field{Path: "userID"}, "posts").
+		Unwind(types.Field{Path: "posts"}).
+		Sort(types.Field{Path: "emial"}, types.Ascending).
+		Group(docql.FieldExpr(status), map[string]types.Accumulator{"n": docql.Sum(docql.FieldExpr(types.Field{Path: "actve"}))}).
+		Sort(types.Field{Path: "n"}, types.Descending))
+	var unknown *docql.UnknownFieldError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("Expected unknown field errors, got: %v", err)
+	}
+	if got := err.Error(); !strings.Contains(got, "userID") || !strings.Contains(got, "'posts'") {
+		t.Errorf("Expected the foreign field to be checked against posts, got: %v", err)
+	}
+	for _, path := range []string{"stauts", "emial", "actve"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("Expected %q to be reported, got: %v", path, err)
+		}
+	}
+	if strings.Contains(err.Error(), "'n'") || strings.Contains(err.Error(), "'posts' not found in collection 'users'") {
+		t.Errorf("Expected fields added by stages to be accepted, got: %v", err)
+	}
+
+	_, err = instance.BuildChecked(docql.Aggregate(users).
+		Lookup("comments", instance.F("users", "_id"), types.Field{Path: "userId"}, "comments"))
+	if !errors.Is(err, docql.ErrUnknownCollection) {
+		t.Errorf("Expected an unknown lookup collection, got: %v", err)
+	}
+
+	_, err = instance.BuildChecked(docql.Aggregate(users).
+		Match(instance.Eq(status, instance.P("status"))).
+		AddFields(map[string]types.Expression{"label": docql.FieldExpr(instance.F("users", "username"))}).
+		Lookup("posts", instance.F("users", "_id"), instance.F("posts", "userId"), "posts").
+		Sort(types.Field{Path: "label"}, types.Ascending).
+		Sort(types.Field{Path: "posts.title"}, types.Ascending))
+	if err != nil {
+		t.Errorf("Expected a valid pipeline to pass, got: %v", err)
+	}
+}
+
+func createEnumInstance(t *testing.T) *docql.DOCQL {
+	t.Helper()
+
+	schema := ddml.NewSchema("test_db").
+		AddEnum(ddml.NewEnum("status", "active", "inactive")).
+		AddCollection(ddml.NewCollection("users").
+			AddField(ddml.NewField("status", ddml.TypeEnum).WithEnumRef("status")).
+			AddField(ddml.NewField("email", ddml.TypeString)))
+
+	instance, err := docql.NewFromDDML(schema)
+	if err != nil {
+		t.Fatalf("Failed to create instance: %v", err)
+	}
+	return instance
+}
+
+func TestEnumValues(t *testing.T) {
+	instance := createEnumInstance(t)
+
+	values, err := instance.EnumValues("users", "status")
+	if err != nil {
+		t.Fatalf("Expected no error for enum field, got: %v", err)
+	}
+	if len(values) != 2 || values[0] != "active" || values[1] != "inactive" {
+		t.Errorf("Expected [active inactive], got %v", values)
+	}
+
+	if _, err := instance.EnumValues("users", "email"); err == nil {
+		t.Error("Expected error for non-enum field")
+	}
+	if _, err := instance.EnumValues("users", "missing"); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+}
+
+func TestRender_EnumParams(t *testing.T) {
+	instance := createEnumInstance(t)
+
+	query := docql.Find(instance.C("users")).
+		Filter(instance.And(
+			instance.Eq(instance.F("users", "status"), instance.P("status")),
+			instance.Eq(instance.F("users", "email"), instance.P("email")),
+		))
+
+	result, err := instance.Render(query, mongodb.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(result.EnumParams) != 1 {
+		t.Fatalf("Expected 1 enum param, got %v", result.EnumParams)
+	}
+	if values := result.EnumParams["status"]; len(values) != 2 {
+		t.Errorf("Expected status to carry enum values, got %v", values)
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	schema := ddml.NewSchema("test_db").
+		AddCollection(ddml.NewCollection("users").
+			AddField(ddml.NewField("email", ddml.TypeString)))
+
+	instance, err := docql.NewFromDDML(schema, docql.WithStrictMode())
+	if err != nil {
+		t.Fatalf("Failed to create instance: %v", err)
+	}
+
+	if instance.Err() != nil {
+		t.Fatalf("Expected no recorded errors, got: %v", instance.Err())
+	}
+
+	// A bad reference must not panic in strict mode.
+	field := instance.F("users", "emial")
+	if field.Path != "" || field.Err() == nil {
+		t.Errorf("Expected an invalid Field carrying the error, got %+v", field)
+	}
+
+	query := docql.Find(instance.C("users")).
+		Filter(instance.Eq(field, instance.P("email")))
+
+	_, err = instance.Build(query)
+	if err == nil {
+		t.Fatal("Expected Build to fail with recorded reference error")
+	}
+	if !strings.Contains(err.Error(), "emial") {
+		t.Errorf("Expected error to name the missing field, got: %v", err)
+	}
+	if instance.Err() == nil {
+		t.Error("Expected Err() to report the recorded error")
+	}
+
+	if _, err := instance.Render(query, mongodb.New()); err == nil {
+		t.Error("Expected Render to fail with recorded reference error")
+	}
+}
+
+func TestStrictMode_ErrorStaysWithQuery(t *testing.T) {
+	schema := ddml.NewSchema("test_db").
+		AddCollection(ddml.NewCollection("users").
+			AddField(ddml.NewField("email", ddml.TypeString)))
+
+	instance, err := docql.NewFromDDML(schema, docql.WithStrictMode())
+	if err != nil {
+		t.Fatalf("Failed to create instance: %v", err)
+	}
+
+	bad := instance.Find("users").Filter(instance.Eq(instance.F("users", "nope"), instance.P("x")))
+	if _, err := bad.Build(); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("Expected the builder to fail with the unknown field, got %v", err)
+	}
+	if _, err := bad.Render(mongodb.New()); err == nil {
+		t.Error("Expected Render to fail rather than emit an empty field path")
+	}
+
+	good := instance.Find("users").Filter(instance.Eq(instance.F("users", "email"), instance.P("email")))
+	if _, err := instance.Render(good, mongodb.New()); err != nil {
+		t.Errorf("Expected a valid query to render after an invalid one, got %v", err)
+	}
+
+	if _, err := docql.Find(instance.C("nope")).Build(); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("Expected an unknown collection to fail the build, got %v", err)
+	}
+	if _, err := instance.Find("users").Filter(instance.Eq(instance.F("users", "email"), instance.P("1x"))).Build(); err == nil || !strings.Contains(err.Error(), "1x") {
+		t.Errorf("Expected an invalid param to fail the build, got %v", err)
+	}
+}
+
+func TestNonStrictModePanics(t *testing.T) {
+	instance := createTestInstance(t)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for invalid field outside strict mode")
+		}
+	}()
+	instance.F("users", "missing")
+}
+
+func TestIndexFields_NestedArrays(t *testing.T) {
+	item := ddml.NewObjectField("item").
+		AddField(ddml.NewField("sku", ddml.TypeString)).
+		AddField(ddml.NewObjectField("price").
+			AddField(ddml.NewField("amount", ddml.TypeFloat)))
+	order := ddml.NewObjectField("order").
+		AddField(ddml.NewField("total", ddml.TypeFloat)).
+		AddField(ddml.NewArrayField("items", item))
+	matrix := ddml.NewArrayField("matrix", ddml.NewArrayField("row",
+		ddml.NewObjectField("cell").AddField(ddml.NewField("value", ddml.TypeInt))))
+
+	schema := ddml.NewSchema("test_db").
+		AddCollection(ddml.NewCollection("users").
+			AddField(ddml.NewArrayField("orders", order)).
+			AddField(ddml.NewObjectField("profile").
+				AddField(ddml.NewArrayField("addresses", ddml.NewObjectField("address").
+					AddField(ddml.NewField("city", ddml.TypeString))))).
+			AddField(matrix))
+
+	instance, err := docql.NewFromDDML(schema)
+	if err != nil {
+		t.Fatalf("Failed to create instance: %v", err)
+	}
+
+	leaves := []string{
+		"orders",
+		"orders.total",
+		"orders.items",
+		"orders.items.sku",
+		"orders.items.price",
+		"orders.items.price.amount",
+		"profile.addresses.city",
+		"matrix.value",
+	}
+	for _, path := range leaves {
+		if _, err := instance.TryF("users", path); err != nil {
+			t.Errorf("Expected %s to resolve, got: %v", path, err)
+		}
+	}
+
+	fields, err := instance.Fields("users")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	indexed := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		indexed[f] = true
+	}
+	for _, path := range leaves {
+		if !indexed[path] {
+			t.Errorf("Expected Fields() to include %s", path)
+		}
+	}
+}
+
+func TestFields_Sorted(t *testing.T) {
+	instance := createTestInstance(t)
+
+	first, err := instance.Fields("users")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !slices.IsSorted(first) {
+		t.Errorf("Expected sorted fields, got %v", first)
+	}
+	for i := 0; i < 20; i++ {
+		fields, err := instance.Fields("users")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !slices.Equal(first, fields) {
+			t.Fatalf("Expected stable field order, got %v then %v", first, fields)
+		}
+	}
+}
+
+func TestCollections_Sorted(t *testing.T) {
+	schema := ddml.NewSchema("test_db").
+		AddCollection(ddml.NewCollection("users").AddField(ddml.NewField("_id", ddml.TypeObjectID))).
+		AddCollection(ddml.NewCollection("accounts").AddField(ddml.NewField("_id", ddml.TypeObjectID))).
+		AddCollection(ddml.NewCollection("posts").AddField(ddml.NewField("_id", ddml.TypeObjectID))).
+		AddCollection(ddml.NewCollection("comments").AddField(ddml.NewField("_id", ddml.TypeObjectID)))
+
+	instance, err := docql.NewFromDDML(schema)
+	if err != nil {
+		t.Fatalf("Failed to create instance: %v", err)
+	}
+
+	expected := []string{"accounts", "comments", "posts", "users"}
+	for i := 0; i < 20; i++ {
+		if got := instance.Collections(); !slices.Equal(got, expected) {
+			t.Fatalf("Expected %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestCaseInsensitiveLookup(t *testing.T) {
+	schema := ddml.NewSchema("test_db").
+		AddCollection(ddml.NewCollection("users").
+			AddField(ddml.NewField("emailAddress", ddml.TypeString)).
+			AddField(ddml.NewObjectField("profile").
+				AddField(ddml.NewField("displayName", ddml.TypeString))))
+
+	strictCase, err := docql.NewFromDDML(schema)
+	if err != nil {
+		t.Fatalf("Failed to create instance: %v", err)
+	}
+	if _, err := strictCase.TryC("Users"); err == nil {
+		t.Error("Expected case-sensitive lookup to reject Users")
+	}
+
+	lenient, err := docql.NewFromDDML(schema, docql.WithCaseInsensitiveLookup())
+	if err != nil {
+		t.Fatalf("Failed to create instance: %v", err)
+	}
+
+	coll := lenient.C("Users")
+	if coll.Name != "users" {
+		t.Errorf("Expected canonical collection users, got %s", coll.Name)
+	}
+
+	field := lenient.F("USERS", "Profile.DisplayName")
+	if field.Path != "profile.displayName" || field.Collection != "users" {
+		t.Errorf("Expected canonical field users.profile.displayName, got %s.%s", field.Collection, field.Path)
+	}
+
+	if _, err := lenient.TryF("users", "missing"); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+}
+
+func TestCaseInsensitiveLookup_Ambiguous(t *testing.T) {
+	schema := ddml.NewSchema("test_db").
+		AddCollection(ddml.NewCollection("users").
+			AddField(ddml.NewField("Name", ddml.TypeString)).
+			AddField(ddml.NewField("name", ddml.TypeString)))
+
+	instance, err := docql.NewFromDDML(schema, docql.WithCaseInsensitiveLookup())
+	if err != nil {
+		t.Fatalf("Failed to create instance: %v", err)
+	}
+
+	if _, err := instance.TryF("users", "NAME"); err == nil {
+		t.Error("Expected error for ambiguous folded field name")
+	}
+	if f, err := instance.TryF("users", "Name"); err != nil || f.Path != "Name" {
+		t.Errorf("Expected exact match to resolve, got %v, %v", f, err)
+	}
+}
+
+func TestUnsupportedError_AcrossRenderers(t *testing.T) {
+	instance := createTestInstance(t)
+	status := instance.F("users", "status")
+	username := instance.F("users", "username")
+
+	tests := []struct {
+		name      string
+		renderer  docql.Renderer
+		query     *docql.Builder
+		provider  string
+		operation docql.Operation
+	}{
+		{"dynamodb operation", dynamodb.New(), docql.Aggregate(instance.C("users")).Match(instance.Eq(status, instance.P("status"))), "DynamoDB", docql.OpAggregate},
+		{"couchdb update", couchdb.New(), docql.Update(instance.C("users")).Filter(instance.Eq(status, instance.P("status"))).Inc(username, instance.P("n")), "CouchDB", docql.OpUpdate},
+		{"firestore or", firestore.New(), docql.Find(instance.C("users")).Filter(instance.Or(instance.Eq(status, instance.P("a")), instance.Eq(username, instance.P("b")))), "firestore", docql.OpFind},
+		{"cosmosdb operation", cosmosdb.New(), docql.Insert(instance.C("users")).Document(types.Document{Fields: map[types.Field]types.Param{status: instance.P("status")}}), "cosmosdb", docql.OpInsert},
+		{"arangodb stage", arangodb.New(), docql.Aggregate(instance.C("users")).Unwind(username), "arangodb", docql.OpAggregate},
+		{"redisearch regex", redisearch.New(), docql.Find(instance.C("users")).Filter(instance.Regex(username, instance.P("pattern"))), "redisearch", docql.OpFind},
+		{"postgres operation", postgres.New(), docql.Aggregate(instance.C("users")).Match(instance.Eq(status, instance.P("status"))), "postgres", docql.OpAggregate},
+		{"kv filter", kv.New(), docql.Find(instance.C("users")).Filter(instance.Eq(status, instance.P("status"))), "kv", docql.OpFind},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.query.Render(tt.renderer)
+			var unsupported *docql.UnsupportedError
+			if !errors.As(err, &unsupported) {
+				t.Fatalf("expected *UnsupportedError, got %T: %v", err, err)
+			}
+			if unsupported.Provider != tt.provider {
+				t.Errorf("Provider = %q, want %q", unsupported.Provider, tt.provider)
+			}
+			if unsupported.Operation != tt.operation {
+				t.Errorf("Operation = %q, want %q", unsupported.Operation, tt.operation)
+			}
+		})
+	}
+}
+
+func TestDOCQL_Distinct(t *testing.T) {
+	instance := createTestInstance(t)
+
+	result, err := instance.Render(instance.Distinct("users", "status"), mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(result.JSON, `"field":"status"`) {
+		t.Errorf("Expected distinct field status, got %s", result.JSON)
+	}
+	if strings.Contains(result.JSON, `"filter"`) || len(result.RequiredParams) != 0 {
+		t.Errorf("Expected no filter or params, got %s %v", result.JSON, result.RequiredParams)
+	}
+
+	query := instance.Distinct("users", "status").
+		Filter(instance.Eq(instance.F("users", "active"), instance.P("active")))
+	result, err = instance.Render(query, mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(result.JSON, `"filter":{"active":{"$eq":":active"}}`) {
+		t.Errorf("Expected active filter, got %s", result.JSON)
+	}
+	if !slices.Equal(result.RequiredParams, []string{"active"}) {
+		t.Errorf("Expected params [active], got %v", result.RequiredParams)
+	}
+}
+
+func TestDOCQL_Distinct_InvalidField(t *testing.T) {
+	instance := createTestInstance(t)
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic for field not in collection")
+		}
+	}()
+	instance.Distinct("users", "title")
+}
+
+func TestDOCQL_Distinct_NestedSortLimit(t *testing.T) {
+	schema := ddml.NewSchema("test_db")
+	users := ddml.NewCollection("users")
+	users.AddField(ddml.NewField("status", ddml.TypeString))
+	users.AddField(ddml.NewObjectField("address").
+		AddField(ddml.NewField("city", ddml.TypeString)))
+	schema.AddCollection(users)
+
+	instance, err := docql.NewFromDDML(schema)
+	if err != nil {
+		t.Fatalf("Failed to create instance: %v", err)
+	}
+
+	result, err := instance.Render(instance.Distinct("users", "address.city"), mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(result.JSON, `"field":"address.city"`) || strings.Contains(result.JSON, `"pipeline"`) {
+		t.Errorf("Expected a plain distinct on address.city, got %s", result.JSON)
+	}
+
+	city := instance.F("users", "address.city")
+	query := instance.Distinct("users", "address.city").
+		Filter(instance.Eq(instance.F("users", "status"), instance.P("status"))).
+		SortAsc(city).
+		Limit(10)
+
+	tests := []struct {
+		name     string
+		renderer docql.Renderer
+		want     string
+	}{
+		{"mongodb", mongodb.New(), `"pipeline":[{"$match":{"status":{"$eq":":status"}}},{"$group":{"_id":"$address.city"}},{"$sort":{"_id":1}},{"$limit":10}]`},
+		{"arangodb", arangodb.New(), "COLLECT value = d.address.city SORT value ASC LIMIT 10 RETURN value"},
+		{"postgres", postgres.New(), `ORDER BY data#\u003e'{address,city}' ASC LIMIT 10`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := instance.Render(query, tt.renderer)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if !strings.Contains(result.JSON, tt.want) {
+				t.Errorf("Expected %s in %s", tt.want, result.JSON)
+			}
+		})
+	}
+
+	if _, err := instance.Render(query, cosmosdb.New()); err == nil || !strings.Contains(err.Error(), "sort or limit on DISTINCT") {
+		t.Errorf("Expected cosmosdb to reject a sorted distinct, got %v", err)
+	}
+
+	bySort := instance.Distinct("users", "address.city").SortAsc(instance.F("users", "status"))
+	if _, err := instance.Render(bySort, mongodb.New()); err == nil || !strings.Contains(err.Error(), "DISTINCT can only sort by its field") {
+		t.Errorf("Expected a sort on another field to be rejected, got %v", err)
+	}
+}
+
+func TestSupportsPipelineStage_GraphLookup(t *testing.T) {
+	renderers := map[string]docql.Renderer{
+		"mongodb":    mongodb.New(),
+		"dynamodb":   dynamodb.New(),
+		"firestore":  firestore.New(),
+		"couchdb":    couchdb.New(),
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+		"kv":         kv.New(),
+	}
+	for name, r := range renderers {
+		if got, want := r.SupportsPipelineStage("$graphLookup"), name == "mongodb"; got != want {
+			t.Errorf("%s: SupportsPipelineStage($graphLookup) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestStructuredErrors_Instance(t *testing.T) {
+	instance := createTestInstance(t)
+
+	_, err := instance.TryC("missing")
+	var unknownColl *docql.UnknownCollectionError
+	if !errors.As(err, &unknownColl) || unknownColl.Collection != "missing" {
+		t.Errorf("TryC: expected *UnknownCollectionError for missing, got %v", err)
+	}
+	if !errors.Is(err, docql.ErrUnknownCollection) {
+		t.Error("TryC: expected errors.Is ErrUnknownCollection")
+	}
+
+	_, err = instance.TryF("users", "nope")
+	var unknownField *docql.UnknownFieldError
+	if !errors.As(err, &unknownField) || unknownField.Collection != "users" || !slices.Equal(unknownField.Fields, []string{"nope"}) {
+		t.Errorf("TryF: expected *UnknownFieldError for users.nope, got %v", err)
+	}
+	if !errors.Is(err, docql.ErrUnknownField) {
+		t.Error("TryF: expected errors.Is ErrUnknownField")
+	}
+
+	_, err = instance.TryP("bad-name")
+	var invalid *docql.InvalidIdentifierError
+	if !errors.As(err, &invalid) || invalid.Kind != "parameter name" || invalid.Value != "bad-name" {
+		t.Errorf("TryP: expected *InvalidIdentifierError, got %v", err)
+	}
+	if !errors.Is(err, docql.ErrInvalidIdentifier) {
+		t.Error("TryP: expected errors.Is ErrInvalidIdentifier")
+	}
+
+	_, err = instance.BuildChecked(docql.Find(instance.C("users")).Filter(
+		docql.Eq(types.Field{Path: "stauts"}, types.Param{Name: "s"}),
+	))
+	if !errors.As(err, &unknownField) || !slices.Equal(unknownField.Fields, []string{"stauts"}) {
+		t.Errorf("BuildChecked: expected *UnknownFieldError, got %v", err)
+	}
+}
+
+func TestStructuredErrors_Renderers(t *testing.T) {
+	instance := createTestInstance(t)
+	status := instance.F("users", "status")
+	overLimit := types.DefaultLimits().MaxLimit + 1
+
+	// Bypass the builder's immediate check so the renderer's validation fails.
+	ast := docql.Find(instance.C("users")).MustBuild()
+	ast.Limit = &types.PaginationValue{Static: &overLimit}
+
+	renderers := map[string]docql.Renderer{
+		"mongodb":   mongodb.New(),
+		"dynamodb":  dynamodb.New(),
+		"couchdb":   couchdb.New(),
+		"firestore": firestore.New(),
+	}
+	for name, r := range renderers {
+		_, err := r.Render(ast)
+		if !errors.Is(err, docql.ErrInvalidAST) {
+			t.Errorf("%s: expected ErrInvalidAST, got %v", name, err)
+		}
+		var exceeded *docql.LimitExceededError
+		if !errors.As(err, &exceeded) || exceeded.Limit != "MaxLimit" || exceeded.Value != overLimit {
+			t.Errorf("%s: expected *LimitExceededError in chain, got %v", name, err)
+		}
+	}
+
+	deleteAll := &types.DocumentAST{Operation: types.OpDeleteMany, Target: instance.C("users")}
+	for name, r := range renderers {
+		_, err := r.Render(deleteAll)
+		var missing *docql.MissingFilterError
+		if !errors.As(err, &missing) || missing.Operation != types.OpDeleteMany {
+			t.Errorf("%s: expected *MissingFilterError, got %v", name, err)
+		}
+	}
+
+	_, err := docql.Find(instance.C("users")).
+		Filter(instance.Or(instance.Eq(status, instance.P("a")), instance.Eq(status, instance.P("b")))).
+		Render(firestore.New())
+	if !errors.Is(err, docql.ErrUnsupportedFilter) {
+		t.Errorf("firestore: expected ErrUnsupportedFilter, got %v", err)
+	}
+	_, err = docql.Aggregate(instance.C("users")).Match(instance.Eq(status, instance.P("s"))).Render(dynamodb.New())
+	if !errors.Is(err, docql.ErrUnsupportedOperation) {
+		t.Errorf("dynamodb: expected ErrUnsupportedOperation, got %v", err)
+	}
+}
+
+func TestQueryResult_OperationAndCollection(t *testing.T) {
+	instance := createTestInstance(t)
+	id := instance.F("users", "_id")
+
+	queries := []*docql.Builder{
+		docql.Find(instance.C("users")).Filter(instance.Eq(id, instance.P("id"))),
+		docql.FindOne(instance.C("users")).Filter(instance.Eq(id, instance.P("id"))),
+		// RediSearch keys documents by "id" and kv by "_id"; set both.
+		docql.Insert(instance.C("users")).Document(types.Document{Fields: map[types.Field]types.Param{
+			id:           instance.P("id"),
+			{Path: "id"}: instance.P("key"),
+		}}),
+	}
+	renderers := map[string]docql.Renderer{
+		"mongodb":    mongodb.New(),
+		"dynamodb":   dynamodb.New(),
+		"firestore":  firestore.New(),
+		"couchdb":    couchdb.New(),
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+		"kv":         kv.New(),
+	}
+
+	for name, r := range renderers {
+		for _, q := range queries {
+			ast := q.MustBuild()
+			if !r.SupportsOperation(ast.Operation) {
+				continue
+			}
+			result, err := r.Render(ast)
+			if err != nil {
+				t.Errorf("%s %s: unexpected error: %v", name, ast.Operation, err)
+				continue
+			}
+			if result.Operation != ast.Operation || result.Collection != ast.Target.Name {
+				t.Errorf("%s %s: got Operation %q Collection %q", name, ast.Operation, result.Operation, result.Collection)
+			}
+		}
+	}
+}
+
+func TestPaginate_RendersIndependently(t *testing.T) {
+	instance := createTestInstance(t)
+	base := docql.Find(instance.C("users")).
+		Filter(instance.Eq(instance.F("users", "status"), instance.P("status"))).
+		Select(instance.F("users", "username")).
+		SortAsc(instance.F("users", "username"))
+
+	find, count, err := docql.Paginate(base, 2, 25)
+	if err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+
+	findResult, err := find.Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("find render failed: %v", err)
+	}
+	countResult, err := count.Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("count render failed: %v", err)
+	}
+
+	for _, want := range []string{`"skip":25`, `"limit":25`, `"sort":{"username":1}`, `"operation":"FIND"`} {
+		if !strings.Contains(findResult.JSON, want) {
+			t.Errorf("find query missing %s: %s", want, findResult.JSON)
+		}
+	}
+	if countResult.Operation != docql.OpCount {
+		t.Errorf("expected count operation, got %s", countResult.Operation)
+	}
+	for _, unwanted := range []string{"skip", "limit", "sort", "projection"} {
+		if strings.Contains(countResult.JSON, unwanted) {
+			t.Errorf("count query should not contain %s: %s", unwanted, countResult.JSON)
+		}
+	}
+	if !strings.Contains(countResult.JSON, `"status":{"$eq":":status"}`) {
+		t.Errorf("count query should keep the filter: %s", countResult.JSON)
+	}
+}
+
+func TestTransaction_AcrossCollections(t *testing.T) {
+	instance := createTestInstance(t)
+	userID := instance.Eq(instance.F("users", "_id"), instance.P("userId"))
+
+	tx := docql.Transaction().
+		Add(docql.Insert(instance.C("posts")).Document(types.Document{Fields: map[types.Field]types.Param{
+			instance.F("posts", "userId"): instance.P("userId"),
+			instance.F("posts", "title"):  instance.P("title"),
+		}})).
+		Add(docql.Update(instance.C("users")).Filter(userID).Set(instance.F("users", "status"), instance.P("status")))
+
+	if _, err := instance.BuildChecked(tx); err != nil {
+		t.Fatalf("BuildChecked failed: %v", err)
+	}
+	bad := docql.Transaction().Add(docql.Delete(instance.C("users")).Filter(instance.Eq(types.Field{Path: "missing"}, instance.P("m"))))
+	if _, err := instance.BuildChecked(bad); !errors.Is(err, docql.ErrUnknownField) {
+		t.Errorf("expected unknown field error from the transaction's operation, got %v", err)
+	}
+
+	result, err := instance.Render(tx, mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if params := slices.Sorted(slices.Values(result.RequiredParams)); !slices.Equal(params, []string{"status", "title", "userId"}) {
+		t.Errorf("expected each param once, got %v", result.RequiredParams)
+	}
+
+	for name, r := range map[string]docql.Renderer{
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+		"kv":         kv.New(),
+	} {
+		if _, err := tx.Render(r); !errors.Is(err, docql.ErrUnsupportedOperation) {
+			t.Errorf("%s: expected unsupported operation error, got %v", name, err)
+		}
+	}
+}
+
+func TestHint_IgnoredOutsideMongoDB(t *testing.T) {
+	instance := createTestInstance(t)
+	b := instance.Find("users").
+		Filter(instance.Eq(instance.F("users", "status"), instance.P("status"))).
+		Hint("status_1")
+
+	renderers := map[string]docql.Renderer{
+		"dynamodb":   dynamodb.New(),
+		"firestore":  firestore.New(),
+		"couchdb":    couchdb.New(),
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+	}
+	for name, r := range renderers {
+		t.Run(name, func(t *testing.T) {
+			result, err := b.Render(r)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if strings.Contains(result.JSON, "status_1") {
+				t.Errorf("expected no hint in %s", result.JSON)
+			}
+		})
+	}
+}
+
+func TestMod_Renderers(t *testing.T) {
+	instance := createTestInstance(t)
+	b := instance.Find("users").Filter(docql.Mod(instance.F("users", "_id"), instance.P("shards"), instance.P("bucket")))
+
+	renderers := map[string]docql.Renderer{
+		"mongodb":    mongodb.New(),
+		"couchdb":    couchdb.New(),
+		"dynamodb":   dynamodb.New(),
+		"firestore":  firestore.New(),
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+		"kv":         kv.New(),
+	}
+
+	for name, r := range renderers {
+		t.Run(name, func(t *testing.T) {
+			result, err := b.Render(r)
+			if !r.SupportsFilter(types.Mod) {
+				if !errors.Is(err, docql.ErrUnsupportedFilter) {
+					t.Errorf("expected unsupported filter error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if !slices.Contains(result.RequiredParams, "shards") || !slices.Contains(result.RequiredParams, "bucket") {
+				t.Errorf("expected both params, got %v", result.RequiredParams)
+			}
+		})
+	}
+}
+
+func TestTypeIs_Renderers(t *testing.T) {
+	instance := createTestInstance(t)
+	b := instance.Find("users").Filter(instance.TypeIs(instance.F("users", "status"), "string"))
+
+	renderers := map[string]docql.Renderer{
+		"mongodb":    mongodb.New(),
+		"couchdb":    couchdb.New(),
+		"dynamodb":   dynamodb.New(),
+		"firestore":  firestore.New(),
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+		"kv":         kv.New(),
+	}
+
+	for name, r := range renderers {
+		t.Run(name, func(t *testing.T) {
+			result, err := b.Render(r)
+			if !r.SupportsFilter(types.Type) {
+				if !errors.Is(err, docql.ErrUnsupportedFilter) {
+					t.Errorf("expected unsupported filter error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if !strings.Contains(result.JSON, `{"status":{"$type":"string"}}`) {
+				t.Errorf("expected $type filter in %s", result.JSON)
+			}
+		})
+	}
+}
+
+func TestParamCollection(t *testing.T) {
+	instance := createTestInstance(t)
+	posts := docql.ParamCollection(instance.C("posts"), instance.P("tenantPosts"))
+	b := docql.Find(posts).Filter(instance.Eq(instance.F("posts", "title"), instance.P("title")))
+
+	result, err := instance.Render(b, mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(result.JSON, `"collection":":tenantPosts"`) {
+		t.Errorf("expected collection placeholder in %s", result.JSON)
+	}
+	if _, err := instance.BuildChecked(b); err != nil {
+		t.Errorf("expected fields to be checked against the base collection, got %v", err)
+	}
+
+	registry := docql.NewRegistry()
+	if err := registry.Register("posts", b); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if params := registry.List()[0].RequiredParams; !slices.Equal(params, []string{"tenantPosts", "title"}) {
+		t.Errorf("unexpected registry params: %v", params)
+	}
+
+	static, err := docql.Find(instance.C("posts")).Filter(instance.Eq(instance.F("posts", "title"), instance.P("title"))).Fingerprint()
+	if err != nil {
+		t.Fatalf("Fingerprint failed: %v", err)
+	}
+	if fp, _ := b.Fingerprint(); fp == static {
+		t.Error("expected parameterized collection to change the fingerprint")
+	}
+
+	renderers := map[string]docql.Renderer{
+		"couchdb":    couchdb.New(),
+		"dynamodb":   dynamodb.New(),
+		"firestore":  firestore.New(),
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+		"kv":         kv.New(),
+	}
+	for name, r := range renderers {
+		t.Run(name, func(t *testing.T) {
+			if _, err := b.Render(r); !errors.Is(err, docql.ErrUnsupportedOperation) {
+				t.Errorf("expected parameterized collection to be unsupported, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWatch_Renderers(t *testing.T) {
+	instance := createTestInstance(t)
+	b := instance.Watch("users").
+		Filter(instance.Eq(instance.F("users", "status"), instance.P("status"))).
+		FullDocument("updateLookup")
+
+	result, err := b.Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := `{"collection":"users","database":"test_db","operation":"WATCH","options":{"fullDocument":"updateLookup"},"pipeline":[{"$match":{"fullDocument.status":{"$eq":":status"}}}]}`
+	if result.JSON != want {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, want)
+	}
+
+	// Document fields are checked against the schema; raw event fields are not.
+	if _, err := instance.BuildChecked(b); err != nil {
+		t.Errorf("BuildChecked failed: %v", err)
+	}
+	raw := instance.Watch("users").RawEvent().Filter(instance.Eq(types.Field{Path: "operationType"}, instance.P("op")))
+	if _, err := instance.BuildChecked(raw); err != nil {
+		t.Errorf("BuildChecked failed for raw event filter: %v", err)
+	}
+
+	renderers := map[string]docql.Renderer{
+		"couchdb":    couchdb.New(),
+		"dynamodb":   dynamodb.New(),
+		"firestore":  firestore.New(),
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+		"kv":         kv.New(),
+	}
+	for name, r := range renderers {
+		t.Run(name, func(t *testing.T) {
+			if r.SupportsOperation(docql.OpWatch) {
+				t.Errorf("%s should not support WATCH", name)
+			}
+			if _, err := b.Render(r); !errors.Is(err, docql.ErrUnsupportedOperation) {
+				t.Errorf("expected unsupported operation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestInsertMany_NamespacesSharedParams(t *testing.T) {
+	instance := createTestInstance(t)
+	email := instance.F("users", "email")
+	username := instance.F("users", "username")
+
+	b := docql.InsertMany(instance.C("users"))
+	for range 3 {
+		b.Document(docql.Doc().Set(email, instance.P("email")).Set(username, instance.P("username")).Build())
+	}
+
+	want := []string{"email_0", "email_1", "email_2", "username_0", "username_1", "username_2"}
+	for name, r := range map[string]docql.Renderer{"mongodb": mongodb.New(), "couchdb": couchdb.New()} {
+		t.Run(name, func(t *testing.T) {
+			result, err := b.Render(r)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if got := slices.Sorted(slices.Values(result.RequiredParams)); !slices.Equal(got, want) {
+				t.Errorf("expected params %v, got %v", want, got)
+			}
+			for _, p := range want {
+				if strings.Count(result.JSON, `":`+p+`"`) != 1 {
+					t.Errorf("expected %s bound exactly once in %s", p, result.JSON)
+				}
+			}
+		})
+	}
+
+	// Document order determines the index, and the builder's documents are
+	// left as written.
+	ast, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if p := ast.Documents[2].Fields[email]; p.Name != "email_2" {
+		t.Errorf("expected email_2 for the third document, got %s", p.Name)
+	}
+	if p := b.MustBuild().Documents[0].Fields[email]; p.Name != "email_0" {
+		t.Errorf("expected repeatable namespacing, got %s", p.Name)
+	}
+
+	// Explicitly indexed and distinct params are kept as they are.
+	explicit := docql.InsertMany(instance.C("users"))
+	for i := range 3 {
+		explicit.Document(docql.Doc().SetIndexed(email, instance.P("email"), i).Build())
+	}
+	result, err := explicit.Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !slices.Equal(result.RequiredParams, []string{"email_0", "email_1", "email_2"}) {
+		t.Errorf("unexpected params for indexed documents: %v", result.RequiredParams)
+	}
+
+	// A generated name that is already in use is rejected.
+	clash := docql.InsertMany(instance.C("users")).
+		Document(docql.Doc().Set(email, instance.P("email")).Build()).
+		Document(docql.Doc().Set(email, instance.P("email")).Set(username, instance.P("email_1")).Build())
+	if _, err := clash.Build(); err == nil || !strings.Contains(err.Error(), "already used") {
+		t.Errorf("expected namespacing collision error, got %v", err)
+	}
+}
+
+func TestNotRange_Renderers(t *testing.T) {
+	instance := createTestInstance(t)
+	lo, hi := instance.P("lo"), instance.P("hi")
+	b := instance.Find("users").Filter(docql.NotRange(instance.F("users", "status"), &lo, &hi))
+
+	supported := map[string]struct {
+		r    docql.Renderer
+		want string
+	}{
+		"mongodb":    {mongodb.New(), `{"status":{"$not":{"$gte":":lo","$lte":":hi"}}}`},
+		"dynamodb":   {dynamodb.New(), `NOT (#n0 BETWEEN :0 AND :1)`},
+		"cosmosdb":   {cosmosdb.New(), `WHERE NOT (c.status`},
+		"arangodb":   {arangodb.New(), `FILTER NOT (d.status`},
+		"redisearch": {redisearch.New(), `-@status:[`},
+		"postgres":   {postgres.New(), `NOT (`},
+	}
+	for name, tc := range supported {
+		t.Run(name, func(t *testing.T) {
+			result, err := b.Render(tc.r)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if !strings.Contains(result.JSON, tc.want) {
+				t.Errorf("expected %s in %s", tc.want, result.JSON)
+			}
+			if !slices.Contains(result.RequiredParams, "lo") || !slices.Contains(result.RequiredParams, "hi") {
+				t.Errorf("expected both params, got %v", result.RequiredParams)
+			}
+		})
+	}
+
+	unsupported := map[string]docql.Renderer{
+		"couchdb":   couchdb.New(),
+		"firestore": firestore.New(),
+		"kv":        kv.New(),
+	}
+	for name, r := range unsupported {
+		t.Run(name, func(t *testing.T) {
+			if _, err := b.Render(r); !errors.Is(err, docql.ErrUnsupportedFilter) {
+				t.Errorf("expected unsupported filter error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFindOne_Renderers(t *testing.T) {
+	instance := createTestInstance(t)
+	filter := instance.Eq(instance.F("users", "_id"), instance.P("id"))
+	one := instance.FindOne("users").Filter(filter)
+	conflicting := instance.FindOne("users").Filter(filter).Limit(5)
+	paramLimit := instance.FindOne("users").Filter(filter).LimitParam(instance.P("n"))
+
+	renderers := map[string]docql.Renderer{
+		"mongodb":    mongodb.New(),
+		"couchdb":    couchdb.New(),
+		"dynamodb":   dynamodb.New(),
+		"firestore":  firestore.New(),
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+		"kv":         kv.New(),
+	}
+
+	for name, r := range renderers {
+		t.Run(name, func(t *testing.T) {
+			result, err := one.Render(r)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if !result.Single {
+				t.Error("expected Single for FIND_ONE")
+			}
+			for _, b := range []*docql.Builder{conflicting, paramLimit} {
+				if _, err := b.Render(r); err == nil || !strings.Contains(err.Error(), "limit is fixed at 1") {
+					t.Errorf("expected conflicting limit error, got %v", err)
+				}
+			}
+		})
+	}
+
+	// An explicit Limit(1) agrees with FIND_ONE.
+	if _, err := instance.FindOne("users").Filter(filter).Limit(1).Render(mongodb.New()); err != nil {
+		t.Errorf("unexpected error for Limit(1): %v", err)
+	}
+	result, err := instance.Find("users").Filter(filter).Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if result.Single {
+		t.Error("expected FIND not to be Single")
+	}
+}
+
+func TestSortOpts_Renderers(t *testing.T) {
+	instance := createTestInstance(t)
+	username := instance.F("users", "username")
+	filter := instance.Eq(instance.F("users", "status"), instance.P("status"))
+	plain := instance.Find("users").Filter(filter).SortAsc(username)
+	zero := instance.Find("users").Filter(filter).SortAscWith(username, docql.SortOpts{})
+	opts := instance.Find("users").Filter(filter).SortAscWith(username, docql.SortOpts{CaseInsensitive: true, Numeric: true})
+
+	renderers := map[string]docql.Renderer{
+		"mongodb":    mongodb.New(),
+		"couchdb":    couchdb.New(),
+		"dynamodb":   dynamodb.New(),
+		"firestore":  firestore.New(),
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+	}
+
+	for name, r := range renderers {
+		t.Run(name, func(t *testing.T) {
+			want, err := plain.Render(r)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			got, err := zero.Render(r)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if got.JSON != want.JSON || !slices.Equal(got.Warnings, want.Warnings) {
+				t.Errorf("zero SortOpts changed output:\n got: %s\nwant: %s", got.JSON, want.JSON)
+			}
+
+			result, err := opts.Render(r)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			warned := slices.ContainsFunc(result.Warnings, func(w string) bool {
+				return strings.Contains(w, "sort options ignored")
+			})
+			if name == "mongodb" {
+				if warned || !strings.Contains(result.JSON, `"collation":{"locale":"en","numericOrdering":true,"strength":2}`) {
+					t.Errorf("expected collation from sort options, got %s (warnings %v)", result.JSON, result.Warnings)
+				}
+				return
+			}
+			if !warned {
+				t.Errorf("expected sort options warning, got %v", result.Warnings)
+			}
+		})
+	}
+}
+
+func TestSizeLiteral_Renderers(t *testing.T) {
+	instance := createTestInstance(t)
+	b := instance.Find("users").Filter(docql.SizeLiteral(instance.F("users", "status"), 3))
+
+	renderers := map[string]struct {
+		r    docql.Renderer
+		want string
+	}{
+		"mongodb":  {mongodb.New(), `{"status":{"$size":3}}`},
+		"cosmosdb": {cosmosdb.New(), `ARRAY_LENGTH(c.status) = 3`},
+		"arangodb": {arangodb.New(), `LENGTH(d.status) == 3`},
+		"postgres": {postgres.New(), `) = 3`},
+	}
+	for name, tc := range renderers {
+		t.Run(name, func(t *testing.T) {
+			result, err := b.Render(tc.r)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if !strings.Contains(result.JSON, tc.want) {
+				t.Errorf("expected %s in %s", tc.want, result.JSON)
+			}
+			if len(result.RequiredParams) != 0 {
+				t.Errorf("expected no params, got %v", result.RequiredParams)
+			}
+		})
+	}
+
+	if _, err := instance.Find("users").Filter(docql.SizeLiteral(instance.F("users", "status"), -1)).Build(); err == nil {
+		t.Error("expected negative size to fail validation")
+	}
+}
+
+func TestEmptyParam_Renderers(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	status := types.Field{Path: "status", Collection: "users"}
+	email := types.Field{Path: "email", Collection: "users"}
+	filter := types.FilterCondition{Field: status, Operator: types.EQ, Value: types.Param{Name: "status"}}
+
+	cases := []struct {
+		name    string
+		ast     *types.DocumentAST
+		context string
+	}{
+		{
+			name:    "filter",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.FilterCondition{Field: status, Operator: types.EQ}},
+			context: "$eq on field 'status'",
+		},
+		{
+			name:    "document",
+			ast:     &types.DocumentAST{Operation: types.OpInsert, Target: users, Documents: []types.Document{{Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "document field 'email'",
+		},
+		{
+			name: "update",
+			ast: &types.DocumentAST{Operation: types.OpUpdate, Target: users, FilterClause: filter,
+				UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "$set field 'email'",
+		},
+		{
+			name:    "pagination",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: filter, Limit: &types.PaginationValue{Param: &types.Param{}}},
+			context: "limit",
+		},
+		{
+			name: "pipeline expression",
+			ast: &types.DocumentAST{Operation: types.OpAggregate, Target: users,
+				Pipeline: []types.PipelineStage{types.AddFieldsStage{Fields: map[string]types.Expression{"flag": types.LiteralExpression{}}}}},
+			context: "$addFields field 'flag'",
+		},
+	}
+
+	renderers := map[string]docql.Renderer{
+		"mongodb":    mongodb.New(),
+		"couchdb":    couchdb.New(),
+		"dynamodb":   dynamodb.New(),
+		"firestore":  firestore.New(),
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+		"kv":         kv.New(),
+	}
+
+	for name, r := range renderers {
+		for _, tc := range cases {
+			t.Run(name+"/"+tc.name, func(t *testing.T) {
+				_, err := r.Render(tc.ast)
+				if !errors.Is(err, docql.ErrInvalidIdentifier) {
+					t.Fatalf("expected invalid identifier error, got %v", err)
+				}
+				if !strings.Contains(err.Error(), tc.context) {
+					t.Errorf("expected error naming %q, got %v", tc.context, err)
+				}
+			})
+		}
+	}
+}
+
+func TestUnset_NoParams(t *testing.T) {
+	instance := createTestInstance(t)
+	b := instance.Update("users").
+		Filter(instance.Eq(instance.F("users", "_id"), instance.P("id"))).
+		Unset(instance.F("users", "email"))
+
+	renderers := map[string]docql.Renderer{
+		"mongodb":   mongodb.New(),
+		"dynamodb":  dynamodb.New(),
+		"firestore": firestore.New(),
+		"arangodb":  arangodb.New(),
+		"postgres":  postgres.New(),
+	}
+	for name, r := range renderers {
+		t.Run(name, func(t *testing.T) {
+			result, err := b.Render(r)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if slices.Contains(result.RequiredParams, "") {
+				t.Errorf("unexpected empty param in %v", result.RequiredParams)
+			}
+			if strings.Contains(result.JSON, `":",`) || strings.Contains(result.JSON, `":"}`) {
+				t.Errorf("unexpected empty placeholder in %s", result.JSON)
+			}
+		})
+	}
+
+	result, err := b.Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(result.JSON, `"$unset":{"email":""}`) {
+		t.Errorf("expected conventional $unset value in %s", result.JSON)
+	}
+}
+
+func TestSort_PreservesClauseOrder(t *testing.T) {
+	instance := createTestInstance(t)
+	b := instance.Find("users").
+		Filter(instance.Eq(instance.F("users", "active"), instance.P("active"))).
+		SortDesc(instance.F("users", "username")).
+		SortAsc(instance.F("users", "email"))
+
+	renderers := map[string]docql.Renderer{
+		"mongodb":   mongodb.New(),
+		"couchdb":   couchdb.New(),
+		"firestore": firestore.New(),
+		"cosmosdb":  cosmosdb.New(),
+		"arangodb":  arangodb.New(),
+		"postgres":  postgres.New(),
+	}
+	for name, r := range renderers {
+		t.Run(name, func(t *testing.T) {
+			result, err := b.Render(r)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			username := strings.LastIndex(result.JSON, "username")
+			email := strings.LastIndex(result.JSON, "email")
+			if username < 0 || email < 0 || username > email {
+				t.Errorf("expected username sorted before email in %s", result.JSON)
+			}
+		})
+	}
+
+	agg := &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "users"},
+		Pipeline: []types.PipelineStage{types.SortStage{Sorts: []types.SortClause{
+			{Field: types.Field{Path: "username"}, Order: types.Descending},
+			{Field: types.Field{Path: "email"}, Order: types.Ascending},
+		}}},
+	}
+	result, err := mongodb.New().Render(agg)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(result.JSON, `{"$sort":{"username":-1,"email":1}}`) {
+		t.Errorf("expected $sort keys in stage order, got %s", result.JSON)
+	}
+}
+
+func TestSort_DuplicateFieldRejected(t *testing.T) {
+	instance := createTestInstance(t)
+	_, err := instance.Find("users").
+		SortAsc(instance.F("users", "username")).
+		SortDesc(instance.F("users", "username")).
+		Build()
+	if err == nil || !strings.Contains(err.Error(), "duplicate sort field 'username'") {
+		t.Errorf("expected duplicate sort field error, got %v", err)
+	}
+}
+
+func TestDatabase_Renderers(t *testing.T) {
+	instance := createTestInstance(t)
+	if instance.Database() != "test_db" {
+		t.Fatalf("expected database test_db, got %q", instance.Database())
+	}
+	b := instance.Find("users").Filter(instance.Eq(instance.F("users", "_id"), instance.P("id")))
+	bound, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bare := bound.Clone()
+	bare.Target = types.Collection{Name: "users"}
+
+	renderers := map[string]struct {
+		r    docql.Renderer
+		emit bool
+	}{
+		"mongodb":    {mongodb.New(), true},
+		"couchdb":    {couchdb.New(), true},
+		"firestore":  {firestore.New(), true},
+		"dynamodb":   {dynamodb.New(), false},
+		"cosmosdb":   {cosmosdb.New(), false},
+		"arangodb":   {arangodb.New(), false},
+		"redisearch": {redisearch.New(), false},
+		"postgres":   {postgres.New(), false},
+		"kv":         {kv.New(), false},
+	}
+	for name, tc := range renderers {
+		t.Run(name, func(t *testing.T) {
+			withDB, err := tc.r.Render(bound)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			without, err := tc.r.Render(bare)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if withDB.Database != "test_db" || without.Database != "" {
+				t.Errorf("unexpected Database: %q with schema, %q without", withDB.Database, without.Database)
+			}
+			if strings.Contains(without.JSON, "database") {
+				t.Errorf("unexpected database key without a schema: %s", without.JSON)
+			}
+			got := withDB.JSON
+			if tc.emit {
+				if !strings.Contains(got, `"database":"test_db"`) {
+					t.Errorf("expected database key in %s", got)
+				}
+				got = strings.Replace(got, `"database":"test_db",`, "", 1)
+			}
+			if got != without.JSON {
+				t.Errorf("database changed the query:\n got: %s\nwant: %s", got, without.JSON)
+			}
+		})
+	}
+}
+
+func TestRender_ParamMinimums(t *testing.T) {
+	instance := createTestInstance(t)
+
+	find := instance.Find("users").SkipParam(instance.P("skip")).LimitParam(instance.P("limit"))
+	result, err := find.Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if result.ParamMinimums["skip"] != 0 || result.ParamMinimums["limit"] != 1 || len(result.ParamMinimums) != 2 {
+		t.Errorf("unexpected minimums: %v", result.ParamMinimums)
+	}
+
+	agg := docql.Aggregate(instance.C("users")).
+		Match(instance.Eq(instance.F("users", "status"), instance.P("status"))).
+		LimitParam(instance.P("n"))
+	result, err = instance.Render(agg, mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if result.ParamMinimums["n"] != 1 || len(result.ParamMinimums) != 1 {
+		t.Errorf("unexpected minimums: %v", result.ParamMinimums)
+	}
+
+	result, err = instance.Find("users").Limit(10).Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if result.ParamMinimums != nil {
+		t.Errorf("expected no minimums for static pagination, got %v", result.ParamMinimums)
+	}
+}
+
+func TestNullFilter_Renderers(t *testing.T) {
+	instance := createTestInstance(t)
+	status := instance.F("users", "status")
+
+	cases := []struct {
+		name   string
+		filter types.NullFilter
+		want   map[string]string
+	}{
+		{
+			name:   "IsNull",
+			filter: docql.IsNull(status),
+			want: map[string]string{
+				"mongodb":   `"filter":{"status":null}`,
+				"couchdb":   `"selector":{"status":null}`,
+				"firestore": `{"field":"status","operator":"==","value":null}`,
+				"dynamodb":  `"FilterExpression":"attribute_type(#n0, :0)"`,
+			},
+		},
+		{
+			name:   "IsNotNull",
+			filter: docql.IsNotNull(status),
+			want: map[string]string{
+				"mongodb":   `"filter":{"status":{"$ne":null}}`,
+				"couchdb":   `"selector":{"status":{"$ne":null}}`,
+				"firestore": `{"field":"status","operator":"!=","value":null}`,
+				"dynamodb":  `"FilterExpression":"NOT attribute_type(#n0, :0)"`,
+			},
+		},
+		{
+			name:   "IsNullStrict",
+			filter: docql.IsNullStrict(status),
+			want: map[string]string{
+				"mongodb":   `"filter":{"status":{"$eq":null,"$exists":true}}`,
+				"couchdb":   `"selector":{"status":null}`,
+				"firestore": `{"field":"status","operator":"==","value":null}`,
+				"dynamodb":  `"FilterExpression":"attribute_type(#n0, :0)"`,
+			},
+		},
+		{
+			name:   "IsNotNullStrict",
+			filter: docql.IsNotNullStrict(status),
+			want: map[string]string{
+				"mongodb":   `"filter":{"status":{"$ne":null}}`,
+				"couchdb":   `"selector":{"status":{"$ne":null}}`,
+				"firestore": `{"field":"status","operator":"!=","value":null}`,
+				"dynamodb":  `"FilterExpression":"attribute_exists(#n0) AND NOT attribute_type(#n0, :0)"`,
+			},
+		},
+	}
+
+	renderers := map[string]docql.Renderer{
+		"mongodb":   mongodb.New(),
+		"couchdb":   couchdb.New(),
+		"firestore": firestore.New(),
+		"dynamodb":  dynamodb.New(),
+	}
+	for _, tc := range cases {
+		b := instance.Find("users").Filter(tc.filter)
+		for name, r := range renderers {
+			t.Run(tc.name+"/"+name, func(t *testing.T) {
+				result, err := b.Render(r)
+				if err != nil {
+					t.Fatalf("Render failed: %v", err)
+				}
+				if !strings.Contains(result.JSON, tc.want[name]) {
+					t.Errorf("expected %s in %s", tc.want[name], result.JSON)
+				}
+				if len(result.RequiredParams) != 0 {
+					t.Errorf("expected no params, got %v", result.RequiredParams)
+				}
+				if name == "dynamodb" && !strings.Contains(result.JSON, `":0":"NULL"`) {
+					t.Errorf("expected NULL type literal in %s", result.JSON)
+				}
+			})
+		}
+	}
+
+	unsupported := map[string]docql.Renderer{
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+		"kv":         kv.New(),
+	}
+	for name, r := range unsupported {
+		t.Run("unsupported/"+name, func(t *testing.T) {
+			if _, err := instance.Find("users").Filter(docql.IsNull(status)).Render(r); !errors.Is(err, docql.ErrUnsupportedFilter) {
+				t.Errorf("expected unsupported filter error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTextSearchOpts_Renderers(t *testing.T) {
+	instance := createTestInstance(t)
+	lang := instance.P("lang")
+	b := instance.Find("users").Filter(docql.TextSearchOpts(instance.P("q"), &lang, true, true))
+
+	result, err := b.Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := `{"$text":{"$caseSensitive":true,"$diacriticSensitive":true,"$language":":lang","$search":":q"}}`
+	if !strings.Contains(result.JSON, want) {
+		t.Errorf("expected %s in %s", want, result.JSON)
+	}
+	if !slices.Equal(result.RequiredParams, []string{"q", "lang"}) {
+		t.Errorf("expected params [q lang], got %v", result.RequiredParams)
+	}
+
+	result, err = b.Render(redisearch.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(result.JSON, `"language":":lang"`) || !slices.Contains(result.RequiredParams, "lang") {
+		t.Errorf("expected the language param in %s, params %v", result.JSON, result.RequiredParams)
+	}
+	if len(result.Warnings) != 2 {
+		t.Errorf("expected case and diacritic sensitivity warnings, got %v", result.Warnings)
+	}
+
+	noLang, err := instance.Find("users").Filter(docql.TextSearchOpts(instance.P("q"), nil, false, false)).Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(noLang.JSON, `{"$text":{"$search":":q"}}`) {
+		t.Errorf("expected a bare $text search, got %s", noLang.JSON)
+	}
+}
+
+func TestAggregateSort_Renderers(t *testing.T) {
+	instance := createTestInstance(t)
+	status := instance.F("users", "status")
+
+	b := docql.Aggregate(instance.C("users")).
+		Match(instance.Eq(status, instance.P("status"))).
+		SortDesc(instance.F("users", "username")).
+		SortAsc(instance.F("users", "_id")).
+		Limit(5)
+
+	tests := []struct {
+		name     string
+		renderer docql.Renderer
+		want     string
+	}{
+		{"mongodb", mongodb.New(), `{"$sort":{"username":-1,"_id":1}},{"$limit":5}`},
+		{"arangodb", arangodb.New(), "SORT d.username DESC, d._id ASC LIMIT 5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := b.Render(tt.renderer)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if !strings.Contains(result.JSON, tt.want) {
+				t.Errorf("Expected %s in %s", tt.want, result.JSON)
+			}
+		})
+	}
+}
+
+func TestWithLimits_PartialAndSeeded(t *testing.T) {
+	instance := createTestInstance(t)
+	instance.WithLimits(docql.Limits{MaxLimit: 50})
+
+	limits := instance.Limits()
+	if limits.MaxLimit != 50 {
+		t.Errorf("Expected MaxLimit 50, got %d", limits.MaxLimit)
+	}
+	if limits.MaxFilterDepth != docql.MaxFilterDepth || limits.MaxFilterConditions == 0 {
+		t.Errorf("Expected omitted fields to keep their defaults, got %+v", limits)
+	}
+
+	filtered := instance.Find("users").Filter(instance.Eq(instance.F("users", "email"), instance.P("email")))
+	if _, err := instance.Render(filtered, mongodb.New()); err != nil {
+		t.Errorf("Expected filtered query under partial limits to render, got: %v", err)
+	}
+	if _, err := instance.Find("users").Limit(51).Build(); err == nil {
+		t.Error("Expected instance builder to carry the instance limits")
+	}
+}
+
+func TestWithLimits_FilterDepth(t *testing.T) {
+	instance := createTestInstance(t)
+	instance.WithLimits(docql.Limits{MaxFilterDepth: 40})
+
+	status := instance.F("users", "status")
+	filter := docql.FilterItem(instance.Eq(status, instance.P("status")))
+	for i := range docql.MaxFilterDepth + 1 {
+		other := instance.Eq(status, instance.P("s"+strconv.Itoa(i)))
+		if i%2 == 0 {
+			filter = docql.Or(filter, other)
+		} else {
+			filter = docql.And(filter, other)
+		}
+	}
+
+	if _, err := instance.Find("users").Filter(filter).Build(); err != nil {
+		t.Errorf("Expected instance builder to use the instance MaxFilterDepth, got: %v", err)
+	}
+	if _, err := instance.Build(docql.Find(instance.C("users")).Filter(filter)); err != nil {
+		t.Errorf("Expected instance Build to use the instance MaxFilterDepth, got: %v", err)
+	}
+
+	instance.WithLimits(docql.Limits{MaxFilterDepth: docql.MaxFilterDepth})
+	if err := instance.Count("users").Filter(filter).Err(); err == nil {
+		t.Error("Expected instance Count to reject the filter early")
+	}
+	if _, err := instance.Build(docql.Count(instance.C("users")).Filter(filter)); err == nil {
+		t.Error("Expected instance Build to check the depth of a Count filter")
+	}
+}
@@ -79,6 +79,22 @@ type LookupStage struct {
 func (LookupStage) isPipelineStage()  {}
 func (LookupStage) StageName() string { return "$lookup" }
 
+// GraphLookupStage represents $graphLookup, a recursive search over From
+// following ConnectFromField to ConnectToField.
+type GraphLookupStage struct {
+	From                    string
+	StartWith               Expression
+	ConnectFromField        Field
+	ConnectToField          Field
+	As                      string
+	MaxDepth                *PaginationValue
+	DepthField              *string
+	RestrictSearchWithMatch FilterItem
+}
+
+func (GraphLookupStage) isPipelineStage()  {}
+func (GraphLookupStage) StageName() string { return "$graphLookup" }
+
 // AddFieldsStage represents $addFields.
 type AddFieldsStage struct {
 	Fields map[string]Expression
@@ -122,6 +138,44 @@ type BucketStage struct {
 func (BucketStage) isPipelineStage()  {}
 func (BucketStage) StageName() string { return "$bucket" }
 
+// SortByCountStage represents $sortByCount: group by Expr, count, and sort
+// by count descending.
+type SortByCountStage struct {
+	Expr Expression
+}
+
+func (SortByCountStage) isPipelineStage()  {}
+func (SortByCountStage) StageName() string { return "$sortByCount" }
+
+// SampleStage represents $sample.
+type SampleStage struct {
+	Size PaginationValue
+}
+
+func (SampleStage) isPipelineStage()  {}
+func (SampleStage) StageName() string { return "$sample" }
+
+// MergeStage represents $merge, writing pipeline output into a collection.
+// Empty WhenMatched/WhenNotMatched use the server defaults (merge/insert).
+type MergeStage struct {
+	Into           string
+	WhenMatched    string
+	WhenNotMatched string
+}
+
+func (MergeStage) isPipelineStage()  {}
+func (MergeStage) StageName() string { return "$merge" }
+
+// $merge whenMatched and whenNotMatched actions.
+const (
+	MergeReplace      = "replace"
+	MergeKeepExisting = "keepExisting"
+	MergeMerge        = "merge"
+	MergeFail         = "fail"
+	MergeInsert       = "insert"
+	MergeDiscard      = "discard"
+)
+
 // Expression represents an aggregation expression.
 type Expression interface {
 	isExpression()
@@ -158,6 +212,14 @@ type ConditionalExpression struct {
 
 func (ConditionalExpression) isExpression() {}
 
+// CompositeExpression represents an object of named expressions, such as the
+// compound _id of a $group over several fields.
+type CompositeExpression struct {
+	Fields map[string]Expression
+}
+
+func (CompositeExpression) isExpression() {}
+
 // Accumulator represents a group accumulator.
 type Accumulator struct {
 	Operator string
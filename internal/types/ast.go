@@ -1,6 +1,12 @@
 package types
 
-import "fmt"
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
 
 // DocumentAST represents the abstract syntax tree for document database queries.
 type DocumentAST struct {
@@ -21,8 +27,10 @@ type DocumentAST struct {
 	Skip  *PaginationValue
 	Limit *PaginationValue
 
-	// Insert-specific.
-	Documents []Document
+	// Insert-specific. IfNotExists fails the insert rather than overwrite a
+	// document with the same key.
+	Documents   []Document
+	IfNotExists bool
 
 	// Update-specific.
 	UpdateOps []UpdateOperation
@@ -33,21 +41,126 @@ type DocumentAST struct {
 
 	// Distinct field (for OpDistinct).
 	DistinctField *Field
+
+	// Watch-specific: the fullDocument mode, the resume token param, and
+	// whether filter paths address the change event rather than the
+	// document, which renderers otherwise reach under "fullDocument.".
+	FullDocument *string
+	ResumeAfter  *Param
+	RawEvent     bool
+
+	// Transaction-specific: the writes to commit together, in order. A
+	// transaction has no Target of its own.
+	Operations []*DocumentAST
+
+	// Server-side execution time limit in milliseconds (read operations only).
+	MaxTimeMS *int
+
+	// AllowDiskUse lets aggregation stages spill to temporary files when they
+	// exceed the server's memory limit (aggregate only).
+	AllowDiskUse bool
+
+	// Index to force for the query; providers without index hints ignore it.
+	Hint *string
+
+	// String comparison rules for filters and sorts.
+	Collation *Collation
+
+	// Read concern level for reads and write concern "w" for writes, such as
+	// "majority"; providers without them ignore them.
+	ReadConcern  *string
+	WriteConcern *string
+
+	// Complexity limits to validate against; nil uses DefaultLimits.
+	Limits *Limits
+
+	// RequireFilter rejects UPDATE and DELETE without a filter, as the
+	// *_MANY variants always do.
+	RequireFilter bool
 }
 
-// Validate validates the DocumentAST.
+// Validate validates the DocumentAST against its own Limits, or the defaults.
 func (ast *DocumentAST) Validate() error {
+	if ast.Limits != nil {
+		return ast.ValidateWithLimits(*ast.Limits)
+	}
+	return ast.ValidateWithLimits(DefaultLimits())
+}
+
+// ValidateWithLimits validates the DocumentAST against the given limits.
+// Fields left at zero use their DefaultLimits value.
+func (ast *DocumentAST) ValidateWithLimits(limits Limits) error {
+	limits = limits.WithDefaults()
+	if ast.Operation == OpTransaction {
+		return ast.validateTransaction(limits)
+	}
+	if ast.Target.err != nil {
+		return ast.Target.err
+	}
 	if ast.Target.Name == "" {
 		return fmt.Errorf("target collection is required")
 	}
+	if err := ast.validateTargetParam(); err != nil {
+		return err
+	}
+
+	if err := ast.validateMaxTime(limits); err != nil {
+		return err
+	}
+	if ast.AllowDiskUse && ast.Operation != OpAggregate {
+		return fmt.Errorf("allowDiskUse is only valid for AGGREGATE, got %s", ast.Operation)
+	}
+	if ast.IfNotExists && ast.Operation != OpInsert {
+		return fmt.Errorf("ifNotExists is only valid for INSERT, got %s", ast.Operation)
+	}
+	if err := ast.validateHint(); err != nil {
+		return err
+	}
+	if err := ast.validateCollation(); err != nil {
+		return err
+	}
+	if err := ast.validateSortOpts(); err != nil {
+		return err
+	}
+	if err := ast.validateSortFields(); err != nil {
+		return err
+	}
+	if err := ast.validateConcern(); err != nil {
+		return err
+	}
+	if err := ast.validateTypeNames(); err != nil {
+		return err
+	}
+	if err := ast.validateParamNames(); err != nil {
+		return err
+	}
+	if err := ast.validatePagination(limits); err != nil {
+		return err
+	}
+	if ast.FilterClause != nil {
+		if err := validateFilterDepth(ast.FilterClause, 0, limits.MaxFilterDepth); err != nil {
+			return err
+		}
+	}
+	if err := ast.validateFilterConditions(limits); err != nil {
+		return err
+	}
+	if ast.FilterClause != nil {
+		if err := validateFilterValues(ast.FilterClause); err != nil {
+			return err
+		}
+	}
+	if ast.Operation != OpWatch && (ast.FullDocument != nil || ast.ResumeAfter != nil || ast.RawEvent) {
+		return fmt.Errorf("change stream options are only valid for WATCH, got %s", ast.Operation)
+	}
 
 	switch ast.Operation {
 	case OpFind, OpFindOne:
-		return ast.validateFind()
+		return ast.validateFind(limits)
 	case OpInsert:
 		return ast.validateInsert()
 	case OpInsertMany:
-		return ast.validateInsertMany()
+		return ast.validateInsertMany(limits)
 	case OpUpdate:
 		return ast.validateUpdate()
 	case OpUpdateMany:
@@ -57,33 +170,33 @@ func (ast *DocumentAST) Validate() error {
 	case OpDeleteMany:
 		return ast.validateDeleteMany()
 	case OpAggregate:
-		return ast.validateAggregate()
+		return ast.validateAggregate(limits)
 	case OpCount:
 		return ast.validateCount()
 	case OpDistinct:
 		return ast.validateDistinct()
+	case OpWatch:
+		return ast.validateWatch()
 	default:
-		return fmt.Errorf("unsupported operation: %s", ast.Operation)
+		return &UnsupportedError{Operation: ast.Operation}
 	}
 }
 
-func (ast *DocumentAST) validateFind() error {
-	if ast.Limit != nil && ast.Limit.Static != nil && *ast.Limit.Static > MaxLimit {
-		return fmt.Errorf("limit exceeds maximum: %d > %d", *ast.Limit.Static, MaxLimit)
-	}
-	if ast.Projection != nil && len(ast.Projection.Fields) > MaxProjectionFields {
-		return fmt.Errorf("projection fields exceed maximum: %d > %d",
-			len(ast.Projection.Fields), MaxProjectionFields)
+func (ast *DocumentAST) validateFind(limits Limits) error {
+	if ast.Operation == OpFindOne && ast.Limit != nil && (ast.Limit.Static == nil || *ast.Limit.Static != 1) {
+		return fmt.Errorf("FIND_ONE limit is fixed at 1")
 	}
-	if len(ast.SortClauses) > MaxSortFields {
-		return fmt.Errorf("sort fields exceed maximum: %d > %d",
-			len(ast.SortClauses), MaxSortFields)
-	}
-	if ast.FilterClause != nil {
-		if err := validateFilterDepth(ast.FilterClause, 0); err != nil {
+	if ast.Projection != nil {
+		if len(ast.Projection.Fields) > limits.MaxProjectionFields {
+			return &LimitExceededError{Limit: "MaxProjectionFields", Value: len(ast.Projection.Fields), Max: limits.MaxProjectionFields}
+		}
+		if err := ast.Projection.Validate(); err != nil {
 			return err
 		}
 	}
+	if len(ast.SortClauses) > limits.MaxSortFields {
+		return &LimitExceededError{Limit: "MaxSortFields", Value: len(ast.SortClauses), Max: limits.MaxSortFields}
+	}
 	return nil
 }
 
@@ -94,13 +207,12 @@ func (ast *DocumentAST) validateInsert() error {
 	return nil
 }
 
-func (ast *DocumentAST) validateInsertMany() error {
+func (ast *DocumentAST) validateInsertMany(limits Limits) error {
 	if len(ast.Documents) == 0 {
 		return fmt.Errorf("INSERT_MANY requires at least one document")
 	}
-	if len(ast.Documents) > MaxBatchSize {
-		return fmt.Errorf("batch size exceeds maximum: %d > %d",
-			len(ast.Documents), MaxBatchSize)
+	if len(ast.Documents) > limits.MaxBatchSize {
+		return &LimitExceededError{Limit: "MaxBatchSize", Value: len(ast.Documents), Max: limits.MaxBatchSize}
 	}
 	return nil
 }
@@ -109,7 +221,10 @@ func (ast *DocumentAST) validateUpdate() error {
 	if len(ast.UpdateOps) == 0 {
 		return fmt.Errorf("UPDATE requires at least one update operation")
 	}
-	return nil
+	if ast.RequireFilter && ast.FilterClause == nil {
+		return &MissingFilterError{Operation: ast.Operation}
+	}
+	return ast.validateUpdatePaths()
 }
 
 func (ast *DocumentAST) validateUpdateMany() error {
@@ -117,29 +232,230 @@ func (ast *DocumentAST) validateUpdateMany() error {
 		return fmt.Errorf("UPDATE_MANY requires at least one update operation")
 	}
 	if ast.FilterClause == nil {
-		return fmt.Errorf("UPDATE_MANY requires a filter for safety")
+		return &MissingFilterError{Operation: ast.Operation}
+	}
+	return ast.validateUpdatePaths()
+}
+
+// validateUpdatePaths rejects update operations that touch the same path
+// twice, or a path and one of its ancestors, which MongoDB refuses to apply
+// together: $set of "address.city" with $unset of "address" conflicts.
+// Sibling paths such as "address.city" and "address.zip" do not.
+func (ast *DocumentAST) validateUpdatePaths() error {
+	ops := make(map[string]UpdateOperator)
+	for _, op := range ast.UpdateOps {
+		paths := make([]string, 0, len(op.Fields))
+		for f := range op.Fields {
+			paths = append(paths, f.Path)
+		}
+		slices.Sort(paths)
+		for _, path := range paths {
+			if prev, ok := ops[path]; ok {
+				return fmt.Errorf("conflicting update paths: '%s' in %s and '%s' in %s", path, prev, path, op.Operator)
+			}
+			ops[path] = op.Operator
+		}
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(ops)) {
+		for i := strings.LastIndexByte(path, '.'); i > 0; i = strings.LastIndexByte(path[:i], '.') {
+			if prev, ok := ops[path[:i]]; ok {
+				return fmt.Errorf("conflicting update paths: '%s' in %s and '%s' in %s", path[:i], prev, path, ops[path])
+			}
+		}
 	}
 	return nil
 }
 
 func (ast *DocumentAST) validateDelete() error {
+	if ast.RequireFilter && ast.FilterClause == nil {
+		return &MissingFilterError{Operation: ast.Operation}
+	}
 	return nil
 }
 
 func (ast *DocumentAST) validateDeleteMany() error {
 	if ast.FilterClause == nil {
-		return fmt.Errorf("DELETE_MANY requires a filter for safety")
+		return &MissingFilterError{Operation: ast.Operation}
 	}
 	return nil
 }
 
-func (ast *DocumentAST) validateAggregate() error {
+func (ast *DocumentAST) validateAggregate(limits Limits) error {
 	if len(ast.Pipeline) == 0 {
 		return fmt.Errorf("AGGREGATE requires at least one pipeline stage")
 	}
-	if len(ast.Pipeline) > MaxPipelineStages {
-		return fmt.Errorf("pipeline stages exceed maximum: %d > %d",
-			len(ast.Pipeline), MaxPipelineStages)
+	if n := countStages(ast.Pipeline); n > limits.MaxPipelineStages {
+		return &LimitExceededError{Limit: "MaxPipelineStages", Value: n, Max: limits.MaxPipelineStages}
+	}
+	if ast.Skip != nil || ast.Limit != nil {
+		return fmt.Errorf("AGGREGATE does not use top-level skip/limit: use $skip/$limit pipeline stages")
+	}
+	if len(ast.SortClauses) > 0 {
+		return fmt.Errorf("AGGREGATE does not use top-level sort: use a $sort pipeline stage")
+	}
+	if err := validatePipelineFilters(ast.Pipeline, limits); err != nil {
+		return err
+	}
+	for i, stage := range ast.Pipeline {
+		if match, ok := stage.(MatchStage); ok && i > 0 && containsTextSearch(match.Filter) {
+			return fmt.Errorf("$match with $text must be the first pipeline stage, found at stage %d", i)
+		}
+		if project, ok := stage.(ProjectStage); ok {
+			if err := project.validate(); err != nil {
+				return fmt.Errorf("stage %d: %w", i, err)
+			}
+		}
+		if limit, ok := stage.(LimitStage); ok && limit.Limit.Static != nil {
+			if err := ValidateLimit(*limit.Limit.Static, limits); err != nil {
+				return fmt.Errorf("stage %d: %w", i, err)
+			}
+		}
+		if skip, ok := stage.(SkipStage); ok && skip.Skip.Static != nil {
+			if err := ValidateSkip(*skip.Skip.Static); err != nil {
+				return fmt.Errorf("stage %d: %w", i, err)
+			}
+		}
+		if sample, ok := stage.(SampleStage); ok && sample.Size.Static != nil && *sample.Size.Static <= 0 {
+			return fmt.Errorf("stage %d: $sample size must be positive: %d", i, *sample.Size.Static)
+		}
+		if graph, ok := stage.(GraphLookupStage); ok {
+			if err := graph.validate(); err != nil {
+				return fmt.Errorf("stage %d: %w", i, err)
+			}
+		}
+		if merge, ok := stage.(MergeStage); ok {
+			if i != len(ast.Pipeline)-1 {
+				return fmt.Errorf("$merge must be the final pipeline stage, found at stage %d", i)
+			}
+			if err := merge.validate(); err != nil {
+				return fmt.Errorf("stage %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// countStages counts the stages of a pipeline including those nested in
+// $facet and $lookup sub-pipelines, which also run on the server.
+func countStages(stages []PipelineStage) int {
+	n := len(stages)
+	for _, stage := range stages {
+		switch s := stage.(type) {
+		case FacetStage:
+			for _, sub := range s.Facets {
+				n += countStages(sub)
+			}
+		case LookupStage:
+			n += countStages(s.Pipeline)
+		}
+	}
+	return n
+}
+
+// validate checks the projection and that computed fields have identifier
+// keys, an expression, and no projected field of the same name. Computed
+// fields cannot join an exclusion projection, except one excluding _id.
+func (p ProjectStage) validate() error {
+	if err := p.Projection.Validate(); err != nil {
+		return err
+	}
+	for _, key := range slices.Sorted(maps.Keys(p.Computed)) {
+		if !IsValidIdentifier(key) {
+			return &InvalidIdentifierError{Kind: "computed projection key", Value: key}
+		}
+		if p.Computed[key] == nil {
+			return fmt.Errorf("computed projection field '%s' requires an expression", key)
+		}
+		for _, f := range p.Projection.Fields {
+			if !f.Include && f.Field.Path != IDField {
+				return fmt.Errorf("$project cannot mix excluded and computed fields: %s", f.Field.Path)
+			}
+			if f.Field.Path == key || (f.Include && f.OutputName() == key) {
+				return fmt.Errorf("computed projection field '%s' is also projected", key)
+			}
+		}
+	}
+	return nil
+}
+
+func (g GraphLookupStage) validate() error {
+	if !IsValidIdentifier(g.From) {
+		return &InvalidIdentifierError{Kind: "$graphLookup from collection", Value: g.From}
+	}
+	if !IsValidIdentifier(g.As) {
+		return &InvalidIdentifierError{Kind: "$graphLookup as name", Value: g.As}
+	}
+	if g.StartWith == nil {
+		return fmt.Errorf("$graphLookup requires startWith")
+	}
+	if g.DepthField != nil && !IsValidIdentifier(*g.DepthField) {
+		return &InvalidIdentifierError{Kind: "$graphLookup depthField", Value: *g.DepthField}
+	}
+	if g.MaxDepth != nil && g.MaxDepth.Static != nil && *g.MaxDepth.Static < 0 {
+		return fmt.Errorf("$graphLookup maxDepth must be non-negative: %d", *g.MaxDepth.Static)
+	}
+	return nil
+}
+
+func (m MergeStage) validate() error {
+	if !IsValidIdentifier(m.Into) {
+		return &InvalidIdentifierError{Kind: "$merge target collection", Value: m.Into}
+	}
+	switch m.WhenMatched {
+	case "", MergeReplace, MergeKeepExisting, MergeMerge, MergeFail:
+	default:
+		return fmt.Errorf("invalid $merge whenMatched: %q", m.WhenMatched)
+	}
+	switch m.WhenNotMatched {
+	case "", MergeInsert, MergeDiscard, MergeFail:
+	default:
+		return fmt.Errorf("invalid $merge whenNotMatched: %q", m.WhenNotMatched)
+	}
+	return nil
+}
+
+func (ast *DocumentAST) validateTransaction(limits Limits) error {
+	if ast.MaxTimeMS != nil {
+		return fmt.Errorf("maxTimeMS is only valid for read operations, got %s", ast.Operation)
+	}
+	if ast.AllowDiskUse {
+		return fmt.Errorf("allowDiskUse is only valid for AGGREGATE, got %s", ast.Operation)
+	}
+	if ast.Hint != nil {
+		return fmt.Errorf("hint is not valid for %s", ast.Operation)
+	}
+	if ast.Collation != nil {
+		return fmt.Errorf("collation is not valid for %s", ast.Operation)
+	}
+	if ast.FullDocument != nil || ast.ResumeAfter != nil || ast.RawEvent {
+		return fmt.Errorf("change stream options are only valid for WATCH, got %s", ast.Operation)
+	}
+	if ast.ReadConcern != nil && !readConcernLevels[*ast.ReadConcern] {
+		return fmt.Errorf("unknown read concern level: %q", *ast.ReadConcern)
+	}
+	if ast.WriteConcern != nil && *ast.WriteConcern == "" {
+		return fmt.Errorf("write concern requires a value")
+	}
+	if len(ast.Operations) == 0 {
+		return fmt.Errorf("TRANSACTION requires at least one operation")
+	}
+	if len(ast.Operations) > limits.MaxBatchSize {
+		return &LimitExceededError{Limit: "MaxBatchSize", Value: len(ast.Operations), Max: limits.MaxBatchSize}
+	}
+	for i, op := range ast.Operations {
+		if op == nil {
+			return fmt.Errorf("operation %d: missing AST", i)
+		}
+		if !op.Operation.IsWrite() {
+			return fmt.Errorf("operation %d: TRANSACTION can only contain write operations, got %s", i, op.Operation)
+		}
+		if op.ReadConcern != nil || op.WriteConcern != nil {
+			return fmt.Errorf("operation %d: read and write concern apply to the whole TRANSACTION", i)
+		}
+		if err := op.ValidateWithLimits(limits); err != nil {
+			return fmt.Errorf("operation %d: %w", i, err)
+		}
 	}
 	return nil
 }
@@ -148,21 +464,754 @@ func (ast *DocumentAST) validateCount() error {
 	return nil
 }
 
+// validateDistinct allows a sort and limit alongside the field and filter.
+// The query returns values rather than documents, so the only thing to sort
+// by is the distinct field itself.
 func (ast *DocumentAST) validateDistinct() error {
-	if ast.DistinctField == nil {
+	switch {
+	case ast.DistinctField == nil:
 		return fmt.Errorf("DISTINCT requires a field")
+	case ast.Projection != nil:
+		return fmt.Errorf("DISTINCT does not support projection")
+	case ast.Skip != nil:
+		return fmt.Errorf("DISTINCT does not support skip")
+	}
+	for _, sc := range ast.SortClauses {
+		if sc.Field.Path != ast.DistinctField.Path {
+			return fmt.Errorf("DISTINCT can only sort by its field %q", ast.DistinctField.Path)
+		}
+	}
+	return nil
+}
+
+// fullDocumentModes are the fullDocument settings MongoDB change streams accept.
+var fullDocumentModes = map[string]bool{
+	"default":       true,
+	"updateLookup":  true,
+	"whenAvailable": true,
+	"required":      true,
+}
+
+// validateWatch allows only a filter alongside the change stream options;
+// other stages belong in an aggregation over the stream.
+func (ast *DocumentAST) validateWatch() error {
+	switch {
+	case ast.Projection != nil:
+		return fmt.Errorf("WATCH does not support projection")
+	case len(ast.SortClauses) > 0:
+		return fmt.Errorf("WATCH does not support sort")
+	case ast.Skip != nil || ast.Limit != nil:
+		return fmt.Errorf("WATCH does not support skip or limit")
+	case len(ast.Pipeline) > 0:
+		return fmt.Errorf("WATCH does not support pipeline stages")
+	case len(ast.Documents) > 0 || len(ast.UpdateOps) > 0 || ast.Upsert:
+		return fmt.Errorf("WATCH does not support documents or updates")
+	case ast.DistinctField != nil:
+		return fmt.Errorf("WATCH does not support a distinct field")
+	}
+	if ast.FullDocument != nil && !fullDocumentModes[*ast.FullDocument] {
+		return fmt.Errorf("unknown fullDocument mode: %q", *ast.FullDocument)
+	}
+	if ast.ResumeAfter != nil && ast.ResumeAfter.Name == "" {
+		return fmt.Errorf("resumeAfter requires a param")
+	}
+	return nil
+}
+
+func (ast *DocumentAST) validateMaxTime(limits Limits) error {
+	if ast.MaxTimeMS == nil {
+		return nil
+	}
+	switch ast.Operation {
+	case OpFind, OpFindOne, OpAggregate, OpCount, OpDistinct:
+	default:
+		return fmt.Errorf("maxTimeMS is only valid for read operations, got %s", ast.Operation)
+	}
+	if *ast.MaxTimeMS <= 0 {
+		return fmt.Errorf("maxTimeMS must be positive: %d", *ast.MaxTimeMS)
+	}
+	if *ast.MaxTimeMS > limits.MaxQueryTimeMS {
+		return &LimitExceededError{Limit: "MaxQueryTimeMS", Value: *ast.MaxTimeMS, Max: limits.MaxQueryTimeMS}
+	}
+	return nil
+}
+
+// validateTargetParam checks a parameterized collection's base name and
+// param, which renderers place in the collection position.
+func (ast *DocumentAST) validateTargetParam() error {
+	if ast.Target.Param == nil {
+		return nil
+	}
+	if !IsValidIdentifier(ast.Target.Name) {
+		return &InvalidIdentifierError{Kind: "collection name", Value: ast.Target.Name}
+	}
+	if !IsValidIdentifier(ast.Target.Param.Name) {
+		return &InvalidIdentifierError{Kind: "collection param", Value: ast.Target.Param.Name}
+	}
+	return nil
+}
+
+func (ast *DocumentAST) validateHint() error {
+	if ast.Hint == nil {
+		return nil
+	}
+	if ast.Operation == OpInsert || ast.Operation == OpInsertMany {
+		return fmt.Errorf("hint is not valid for %s", ast.Operation)
+	}
+	if *ast.Hint == "" {
+		return fmt.Errorf("hint requires an index name")
+	}
+	return nil
+}
+
+// readConcernLevels are the read concern levels MongoDB accepts.
+var readConcernLevels = map[string]bool{
+	"local":        true,
+	"available":    true,
+	"majority":     true,
+	"linearizable": true,
+	"snapshot":     true,
+}
+
+func (ast *DocumentAST) validateConcern() error {
+	if ast.ReadConcern != nil {
+		if ast.Operation.IsWrite() {
+			return fmt.Errorf("read concern is only valid for read operations, got %s", ast.Operation)
+		}
+		if !readConcernLevels[*ast.ReadConcern] {
+			return fmt.Errorf("unknown read concern level: %q", *ast.ReadConcern)
+		}
+	}
+	if ast.WriteConcern != nil {
+		if !ast.Operation.IsWrite() {
+			return fmt.Errorf("write concern is only valid for write operations, got %s", ast.Operation)
+		}
+		if *ast.WriteConcern == "" {
+			return fmt.Errorf("write concern requires a value")
+		}
+	}
+	return nil
+}
+
+func (ast *DocumentAST) validateCollation() error {
+	if ast.Collation == nil {
+		return nil
+	}
+	if ast.Operation == OpInsert || ast.Operation == OpInsertMany {
+		return fmt.Errorf("collation is not valid for %s", ast.Operation)
+	}
+	if ast.Collation.Locale == "" {
+		return fmt.Errorf("collation requires a locale")
+	}
+	if ast.Collation.Strength < 1 || ast.Collation.Strength > 5 {
+		return fmt.Errorf("collation strength must be between 1 and 5: %d", ast.Collation.Strength)
+	}
+	return nil
+}
+
+func containsTextSearch(f FilterItem) bool {
+	switch filter := f.(type) {
+	case TextSearchFilter:
+		return true
+	case FilterGroup:
+		for _, c := range filter.Conditions {
+			if containsTextSearch(c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateFilterValues rejects empty value lists, which most providers
+// cannot render (SQL dialects have no empty IN list).
+func validateFilterValues(f FilterItem) error {
+	switch filter := f.(type) {
+	case ValuesFilter:
+		if len(filter.Values) == 0 {
+			return fmt.Errorf("%s on field %s requires at least one value", filter.Operator, filter.Field.Path)
+		}
+	case ArrayFilter:
+		if filter.Literal != nil && *filter.Literal < 0 {
+			return fmt.Errorf("%s on field %s must not be negative: %d", filter.Operator, filter.Field.Path, *filter.Literal)
+		}
+	case FilterGroup:
+		for _, c := range filter.Conditions {
+			if err := validateFilterValues(c); err != nil {
+				return err
+			}
+		}
+	case ElemMatchFilter:
+		for _, c := range filter.Conditions {
+			if err := validateFilterValues(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateTypeNames rejects $type filters naming a type outside TypeNames,
+// in the filter clause and in $match stages.
+func (ast *DocumentAST) validateTypeNames() error {
+	if ast.FilterClause != nil {
+		if err := validateFilterTypeNames(ast.FilterClause); err != nil {
+			return err
+		}
+	}
+	for _, stage := range ast.Pipeline {
+		if match, ok := stage.(MatchStage); ok {
+			if err := validateFilterTypeNames(match.Filter); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateFilterTypeNames(f FilterItem) error {
+	switch filter := f.(type) {
+	case TypeFilter:
+		if !slices.Contains(TypeNames, filter.TypeName) {
+			return fmt.Errorf("$type on field %s: unknown type name %q", filter.Field.Path, filter.TypeName)
+		}
+	case FilterGroup:
+		for _, c := range filter.Conditions {
+			if err := validateFilterTypeNames(c); err != nil {
+				return err
+			}
+		}
+	case ElemMatchFilter:
+		for _, c := range filter.Conditions {
+			if err := validateFilterTypeNames(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateParamNames rejects params with no name, which renderers would
+// otherwise emit as a bare ":" placeholder, and fields with no path. $unset
+// and $currentDate take no value, so their fields may leave the param empty.
+func (ast *DocumentAST) validateParamNames() error {
+	if ast.DistinctField != nil {
+		if err := checkField(*ast.DistinctField, "distinct"); err != nil {
+			return err
+		}
+	}
+	if ast.FilterClause != nil {
+		if err := checkFilterParams(ast.FilterClause); err != nil {
+			return err
+		}
+	}
+	if ast.Projection != nil {
+		if err := checkProjectionParams(*ast.Projection); err != nil {
+			return err
+		}
+	}
+	if err := checkPaginationParam(ast.Skip, "skip"); err != nil {
+		return err
+	}
+	if err := checkPaginationParam(ast.Limit, "limit"); err != nil {
+		return err
+	}
+	for _, doc := range ast.Documents {
+		if err := checkFieldParams(doc.Fields, "document"); err != nil {
+			return err
+		}
+	}
+	for _, op := range ast.UpdateOps {
+		if op.Operator == Unset || op.Operator == CurrentDate {
+			for f := range op.Fields {
+				if err := checkField(f, string(op.Operator)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := checkFieldParams(op.Fields, string(op.Operator)); err != nil {
+			return err
+		}
+	}
+	for i, stage := range ast.Pipeline {
+		if err := checkStageParams(stage); err != nil {
+			return fmt.Errorf("stage %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// checkParam reports an invalid param reference, or a param name that is
+// empty or not an identifier; context says where it was used.
+func checkParam(p *Param, context string) error {
+	if validParam(p) {
+		return nil
+	}
+	if p.err != nil {
+		return p.err
+	}
+	return &InvalidIdentifierError{Kind: "param name for " + context, Value: p.Name}
+}
+
+// validParam reports whether p is absent or a valid param, so callers can
+// skip building an error context for it.
+func validParam(p *Param) bool {
+	return p == nil || (p.err == nil && IsValidIdentifier(p.Name))
+}
+
+// checkField reports an invalid field reference, such as one an instance
+// could not resolve in strict mode, or a field with no path.
+func checkField(f Field, context string) error {
+	if validField(f) {
+		return nil
+	}
+	if f.err != nil {
+		return f.err
+	}
+	return &InvalidIdentifierError{Kind: "field path for " + context, Value: f.Path}
+}
+
+func validField(f Field) bool {
+	return f.err == nil && f.Path != ""
+}
+
+func checkPaginationParam(p *PaginationValue, context string) error {
+	if p == nil {
+		return nil
+	}
+	return checkParam(p.Param, context)
+}
+
+func checkFieldParams(fields map[Field]Param, context string) error {
+	for f, p := range fields {
+		if err := checkField(f, context); err != nil {
+			return err
+		}
+		if !validParam(&p) {
+			return checkParam(&p, context+" field '"+f.Path+"'")
+		}
+	}
+	return nil
+}
+
+func checkFilterParams(f FilterItem) error {
+	if field, ok := FilterField(f); ok {
+		if err := checkField(field, FilterName(f)); err != nil {
+			return err
+		}
+	}
+	switch filter := f.(type) {
+	case FilterCondition:
+		return checkFilterParam(f, filter.Field.Path, &filter.Value)
+	case RangeFilter:
+		return checkFilterParam(f, filter.Field.Path, filter.Min, filter.Max)
+	case RegexFilter:
+		return checkFilterParam(f, filter.Field.Path, &filter.Pattern, filter.Options)
+	case TextSearchFilter:
+		return checkFilterParam(f, "", &filter.Search, filter.Language)
+	case GeoFilter:
+		return checkFilterParam(f, filter.Field.Path, &filter.Center.Lon, &filter.Center.Lat, filter.Radius, filter.MaxDistance, filter.MinDistance)
+	case ArrayFilter:
+		if filter.Literal == nil {
+			return checkFilterParam(f, filter.Field.Path, &filter.Value)
+		}
+	case ValuesFilter:
+		for i := range filter.Values {
+			if err := checkFilterParam(f, filter.Field.Path, &filter.Values[i]); err != nil {
+				return err
+			}
+		}
+	case ModFilter:
+		return checkFilterParam(f, filter.Field.Path, &filter.Divisor, &filter.Remainder)
+	case FilterGroup:
+		for _, c := range filter.Conditions {
+			if err := checkFilterParams(c); err != nil {
+				return err
+			}
+		}
+	case ElemMatchFilter:
+		for _, c := range filter.Conditions {
+			if err := checkFilterParams(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkFilterParam checks the params of filter f, naming f and its field in
+// the error.
+func checkFilterParam(f FilterItem, field string, params ...*Param) error {
+	for _, p := range params {
+		if validParam(p) {
+			continue
+		}
+		context := FilterName(f)
+		if field != "" {
+			context += " on field '" + field + "'"
+		}
+		return checkParam(p, context)
+	}
+	return nil
+}
+
+// FilterField returns the field a filter applies to; groups and text search
+// have none.
+func FilterField(f FilterItem) (Field, bool) {
+	switch filter := f.(type) {
+	case FilterCondition:
+		return filter.Field, true
+	case RangeFilter:
+		return filter.Field, true
+	case RegexFilter:
+		return filter.Field, true
+	case GeoFilter:
+		return filter.Field, true
+	case ArrayFilter:
+		return filter.Field, true
+	case ValuesFilter:
+		return filter.Field, true
+	case ModFilter:
+		return filter.Field, true
+	case TypeFilter:
+		return filter.Field, true
+	case ElemMatchFilter:
+		return filter.Field, true
+	case NullFilter:
+		return filter.Field, true
+	case ExistsFilter:
+		return filter.Field, true
+	}
+	return Field{}, false
+}
+
+func checkProjectionParams(p Projection) error {
+	for _, f := range p.Fields {
+		if err := checkField(f.Field, "projection"); err != nil {
+			return err
+		}
+		if f.Slice != nil && (!validParam(&f.Slice.Count) || !validParam(f.Slice.Skip)) {
+			context := "$slice on field '" + f.Field.Path + "'"
+			if err := checkParam(&f.Slice.Count, context); err != nil {
+				return err
+			}
+			return checkParam(f.Slice.Skip, context)
+		}
+		if f.ElemMatch != nil {
+			for _, c := range f.ElemMatch.Conditions {
+				if err := checkFilterParams(c); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkExprParams checks the fields and params of e; context says where it
+// was used.
+func checkExprParams(e Expression, context string) error {
+	switch expr := e.(type) {
+	case FieldExpression:
+		return checkField(expr.Field, context)
+	case LiteralExpression:
+		return checkParam(&expr.Value, context)
+	case OperatorExpression:
+		for _, arg := range expr.Args {
+			if err := checkExprParams(arg, context); err != nil {
+				return err
+			}
+		}
+	case ConditionalExpression:
+		for _, sub := range [...]Expression{expr.If, expr.Then, expr.Else} {
+			if err := checkExprParams(sub, context); err != nil {
+				return err
+			}
+		}
+	case CompositeExpression:
+		for name, sub := range expr.Fields {
+			if !validExpr(sub) {
+				return checkExprParams(sub, context+" key '"+name+"'")
+			}
+		}
 	}
 	return nil
 }
 
-func validateFilterDepth(f FilterItem, depth int) error {
-	if depth > MaxFilterDepth {
-		return fmt.Errorf("filter nesting exceeds maximum depth: %d > %d", depth, MaxFilterDepth)
+// validExpr reports whether checkExprParams accepts e, so callers can skip
+// building an error context for it.
+func validExpr(e Expression) bool {
+	switch expr := e.(type) {
+	case FieldExpression:
+		return validField(expr.Field)
+	case LiteralExpression:
+		return validParam(&expr.Value)
+	case OperatorExpression:
+		for _, arg := range expr.Args {
+			if !validExpr(arg) {
+				return false
+			}
+		}
+	case ConditionalExpression:
+		return validExpr(expr.If) && validExpr(expr.Then) && validExpr(expr.Else)
+	case CompositeExpression:
+		for _, sub := range expr.Fields {
+			if !validExpr(sub) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// checkExprFields checks each expression in exprs, naming its key as label
+// within stage.
+func checkExprFields(exprs map[string]Expression, stage, label string) error {
+	for key, expr := range exprs {
+		if !validExpr(expr) {
+			return checkExprParams(expr, stage+" "+label+" '"+key+"'")
+		}
+	}
+	return nil
+}
+
+func checkStageParams(s PipelineStage) error {
+	name := s.StageName()
+	switch stage := s.(type) {
+	case MatchStage:
+		return checkFilterParams(stage.Filter)
+	case ProjectStage:
+		if err := checkProjectionParams(stage.Projection); err != nil {
+			return err
+		}
+		return checkExprFields(stage.Computed, name, "field")
+	case GroupStage:
+		if !validExpr(stage.ID) {
+			return checkExprParams(stage.ID, name+" _id")
+		}
+		return checkAccumulatorParams(stage.Accumulators, name)
+	case UnwindStage:
+		return checkField(stage.Path, name)
+	case LimitStage:
+		return checkPaginationParam(&stage.Limit, name)
+	case SkipStage:
+		return checkPaginationParam(&stage.Skip, name)
+	case SampleStage:
+		return checkPaginationParam(&stage.Size, name)
+	case LookupStage:
+		// The pipeline form of $lookup leaves the join fields empty.
+		if err := cmp.Or(stage.LocalField.err, stage.ForeignField.err); err != nil {
+			return err
+		}
+		if err := checkExprFields(stage.Let, name, "let"); err != nil {
+			return err
+		}
+		for _, sub := range stage.Pipeline {
+			if err := checkStageParams(sub); err != nil {
+				return err
+			}
+		}
+	case GraphLookupStage:
+		if err := checkField(stage.ConnectFromField, "$graphLookup connectFromField"); err != nil {
+			return err
+		}
+		if err := checkField(stage.ConnectToField, "$graphLookup connectToField"); err != nil {
+			return err
+		}
+		if err := checkExprParams(stage.StartWith, "$graphLookup startWith"); err != nil {
+			return err
+		}
+		if err := checkPaginationParam(stage.MaxDepth, "$graphLookup maxDepth"); err != nil {
+			return err
+		}
+		if stage.RestrictSearchWithMatch != nil {
+			return checkFilterParams(stage.RestrictSearchWithMatch)
+		}
+	case AddFieldsStage:
+		return checkExprFields(stage.Fields, name, "field")
+	case ReplaceRootStage:
+		return checkExprParams(stage.NewRoot, name)
+	case FacetStage:
+		for _, facet := range stage.Facets {
+			for _, sub := range facet {
+				if err := checkStageParams(sub); err != nil {
+					return err
+				}
+			}
+		}
+	case BucketStage:
+		if err := checkExprParams(stage.GroupBy, "$bucket groupBy"); err != nil {
+			return err
+		}
+		for i := range stage.Boundaries {
+			if err := checkParam(&stage.Boundaries[i], "$bucket boundaries"); err != nil {
+				return err
+			}
+		}
+		if err := checkParam(stage.Default, "$bucket default"); err != nil {
+			return err
+		}
+		return checkAccumulatorParams(stage.Output, name)
+	case SortByCountStage:
+		return checkExprParams(stage.Expr, name)
+	}
+	return nil
+}
+
+func checkAccumulatorParams(accs map[string]Accumulator, stage string) error {
+	for key, acc := range accs {
+		if !validExpr(acc.Expr) {
+			return checkExprParams(acc.Expr, stage+" field '"+key+"'")
+		}
+	}
+	return nil
+}
+
+// ValidateLimit reports a static limit that is not positive or exceeds
+// limits.MaxLimit. A limit of 0 is rejected rather than passed through, since
+// MongoDB reads it as no limit and CouchDB as no documents.
+func ValidateLimit(n int, limits Limits) error {
+	switch {
+	case n == 0:
+		return fmt.Errorf("limit 0 means no limit in some providers and no documents in others: omit the limit to return every document")
+	case n < 0:
+		return fmt.Errorf("limit must be positive: %d", n)
+	case n > limits.MaxLimit:
+		return &LimitExceededError{Limit: "MaxLimit", Value: n, Max: limits.MaxLimit}
+	}
+	return nil
+}
+
+// ValidateSkip reports a negative static skip.
+func ValidateSkip(n int) error {
+	if n < 0 {
+		return fmt.Errorf("skip must not be negative: %d", n)
+	}
+	return nil
+}
+
+// validatePagination checks the static skip and limit of the query.
+func (ast *DocumentAST) validatePagination(limits Limits) error {
+	if ast.Skip != nil && ast.Skip.Static != nil {
+		if err := ValidateSkip(*ast.Skip.Static); err != nil {
+			return err
+		}
+	}
+	if ast.Limit != nil && ast.Limit.Static != nil {
+		return ValidateLimit(*ast.Limit.Static, limits)
+	}
+	return nil
+}
+
+// validatePipelineFilters checks the depth and values of the filters in $match
+// and $graphLookup stages, including those in $facet and $lookup
+// sub-pipelines.
+func validatePipelineFilters(stages []PipelineStage, limits Limits) error {
+	for i, stage := range stages {
+		var err error
+		switch s := stage.(type) {
+		case MatchStage:
+			err = validateFilter(s.Filter, limits)
+		case GraphLookupStage:
+			err = validateFilter(s.RestrictSearchWithMatch, limits)
+		case LookupStage:
+			err = validatePipelineFilters(s.Pipeline, limits)
+		case FacetStage:
+			for _, name := range slices.Sorted(maps.Keys(s.Facets)) {
+				if err = validatePipelineFilters(s.Facets[name], limits); err != nil {
+					err = fmt.Errorf("facet %q: %w", name, err)
+					break
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("stage %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validateFilter checks a filter's depth and values; nil is valid.
+func validateFilter(f FilterItem, limits Limits) error {
+	if f == nil {
+		return nil
+	}
+	if err := validateFilterDepth(f, 0, limits.MaxFilterDepth); err != nil {
+		return err
+	}
+	return validateFilterValues(f)
+}
+
+// validateFilterConditions bounds the number of filter nodes in the query,
+// counting groups and $elemMatch as well as the conditions they hold, across
+// the filter clause and every pipeline filter. Depth alone does not bound a
+// filter: a flat AND can hold any number of conditions.
+func (ast *DocumentAST) validateFilterConditions(limits Limits) error {
+	n := countFilterNodes(ast.FilterClause) + countPipelineFilterNodes(ast.Pipeline)
+	if n > limits.MaxFilterConditions {
+		return &LimitExceededError{Limit: "MaxFilterConditions", Value: n, Max: limits.MaxFilterConditions}
+	}
+	return nil
+}
+
+// countFilterNodes counts f and every filter nested in it.
+func countFilterNodes(f FilterItem) int {
+	switch filter := f.(type) {
+	case nil:
+		return 0
+	case FilterGroup:
+		n := 1
+		for _, c := range filter.Conditions {
+			n += countFilterNodes(c)
+		}
+		return n
+	case ElemMatchFilter:
+		n := 1
+		for _, c := range filter.Conditions {
+			n += countFilterNodes(c)
+		}
+		return n
+	default:
+		return 1
+	}
+}
+
+// countPipelineFilterNodes counts the filter nodes of $match and
+// $graphLookup stages, including those in $facet and $lookup sub-pipelines.
+func countPipelineFilterNodes(stages []PipelineStage) int {
+	n := 0
+	for _, stage := range stages {
+		switch s := stage.(type) {
+		case MatchStage:
+			n += countFilterNodes(s.Filter)
+		case GraphLookupStage:
+			n += countFilterNodes(s.RestrictSearchWithMatch)
+		case LookupStage:
+			n += countPipelineFilterNodes(s.Pipeline)
+		case FacetStage:
+			for _, sub := range s.Facets {
+				n += countPipelineFilterNodes(sub)
+			}
+		}
+	}
+	return n
+}
+
+// ValidateFilterDepth reports whether f nests groups and $elemMatch deeper
+// than maxDepth, as validation does.
+func ValidateFilterDepth(f FilterItem, maxDepth int) error {
+	return validateFilterDepth(f, 0, maxDepth)
+}
+
+func validateFilterDepth(f FilterItem, depth, maxDepth int) error {
+	if depth > maxDepth {
+		return &LimitExceededError{Limit: "MaxFilterDepth", Value: depth, Max: maxDepth}
 	}
 
 	if group, ok := f.(FilterGroup); ok {
 		for _, c := range group.Conditions {
-			if err := validateFilterDepth(c, depth+1); err != nil {
+			if err := validateFilterDepth(c, depth+1, maxDepth); err != nil {
 				return err
 			}
 		}
@@ -170,7 +1219,7 @@ func validateFilterDepth(f FilterItem, depth int) error {
 
 	if em, ok := f.(ElemMatchFilter); ok {
 		for _, c := range em.Conditions {
-			if err := validateFilterDepth(c, depth+1); err != nil {
+			if err := validateFilterDepth(c, depth+1, maxDepth); err != nil {
 				return err
 			}
 		}
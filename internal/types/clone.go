@@ -0,0 +1,257 @@
+package types
+
+import (
+	"maps"
+	"slices"
+)
+
+// Clone returns a deep copy of the AST. Filters, projections, documents,
+// update operations, and pipeline stages are copied, so changes made through
+// either AST are never visible through the other.
+func (ast *DocumentAST) Clone() *DocumentAST {
+	if ast == nil {
+		return nil
+	}
+	out := *ast
+	out.Target.Param = clonePtr(ast.Target.Param)
+	out.FilterClause = cloneFilter(ast.FilterClause)
+	if ast.Projection != nil {
+		p := cloneProjection(*ast.Projection)
+		out.Projection = &p
+	}
+	out.SortClauses = slices.Clone(ast.SortClauses)
+	out.Skip = clonePagination(ast.Skip)
+	out.Limit = clonePagination(ast.Limit)
+	if ast.Documents != nil {
+		out.Documents = make([]Document, len(ast.Documents))
+		for i, doc := range ast.Documents {
+			out.Documents[i] = Document{Fields: maps.Clone(doc.Fields)}
+		}
+	}
+	if ast.UpdateOps != nil {
+		out.UpdateOps = make([]UpdateOperation, len(ast.UpdateOps))
+		for i, op := range ast.UpdateOps {
+			out.UpdateOps[i] = UpdateOperation{Operator: op.Operator, Fields: maps.Clone(op.Fields)}
+		}
+	}
+	out.Pipeline = clonePipeline(ast.Pipeline)
+	out.DistinctField = clonePtr(ast.DistinctField)
+	if ast.Operations != nil {
+		out.Operations = make([]*DocumentAST, len(ast.Operations))
+		for i, op := range ast.Operations {
+			out.Operations[i] = op.Clone()
+		}
+	}
+	out.MaxTimeMS = clonePtr(ast.MaxTimeMS)
+	out.Hint = clonePtr(ast.Hint)
+	out.Collation = clonePtr(ast.Collation)
+	out.ReadConcern = clonePtr(ast.ReadConcern)
+	out.WriteConcern = clonePtr(ast.WriteConcern)
+	out.FullDocument = clonePtr(ast.FullDocument)
+	out.ResumeAfter = clonePtr(ast.ResumeAfter)
+	out.Limits = clonePtr(ast.Limits)
+	return &out
+}
+
+// cloneFilter returns a deep copy of a filter tree.
+func cloneFilter(f FilterItem) FilterItem {
+	switch filter := f.(type) {
+	case FilterGroup:
+		filter.Conditions = cloneFilters(filter.Conditions)
+		return filter
+	case ElemMatchFilter:
+		filter.Conditions = cloneFilters(filter.Conditions)
+		return filter
+	case ValuesFilter:
+		filter.Values = slices.Clone(filter.Values)
+		return filter
+	case RangeFilter:
+		filter.Min = clonePtr(filter.Min)
+		filter.Max = clonePtr(filter.Max)
+		return filter
+	case RegexFilter:
+		filter.Options = clonePtr(filter.Options)
+		return filter
+	case TextSearchFilter:
+		filter.Language = clonePtr(filter.Language)
+		return filter
+	case GeoFilter:
+		filter.Radius = clonePtr(filter.Radius)
+		filter.MaxDistance = clonePtr(filter.MaxDistance)
+		filter.MinDistance = clonePtr(filter.MinDistance)
+		return filter
+	case ArrayFilter:
+		filter.Literal = clonePtr(filter.Literal)
+		return filter
+	default:
+		return f
+	}
+}
+
+func cloneFilters(items []FilterItem) []FilterItem {
+	if items == nil {
+		return nil
+	}
+	out := make([]FilterItem, len(items))
+	for i, item := range items {
+		out[i] = cloneFilter(item)
+	}
+	return out
+}
+
+func cloneProjection(p Projection) Projection {
+	if p.Fields == nil {
+		return p
+	}
+	fields := make([]ProjectionField, len(p.Fields))
+	for i, f := range p.Fields {
+		if f.Slice != nil {
+			f.Slice = &SliceOp{Count: f.Slice.Count, Skip: clonePtr(f.Slice.Skip)}
+		}
+		if f.ElemMatch != nil {
+			f.ElemMatch = &ElemMatchProjection{Conditions: cloneFilters(f.ElemMatch.Conditions)}
+		}
+		fields[i] = f
+	}
+	p.Fields = fields
+	return p
+}
+
+func clonePipeline(stages []PipelineStage) []PipelineStage {
+	if stages == nil {
+		return nil
+	}
+	out := make([]PipelineStage, len(stages))
+	for i, stage := range stages {
+		out[i] = cloneStage(stage)
+	}
+	return out
+}
+
+func cloneStage(stage PipelineStage) PipelineStage {
+	switch s := stage.(type) {
+	case MatchStage:
+		s.Filter = cloneFilter(s.Filter)
+		return s
+	case ProjectStage:
+		s.Projection = cloneProjection(s.Projection)
+		s.Computed = cloneExprMap(s.Computed)
+		return s
+	case GroupStage:
+		s.ID = cloneExpr(s.ID)
+		s.Accumulators = cloneAccumulators(s.Accumulators)
+		return s
+	case SortStage:
+		s.Sorts = slices.Clone(s.Sorts)
+		return s
+	case LimitStage:
+		s.Limit = *clonePagination(&s.Limit)
+		return s
+	case SkipStage:
+		s.Skip = *clonePagination(&s.Skip)
+		return s
+	case UnwindStage:
+		s.IncludeArrayIndex = clonePtr(s.IncludeArrayIndex)
+		return s
+	case LookupStage:
+		s.Pipeline = clonePipeline(s.Pipeline)
+		s.Let = cloneExprMap(s.Let)
+		return s
+	case GraphLookupStage:
+		s.StartWith = cloneExpr(s.StartWith)
+		s.MaxDepth = clonePagination(s.MaxDepth)
+		s.DepthField = clonePtr(s.DepthField)
+		s.RestrictSearchWithMatch = cloneFilter(s.RestrictSearchWithMatch)
+		return s
+	case AddFieldsStage:
+		s.Fields = cloneExprMap(s.Fields)
+		return s
+	case ReplaceRootStage:
+		s.NewRoot = cloneExpr(s.NewRoot)
+		return s
+	case FacetStage:
+		if s.Facets != nil {
+			facets := make(map[string][]PipelineStage, len(s.Facets))
+			for name, pipeline := range s.Facets {
+				facets[name] = clonePipeline(pipeline)
+			}
+			s.Facets = facets
+		}
+		return s
+	case BucketStage:
+		s.GroupBy = cloneExpr(s.GroupBy)
+		s.Boundaries = slices.Clone(s.Boundaries)
+		s.Default = clonePtr(s.Default)
+		s.Output = cloneAccumulators(s.Output)
+		return s
+	case SortByCountStage:
+		s.Expr = cloneExpr(s.Expr)
+		return s
+	case SampleStage:
+		s.Size = *clonePagination(&s.Size)
+		return s
+	default:
+		return stage
+	}
+}
+
+func cloneExpr(expr Expression) Expression {
+	switch e := expr.(type) {
+	case OperatorExpression:
+		if e.Args != nil {
+			args := make([]Expression, len(e.Args))
+			for i, arg := range e.Args {
+				args[i] = cloneExpr(arg)
+			}
+			e.Args = args
+		}
+		return e
+	case ConditionalExpression:
+		e.If = cloneExpr(e.If)
+		e.Then = cloneExpr(e.Then)
+		e.Else = cloneExpr(e.Else)
+		return e
+	case CompositeExpression:
+		e.Fields = cloneExprMap(e.Fields)
+		return e
+	default:
+		return expr
+	}
+}
+
+func cloneExprMap(m map[string]Expression) map[string]Expression {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]Expression, len(m))
+	for k, v := range m {
+		out[k] = cloneExpr(v)
+	}
+	return out
+}
+
+func cloneAccumulators(m map[string]Accumulator) map[string]Accumulator {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]Accumulator, len(m))
+	for k, v := range m {
+		out[k] = Accumulator{Operator: v.Operator, Expr: cloneExpr(v.Expr)}
+	}
+	return out
+}
+
+func clonePagination(p *PaginationValue) *PaginationValue {
+	if p == nil {
+		return nil
+	}
+	return &PaginationValue{Static: clonePtr(p.Static), Param: clonePtr(p.Param)}
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
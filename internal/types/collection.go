@@ -3,4 +3,42 @@ package types
 // Collection represents a reference to a document collection.
 type Collection struct {
 	Name string
+
+	// Param, when set, names the physical collection at bind time, as for
+	// per-tenant collections. Name stays the schema collection the query is
+	// built and checked against.
+	Param *Param
+
+	// Database names the database the collection belongs to, taken from the
+	// schema name by instance-bound constructors. Empty when unknown.
+	Database string
+
+	// err records why the reference is invalid; see InvalidCollection.
+	err error
+}
+
+// InvalidCollection returns a collection reference that fails validation
+// with err.
+func InvalidCollection(err error) Collection {
+	return Collection{err: err}
+}
+
+// Err returns the error an invalid collection reference was created with, or
+// nil.
+func (c Collection) Err() error {
+	return c.err
+}
+
+// ParameterizedTarget reports whether ast, or any operation of a
+// transaction, targets a collection named by a param.
+func (ast *DocumentAST) ParameterizedTarget() bool {
+	if ast.Target.Param != nil {
+		return true
+	}
+	for _, op := range ast.Operations {
+		if op.ParameterizedTarget() {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,283 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors for errors.Is. Each is matched by the structured error type
+// of the same kind, which errors.As extracts along with its context.
+var (
+	ErrUnknownCollection    = errors.New("unknown collection")
+	ErrUnknownField         = errors.New("unknown field")
+	ErrInvalidIdentifier    = errors.New("invalid identifier")
+	ErrUnsupportedOperation = errors.New("unsupported operation")
+	ErrUnsupportedFilter    = errors.New("unsupported filter")
+	ErrLimitExceeded        = errors.New("limit exceeded")
+	ErrMissingFilter        = errors.New("missing filter")
+	ErrInvalidAST           = errors.New("invalid AST")
+	ErrPolicyViolation      = errors.New("policy violation")
+)
+
+// UnknownCollectionError reports a collection name absent from the schema.
+type UnknownCollectionError struct {
+	Collection string
+}
+
+func (e *UnknownCollectionError) Error() string {
+	return fmt.Sprintf("collection '%s' not found in schema", e.Collection)
+}
+
+// Is matches ErrUnknownCollection.
+func (e *UnknownCollectionError) Is(target error) bool {
+	return target == ErrUnknownCollection
+}
+
+// UnknownFieldError reports field paths absent from a collection's schema.
+type UnknownFieldError struct {
+	Collection string
+	Fields     []string
+}
+
+func (e *UnknownFieldError) Error() string {
+	if len(e.Fields) == 1 {
+		return fmt.Sprintf("field '%s' not found in collection '%s'", e.Fields[0], e.Collection)
+	}
+	return fmt.Sprintf("unknown fields in collection '%s': %s", e.Collection, strings.Join(e.Fields, ", "))
+}
+
+// Is matches ErrUnknownField.
+func (e *UnknownFieldError) Is(target error) bool {
+	return target == ErrUnknownField
+}
+
+// InvalidIdentifierError reports a name that fails identifier validation.
+// Kind says what the name was for, e.g. "collection name" or "$addFields key".
+type InvalidIdentifierError struct {
+	Kind  string
+	Value string
+}
+
+func (e *InvalidIdentifierError) Error() string {
+	return fmt.Sprintf("invalid %s: %q", e.Kind, e.Value)
+}
+
+// Is matches ErrInvalidIdentifier.
+func (e *InvalidIdentifierError) Is(target error) bool {
+	return target == ErrInvalidIdentifier
+}
+
+// limitSubjects phrases LimitExceededError messages per Limits field.
+var limitSubjects = map[string]string{
+	"MaxLimit":            "limit exceeds maximum",
+	"MaxProjectionFields": "projection fields exceed maximum",
+	"MaxSortFields":       "sort fields exceed maximum",
+	"MaxBatchSize":        "batch size exceeds maximum",
+	"MaxPipelineStages":   "pipeline stages exceed maximum",
+	"MaxQueryTimeMS":      "maxTimeMS exceeds maximum",
+	"MaxFilterDepth":      "filter nesting exceeds maximum depth",
+	"MaxFilterConditions": "filter conditions exceed maximum",
+}
+
+// LimitExceededError reports a value over one of the configured Limits.
+// Limit names the Limits field that was exceeded, e.g. "MaxLimit".
+type LimitExceededError struct {
+	Limit string
+	Value int
+	Max   int
+}
+
+func (e *LimitExceededError) Error() string {
+	subject, ok := limitSubjects[e.Limit]
+	if !ok {
+		subject = e.Limit + " exceeded"
+	}
+	return fmt.Sprintf("%s: %d > %d", subject, e.Value, e.Max)
+}
+
+// Is matches ErrLimitExceeded.
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// MissingFilterError reports a write operation that requires a filter but
+// has none: the *_MANY operations always, the single-document writes when
+// RequireFilter is set.
+type MissingFilterError struct {
+	Operation Operation
+}
+
+func (e *MissingFilterError) Error() string {
+	switch e.Operation {
+	case OpUpdate, OpDelete:
+		return fmt.Sprintf("%s requires a filter when single-write filters are required", e.Operation)
+	default:
+		return fmt.Sprintf("%s requires a filter for safety", e.Operation)
+	}
+}
+
+// Is matches ErrMissingFilter.
+func (e *MissingFilterError) Is(target error) bool {
+	return target == ErrMissingFilter
+}
+
+// PolicyViolation is one way a query breaks a rendering policy. Rule names
+// the policy setting, such as "operation" or "field", and Value what broke
+// it, such as the operation or field path.
+type PolicyViolation struct {
+	Rule   string
+	Value  string
+	Reason string
+}
+
+func (v PolicyViolation) String() string {
+	return fmt.Sprintf("%s '%s' %s", v.Rule, v.Value, v.Reason)
+}
+
+// PolicyError lists every policy violation found in a query.
+type PolicyError struct {
+	Violations []PolicyViolation
+}
+
+func (e *PolicyError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	return "policy violation: " + strings.Join(parts, "; ")
+}
+
+// Is matches ErrPolicyViolation.
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// InvalidASTError wraps the validation failure a renderer hit before
+// rendering. The cause stays reachable through errors.Is and errors.As.
+type InvalidASTError struct {
+	Err error
+}
+
+func (e *InvalidASTError) Error() string {
+	return "invalid AST: " + e.Err.Error()
+}
+
+func (e *InvalidASTError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrInvalidAST.
+func (e *InvalidASTError) Is(target error) bool {
+	return target == ErrInvalidAST
+}
+
+// UnsupportedError reports AST features a renderer cannot express. Renderers
+// return it (possibly wrapped) for every unsupported case, so callers can use
+// errors.As to detect it and route the query to another provider.
+type UnsupportedError struct {
+	// Provider names the renderer that rejected the query.
+	Provider string
+	// Operation is the operation being rendered.
+	Operation Operation
+	// Filters lists unsupported filter and logic operators, e.g. "$regex".
+	Filters []string
+	// Features lists other unsupported features, e.g. "sort" or "update operator: $rename".
+	Features []string
+	// Reason optionally explains what the provider does support.
+	Reason string
+}
+
+// Error describes what was unsupported, falling back to the operation itself
+// when no specific filter or feature was recorded.
+func (e *UnsupportedError) Error() string {
+	var parts []string
+	if len(e.Filters) > 0 {
+		parts = append(parts, "filter operator: "+strings.Join(e.Filters, ", "))
+	}
+	parts = append(parts, e.Features...)
+	if len(parts) == 0 {
+		parts = append(parts, "operation: "+string(e.Operation))
+	}
+	msg := e.Provider + " does not support " + strings.Join(parts, "; ")
+	if e.Provider == "" {
+		msg = "unsupported " + strings.Join(parts, "; ")
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// Is matches ErrUnsupportedFilter when filter operators were recorded, and
+// ErrUnsupportedOperation when anything else, or nothing specific, was.
+func (e *UnsupportedError) Is(target error) bool {
+	switch target {
+	case ErrUnsupportedFilter:
+		return len(e.Filters) > 0
+	case ErrUnsupportedOperation:
+		return len(e.Features) > 0 || len(e.Filters) == 0
+	}
+	return false
+}
+
+// UnsupportedOperation reports an operation the provider cannot render at all.
+func UnsupportedOperation(provider string, op Operation) *UnsupportedError {
+	return &UnsupportedError{Provider: provider, Operation: op}
+}
+
+// UnsupportedFilter reports filter or logic operators the provider cannot render.
+func UnsupportedFilter(provider string, filters ...string) *UnsupportedError {
+	return &UnsupportedError{Provider: provider, Filters: filters}
+}
+
+// UnsupportedFeature reports non-filter features the provider cannot render.
+func UnsupportedFeature(provider string, features ...string) *UnsupportedError {
+	return &UnsupportedError{Provider: provider, Features: features}
+}
+
+// WithOperation records op on an UnsupportedError in err's chain that was
+// raised below the level where the operation is known. Other errors are
+// returned unchanged.
+func WithOperation(err error, op Operation) error {
+	var unsupported *UnsupportedError
+	if errors.As(err, &unsupported) && unsupported.Operation == "" {
+		unsupported.Operation = op
+	}
+	return err
+}
+
+// FilterName returns the operator name that identifies a filter item, such
+// as "$eq" for an equality condition or "$and" for a group.
+func FilterName(f FilterItem) string {
+	switch filter := f.(type) {
+	case FilterCondition:
+		return string(filter.Operator)
+	case FilterGroup:
+		return string(filter.Logic)
+	case RangeFilter:
+		return "range"
+	case RegexFilter:
+		return string(Regex)
+	case TextSearchFilter:
+		return string(Text)
+	case GeoFilter:
+		return string(filter.Operator)
+	case ArrayFilter:
+		return string(filter.Operator)
+	case ValuesFilter:
+		return string(filter.Operator)
+	case ModFilter:
+		return string(Mod)
+	case TypeFilter:
+		return string(Type)
+	case ElemMatchFilter:
+		return string(ElemMatch)
+	case ExistsFilter:
+		return string(Exists)
+	case NullFilter:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", f)
+	}
+}
@@ -5,4 +5,19 @@ package types
 type Field struct {
 	Path       string
 	Collection string
+
+	// err records why the reference is invalid; see InvalidField.
+	err error
+}
+
+// InvalidField returns a field reference that fails validation with err, for
+// constructors that report a bad reference through the query rather than by
+// panicking.
+func InvalidField(err error) Field {
+	return Field{err: err}
+}
+
+// Err returns the error an invalid field reference was created with, or nil.
+func (f Field) Err() error {
+	return f.err
 }
@@ -22,13 +22,15 @@ type FilterGroup struct {
 
 func (FilterGroup) isFilterItem() {}
 
-// RangeFilter represents a range query with min/max bounds.
+// RangeFilter represents a range query with min/max bounds. Negated matches
+// values outside the range instead.
 type RangeFilter struct {
 	Field        Field
 	Min          *Param
 	Max          *Param
 	MinExclusive bool
 	MaxExclusive bool
+	Negated      bool
 }
 
 func (RangeFilter) isFilterItem() {}
@@ -75,10 +77,48 @@ type ArrayFilter struct {
 	Field    Field
 	Operator FilterOperator
 	Value    Param
+
+	// Literal, when set, is a $size length rendered directly in place of
+	// Value.
+	Literal *int
 }
 
 func (ArrayFilter) isFilterItem() {}
 
+// ValuesFilter represents $in/$nin against a list of individually bound
+// params, as opposed to a FilterCondition whose single param holds an array.
+type ValuesFilter struct {
+	Field    Field
+	Operator FilterOperator // IN or NotIn
+	Values   []Param
+}
+
+func (ValuesFilter) isFilterItem() {}
+
+// ModFilter represents a $mod query matching documents where the field
+// divided by Divisor leaves Remainder.
+type ModFilter struct {
+	Field     Field
+	Divisor   Param
+	Remainder Param
+}
+
+func (ModFilter) isFilterItem() {}
+
+// TypeFilter represents a $type query matching documents whose field holds a
+// value of the named BSON type. The type name is part of the query's shape,
+// not user data, so it is a literal checked against TypeNames instead of a
+// Param.
+type TypeFilter struct {
+	Field    Field
+	TypeName string
+}
+
+func (TypeFilter) isFilterItem() {}
+
+// TypeNames lists the BSON type names a TypeFilter accepts.
+var TypeNames = []string{"string", "int", "double", "bool", "date", "objectId", "array", "object", "null", "long", "decimal"}
+
 // ElemMatchFilter represents an $elemMatch query for array elements.
 type ElemMatchFilter struct {
 	Field      Field
@@ -87,6 +127,18 @@ type ElemMatchFilter struct {
 
 func (ElemMatchFilter) isFilterItem() {}
 
+// NullFilter matches fields that are null or, when Negated, not null. Stores
+// differ on whether a missing field counts as null; Strict requires the
+// field to be present, so a strict null match skips missing fields and a
+// strict not-null match requires the field to exist.
+type NullFilter struct {
+	Field   Field
+	Negated bool
+	Strict  bool
+}
+
+func (NullFilter) isFilterItem() {}
+
 // ExistsFilter represents a field existence check.
 type ExistsFilter struct {
 	Field  Field
@@ -0,0 +1,108 @@
+package types
+
+import "strings"
+
+var suspiciousPatterns = []string{
+	";", "--", "/*", "*/", "'", "\"", "`", "\\",
+	" or ", " and ", "drop ", "delete ", "insert ",
+	"update ", "select ", "union ", "exec ", "execute ",
+}
+
+// IsValidIdentifier reports whether s is a safe collection, param, or alias
+// name: an ASCII letter or underscore followed by letters, digits, or
+// underscores, free of injection-suspicious substrings.
+func IsValidIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	// Explicit space rejection as defense-in-depth
+	if strings.Contains(s, " ") {
+		return false
+	}
+
+	for i, r := range s {
+		if i == 0 {
+			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && r != '_' {
+				return false
+			}
+		} else {
+			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+				return false
+			}
+		}
+	}
+
+	return !hasSuspiciousPattern(s)
+}
+
+// IsValidFieldPath reports whether s is a safe dot-notation field path. Each
+// segment follows the identifier rules, except that a segment may begin with $.
+func IsValidFieldPath(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	// Explicit space rejection as defense-in-depth
+	if strings.Contains(s, " ") {
+		return false
+	}
+
+	for part := range strings.SplitSeq(s, ".") {
+		if part == "" {
+			return false
+		}
+		for i, r := range part {
+			if i == 0 {
+				if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && r != '_' && r != '$' {
+					return false
+				}
+			} else {
+				if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+					return false
+				}
+			}
+		}
+	}
+
+	return !hasSuspiciousPattern(s)
+}
+
+// patternBytes holds a byte of every suspicious pattern, so a string with
+// none of them cannot match and skips the scan.
+const patternBytes = ";-/*'\"`\\ "
+
+// hasSuspiciousPattern reports whether s contains a suspicious pattern,
+// ignoring ASCII case, without allocating a lowercased copy.
+func hasSuspiciousPattern(s string) bool {
+	if !strings.ContainsAny(s, patternBytes) {
+		return false
+	}
+	for _, pattern := range suspiciousPatterns {
+		if containsFoldASCII(s, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFoldASCII reports whether s contains the lowercase pattern with
+// ASCII letters in s matched case-insensitively.
+func containsFoldASCII(s, pattern string) bool {
+	for i := 0; i+len(pattern) <= len(s); i++ {
+		j := 0
+		for ; j < len(pattern); j++ {
+			c := s[i+j]
+			if 'A' <= c && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			if c != pattern[j] {
+				break
+			}
+		}
+		if j == len(pattern) {
+			return true
+		}
+	}
+	return false
+}
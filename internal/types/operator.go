@@ -1,5 +1,7 @@
 package types
 
+import "cmp"
+
 // FilterOperator represents document filter operators.
 type FilterOperator string
 
@@ -108,14 +110,75 @@ const (
 	OpAggregate  Operation = "AGGREGATE"
 	OpCount      Operation = "COUNT"
 	OpDistinct   Operation = "DISTINCT"
+
+	// OpWatch subscribes to a collection's change stream.
+	OpWatch Operation = "WATCH"
+
+	// OpTransaction groups write operations that commit atomically.
+	OpTransaction Operation = "TRANSACTION"
 )
 
+// IsWrite reports whether op inserts, updates, or deletes documents.
+func (op Operation) IsWrite() bool {
+	switch op {
+	case OpInsert, OpInsertMany, OpUpdate, OpUpdateMany, OpDelete, OpDeleteMany:
+		return true
+	default:
+		return false
+	}
+}
+
 // Complexity limits.
 const (
 	MaxFilterDepth      = 10
+	MaxFilterConditions = 500
 	MaxBatchSize        = 1000
 	MaxLimit            = 10000
 	MaxProjectionFields = 100
 	MaxSortFields       = 10
 	MaxPipelineStages   = 50
+	MaxQueryTimeMS      = 60 * 60 * 1000
 )
+
+// Limits holds the complexity ceilings applied during validation.
+type Limits struct {
+	MaxFilterDepth      int
+	MaxFilterConditions int
+	MaxBatchSize        int
+	MaxLimit            int
+	MaxProjectionFields int
+	MaxSortFields       int
+	MaxPipelineStages   int
+	MaxQueryTimeMS      int
+}
+
+// WithDefaults returns l with every field left at zero taken from
+// DefaultLimits, so a Limits that sets only some ceilings keeps the defaults
+// for the rest.
+func (l Limits) WithDefaults() Limits {
+	d := DefaultLimits()
+	return Limits{
+		MaxFilterDepth:      cmp.Or(l.MaxFilterDepth, d.MaxFilterDepth),
+		MaxFilterConditions: cmp.Or(l.MaxFilterConditions, d.MaxFilterConditions),
+		MaxBatchSize:        cmp.Or(l.MaxBatchSize, d.MaxBatchSize),
+		MaxLimit:            cmp.Or(l.MaxLimit, d.MaxLimit),
+		MaxProjectionFields: cmp.Or(l.MaxProjectionFields, d.MaxProjectionFields),
+		MaxSortFields:       cmp.Or(l.MaxSortFields, d.MaxSortFields),
+		MaxPipelineStages:   cmp.Or(l.MaxPipelineStages, d.MaxPipelineStages),
+		MaxQueryTimeMS:      cmp.Or(l.MaxQueryTimeMS, d.MaxQueryTimeMS),
+	}
+}
+
+// DefaultLimits returns the package-wide complexity limits.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxFilterDepth:      MaxFilterDepth,
+		MaxFilterConditions: MaxFilterConditions,
+		MaxBatchSize:        MaxBatchSize,
+		MaxLimit:            MaxLimit,
+		MaxProjectionFields: MaxProjectionFields,
+		MaxSortFields:       MaxSortFields,
+		MaxPipelineStages:   MaxPipelineStages,
+		MaxQueryTimeMS:      MaxQueryTimeMS,
+	}
+}
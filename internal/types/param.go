@@ -3,4 +3,17 @@ package types
 // Param represents a named parameter reference.
 type Param struct {
 	Name string
+
+	// err records why the reference is invalid; see InvalidParam.
+	err error
+}
+
+// InvalidParam returns a param reference that fails validation with err.
+func InvalidParam(err error) Param {
+	return Param{err: err}
+}
+
+// Err returns the error an invalid param reference was created with, or nil.
+func (p Param) Err() error {
+	return p.err
 }
@@ -1,5 +1,15 @@
 package types
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// IDField is the document identifier field, the only field that may be
+// excluded from an include projection (or included in an exclude projection).
+const IDField = "_id"
+
 // Projection represents field selection for query results.
 type Projection struct {
 	Fields  []ProjectionField
@@ -12,6 +22,50 @@ type ProjectionField struct {
 	Include   bool
 	Slice     *SliceOp
 	ElemMatch *ElemMatchProjection
+	// Alias renames an included field in results; empty keeps its path.
+	Alias string
+}
+
+// OutputName returns the name the field has in results: its alias, or its path.
+func (f ProjectionField) OutputName() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Field.Path
+}
+
+// Aliases maps the path of each aliased field to its alias, or returns nil
+// when no field is aliased. Renderers for providers that cannot rename fields
+// server-side report it as QueryResult.FieldAliases.
+func (p *Projection) Aliases() map[string]string {
+	var aliases map[string]string
+	for _, f := range p.Fields {
+		if f.Alias == "" {
+			continue
+		}
+		if aliases == nil {
+			aliases = make(map[string]string)
+		}
+		aliases[f.Field.Path] = f.Alias
+	}
+	return aliases
+}
+
+// ExcludesOnly reports whether p lists fields but includes none of them, as
+// when only _id is excluded. Renderers whose select lists can only name
+// included fields would otherwise list nothing and return every field.
+func (p *Projection) ExcludesOnly() bool {
+	return len(p.Fields) > 0 && !slices.ContainsFunc(p.Fields, func(f ProjectionField) bool { return f.Include })
+}
+
+// ExcludedPaths joins the field paths of an exclusion projection, for
+// errors naming the fields a renderer cannot exclude.
+func (p *Projection) ExcludedPaths() string {
+	paths := make([]string, len(p.Fields))
+	for i, f := range p.Fields {
+		paths[i] = f.Field.Path
+	}
+	return strings.Join(paths, ", ")
 }
 
 // SliceOp represents $slice projection for arrays.
@@ -24,3 +78,40 @@ type SliceOp struct {
 type ElemMatchProjection struct {
 	Conditions []FilterItem
 }
+
+// Validate rejects duplicate field paths, mixed include/exclude fields other
+// than the _id special case, and aliases that are invalid, attached to
+// anything but a plain included field, or clash with another output name.
+func (p *Projection) Validate() error {
+	seen := make(map[string]bool, len(p.Fields))
+	outputs := make(map[string]bool, len(p.Fields))
+	for _, f := range p.Fields {
+		if seen[f.Field.Path] {
+			return fmt.Errorf("duplicate projection field: %s", f.Field.Path)
+		}
+		seen[f.Field.Path] = true
+
+		if f.Alias != "" {
+			if !IsValidIdentifier(f.Alias) {
+				return &InvalidIdentifierError{Kind: "alias", Value: f.Alias}
+			}
+			if !f.Include || f.Slice != nil || f.ElemMatch != nil {
+				return fmt.Errorf("alias requires a plain included field: %s", f.Field.Path)
+			}
+		}
+		if f.Include {
+			if outputs[f.OutputName()] {
+				return fmt.Errorf("duplicate projection output name: %s", f.OutputName())
+			}
+			outputs[f.OutputName()] = true
+		}
+
+		if f.Field.Path == IDField {
+			continue
+		}
+		if f.Include == p.Exclude {
+			return fmt.Errorf("projection cannot mix included and excluded fields: %s", f.Field.Path)
+		}
+	}
+	return nil
+}
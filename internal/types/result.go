@@ -1,10 +1,112 @@
 package types
 
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
 // QueryResult represents the result of rendering a document query.
 type QueryResult struct {
+	// Operation is the operation of the rendered AST.
+	Operation Operation
+
+	// Single reports a FIND_ONE, which renderers limit to one document, so
+	// executors can fetch and return a single result.
+	Single bool
+
+	// Collection is the target collection of the rendered AST.
+	Collection string
+
+	// Database is the database of the target collection, when known.
+	Database string
+
 	// JSON contains the rendered query in provider-specific format.
 	JSON string
 
 	// RequiredParams lists the parameter names that must be provided at execution time.
 	RequiredParams []string
+
+	// Warnings lists query features the provider could not express natively.
+	Warnings []string
+
+	// EnumParams maps parameter names to the enum values they must match.
+	// Populated by DOCQL.Render from the schema; nil when rendering directly.
+	EnumParams map[string][]string
+
+	// ParamMinimums maps params bound to a skip or limit to the smallest
+	// value they accept: 0 for a skip and 1 for a limit, the bounds
+	// validation applies to static values. Populated by Builder.Render and
+	// DOCQL.Render; nil when rendering directly or when none are bound.
+	ParamMinimums map[string]int
+
+	// FieldAliases maps source field paths to the names results should expose,
+	// for providers that cannot rename fields server-side. Executors rename
+	// the fields after reading results; nil when nothing needs renaming.
+	FieldAliases map[string]string
+}
+
+// Fingerprint returns a stable hash of the rendered query's shape: the hex
+// SHA-256 of its JSON with insignificant whitespace removed. Object keys keep
+// the order the renderer wrote them in, since order is significant in places
+// such as a MongoDB sort document. Params render as placeholders, so queries
+// that differ only in the values bound at execution share a fingerprint.
+// Output that is not JSON is hashed as is.
+func (r *QueryResult) Fingerprint() string {
+	data := []byte(r.JSON)
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err == nil {
+		data = compact.Bytes()
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Redacted returns the rendered query with each :param placeholder of a
+// required param replaced by the token <redacted:param>, and a map from each
+// token to the param it stands for. Unlike binding, no values are
+// substituted: logs show where each param goes in a form that cannot be
+// mistaken for an executable query. A placeholder matches only whole param
+// names: with params id and id2, :id2 becomes <redacted:id2>.
+func (r *QueryResult) Redacted() (string, map[string]string) {
+	tokens := make(map[string]string, len(r.RequiredParams))
+	names := make(map[string]bool, len(r.RequiredParams))
+	for _, name := range r.RequiredParams {
+		names[name] = true
+		tokens[redactionToken(name)] = name
+	}
+
+	var out strings.Builder
+	out.Grow(len(r.JSON))
+	s := r.JSON
+	for {
+		i := strings.IndexByte(s, ':')
+		if i < 0 {
+			out.WriteString(s)
+			break
+		}
+		end := i + 1
+		for end < len(s) && isIdentByte(s[end]) {
+			end++
+		}
+		out.WriteString(s[:i])
+		if name := s[i+1 : end]; names[name] {
+			out.WriteString(redactionToken(name))
+		} else {
+			out.WriteString(s[i:end])
+		}
+		s = s[end:]
+	}
+	return out.String(), tokens
+}
+
+func redactionToken(name string) string {
+	return "<redacted:" + name + ">"
+}
+
+// isIdentByte reports whether c can appear in a param name.
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
 }
@@ -1,9 +1,119 @@
 package types
 
+import "fmt"
+
 // SortClause represents a single sort specification.
 type SortClause struct {
 	Field Field
 	Order SortOrder
+	Opts  SortOpts
+}
+
+// SortOpts adjusts how a sort clause compares strings. Collation applies to
+// a whole query, so every clause that sets options must set the same ones;
+// providers without collation ignore them with a warning.
+type SortOpts struct {
+	// CaseInsensitive orders "apple" and "Apple" together.
+	CaseInsensitive bool
+
+	// Numeric compares digit runs by value, so "SKU-9" sorts before "SKU-10".
+	Numeric bool
+}
+
+// IsZero reports whether no options are set.
+func (o SortOpts) IsZero() bool {
+	return !o.CaseInsensitive && !o.Numeric
+}
+
+// SortOpts returns the options set by the query's sort clauses, including
+// those of top-level $sort stages, and whether any clause set them.
+func (ast *DocumentAST) SortOpts() (SortOpts, bool) {
+	if opts, ok := firstSortOpts(ast.SortClauses); ok {
+		return opts, true
+	}
+	for _, stage := range ast.Pipeline {
+		if s, ok := stage.(SortStage); ok {
+			if opts, ok := firstSortOpts(s.Sorts); ok {
+				return opts, true
+			}
+		}
+	}
+	return SortOpts{}, false
+}
+
+func firstSortOpts(sorts []SortClause) (SortOpts, bool) {
+	for _, s := range sorts {
+		if !s.Opts.IsZero() {
+			return s.Opts, true
+		}
+	}
+	return SortOpts{}, false
+}
+
+// validateSortOpts checks the query's sort clauses, then those of its
+// top-level $sort stages, against the first clause that sets options.
+func (ast *DocumentAST) validateSortOpts() error {
+	var first *SortClause
+	check := func(sorts []SortClause) error {
+		for i := range sorts {
+			s := &sorts[i]
+			if s.Opts.IsZero() {
+				continue
+			}
+			if first == nil {
+				first = s
+				if ast.Collation != nil && s.Opts.CaseInsensitive && ast.Collation.Strength > 2 {
+					return fmt.Errorf("case-insensitive sort on '%s' conflicts with collation strength %d", s.Field.Path, ast.Collation.Strength)
+				}
+				continue
+			}
+			if s.Opts != first.Opts {
+				return fmt.Errorf("conflicting sort options on '%s' and '%s': collation applies to the whole query", first.Field.Path, s.Field.Path)
+			}
+		}
+		return nil
+	}
+	if err := check(ast.SortClauses); err != nil {
+		return err
+	}
+	for _, stage := range ast.Pipeline {
+		if s, ok := stage.(SortStage); ok {
+			if err := check(s.Sorts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateSortFields rejects a field sorted twice in one sort, which leaves
+// the intended order ambiguous.
+func (ast *DocumentAST) validateSortFields() error {
+	if err := uniqueSortFields(ast.SortClauses); err != nil {
+		return err
+	}
+	for i, stage := range ast.Pipeline {
+		if s, ok := stage.(SortStage); ok {
+			if err := uniqueSortFields(s.Sorts); err != nil {
+				return fmt.Errorf("stage %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func uniqueSortFields(sorts []SortClause) error {
+	seen := make(map[string]bool, len(sorts))
+	for _, s := range sorts {
+		if err := checkField(s.Field, "sort"); err != nil {
+			return err
+		}
+		if seen[s.Field.Path] {
+			return fmt.Errorf("duplicate sort field '%s'", s.Field.Path)
+		}
+		seen[s.Field.Path] = true
+	}
+	return nil
 }
 
 // PaginationValue represents a skip or limit value (static or parameterized).
@@ -11,3 +121,11 @@ type PaginationValue struct {
 	Static *int
 	Param  *Param
 }
+
+// Collation selects language-aware string comparison for matching and
+// sorting. Strength follows ICU levels 1-5: 1 compares base letters only,
+// 2 adds accents, and 3 (the default) adds case.
+type Collation struct {
+	Locale   string
+	Strength int
+}
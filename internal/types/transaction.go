@@ -0,0 +1,31 @@
+package types
+
+import "fmt"
+
+// RenderOperations renders each operation of a transaction with render, for
+// renderers that describe a transaction as a list of their ordinary writes.
+// It returns the rendered operations in order, their parameters
+// de-duplicated in first-use order, and their warnings prefixed with the
+// operation index.
+func RenderOperations(ast *DocumentAST, render func(*DocumentAST) (*QueryResult, error)) ([]*QueryResult, []string, []string, error) {
+	results := make([]*QueryResult, len(ast.Operations))
+	var params, warnings []string
+	seen := make(map[string]bool)
+	for i, op := range ast.Operations {
+		result, err := render(op)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		results[i] = result
+		for _, p := range result.RequiredParams {
+			if !seen[p] {
+				seen[p] = true
+				params = append(params, p)
+			}
+		}
+		for _, w := range result.Warnings {
+			warnings = append(warnings, fmt.Sprintf("operation %d: %s", i, w))
+		}
+	}
+	return results, params, warnings, nil
+}
@@ -1,6 +1,13 @@
 package types
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestDocumentAST_Validate_FindOperation(t *testing.T) {
 	ast := &DocumentAST{
@@ -213,6 +220,102 @@ func TestDocumentAST_Validate_Distinct_WithField(t *testing.T) {
 	}
 }
 
+func TestDocumentAST_Validate_RejectsEmptyFieldPath(t *testing.T) {
+	empty := Field{}
+	tests := []struct {
+		name string
+		ast  *DocumentAST
+	}{
+		{"filter", &DocumentAST{Operation: OpFind, Target: Collection{Name: "users"},
+			FilterClause: FilterCondition{Field: empty, Operator: EQ, Value: Param{Name: "x"}}}},
+		{"sort", &DocumentAST{Operation: OpFind, Target: Collection{Name: "users"},
+			SortClauses: []SortClause{{Field: empty, Order: Ascending}}}},
+		{"projection", &DocumentAST{Operation: OpFind, Target: Collection{Name: "users"},
+			Projection: &Projection{Fields: []ProjectionField{{Field: empty, Include: true}}}}},
+		{"document", &DocumentAST{Operation: OpInsert, Target: Collection{Name: "users"},
+			Documents: []Document{{Fields: map[Field]Param{empty: {Name: "x"}}}}}},
+		{"unset", &DocumentAST{Operation: OpUpdate, Target: Collection{Name: "users"},
+			UpdateOps: []UpdateOperation{{Operator: Unset, Fields: map[Field]Param{empty: {}}}}}},
+		{"distinct", &DocumentAST{Operation: OpDistinct, Target: Collection{Name: "users"}, DistinctField: &empty}},
+		{"stage", &DocumentAST{Operation: OpAggregate, Target: Collection{Name: "users"},
+			Pipeline: []PipelineStage{UnwindStage{Path: empty}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.ast.Validate()
+			if err == nil || !strings.Contains(err.Error(), "invalid field path") {
+				t.Errorf("Expected an empty field path to be rejected, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDocumentAST_Validate_InvalidReferences(t *testing.T) {
+	cause := errors.New("unknown field: nope")
+	ast := &DocumentAST{
+		Operation:    OpFind,
+		Target:       Collection{Name: "users"},
+		FilterClause: FilterCondition{Field: InvalidField(cause), Operator: EQ, Value: Param{Name: "x"}},
+	}
+	if err := ast.Validate(); !errors.Is(err, cause) {
+		t.Errorf("Expected the field's error, got %v", err)
+	}
+
+	ast.FilterClause = FilterCondition{Field: Field{Path: "status"}, Operator: EQ, Value: InvalidParam(cause)}
+	if err := ast.Validate(); !errors.Is(err, cause) {
+		t.Errorf("Expected the param's error, got %v", err)
+	}
+
+	ast.Target = InvalidCollection(cause)
+	if err := ast.Validate(); !errors.Is(err, cause) {
+		t.Errorf("Expected the collection's error, got %v", err)
+	}
+}
+
+func TestDocumentAST_Validate_Distinct_SortAndLimit(t *testing.T) {
+	field := Field{Path: "address.city"}
+	limit := 10
+	tests := []struct {
+		name    string
+		modify  func(*DocumentAST)
+		wantErr string
+	}{
+		{"sort and limit", func(ast *DocumentAST) {
+			ast.SortClauses = []SortClause{{Field: field, Order: Descending}}
+			ast.Limit = &PaginationValue{Static: &limit}
+		}, ""},
+		{"sort by other field", func(ast *DocumentAST) {
+			ast.SortClauses = []SortClause{{Field: Field{Path: "status"}, Order: Ascending}}
+		}, `DISTINCT can only sort by its field "address.city"`},
+		{"skip", func(ast *DocumentAST) {
+			ast.Skip = &PaginationValue{Static: &limit}
+		}, "DISTINCT does not support skip"},
+		{"projection", func(ast *DocumentAST) {
+			ast.Projection = &Projection{Fields: []ProjectionField{{Field: field, Include: true}}}
+		}, "DISTINCT does not support projection"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast := &DocumentAST{
+				Operation:     OpDistinct,
+				Target:        Collection{Name: "users"},
+				DistinctField: &field,
+			}
+			tt.modify(ast)
+			err := ast.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestDocumentAST_Validate_LimitExceedsMax(t *testing.T) {
 	limit := MaxLimit + 1
 	ast := &DocumentAST{
@@ -327,3 +430,772 @@ func TestMaxLimit_Value(t *testing.T) {
 		t.Errorf("Expected MaxLimit to be 10000, got %d", MaxLimit)
 	}
 }
+
+func TestDocumentAST_Validate_MaxTimeMS(t *testing.T) {
+	valid := 5000
+	zero := 0
+	tooLarge := MaxQueryTimeMS + 1
+
+	ast := &DocumentAST{Operation: OpFind, Target: Collection{Name: "users"}, MaxTimeMS: &valid}
+	if err := ast.Validate(); err != nil {
+		t.Errorf("Expected no error for valid maxTimeMS, got: %v", err)
+	}
+
+	ast.MaxTimeMS = &zero
+	if err := ast.Validate(); err == nil {
+		t.Error("Expected error for zero maxTimeMS")
+	}
+
+	ast.MaxTimeMS = &tooLarge
+	if err := ast.Validate(); err == nil {
+		t.Error("Expected error for maxTimeMS over an hour")
+	}
+
+	ast = &DocumentAST{Operation: OpDelete, Target: Collection{Name: "users"}, MaxTimeMS: &valid}
+	if err := ast.Validate(); err == nil {
+		t.Error("Expected error for maxTimeMS on a write operation")
+	}
+}
+
+func TestDocumentAST_Validate_Aggregate_TextSearchFirstStage(t *testing.T) {
+	ast := &DocumentAST{
+		Operation: OpAggregate,
+		Target:    Collection{Name: "articles"},
+		Pipeline: []PipelineStage{
+			MatchStage{Filter: TextSearchFilter{Search: Param{Name: "q"}}},
+			LimitStage{Limit: PaginationValue{Param: &Param{Name: "limit"}}},
+		},
+	}
+
+	if err := ast.Validate(); err != nil {
+		t.Errorf("Expected no error for $text in first stage, got: %v", err)
+	}
+}
+
+func TestDocumentAST_Validate_Aggregate_TextSearchLaterStage(t *testing.T) {
+	ast := &DocumentAST{
+		Operation: OpAggregate,
+		Target:    Collection{Name: "articles"},
+		Pipeline: []PipelineStage{
+			MatchStage{Filter: FilterCondition{Field: Field{Path: "status"}, Operator: EQ, Value: Param{Name: "status"}}},
+			MatchStage{Filter: FilterGroup{
+				Logic: AND,
+				Conditions: []FilterItem{
+					FilterCondition{Field: Field{Path: "lang"}, Operator: EQ, Value: Param{Name: "lang"}},
+					TextSearchFilter{Search: Param{Name: "q"}},
+				},
+			}},
+		},
+	}
+
+	if err := ast.Validate(); err == nil {
+		t.Error("Expected error for $text in a later $match stage")
+	}
+}
+
+func TestDocumentAST_Validate_Aggregate_RejectsTopLevelLimit(t *testing.T) {
+	limit := 10
+	ast := &DocumentAST{
+		Operation: OpAggregate,
+		Target:    Collection{Name: "orders"},
+		Pipeline:  []PipelineStage{CountStage{FieldName: "total"}},
+		Limit:     &PaginationValue{Static: &limit},
+	}
+
+	if err := ast.Validate(); err == nil {
+		t.Error("Expected error for top-level limit on aggregate")
+	}
+}
+
+func TestDocumentAST_Validate_Aggregate_RejectsTopLevelSort(t *testing.T) {
+	ast := &DocumentAST{
+		Operation:   OpAggregate,
+		Target:      Collection{Name: "orders"},
+		Pipeline:    []PipelineStage{CountStage{FieldName: "total"}},
+		SortClauses: []SortClause{{Field: Field{Path: "total"}, Order: Descending}},
+	}
+
+	err := ast.Validate()
+	if err == nil || !strings.Contains(err.Error(), "$sort pipeline stage") {
+		t.Errorf("Expected error directing to a $sort stage, got: %v", err)
+	}
+}
+
+func TestDocumentAST_ValidateWithLimits(t *testing.T) {
+	limit := 500
+	ast := &DocumentAST{
+		Operation: OpFind,
+		Target:    Collection{Name: "users"},
+		Limit:     &PaginationValue{Static: &limit},
+	}
+
+	if err := ast.Validate(); err != nil {
+		t.Errorf("Expected default limits to allow %d, got %v", limit, err)
+	}
+
+	lowered := DefaultLimits()
+	lowered.MaxLimit = 100
+	if err := ast.ValidateWithLimits(lowered); err == nil {
+		t.Error("Expected lowered MaxLimit to reject limit")
+	}
+
+	ast.Limits = &lowered
+	if err := ast.Validate(); err == nil {
+		t.Error("Expected Validate to use AST limits")
+	}
+}
+
+func TestDocumentAST_ValidateWithLimits_Raised(t *testing.T) {
+	limit := MaxLimit * 2
+	ast := &DocumentAST{
+		Operation: OpFind,
+		Target:    Collection{Name: "users"},
+		Limit:     &PaginationValue{Static: &limit},
+	}
+
+	if err := ast.Validate(); err == nil {
+		t.Error("Expected default limits to reject limit")
+	}
+
+	raised := DefaultLimits()
+	raised.MaxLimit = limit
+	if err := ast.ValidateWithLimits(raised); err != nil {
+		t.Errorf("Expected raised MaxLimit to allow limit, got %v", err)
+	}
+}
+
+func TestDocumentAST_ValidateWithLimits_FilterDepth(t *testing.T) {
+	ast := &DocumentAST{
+		Operation: OpFind,
+		Target:    Collection{Name: "users"},
+		FilterClause: FilterGroup{
+			Logic: AND,
+			Conditions: []FilterItem{
+				FilterGroup{Logic: OR, Conditions: []FilterItem{
+					FilterCondition{Field: Field{Path: "a"}, Operator: EQ, Value: Param{Name: "a"}},
+				}},
+			},
+		},
+	}
+
+	limits := DefaultLimits()
+	limits.MaxFilterDepth = 1
+	if err := ast.ValidateWithLimits(limits); err == nil {
+		t.Error("Expected lowered MaxFilterDepth to reject nested filter")
+	}
+}
+
+func TestDocumentAST_ValidateWithLimits_PartialWithFilter(t *testing.T) {
+	ast := &DocumentAST{
+		Operation: OpFind,
+		Target:    Collection{Name: "users"},
+		FilterClause: FilterCondition{
+			Field: Field{Path: "a"}, Operator: EQ, Value: Param{Name: "a"},
+		},
+	}
+
+	partial := Limits{MaxLimit: 50}
+	if err := ast.ValidateWithLimits(partial); err != nil {
+		t.Errorf("Expected partial limits to keep default MaxFilterConditions, got %v", err)
+	}
+
+	ast.Limits = &partial
+	if err := ast.Validate(); err != nil {
+		t.Errorf("Expected partial AST limits to keep defaults, got %v", err)
+	}
+}
+
+// nestedFilter returns a filter nesting one condition depth groups deep.
+func nestedFilter(depth int) FilterItem {
+	var f FilterItem = FilterCondition{Field: Field{Path: "a"}, Operator: EQ, Value: Param{Name: "a"}}
+	for range depth {
+		f = FilterGroup{Logic: AND, Conditions: []FilterItem{f}}
+	}
+	return f
+}
+
+func TestDocumentAST_Validate_FilterConditions(t *testing.T) {
+	conditions := make([]FilterItem, 100000)
+	for i := range conditions {
+		conditions[i] = FilterCondition{Field: Field{Path: "a"}, Operator: EQ, Value: Param{Name: "a"}}
+	}
+	ast := &DocumentAST{
+		Operation:    OpFind,
+		Target:       Collection{Name: "users"},
+		FilterClause: FilterGroup{Logic: AND, Conditions: conditions},
+	}
+	var exceeded *LimitExceededError
+	err := ast.Validate()
+	if !errors.As(err, &exceeded) || exceeded.Limit != "MaxFilterConditions" || exceeded.Value != 100001 {
+		t.Fatalf("expected MaxFilterConditions error counting 100001 nodes, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "100001 > 500") {
+		t.Errorf("expected the count in the message, got %q", err)
+	}
+
+	// $elemMatch conditions and pipeline filters count toward the same total.
+	limits := DefaultLimits()
+	limits.MaxFilterConditions = 4
+	elem := ElemMatchFilter{Field: Field{Path: "items"}, Conditions: conditions[:2]}
+	ast.FilterClause = FilterGroup{Logic: AND, Conditions: []FilterItem{elem, conditions[0]}}
+	if err := ast.ValidateWithLimits(limits); !errors.As(err, &exceeded) || exceeded.Value != 5 {
+		t.Errorf("expected 5 nodes counted inside $elemMatch, got %v", err)
+	}
+
+	agg := &DocumentAST{
+		Operation: OpAggregate,
+		Target:    Collection{Name: "users"},
+		Pipeline: []PipelineStage{
+			MatchStage{Filter: FilterGroup{Logic: OR, Conditions: conditions[:2]}},
+			FacetStage{Facets: map[string][]PipelineStage{
+				"recent": {MatchStage{Filter: FilterGroup{Logic: AND, Conditions: conditions[:2]}}},
+			}},
+		},
+	}
+	if err := agg.ValidateWithLimits(limits); !errors.As(err, &exceeded) || exceeded.Value != 6 {
+		t.Errorf("expected 6 nodes counted across pipeline filters, got %v", err)
+	}
+	limits.MaxFilterConditions = 6
+	if err := agg.ValidateWithLimits(limits); err != nil {
+		t.Errorf("expected 6 nodes to be allowed, got %v", err)
+	}
+}
+
+func TestDocumentAST_Validate_PipelineFilterDepth(t *testing.T) {
+	deep := nestedFilter(MaxFilterDepth + 1)
+	tests := []struct {
+		name     string
+		pipeline []PipelineStage
+		wantErr  string
+	}{
+		{
+			name:     "match",
+			pipeline: []PipelineStage{MatchStage{Filter: deep}},
+			wantErr:  "stage 0: filter nesting",
+		},
+		{
+			name: "facet",
+			pipeline: []PipelineStage{
+				MatchStage{Filter: nestedFilter(1)},
+				FacetStage{Facets: map[string][]PipelineStage{
+					"ok":  {MatchStage{Filter: nestedFilter(1)}},
+					"bad": {CountStage{FieldName: "n"}, MatchStage{Filter: deep}},
+				}},
+			},
+			wantErr: `stage 1: facet "bad": stage 1: filter nesting`,
+		},
+		{
+			name: "facet in lookup",
+			pipeline: []PipelineStage{
+				LookupStage{From: "posts", As: "posts", Pipeline: []PipelineStage{
+					FacetStage{Facets: map[string][]PipelineStage{"x": {MatchStage{Filter: deep}}}},
+				}},
+			},
+			wantErr: `stage 0: stage 0: facet "x": stage 0: filter nesting`,
+		},
+		{
+			name: "graph lookup restriction",
+			pipeline: []PipelineStage{
+				GraphLookupStage{
+					From:                    "users",
+					StartWith:               FieldExpression{Field: Field{Path: "manager"}},
+					ConnectFromField:        Field{Path: "manager"},
+					ConnectToField:          Field{Path: "_id"},
+					As:                      "chain",
+					RestrictSearchWithMatch: deep,
+				},
+			},
+			wantErr: "stage 0: filter nesting",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast := &DocumentAST{Operation: OpAggregate, Target: Collection{Name: "users"}, Pipeline: tt.pipeline}
+			err := ast.Validate()
+			if !errors.Is(err, ErrLimitExceeded) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestProjection_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		proj    Projection
+		wantErr bool
+	}{
+		{
+			name: "includes",
+			proj: Projection{Fields: []ProjectionField{
+				{Field: Field{Path: "a"}, Include: true},
+				{Field: Field{Path: "b"}, Include: true},
+			}},
+		},
+		{
+			name: "excludes",
+			proj: Projection{Exclude: true, Fields: []ProjectionField{
+				{Field: Field{Path: "a"}},
+			}},
+		},
+		{
+			name: "includes without _id",
+			proj: Projection{Fields: []ProjectionField{
+				{Field: Field{Path: IDField}},
+				{Field: Field{Path: "a"}, Include: true},
+			}},
+		},
+		{
+			name: "mixed",
+			proj: Projection{Fields: []ProjectionField{
+				{Field: Field{Path: "a"}, Include: true},
+				{Field: Field{Path: "b"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate",
+			proj: Projection{Fields: []ProjectionField{
+				{Field: Field{Path: "a"}, Include: true},
+				{Field: Field{Path: "a"}, Include: true},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "aliases",
+			proj: Projection{Fields: []ProjectionField{
+				{Field: Field{Path: IDField}, Include: true, Alias: "id"},
+				{Field: Field{Path: "createdAt"}, Include: true, Alias: "created_at"},
+				{Field: Field{Path: "name"}, Include: true},
+			}},
+		},
+		{
+			name: "invalid alias",
+			proj: Projection{Fields: []ProjectionField{
+				{Field: Field{Path: "a"}, Include: true, Alias: "a-b"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "alias on exclusion",
+			proj: Projection{Exclude: true, Fields: []ProjectionField{
+				{Field: Field{Path: "a"}, Alias: "b"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "alias clashes with included field",
+			proj: Projection{Fields: []ProjectionField{
+				{Field: Field{Path: "a"}, Include: true, Alias: "b"},
+				{Field: Field{Path: "b"}, Include: true},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.proj.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("Expected error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestProjection_Exclusions(t *testing.T) {
+	id := ProjectionField{Field: Field{Path: IDField}}
+	name := ProjectionField{Field: Field{Path: "name"}, Include: true}
+
+	if (&Projection{}).ExcludesOnly() {
+		t.Error("expected an empty projection not to exclude only")
+	}
+	if !(&Projection{Fields: []ProjectionField{id}}).ExcludesOnly() {
+		t.Error("expected an _id-only exclusion to exclude only")
+	}
+	if (&Projection{Fields: []ProjectionField{id, name}}).ExcludesOnly() {
+		t.Error("expected a projection with an included field not to exclude only")
+	}
+
+	p := &Projection{Exclude: true, Fields: []ProjectionField{{Field: Field{Path: "password"}}, {Field: Field{Path: "token"}}}}
+	if got := p.ExcludedPaths(); got != "password, token" {
+		t.Errorf("ExcludedPaths() = %q, want %q", got, "password, token")
+	}
+}
+
+func TestDocumentAST_Validate_MixedProjection(t *testing.T) {
+	ast := &DocumentAST{
+		Operation: OpFind,
+		Target:    Collection{Name: "users"},
+		Projection: &Projection{Fields: []ProjectionField{
+			{Field: Field{Path: "a"}, Include: true},
+			{Field: Field{Path: "b"}},
+		}},
+	}
+	if err := ast.Validate(); err == nil {
+		t.Error("Expected error for mixed projection")
+	}
+
+	ast = &DocumentAST{
+		Operation: OpAggregate,
+		Target:    Collection{Name: "users"},
+		Pipeline: []PipelineStage{
+			ProjectStage{Projection: Projection{Fields: []ProjectionField{
+				{Field: Field{Path: "a"}, Include: true},
+				{Field: Field{Path: "a"}, Include: true},
+			}}},
+		},
+	}
+	if err := ast.Validate(); err == nil {
+		t.Error("Expected error for duplicate $project field")
+	}
+}
+
+func TestUnsupportedError_Error(t *testing.T) {
+	tests := []struct {
+		err  *UnsupportedError
+		want string
+	}{
+		{UnsupportedOperation("postgres", OpAggregate), "postgres does not support operation: AGGREGATE"},
+		{UnsupportedFilter("firestore", "$or"), "firestore does not support filter operator: $or"},
+		{UnsupportedFeature("kv", "sort", "skip"), "kv does not support sort; skip"},
+		{&UnsupportedError{Provider: "kv", Filters: []string{"$gt"}, Features: []string{"sort"}, Reason: "only get by _id"}, "kv does not support filter operator: $gt; sort: only get by _id"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestWithOperation(t *testing.T) {
+	err := WithOperation(fmt.Errorf("stage 0: %w", UnsupportedFilter("mongo", "$near")), OpAggregate)
+
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("expected *UnsupportedError in chain, got %v", err)
+	}
+	if unsupported.Operation != OpAggregate {
+		t.Errorf("Operation = %q, want %q", unsupported.Operation, OpAggregate)
+	}
+
+	unsupported = UnsupportedOperation("mongo", OpFind)
+	_ = WithOperation(unsupported, OpAggregate)
+	if unsupported.Operation != OpFind {
+		t.Errorf("WithOperation overwrote Operation: %q", unsupported.Operation)
+	}
+
+	plain := errors.New("boom")
+	if WithOperation(plain, OpFind) != plain {
+		t.Error("WithOperation should return other errors unchanged")
+	}
+}
+
+func TestDocumentAST_Validate_EmptyValues(t *testing.T) {
+	ast := &DocumentAST{
+		Operation: OpFind,
+		Target:    Collection{Name: "users"},
+		FilterClause: FilterGroup{Logic: AND, Conditions: []FilterItem{
+			ValuesFilter{Field: Field{Path: "status"}, Operator: IN},
+		}},
+	}
+	if err := ast.Validate(); err == nil {
+		t.Error("Expected error for empty $in value list")
+	}
+
+	for _, op := range []Operation{OpUpdate, OpUpdateMany, OpDelete, OpDeleteMany, OpCount, OpDistinct} {
+		ast := &DocumentAST{
+			Operation:    op,
+			Target:       Collection{Name: "users"},
+			FilterClause: ValuesFilter{Field: Field{Path: "status"}, Operator: IN},
+			UpdateOps:    []UpdateOperation{{Operator: Set, Fields: map[Field]Param{{Path: "status"}: {Name: "status"}}}},
+		}
+		if op == OpDistinct {
+			ast.UpdateOps = nil
+			ast.DistinctField = &Field{Path: "status"}
+		} else if op != OpUpdate && op != OpUpdateMany {
+			ast.UpdateOps = nil
+		}
+		if err := ast.Validate(); err == nil || !strings.Contains(err.Error(), "at least one value") {
+			t.Errorf("%s: expected error for empty $in value list, got %v", op, err)
+		}
+	}
+}
+
+func TestValidate_StructuredErrors(t *testing.T) {
+	limits := DefaultLimits()
+	over := limits.MaxLimit + 1
+
+	err := (&DocumentAST{Operation: OpFind, Target: Collection{Name: "users"}, Limit: &PaginationValue{Static: &over}}).Validate()
+	var exceeded *LimitExceededError
+	if !errors.As(err, &exceeded) || exceeded.Limit != "MaxLimit" || exceeded.Value != over || exceeded.Max != limits.MaxLimit {
+		t.Errorf("expected *LimitExceededError, got %v", err)
+	}
+	if want := fmt.Sprintf("limit exceeds maximum: %d > %d", over, limits.MaxLimit); err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	err = (&DocumentAST{Operation: OpDeleteMany, Target: Collection{Name: "users"}}).Validate()
+	var missing *MissingFilterError
+	if !errors.As(err, &missing) || missing.Operation != OpDeleteMany {
+		t.Errorf("expected *MissingFilterError, got %v", err)
+	}
+	if err.Error() != "DELETE_MANY requires a filter for safety" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+
+	err = (&DocumentAST{Operation: "DROP", Target: Collection{Name: "users"}}).Validate()
+	if !errors.Is(err, ErrUnsupportedOperation) || err.Error() != "unsupported operation: DROP" {
+		t.Errorf("expected unsupported operation error, got %v", err)
+	}
+}
+
+func TestUnsupportedError_Is(t *testing.T) {
+	if !errors.Is(UnsupportedFilter("firestore", "$or"), ErrUnsupportedFilter) {
+		t.Error("filter error should match ErrUnsupportedFilter")
+	}
+	if errors.Is(UnsupportedFilter("firestore", "$or"), ErrUnsupportedOperation) {
+		t.Error("filter-only error should not match ErrUnsupportedOperation")
+	}
+	if !errors.Is(UnsupportedOperation("postgres", OpAggregate), ErrUnsupportedOperation) {
+		t.Error("operation error should match ErrUnsupportedOperation")
+	}
+	if !errors.Is(fmt.Errorf("stage 0: %w", UnsupportedFeature("kv", "sort")), ErrUnsupportedOperation) {
+		t.Error("wrapped feature error should match ErrUnsupportedOperation")
+	}
+}
+
+func TestValidate_NestedPipelineStagesCount(t *testing.T) {
+	limits := DefaultLimits()
+	limits.MaxPipelineStages = 5
+
+	sub := []PipelineStage{
+		MatchStage{Filter: FilterCondition{Field: Field{Path: "a"}, Operator: EQ, Value: Param{Name: "a"}}},
+		CountStage{FieldName: "n"},
+	}
+	facet := FacetStage{Facets: map[string][]PipelineStage{"x": sub, "y": sub}}
+
+	// 1 facet stage + 4 nested = 5: at the cap.
+	ast := &DocumentAST{Operation: OpAggregate, Target: Collection{Name: "users"}, Pipeline: []PipelineStage{facet}, Limits: &limits}
+	if err := ast.Validate(); err != nil {
+		t.Fatalf("unexpected error at cap: %v", err)
+	}
+
+	facet.Facets["z"] = sub
+	ast.Pipeline = []PipelineStage{facet}
+	err := ast.Validate()
+	var exceeded *LimitExceededError
+	if !errors.As(err, &exceeded) || exceeded.Limit != "MaxPipelineStages" || exceeded.Value != 7 {
+		t.Errorf("expected MaxPipelineStages exceeded with 7 stages, got %v", err)
+	}
+
+	ast.Pipeline = []PipelineStage{LookupStage{From: "orders", As: "o", Pipeline: append(sub, sub...)}, CountStage{FieldName: "n"}}
+	if err := ast.Validate(); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected $lookup sub-pipeline to count toward the cap, got %v", err)
+	}
+}
+
+func TestSuspiciousPatterns_ContainPatternByte(t *testing.T) {
+	for _, pattern := range suspiciousPatterns {
+		if !strings.ContainsAny(pattern, patternBytes) {
+			t.Errorf("pattern %q has no byte in patternBytes; hasSuspiciousPattern would skip it", pattern)
+		}
+	}
+}
+
+func TestHasSuspiciousPattern(t *testing.T) {
+	for _, s := range []string{"a;b", "x--y", "/*c*/", "it's", "1 OR 1", "Drop table", "UNION all", "Execute proc", "a\\b"} {
+		if !hasSuspiciousPattern(s) {
+			t.Errorf("expected %q to be suspicious", s)
+		}
+	}
+	for _, s := range []string{"users", "orders.items.sku", "DropTable", "_id", "$elemMatch", "a-b"} {
+		if hasSuspiciousPattern(s) {
+			t.Errorf("expected %q not to be suspicious", s)
+		}
+	}
+}
+
+func TestQueryResult_Fingerprint(t *testing.T) {
+	a := &QueryResult{JSON: `{"collection":"users","filter":{"age":{"$gt":":min"},"status":{"$eq":":s"}},"limit":10}`}
+	b := &QueryResult{JSON: "{\n  \"collection\": \"users\",\n  \"filter\": {\"age\": {\"$gt\": \":min\"}, \"status\": {\"$eq\": \":s\"}},\n  \"limit\": 10\n}"}
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Error("expected whitespace to be ignored")
+	}
+	if len(a.Fingerprint()) != 64 {
+		t.Errorf("expected a hex SHA-256, got %q", a.Fingerprint())
+	}
+
+	c := &QueryResult{JSON: `{"collection":"users","filter":{"age":{"$gt":":min"},"status":{"$eq":":s"}},"limit":11}`}
+	if a.Fingerprint() == c.Fingerprint() {
+		t.Error("expected different values to change the fingerprint")
+	}
+
+	ab := &QueryResult{JSON: `{"collection":"users","sort":{"a":1,"b":1}}`}
+	ba := &QueryResult{JSON: `{"collection":"users","sort":{"b":1,"a":1}}`}
+	if ab.Fingerprint() == ba.Fingerprint() {
+		t.Error("expected key order to change the fingerprint")
+	}
+
+	sql := &QueryResult{JSON: "SELECT * FROM users WHERE status = $1"}
+	other := &QueryResult{JSON: "SELECT * FROM users WHERE status = $2"}
+	if sql.Fingerprint() == other.Fingerprint() || sql.Fingerprint() != sql.Fingerprint() {
+		t.Error("expected non-JSON output to be hashed as is")
+	}
+}
+
+func TestQueryResult_Redacted(t *testing.T) {
+	r := &QueryResult{
+		JSON:           `{"filter":{"_id":{"$in":[":id",":id2"]},"status":":status"},"key":"users::id","note":"10:30","query":"to_jsonb(:status::text)"}`,
+		RequiredParams: []string{"id", "id2", "status"},
+	}
+	redacted, tokens := r.Redacted()
+
+	want := `{"filter":{"_id":{"$in":["<redacted:id>","<redacted:id2>"]},"status":"<redacted:status>"},"key":"users:<redacted:id>","note":"10:30","query":"to_jsonb(<redacted:status>::text)"}`
+	if redacted != want {
+		t.Errorf("unexpected redaction:\n got: %s\nwant: %s", redacted, want)
+	}
+	wantTokens := map[string]string{"<redacted:id>": "id", "<redacted:id2>": "id2", "<redacted:status>": "status"}
+	if !reflect.DeepEqual(tokens, wantTokens) {
+		t.Errorf("expected tokens %v, got %v", wantTokens, tokens)
+	}
+	if !json.Valid([]byte(redacted)) {
+		t.Errorf("expected redacted JSON to stay valid: %s", redacted)
+	}
+
+	empty := &QueryResult{JSON: `{"collection":"users"}`}
+	if redacted, tokens := empty.Redacted(); redacted != empty.JSON || len(tokens) != 0 {
+		t.Errorf("expected a query without params to be unchanged, got %s and %v", redacted, tokens)
+	}
+}
+
+func TestDocumentAST_Validate_UpdatePathConflicts(t *testing.T) {
+	set := func(path string) UpdateOperation {
+		return UpdateOperation{Operator: Set, Fields: map[Field]Param{{Path: path}: {Name: "v"}}}
+	}
+	ast := &DocumentAST{
+		Operation: OpUpdate,
+		Target:    Collection{Name: "users"},
+		UpdateOps: []UpdateOperation{set("profile.name"), set("profile.name")},
+	}
+	if err := ast.Validate(); err == nil || !strings.Contains(err.Error(), "'profile.name' in $set and 'profile.name' in $set") {
+		t.Errorf("expected duplicate path error, got %v", err)
+	}
+
+	ast.UpdateOps = []UpdateOperation{set("profile.name"), set("profile.email")}
+	if err := ast.Validate(); err != nil {
+		t.Errorf("expected sibling paths to be allowed, got %v", err)
+	}
+}
+
+func TestDocumentAST_Validate_SortOpts(t *testing.T) {
+	name := Field{Path: "name"}
+	sku := Field{Path: "sku"}
+	ci := SortOpts{CaseInsensitive: true}
+	numeric := SortOpts{Numeric: true}
+
+	tests := []struct {
+		name      string
+		sorts     []SortClause
+		pipeline  []PipelineStage
+		collation *Collation
+		wantErr   string
+	}{
+		{name: "matching options", sorts: []SortClause{{Field: name, Opts: ci}, {Field: sku, Opts: ci}}},
+		{name: "options with plain clause", sorts: []SortClause{{Field: name, Opts: numeric}, {Field: sku}}},
+		{name: "case-insensitive under strength 2", sorts: []SortClause{{Field: name, Opts: ci}}, collation: &Collation{Locale: "en", Strength: 2}},
+		{name: "conflicting clauses", sorts: []SortClause{{Field: name, Opts: ci}, {Field: sku, Opts: numeric}}, wantErr: "conflicting sort options on 'name' and 'sku'"},
+		{name: "conflicting collation", sorts: []SortClause{{Field: name, Opts: ci}}, collation: &Collation{Locale: "en", Strength: 3}, wantErr: "conflicts with collation strength 3"},
+		{
+			name:     "conflicting sort stages",
+			pipeline: []PipelineStage{SortStage{Sorts: []SortClause{{Field: name, Opts: ci}}}, SortStage{Sorts: []SortClause{{Field: sku, Opts: numeric}}}},
+			wantErr:  "conflicting sort options",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast := &DocumentAST{Operation: OpFind, Target: Collection{Name: "products"}, SortClauses: tt.sorts, Collation: tt.collation}
+			if tt.pipeline != nil {
+				ast.Operation = OpAggregate
+				ast.Pipeline = tt.pipeline
+			}
+			err := ast.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDocumentAST_Validate_Pagination(t *testing.T) {
+	n := func(v int) *PaginationValue { return &PaginationValue{Static: &v} }
+
+	tests := []struct {
+		name        string
+		skip, limit *PaginationValue
+		ok          bool
+	}{
+		{"skip 0", n(0), nil, true},
+		{"skip -1", n(-1), nil, false},
+		{"limit 1", nil, n(1), true},
+		{"limit 0", nil, n(0), false},
+		{"limit -1", nil, n(-1), false},
+		{"limit over max", nil, n(MaxLimit + 1), false},
+		{"param", &PaginationValue{Param: &Param{Name: "skip"}}, &PaginationValue{Param: &Param{Name: "limit"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, op := range []Operation{OpFind, OpCount} {
+				ast := &DocumentAST{Operation: op, Target: Collection{Name: "users"}, Skip: tt.skip, Limit: tt.limit}
+				if err := ast.Validate(); (err == nil) != tt.ok {
+					t.Errorf("%s: Validate() = %v, want ok %t", op, err, tt.ok)
+				}
+			}
+		})
+	}
+
+	stages := map[string]PipelineStage{
+		"$skip -1": SkipStage{Skip: *n(-1)},
+		"$limit 0": LimitStage{Limit: *n(0)},
+	}
+	for name, stage := range stages {
+		agg := &DocumentAST{Operation: OpAggregate, Target: Collection{Name: "users"}, Pipeline: []PipelineStage{stage}}
+		if err := agg.Validate(); err == nil || !strings.HasPrefix(err.Error(), "stage 0: ") {
+			t.Errorf("%s: expected a stage error, got %v", name, err)
+		}
+	}
+}
+
+func TestDocumentAST_Validate_DuplicateSortFields(t *testing.T) {
+	name := Field{Path: "name"}
+	find := &DocumentAST{
+		Operation:   OpFind,
+		Target:      Collection{Name: "products"},
+		SortClauses: []SortClause{{Field: name, Order: Ascending}, {Field: name, Order: Descending}},
+	}
+	if err := find.Validate(); err == nil || !strings.Contains(err.Error(), "duplicate sort field 'name'") {
+		t.Errorf("expected duplicate sort field error, got %v", err)
+	}
+
+	agg := &DocumentAST{
+		Operation: OpAggregate,
+		Target:    Collection{Name: "products"},
+		Pipeline: []PipelineStage{
+			SortStage{Sorts: []SortClause{{Field: name, Order: Ascending}}},
+			SortStage{Sorts: []SortClause{{Field: name, Order: Descending}, {Field: name, Order: Ascending}}},
+		},
+	}
+	if err := agg.Validate(); err == nil || !strings.Contains(err.Error(), "stage 1: duplicate sort field") {
+		t.Errorf("expected duplicate sort field error in stage 1, got %v", err)
+	}
+}
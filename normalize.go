@@ -0,0 +1,247 @@
+package docql
+
+import "github.com/zoobzio/docql/internal/types"
+
+// NormalizeFilter returns an equivalent filter tree with redundant structure
+// removed. AND groups nested in AND groups, and OR in OR, are flattened;
+// duplicate conditions in an AND or OR are dropped; AND and OR groups with a
+// single condition are replaced by it; and within an AND, one lower and one
+// upper bound on the same field are merged into a single RangeFilter. Two
+// bounds on the same side are kept, since which is tighter depends on the
+// param values. $elemMatch conditions are normalized as an AND. NOR and NOT
+// groups keep their shape; only their children are normalized.
+//
+// Build applies NormalizeFilter to the filter clause unless PreserveFilter is
+// set. The input is not modified, and is returned as it is when already
+// normalized.
+func NormalizeFilter(f types.FilterItem) types.FilterItem {
+	if isNormalized(f) {
+		return f
+	}
+	switch filter := f.(type) {
+	case types.FilterGroup:
+		return normalizeGroup(filter)
+	case types.ElemMatchFilter:
+		filter.Conditions = normalizeConditions(types.AND, filter.Conditions)
+		return filter
+	default:
+		return f
+	}
+}
+
+// maxNormalizedCheck bounds the operands isNormalized compares pairwise;
+// larger groups take the full pass.
+const maxNormalizedCheck = 16
+
+// isNormalized reports whether NormalizeFilter would leave f unchanged,
+// without building anything. It is conservative: operands that might be
+// duplicates or mergeable bounds report false and are left to the full pass.
+func isNormalized(f types.FilterItem) bool {
+	switch filter := f.(type) {
+	case types.FilterGroup:
+		if filter.Logic != types.AND && filter.Logic != types.OR {
+			for _, c := range filter.Conditions {
+				if !isNormalized(c) {
+					return false
+				}
+			}
+			return true
+		}
+		return len(filter.Conditions) != 1 && normalizedConditions(filter.Logic, filter.Conditions)
+	case types.ElemMatchFilter:
+		return normalizedConditions(types.AND, filter.Conditions)
+	default:
+		return true
+	}
+}
+
+// normalizedConditions reports whether normalizeConditions would leave the
+// operands of an AND or OR unchanged.
+func normalizedConditions(logic types.LogicOperator, conditions []types.FilterItem) bool {
+	if len(conditions) > maxNormalizedCheck {
+		return false
+	}
+	for i, c := range conditions {
+		if nested, ok := c.(types.FilterGroup); ok && nested.Logic == logic {
+			return false
+		}
+		if !isNormalized(c) {
+			return false
+		}
+		for _, prev := range conditions[:i] {
+			if mayDuplicate(prev, c) || (logic == types.AND && isBound(prev) && isBound(c) && samePath(prev, c)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// mayDuplicate reports whether a and b might have the same canonical form.
+func mayDuplicate(a, b types.FilterItem) bool {
+	if x, ok := a.(types.FilterCondition); ok {
+		if y, ok := b.(types.FilterCondition); ok {
+			return x.Field.Path == y.Field.Path && x.Operator == y.Operator && x.Value.Name == y.Value.Name
+		}
+	}
+	x, aGroup := a.(types.FilterGroup)
+	y, bGroup := b.(types.FilterGroup)
+	if aGroup || bGroup {
+		return aGroup && bGroup && x.Logic == y.Logic && len(x.Conditions) == len(y.Conditions)
+	}
+	return samePath(a, b)
+}
+
+// samePath reports whether a and b apply to the same field, or either has
+// no field.
+func samePath(a, b types.FilterItem) bool {
+	fa, okA := types.FilterField(a)
+	fb, okB := types.FilterField(b)
+	return !okA || !okB || fa.Path == fb.Path
+}
+
+// isBound reports whether asRange accepts f.
+func isBound(f types.FilterItem) bool {
+	switch filter := f.(type) {
+	case types.RangeFilter:
+		return !filter.Negated
+	case types.FilterCondition:
+		switch filter.Operator {
+		case types.GT, types.GTE, types.LT, types.LTE:
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeGroup(group types.FilterGroup) types.FilterItem {
+	if group.Logic != types.AND && group.Logic != types.OR {
+		conditions := make([]types.FilterItem, len(group.Conditions))
+		for i, c := range group.Conditions {
+			conditions[i] = NormalizeFilter(c)
+		}
+		return types.FilterGroup{Logic: group.Logic, Conditions: conditions}
+	}
+
+	conditions := normalizeConditions(group.Logic, group.Conditions)
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	return types.FilterGroup{Logic: group.Logic, Conditions: conditions}
+}
+
+// normalizeConditions normalizes the operands of an AND or OR: children are
+// normalized, same-logic children are inlined, duplicates are dropped, and
+// under AND, complementary bounds are merged.
+func normalizeConditions(logic types.LogicOperator, conditions []types.FilterItem) []types.FilterItem {
+	out := make([]types.FilterItem, 0, len(conditions))
+	seen := make(map[string]bool, len(conditions))
+	var add func(types.FilterItem)
+	add = func(c types.FilterItem) {
+		if nested, ok := c.(types.FilterGroup); ok && nested.Logic == logic {
+			for _, inner := range nested.Conditions {
+				add(inner)
+			}
+			return
+		}
+		key := canonicalFilter(c)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		out = append(out, c)
+	}
+	for _, c := range conditions {
+		add(NormalizeFilter(c))
+	}
+
+	if logic == types.AND {
+		out = mergeBounds(out)
+	}
+	return out
+}
+
+// fieldBounds tracks the range conditions on one field within an AND.
+type fieldBounds struct {
+	first        int
+	count        int
+	lower, upper int
+	merged       types.RangeFilter
+}
+
+// mergeBounds replaces the range conditions on a field with one RangeFilter,
+// at the position of the first, when together they set at most one lower and
+// one upper bound.
+func mergeBounds(conditions []types.FilterItem) []types.FilterItem {
+	var fields map[types.Field]*fieldBounds
+	for i, c := range conditions {
+		r, ok := asRange(c)
+		if !ok {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[types.Field]*fieldBounds)
+		}
+		fb, ok := fields[r.Field]
+		if !ok {
+			fb = &fieldBounds{first: i, merged: types.RangeFilter{Field: r.Field}}
+			fields[r.Field] = fb
+		}
+		fb.count++
+		if r.Min != nil {
+			fb.lower++
+			fb.merged.Min, fb.merged.MinExclusive = r.Min, r.MinExclusive
+		}
+		if r.Max != nil {
+			fb.upper++
+			fb.merged.Max, fb.merged.MaxExclusive = r.Max, r.MaxExclusive
+		}
+	}
+
+	mergeable := func(fb *fieldBounds) bool {
+		return fb.count > 1 && fb.lower <= 1 && fb.upper <= 1
+	}
+	merging := false
+	for _, fb := range fields {
+		merging = merging || mergeable(fb)
+	}
+	if !merging {
+		return conditions
+	}
+
+	out := make([]types.FilterItem, 0, len(conditions))
+	for i, c := range conditions {
+		if r, ok := asRange(c); ok {
+			if fb := fields[r.Field]; mergeable(fb) {
+				if i == fb.first {
+					out = append(out, fb.merged)
+				}
+				continue
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// asRange expresses an ordered comparison or range filter as a RangeFilter.
+func asRange(f types.FilterItem) (types.RangeFilter, bool) {
+	switch filter := f.(type) {
+	case types.RangeFilter:
+		return filter, !filter.Negated
+	case types.FilterCondition:
+		value := filter.Value
+		r := types.RangeFilter{Field: filter.Field}
+		switch filter.Operator {
+		case types.GT, types.GTE:
+			r.Min, r.MinExclusive = &value, filter.Operator == types.GT
+		case types.LT, types.LTE:
+			r.Max, r.MaxExclusive = &value, filter.Operator == types.LT
+		default:
+			return types.RangeFilter{}, false
+		}
+		return r, true
+	default:
+		return types.RangeFilter{}, false
+	}
+}
@@ -0,0 +1,166 @@
+package docql_test
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/zoobzio/docql"
+	"github.com/zoobzio/docql/internal/types"
+	"github.com/zoobzio/docql/pkg/mongodb"
+)
+
+func TestNormalizeFilter(t *testing.T) {
+	instance := createTestInstance(t)
+	status := instance.F("users", "status")
+	name := instance.F("users", "username")
+	a := instance.Eq(status, instance.P("a"))
+	b := instance.Eq(status, instance.P("b"))
+	lo, hi := instance.P("lo"), instance.P("hi")
+
+	tests := []struct {
+		name   string
+		filter types.FilterItem
+		want   types.FilterItem
+	}{
+		{
+			name:   "flattens same logic",
+			filter: instance.And(a, instance.And(b, instance.And(a))),
+			want:   instance.And(a, b),
+		},
+		{
+			name:   "keeps mixed logic",
+			filter: instance.And(a, instance.Or(a, b)),
+			want:   instance.And(a, instance.Or(a, b)),
+		},
+		{
+			name:   "removes duplicates",
+			filter: instance.Or(a, b, a, instance.Or(b)),
+			want:   instance.Or(a, b),
+		},
+		{
+			name:   "collapses single condition",
+			filter: instance.And(instance.Or(a, a)),
+			want:   a,
+		},
+		{
+			name:   "merges bounds",
+			filter: instance.And(instance.Gt(name, lo), a, instance.Lte(name, hi)),
+			want:   instance.And(types.RangeFilter{Field: name, Min: &lo, MinExclusive: true, Max: &hi}, a),
+		},
+		{
+			name:   "merges range with bound",
+			filter: instance.And(instance.Range(name, &lo, nil), instance.Lt(name, hi)),
+			want:   types.RangeFilter{Field: name, Min: &lo, Max: &hi, MaxExclusive: true},
+		},
+		{
+			name:   "keeps negated range apart",
+			filter: instance.And(docql.NotRange(name, &lo, nil), instance.Lt(name, hi)),
+			want:   instance.And(docql.NotRange(name, &lo, nil), instance.Lt(name, hi)),
+		},
+		{
+			name:   "keeps two lower bounds",
+			filter: instance.And(instance.Gt(name, lo), instance.Gte(name, hi)),
+			want:   instance.And(instance.Gt(name, lo), instance.Gte(name, hi)),
+		},
+		{
+			name:   "no merge under OR",
+			filter: instance.Or(instance.Gt(name, lo), instance.Lt(name, hi)),
+			want:   instance.Or(instance.Gt(name, lo), instance.Lt(name, hi)),
+		},
+		{
+			name:   "NOR keeps shape",
+			filter: instance.Nor(instance.And(a), a),
+			want:   instance.Nor(a, a),
+		},
+		{
+			name:   "leaf unchanged",
+			filter: a,
+			want:   a,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := docql.NormalizeFilter(tt.filter)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeFilter() =\n%#v\nwant\n%#v", got, tt.want)
+			}
+			if again := docql.NormalizeFilter(got); !reflect.DeepEqual(again, got) {
+				t.Errorf("NormalizeFilter() is not idempotent:\n%#v\nthen\n%#v", got, again)
+			}
+		})
+	}
+}
+
+func TestNormalizeFilter_AlreadyNormalizedDoesNotAllocate(t *testing.T) {
+	instance := createTestInstance(t)
+	status := instance.F("users", "status")
+	name := instance.F("users", "username")
+	email := instance.F("users", "email")
+	var filter types.FilterItem = instance.And(
+		instance.Or(instance.Eq(status, instance.P("a")), instance.Eq(status, instance.P("b"))),
+		instance.Gt(name, instance.P("lo")),
+		types.ElemMatchFilter{Field: email, Conditions: []types.FilterItem{instance.Eq(email, instance.P("c"))}},
+	)
+
+	if got := docql.NormalizeFilter(filter); !reflect.DeepEqual(got, filter) {
+		t.Fatalf("NormalizeFilter() changed a normalized filter: %#v", got)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		docql.NormalizeFilter(filter)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations for a normalized filter, got %v", allocs)
+	}
+}
+
+func TestNormalizeFilter_DoesNotModifyInput(t *testing.T) {
+	instance := createTestInstance(t)
+	a := instance.Eq(instance.F("users", "status"), instance.P("a"))
+	filter := instance.And(a, instance.And(a))
+
+	docql.NormalizeFilter(filter)
+	if inner, ok := filter.Conditions[1].(types.FilterGroup); !ok || len(inner.Conditions) != 1 {
+		t.Errorf("input was modified: %#v", filter)
+	}
+}
+
+func TestBuild_NormalizesFilter(t *testing.T) {
+	instance := createTestInstance(t)
+	status := instance.F("users", "status")
+	name := instance.F("users", "username")
+	filter := instance.And(
+		instance.Eq(status, instance.P("status")),
+		instance.And(
+			instance.Gte(name, instance.P("from")),
+			instance.Eq(status, instance.P("status")),
+			instance.And(instance.Lt(name, instance.P("to"))),
+		),
+	)
+
+	raw, err := instance.Find("users").Filter(filter).PreserveFilter().Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	normalized, err := instance.Find("users").Filter(filter).Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	wantRaw := `{"collection":"users","database":"test_db","filter":{"$and":[{"status":{"$eq":":status"}},{"$and":[{"username":{"$gte":":from"}},{"status":{"$eq":":status"}},{"$and":[{"username":{"$lt":":to"}}]}]}]},"operation":"FIND"}`
+	wantNormalized := `{"collection":"users","database":"test_db","filter":{"$and":[{"status":{"$eq":":status"}},{"username":{"$gte":":from","$lt":":to"}}]},"operation":"FIND"}`
+	if raw.JSON != wantRaw {
+		t.Errorf("unexpected raw JSON:\n got: %s\nwant: %s", raw.JSON, wantRaw)
+	}
+	if normalized.JSON != wantNormalized {
+		t.Errorf("unexpected normalized JSON:\n got: %s\nwant: %s", normalized.JSON, wantNormalized)
+	}
+	if len(normalized.JSON) >= len(raw.JSON) {
+		t.Errorf("normalized render should be smaller: %d >= %d", len(normalized.JSON), len(raw.JSON))
+	}
+	distinct := func(params []string) []string { return slices.Compact(slices.Sorted(slices.Values(params))) }
+	if !slices.Equal(distinct(raw.RequiredParams), distinct(normalized.RequiredParams)) {
+		t.Errorf("params differ: %v vs %v", raw.RequiredParams, normalized.RequiredParams)
+	}
+}
@@ -0,0 +1,840 @@
+package docql
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/zoobzio/docql/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseError reports where in a query definition parsing failed. Err keeps
+// the underlying cause, such as an *UnknownFieldError from the schema lookup.
+type ParseError struct {
+	Line int
+	Path string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s: %v", e.Line, e.Path, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseQuery translates a declarative JSON or YAML query definition into a
+// Builder, resolving every collection, field, and param through d so the
+// result is schema-validated. A definition looks like:
+//
+//	collection: users
+//	operation: find
+//	filter:
+//	  and:
+//	    - {field: active, op: eq, param: active}
+//	    - {field: age, op: gt, param: minAge}
+//	sort: [{field: age, dir: desc}]
+//	limit: 10
+//
+// Filters are groups ({and|or|nor: [...]}) or conditions on a field: a
+// comparison ({op, param}, or {op: in|nin, params: [...]}), {exists: bool},
+// {regex, options}, or {range: {min, max, minExclusive, maxExclusive}}.
+// Reads accept select or exclude, sort, skip, and limit; skip and limit take
+// an integer or {param: name}. Inserts take document or documents, updates
+// take update ({set, inc, mul, push, pull, addToSet: {field: param}, unset:
+// [fields]}) and upsert, distinct takes field, and aggregates take a pipeline
+// of match, project, sort, skip, limit, unwind, lookup, and count stages.
+// The query carries the instance's soft-delete field, limits, and hooks;
+// includeDeleted: true opts out of the soft-delete filter and softDelete: true
+// turns a delete into a soft delete.
+//
+// Errors are *ParseError values carrying the line and path of the offending node.
+func ParseQuery(def []byte, d *DOCQL) (*Builder, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(def, &doc); err != nil {
+		return nil, fmt.Errorf("invalid query definition: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("invalid query definition: empty document")
+	}
+
+	p := &queryParser{d: d}
+	b, err := p.parseQuery(doc.Content[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Err(); err != nil {
+		return nil, &ParseError{Line: doc.Content[0].Line, Err: err}
+	}
+	return b, nil
+}
+
+type queryParser struct {
+	d          *DOCQL
+	collection string
+}
+
+// starters maps definition operation names to query starters. Distinct is
+// handled separately because it needs its field up front.
+var starters = map[string]func(types.Collection) *Builder{
+	"find":       Find,
+	"findOne":    FindOne,
+	"insert":     Insert,
+	"insertMany": InsertMany,
+	"update":     Update,
+	"updateMany": UpdateMany,
+	"delete":     Delete,
+	"deleteMany": DeleteMany,
+	"aggregate":  Aggregate,
+	"count":      Count,
+}
+
+// updateSetters maps update definition keys to the builder methods taking a
+// field and a param.
+var updateSetters = map[string]func(*Builder, types.Field, types.Param) *Builder{
+	"set":      (*Builder).Set,
+	"inc":      (*Builder).Inc,
+	"mul":      (*Builder).Mul,
+	"push":     (*Builder).Push,
+	"pull":     (*Builder).Pull,
+	"addToSet": (*Builder).AddToSet,
+}
+
+// comparisons maps definition comparison operators to filter constructors.
+var comparisons = map[string]func(types.Field, types.Param) types.FilterItem{
+	"eq":   func(f types.Field, p types.Param) types.FilterItem { return Eq(f, p) },
+	"ne":   func(f types.Field, p types.Param) types.FilterItem { return Ne(f, p) },
+	"gt":   func(f types.Field, p types.Param) types.FilterItem { return Gt(f, p) },
+	"gte":  func(f types.Field, p types.Param) types.FilterItem { return Gte(f, p) },
+	"lt":   func(f types.Field, p types.Param) types.FilterItem { return Lt(f, p) },
+	"lte":  func(f types.Field, p types.Param) types.FilterItem { return Lte(f, p) },
+	"in":   func(f types.Field, p types.Param) types.FilterItem { return In(f, p) },
+	"nin":  func(f types.Field, p types.Param) types.FilterItem { return NotIn(f, p) },
+	"all":  func(f types.Field, p types.Param) types.FilterItem { return All(f, p) },
+	"size": func(f types.Field, p types.Param) types.FilterItem { return Size(f, p) },
+}
+
+func (p *queryParser) parseQuery(node *yaml.Node) (*Builder, error) {
+	m, err := mapping(node, "", "collection", "operation", "filter", "select", "exclude",
+		"sort", "skip", "limit", "document", "documents", "update", "upsert", "field", "pipeline", "includeDeleted", "softDelete")
+	if err != nil {
+		return nil, err
+	}
+
+	collNode, ok := m["collection"]
+	if !ok {
+		return nil, errAt(node, "", "collection is required")
+	}
+	name, err := scalar(collNode, "collection")
+	if err != nil {
+		return nil, err
+	}
+	coll, err := p.d.TryC(name)
+	if err != nil {
+		return nil, wrapAt(collNode, "collection", err)
+	}
+	p.collection = coll.Name
+
+	opNode, ok := m["operation"]
+	if !ok {
+		return nil, errAt(node, "", "operation is required")
+	}
+	op, err := scalar(opNode, "operation")
+	if err != nil {
+		return nil, err
+	}
+
+	var b *Builder
+	if op == "distinct" {
+		fieldNode, ok := m["field"]
+		if !ok {
+			return nil, errAt(node, "", "distinct requires field")
+		}
+		field, err := p.field(fieldNode, "field")
+		if err != nil {
+			return nil, err
+		}
+		b = Distinct(coll, field)
+	} else {
+		start, ok := starters[op]
+		if !ok {
+			return nil, errAt(opNode, "operation", "unknown operation %q", op)
+		}
+		if n, ok := m["field"]; ok {
+			return nil, errAt(n, "field", "field is only valid for distinct")
+		}
+		b = start(coll)
+	}
+	// Parsed queries carry the instance's soft-delete field, limits, and
+	// hooks, the same as builders from its constructors.
+	b = p.d.withHooks(p.d.withLimits(p.d.withSoftDelete(b)))
+
+	if n, ok := m["filter"]; ok {
+		f, err := p.filter(n, "filter")
+		if err != nil {
+			return nil, err
+		}
+		b.Filter(f)
+	}
+	if n, ok := m["select"]; ok {
+		fields, err := p.fields(n, "select")
+		if err != nil {
+			return nil, err
+		}
+		b.Select(fields...)
+	}
+	if n, ok := m["exclude"]; ok {
+		fields, err := p.fields(n, "exclude")
+		if err != nil {
+			return nil, err
+		}
+		b.Exclude(fields...)
+	}
+	if n, ok := m["sort"]; ok {
+		sorts, err := p.sorts(n, "sort")
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range sorts {
+			b.Sort(s.Field, s.Order)
+		}
+	}
+	if n, ok := m["skip"]; ok {
+		v, err := p.pagination(n, "skip")
+		if err != nil {
+			return nil, err
+		}
+		if v.Static != nil {
+			b.Skip(*v.Static)
+		} else {
+			b.SkipParam(*v.Param)
+		}
+	}
+	if n, ok := m["limit"]; ok {
+		v, err := p.pagination(n, "limit")
+		if err != nil {
+			return nil, err
+		}
+		if v.Static != nil {
+			b.Limit(*v.Static)
+		} else {
+			b.LimitParam(*v.Param)
+		}
+	}
+	if n, ok := m["document"]; ok {
+		doc, err := p.document(n, "document")
+		if err != nil {
+			return nil, err
+		}
+		b.Document(doc)
+	}
+	if n, ok := m["documents"]; ok {
+		if n.Kind != yaml.SequenceNode {
+			return nil, errAt(n, "documents", "expected a list of documents")
+		}
+		docs := make([]types.Document, 0, len(n.Content))
+		for i, item := range n.Content {
+			doc, err := p.document(item, index("documents", i))
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+		b.Documents(docs)
+	}
+	if n, ok := m["update"]; ok {
+		if err := p.update(b, n, "update"); err != nil {
+			return nil, err
+		}
+	}
+	if n, ok := m["upsert"]; ok {
+		upsert, err := boolean(n, "upsert")
+		if err != nil {
+			return nil, err
+		}
+		if upsert {
+			b.Upsert()
+		}
+	}
+	if n, ok := m["includeDeleted"]; ok {
+		include, err := boolean(n, "includeDeleted")
+		if err != nil {
+			return nil, err
+		}
+		if include {
+			b.IncludeDeleted()
+		}
+	}
+	if n, ok := m["softDelete"]; ok {
+		soft, err := boolean(n, "softDelete")
+		if err != nil {
+			return nil, err
+		}
+		if soft {
+			if err := b.AsSoftDelete().Err(); err != nil {
+				return nil, wrapAt(n, "softDelete", err)
+			}
+		}
+	}
+	if n, ok := m["pipeline"]; ok {
+		if err := p.pipeline(b, n, "pipeline"); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (p *queryParser) filter(node *yaml.Node, path string) (types.FilterItem, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, errAt(node, path, "expected a filter mapping")
+	}
+	for _, logic := range []string{"and", "or", "nor"} {
+		if _, ok := lookup(node, logic); ok {
+			return p.group(node, path, logic)
+		}
+	}
+
+	m, err := mapping(node, path, "field", "op", "param", "params", "exists", "regex", "options", "range")
+	if err != nil {
+		return nil, err
+	}
+	fieldNode, ok := m["field"]
+	if !ok {
+		return nil, errAt(node, path, "filter requires field or one of and, or, nor")
+	}
+	field, err := p.field(fieldNode, join(path, "field"))
+	if err != nil {
+		return nil, err
+	}
+
+	var forms []string
+	for _, key := range []string{"op", "exists", "regex", "range"} {
+		if _, ok := m[key]; ok {
+			forms = append(forms, key)
+		}
+	}
+	if len(forms) != 1 {
+		return nil, errAt(node, path, "filter on %s needs exactly one of op, exists, regex, range", field.Path)
+	}
+	for _, key := range []string{"param", "params"} {
+		if n, ok := m[key]; ok && forms[0] != "op" {
+			return nil, errAt(n, join(path, key), "%s is only valid with op", key)
+		}
+	}
+	if n, ok := m["options"]; ok && forms[0] != "regex" {
+		return nil, errAt(n, join(path, "options"), "options is only valid with regex")
+	}
+
+	switch forms[0] {
+	case "exists":
+		exists, err := boolean(m["exists"], join(path, "exists"))
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return Exists(field), nil
+		}
+		return NotExists(field), nil
+
+	case "regex":
+		pattern, err := p.param(m["regex"], join(path, "regex"))
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := m["options"]; ok {
+			options, err := p.param(n, join(path, "options"))
+			if err != nil {
+				return nil, err
+			}
+			return RegexWithOptions(field, pattern, options), nil
+		}
+		return Regex(field, pattern), nil
+
+	case "range":
+		return p.rangeFilter(field, m["range"], join(path, "range"))
+	}
+
+	opNode := m["op"]
+	op, err := scalar(opNode, join(path, "op"))
+	if err != nil {
+		return nil, err
+	}
+	if n, ok := m["params"]; ok {
+		if _, ok := m["param"]; ok {
+			return nil, errAt(n, join(path, "params"), "use either param or params")
+		}
+		if op != "in" && op != "nin" {
+			return nil, errAt(n, join(path, "params"), "params is only valid with op in or nin")
+		}
+		values, err := p.params(n, join(path, "params"))
+		if err != nil {
+			return nil, err
+		}
+		if op == "in" {
+			return InValues(field, values...), nil
+		}
+		return NinValues(field, values...), nil
+	}
+	build, ok := comparisons[op]
+	if !ok {
+		return nil, errAt(opNode, join(path, "op"), "unknown operator %q", op)
+	}
+	paramNode, ok := m["param"]
+	if !ok {
+		return nil, errAt(node, path, "op %s requires param", op)
+	}
+	value, err := p.param(paramNode, join(path, "param"))
+	if err != nil {
+		return nil, err
+	}
+	return build(field, value), nil
+}
+
+func (p *queryParser) group(node *yaml.Node, path, logic string) (types.FilterItem, error) {
+	m, err := mapping(node, path, logic)
+	if err != nil {
+		return nil, err
+	}
+	list := m[logic]
+	groupPath := join(path, logic)
+	if list.Kind != yaml.SequenceNode || len(list.Content) == 0 {
+		return nil, errAt(list, groupPath, "expected a non-empty list of filters")
+	}
+	conditions := make([]types.FilterItem, 0, len(list.Content))
+	for i, item := range list.Content {
+		f, err := p.filter(item, index(groupPath, i))
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, f)
+	}
+	switch logic {
+	case "and":
+		return And(conditions...), nil
+	case "or":
+		return Or(conditions...), nil
+	default:
+		return Nor(conditions...), nil
+	}
+}
+
+func (p *queryParser) rangeFilter(field types.Field, node *yaml.Node, path string) (types.FilterItem, error) {
+	m, err := mapping(node, path, "min", "max", "minExclusive", "maxExclusive")
+	if err != nil {
+		return nil, err
+	}
+	r := types.RangeFilter{Field: field}
+	if n, ok := m["min"]; ok {
+		v, err := p.param(n, join(path, "min"))
+		if err != nil {
+			return nil, err
+		}
+		r.Min = &v
+	}
+	if n, ok := m["max"]; ok {
+		v, err := p.param(n, join(path, "max"))
+		if err != nil {
+			return nil, err
+		}
+		r.Max = &v
+	}
+	if r.Min == nil && r.Max == nil {
+		return nil, errAt(node, path, "range requires at least min or max")
+	}
+	if n, ok := m["minExclusive"]; ok {
+		if r.MinExclusive, err = boolean(n, join(path, "minExclusive")); err != nil {
+			return nil, err
+		}
+	}
+	if n, ok := m["maxExclusive"]; ok {
+		if r.MaxExclusive, err = boolean(n, join(path, "maxExclusive")); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (p *queryParser) sorts(node *yaml.Node, path string) ([]types.SortClause, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, errAt(node, path, "expected a list of sort clauses")
+	}
+	out := make([]types.SortClause, 0, len(node.Content))
+	for i, item := range node.Content {
+		itemPath := index(path, i)
+		m, err := mapping(item, itemPath, "field", "dir")
+		if err != nil {
+			return nil, err
+		}
+		fieldNode, ok := m["field"]
+		if !ok {
+			return nil, errAt(item, itemPath, "sort requires field")
+		}
+		field, err := p.field(fieldNode, join(itemPath, "field"))
+		if err != nil {
+			return nil, err
+		}
+		order := types.Ascending
+		if n, ok := m["dir"]; ok {
+			dir, err := scalar(n, join(itemPath, "dir"))
+			if err != nil {
+				return nil, err
+			}
+			switch dir {
+			case "asc":
+			case "desc":
+				order = types.Descending
+			default:
+				return nil, errAt(n, join(itemPath, "dir"), "unknown sort direction %q: use asc or desc", dir)
+			}
+		}
+		out = append(out, types.SortClause{Field: field, Order: order})
+	}
+	return out, nil
+}
+
+func (p *queryParser) pagination(node *yaml.Node, path string) (types.PaginationValue, error) {
+	if node.Kind == yaml.MappingNode {
+		m, err := mapping(node, path, "param")
+		if err != nil {
+			return types.PaginationValue{}, err
+		}
+		n, ok := m["param"]
+		if !ok {
+			return types.PaginationValue{}, errAt(node, path, "expected an integer or {param: name}")
+		}
+		param, err := p.param(n, join(path, "param"))
+		if err != nil {
+			return types.PaginationValue{}, err
+		}
+		return types.PaginationValue{Param: &param}, nil
+	}
+	s, err := scalar(node, path)
+	if err != nil {
+		return types.PaginationValue{}, err
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return types.PaginationValue{}, errAt(node, path, "expected a non-negative integer or {param: name}, got %q", s)
+	}
+	return types.PaginationValue{Static: &n}, nil
+}
+
+func (p *queryParser) document(node *yaml.Node, path string) (types.Document, error) {
+	pairs, err := p.fieldParams(node, path)
+	if err != nil {
+		return types.Document{}, err
+	}
+	db := Doc()
+	for _, pair := range pairs {
+		db.Set(pair.field, pair.param)
+	}
+	return db.Build(), nil
+}
+
+func (p *queryParser) update(b *Builder, node *yaml.Node, path string) error {
+	keys := []string{"unset"}
+	for key := range updateSetters {
+		keys = append(keys, key)
+	}
+	if _, err := mapping(node, path, keys...); err != nil {
+		return err
+	}
+	// Walk the mapping in document order so rendered updates follow the definition.
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		value := node.Content[i+1]
+		opPath := join(path, key)
+		if key == "unset" {
+			fields, err := p.fields(value, opPath)
+			if err != nil {
+				return err
+			}
+			b.Unset(fields...)
+			continue
+		}
+		pairs, err := p.fieldParams(value, opPath)
+		if err != nil {
+			return err
+		}
+		for _, pair := range pairs {
+			updateSetters[key](b, pair.field, pair.param)
+		}
+	}
+	return nil
+}
+
+func (p *queryParser) pipeline(b *Builder, node *yaml.Node, path string) error {
+	if node.Kind != yaml.SequenceNode {
+		return errAt(node, path, "expected a list of stages")
+	}
+	for i, item := range node.Content {
+		stagePath := index(path, i)
+		if item.Kind != yaml.MappingNode || len(item.Content) != 2 {
+			return errAt(item, stagePath, "expected a single-key stage mapping")
+		}
+		name, value := item.Content[0].Value, item.Content[1]
+		valuePath := join(stagePath, name)
+
+		switch name {
+		case "match":
+			f, err := p.filter(value, valuePath)
+			if err != nil {
+				return err
+			}
+			b.Match(f)
+		case "project":
+			fields, err := p.fields(value, valuePath)
+			if err != nil {
+				return err
+			}
+			proj := types.Projection{Fields: make([]types.ProjectionField, len(fields))}
+			for j, f := range fields {
+				proj.Fields[j] = types.ProjectionField{Field: f, Include: true}
+			}
+			b.Project(proj)
+		case "sort":
+			sorts, err := p.sorts(value, valuePath)
+			if err != nil {
+				return err
+			}
+			b.Stage(types.SortStage{Sorts: sorts})
+		case "skip", "limit":
+			v, err := p.pagination(value, valuePath)
+			if err != nil {
+				return err
+			}
+			if name == "skip" {
+				b.Stage(types.SkipStage{Skip: v})
+			} else {
+				b.Stage(types.LimitStage{Limit: v})
+			}
+		case "unwind":
+			field, err := p.field(value, valuePath)
+			if err != nil {
+				return err
+			}
+			b.Unwind(field)
+		case "count":
+			countName, err := scalar(value, valuePath)
+			if err != nil {
+				return err
+			}
+			b.Stage(types.CountStage{FieldName: countName})
+		case "lookup":
+			if err := p.lookup(b, value, valuePath); err != nil {
+				return err
+			}
+		default:
+			return errAt(item.Content[0], stagePath, "unknown pipeline stage %q", name)
+		}
+	}
+	return nil
+}
+
+func (p *queryParser) lookup(b *Builder, node *yaml.Node, path string) error {
+	m, err := mapping(node, path, "from", "localField", "foreignField", "as")
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{"from", "localField", "foreignField", "as"} {
+		if _, ok := m[key]; !ok {
+			return errAt(node, path, "lookup requires %s", key)
+		}
+	}
+	fromName, err := scalar(m["from"], join(path, "from"))
+	if err != nil {
+		return err
+	}
+	from, err := p.d.TryC(fromName)
+	if err != nil {
+		return wrapAt(m["from"], join(path, "from"), err)
+	}
+	local, err := p.field(m["localField"], join(path, "localField"))
+	if err != nil {
+		return err
+	}
+	foreignPath, err := scalar(m["foreignField"], join(path, "foreignField"))
+	if err != nil {
+		return err
+	}
+	foreign, err := p.d.TryF(from.Name, foreignPath)
+	if err != nil {
+		return wrapAt(m["foreignField"], join(path, "foreignField"), err)
+	}
+	as, err := scalar(m["as"], join(path, "as"))
+	if err != nil {
+		return err
+	}
+	b.Lookup(from.Name, local, foreign, as)
+	return nil
+}
+
+type fieldParam struct {
+	field types.Field
+	param types.Param
+}
+
+// fieldParams parses a {field: param} mapping in document order.
+func (p *queryParser) fieldParams(node *yaml.Node, path string) ([]fieldParam, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, errAt(node, path, "expected a mapping of field to param")
+	}
+	if err := checkDuplicates(node, path); err != nil {
+		return nil, err
+	}
+	out := make([]fieldParam, 0, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		fieldPath := join(path, keyNode.Value)
+		field, err := p.field(keyNode, fieldPath)
+		if err != nil {
+			return nil, err
+		}
+		param, err := p.param(valueNode, fieldPath)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, fieldParam{field: field, param: param})
+	}
+	return out, nil
+}
+
+func (p *queryParser) fields(node *yaml.Node, path string) ([]types.Field, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, errAt(node, path, "expected a list of fields")
+	}
+	out := make([]types.Field, 0, len(node.Content))
+	for i, item := range node.Content {
+		f, err := p.field(item, index(path, i))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+func (p *queryParser) field(node *yaml.Node, path string) (types.Field, error) {
+	name, err := scalar(node, path)
+	if err != nil {
+		return types.Field{}, err
+	}
+	f, err := p.d.TryF(p.collection, name)
+	if err != nil {
+		return types.Field{}, wrapAt(node, path, err)
+	}
+	return f, nil
+}
+
+func (p *queryParser) params(node *yaml.Node, path string) ([]types.Param, error) {
+	if node.Kind != yaml.SequenceNode || len(node.Content) == 0 {
+		return nil, errAt(node, path, "expected a non-empty list of params")
+	}
+	out := make([]types.Param, 0, len(node.Content))
+	for i, item := range node.Content {
+		v, err := p.param(item, index(path, i))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (p *queryParser) param(node *yaml.Node, path string) (types.Param, error) {
+	name, err := scalar(node, path)
+	if err != nil {
+		return types.Param{}, err
+	}
+	v, err := p.d.TryP(name)
+	if err != nil {
+		return types.Param{}, wrapAt(node, path, err)
+	}
+	return v, nil
+}
+
+// mapping checks that node is a mapping whose keys are all in allowed and
+// returns its values by key.
+func mapping(node *yaml.Node, path string, allowed ...string) (map[string]*yaml.Node, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, errAt(node, path, "expected a mapping")
+	}
+	if err := checkDuplicates(node, path); err != nil {
+		return nil, err
+	}
+	out := make(map[string]*yaml.Node, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		known := false
+		for _, a := range allowed {
+			if key.Value == a {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, errAt(key, join(path, key.Value), "unknown key")
+		}
+		out[key.Value] = node.Content[i+1]
+	}
+	return out, nil
+}
+
+func checkDuplicates(node *yaml.Node, path string) error {
+	seen := make(map[string]bool, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if seen[key.Value] {
+			return errAt(key, join(path, key.Value), "duplicate key")
+		}
+		seen[key.Value] = true
+	}
+	return nil
+}
+
+func lookup(node *yaml.Node, key string) (*yaml.Node, bool) {
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1], true
+		}
+	}
+	return nil, false
+}
+
+func scalar(node *yaml.Node, path string) (string, error) {
+	if node.Kind != yaml.ScalarNode || node.Value == "" {
+		return "", errAt(node, path, "expected a non-empty string")
+	}
+	return node.Value, nil
+}
+
+func boolean(node *yaml.Node, path string) (bool, error) {
+	var v bool
+	if node.Kind != yaml.ScalarNode || node.Decode(&v) != nil {
+		return false, errAt(node, path, "expected true or false")
+	}
+	return v, nil
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func index(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+func errAt(node *yaml.Node, path, format string, args ...interface{}) error {
+	return &ParseError{Line: node.Line, Path: path, Err: fmt.Errorf(format, args...)}
+}
+
+func wrapAt(node *yaml.Node, path string, err error) error {
+	return &ParseError{Line: node.Line, Path: path, Err: err}
+}
@@ -0,0 +1,272 @@
+package docql_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zoobzio/ddml"
+	"github.com/zoobzio/docql"
+	"github.com/zoobzio/docql/pkg/mongodb"
+)
+
+func parseTestInstance(t *testing.T) *docql.DOCQL {
+	t.Helper()
+
+	schema := ddml.NewSchema("test_db")
+
+	users := ddml.NewCollection("users")
+	users.AddField(ddml.NewField("_id", ddml.TypeObjectID))
+	users.AddField(ddml.NewField("name", ddml.TypeString))
+	users.AddField(ddml.NewField("active", ddml.TypeBool))
+	users.AddField(ddml.NewField("age", ddml.TypeInt))
+	users.AddField(ddml.NewField("status", ddml.TypeString))
+	users.AddField(ddml.NewField("tags", ddml.TypeArray))
+	users.AddField(ddml.NewField("deletedAt", ddml.TypeDate))
+	schema.AddCollection(users)
+
+	orders := ddml.NewCollection("orders")
+	orders.AddField(ddml.NewField("_id", ddml.TypeObjectID))
+	orders.AddField(ddml.NewField("userId", ddml.TypeObjectID))
+	orders.AddField(ddml.NewField("total", ddml.TypeFloat))
+	schema.AddCollection(orders)
+
+	instance, err := docql.NewFromDDML(schema)
+	if err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	return instance
+}
+
+func TestParseQuery_Golden(t *testing.T) {
+	instance := parseTestInstance(t)
+
+	tests := []struct {
+		name   string
+		def    string
+		golden string
+	}{
+		{
+			name: "find yaml",
+			def: `
+collection: users
+operation: find
+filter:
+  and:
+    - {field: active, op: eq, param: active}
+    - {field: age, op: gt, param: minAge}
+sort: [{field: age, dir: desc}]
+limit: 10
+`,
+			golden: `{"collection":"users","database":"test_db","filter":{"$and":[{"active":{"$eq":":active"}},{"age":{"$gt":":minAge"}}]},"limit":10,"operation":"FIND","sort":{"age":-1}}`,
+		},
+		{
+			name:   "find json",
+			def:    `{"collection": "users", "operation": "find", "filter": {"and": [{"field": "active", "op": "eq", "param": "active"}, {"field": "age", "op": "gt", "param": "minAge"}]}, "sort": [{"field": "age", "dir": "desc"}], "limit": 10}`,
+			golden: `{"collection":"users","database":"test_db","filter":{"$and":[{"active":{"$eq":":active"}},{"age":{"$gt":":minAge"}}]},"limit":10,"operation":"FIND","sort":{"age":-1}}`,
+		},
+		{
+			name: "filter forms",
+			def: `
+collection: users
+operation: findOne
+filter:
+  or:
+    - {field: name, regex: pattern, options: flags}
+    - {field: age, range: {min: lo, max: hi, maxExclusive: true}}
+    - {field: deletedAt, exists: false}
+    - {field: status, op: in, params: [a, b]}
+    - nor:
+        - {field: tags, op: all, param: required}
+select: [name, age]
+`,
+			golden: `{"collection":"users","database":"test_db","filter":{"$or":[{"name":{"$options":":flags","$regex":":pattern"}},{"age":{"$gte":":lo","$lt":":hi"}},{"deletedAt":{"$exists":false}},{"status":{"$in":[":a",":b"]}},{"$nor":[{"tags":{"$all":":required"}}]}]},"limit":1,"operation":"FIND_ONE","projection":{"age":1,"name":1}}`,
+		},
+		{
+			name: "pagination params",
+			def: `
+collection: users
+operation: find
+exclude: [tags]
+skip: {param: offset}
+limit: {param: pageSize}
+`,
+			golden: `{"collection":"users","database":"test_db","filter":{},"limit":":pageSize","operation":"FIND","projection":{"tags":0},"skip":":offset"}`,
+		},
+		{
+			name: "insert",
+			def: `
+collection: users
+operation: insert
+document: {name: name, age: age}
+`,
+			golden: `{"collection":"users","database":"test_db","document":{"age":":age","name":":name"},"operation":"INSERT"}`,
+		},
+		{
+			name: "insert many",
+			def: `
+collection: users
+operation: insertMany
+documents:
+  - {name: name1}
+  - {name: name2}
+`,
+			golden: `{"collection":"users","database":"test_db","documents":[{"name":":name1"},{"name":":name2"}],"operation":"INSERT_MANY"}`,
+		},
+		{
+			name: "update",
+			def: `
+collection: users
+operation: updateMany
+filter: {field: status, op: eq, param: status}
+update:
+  set: {status: newStatus}
+  inc: {age: delta}
+  unset: [deletedAt]
+upsert: true
+`,
+			golden: `{"collection":"users","database":"test_db","filter":{"status":{"$eq":":status"}},"operation":"UPDATE_MANY","update":{"$inc":{"age":":delta"},"$set":{"status":":newStatus"},"$unset":{"deletedAt":""}},"upsert":true}`,
+		},
+		{
+			name: "distinct",
+			def: `
+collection: users
+operation: distinct
+field: status
+filter: {field: active, op: eq, param: active}
+`,
+			golden: `{"collection":"users","database":"test_db","field":"status","filter":{"active":{"$eq":":active"}},"operation":"DISTINCT"}`,
+		},
+		{
+			name: "aggregate",
+			def: `
+collection: users
+operation: aggregate
+pipeline:
+  - match: {field: active, op: eq, param: active}
+  - lookup: {from: orders, localField: _id, foreignField: userId, as: orders}
+  - unwind: tags
+  - project: [name, tags]
+  - sort: [{field: name}]
+  - skip: 5
+  - limit: {param: n}
+  - count: total
+`,
+			golden: `{"collection":"users","database":"test_db","operation":"AGGREGATE","pipeline":[{"$match":{"active":{"$eq":":active"}}},{"$lookup":{"as":"orders","foreignField":"userId","from":"orders","localField":"_id"}},{"$unwind":{"path":"$tags"}},{"$project":{"name":1,"tags":1}},{"$sort":{"name":1}},{"$skip":5},{"$limit":":n"},{"$count":"total"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := docql.ParseQuery([]byte(tt.def), instance)
+			if err != nil {
+				t.Fatalf("ParseQuery failed: %v", err)
+			}
+			result, err := b.Render(mongodb.New())
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if result.JSON != tt.golden {
+				t.Errorf("rendered:\n%s\nwant:\n%s", result.JSON, tt.golden)
+			}
+		})
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	instance := parseTestInstance(t)
+
+	tests := []struct {
+		name string
+		def  string
+		line int
+		path string
+		msg  string
+	}{
+		{
+			name: "unknown top-level key",
+			def:  "collection: users\noperation: find\nlimt: 10\n",
+			line: 3, path: "limt", msg: "unknown key",
+		},
+		{
+			name: "unknown operator",
+			def:  "collection: users\noperation: find\nfilter:\n  and:\n    - {field: age, op: gtx, param: a}\n",
+			line: 5, path: "filter.and[0].op", msg: `unknown operator "gtx"`,
+		},
+		{
+			name: "unknown field",
+			def:  "collection: users\noperation: find\nsort:\n  - {field: agee}\n",
+			line: 4, path: "sort[0].field", msg: "field 'agee' not found",
+		},
+		{
+			name: "unknown collection",
+			def:  "collection: user\noperation: find\n",
+			line: 1, path: "collection", msg: "collection 'user' not found",
+		},
+		{
+			name: "unknown operation",
+			def:  "collection: users\noperation: upsert\n",
+			line: 2, path: "operation", msg: `unknown operation "upsert"`,
+		},
+		{
+			name: "unknown filter key",
+			def:  "collection: users\noperation: find\nfilter: {field: age, op: eq, value: a}\n",
+			line: 3, path: "filter.value", msg: "unknown key",
+		},
+		{
+			name: "ambiguous filter",
+			def:  "collection: users\noperation: find\nfilter: {field: age, op: eq, param: a, exists: true}\n",
+			line: 3, path: "filter", msg: "exactly one of",
+		},
+		{
+			name: "unknown stage",
+			def:  "collection: users\noperation: aggregate\npipeline:\n  - group: {}\n",
+			line: 4, path: "pipeline[0]", msg: `unknown pipeline stage "group"`,
+		},
+		{
+			name: "invalid param",
+			def:  "collection: users\noperation: find\nlimit: {param: page-size}\n",
+			line: 3, path: "limit.param", msg: "invalid parameter name",
+		},
+		{
+			name: "bad sort direction",
+			def:  "collection: users\noperation: find\nsort: [{field: age, dir: down}]\n",
+			line: 3, path: "sort[0].dir", msg: "unknown sort direction",
+		},
+		{
+			name: "builder error",
+			def:  "collection: users\noperation: insert\nsort: [{field: age}]\n",
+			line: 1, msg: "Sort() can only be used with read operations",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := docql.ParseQuery([]byte(tt.def), instance)
+			var parseErr *docql.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("expected *ParseError, got %T: %v", err, err)
+			}
+			if parseErr.Line != tt.line || parseErr.Path != tt.path {
+				t.Errorf("got line %d path %q, want line %d path %q", parseErr.Line, parseErr.Path, tt.line, tt.path)
+			}
+			if !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("error %q does not contain %q", err.Error(), tt.msg)
+			}
+		})
+	}
+}
+
+func TestParseQuery_UnwrapsSchemaErrors(t *testing.T) {
+	instance := parseTestInstance(t)
+
+	_, err := docql.ParseQuery([]byte("collection: users\noperation: find\nselect: [nope]\n"), instance)
+	var unknown *docql.UnknownFieldError
+	if !errors.As(err, &unknown) || unknown.Fields[0] != "nope" {
+		t.Errorf("expected *UnknownFieldError in chain, got %v", err)
+	}
+
+	if _, err := docql.ParseQuery([]byte("collection: [users"), instance); err == nil {
+		t.Error("expected error for malformed definition")
+	}
+}
@@ -0,0 +1,904 @@
+// Package arangodb provides an ArangoDB (AQL) renderer for DOCQL.
+package arangodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// docVar is the loop variable bound to each document of the target collection.
+const docVar = "d"
+
+// keywords lists AQL keywords that must be backtick-quoted when used as
+// attribute names.
+var keywords = map[string]bool{
+	"aggregate": true, "all": true, "and": true, "any": true, "asc": true,
+	"collect": true, "desc": true, "distinct": true, "false": true, "filter": true,
+	"for": true, "graph": true, "in": true, "inbound": true, "insert": true,
+	"into": true, "k_paths": true, "k_shortest_paths": true, "keep": true,
+	"let": true, "like": true, "limit": true, "none": true, "not": true,
+	"null": true, "options": true, "or": true, "outbound": true, "prune": true,
+	"remove": true, "replace": true, "return": true, "search": true,
+	"shortest_path": true, "sort": true, "true": true, "update": true,
+	"upsert": true, "window": true, "with": true,
+}
+
+// Renderer renders DocumentAST to AQL.
+type Renderer struct{}
+
+// provider names ArangoDB in UnsupportedError values.
+const provider = "arangodb"
+
+// New creates a new ArangoDB renderer.
+func New() *Renderer {
+	return &Renderer{}
+}
+
+// query accumulates the bind variables referenced by a rendered AQL statement.
+type query struct {
+	params   []string
+	bindVars map[string]string
+}
+
+// bind returns the @-reference for a DOCQL param, registering it on first use.
+func (q *query) bind(p types.Param) (string, error) {
+	if !types.IsValidIdentifier(p.Name) {
+		return "", &types.InvalidIdentifierError{Kind: "param name", Value: p.Name}
+	}
+	if _, ok := q.bindVars[p.Name]; !ok {
+		if q.bindVars == nil {
+			q.bindVars = make(map[string]string)
+		}
+		q.bindVars[p.Name] = ":" + p.Name
+		q.params = append(q.params, p.Name)
+	}
+	return "@" + p.Name, nil
+}
+
+// Render converts a DocumentAST to AQL.
+func (r *Renderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
+	if err := ast.Validate(); err != nil {
+		return nil, &types.InvalidASTError{Err: err}
+	}
+
+	if ast.ParameterizedTarget() {
+		return nil, types.UnsupportedFeature(provider, "parameterized collection")
+	}
+
+	if !r.SupportsOperation(ast.Operation) {
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
+	}
+
+	if !types.IsValidIdentifier(ast.Target.Name) {
+		return nil, &types.InvalidIdentifierError{Kind: "collection name", Value: ast.Target.Name}
+	}
+
+	q := &query{}
+	var aql string
+	var err error
+
+	switch ast.Operation {
+	case types.OpFind, types.OpFindOne:
+		aql, err = r.renderFind(ast, q)
+	case types.OpCount:
+		aql, err = r.renderCount(ast, q)
+	case types.OpDistinct:
+		aql, err = r.renderDistinct(ast, q)
+	case types.OpInsert:
+		aql, err = r.renderInsert(ast, q)
+	case types.OpUpdate, types.OpUpdateMany:
+		aql, err = r.renderUpdate(ast, q)
+	case types.OpDelete, types.OpDeleteMany:
+		aql, err = r.renderDelete(ast, q)
+	case types.OpAggregate:
+		aql, err = r.renderAggregate(ast, q)
+	default:
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
+	}
+	if err != nil {
+		return nil, types.WithOperation(err, ast.Operation)
+	}
+
+	var warnings []string
+	if ast.MaxTimeMS != nil {
+		warnings = append(warnings, "arangodb sets query timeouts through cursor options: maxTimeMS ignored")
+	}
+	if _, ok := ast.SortOpts(); ok {
+		warnings = append(warnings, "arangodb orders by raw value: case-insensitive and numeric sort options ignored")
+	}
+
+	result, err := toResult(aql, q)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = warnings
+	result.Operation = ast.Operation
+	result.Single = ast.Operation == types.OpFindOne
+	result.Collection = ast.Target.Name
+	result.Database = ast.Target.Database
+	return result, nil
+}
+
+// writeFor writes the FOR loop over the target collection and its FILTER.
+func (r *Renderer) writeFor(sb *strings.Builder, ast *types.DocumentAST, q *query) error {
+	sb.WriteString("FOR " + docVar + " IN " + ast.Target.Name)
+	if ast.FilterClause == nil {
+		return nil
+	}
+	expr, err := r.buildFilter(ast.FilterClause, docVar, q)
+	if err != nil {
+		return err
+	}
+	if expr != "" {
+		sb.WriteString(" FILTER " + expr)
+	}
+	return nil
+}
+
+func (r *Renderer) renderFind(ast *types.DocumentAST, q *query) (string, error) {
+	var sb strings.Builder
+	if err := r.writeFor(&sb, ast, q); err != nil {
+		return "", err
+	}
+	if err := r.writeSort(&sb, ast.SortClauses, docVar); err != nil {
+		return "", err
+	}
+
+	limit := ast.Limit
+	if ast.Operation == types.OpFindOne {
+		one := 1
+		limit = &types.PaginationValue{Static: &one}
+	}
+	if err := r.writeLimit(&sb, ast.Skip, limit, q); err != nil {
+		return "", err
+	}
+
+	ret, err := r.buildReturn(ast.Projection)
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString(" RETURN " + ret)
+	return sb.String(), nil
+}
+
+func (r *Renderer) renderCount(ast *types.DocumentAST, q *query) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("RETURN LENGTH(")
+	if err := r.writeFor(&sb, ast, q); err != nil {
+		return "", err
+	}
+	sb.WriteString(" RETURN 1)")
+	return sb.String(), nil
+}
+
+func (r *Renderer) renderDistinct(ast *types.DocumentAST, q *query) (string, error) {
+	path, err := fieldPath(docVar, ast.DistinctField.Path)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := r.writeFor(&sb, ast, q); err != nil {
+		return "", err
+	}
+	if len(ast.SortClauses) == 0 && ast.Limit == nil {
+		sb.WriteString(" RETURN DISTINCT " + path)
+		return sb.String(), nil
+	}
+	// RETURN DISTINCT cannot be sorted or limited, so collect the values
+	// first. Validation limits the sort to the distinct field.
+	sb.WriteString(" COLLECT value = " + path)
+	for _, sc := range ast.SortClauses {
+		dir := "ASC"
+		if sc.Order == types.Descending {
+			dir = "DESC"
+		}
+		sb.WriteString(" SORT value " + dir)
+	}
+	if err := r.writeLimit(&sb, nil, ast.Limit, q); err != nil {
+		return "", err
+	}
+	sb.WriteString(" RETURN value")
+	return sb.String(), nil
+}
+
+func (r *Renderer) renderInsert(ast *types.DocumentAST, q *query) (string, error) {
+	obj := newObject()
+	for _, field := range sortedFields(ast.Documents[0].Fields) {
+		ref, err := q.bind(ast.Documents[0].Fields[field])
+		if err != nil {
+			return "", err
+		}
+		if err := obj.set(field.Path, ref); err != nil {
+			return "", err
+		}
+	}
+	return "INSERT " + obj.String() + " INTO " + ast.Target.Name, nil
+}
+
+func (r *Renderer) renderUpdate(ast *types.DocumentAST, q *query) (string, error) {
+	if ast.Upsert {
+		return "", types.UnsupportedFeature(provider, "upsert updates")
+	}
+
+	var sb strings.Builder
+	if err := r.writeFor(&sb, ast, q); err != nil {
+		return "", err
+	}
+	if ast.Operation == types.OpUpdate {
+		sb.WriteString(" LIMIT 1")
+	}
+
+	// keepNull: false applies to the whole patch, so it would also drop a
+	// $set value that binds to null, or a null nested in one.
+	if hasUpdateOperator(ast.UpdateOps, types.Unset) && hasUpdateOperator(ast.UpdateOps, types.Set) {
+		return "", types.UnsupportedFeature(provider, "$unset combined with $set")
+	}
+
+	patch := newObject()
+	keepNull := true
+	for _, op := range ast.UpdateOps {
+		// Rejected before binding, since some unsupported operators carry
+		// no param to bind.
+		if !r.SupportsUpdate(op.Operator) {
+			return "", types.UnsupportedFeature(provider, "update operator: "+string(op.Operator))
+		}
+		for _, field := range sortedFields(op.Fields) {
+			current, err := fieldPath(docVar, field.Path)
+			if err != nil {
+				return "", err
+			}
+			if op.Operator == types.Unset {
+				// Unset is expressed as a null patch value that keepNull: false removes.
+				if err := patch.set(field.Path, "null"); err != nil {
+					return "", err
+				}
+				keepNull = false
+				continue
+			}
+			if op.Operator == types.CurrentDate {
+				// The server's clock, as an ISO 8601 string; there is no param.
+				if err := patch.set(field.Path, "DATE_ISO8601(DATE_NOW())"); err != nil {
+					return "", err
+				}
+				continue
+			}
+			ref, err := q.bind(op.Fields[field])
+			if err != nil {
+				return "", err
+			}
+			var expr string
+			switch op.Operator {
+			case types.Set:
+				expr = ref
+			case types.Inc:
+				expr = current + " + " + ref
+			case types.Mul:
+				expr = current + " * " + ref
+			case types.Min:
+				expr = "MIN([" + current + ", " + ref + "])"
+			case types.Max:
+				expr = "MAX([" + current + ", " + ref + "])"
+			case types.Push:
+				expr = "PUSH(" + current + ", " + ref + ")"
+			case types.AddToSet:
+				expr = "PUSH(" + current + ", " + ref + ", true)"
+			case types.Pull:
+				expr = "REMOVE_VALUE(" + current + ", " + ref + ")"
+			case types.PullAll:
+				expr = "REMOVE_VALUES(" + current + ", " + ref + ")"
+			default:
+				return "", types.UnsupportedFeature(provider, "update operator: "+string(op.Operator))
+			}
+			if err := patch.set(field.Path, expr); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	sb.WriteString(" UPDATE " + docVar + " WITH " + patch.String() + " IN " + ast.Target.Name)
+	if !keepNull {
+		sb.WriteString(" OPTIONS { keepNull: false }")
+	}
+	return sb.String(), nil
+}
+
+// hasUpdateOperator reports whether ops include op.
+func hasUpdateOperator(ops []types.UpdateOperation, op types.UpdateOperator) bool {
+	for _, u := range ops {
+		if u.Operator == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Renderer) renderDelete(ast *types.DocumentAST, q *query) (string, error) {
+	var sb strings.Builder
+	if err := r.writeFor(&sb, ast, q); err != nil {
+		return "", err
+	}
+	if ast.Operation == types.OpDelete {
+		sb.WriteString(" LIMIT 1")
+	}
+	sb.WriteString(" REMOVE " + docVar + " IN " + ast.Target.Name)
+	return sb.String(), nil
+}
+
+// renderAggregate renders a pipeline as a single FOR loop. After a $group
+// stage the COLLECT output is rebound with LET so later stages and the final
+// RETURN read grouped rows the same way earlier stages read documents.
+func (r *Renderer) renderAggregate(ast *types.DocumentAST, q *query) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("FOR " + docVar + " IN " + ast.Target.Name)
+
+	root := docVar
+	groups := 0
+	for i := 0; i < len(ast.Pipeline); i++ {
+		switch stage := ast.Pipeline[i].(type) {
+		case types.MatchStage:
+			expr, err := r.buildFilter(stage.Filter, root, q)
+			if err != nil {
+				return "", fmt.Errorf("stage %d: %w", i, err)
+			}
+			if expr != "" {
+				sb.WriteString(" FILTER " + expr)
+			}
+
+		case types.GroupStage:
+			row := "g" + strconv.Itoa(groups)
+			if err := r.writeCollect(&sb, stage, root, row, q); err != nil {
+				return "", fmt.Errorf("stage %d: %w", i, err)
+			}
+			root = row
+			groups++
+
+		case types.SortStage:
+			if err := r.writeSort(&sb, stage.Sorts, root); err != nil {
+				return "", fmt.Errorf("stage %d: %w", i, err)
+			}
+
+		case types.SkipStage:
+			next, ok := nextStage(ast.Pipeline, i).(types.LimitStage)
+			if !ok {
+				return "", fmt.Errorf("stage %d: %w", i, &types.UnsupportedError{
+					Provider: provider,
+					Features: []string{"$skip without $limit"},
+					Reason:   "$skip must be followed by $limit",
+				})
+			}
+			if err := r.writeLimit(&sb, &stage.Skip, &next.Limit, q); err != nil {
+				return "", fmt.Errorf("stage %d: %w", i, err)
+			}
+			i++
+
+		case types.LimitStage:
+			if err := r.writeLimit(&sb, nil, &stage.Limit, q); err != nil {
+				return "", fmt.Errorf("stage %d: %w", i, err)
+			}
+
+		default:
+			return "", fmt.Errorf("stage %d: %w", i, types.UnsupportedFeature(provider, "pipeline stage: "+stage.StageName()))
+		}
+	}
+
+	sb.WriteString(" RETURN " + root)
+	return sb.String(), nil
+}
+
+func nextStage(pipeline []types.PipelineStage, i int) types.PipelineStage {
+	if i+1 < len(pipeline) {
+		return pipeline[i+1]
+	}
+	return nil
+}
+
+// writeCollect renders a $group stage as COLLECT ... AGGREGATE and binds the
+// grouped row to row. The key and aggregate variables are prefixed with the
+// row name so they cannot shadow loop variables or each other.
+func (r *Renderer) writeCollect(sb *strings.Builder, stage types.GroupStage, root, row string, q *query) error {
+	out := newObject()
+	sb.WriteString(" COLLECT")
+
+	if stage.ID != nil {
+		key, err := r.buildExpr(stage.ID, root, q)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(" " + row + "key = " + key)
+		if err := out.set(types.IDField, row+"key"); err != nil {
+			return err
+		}
+	} else if err := out.set(types.IDField, "null"); err != nil {
+		return err
+	}
+
+	names := slices.Sorted(maps.Keys(stage.Accumulators))
+	aggs := make([]string, 0, len(names))
+	for _, name := range names {
+		if !types.IsValidIdentifier(name) {
+			return &types.InvalidIdentifierError{Kind: "accumulator name", Value: name}
+		}
+		acc := stage.Accumulators[name]
+		fn := mapAccumulator(acc.Operator)
+		if fn == "" {
+			return types.UnsupportedFeature(provider, "accumulator: "+string(acc.Operator))
+		}
+		arg, err := r.buildExpr(acc.Expr, root, q)
+		if err != nil {
+			return err
+		}
+		variable := row + "_" + name
+		aggs = append(aggs, variable+" = "+fn+"("+arg+")")
+		if err := out.set(name, variable); err != nil {
+			return err
+		}
+	}
+	if len(aggs) > 0 {
+		sb.WriteString(" AGGREGATE " + strings.Join(aggs, ", "))
+	} else if stage.ID == nil {
+		// COLLECT needs a group or an aggregate; counting groups everything.
+		sb.WriteString(" WITH COUNT INTO " + row + "count")
+	}
+
+	sb.WriteString(" LET " + row + " = " + out.String())
+	return nil
+}
+
+func (r *Renderer) buildExpr(expr types.Expression, root string, q *query) (string, error) {
+	switch e := expr.(type) {
+	case types.FieldExpression:
+		return fieldPath(root, e.Field.Path)
+	case types.LiteralExpression:
+		return q.bind(e.Value)
+	default:
+		return "", types.UnsupportedFeature(provider, fmt.Sprintf("expression type: %T", expr))
+	}
+}
+
+func mapAccumulator(op string) string {
+	switch op {
+	case types.AccSum:
+		return "SUM"
+	case types.AccAvg:
+		return "AVERAGE"
+	case types.AccMin:
+		return "MIN"
+	case types.AccMax:
+		return "MAX"
+	default:
+		return ""
+	}
+}
+
+func (r *Renderer) writeSort(sb *strings.Builder, sorts []types.SortClause, root string) error {
+	if len(sorts) == 0 {
+		return nil
+	}
+	parts := make([]string, len(sorts))
+	for i, s := range sorts {
+		path, err := fieldPath(root, s.Field.Path)
+		if err != nil {
+			return err
+		}
+		dir := "ASC"
+		if s.Order == types.Descending {
+			dir = "DESC"
+		}
+		parts[i] = path + " " + dir
+	}
+	sb.WriteString(" SORT " + strings.Join(parts, ", "))
+	return nil
+}
+
+// writeLimit writes AQL's LIMIT offset, count form. The offset comes first,
+// unlike most dialects, and cannot be given without a count.
+func (r *Renderer) writeLimit(sb *strings.Builder, skip, limit *types.PaginationValue, q *query) error {
+	if limit == nil {
+		if skip != nil {
+			return &types.UnsupportedError{
+				Provider: provider,
+				Features: []string{"skip without limit"},
+				Reason:   "skip must be combined with a limit",
+			}
+		}
+		return nil
+	}
+	sb.WriteString(" LIMIT ")
+	if skip != nil {
+		offset, err := r.pagination(*skip, q)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(offset + ", ")
+	}
+	count, err := r.pagination(*limit, q)
+	if err != nil {
+		return err
+	}
+	sb.WriteString(count)
+	return nil
+}
+
+func (r *Renderer) pagination(p types.PaginationValue, q *query) (string, error) {
+	if p.Static != nil {
+		return strconv.Itoa(*p.Static), nil
+	}
+	if p.Param != nil {
+		return q.bind(*p.Param)
+	}
+	return "", fmt.Errorf("pagination value has neither a static value nor a param")
+}
+
+// buildReturn renders the RETURN expression: the whole document, an object
+// of included fields, or UNSET for top-level exclusions.
+func (r *Renderer) buildReturn(p *types.Projection) (string, error) {
+	if p == nil {
+		return docVar, nil
+	}
+
+	obj := newObject()
+	var excluded []string
+	for _, f := range p.Fields {
+		if f.Slice != nil || f.ElemMatch != nil {
+			return "", types.UnsupportedFeature(provider, "array projection operators on field: "+f.Field.Path)
+		}
+		if f.Include {
+			path, err := fieldPath(docVar, f.Field.Path)
+			if err != nil {
+				return "", err
+			}
+			if err := obj.set(f.OutputName(), path); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if !types.IsValidFieldPath(f.Field.Path) {
+			return "", &types.InvalidIdentifierError{Kind: "field path", Value: f.Field.Path}
+		}
+		if strings.Contains(f.Field.Path, ".") {
+			return "", types.UnsupportedFeature(provider, "nested projection exclusions: "+f.Field.Path)
+		}
+		excluded = append(excluded, strconv.Quote(f.Field.Path))
+	}
+
+	if !obj.empty() {
+		// Included fields already define the result; a lone _id exclusion
+		// simply leaves it out of the object.
+		return obj.String(), nil
+	}
+	return "UNSET(" + docVar + ", " + strings.Join(excluded, ", ") + ")", nil
+}
+
+func (r *Renderer) buildFilter(f types.FilterItem, root string, q *query) (string, error) {
+	switch filter := f.(type) {
+	case types.FilterCondition:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		ref, err := q.bind(filter.Value)
+		if err != nil {
+			return "", err
+		}
+		op := mapOperator(filter.Operator)
+		if op == "" {
+			return "", types.UnsupportedFilter(provider, string(filter.Operator))
+		}
+		return fmt.Sprintf("%s %s %s", path, op, ref), nil
+
+	case types.ValuesFilter:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		refs := make([]string, len(filter.Values))
+		for i, v := range filter.Values {
+			if refs[i], err = q.bind(v); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("%s %s [%s]", path, mapOperator(filter.Operator), strings.Join(refs, ", ")), nil
+
+	case types.FilterGroup:
+		exprs := make([]string, 0, len(filter.Conditions))
+		for _, c := range filter.Conditions {
+			expr, err := r.buildFilter(c, root, q)
+			if err != nil {
+				return "", err
+			}
+			if expr != "" {
+				exprs = append(exprs, "("+expr+")")
+			}
+		}
+		if len(exprs) == 0 {
+			return "", nil
+		}
+		switch filter.Logic {
+		case types.AND:
+			return strings.Join(exprs, " AND "), nil
+		case types.OR:
+			return strings.Join(exprs, " OR "), nil
+		case types.NOR:
+			return "NOT (" + strings.Join(exprs, " OR ") + ")", nil
+		case types.NOT:
+			return "NOT (" + strings.Join(exprs, " AND ") + ")", nil
+		default:
+			return "", types.UnsupportedFilter(provider, string(filter.Logic))
+		}
+
+	case types.RangeFilter:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		var parts []string
+		if filter.Min != nil {
+			ref, err := q.bind(*filter.Min)
+			if err != nil {
+				return "", err
+			}
+			op := ">="
+			if filter.MinExclusive {
+				op = ">"
+			}
+			parts = append(parts, fmt.Sprintf("%s %s %s", path, op, ref))
+		}
+		if filter.Max != nil {
+			ref, err := q.bind(*filter.Max)
+			if err != nil {
+				return "", err
+			}
+			op := "<="
+			if filter.MaxExclusive {
+				op = "<"
+			}
+			parts = append(parts, fmt.Sprintf("%s %s %s", path, op, ref))
+		}
+		if filter.Negated {
+			return "NOT (" + strings.Join(parts, " AND ") + ")", nil
+		}
+		return strings.Join(parts, " AND "), nil
+
+	case types.RegexFilter:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		pattern, err := q.bind(filter.Pattern)
+		if err != nil {
+			return "", err
+		}
+		if filter.Options != nil {
+			opts, err := q.bind(*filter.Options)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("REGEX_TEST(%s, %s, CONTAINS(%s, \"i\"))", path, pattern, opts), nil
+		}
+		return fmt.Sprintf("REGEX_TEST(%s, %s)", path, pattern), nil
+
+	case types.ArrayFilter:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		if filter.Literal != nil && filter.Operator == types.Size {
+			return fmt.Sprintf("LENGTH(%s) == %d", path, *filter.Literal), nil
+		}
+		ref, err := q.bind(filter.Value)
+		if err != nil {
+			return "", err
+		}
+		switch filter.Operator {
+		case types.All:
+			return fmt.Sprintf("%s ALL IN %s", ref, path), nil
+		case types.Size:
+			return fmt.Sprintf("LENGTH(%s) == %s", path, ref), nil
+		default:
+			return "", types.UnsupportedFilter(provider, string(filter.Operator))
+		}
+
+	case types.ElemMatchFilter:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		conds := make([]string, 0, len(filter.Conditions))
+		for _, c := range filter.Conditions {
+			expr, err := r.buildFilter(c, "CURRENT", q)
+			if err != nil {
+				return "", err
+			}
+			conds = append(conds, "("+expr+")")
+		}
+		return fmt.Sprintf("LENGTH(%s[* FILTER %s]) > 0", path, strings.Join(conds, " AND ")), nil
+
+	case types.ExistsFilter:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		if filter.Exists {
+			return path + " != null", nil
+		}
+		return path + " == null", nil
+
+	default:
+		return "", types.UnsupportedFilter(provider, types.FilterName(f))
+	}
+}
+
+func mapOperator(op types.FilterOperator) string {
+	switch op {
+	case types.EQ:
+		return "=="
+	case types.NE:
+		return "!="
+	case types.GT:
+		return ">"
+	case types.GTE:
+		return ">="
+	case types.LT:
+		return "<"
+	case types.LTE:
+		return "<="
+	case types.IN:
+		return "IN"
+	case types.NotIn:
+		return "NOT IN"
+	default:
+		return ""
+	}
+}
+
+// fieldPath renders a dot-notation path against root, e.g. address.city
+// becomes d.address.city. Keyword and $-prefixed segments are backtick-quoted.
+func fieldPath(root, path string) (string, error) {
+	if !types.IsValidFieldPath(path) {
+		return "", &types.InvalidIdentifierError{Kind: "field path", Value: path}
+	}
+	var sb strings.Builder
+	sb.WriteString(root)
+	for _, seg := range strings.Split(path, ".") {
+		sb.WriteByte('.')
+		if strings.HasPrefix(seg, "$") || keywords[strings.ToLower(seg)] {
+			sb.WriteString("`" + seg + "`")
+			continue
+		}
+		sb.WriteString(seg)
+	}
+	return sb.String(), nil
+}
+
+// object builds an AQL object literal from dot-notation paths, nesting
+// sub-objects so address.city becomes {"address": {"city": ...}}.
+type object struct {
+	values   map[string]string
+	children map[string]*object
+}
+
+func newObject() *object {
+	return &object{values: map[string]string{}, children: map[string]*object{}}
+}
+
+func (o *object) empty() bool {
+	return len(o.values) == 0 && len(o.children) == 0
+}
+
+func (o *object) set(path, expr string) error {
+	if !types.IsValidFieldPath(path) {
+		return &types.InvalidIdentifierError{Kind: "field path", Value: path}
+	}
+	segs := strings.Split(path, ".")
+	node := o
+	for _, seg := range segs[:len(segs)-1] {
+		if _, ok := node.values[seg]; ok {
+			return fmt.Errorf("conflicting field paths at: %s", path)
+		}
+		child, ok := node.children[seg]
+		if !ok {
+			child = newObject()
+			node.children[seg] = child
+		}
+		node = child
+	}
+	last := segs[len(segs)-1]
+	if _, ok := node.children[last]; ok {
+		return fmt.Errorf("conflicting field paths at: %s", path)
+	}
+	if _, ok := node.values[last]; ok {
+		return fmt.Errorf("conflicting field paths at: %s", path)
+	}
+	node.values[last] = expr
+	return nil
+}
+
+func (o *object) String() string {
+	keys := slices.Sorted(maps.Keys(o.values))
+	for k := range o.children {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		if child, ok := o.children[k]; ok {
+			parts[i] = strconv.Quote(k) + ": " + child.String()
+		} else {
+			parts[i] = strconv.Quote(k) + ": " + o.values[k]
+		}
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func sortedFields(fields map[types.Field]types.Param) []types.Field {
+	return slices.SortedFunc(maps.Keys(fields), func(a, b types.Field) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+}
+
+// SupportsOperation indicates if ArangoDB supports an operation.
+func (r *Renderer) SupportsOperation(op types.Operation) bool {
+	switch op {
+	case types.OpFind, types.OpFindOne, types.OpCount, types.OpDistinct,
+		types.OpInsert, types.OpUpdate, types.OpUpdateMany,
+		types.OpDelete, types.OpDeleteMany, types.OpAggregate:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsFilter indicates if ArangoDB supports a filter operator.
+func (r *Renderer) SupportsFilter(op types.FilterOperator) bool {
+	switch op {
+	case types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE,
+		types.IN, types.NotIn, types.Exists, types.Regex,
+		types.All, types.Size, types.ElemMatch:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsUpdate indicates if ArangoDB supports an update operator.
+func (r *Renderer) SupportsUpdate(op types.UpdateOperator) bool {
+	switch op {
+	case types.Set, types.Unset, types.Inc, types.Mul, types.Min, types.Max,
+		types.CurrentDate, types.Push, types.AddToSet, types.Pull, types.PullAll:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsPipelineStage indicates if ArangoDB supports a pipeline stage.
+func (r *Renderer) SupportsPipelineStage(stage string) bool {
+	switch stage {
+	case "$match", "$group", "$sort", "$skip", "$limit":
+		return true
+	default:
+		return false
+	}
+}
+
+func toResult(aql string, q *query) (*types.QueryResult, error) {
+	out := map[string]interface{}{"query": aql}
+	if len(q.bindVars) > 0 {
+		out["bindVars"] = q.bindVars
+	}
+	jsonBytes, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize query: %w", err)
+	}
+	return &types.QueryResult{
+		JSON:           string(jsonBytes),
+		RequiredParams: q.params,
+	}, nil
+}
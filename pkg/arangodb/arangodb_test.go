@@ -0,0 +1,525 @@
+package arangodb
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"encoding/json"
+	"github.com/zoobzio/docql/internal/types"
+)
+
+type rendered struct {
+	Query    string            `json:"query"`
+	BindVars map[string]string `json:"bindVars"`
+}
+
+func render(t *testing.T, ast *types.DocumentAST) (rendered, *types.QueryResult) {
+	t.Helper()
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out rendered
+	if err := json.Unmarshal([]byte(result.JSON), &out); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	return out, result
+}
+
+func intPtr(i int) *int { return &i }
+
+func eq(path, param string) types.FilterCondition {
+	return types.FilterCondition{Field: types.Field{Path: path}, Operator: types.EQ, Value: types.Param{Name: param}}
+}
+
+func TestRenderFind(t *testing.T) {
+	out, result := render(t, &types.DocumentAST{
+		Operation:    types.OpFind,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: eq("active", "active"),
+		SortClauses:  []types.SortClause{{Field: types.Field{Path: "age"}, Order: types.Descending}},
+		Skip:         &types.PaginationValue{Static: intPtr(20)},
+		Limit:        &types.PaginationValue{Static: intPtr(10)},
+	})
+
+	expected := "FOR d IN users FILTER d.active == @active SORT d.age DESC LIMIT 20, 10 RETURN d"
+	if out.Query != expected {
+		t.Errorf("expected %q, got %q", expected, out.Query)
+	}
+	if len(result.RequiredParams) != 1 || result.RequiredParams[0] != "active" {
+		t.Errorf("expected [active], got %v", result.RequiredParams)
+	}
+	if out.BindVars["active"] != ":active" {
+		t.Errorf("expected bind var active, got %v", out.BindVars)
+	}
+}
+
+func TestRenderFind_LimitOffsetOrder(t *testing.T) {
+	// AQL puts the offset before the count: LIMIT offset, count.
+	out, result := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Limit:     &types.PaginationValue{Param: &types.Param{Name: "pageSize"}},
+		Skip:      &types.PaginationValue{Param: &types.Param{Name: "offset"}},
+	})
+
+	if !strings.Contains(out.Query, "LIMIT @offset, @pageSize") {
+		t.Errorf("expected offset before count, got %q", out.Query)
+	}
+	if len(result.RequiredParams) != 2 || result.RequiredParams[0] != "offset" || result.RequiredParams[1] != "pageSize" {
+		t.Errorf("expected [offset pageSize], got %v", result.RequiredParams)
+	}
+
+	out, _ = render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Limit:     &types.PaginationValue{Static: intPtr(5)},
+	})
+	if out.Query != "FOR d IN users LIMIT 5 RETURN d" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+
+	_, err := New().Render(&types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Skip:      &types.PaginationValue{Static: intPtr(5)},
+	})
+	if err == nil {
+		t.Error("expected error for skip without limit")
+	}
+}
+
+func TestRenderFindOne(t *testing.T) {
+	out, _ := render(t, &types.DocumentAST{
+		Operation: types.OpFindOne,
+		Target:    types.Collection{Name: "users"},
+		Skip:      &types.PaginationValue{Static: intPtr(2)},
+	})
+	if out.Query != "FOR d IN users LIMIT 2, 1 RETURN d" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+}
+
+func TestRenderFind_Projection(t *testing.T) {
+	out, _ := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: types.IDField}, Include: false},
+			{Field: types.Field{Path: "username"}, Include: true},
+			{Field: types.Field{Path: "address.city"}, Include: true},
+		}},
+	})
+	expected := `FOR d IN users RETURN {"address": {"city": d.address.city}, "username": d.username}`
+	if out.Query != expected {
+		t.Errorf("expected %q, got %q", expected, out.Query)
+	}
+
+	out, _ = render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Exclude: true, Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "password"}, Include: false},
+		}},
+	})
+	if out.Query != `FOR d IN users RETURN UNSET(d, "password")` {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+}
+
+func TestRenderFind_ProjectionAliases(t *testing.T) {
+	out, result := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: types.IDField}, Include: true, Alias: "id"},
+			{Field: types.Field{Path: "username"}, Include: true},
+		}},
+	})
+	expected := `FOR d IN users RETURN {"id": d._id, "username": d.username}`
+	if out.Query != expected {
+		t.Errorf("expected %q, got %q", expected, out.Query)
+	}
+	if result.FieldAliases != nil {
+		t.Errorf("expected no FieldAliases, got %v", result.FieldAliases)
+	}
+}
+
+func TestRenderFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   types.FilterItem
+		expected string
+	}{
+		{
+			name:     "keyword segment",
+			filter:   eq("meta.filter", "v"),
+			expected: "d.meta.`filter` == @v",
+		},
+		{
+			name:     "in",
+			filter:   types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.IN, Value: types.Param{Name: "s"}},
+			expected: "d.status IN @s",
+		},
+		{
+			name:     "not in",
+			filter:   types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.NotIn, Value: types.Param{Name: "s"}},
+			expected: "d.status NOT IN @s",
+		},
+		{
+			name: "group",
+			filter: types.FilterGroup{Logic: types.NOR, Conditions: []types.FilterItem{
+				eq("a", "a"), eq("b", "b"),
+			}},
+			expected: "NOT ((d.a == @a) OR (d.b == @b))",
+		},
+		{
+			name:     "range",
+			filter:   types.RangeFilter{Field: types.Field{Path: "age"}, Min: &types.Param{Name: "min"}, MinExclusive: true},
+			expected: "d.age > @min",
+		},
+		{
+			name:     "exists",
+			filter:   types.ExistsFilter{Field: types.Field{Path: "email"}, Exists: true},
+			expected: "d.email != null",
+		},
+		{
+			name:     "regex",
+			filter:   types.RegexFilter{Field: types.Field{Path: "name"}, Pattern: types.Param{Name: "p"}, Options: &types.Param{Name: "o"}},
+			expected: `REGEX_TEST(d.name, @p, CONTAINS(@o, "i"))`,
+		},
+		{
+			name:     "all",
+			filter:   types.ArrayFilter{Field: types.Field{Path: "tags"}, Operator: types.All, Value: types.Param{Name: "tags"}},
+			expected: "@tags ALL IN d.tags",
+		},
+		{
+			name: "elemMatch",
+			filter: types.ElemMatchFilter{Field: types.Field{Path: "items"}, Conditions: []types.FilterItem{
+				types.FilterCondition{Field: types.Field{Path: "qty"}, Operator: types.GT, Value: types.Param{Name: "qty"}},
+			}},
+			expected: "LENGTH(d.items[* FILTER (CURRENT.qty > @qty)]) > 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _ := render(t, &types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: tt.filter,
+			})
+			expected := "FOR d IN users FILTER " + tt.expected + " RETURN d"
+			if out.Query != expected {
+				t.Errorf("expected %q, got %q", expected, out.Query)
+			}
+		})
+	}
+}
+
+func TestRenderCount(t *testing.T) {
+	out, _ := render(t, &types.DocumentAST{
+		Operation:    types.OpCount,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: eq("active", "active"),
+	})
+	if out.Query != "RETURN LENGTH(FOR d IN users FILTER d.active == @active RETURN 1)" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+}
+
+func TestRenderInsert(t *testing.T) {
+	out, result := render(t, &types.DocumentAST{
+		Operation: types.OpInsert,
+		Target:    types.Collection{Name: "users"},
+		Documents: []types.Document{{Fields: map[types.Field]types.Param{
+			{Path: "username"}:     {Name: "username"},
+			{Path: "address.city"}: {Name: "city"},
+		}}},
+	})
+	expected := `INSERT {"address": {"city": @city}, "username": @username} INTO users`
+	if out.Query != expected {
+		t.Errorf("expected %q, got %q", expected, out.Query)
+	}
+	if len(result.RequiredParams) != 2 {
+		t.Errorf("expected 2 params, got %v", result.RequiredParams)
+	}
+}
+
+func TestRenderUpdate(t *testing.T) {
+	out, _ := render(t, &types.DocumentAST{
+		Operation:    types.OpUpdateMany,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: eq("status", "status"),
+		UpdateOps: []types.UpdateOperation{
+			{Operator: types.Inc, Fields: map[types.Field]types.Param{{Path: "logins"}: {Name: "n"}}},
+			{Operator: types.Unset, Fields: map[types.Field]types.Param{{Path: "token"}: {Name: "token"}}},
+		},
+	})
+	expected := `FOR d IN users FILTER d.status == @status UPDATE d WITH {"logins": d.logins + @n, "token": null} IN users OPTIONS { keepNull: false }`
+	if out.Query != expected {
+		t.Errorf("expected %q, got %q", expected, out.Query)
+	}
+
+	// keepNull: false would also drop a $set value bound to null.
+	_, err := New().Render(&types.DocumentAST{
+		Operation:    types.OpUpdateMany,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: eq("status", "status"),
+		UpdateOps: []types.UpdateOperation{
+			{Operator: types.Set, Fields: map[types.Field]types.Param{{Path: "active"}: {Name: "active"}}},
+			{Operator: types.Unset, Fields: map[types.Field]types.Param{{Path: "token"}: {Name: "token"}}},
+		},
+	})
+	var unsupported *types.UnsupportedError
+	if !errors.As(err, &unsupported) {
+		t.Errorf("expected $unset with $set to be unsupported, got %v", err)
+	}
+
+	out, _ = render(t, &types.DocumentAST{
+		Operation:    types.OpUpdate,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: eq("_key", "key"),
+		UpdateOps: []types.UpdateOperation{
+			{Operator: types.Set, Fields: map[types.Field]types.Param{{Path: "active"}: {Name: "active"}}},
+		},
+	})
+	expected = `FOR d IN users FILTER d._key == @key LIMIT 1 UPDATE d WITH {"active": @active} IN users`
+	if out.Query != expected {
+		t.Errorf("expected %q, got %q", expected, out.Query)
+	}
+}
+
+func TestRenderDelete(t *testing.T) {
+	out, _ := render(t, &types.DocumentAST{
+		Operation:    types.OpDeleteMany,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: eq("active", "active"),
+	})
+	if out.Query != "FOR d IN users FILTER d.active == @active REMOVE d IN users" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+
+	out, _ = render(t, &types.DocumentAST{
+		Operation:    types.OpDelete,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: eq("active", "active"),
+	})
+	if out.Query != "FOR d IN users FILTER d.active == @active LIMIT 1 REMOVE d IN users" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+}
+
+func TestRenderAggregate(t *testing.T) {
+	out, _ := render(t, &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "orders"},
+		Pipeline: []types.PipelineStage{
+			types.MatchStage{Filter: eq("status", "status")},
+			types.GroupStage{
+				ID: types.FieldExpression{Field: types.Field{Path: "customer"}},
+				Accumulators: map[string]types.Accumulator{
+					"total": {Operator: types.AccSum, Expr: types.FieldExpression{Field: types.Field{Path: "amount"}}},
+					"avg":   {Operator: types.AccAvg, Expr: types.FieldExpression{Field: types.Field{Path: "amount"}}},
+				},
+			},
+			types.SortStage{Sorts: []types.SortClause{{Field: types.Field{Path: "total"}, Order: types.Descending}}},
+			types.SkipStage{Skip: types.PaginationValue{Static: intPtr(10)}},
+			types.LimitStage{Limit: types.PaginationValue{Static: intPtr(5)}},
+		},
+	})
+
+	expected := "FOR d IN orders FILTER d.status == @status" +
+		" COLLECT g0key = d.customer AGGREGATE g0_avg = AVERAGE(d.amount), g0_total = SUM(d.amount)" +
+		` LET g0 = {"_id": g0key, "avg": g0_avg, "total": g0_total}` +
+		" SORT g0.total DESC LIMIT 10, 5 RETURN g0"
+	if out.Query != expected {
+		t.Errorf("expected %q, got %q", expected, out.Query)
+	}
+
+	out, _ = render(t, &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "orders"},
+		Pipeline:  []types.PipelineStage{types.GroupStage{}},
+	})
+	expected = `FOR d IN orders COLLECT WITH COUNT INTO g0count LET g0 = {"_id": null} RETURN g0`
+	if out.Query != expected {
+		t.Errorf("expected %q, got %q", expected, out.Query)
+	}
+}
+
+func TestRenderAggregate_Unsupported(t *testing.T) {
+	tests := []struct {
+		name  string
+		stage types.PipelineStage
+	}{
+		{"stage", types.UnwindStage{Path: types.Field{Path: "tags"}}},
+		{"accumulator", types.GroupStage{Accumulators: map[string]types.Accumulator{
+			"all": {Operator: types.AccPush, Expr: types.FieldExpression{Field: types.Field{Path: "x"}}},
+		}}},
+		{"skip without limit", types.SkipStage{Skip: types.PaginationValue{Static: intPtr(1)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New().Render(&types.DocumentAST{
+				Operation: types.OpAggregate,
+				Target:    types.Collection{Name: "orders"},
+				Pipeline:  []types.PipelineStage{tt.stage},
+			})
+			if err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestRender_RejectsUnsafeIdentifiers(t *testing.T) {
+	tests := []struct {
+		name string
+		ast  *types.DocumentAST
+	}{
+		{"collection", &types.DocumentAST{Operation: types.OpFind, Target: types.Collection{Name: "users RETURN 1"}}},
+		{"field", &types.DocumentAST{
+			Operation: types.OpFind, Target: types.Collection{Name: "users"},
+			FilterClause: eq("a == 1 || d.b", "x"),
+		}},
+		{"param", &types.DocumentAST{
+			Operation: types.OpFind, Target: types.Collection{Name: "users"},
+			FilterClause: eq("a", "x || true"),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New().Render(tt.ast); err == nil {
+				t.Error("expected error for unsafe identifier")
+			}
+		})
+	}
+}
+
+func TestSupports(t *testing.T) {
+	r := New()
+	if r.SupportsOperation(types.OpInsertMany) {
+		t.Error("expected InsertMany to be unsupported")
+	}
+	if !r.SupportsOperation(types.OpAggregate) {
+		t.Error("expected Aggregate to be supported")
+	}
+	if r.SupportsFilter(types.Text) {
+		t.Error("expected $text to be unsupported")
+	}
+	if r.SupportsUpdate(types.Rename) {
+		t.Error("expected $rename to be unsupported")
+	}
+	if !r.SupportsPipelineStage("$group") || r.SupportsPipelineStage("$lookup") {
+		t.Error("unexpected pipeline stage support")
+	}
+}
+
+func TestRenderFind_InValues(t *testing.T) {
+	out, result := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+			types.ValuesFilter{Field: types.Field{Path: "status"}, Operator: types.IN, Values: []types.Param{{Name: "a"}, {Name: "b"}}},
+			types.ValuesFilter{Field: types.Field{Path: "role"}, Operator: types.NotIn, Values: []types.Param{{Name: "banned"}}},
+		}},
+	})
+	if !strings.Contains(out.Query, "FILTER (d.status IN [@a, @b]) AND (d.role NOT IN [@banned])") {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+	if strings.Join(result.RequiredParams, ",") != "a,b,banned" {
+		t.Errorf("RequiredParams = %v", result.RequiredParams)
+	}
+}
+
+func TestRender_EmptyParams(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	email := types.Field{Path: "email"}
+	age := types.Field{Path: "age"}
+	byKey := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+
+	tests := []struct {
+		name    string
+		ast     *types.DocumentAST
+		context string
+	}{
+		{
+			name:    "filter",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.FilterCondition{Field: email, Operator: types.EQ}},
+			context: "$eq on field 'email'",
+		},
+		{
+			name:    "range bound",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.RangeFilter{Field: age, Min: &types.Param{}}},
+			context: "range on field 'age'",
+		},
+		{
+			name:    "document",
+			ast:     &types.DocumentAST{Operation: types.OpInsert, Target: users, Documents: []types.Document{{Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "document field 'email'",
+		},
+		{
+			name: "update",
+			ast: &types.DocumentAST{Operation: types.OpUpdate, Target: users, FilterClause: byKey,
+				UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "$set field 'email'",
+		},
+		{
+			name:    "pagination",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: byKey, Limit: &types.PaginationValue{Param: &types.Param{}}},
+			context: "limit",
+		},
+		{
+			name: "pipeline expression",
+			ast: &types.DocumentAST{Operation: types.OpAggregate, Target: users,
+				Pipeline: []types.PipelineStage{types.AddFieldsStage{Fields: map[string]types.Expression{"flag": types.LiteralExpression{}}}}},
+			context: "$addFields field 'flag'",
+		},
+	}
+
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Render(tt.ast)
+			if !errors.Is(err, types.ErrInvalidIdentifier) {
+				t.Fatalf("expected invalid identifier error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.context) {
+				t.Errorf("expected error naming %q, got %v", tt.context, err)
+			}
+		})
+	}
+}
+
+func TestRender_ValuelessUpdateOperators(t *testing.T) {
+	r := New()
+	for _, op := range []types.UpdateOperator{types.Unset, types.CurrentDate} {
+		t.Run(string(op), func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:    types.OpUpdate,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+				UpdateOps:    []types.UpdateOperation{{Operator: op, Fields: map[types.Field]types.Param{{Path: "deletedAt"}: {}}}},
+			}
+			result, err := r.Render(ast)
+			if !r.SupportsUpdate(op) {
+				var unsupported *types.UnsupportedError
+				if !errors.As(err, &unsupported) {
+					t.Errorf("expected unsupported operator error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if slices.Contains(result.RequiredParams, "") {
+				t.Errorf("unexpected empty param in %v", result.RequiredParams)
+			}
+		})
+	}
+}
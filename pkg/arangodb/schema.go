@@ -0,0 +1,24 @@
+package arangodb
+
+// OutputSchema returns a JSON Schema (draft 2020-12) describing the JSON that
+// Render produces: an AQL query and its bind variables, in the form the
+// cursor API accepts.
+func OutputSchema() string {
+	return outputSchema
+}
+
+const outputSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "docql ArangoDB query",
+  "type": "object",
+  "properties": {
+    "query": {"type": "string"},
+    "bindVars": {
+      "type": "object",
+      "description": "Bind variable names, as the query refers to them without @, to :param placeholders.",
+      "additionalProperties": {"type": "string"}
+    }
+  },
+  "required": ["query"],
+  "additionalProperties": false
+}`
@@ -0,0 +1,537 @@
+// Package cosmosdb provides an Azure Cosmos DB (SQL API) renderer for DOCQL.
+package cosmosdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// alias is the name Cosmos SQL queries use for the container's documents.
+const alias = "c"
+
+// reservedWords lists Cosmos SQL keywords that cannot appear as bare
+// property names and must use bracket notation instead.
+var reservedWords = map[string]bool{
+	"and": true, "array": true, "as": true, "asc": true, "between": true,
+	"by": true, "case": true, "desc": true, "distinct": true, "escape": true,
+	"exists": true, "false": true, "for": true, "from": true, "group": true,
+	"in": true, "join": true, "like": true, "limit": true, "not": true,
+	"null": true, "offset": true, "or": true, "order": true, "select": true,
+	"top": true, "true": true, "udf": true, "undefined": true, "value": true,
+	"where": true,
+}
+
+// Renderer renders DocumentAST to Cosmos DB SQL query format.
+type Renderer struct{}
+
+// provider names Cosmos DB in UnsupportedError values.
+const provider = "cosmosdb"
+
+// New creates a new Cosmos DB renderer.
+func New() *Renderer {
+	return &Renderer{}
+}
+
+// query accumulates the parameters referenced by a rendered SQL statement.
+type query struct {
+	params     []string
+	parameters []map[string]string
+	seen       map[string]bool
+}
+
+// param returns the @-reference for a DOCQL param, registering it on first use.
+func (q *query) param(p types.Param) (string, error) {
+	if !types.IsValidIdentifier(p.Name) {
+		return "", &types.InvalidIdentifierError{Kind: "param name", Value: p.Name}
+	}
+	ref := "@" + p.Name
+	if !q.seen[p.Name] {
+		if q.seen == nil {
+			q.seen = make(map[string]bool)
+		}
+		q.seen[p.Name] = true
+		q.params = append(q.params, p.Name)
+		q.parameters = append(q.parameters, map[string]string{
+			"name":  ref,
+			"value": ":" + p.Name,
+		})
+	}
+	return ref, nil
+}
+
+// Render converts a DocumentAST to Cosmos DB SQL query format.
+func (r *Renderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
+	if err := ast.Validate(); err != nil {
+		return nil, &types.InvalidASTError{Err: err}
+	}
+
+	if ast.ParameterizedTarget() {
+		return nil, types.UnsupportedFeature(provider, "parameterized collection")
+	}
+
+	if !r.SupportsOperation(ast.Operation) {
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
+	}
+
+	q := &query{}
+	var sql string
+	var err error
+
+	switch ast.Operation {
+	case types.OpFind, types.OpFindOne:
+		sql, err = r.renderSelect(ast, q)
+	case types.OpCount:
+		sql, err = r.renderCount(ast, q)
+	case types.OpDistinct:
+		sql, err = r.renderDistinct(ast, q)
+	default:
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
+	}
+	if err != nil {
+		return nil, types.WithOperation(err, ast.Operation)
+	}
+
+	var warnings []string
+	if ast.MaxTimeMS != nil {
+		warnings = append(warnings, "cosmosdb does not support per-query timeouts in SQL: maxTimeMS ignored")
+	}
+	if _, ok := ast.SortOpts(); ok {
+		warnings = append(warnings, "cosmosdb orders by raw value: case-insensitive and numeric sort options ignored")
+	}
+
+	result, err := toResult(ast.Target.Name, sql, q)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = warnings
+	result.Operation = ast.Operation
+	result.Single = ast.Operation == types.OpFindOne
+	result.Collection = ast.Target.Name
+	result.Database = ast.Target.Database
+	return result, nil
+}
+
+func (r *Renderer) renderSelect(ast *types.DocumentAST, q *query) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+
+	// TOP cannot be combined with OFFSET, so FindOne only uses it when no
+	// skip is present; otherwise the pagination clause carries LIMIT 1.
+	top := ast.Operation == types.OpFindOne && ast.Skip == nil
+	if top {
+		sb.WriteString("TOP 1 ")
+	}
+
+	selectList, err := r.buildSelectList(ast.Projection)
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString(selectList)
+	sb.WriteString(" FROM " + alias)
+
+	if err := r.writeWhere(&sb, ast.FilterClause, q); err != nil {
+		return "", err
+	}
+
+	if len(ast.SortClauses) > 0 {
+		parts := make([]string, len(ast.SortClauses))
+		for i, s := range ast.SortClauses {
+			path, err := fieldPath(alias, s.Field.Path)
+			if err != nil {
+				return "", err
+			}
+			dir := "ASC"
+			if s.Order == types.Descending {
+				dir = "DESC"
+			}
+			parts[i] = path + " " + dir
+		}
+		sb.WriteString(" ORDER BY " + strings.Join(parts, ", "))
+	}
+
+	if top {
+		return sb.String(), nil
+	}
+
+	limit := ast.Limit
+	if ast.Operation == types.OpFindOne {
+		one := 1
+		limit = &types.PaginationValue{Static: &one}
+	}
+
+	if ast.Skip != nil || limit != nil {
+		// Cosmos only accepts OFFSET and LIMIT as a pair.
+		if limit == nil {
+			return "", &types.UnsupportedError{
+				Provider: provider,
+				Features: []string{"skip without limit"},
+				Reason:   "skip must be combined with a limit",
+			}
+		}
+		offset := "0"
+		if ast.Skip != nil {
+			offset, err = r.pagination(*ast.Skip, q)
+			if err != nil {
+				return "", err
+			}
+		}
+		count, err := r.pagination(*limit, q)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(" OFFSET " + offset + " LIMIT " + count)
+	}
+
+	return sb.String(), nil
+}
+
+func (r *Renderer) renderCount(ast *types.DocumentAST, q *query) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("SELECT VALUE COUNT(1) FROM " + alias)
+	if err := r.writeWhere(&sb, ast.FilterClause, q); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (r *Renderer) renderDistinct(ast *types.DocumentAST, q *query) (string, error) {
+	if len(ast.SortClauses) > 0 || ast.Limit != nil {
+		return "", types.UnsupportedFeature(provider, "sort or limit on DISTINCT")
+	}
+	path, err := fieldPath(alias, ast.DistinctField.Path)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString("SELECT DISTINCT VALUE " + path + " FROM " + alias)
+	if err := r.writeWhere(&sb, ast.FilterClause, q); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (r *Renderer) writeWhere(sb *strings.Builder, f types.FilterItem, q *query) error {
+	if f == nil {
+		return nil
+	}
+	expr, err := r.buildFilter(f, alias, q)
+	if err != nil {
+		return err
+	}
+	if expr != "" {
+		sb.WriteString(" WHERE " + expr)
+	}
+	return nil
+}
+
+// buildSelectList renders the projection as a select list. Cosmos SQL can only
+// name the properties to return, so exclusions are rejected, apart from an
+// _id exclusion alongside included fields, which the select list already implies.
+func (r *Renderer) buildSelectList(p *types.Projection) (string, error) {
+	if p == nil {
+		return "*", nil
+	}
+	fields := make([]string, 0, len(p.Fields))
+	for _, f := range p.Fields {
+		if f.Slice != nil || f.ElemMatch != nil {
+			return "", types.UnsupportedFeature(provider, "array projection operators on field: "+f.Field.Path)
+		}
+		if !f.Include {
+			if f.Field.Path == types.IDField {
+				continue
+			}
+			return "", types.UnsupportedFeature(provider, "projection exclusions: "+f.Field.Path)
+		}
+		path, err := fieldPath(alias, f.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		if f.Alias != "" {
+			path += " AS " + f.Alias
+		}
+		fields = append(fields, path)
+	}
+	if len(fields) == 0 {
+		return "", types.UnsupportedFeature(provider, "projection exclusions: "+types.IDField)
+	}
+	return strings.Join(fields, ", "), nil
+}
+
+func (r *Renderer) pagination(p types.PaginationValue, q *query) (string, error) {
+	if p.Static != nil {
+		return strconv.Itoa(*p.Static), nil
+	}
+	if p.Param != nil {
+		return q.param(*p.Param)
+	}
+	return "", fmt.Errorf("pagination value has neither a static value nor a param")
+}
+
+func (r *Renderer) buildFilter(f types.FilterItem, root string, q *query) (string, error) {
+	switch filter := f.(type) {
+	case types.FilterCondition:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		ref, err := q.param(filter.Value)
+		if err != nil {
+			return "", err
+		}
+		switch filter.Operator {
+		case types.IN:
+			return fmt.Sprintf("ARRAY_CONTAINS(%s, %s)", ref, path), nil
+		case types.NotIn:
+			return fmt.Sprintf("NOT ARRAY_CONTAINS(%s, %s)", ref, path), nil
+		}
+		op := mapOperator(filter.Operator)
+		if op == "" {
+			return "", types.UnsupportedFilter(provider, string(filter.Operator))
+		}
+		return fmt.Sprintf("%s %s %s", path, op, ref), nil
+
+	case types.ValuesFilter:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		refs := make([]string, len(filter.Values))
+		for i, v := range filter.Values {
+			if refs[i], err = q.param(v); err != nil {
+				return "", err
+			}
+		}
+		op := "IN"
+		if filter.Operator == types.NotIn {
+			op = "NOT IN"
+		}
+		return fmt.Sprintf("%s %s (%s)", path, op, strings.Join(refs, ", ")), nil
+
+	case types.FilterGroup:
+		if len(filter.Conditions) == 0 {
+			return "", nil
+		}
+		exprs := make([]string, 0, len(filter.Conditions))
+		for _, c := range filter.Conditions {
+			expr, err := r.buildFilter(c, root, q)
+			if err != nil {
+				return "", err
+			}
+			if expr != "" {
+				exprs = append(exprs, "("+expr+")")
+			}
+		}
+		if len(exprs) == 0 {
+			return "", nil
+		}
+		switch filter.Logic {
+		case types.AND:
+			return strings.Join(exprs, " AND "), nil
+		case types.OR:
+			return strings.Join(exprs, " OR "), nil
+		case types.NOR:
+			return "NOT (" + strings.Join(exprs, " OR ") + ")", nil
+		case types.NOT:
+			return "NOT (" + strings.Join(exprs, " AND ") + ")", nil
+		default:
+			return "", types.UnsupportedFilter(provider, string(filter.Logic))
+		}
+
+	case types.RangeFilter:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		var parts []string
+		if filter.Min != nil {
+			ref, err := q.param(*filter.Min)
+			if err != nil {
+				return "", err
+			}
+			op := ">="
+			if filter.MinExclusive {
+				op = ">"
+			}
+			parts = append(parts, fmt.Sprintf("%s %s %s", path, op, ref))
+		}
+		if filter.Max != nil {
+			ref, err := q.param(*filter.Max)
+			if err != nil {
+				return "", err
+			}
+			op := "<="
+			if filter.MaxExclusive {
+				op = "<"
+			}
+			parts = append(parts, fmt.Sprintf("%s %s %s", path, op, ref))
+		}
+		if filter.Negated {
+			return "NOT (" + strings.Join(parts, " AND ") + ")", nil
+		}
+		return strings.Join(parts, " AND "), nil
+
+	case types.RegexFilter:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		pattern, err := q.param(filter.Pattern)
+		if err != nil {
+			return "", err
+		}
+		if filter.Options != nil {
+			opts, err := q.param(*filter.Options)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("RegexMatch(%s, %s, %s)", path, pattern, opts), nil
+		}
+		return fmt.Sprintf("RegexMatch(%s, %s)", path, pattern), nil
+
+	case types.ArrayFilter:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		if filter.Literal != nil && filter.Operator == types.Size {
+			return fmt.Sprintf("ARRAY_LENGTH(%s) = %d", path, *filter.Literal), nil
+		}
+		ref, err := q.param(filter.Value)
+		if err != nil {
+			return "", err
+		}
+		switch filter.Operator {
+		case types.Size:
+			return fmt.Sprintf("ARRAY_LENGTH(%s) = %s", path, ref), nil
+		default:
+			return "", types.UnsupportedFilter(provider, string(filter.Operator))
+		}
+
+	case types.ElemMatchFilter:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		// Element conditions use paths relative to the array element, which
+		// the subquery binds to its own alias.
+		elem := "e"
+		if root == elem {
+			elem = root + "e"
+		}
+		conds := make([]string, 0, len(filter.Conditions))
+		for _, c := range filter.Conditions {
+			expr, err := r.buildFilter(c, elem, q)
+			if err != nil {
+				return "", err
+			}
+			conds = append(conds, "("+expr+")")
+		}
+		return fmt.Sprintf("EXISTS(SELECT VALUE %s FROM %s IN %s WHERE %s)",
+			elem, elem, path, strings.Join(conds, " AND ")), nil
+
+	case types.ExistsFilter:
+		path, err := fieldPath(root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		if filter.Exists {
+			return fmt.Sprintf("IS_DEFINED(%s)", path), nil
+		}
+		return fmt.Sprintf("NOT IS_DEFINED(%s)", path), nil
+
+	default:
+		return "", types.UnsupportedFilter(provider, types.FilterName(f))
+	}
+}
+
+// fieldPath renders a dot-notation path against root, e.g. address.city
+// becomes c.address.city. Segments that collide with reserved words or start
+// with $ use bracket notation.
+func fieldPath(root, path string) (string, error) {
+	if !types.IsValidFieldPath(path) {
+		return "", &types.InvalidIdentifierError{Kind: "field path", Value: path}
+	}
+	var sb strings.Builder
+	sb.WriteString(root)
+	for _, seg := range strings.Split(path, ".") {
+		if strings.HasPrefix(seg, "$") || reservedWords[strings.ToLower(seg)] {
+			sb.WriteString(`["` + seg + `"]`)
+			continue
+		}
+		sb.WriteString("." + seg)
+	}
+	return sb.String(), nil
+}
+
+func mapOperator(op types.FilterOperator) string {
+	switch op {
+	case types.EQ:
+		return "="
+	case types.NE:
+		return "!="
+	case types.GT:
+		return ">"
+	case types.GTE:
+		return ">="
+	case types.LT:
+		return "<"
+	case types.LTE:
+		return "<="
+	default:
+		return ""
+	}
+}
+
+// SupportsOperation indicates if Cosmos DB SQL supports an operation.
+// Writes go through the Cosmos item APIs rather than SQL and are rejected.
+func (r *Renderer) SupportsOperation(op types.Operation) bool {
+	switch op {
+	case types.OpFind, types.OpFindOne, types.OpCount, types.OpDistinct:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsFilter indicates if Cosmos DB SQL supports a filter operator.
+func (r *Renderer) SupportsFilter(op types.FilterOperator) bool {
+	switch op {
+	case types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE,
+		types.IN, types.NotIn, types.Exists, types.Regex, types.Size, types.ElemMatch:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsUpdate indicates if Cosmos DB SQL supports an update operator.
+func (r *Renderer) SupportsUpdate(op types.UpdateOperator) bool {
+	return false
+}
+
+// SupportsPipelineStage indicates if Cosmos DB SQL supports a pipeline stage.
+func (r *Renderer) SupportsPipelineStage(stage string) bool {
+	return false
+}
+
+func toResult(container, sql string, q *query) (*types.QueryResult, error) {
+	out := map[string]interface{}{
+		"container": container,
+		"query":     sql,
+	}
+	if len(q.parameters) > 0 {
+		out["parameters"] = q.parameters
+	}
+	jsonBytes, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize query: %w", err)
+	}
+	return &types.QueryResult{
+		JSON:           string(jsonBytes),
+		RequiredParams: q.params,
+	}, nil
+}
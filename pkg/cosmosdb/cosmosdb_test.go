@@ -0,0 +1,456 @@
+package cosmosdb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"encoding/json"
+	"github.com/zoobzio/docql/internal/types"
+)
+
+type rendered struct {
+	Container  string              `json:"container"`
+	Query      string              `json:"query"`
+	Parameters []map[string]string `json:"parameters"`
+}
+
+func render(t *testing.T, ast *types.DocumentAST) (rendered, *types.QueryResult) {
+	t.Helper()
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out rendered
+	if err := json.Unmarshal([]byte(result.JSON), &out); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	return out, result
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestRenderFind(t *testing.T) {
+	out, result := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+	})
+
+	if out.Container != "users" {
+		t.Errorf("expected container users, got %s", out.Container)
+	}
+	if out.Query != "SELECT * FROM c" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+	if len(result.RequiredParams) != 0 {
+		t.Errorf("expected no params, got %v", result.RequiredParams)
+	}
+}
+
+func TestRenderFind_Full(t *testing.T) {
+	out, result := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{
+			Field:    types.Field{Path: "active"},
+			Operator: types.EQ,
+			Value:    types.Param{Name: "active"},
+		},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "username"}, Include: true},
+			{Field: types.Field{Path: "email"}, Include: true},
+		}},
+		SortClauses: []types.SortClause{{Field: types.Field{Path: "age"}, Order: types.Descending}},
+		Skip:        &types.PaginationValue{Static: intPtr(20)},
+		Limit:       &types.PaginationValue{Static: intPtr(10)},
+	})
+
+	expected := "SELECT c.username, c.email FROM c WHERE c.active = @active ORDER BY c.age DESC OFFSET 20 LIMIT 10"
+	if out.Query != expected {
+		t.Errorf("expected %q, got %q", expected, out.Query)
+	}
+	if len(result.RequiredParams) != 1 || result.RequiredParams[0] != "active" {
+		t.Errorf("expected [active], got %v", result.RequiredParams)
+	}
+	if len(out.Parameters) != 1 || out.Parameters[0]["name"] != "@active" || out.Parameters[0]["value"] != ":active" {
+		t.Errorf("unexpected parameters: %v", out.Parameters)
+	}
+}
+
+func TestRenderFind_LimitWithoutSkip(t *testing.T) {
+	out, _ := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Limit:     &types.PaginationValue{Param: &types.Param{Name: "limit"}},
+	})
+
+	if out.Query != "SELECT * FROM c OFFSET 0 LIMIT @limit" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+}
+
+func TestRenderFind_SkipWithoutLimit(t *testing.T) {
+	_, err := New().Render(&types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Skip:      &types.PaginationValue{Static: intPtr(5)},
+	})
+	if err == nil {
+		t.Error("expected error for skip without limit")
+	}
+}
+
+func TestRenderFindOne(t *testing.T) {
+	out, _ := render(t, &types.DocumentAST{
+		Operation: types.OpFindOne,
+		Target:    types.Collection{Name: "users"},
+	})
+	if out.Query != "SELECT TOP 1 * FROM c" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+
+	out, _ = render(t, &types.DocumentAST{
+		Operation: types.OpFindOne,
+		Target:    types.Collection{Name: "users"},
+		Skip:      &types.PaginationValue{Static: intPtr(3)},
+	})
+	if out.Query != "SELECT * FROM c OFFSET 3 LIMIT 1" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+}
+
+func TestRenderCount(t *testing.T) {
+	out, _ := render(t, &types.DocumentAST{
+		Operation: types.OpCount,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{
+			Field:    types.Field{Path: "status"},
+			Operator: types.NE,
+			Value:    types.Param{Name: "status"},
+		},
+	})
+	if out.Query != "SELECT VALUE COUNT(1) FROM c WHERE c.status != @status" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+}
+
+func TestRenderDistinct(t *testing.T) {
+	out, _ := render(t, &types.DocumentAST{
+		Operation:     types.OpDistinct,
+		Target:        types.Collection{Name: "users"},
+		DistinctField: &types.Field{Path: "address.city"},
+	})
+	if out.Query != "SELECT DISTINCT VALUE c.address.city FROM c" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+}
+
+func TestRenderFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   types.FilterItem
+		expected string
+	}{
+		{
+			name: "nested path",
+			filter: types.FilterCondition{
+				Field: types.Field{Path: "address.city"}, Operator: types.EQ, Value: types.Param{Name: "city"},
+			},
+			expected: "c.address.city = @city",
+		},
+		{
+			name: "reserved word segment",
+			filter: types.FilterCondition{
+				Field: types.Field{Path: "meta.value"}, Operator: types.GT, Value: types.Param{Name: "v"},
+			},
+			expected: `c.meta["value"] > @v`,
+		},
+		{
+			name: "in",
+			filter: types.FilterCondition{
+				Field: types.Field{Path: "status"}, Operator: types.IN, Value: types.Param{Name: "statuses"},
+			},
+			expected: "ARRAY_CONTAINS(@statuses, c.status)",
+		},
+		{
+			name: "not in",
+			filter: types.FilterCondition{
+				Field: types.Field{Path: "status"}, Operator: types.NotIn, Value: types.Param{Name: "statuses"},
+			},
+			expected: "NOT ARRAY_CONTAINS(@statuses, c.status)",
+		},
+		{
+			name: "group",
+			filter: types.FilterGroup{Logic: types.OR, Conditions: []types.FilterItem{
+				types.FilterCondition{Field: types.Field{Path: "a"}, Operator: types.EQ, Value: types.Param{Name: "a"}},
+				types.FilterGroup{Logic: types.NOT, Conditions: []types.FilterItem{
+					types.FilterCondition{Field: types.Field{Path: "b"}, Operator: types.LT, Value: types.Param{Name: "b"}},
+				}},
+			}},
+			expected: "(c.a = @a) OR (NOT ((c.b < @b)))",
+		},
+		{
+			name: "range",
+			filter: types.RangeFilter{
+				Field: types.Field{Path: "age"}, Min: &types.Param{Name: "min"}, Max: &types.Param{Name: "max"}, MaxExclusive: true,
+			},
+			expected: "c.age >= @min AND c.age < @max",
+		},
+		{
+			name:     "exists",
+			filter:   types.ExistsFilter{Field: types.Field{Path: "email"}, Exists: true},
+			expected: "IS_DEFINED(c.email)",
+		},
+		{
+			name:     "not exists",
+			filter:   types.ExistsFilter{Field: types.Field{Path: "email"}, Exists: false},
+			expected: "NOT IS_DEFINED(c.email)",
+		},
+		{
+			name:     "regex",
+			filter:   types.RegexFilter{Field: types.Field{Path: "name"}, Pattern: types.Param{Name: "pattern"}},
+			expected: "RegexMatch(c.name, @pattern)",
+		},
+		{
+			name:     "size",
+			filter:   types.ArrayFilter{Field: types.Field{Path: "tags"}, Operator: types.Size, Value: types.Param{Name: "n"}},
+			expected: "ARRAY_LENGTH(c.tags) = @n",
+		},
+		{
+			name: "elemMatch",
+			filter: types.ElemMatchFilter{Field: types.Field{Path: "items"}, Conditions: []types.FilterItem{
+				types.FilterCondition{Field: types.Field{Path: "qty"}, Operator: types.GT, Value: types.Param{Name: "qty"}},
+			}},
+			expected: "EXISTS(SELECT VALUE e FROM e IN c.items WHERE (e.qty > @qty))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _ := render(t, &types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: tt.filter,
+			})
+			expected := "SELECT * FROM c WHERE " + tt.expected
+			if out.Query != expected {
+				t.Errorf("expected %q, got %q", expected, out.Query)
+			}
+		})
+	}
+}
+
+func TestRender_DeduplicatesParams(t *testing.T) {
+	out, result := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{Logic: types.OR, Conditions: []types.FilterItem{
+			types.FilterCondition{Field: types.Field{Path: "owner"}, Operator: types.EQ, Value: types.Param{Name: "user"}},
+			types.FilterCondition{Field: types.Field{Path: "editor"}, Operator: types.EQ, Value: types.Param{Name: "user"}},
+		}},
+	})
+
+	if len(result.RequiredParams) != 1 {
+		t.Errorf("expected 1 required param, got %v", result.RequiredParams)
+	}
+	if len(out.Parameters) != 1 {
+		t.Errorf("expected 1 parameter, got %v", out.Parameters)
+	}
+}
+
+func TestRender_RejectsUnsafeIdentifiers(t *testing.T) {
+	tests := []struct {
+		name string
+		ast  *types.DocumentAST
+	}{
+		{
+			name: "field path",
+			ast: &types.DocumentAST{
+				Operation: types.OpFind,
+				Target:    types.Collection{Name: "users"},
+				FilterClause: types.FilterCondition{
+					Field: types.Field{Path: "a = 1 OR c.b"}, Operator: types.EQ, Value: types.Param{Name: "x"},
+				},
+			},
+		},
+		{
+			name: "param name",
+			ast: &types.DocumentAST{
+				Operation: types.OpFind,
+				Target:    types.Collection{Name: "users"},
+				FilterClause: types.FilterCondition{
+					Field: types.Field{Path: "a"}, Operator: types.EQ, Value: types.Param{Name: "x OR 1=1"},
+				},
+			},
+		},
+		{
+			name: "sort field",
+			ast: &types.DocumentAST{
+				Operation:   types.OpFind,
+				Target:      types.Collection{Name: "users"},
+				SortClauses: []types.SortClause{{Field: types.Field{Path: "age;--"}, Order: types.Ascending}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New().Render(tt.ast); err == nil {
+				t.Error("expected error for unsafe identifier")
+			}
+		})
+	}
+}
+
+func TestRender_ProjectionExclusion(t *testing.T) {
+	_, err := New().Render(&types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Exclude: true, Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "password"}, Include: false},
+		}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "exclusions") {
+		t.Errorf("expected exclusion error, got %v", err)
+	}
+
+	out, _ := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: types.IDField}, Include: false},
+			{Field: types.Field{Path: "email"}, Include: true},
+		}},
+	})
+	if out.Query != "SELECT c.email FROM c" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+}
+
+func TestRender_UnsupportedOperations(t *testing.T) {
+	renderer := New()
+	for _, op := range []types.Operation{
+		types.OpInsert, types.OpInsertMany, types.OpUpdate, types.OpUpdateMany,
+		types.OpDelete, types.OpDeleteMany, types.OpAggregate,
+	} {
+		if renderer.SupportsOperation(op) {
+			t.Errorf("expected %s to be unsupported", op)
+		}
+	}
+
+	_, err := renderer.Render(&types.DocumentAST{
+		Operation: types.OpDelete,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{
+			Field: types.Field{Path: "a"}, Operator: types.EQ, Value: types.Param{Name: "a"},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "does not support operation") {
+		t.Errorf("expected unsupported operation error, got %v", err)
+	}
+}
+
+func TestRender_UnsupportedFilter(t *testing.T) {
+	_, err := New().Render(&types.DocumentAST{
+		Operation:    types.OpFind,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.TextSearchFilter{Search: types.Param{Name: "q"}},
+	})
+	if err == nil {
+		t.Error("expected error for text search")
+	}
+}
+
+func TestRenderFind_InValues(t *testing.T) {
+	out, result := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+			types.ValuesFilter{Field: types.Field{Path: "status"}, Operator: types.IN, Values: []types.Param{{Name: "a"}, {Name: "b"}}},
+			types.ValuesFilter{Field: types.Field{Path: "role"}, Operator: types.NotIn, Values: []types.Param{{Name: "banned"}}},
+		}},
+	})
+	want := "SELECT * FROM c WHERE (c.status IN (@a, @b)) AND (c.role NOT IN (@banned))"
+	if out.Query != want {
+		t.Errorf("query = %q, want %q", out.Query, want)
+	}
+	if strings.Join(result.RequiredParams, ",") != "a,b,banned" {
+		t.Errorf("RequiredParams = %v", result.RequiredParams)
+	}
+}
+
+func TestRenderFind_ProjectionAliases(t *testing.T) {
+	out, result := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "username"}, Include: true},
+			{Field: types.Field{Path: "createdAt"}, Include: true, Alias: "created_at"},
+		}},
+	})
+	if out.Query != "SELECT c.username, c.createdAt AS created_at FROM c" {
+		t.Errorf("unexpected query: %s", out.Query)
+	}
+	if result.FieldAliases != nil {
+		t.Errorf("expected no FieldAliases, got %v", result.FieldAliases)
+	}
+}
+
+func TestRender_EmptyParams(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	email := types.Field{Path: "email"}
+	age := types.Field{Path: "age"}
+	byKey := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+
+	tests := []struct {
+		name    string
+		ast     *types.DocumentAST
+		context string
+	}{
+		{
+			name:    "filter",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.FilterCondition{Field: email, Operator: types.EQ}},
+			context: "$eq on field 'email'",
+		},
+		{
+			name:    "range bound",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.RangeFilter{Field: age, Min: &types.Param{}}},
+			context: "range on field 'age'",
+		},
+		{
+			name:    "document",
+			ast:     &types.DocumentAST{Operation: types.OpInsert, Target: users, Documents: []types.Document{{Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "document field 'email'",
+		},
+		{
+			name: "update",
+			ast: &types.DocumentAST{Operation: types.OpUpdate, Target: users, FilterClause: byKey,
+				UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "$set field 'email'",
+		},
+		{
+			name:    "pagination",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: byKey, Limit: &types.PaginationValue{Param: &types.Param{}}},
+			context: "limit",
+		},
+		{
+			name: "pipeline expression",
+			ast: &types.DocumentAST{Operation: types.OpAggregate, Target: users,
+				Pipeline: []types.PipelineStage{types.AddFieldsStage{Fields: map[string]types.Expression{"flag": types.LiteralExpression{}}}}},
+			context: "$addFields field 'flag'",
+		},
+	}
+
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Render(tt.ast)
+			if !errors.Is(err, types.ErrInvalidIdentifier) {
+				t.Fatalf("expected invalid identifier error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.context) {
+				t.Errorf("expected error naming %q, got %v", tt.context, err)
+			}
+		})
+	}
+}
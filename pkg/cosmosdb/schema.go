@@ -0,0 +1,32 @@
+package cosmosdb
+
+// OutputSchema returns a JSON Schema (draft 2020-12) describing the JSON that
+// Render produces: a SQL query for the named container with its parameters,
+// in the form the Cosmos DB SDKs accept.
+func OutputSchema() string {
+	return outputSchema
+}
+
+const outputSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "docql Cosmos DB query",
+  "type": "object",
+  "properties": {
+    "container": {"type": "string"},
+    "query": {"type": "string", "description": "Cosmos DB SQL over the document alias c."},
+    "parameters": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "description": "The @name the query refers to."},
+          "value": {"type": "string", "description": "The :param placeholder to bind."}
+        },
+        "required": ["name", "value"],
+        "additionalProperties": false
+      }
+    }
+  },
+  "required": ["container", "query"],
+  "additionalProperties": false
+}`
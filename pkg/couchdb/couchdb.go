@@ -4,60 +4,132 @@ package couchdb
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/zoobzio/docql/internal/types"
 )
 
 // Renderer renders DocumentAST to CouchDB Mango query format.
-type Renderer struct{}
+type Renderer struct {
+	// TypeField names a document attribute used as a collection discriminator
+	// (optional). When set, inserted documents carry the collection name in this
+	// field and every selector is scoped to it.
+	TypeField string
+
+	// KnownIndexes lists the fields of each Mango index on the database, in
+	// index order (optional). When set, a sort that no index can serve is
+	// rejected at render rather than by the server.
+	KnownIndexes [][]string
+}
+
+// provider names CouchDB in UnsupportedError values.
+const provider = "CouchDB"
 
 // New creates a new CouchDB renderer.
 func New() *Renderer {
 	return &Renderer{}
 }
 
+// WithTypeField sets the collection discriminator attribute name.
+func (r *Renderer) WithTypeField(field string) *Renderer {
+	r.TypeField = field
+	return r
+}
+
+// WithKnownIndexes sets the Mango indexes sorts are checked against.
+func (r *Renderer) WithKnownIndexes(indexes [][]string) *Renderer {
+	r.KnownIndexes = indexes
+	return r
+}
+
+// CacheKey identifies the renderer's options, so a registry caches its
+// results apart from those of differently configured renderers.
+func (r *Renderer) CacheKey() (string, bool) {
+	return fmt.Sprintf("%q %q", r.TypeField, r.KnownIndexes), true
+}
+
 // Render converts a DocumentAST to CouchDB Mango query format.
 func (r *Renderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
 	if err := ast.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid AST: %w", err)
+		return nil, &types.InvalidASTError{Err: err}
+	}
+
+	if ast.ParameterizedTarget() {
+		return nil, types.UnsupportedFeature(provider, "parameterized collection")
 	}
 
 	if !r.SupportsOperation(ast.Operation) {
-		return nil, fmt.Errorf("CouchDB does not support operation: %s", ast.Operation)
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
 	}
 
 	var params []string
+	var result *types.QueryResult
+	var err error
 
 	switch ast.Operation {
 	case types.OpFind, types.OpFindOne:
-		return r.renderFind(ast, &params)
+		result, err = r.renderFind(ast, &params)
 	case types.OpInsert:
-		return r.renderInsert(ast, &params)
+		result, err = r.renderInsert(ast, &params)
+	case types.OpInsertMany:
+		result, err = r.renderBulkInsert(ast, &params)
 	case types.OpUpdate:
-		return r.renderUpdate(ast, &params)
+		result, err = r.renderUpdate(ast, &params)
 	case types.OpDelete:
-		return r.renderDelete(ast, &params)
+		result, err = r.renderDelete(ast, &params)
+	case types.OpTransaction:
+		result, err = r.renderBulkDocs(ast)
 	default:
-		return nil, fmt.Errorf("unsupported operation: %s", ast.Operation)
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
+	}
+	if err != nil {
+		return nil, types.WithOperation(err, ast.Operation)
+	}
+	result.Operation = ast.Operation
+	result.Single = ast.Operation == types.OpFindOne
+	result.Collection = ast.Target.Name
+	result.Database = ast.Target.Database
+	return result, nil
+}
+
+// newQuery returns a query map naming the target CouchDB database and, when
+// the schema names one, the logical database it belongs to.
+func newQuery(ast *types.DocumentAST) map[string]interface{} {
+	query := map[string]interface{}{"db": ast.Target.Name}
+	if ast.Target.Database != "" {
+		query["database"] = ast.Target.Database
 	}
+	return query
 }
 
 func (r *Renderer) renderFind(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
+	query := newQuery(ast)
 
 	if ast.FilterClause != nil {
 		selector, err := r.buildSelector(ast.FilterClause, params)
 		if err != nil {
 			return nil, err
 		}
-		query["selector"] = selector
+		query["selector"] = r.scopeSelector(ast.Target.Name, selector)
 	} else {
-		query["selector"] = map[string]interface{}{}
+		query["selector"] = r.scopeSelector(ast.Target.Name, map[string]interface{}{})
 	}
 
 	if ast.Projection != nil {
+		if ast.Projection.Exclude || ast.Projection.ExcludesOnly() {
+			return nil, &types.UnsupportedError{
+				Provider: provider,
+				Features: []string{"projection exclusions: " + ast.Projection.ExcludedPaths()},
+				Reason:   "Mango fields can only list fields to include",
+			}
+		}
 		fields := make([]string, 0)
 		for _, f := range ast.Projection.Fields {
+			if f.Slice != nil || f.ElemMatch != nil {
+				return nil, types.UnsupportedFeature(provider, "array projection operators on field: "+f.Field.Path)
+			}
 			if f.Include {
 				fields = append(fields, f.Field.Path)
 			}
@@ -68,6 +140,9 @@ func (r *Renderer) renderFind(ast *types.DocumentAST, params *[]string) (*types.
 	}
 
 	if len(ast.SortClauses) > 0 {
+		if err := r.checkSortIndex(ast); err != nil {
+			return nil, err
+		}
 		sort := make([]map[string]string, len(ast.SortClauses))
 		for i, s := range ast.SortClauses {
 			direction := "asc"
@@ -81,7 +156,10 @@ func (r *Renderer) renderFind(ast *types.DocumentAST, params *[]string) (*types.
 		query["sort"] = sort
 	}
 
-	if ast.Limit != nil {
+	// FindOne returns at most one document, so its limit is fixed at 1.
+	if ast.Operation == types.OpFindOne {
+		query["limit"] = 1
+	} else if ast.Limit != nil {
 		if ast.Limit.Static != nil {
 			query["limit"] = *ast.Limit.Static
 		} else if ast.Limit.Param != nil {
@@ -99,41 +177,185 @@ func (r *Renderer) renderFind(ast *types.DocumentAST, params *[]string) (*types.
 		}
 	}
 
-	return toResult(query, *params)
+	var warnings []string
+	if ast.MaxTimeMS != nil {
+		// Mango has no per-query timeout; echo the value for the executor to enforce.
+		query["options"] = map[string]interface{}{
+			"maxTimeMS": *ast.MaxTimeMS,
+		}
+		warnings = append(warnings,
+			"CouchDB has no per-query timeout: enforce maxTimeMS client-side or tune the server r_timeout setting")
+	}
+	if _, ok := ast.SortOpts(); ok {
+		warnings = append(warnings, "CouchDB sorts by raw JSON collation: case-insensitive and numeric sort options ignored")
+	}
+
+	result, err := toResult(query, *params)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = warnings
+	if ast.Projection != nil {
+		result.FieldAliases = ast.Projection.Aliases()
+	}
+	return result, nil
+}
+
+// checkSortIndex reports a sort that none of the KnownIndexes can serve.
+// Mango sorts with an index whose fields, after any leading fields the
+// selector pins to one value, begin with the sort fields in order.
+func (r *Renderer) checkSortIndex(ast *types.DocumentAST) error {
+	if len(r.KnownIndexes) == 0 {
+		return nil
+	}
+	fields := make([]string, len(ast.SortClauses))
+	for i, s := range ast.SortClauses {
+		fields[i] = s.Field.Path
+	}
+	pinned := make(map[string]bool)
+	if r.TypeField != "" {
+		pinned[r.TypeField] = true
+	}
+	equalityFields(ast.FilterClause, pinned)
+
+	for _, index := range r.KnownIndexes {
+		rest := index
+		for len(rest) > 0 && pinned[rest[0]] && rest[0] != fields[0] {
+			rest = rest[1:]
+		}
+		if len(rest) >= len(fields) && slices.Equal(rest[:len(fields)], fields) {
+			return nil
+		}
+	}
+	return &types.UnsupportedError{
+		Provider: provider,
+		Features: []string{"sort on " + strings.Join(fields, ", ")},
+		Reason:   "no known index begins with the sort fields",
+	}
+}
+
+// equalityFields adds to pinned the fields f requires to equal a single
+// value: $eq conditions, alone or under AND.
+func equalityFields(f types.FilterItem, pinned map[string]bool) {
+	switch filter := f.(type) {
+	case types.FilterCondition:
+		if filter.Operator == types.EQ {
+			pinned[filter.Field.Path] = true
+		}
+	case types.FilterGroup:
+		if filter.Logic == types.AND {
+			for _, c := range filter.Conditions {
+				equalityFields(c, pinned)
+			}
+		}
+	}
 }
 
 func (r *Renderer) renderInsert(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
+	query := newQuery(ast)
 	query["operation"] = "insert"
 
 	if len(ast.Documents) > 0 {
-		doc := make(map[string]interface{})
-		for field, value := range ast.Documents[0].Fields {
-			*params = append(*params, value.Name)
-			doc[field.Path] = fmt.Sprintf(":%s", value.Name)
-		}
-		query["doc"] = doc
+		query["doc"] = r.renderDocument(ast.Target.Name, ast.Documents[0], params)
+	}
+
+	return toResult(query, *params)
+}
+
+func (r *Renderer) renderBulkInsert(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
+	query := newQuery(ast)
+	query["operation"] = "bulk_insert"
+
+	docs := make([]map[string]interface{}, len(ast.Documents))
+	for i, doc := range ast.Documents {
+		docs[i] = r.renderDocument(ast.Target.Name, doc, params)
 	}
+	query["docs"] = docs
 
 	return toResult(query, *params)
 }
 
+// renderBulkDocs renders a transaction as the ordered writes to submit through
+// _bulk_docs. Updates and deletes still need their selector resolved to
+// documents and revisions before they can join the request.
+func (r *Renderer) renderBulkDocs(ast *types.DocumentAST) (*types.QueryResult, error) {
+	results, params, warnings, err := types.RenderOperations(ast, r.Render)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := make([]json.RawMessage, len(results))
+	for i, result := range results {
+		operations[i] = json.RawMessage(result.JSON)
+	}
+	query := map[string]interface{}{
+		"operation":  "bulk_docs",
+		"operations": operations,
+	}
+
+	result, err := toResult(query, params)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = append(warnings, "CouchDB has no transactions: _bulk_docs applies each document independently and does not roll back on failure")
+	return result, nil
+}
+
+// renderDocument renders a document's fields, collecting params in field path order
+// so that RequiredParams is deterministic across renders.
+func (r *Renderer) renderDocument(collection string, doc types.Document, params *[]string) map[string]interface{} {
+	fields := make([]types.Field, 0, len(doc.Fields))
+	for field := range doc.Fields {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Path < fields[j].Path
+	})
+
+	result := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		value := doc.Fields[field]
+		*params = append(*params, value.Name)
+		result[field.Path] = fmt.Sprintf(":%s", value.Name)
+	}
+	if r.TypeField != "" {
+		result[r.TypeField] = collection
+	}
+	return result
+}
+
+// scopeSelector adds the type discriminator clause to a selector when TypeField is set.
+func (r *Renderer) scopeSelector(collection string, selector interface{}) interface{} {
+	if r.TypeField == "" {
+		return selector
+	}
+	clause := map[string]interface{}{
+		r.TypeField: map[string]interface{}{"$eq": collection},
+	}
+	if m, ok := selector.(map[string]interface{}); selector == nil || (ok && len(m) == 0) {
+		return clause
+	}
+	return map[string]interface{}{
+		"$and": []interface{}{clause, selector},
+	}
+}
+
 func (r *Renderer) renderUpdate(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
+	query := newQuery(ast)
 	query["operation"] = "update"
 
-	if ast.FilterClause != nil {
-		selector, err := r.buildSelector(ast.FilterClause, params)
-		if err != nil {
-			return nil, err
-		}
-		query["selector"] = selector
+	if err := r.setSelector(query, ast, params); err != nil {
+		return nil, err
 	}
 
 	updates := make(map[string]interface{})
 	for _, op := range ast.UpdateOps {
 		if op.Operator != types.Set {
-			return nil, fmt.Errorf("CouchDB only supports $set updates")
+			return nil, &types.UnsupportedError{
+				Provider: provider,
+				Features: []string{"update operator: " + string(op.Operator)},
+				Reason:   "only $set updates are supported",
+			}
 		}
 		for field, value := range op.Fields {
 			*params = append(*params, value.Name)
@@ -146,18 +368,31 @@ func (r *Renderer) renderUpdate(ast *types.DocumentAST, params *[]string) (*type
 }
 
 func (r *Renderer) renderDelete(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
+	query := newQuery(ast)
 	query["operation"] = "delete"
 
+	if err := r.setSelector(query, ast, params); err != nil {
+		return nil, err
+	}
+
+	return toResult(query, *params)
+}
+
+// setSelector renders the write selector, which is omitted when there is
+// neither a filter nor a type discriminator.
+func (r *Renderer) setSelector(query map[string]interface{}, ast *types.DocumentAST, params *[]string) error {
+	var selector interface{}
 	if ast.FilterClause != nil {
-		selector, err := r.buildSelector(ast.FilterClause, params)
+		rendered, err := r.buildSelector(ast.FilterClause, params)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		selector = rendered
+	}
+	if selector = r.scopeSelector(ast.Target.Name, selector); selector != nil {
 		query["selector"] = selector
 	}
-
-	return toResult(query, *params)
+	return nil
 }
 
 func (r *Renderer) buildSelector(f types.FilterItem, params *[]string) (interface{}, error) {
@@ -166,7 +401,7 @@ func (r *Renderer) buildSelector(f types.FilterItem, params *[]string) (interfac
 		*params = append(*params, filter.Value.Name)
 		op := mapOperator(filter.Operator)
 		if op == "" {
-			return nil, fmt.Errorf("CouchDB does not support filter operator: %s", filter.Operator)
+			return nil, types.UnsupportedFilter(provider, string(filter.Operator))
 		}
 		return map[string]interface{}{
 			filter.Field.Path: map[string]interface{}{
@@ -174,6 +409,18 @@ func (r *Renderer) buildSelector(f types.FilterItem, params *[]string) (interfac
 			},
 		}, nil
 
+	case types.ValuesFilter:
+		values := make([]string, len(filter.Values))
+		for i, v := range filter.Values {
+			*params = append(*params, v.Name)
+			values[i] = fmt.Sprintf(":%s", v.Name)
+		}
+		return map[string]interface{}{
+			filter.Field.Path: map[string]interface{}{
+				mapOperator(filter.Operator): values,
+			},
+		}, nil
+
 	case types.FilterGroup:
 		conditions := make([]interface{}, 0, len(filter.Conditions))
 		for _, c := range filter.Conditions {
@@ -189,6 +436,9 @@ func (r *Renderer) buildSelector(f types.FilterItem, params *[]string) (interfac
 		}, nil
 
 	case types.RangeFilter:
+		if filter.Negated {
+			return nil, types.UnsupportedFilter(provider, "negated range")
+		}
 		rangeSelector := make(map[string]interface{})
 		if filter.Min != nil {
 			*params = append(*params, filter.Min.Name)
@@ -218,6 +468,25 @@ func (r *Renderer) buildSelector(f types.FilterItem, params *[]string) (interfac
 			},
 		}, nil
 
+	case types.TypeFilter:
+		mangoType, ok := mangoTypes[filter.TypeName]
+		if !ok {
+			return nil, types.UnsupportedFilter(provider, fmt.Sprintf("$type %q", filter.TypeName))
+		}
+		return map[string]interface{}{
+			filter.Field.Path: map[string]interface{}{
+				"$type": mangoType,
+			},
+		}, nil
+
+	case types.ModFilter:
+		*params = append(*params, filter.Divisor.Name, filter.Remainder.Name)
+		return map[string]interface{}{
+			filter.Field.Path: map[string]interface{}{
+				"$mod": []string{fmt.Sprintf(":%s", filter.Divisor.Name), fmt.Sprintf(":%s", filter.Remainder.Name)},
+			},
+		}, nil
+
 	case types.ExistsFilter:
 		return map[string]interface{}{
 			filter.Field.Path: map[string]interface{}{
@@ -225,11 +494,33 @@ func (r *Renderer) buildSelector(f types.FilterItem, params *[]string) (interfac
 			},
 		}, nil
 
+	case types.NullFilter:
+		// Mango conditions never match a missing field, so both forms are
+		// already strict.
+		if filter.Negated {
+			return map[string]interface{}{filter.Field.Path: map[string]interface{}{"$ne": nil}}, nil
+		}
+		return map[string]interface{}{filter.Field.Path: nil}, nil
+
 	default:
-		return nil, fmt.Errorf("CouchDB does not support filter type: %T", f)
+		return nil, types.UnsupportedFilter(provider, types.FilterName(f))
 	}
 }
 
+// mangoTypes maps $type names to Mango's JSON types. Dates and ObjectIDs are
+// stored as strings in CouchDB, so they have no type of their own to match.
+var mangoTypes = map[string]string{
+	"string":  "string",
+	"int":     "number",
+	"long":    "number",
+	"double":  "number",
+	"decimal": "number",
+	"bool":    "boolean",
+	"array":   "array",
+	"object":  "object",
+	"null":    "null",
+}
+
 func mapOperator(op types.FilterOperator) string {
 	switch op {
 	case types.EQ:
@@ -273,7 +564,7 @@ func mapLogic(op types.LogicOperator) string {
 // SupportsOperation indicates if CouchDB supports an operation.
 func (r *Renderer) SupportsOperation(op types.Operation) bool {
 	switch op {
-	case types.OpFind, types.OpFindOne, types.OpInsert, types.OpUpdate, types.OpDelete:
+	case types.OpFind, types.OpFindOne, types.OpInsert, types.OpInsertMany, types.OpUpdate, types.OpDelete, types.OpTransaction:
 		return true
 	default:
 		return false
@@ -283,7 +574,7 @@ func (r *Renderer) SupportsOperation(op types.Operation) bool {
 // SupportsFilter indicates if CouchDB supports a filter operator.
 func (r *Renderer) SupportsFilter(op types.FilterOperator) bool {
 	switch op {
-	case types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE, types.IN, types.NotIn, types.Regex, types.Exists:
+	case types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE, types.IN, types.NotIn, types.Regex, types.Exists, types.Mod, types.Type:
 		return true
 	default:
 		return false
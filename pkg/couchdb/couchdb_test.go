@@ -1,9 +1,12 @@
 package couchdb
 
 import (
-	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 
+	"encoding/json"
 	"github.com/zoobzio/docql/internal/types"
 )
 
@@ -96,6 +99,62 @@ func TestRenderFind_WithSort(t *testing.T) {
 	}
 }
 
+func TestRenderFind_SortKnownIndexes(t *testing.T) {
+	sortBy := func(paths ...string) []types.SortClause {
+		clauses := make([]types.SortClause, len(paths))
+		for i, p := range paths {
+			clauses[i] = types.SortClause{Field: types.Field{Path: p}, Order: types.Ascending}
+		}
+		return clauses
+	}
+	statusEq := types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "status"}}
+	indexes := [][]string{{"createdAt"}, {"status", "age", "name"}}
+
+	tests := []struct {
+		name    string
+		sort    []types.SortClause
+		filter  types.FilterItem
+		r       *Renderer
+		covered bool
+	}{
+		{"single field index", sortBy("createdAt"), nil, New(), true},
+		{"index prefix", sortBy("status", "age"), nil, New(), true},
+		{"after pinned field", sortBy("age", "name"), statusEq, New(), true},
+		{"after type field", sortBy("createdAt"), nil, New().WithTypeField("type"), true},
+		{"unpinned leading field", sortBy("age"), nil, New(), false},
+		{"not a prefix", sortBy("status", "name"), nil, New(), false},
+		{"unindexed", sortBy("email"), nil, New(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: tt.filter,
+				SortClauses:  tt.sort,
+			}
+			known := indexes
+			if tt.r.TypeField != "" {
+				known = [][]string{{"type", "createdAt"}}
+			}
+			_, err := tt.r.WithKnownIndexes(known).Render(ast)
+			if tt.covered && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			var unsupported *types.UnsupportedError
+			if !tt.covered && (!errors.As(err, &unsupported) || !strings.HasPrefix(unsupported.Features[0], "sort on ")) {
+				t.Errorf("expected an uncovered sort error, got %v", err)
+			}
+		})
+	}
+
+	// Without known indexes every sort renders.
+	ast := &types.DocumentAST{Operation: types.OpFind, Target: types.Collection{Name: "users"}, SortClauses: sortBy("email")}
+	if _, err := New().Render(ast); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 func TestRenderFind_WithPagination(t *testing.T) {
 	limit := 10
 	skip := 20
@@ -197,6 +256,65 @@ func TestRenderInsert(t *testing.T) {
 	}
 }
 
+func TestRenderInsertMany(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpInsertMany,
+		Target:    types.Collection{Name: "users"},
+		Documents: []types.Document{
+			{
+				Fields: map[types.Field]types.Param{
+					{Path: "name"}:  {Name: "name1"},
+					{Path: "email"}: {Name: "email1"},
+				},
+			},
+			{
+				Fields: map[types.Field]types.Param{
+					{Path: "name"}:  {Name: "name2"},
+					{Path: "email"}: {Name: "email2"},
+				},
+			},
+			{
+				Fields: map[types.Field]types.Param{
+					{Path: "email"}: {Name: "email3"},
+				},
+			},
+		},
+	}
+
+	renderer := New()
+	result, err := renderer.Render(ast)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"email1", "name1", "email2", "name2", "email3"}
+	if len(result.RequiredParams) != len(expected) {
+		t.Fatalf("expected %d required params, got %d", len(expected), len(result.RequiredParams))
+	}
+	for i, p := range expected {
+		if result.RequiredParams[i] != p {
+			t.Errorf("expected param %d to be %s, got %s", i, p, result.RequiredParams[i])
+		}
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	if query["operation"] != "bulk_insert" {
+		t.Errorf("expected operation bulk_insert, got %v", query["operation"])
+	}
+	docs, ok := query["docs"].([]interface{})
+	if !ok {
+		t.Fatal("expected docs to be an array")
+	}
+	if len(docs) != 3 {
+		t.Errorf("expected 3 docs, got %d", len(docs))
+	}
+}
+
 func TestRenderUpdate(t *testing.T) {
 	ast := &types.DocumentAST{
 		Operation: types.OpUpdate,
@@ -280,7 +398,7 @@ func TestSupportsOperation(t *testing.T) {
 	renderer := New()
 
 	supported := []types.Operation{
-		types.OpFind, types.OpFindOne, types.OpInsert, types.OpUpdate, types.OpDelete,
+		types.OpFind, types.OpFindOne, types.OpInsert, types.OpInsertMany, types.OpUpdate, types.OpDelete,
 	}
 
 	for _, op := range supported {
@@ -304,7 +422,7 @@ func TestSupportsFilter(t *testing.T) {
 	renderer := New()
 
 	supported := []types.FilterOperator{
-		types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE, types.IN, types.NotIn, types.Regex, types.Exists,
+		types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE, types.IN, types.NotIn, types.Regex, types.Exists, types.Mod, types.Type,
 	}
 
 	for _, op := range supported {
@@ -411,6 +529,72 @@ func TestRenderFind_WithRegexFilter(t *testing.T) {
 	}
 }
 
+func TestRenderFind_WithModFilter(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.ModFilter{
+			Field:     types.Field{Path: "userId"},
+			Divisor:   types.Param{Name: "divisor"},
+			Remainder: types.Param{Name: "remainder"},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(result.JSON, `"selector":{"userId":{"$mod":[":divisor",":remainder"]}}`) {
+		t.Errorf("expected $mod selector in %s", result.JSON)
+	}
+	if len(result.RequiredParams) != 2 || result.RequiredParams[0] != "divisor" || result.RequiredParams[1] != "remainder" {
+		t.Errorf("unexpected params: %v", result.RequiredParams)
+	}
+}
+
+func TestRenderFind_WithTypeFilter(t *testing.T) {
+	tests := map[string]string{
+		"string":  "string",
+		"int":     "number",
+		"long":    "number",
+		"double":  "number",
+		"decimal": "number",
+		"bool":    "boolean",
+		"array":   "array",
+		"object":  "object",
+		"null":    "null",
+	}
+
+	for name, mango := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := New().Render(&types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: types.TypeFilter{Field: types.Field{Path: "value"}, TypeName: name},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := `"selector":{"value":{"$type":"` + mango + `"}}`
+			if !strings.Contains(result.JSON, want) {
+				t.Errorf("expected %s in %s", want, result.JSON)
+			}
+		})
+	}
+
+	for _, name := range []string{"date", "objectId"} {
+		_, err := New().Render(&types.DocumentAST{
+			Operation:    types.OpFind,
+			Target:       types.Collection{Name: "users"},
+			FilterClause: types.TypeFilter{Field: types.Field{Path: "value"}, TypeName: name},
+		})
+		if !errors.Is(err, types.ErrUnsupportedFilter) {
+			t.Errorf("%s: expected unsupported error, got %v", name, err)
+		}
+	}
+}
+
 func TestRenderFind_WithFilterGroup(t *testing.T) {
 	ast := &types.DocumentAST{
 		Operation: types.OpFind,
@@ -448,3 +632,467 @@ func TestRenderFind_WithFilterGroup(t *testing.T) {
 		t.Error("expected $and in selector")
 	}
 }
+
+func TestRenderFind_WithMaxTimeMS(t *testing.T) {
+	maxTime := 3000
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		MaxTimeMS: &maxTime,
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", result.Warnings)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	options, ok := query["options"].(map[string]interface{})
+	if !ok {
+		t.Fatal("expected options to be a map")
+	}
+	if options["maxTimeMS"] != float64(3000) {
+		t.Errorf("expected options.maxTimeMS 3000, got %v", options["maxTimeMS"])
+	}
+}
+
+func TestRender_EmitsDatabase(t *testing.T) {
+	asts := []*types.DocumentAST{
+		{Operation: types.OpFind, Target: types.Collection{Name: "users"}},
+		{
+			Operation: types.OpInsert,
+			Target:    types.Collection{Name: "users"},
+			Documents: []types.Document{{Fields: map[types.Field]types.Param{{Path: "email"}: {Name: "email"}}}},
+		},
+		{
+			Operation: types.OpUpdate,
+			Target:    types.Collection{Name: "users"},
+			UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{{Path: "status"}: {Name: "status"}}}},
+		},
+		{Operation: types.OpDelete, Target: types.Collection{Name: "users"}},
+	}
+
+	for _, ast := range asts {
+		result, err := New().Render(ast)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", ast.Operation, err)
+		}
+
+		var query map[string]interface{}
+		if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		if query["db"] != "users" {
+			t.Errorf("expected db users for %s, got %v", ast.Operation, query["db"])
+		}
+	}
+}
+
+func TestRender_WithTypeField(t *testing.T) {
+	renderer := New().WithTypeField("type")
+
+	find := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{
+			Field:    types.Field{Path: "status"},
+			Operator: types.EQ,
+			Value:    types.Param{Name: "status"},
+		},
+	}
+	result, err := renderer.Render(find)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	selector := query["selector"].(map[string]interface{})
+	and, ok := selector["$and"].([]interface{})
+	if !ok || len(and) != 2 {
+		t.Fatalf("expected $and with type clause, got %v", selector)
+	}
+	typeClause := and[0].(map[string]interface{})["type"].(map[string]interface{})
+	if typeClause["$eq"] != "users" {
+		t.Errorf("expected type $eq users, got %v", typeClause)
+	}
+
+	del := &types.DocumentAST{Operation: types.OpDelete, Target: types.Collection{Name: "users"}}
+	result, err = renderer.Render(del)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	query = nil
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	selector = query["selector"].(map[string]interface{})
+	if selector["type"] == nil {
+		t.Errorf("expected bare type selector for unfiltered delete, got %v", selector)
+	}
+
+	insert := &types.DocumentAST{
+		Operation: types.OpInsert,
+		Target:    types.Collection{Name: "users"},
+		Documents: []types.Document{{Fields: map[types.Field]types.Param{{Path: "email"}: {Name: "email"}}}},
+	}
+	result, err = renderer.Render(insert)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	query = nil
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	doc := query["doc"].(map[string]interface{})
+	if doc["type"] != "users" {
+		t.Errorf("expected doc type users, got %v", doc["type"])
+	}
+	if len(result.RequiredParams) != 1 {
+		t.Errorf("expected type field to add no params, got %v", result.RequiredParams)
+	}
+}
+
+func TestRender_WithoutTypeField(t *testing.T) {
+	del := &types.DocumentAST{Operation: types.OpDelete, Target: types.Collection{Name: "users"}}
+	result, err := New().Render(del)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if _, ok := query["selector"]; ok {
+		t.Error("expected no selector for unfiltered delete without type field")
+	}
+}
+
+func TestRenderFind_InValues(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.ValuesFilter{
+			Field:    types.Field{Path: "status"},
+			Operator: types.IN,
+			Values:   []types.Param{{Name: "a"}, {Name: "b"}},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query struct {
+		Selector map[string]map[string][]string `json:"selector"`
+	}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	in := query.Selector["status"]["$in"]
+	if len(in) != 2 || in[0] != ":a" || in[1] != ":b" {
+		t.Errorf("expected $in [:a :b], got %v", in)
+	}
+	if len(result.RequiredParams) != 2 || result.RequiredParams[0] != "a" || result.RequiredParams[1] != "b" {
+		t.Errorf("expected params [a b], got %v", result.RequiredParams)
+	}
+}
+
+func TestRenderFind_ProjectionAliases(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "name"}, Include: true},
+			{Field: types.Field{Path: "createdAt"}, Include: true, Alias: "created_at"},
+		}},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.FieldAliases) != 1 || result.FieldAliases["createdAt"] != "created_at" {
+		t.Errorf("expected createdAt -> created_at alias, got %v", result.FieldAliases)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	fields, ok := query["fields"].([]interface{})
+	if !ok || len(fields) != 2 || fields[0] != "name" || fields[1] != "createdAt" {
+		t.Errorf("expected source fields [name createdAt], got %v", query["fields"])
+	}
+}
+
+func TestRenderFind_RejectsExcludeProjection(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Exclude: true, Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "password"}},
+			{Field: types.Field{Path: "token"}},
+		}},
+	}
+
+	_, err := New().Render(ast)
+	var unsupported *types.UnsupportedError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("expected *UnsupportedError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "projection exclusions: password, token") {
+		t.Errorf("error should name the excluded fields, got %q", err.Error())
+	}
+}
+
+func TestRenderFind_RejectsIDOnlyExclusion(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: types.IDField}, Include: false},
+		}},
+	}
+
+	_, err := New().Render(ast)
+	var unsupported *types.UnsupportedError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("expected *UnsupportedError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "projection exclusions: _id") {
+		t.Errorf("error should name the excluded field, got %q", err.Error())
+	}
+}
+
+func TestRenderFind_ProjectionWithoutID(t *testing.T) {
+	// Mango returns only the listed fields, so leaving _id out of fields
+	// excludes it.
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: types.IDField}, Include: false},
+			{Field: types.Field{Path: "email"}, Include: true},
+		}},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if fields, _ := query["fields"].([]interface{}); len(fields) != 1 || fields[0] != "email" {
+		t.Errorf("expected fields [email], got %v", query["fields"])
+	}
+}
+
+func TestRenderFind_RejectsArrayProjectionOperators(t *testing.T) {
+	tests := []struct {
+		name  string
+		field types.ProjectionField
+	}{
+		{
+			name: "slice",
+			field: types.ProjectionField{
+				Field:   types.Field{Path: "comments"},
+				Include: true,
+				Slice:   &types.SliceOp{Count: types.Param{Name: "n"}},
+			},
+		},
+		{
+			name: "elemMatch",
+			field: types.ProjectionField{
+				Field:   types.Field{Path: "scores"},
+				Include: true,
+				ElemMatch: &types.ElemMatchProjection{Conditions: []types.FilterItem{
+					types.FilterCondition{Field: types.Field{Path: "value"}, Operator: types.GT, Value: types.Param{Name: "min"}},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:  types.OpFind,
+				Target:     types.Collection{Name: "users"},
+				Projection: &types.Projection{Fields: []types.ProjectionField{tt.field}},
+			}
+
+			_, err := New().Render(ast)
+			if !errors.Is(err, types.ErrUnsupportedOperation) {
+				t.Fatalf("expected unsupported error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "array projection operators on field: "+tt.field.Field.Path) {
+				t.Errorf("error should name the field, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestRenderTransaction(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpTransaction,
+		Operations: []*types.DocumentAST{
+			{
+				Operation: types.OpInsert,
+				Target:    types.Collection{Name: "orders"},
+				Documents: []types.Document{{Fields: map[types.Field]types.Param{{Path: "userId"}: {Name: "id"}}}},
+			},
+			{
+				Operation:    types.OpDelete,
+				Target:       types.Collection{Name: "carts"},
+				FilterClause: types.FilterCondition{Field: types.Field{Path: "userId"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+			},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"operation":"bulk_docs","operations":[` +
+		`{"db":"orders","doc":{"userId":":id"},"operation":"insert"},` +
+		`{"db":"carts","operation":"delete","selector":{"userId":{"$eq":":id"}}}]}`
+	if result.JSON != expected {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, expected)
+	}
+	if len(result.RequiredParams) != 1 || result.RequiredParams[0] != "id" {
+		t.Errorf("expected deduplicated params [id], got %v", result.RequiredParams)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "does not roll back") {
+		t.Errorf("expected non-atomicity warning, got %v", result.Warnings)
+	}
+}
+
+func TestRenderFindOne_LimitsToOne(t *testing.T) {
+	for op, want := range map[types.Operation]interface{}{
+		types.OpFindOne: float64(1),
+		types.OpFind:    nil,
+	} {
+		ast := &types.DocumentAST{
+			Operation: op,
+			Target:    types.Collection{Name: "users"},
+		}
+
+		result, err := New().Render(ast)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", op, err)
+		}
+		if result.Single != (op == types.OpFindOne) {
+			t.Errorf("%s: unexpected Single %v", op, result.Single)
+		}
+
+		var query map[string]interface{}
+		if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+			t.Fatalf("%s: failed to parse JSON: %v", op, err)
+		}
+		if query["limit"] != want {
+			t.Errorf("%s: expected limit %v, got %v", op, want, query["limit"])
+		}
+	}
+}
+
+func TestRender_EmptyParams(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	email := types.Field{Path: "email"}
+	age := types.Field{Path: "age"}
+	byKey := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+
+	tests := []struct {
+		name    string
+		ast     *types.DocumentAST
+		context string
+	}{
+		{
+			name:    "filter",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.FilterCondition{Field: email, Operator: types.EQ}},
+			context: "$eq on field 'email'",
+		},
+		{
+			name:    "range bound",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.RangeFilter{Field: age, Min: &types.Param{}}},
+			context: "range on field 'age'",
+		},
+		{
+			name:    "document",
+			ast:     &types.DocumentAST{Operation: types.OpInsert, Target: users, Documents: []types.Document{{Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "document field 'email'",
+		},
+		{
+			name: "update",
+			ast: &types.DocumentAST{Operation: types.OpUpdate, Target: users, FilterClause: byKey,
+				UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "$set field 'email'",
+		},
+		{
+			name:    "pagination",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: byKey, Limit: &types.PaginationValue{Param: &types.Param{}}},
+			context: "limit",
+		},
+		{
+			name: "pipeline expression",
+			ast: &types.DocumentAST{Operation: types.OpAggregate, Target: users,
+				Pipeline: []types.PipelineStage{types.AddFieldsStage{Fields: map[string]types.Expression{"flag": types.LiteralExpression{}}}}},
+			context: "$addFields field 'flag'",
+		},
+	}
+
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Render(tt.ast)
+			if !errors.Is(err, types.ErrInvalidIdentifier) {
+				t.Fatalf("expected invalid identifier error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.context) {
+				t.Errorf("expected error naming %q, got %v", tt.context, err)
+			}
+		})
+	}
+}
+
+func TestRender_ValuelessUpdateOperators(t *testing.T) {
+	r := New()
+	for _, op := range []types.UpdateOperator{types.Unset, types.CurrentDate} {
+		t.Run(string(op), func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:    types.OpUpdate,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+				UpdateOps:    []types.UpdateOperation{{Operator: op, Fields: map[types.Field]types.Param{{Path: "deletedAt"}: {}}}},
+			}
+			result, err := r.Render(ast)
+			if !r.SupportsUpdate(op) {
+				var unsupported *types.UnsupportedError
+				if !errors.As(err, &unsupported) {
+					t.Errorf("expected unsupported operator error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if slices.Contains(result.RequiredParams, "") {
+				t.Errorf("unexpected empty param in %v", result.RequiredParams)
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+// Package exec runs rendered CouchDB queries over the CouchDB HTTP API.
+//
+// Finds are posted to _find. Inserts, updates, deletes, and transactions are
+// written through _bulk_docs; updates and deletes first resolve their
+// selector to a document and revision with _find.
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// Executor runs queries rendered by the CouchDB renderer against a server.
+type Executor struct {
+	baseURL  string
+	client   *http.Client
+	username string
+	password string
+}
+
+// New creates an Executor for the server at baseURL, such as
+// "http://localhost:5984".
+func New(baseURL string) *Executor {
+	return &Executor{baseURL: strings.TrimRight(baseURL, "/"), client: http.DefaultClient}
+}
+
+// WithClient sets the HTTP client requests are sent with.
+func (e *Executor) WithClient(client *http.Client) *Executor {
+	e.client = client
+	return e
+}
+
+// WithBasicAuth sets the credentials sent with each request.
+func (e *Executor) WithBasicAuth(username, password string) *Executor {
+	e.username = username
+	e.password = password
+	return e
+}
+
+// Result holds what a query returned. Only the fields the operation produces
+// are set.
+type Result struct {
+	// Documents holds the documents a FIND or FIND_ONE returned.
+	Documents []map[string]interface{}
+
+	// Bookmark continues a FIND from where it stopped.
+	Bookmark string
+
+	// Warning is the warning CouchDB returned with a FIND, such as a
+	// missing index.
+	Warning string
+
+	// InsertedIDs holds the _id of each inserted document.
+	InsertedIDs []string
+
+	ModifiedCount int64
+	DeletedCount  int64
+}
+
+// Run substitutes params into result and executes it. Every required param
+// must be given. An UPDATE or DELETE applies to the first document its
+// selector matches; the document is read and written back in two requests,
+// so a concurrent write between them fails with a *ConflictError, and the
+// caller can retry the query. A TRANSACTION is submitted with one _bulk_docs request
+// per database, which CouchDB does not apply atomically: when some writes
+// fail, Run returns the counts of those that succeeded with an error naming
+// the rest.
+func (e *Executor) Run(ctx context.Context, result *types.QueryResult, params map[string]interface{}) (*Result, error) {
+	if result == nil {
+		return nil, errors.New("nil query result")
+	}
+	query, err := bind(result, params)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{}
+	if result.Operation == types.OpFind || result.Operation == types.OpFindOne {
+		return res, e.find(ctx, query, res)
+	}
+	var writes []write
+	if result.Operation == types.OpTransaction {
+		operations, _ := query["operations"].([]interface{})
+		for i, op := range operations {
+			sub, ok := op.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("operation %d: not an object", i)
+			}
+			w, err := e.resolve(ctx, sub)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			writes = append(writes, w...)
+		}
+	} else if writes, err = e.resolve(ctx, query); err != nil {
+		return nil, err
+	}
+	return res, e.bulk(ctx, writes, res)
+}
+
+// bind decodes result's JSON and replaces each placeholder for a required
+// param with its value. It fails if a required param is missing.
+func bind(result *types.QueryResult, params map[string]interface{}) (map[string]interface{}, error) {
+	var missing []string
+	required := make(map[string]bool, len(result.RequiredParams))
+	for _, name := range result.RequiredParams {
+		if _, ok := params[name]; !ok {
+			missing = append(missing, name)
+		}
+		required[name] = true
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing params: %s", strings.Join(missing, ", "))
+	}
+
+	dec := json.NewDecoder(strings.NewReader(result.JSON))
+	dec.UseNumber()
+	var query map[string]interface{}
+	if err := dec.Decode(&query); err != nil {
+		return nil, fmt.Errorf("invalid query JSON: %w", err)
+	}
+	substitute(query, required, params)
+	return query, nil
+}
+
+// substitute returns v with every string ":name" naming a required param
+// replaced by the param's value.
+func substitute(v interface{}, required map[string]bool, params map[string]interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		if name, ok := strings.CutPrefix(value, ":"); ok && required[name] {
+			return params[name]
+		}
+	case map[string]interface{}:
+		for k, sub := range value {
+			value[k] = substitute(sub, required, params)
+		}
+	case []interface{}:
+		for i, sub := range value {
+			value[i] = substitute(sub, required, params)
+		}
+	}
+	return v
+}
+
+// findResponse is the body _find returns.
+type findResponse struct {
+	Docs     []map[string]interface{} `json:"docs"`
+	Bookmark string                   `json:"bookmark"`
+	Warning  string                   `json:"warning"`
+}
+
+func (e *Executor) find(ctx context.Context, query map[string]interface{}, res *Result) error {
+	db, body := findBody(query)
+	if options, ok := query["options"].(map[string]interface{}); ok {
+		if n, ok := options["maxTimeMS"].(json.Number); ok {
+			ms, err := n.Int64()
+			if err != nil {
+				return fmt.Errorf("maxTimeMS: %w", err)
+			}
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+			defer cancel()
+		}
+	}
+
+	var out findResponse
+	if err := e.post(ctx, db, "_find", body, &out); err != nil {
+		return err
+	}
+	res.Documents = out.Docs
+	res.Bookmark = out.Bookmark
+	res.Warning = out.Warning
+	return nil
+}
+
+// findBody splits a rendered find into its database and the _find request
+// body, dropping the keys only the executor reads.
+func findBody(query map[string]interface{}) (string, map[string]interface{}) {
+	db, _ := query["db"].(string)
+	body := make(map[string]interface{}, len(query))
+	for k, v := range query {
+		switch k {
+		case "db", "database", "operation", "options", "updates":
+		default:
+			body[k] = v
+		}
+	}
+	return db, body
+}
+
+// write is one document to submit through _bulk_docs.
+type write struct {
+	db   string
+	kind string
+	doc  map[string]interface{}
+}
+
+// resolve turns a rendered write into the documents to submit. An update or
+// delete that matches nothing resolves to no documents.
+func (e *Executor) resolve(ctx context.Context, query map[string]interface{}) ([]write, error) {
+	db, _ := query["db"].(string)
+	op, _ := query["operation"].(string)
+	switch op {
+	case "insert":
+		doc, _ := query["doc"].(map[string]interface{})
+		return []write{{db: db, kind: op, doc: doc}}, nil
+	case "bulk_insert":
+		docs, _ := query["docs"].([]interface{})
+		writes := make([]write, 0, len(docs))
+		for _, d := range docs {
+			doc, _ := d.(map[string]interface{})
+			writes = append(writes, write{db: db, kind: "insert", doc: doc})
+		}
+		return writes, nil
+	case "update", "delete":
+		_, body := findBody(query)
+		if body["selector"] == nil {
+			body["selector"] = map[string]interface{}{}
+		}
+		body["limit"] = 1
+		var out findResponse
+		if err := e.post(ctx, db, "_find", body, &out); err != nil {
+			return nil, err
+		}
+		if len(out.Docs) == 0 {
+			return nil, nil
+		}
+		doc := out.Docs[0]
+		if op == "delete" {
+			doc = map[string]interface{}{"_id": doc["_id"], "_rev": doc["_rev"], "_deleted": true}
+		} else {
+			updates, _ := query["updates"].(map[string]interface{})
+			for path, value := range updates {
+				setPath(doc, path, value)
+			}
+		}
+		return []write{{db: db, kind: op, doc: doc}}, nil
+	}
+	return nil, fmt.Errorf("operation %q cannot be executed", op)
+}
+
+// setPath sets the dotted path in doc to value, creating objects along the
+// way.
+func setPath(doc map[string]interface{}, path string, value interface{}) {
+	parts := strings.Split(path, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := doc[part].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			doc[part] = next
+		}
+		doc = next
+	}
+	doc[parts[len(parts)-1]] = value
+}
+
+// ConflictError reports a write CouchDB rejected because the document's
+// revision changed, or, for an insert, because the _id already exists.
+// Retrying the query resolves the current revision again.
+type ConflictError struct {
+	// Kind is the write that conflicted: insert, update, or delete.
+	Kind string
+
+	// ID is the _id of the conflicting document.
+	ID string
+
+	// Reason is the reason CouchDB gave.
+	Reason string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %s: conflict: %s", e.Kind, e.ID, e.Reason)
+}
+
+// bulkStatus is one entry of the body _bulk_docs returns.
+type bulkStatus struct {
+	ID     string `json:"id"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error"`
+	Reason string `json:"reason"`
+}
+
+// bulk submits writes with one _bulk_docs request per database, in the order
+// each database first appears, counting the writes that succeed.
+func (e *Executor) bulk(ctx context.Context, writes []write, res *Result) error {
+	var order []string
+	byDB := make(map[string][]write)
+	for _, w := range writes {
+		if _, ok := byDB[w.db]; !ok {
+			order = append(order, w.db)
+		}
+		byDB[w.db] = append(byDB[w.db], w)
+	}
+
+	var errs []error
+	for _, db := range order {
+		batch := byDB[db]
+		docs := make([]map[string]interface{}, len(batch))
+		for i, w := range batch {
+			docs[i] = w.doc
+		}
+		var statuses []bulkStatus
+		if err := e.post(ctx, db, "_bulk_docs", map[string]interface{}{"docs": docs}, &statuses); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for i, status := range statuses {
+			if i >= len(batch) {
+				break
+			}
+			if status.Error == "conflict" {
+				errs = append(errs, &ConflictError{Kind: batch[i].kind, ID: status.ID, Reason: status.Reason})
+				continue
+			}
+			if !status.OK {
+				errs = append(errs, fmt.Errorf("%s %s: %s: %s", batch[i].kind, status.ID, status.Error, status.Reason))
+				continue
+			}
+			switch batch[i].kind {
+			case "insert":
+				res.InsertedIDs = append(res.InsertedIDs, status.ID)
+			case "update":
+				res.ModifiedCount++
+			case "delete":
+				res.DeletedCount++
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// post sends body as JSON to the endpoint of db and decodes the response
+// into out.
+func (e *Executor) post(ctx context.Context, db, endpoint string, body, out interface{}) error {
+	if db == "" {
+		return errors.New("no database")
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/"+url.PathEscape(db)+"/"+endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.username != "" {
+		req.SetBasicAuth(e.username, e.password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Error  string `json:"error"`
+			Reason string `json:"reason"`
+		}
+		_ = json.Unmarshal(respBody, &failure)
+		return fmt.Errorf("%s %s: %s: %s: %s", db, endpoint, resp.Status, failure.Error, failure.Reason)
+	}
+	return json.Unmarshal(respBody, out)
+}
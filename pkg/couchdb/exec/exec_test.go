@@ -0,0 +1,216 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zoobzio/docql/internal/types"
+	"github.com/zoobzio/docql/pkg/couchdb"
+)
+
+// fakeCouch serves _find and _bulk_docs for one database from an in-memory
+// document set, recording each request body.
+type fakeCouch struct {
+	docs     []map[string]interface{}
+	requests []string
+	bodies   []map[string]interface{}
+}
+
+func (f *fakeCouch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	f.bodies = append(f.bodies, body)
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/_find"):
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"docs": f.docs, "bookmark": "b1"})
+	case strings.HasSuffix(r.URL.Path, "/_bulk_docs"):
+		docs, _ := body["docs"].([]interface{})
+		statuses := make([]map[string]interface{}, len(docs))
+		for i, d := range docs {
+			id, _ := d.(map[string]interface{})["_id"].(string)
+			if id == "" {
+				id = "generated"
+			}
+			statuses[i] = map[string]interface{}{"ok": true, "id": id, "rev": "2-x"}
+			if id == "conflict" {
+				statuses[i] = map[string]interface{}{"id": id, "error": "conflict", "reason": "Document update conflict."}
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(statuses)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "not_found", "reason": "missing"})
+	}
+}
+
+func render(t *testing.T, ast *types.DocumentAST) *types.QueryResult {
+	t.Helper()
+	result, err := couchdb.New().Render(ast)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	return result
+}
+
+func TestRun_Find(t *testing.T) {
+	fake := &fakeCouch{docs: []map[string]interface{}{{"_id": "1", "status": "active"}}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	result := render(t, &types.DocumentAST{
+		Operation:    types.OpFind,
+		Target:       types.Collection{Name: "users", Database: "app"},
+		FilterClause: types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "status"}},
+		Limit:        &types.PaginationValue{Param: &types.Param{Name: "n"}},
+	})
+	res, err := New(server.URL).Run(context.Background(), result, map[string]interface{}{"status": "active", "n": 5})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(res.Documents) != 1 || res.Bookmark != "b1" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if got := fake.requests; !reflect.DeepEqual(got, []string{"POST /users/_find"}) {
+		t.Errorf("unexpected requests: %v", got)
+	}
+	want := map[string]interface{}{
+		"selector": map[string]interface{}{"status": map[string]interface{}{"$eq": "active"}},
+		"limit":    float64(5),
+	}
+	if !reflect.DeepEqual(fake.bodies[0], want) {
+		t.Errorf("unexpected _find body:\n got: %v\nwant: %v", fake.bodies[0], want)
+	}
+}
+
+func TestRun_Update(t *testing.T) {
+	fake := &fakeCouch{docs: []map[string]interface{}{{"_id": "1", "_rev": "1-a", "status": "active"}}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	result := render(t, &types.DocumentAST{
+		Operation:    types.OpUpdate,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+		UpdateOps: []types.UpdateOperation{{
+			Operator: types.Set,
+			Fields:   map[types.Field]types.Param{{Path: "address.city"}: {Name: "city"}},
+		}},
+	})
+	res, err := New(server.URL).Run(context.Background(), result, map[string]interface{}{"id": "1", "city": "Oslo"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.ModifiedCount != 1 {
+		t.Errorf("expected 1 modified, got %d", res.ModifiedCount)
+	}
+	if got := fake.requests; !reflect.DeepEqual(got, []string{"POST /users/_find", "POST /users/_bulk_docs"}) {
+		t.Errorf("unexpected requests: %v", got)
+	}
+	if limit := fake.bodies[0]["limit"]; limit != float64(1) {
+		t.Errorf("expected the update to resolve one document, got limit %v", limit)
+	}
+	doc := fake.bodies[1]["docs"].([]interface{})[0].(map[string]interface{})
+	want := map[string]interface{}{"_id": "1", "_rev": "1-a", "status": "active", "address": map[string]interface{}{"city": "Oslo"}}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("unexpected updated document:\n got: %v\nwant: %v", doc, want)
+	}
+}
+
+func TestRun_DeleteNoMatch(t *testing.T) {
+	fake := &fakeCouch{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	result := render(t, &types.DocumentAST{
+		Operation:    types.OpDelete,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+	})
+	res, err := New(server.URL).Run(context.Background(), result, map[string]interface{}{"id": "9"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.DeletedCount != 0 || len(fake.requests) != 1 {
+		t.Errorf("expected one _find and nothing deleted, got %+v after %v", res, fake.requests)
+	}
+}
+
+func TestRun_InsertConflict(t *testing.T) {
+	fake := &fakeCouch{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	result := render(t, &types.DocumentAST{
+		Operation: types.OpInsertMany,
+		Target:    types.Collection{Name: "users"},
+		Documents: []types.Document{
+			{Fields: map[types.Field]types.Param{{Path: "_id"}: {Name: "a"}}},
+			{Fields: map[types.Field]types.Param{{Path: "_id"}: {Name: "b"}}},
+		},
+	})
+	res, err := New(server.URL).Run(context.Background(), result, map[string]interface{}{"a": "1", "b": "conflict"})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Kind != "insert" || conflict.ID != "conflict" {
+		t.Errorf("expected an insert ConflictError, got %v", err)
+	}
+	if !reflect.DeepEqual(res.InsertedIDs, []string{"1"}) {
+		t.Errorf("expected the first insert to be counted, got %v", res.InsertedIDs)
+	}
+}
+
+func TestRun_UpdateConflict(t *testing.T) {
+	fake := &fakeCouch{docs: []map[string]interface{}{{"_id": "conflict", "_rev": "1-a"}}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	result := render(t, &types.DocumentAST{
+		Operation:    types.OpUpdate,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+		UpdateOps: []types.UpdateOperation{{
+			Operator: types.Set,
+			Fields:   map[types.Field]types.Param{{Path: "status"}: {Name: "status"}},
+		}},
+	})
+	res, err := New(server.URL).Run(context.Background(), result, map[string]interface{}{"id": "conflict", "status": "done"})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Kind != "update" || conflict.Reason != "Document update conflict." {
+		t.Fatalf("expected an update ConflictError, got %v", err)
+	}
+	if res.ModifiedCount != 0 {
+		t.Errorf("expected nothing modified, got %d", res.ModifiedCount)
+	}
+}
+
+func TestRun_ServerError(t *testing.T) {
+	server := httptest.NewServer(&fakeCouch{})
+	defer server.Close()
+
+	result := &types.QueryResult{JSON: `{"db":"users","operation":"purge"}`, Operation: types.OpDelete}
+	if _, err := New(server.URL).Run(context.Background(), result, nil); err == nil {
+		t.Error("expected an unknown operation to fail")
+	}
+
+	result = &types.QueryResult{JSON: `{"db":"users","selector":{}}`, Operation: types.OpFind}
+	server.Config.Handler = http.NotFoundHandler()
+	if _, err := New(server.URL).Run(context.Background(), result, nil); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a 404 error, got %v", err)
+	}
+}
+
+func TestBind_MissingParams(t *testing.T) {
+	result := &types.QueryResult{JSON: `{"db":"users","selector":{"a":{"$eq":":a"}}}`, RequiredParams: []string{"a"}}
+	if _, err := bind(result, nil); err == nil || !strings.Contains(err.Error(), "missing params: a") {
+		t.Errorf("expected missing param a, got %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/zoobzio/docql/pkg/couchdb/exec
+
+go 1.25.5
+
+replace github.com/zoobzio/docql => ../../..
+
+require github.com/zoobzio/docql v0.0.0-00010101000000-000000000000
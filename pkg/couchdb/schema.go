@@ -0,0 +1,126 @@
+package couchdb
+
+// OutputSchema returns a JSON Schema (draft 2020-12) describing the JSON that
+// Render produces. A find is a Mango query for the _find endpoint, with the
+// target database in db; writes name their operation. Selectors are Mango
+// and are only typed as objects.
+func OutputSchema() string {
+	return outputSchema
+}
+
+const outputSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "docql CouchDB query",
+  "anyOf": [
+    {"$ref": "#/$defs/find"},
+    {"$ref": "#/$defs/insert"},
+    {"$ref": "#/$defs/bulkInsert"},
+    {"$ref": "#/$defs/update"},
+    {"$ref": "#/$defs/delete"},
+    {"$ref": "#/$defs/bulkDocs"}
+  ],
+  "$defs": {
+    "find": {
+      "description": "FIND and FIND_ONE.",
+      "type": "object",
+      "properties": {
+        "db": {"type": "string"},
+        "database": {"type": "string"},
+        "selector": {"type": "object"},
+        "fields": {"type": "array", "items": {"type": "string"}},
+        "sort": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "additionalProperties": {"enum": ["asc", "desc"]},
+            "minProperties": 1,
+            "maxProperties": 1
+          }
+        },
+        "limit": {"$ref": "#/$defs/pagination"},
+        "skip": {"$ref": "#/$defs/pagination"},
+        "options": {
+          "type": "object",
+          "properties": {"maxTimeMS": {"type": "integer"}},
+          "additionalProperties": false
+        }
+      },
+      "required": ["db", "selector"],
+      "additionalProperties": false
+    },
+    "insert": {
+      "description": "INSERT.",
+      "type": "object",
+      "properties": {
+        "db": {"type": "string"},
+        "database": {"type": "string"},
+        "operation": {"const": "insert"},
+        "doc": {"$ref": "#/$defs/document"}
+      },
+      "required": ["db", "operation", "doc"],
+      "additionalProperties": false
+    },
+    "bulkInsert": {
+      "description": "INSERT_MANY.",
+      "type": "object",
+      "properties": {
+        "db": {"type": "string"},
+        "database": {"type": "string"},
+        "operation": {"const": "bulk_insert"},
+        "docs": {"type": "array", "items": {"$ref": "#/$defs/document"}}
+      },
+      "required": ["db", "operation", "docs"],
+      "additionalProperties": false
+    },
+    "update": {
+      "description": "UPDATE: the fields to set on the document the selector finds.",
+      "type": "object",
+      "properties": {
+        "db": {"type": "string"},
+        "database": {"type": "string"},
+        "operation": {"const": "update"},
+        "selector": {"type": "object"},
+        "updates": {"$ref": "#/$defs/document"}
+      },
+      "required": ["db", "operation", "updates"],
+      "additionalProperties": false
+    },
+    "delete": {
+      "description": "DELETE: the document the selector finds.",
+      "type": "object",
+      "properties": {
+        "db": {"type": "string"},
+        "database": {"type": "string"},
+        "operation": {"const": "delete"},
+        "selector": {"type": "object"}
+      },
+      "required": ["db", "operation"],
+      "additionalProperties": false
+    },
+    "bulkDocs": {
+      "description": "TRANSACTION: the writes to submit through _bulk_docs, in order.",
+      "type": "object",
+      "properties": {
+        "operation": {"const": "bulk_docs"},
+        "operations": {
+          "type": "array",
+          "items": {
+            "anyOf": [
+              {"$ref": "#/$defs/insert"},
+              {"$ref": "#/$defs/update"},
+              {"$ref": "#/$defs/delete"}
+            ]
+          }
+        }
+      },
+      "required": ["operation", "operations"],
+      "additionalProperties": false
+    },
+    "document": {
+      "type": "object",
+      "description": "Field paths to :param placeholders, plus the type field when one is configured.",
+      "additionalProperties": {"type": "string"}
+    },
+    "pagination": {"type": ["integer", "string"], "description": "A static count, or a :param placeholder."}
+  }
+}`
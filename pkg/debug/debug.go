@@ -0,0 +1,603 @@
+// Package debug provides a renderer that emits the DocumentAST itself as
+// normalized JSON, for inspecting queries and writing golden tests without a
+// provider.
+//
+// Every operation, filter, update operator, and pipeline stage is supported.
+// Object keys are sorted, params render as ":name" placeholders, and
+// RequiredParams lists each param once in the order the AST first uses it.
+package debug
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// provider names the debug renderer in UnsupportedError values.
+const provider = "debug"
+
+// Renderer renders DocumentAST to its normalized JSON representation.
+type Renderer struct{}
+
+// New creates a new debug renderer.
+func New() *Renderer {
+	return &Renderer{}
+}
+
+type object = map[string]interface{}
+
+// encoder collects params in first-use order while the AST is converted.
+type encoder struct {
+	params []string
+	seen   map[string]bool
+}
+
+func (e *encoder) param(p types.Param) string {
+	if !e.seen[p.Name] {
+		e.seen[p.Name] = true
+		e.params = append(e.params, p.Name)
+	}
+	return ":" + p.Name
+}
+
+// optionalParam renders p, or nil when it is absent.
+func (e *encoder) optionalParam(p *types.Param) interface{} {
+	if p == nil {
+		return nil
+	}
+	return e.param(*p)
+}
+
+// Render converts a DocumentAST to normalized JSON.
+func (r *Renderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
+	if err := ast.Validate(); err != nil {
+		return nil, &types.InvalidASTError{Err: err}
+	}
+
+	e := &encoder{seen: make(map[string]bool)}
+	out, err := e.ast(ast)
+	if err != nil {
+		return nil, types.WithOperation(err, ast.Operation)
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize AST: %w", err)
+	}
+	return &types.QueryResult{
+		Operation:      ast.Operation,
+		Single:         ast.Operation == types.OpFindOne,
+		Collection:     ast.Target.Name,
+		Database:       ast.Target.Database,
+		JSON:           string(data),
+		RequiredParams: e.params,
+	}, nil
+}
+
+func (e *encoder) ast(ast *types.DocumentAST) (object, error) {
+	out := object{
+		"operation": string(ast.Operation),
+		"target":    ast.Target.Name,
+	}
+	if ast.Target.Param != nil {
+		out["targetParam"] = e.param(*ast.Target.Param)
+	}
+	if ast.Target.Database != "" {
+		out["database"] = ast.Target.Database
+	}
+
+	if ast.FilterClause != nil {
+		filter, err := e.filter(ast.FilterClause)
+		if err != nil {
+			return nil, err
+		}
+		out["filter"] = filter
+	}
+	if ast.Projection != nil {
+		projection, err := e.projection(*ast.Projection)
+		if err != nil {
+			return nil, err
+		}
+		out["projection"] = projection
+	}
+	if len(ast.SortClauses) > 0 {
+		out["sort"] = sorts(ast.SortClauses)
+	}
+	if ast.Skip != nil {
+		out["skip"] = e.pagination(*ast.Skip)
+	}
+	if ast.Limit != nil {
+		out["limit"] = e.pagination(*ast.Limit)
+	}
+	if len(ast.Documents) > 0 {
+		docs := make([]interface{}, len(ast.Documents))
+		for i, doc := range ast.Documents {
+			docs[i] = e.fields(doc.Fields)
+		}
+		out["documents"] = docs
+	}
+	if len(ast.UpdateOps) > 0 {
+		ops := make([]interface{}, len(ast.UpdateOps))
+		for i, op := range ast.UpdateOps {
+			ops[i] = object{"op": string(op.Operator), "fields": e.fields(op.Fields)}
+		}
+		out["update"] = ops
+	}
+	if ast.Upsert {
+		out["upsert"] = true
+	}
+	if ast.IfNotExists {
+		out["ifNotExists"] = true
+	}
+	if len(ast.Pipeline) > 0 {
+		pipeline, err := e.pipeline(ast.Pipeline)
+		if err != nil {
+			return nil, err
+		}
+		out["pipeline"] = pipeline
+	}
+	if ast.DistinctField != nil {
+		out["distinctField"] = field(*ast.DistinctField)
+	}
+	if ast.MaxTimeMS != nil {
+		out["maxTimeMS"] = *ast.MaxTimeMS
+	}
+	if ast.AllowDiskUse {
+		out["allowDiskUse"] = true
+	}
+	if ast.Hint != nil {
+		out["hint"] = *ast.Hint
+	}
+	if ast.Collation != nil {
+		out["collation"] = object{"locale": ast.Collation.Locale, "strength": ast.Collation.Strength}
+	}
+	if ast.ReadConcern != nil {
+		out["readConcern"] = *ast.ReadConcern
+	}
+	if ast.WriteConcern != nil {
+		out["writeConcern"] = *ast.WriteConcern
+	}
+	if ast.FullDocument != nil {
+		out["fullDocument"] = *ast.FullDocument
+	}
+	if ast.ResumeAfter != nil {
+		out["resumeAfter"] = e.param(*ast.ResumeAfter)
+	}
+	if ast.RawEvent {
+		out["rawEvent"] = true
+	}
+	if ast.RequireFilter {
+		out["requireFilter"] = true
+	}
+	if len(ast.Operations) > 0 {
+		ops := make([]interface{}, len(ast.Operations))
+		for i, op := range ast.Operations {
+			rendered, err := e.ast(op)
+			if err != nil {
+				return nil, err
+			}
+			ops[i] = rendered
+		}
+		out["operations"] = ops
+	}
+	return out, nil
+}
+
+// field renders a field reference, omitting an empty collection.
+func field(f types.Field) object {
+	out := object{"path": f.Path}
+	if f.Collection != "" {
+		out["collection"] = f.Collection
+	}
+	return out
+}
+
+// fields renders a field-to-param map in path order; params without a name,
+// as $unset and $currentDate use, render as null.
+func (e *encoder) fields(m map[types.Field]types.Param) []interface{} {
+	keys := slices.SortedFunc(maps.Keys(m), func(a, b types.Field) int {
+		if a.Path != b.Path {
+			return cmp.Compare(a.Path, b.Path)
+		}
+		return cmp.Compare(a.Collection, b.Collection)
+	})
+	out := make([]interface{}, len(keys))
+	for i, f := range keys {
+		entry := object{"field": field(f), "value": nil}
+		if p := m[f]; p.Name != "" {
+			entry["value"] = e.param(p)
+		}
+		out[i] = entry
+	}
+	return out
+}
+
+func sorts(clauses []types.SortClause) []interface{} {
+	out := make([]interface{}, len(clauses))
+	for i, s := range clauses {
+		sort := object{"field": field(s.Field), "order": int(s.Order)}
+		if s.Opts.CaseInsensitive {
+			sort["caseInsensitive"] = true
+		}
+		if s.Opts.Numeric {
+			sort["numeric"] = true
+		}
+		out[i] = sort
+	}
+	return out
+}
+
+func (e *encoder) pagination(p types.PaginationValue) interface{} {
+	if p.Param != nil {
+		return e.param(*p.Param)
+	}
+	if p.Static != nil {
+		return *p.Static
+	}
+	return nil
+}
+
+func (e *encoder) filters(items []types.FilterItem) ([]interface{}, error) {
+	out := make([]interface{}, len(items))
+	for i, item := range items {
+		f, err := e.filter(item)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = f
+	}
+	return out, nil
+}
+
+func (e *encoder) filter(f types.FilterItem) (object, error) {
+	switch filter := f.(type) {
+	case types.FilterCondition:
+		return object{
+			"type":  "condition",
+			"field": field(filter.Field),
+			"op":    string(filter.Operator),
+			"value": e.param(filter.Value),
+		}, nil
+
+	case types.FilterGroup:
+		conditions, err := e.filters(filter.Conditions)
+		if err != nil {
+			return nil, err
+		}
+		return object{"type": "group", "logic": string(filter.Logic), "conditions": conditions}, nil
+
+	case types.RangeFilter:
+		return object{
+			"type":         "range",
+			"field":        field(filter.Field),
+			"min":          e.optionalParam(filter.Min),
+			"max":          e.optionalParam(filter.Max),
+			"minExclusive": filter.MinExclusive,
+			"maxExclusive": filter.MaxExclusive,
+			"negated":      filter.Negated,
+		}, nil
+
+	case types.RegexFilter:
+		return object{
+			"type":    "regex",
+			"field":   field(filter.Field),
+			"pattern": e.param(filter.Pattern),
+			"options": e.optionalParam(filter.Options),
+		}, nil
+
+	case types.TextSearchFilter:
+		return object{
+			"type":               "text",
+			"search":             e.param(filter.Search),
+			"language":           e.optionalParam(filter.Language),
+			"caseSensitive":      filter.CaseSensitive,
+			"diacriticSensitive": filter.DiacriticSensitive,
+		}, nil
+
+	case types.GeoFilter:
+		return object{
+			"type":        "geo",
+			"field":       field(filter.Field),
+			"op":          string(filter.Operator),
+			"center":      object{"lon": e.param(filter.Center.Lon), "lat": e.param(filter.Center.Lat)},
+			"radius":      e.optionalParam(filter.Radius),
+			"maxDistance": e.optionalParam(filter.MaxDistance),
+			"minDistance": e.optionalParam(filter.MinDistance),
+		}, nil
+
+	case types.ArrayFilter:
+		var value interface{} = e.param(filter.Value)
+		if filter.Literal != nil {
+			value = *filter.Literal
+		}
+		return object{
+			"type":  "array",
+			"field": field(filter.Field),
+			"op":    string(filter.Operator),
+			"value": value,
+		}, nil
+
+	case types.ValuesFilter:
+		values := make([]interface{}, len(filter.Values))
+		for i, v := range filter.Values {
+			values[i] = e.param(v)
+		}
+		return object{
+			"type":   "values",
+			"field":  field(filter.Field),
+			"op":     string(filter.Operator),
+			"values": values,
+		}, nil
+
+	case types.ModFilter:
+		return object{
+			"type":      "mod",
+			"field":     field(filter.Field),
+			"divisor":   e.param(filter.Divisor),
+			"remainder": e.param(filter.Remainder),
+		}, nil
+
+	case types.TypeFilter:
+		return object{"type": "typeIs", "field": field(filter.Field), "typeName": filter.TypeName}, nil
+
+	case types.ElemMatchFilter:
+		conditions, err := e.filters(filter.Conditions)
+		if err != nil {
+			return nil, err
+		}
+		return object{"type": "elemMatch", "field": field(filter.Field), "conditions": conditions}, nil
+
+	case types.ExistsFilter:
+		return object{"type": "exists", "field": field(filter.Field), "exists": filter.Exists}, nil
+
+	case types.NullFilter:
+		return object{"type": "null", "field": field(filter.Field), "negated": filter.Negated, "strict": filter.Strict}, nil
+
+	default:
+		return nil, types.UnsupportedFilter(provider, types.FilterName(f))
+	}
+}
+
+func (e *encoder) projection(p types.Projection) (object, error) {
+	fields := make([]interface{}, len(p.Fields))
+	for i, f := range p.Fields {
+		entry := object{"field": field(f.Field), "include": f.Include}
+		if f.Alias != "" {
+			entry["alias"] = f.Alias
+		}
+		if f.Slice != nil {
+			entry["slice"] = object{"count": e.param(f.Slice.Count), "skip": e.optionalParam(f.Slice.Skip)}
+		}
+		if f.ElemMatch != nil {
+			conditions, err := e.filters(f.ElemMatch.Conditions)
+			if err != nil {
+				return nil, err
+			}
+			entry["elemMatch"] = conditions
+		}
+		fields[i] = entry
+	}
+	return object{"exclude": p.Exclude, "fields": fields}, nil
+}
+
+func (e *encoder) expr(expr types.Expression) (interface{}, error) {
+	switch x := expr.(type) {
+	case nil:
+		return nil, nil
+	case types.FieldExpression:
+		return object{"field": field(x.Field)}, nil
+	case types.LiteralExpression:
+		return object{"literal": e.param(x.Value)}, nil
+	case types.OperatorExpression:
+		args := make([]interface{}, len(x.Args))
+		for i, arg := range x.Args {
+			a, err := e.expr(arg)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = a
+		}
+		return object{"op": x.Operator, "args": args}, nil
+	case types.ConditionalExpression:
+		cond, err := e.expr(x.If)
+		if err != nil {
+			return nil, err
+		}
+		then, err := e.expr(x.Then)
+		if err != nil {
+			return nil, err
+		}
+		els, err := e.expr(x.Else)
+		if err != nil {
+			return nil, err
+		}
+		return object{"if": cond, "then": then, "else": els}, nil
+	case types.CompositeExpression:
+		fields, err := e.exprs(x.Fields)
+		if err != nil {
+			return nil, err
+		}
+		return object{"fields": fields}, nil
+	default:
+		return nil, types.UnsupportedFeature(provider, fmt.Sprintf("expression %T", expr))
+	}
+}
+
+// exprs renders a named expression map in key order so params are collected
+// deterministically.
+func (e *encoder) exprs(m map[string]types.Expression) (object, error) {
+	out := make(object, len(m))
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		x, err := e.expr(m[name])
+		if err != nil {
+			return nil, err
+		}
+		out[name] = x
+	}
+	return out, nil
+}
+
+func (e *encoder) accumulators(m map[string]types.Accumulator) (object, error) {
+	out := make(object, len(m))
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		acc := m[name]
+		x, err := e.expr(acc.Expr)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = object{"op": acc.Operator, "expr": x}
+	}
+	return out, nil
+}
+
+func (e *encoder) pipeline(stages []types.PipelineStage) ([]interface{}, error) {
+	out := make([]interface{}, len(stages))
+	for i, stage := range stages {
+		s, err := e.stage(stage)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+func (e *encoder) stage(stage types.PipelineStage) (object, error) {
+	out := object{"stage": stage.StageName()}
+	var err error
+
+	switch s := stage.(type) {
+	case types.MatchStage:
+		out["filter"], err = e.filter(s.Filter)
+
+	case types.ProjectStage:
+		if out["projection"], err = e.projection(s.Projection); err == nil && len(s.Computed) > 0 {
+			out["computed"], err = e.exprs(s.Computed)
+		}
+
+	case types.GroupStage:
+		if out["id"], err = e.expr(s.ID); err == nil {
+			out["accumulators"], err = e.accumulators(s.Accumulators)
+		}
+
+	case types.SortStage:
+		out["sort"] = sorts(s.Sorts)
+
+	case types.LimitStage:
+		out["limit"] = e.pagination(s.Limit)
+
+	case types.SkipStage:
+		out["skip"] = e.pagination(s.Skip)
+
+	case types.UnwindStage:
+		out["path"] = field(s.Path)
+		if s.IncludeArrayIndex != nil {
+			out["includeArrayIndex"] = *s.IncludeArrayIndex
+		}
+		out["preserveNullAndEmptyArrays"] = s.PreserveNullAndEmptyArrays
+
+	case types.LookupStage:
+		out["from"] = s.From
+		out["localField"] = field(s.LocalField)
+		out["foreignField"] = field(s.ForeignField)
+		out["as"] = s.As
+		if len(s.Let) > 0 {
+			if out["let"], err = e.exprs(s.Let); err != nil {
+				break
+			}
+		}
+		if len(s.Pipeline) > 0 {
+			out["pipeline"], err = e.pipeline(s.Pipeline)
+		}
+
+	case types.GraphLookupStage:
+		out["from"] = s.From
+		out["connectFromField"] = field(s.ConnectFromField)
+		out["connectToField"] = field(s.ConnectToField)
+		out["as"] = s.As
+		if s.MaxDepth != nil {
+			out["maxDepth"] = e.pagination(*s.MaxDepth)
+		}
+		if s.DepthField != nil {
+			out["depthField"] = *s.DepthField
+		}
+		if out["startWith"], err = e.expr(s.StartWith); err == nil && s.RestrictSearchWithMatch != nil {
+			out["restrictSearchWithMatch"], err = e.filter(s.RestrictSearchWithMatch)
+		}
+
+	case types.AddFieldsStage:
+		out["fields"], err = e.exprs(s.Fields)
+
+	case types.ReplaceRootStage:
+		out["newRoot"], err = e.expr(s.NewRoot)
+
+	case types.CountStage:
+		out["field"] = s.FieldName
+
+	case types.FacetStage:
+		facets := make(object, len(s.Facets))
+		for _, name := range slices.Sorted(maps.Keys(s.Facets)) {
+			if facets[name], err = e.pipeline(s.Facets[name]); err != nil {
+				break
+			}
+		}
+		out["facets"] = facets
+
+	case types.BucketStage:
+		boundaries := make([]interface{}, len(s.Boundaries))
+		for i, b := range s.Boundaries {
+			boundaries[i] = e.param(b)
+		}
+		if out["groupBy"], err = e.expr(s.GroupBy); err != nil {
+			break
+		}
+		out["boundaries"] = boundaries
+		if s.Default != nil {
+			out["default"] = e.param(*s.Default)
+		}
+		out["output"], err = e.accumulators(s.Output)
+
+	case types.SortByCountStage:
+		out["expr"], err = e.expr(s.Expr)
+
+	case types.SampleStage:
+		out["size"] = e.pagination(s.Size)
+
+	case types.MergeStage:
+		out["into"] = s.Into
+		out["whenMatched"] = s.WhenMatched
+		out["whenNotMatched"] = s.WhenNotMatched
+
+	default:
+		return nil, types.UnsupportedFeature(provider, "pipeline stage: "+stage.StageName())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SupportsOperation reports true: the debug renderer accepts every operation.
+func (r *Renderer) SupportsOperation(op types.Operation) bool {
+	return true
+}
+
+// SupportsFilter reports true: the debug renderer accepts every filter.
+func (r *Renderer) SupportsFilter(op types.FilterOperator) bool {
+	return true
+}
+
+// SupportsUpdate reports true: the debug renderer accepts every update operator.
+func (r *Renderer) SupportsUpdate(op types.UpdateOperator) bool {
+	return true
+}
+
+// SupportsPipelineStage reports true: the debug renderer accepts every stage.
+func (r *Renderer) SupportsPipelineStage(stage string) bool {
+	return true
+}
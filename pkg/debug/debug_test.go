@@ -0,0 +1,217 @@
+package debug
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+func intPtr(i int) *int { return &i }
+
+func TestRender_NestedFilter(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{
+			Logic: types.AND,
+			Conditions: []types.FilterItem{
+				types.FilterCondition{Field: types.Field{Path: "status", Collection: "users"}, Operator: types.EQ, Value: types.Param{Name: "status"}},
+				types.FilterGroup{
+					Logic: types.OR,
+					Conditions: []types.FilterItem{
+						types.ExistsFilter{Field: types.Field{Path: "deletedAt"}},
+						types.ValuesFilter{Field: types.Field{Path: "role"}, Operator: types.IN, Values: []types.Param{{Name: "r1"}, {Name: "r2"}}},
+					},
+				},
+			},
+		},
+		SortClauses: []types.SortClause{{Field: types.Field{Path: "name"}, Order: types.Descending}},
+		Skip:        &types.PaginationValue{Static: intPtr(10)},
+		Limit:       &types.PaginationValue{Param: &types.Param{Name: "limit"}},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"filter":{"conditions":[` +
+		`{"field":{"collection":"users","path":"status"},"op":"$eq","type":"condition","value":":status"},` +
+		`{"conditions":[{"exists":false,"field":{"path":"deletedAt"},"type":"exists"},` +
+		`{"field":{"path":"role"},"op":"$in","type":"values","values":[":r1",":r2"]}],"logic":"$or","type":"group"}` +
+		`],"logic":"$and","type":"group"},` +
+		`"limit":":limit","operation":"FIND","skip":10,"sort":[{"field":{"path":"name"},"order":-1}],"target":"users"}`
+	if result.JSON != expected {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, expected)
+	}
+	if result.Operation != types.OpFind || result.Collection != "users" {
+		t.Errorf("unexpected result metadata: %s %s", result.Operation, result.Collection)
+	}
+}
+
+func TestRender_StableParamOrder(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpUpdateMany,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{
+			Logic: types.AND,
+			Conditions: []types.FilterItem{
+				types.FilterCondition{Field: types.Field{Path: "b"}, Operator: types.EQ, Value: types.Param{Name: "z"}},
+				types.RangeFilter{Field: types.Field{Path: "a"}, Min: &types.Param{Name: "lo"}, Max: &types.Param{Name: "hi"}},
+				types.FilterCondition{Field: types.Field{Path: "c"}, Operator: types.NE, Value: types.Param{Name: "z"}},
+			},
+		},
+		UpdateOps: []types.UpdateOperation{
+			{Operator: types.Set, Fields: map[types.Field]types.Param{
+				{Path: "y"}: {Name: "setY"},
+				{Path: "x"}: {Name: "setX"},
+				{Path: "w"}: {Name: "setW"},
+			}},
+			{Operator: types.Unset, Fields: map[types.Field]types.Param{{Path: "old"}: {}}},
+		},
+	}
+
+	want := []string{"z", "lo", "hi", "setW", "setX", "setY"}
+	var first string
+	for i := range 20 {
+		result, err := New().Render(ast)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(result.RequiredParams, want) {
+			t.Fatalf("RequiredParams = %v, want %v", result.RequiredParams, want)
+		}
+		if i == 0 {
+			first = result.JSON
+		} else if result.JSON != first {
+			t.Fatalf("render %d differs:\n%s\n%s", i, result.JSON, first)
+		}
+	}
+	if !strings.Contains(first, `{"fields":[{"field":{"path":"old"},"value":null}],"op":"$unset"}`) {
+		t.Errorf("expected $unset with null value in %s", first)
+	}
+}
+
+func TestRender_EveryOperation(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	filter := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+	doc := types.Document{Fields: map[types.Field]types.Param{{Path: "name"}: {Name: "name"}}}
+	set := []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{{Path: "name"}: {Name: "name"}}}}
+
+	asts := []*types.DocumentAST{
+		{Operation: types.OpFind, Target: users},
+		{Operation: types.OpFindOne, Target: users, FilterClause: filter},
+		{Operation: types.OpInsert, Target: users, Documents: []types.Document{doc}},
+		{Operation: types.OpInsertMany, Target: users, Documents: []types.Document{doc, doc}},
+		{Operation: types.OpUpdate, Target: users, FilterClause: filter, UpdateOps: set, Upsert: true},
+		{Operation: types.OpUpdateMany, Target: users, FilterClause: filter, UpdateOps: set},
+		{Operation: types.OpDelete, Target: users, FilterClause: filter},
+		{Operation: types.OpDeleteMany, Target: users, FilterClause: filter},
+		{Operation: types.OpCount, Target: users, FilterClause: filter},
+		{Operation: types.OpDistinct, Target: users, DistinctField: &types.Field{Path: "name"}},
+		{Operation: types.OpAggregate, Target: users, Pipeline: []types.PipelineStage{types.CountStage{FieldName: "n"}}},
+	}
+
+	for _, ast := range asts {
+		t.Run(string(ast.Operation), func(t *testing.T) {
+			result, err := New().Render(ast)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var out map[string]interface{}
+			if err := json.Unmarshal([]byte(result.JSON), &out); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if out["operation"] != string(ast.Operation) || out["target"] != "users" {
+				t.Errorf("unexpected header: %v", out)
+			}
+		})
+	}
+}
+
+func TestRender_EveryFilter(t *testing.T) {
+	f := types.Field{Path: "f"}
+	p := types.Param{Name: "p"}
+	filters := map[string]types.FilterItem{
+		"condition": types.FilterCondition{Field: f, Operator: types.GT, Value: p},
+		"group":     types.FilterGroup{Logic: types.NOR, Conditions: []types.FilterItem{types.ExistsFilter{Field: f, Exists: true}}},
+		"range":     types.RangeFilter{Field: f, Min: &p, MinExclusive: true},
+		"regex":     types.RegexFilter{Field: f, Pattern: p, Options: &types.Param{Name: "opts"}},
+		"text":      types.TextSearchFilter{Search: p, CaseSensitive: true},
+		"geo":       types.GeoFilter{Field: f, Operator: types.Near, Center: types.GeoPoint{Lon: types.Param{Name: "lon"}, Lat: types.Param{Name: "lat"}}, MaxDistance: &p},
+		"array":     types.ArrayFilter{Field: f, Operator: types.Size, Value: p},
+		"values":    types.ValuesFilter{Field: f, Operator: types.NotIn, Values: []types.Param{p}},
+		"mod":       types.ModFilter{Field: f, Divisor: p, Remainder: types.Param{Name: "r"}},
+		"typeIs":    types.TypeFilter{Field: f, TypeName: "string"},
+		"elemMatch": types.ElemMatchFilter{Field: f, Conditions: []types.FilterItem{types.FilterCondition{Field: types.Field{Path: "x"}, Operator: types.EQ, Value: p}}},
+		"exists":    types.ExistsFilter{Field: f},
+	}
+
+	for kind, filter := range filters {
+		t.Run(kind, func(t *testing.T) {
+			result, err := New().Render(&types.DocumentAST{Operation: types.OpFind, Target: types.Collection{Name: "c"}, FilterClause: filter})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var out struct {
+				Filter map[string]interface{} `json:"filter"`
+			}
+			if err := json.Unmarshal([]byte(result.JSON), &out); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if out.Filter["type"] != kind {
+				t.Errorf("expected filter type %s, got %v", kind, out.Filter)
+			}
+		})
+	}
+}
+
+func TestRender_Pipeline(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "orders"},
+		Pipeline: []types.PipelineStage{
+			types.MatchStage{Filter: types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "status"}}},
+			types.GroupStage{
+				ID: types.FieldExpression{Field: types.Field{Path: "userId"}},
+				Accumulators: map[string]types.Accumulator{
+					"total": {Operator: types.AccSum, Expr: types.FieldExpression{Field: types.Field{Path: "amount"}}},
+					"bonus": {Operator: types.AccSum, Expr: types.LiteralExpression{Value: types.Param{Name: "bonus"}}},
+				},
+			},
+			types.FacetStage{Facets: map[string][]types.PipelineStage{
+				"top":   {types.LimitStage{Limit: types.PaginationValue{Param: &types.Param{Name: "top"}}}},
+				"count": {types.CountStage{FieldName: "n"}},
+			}},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(result.RequiredParams, []string{"status", "bonus", "top"}) {
+		t.Errorf("unexpected RequiredParams: %v", result.RequiredParams)
+	}
+	for _, want := range []string{
+		`{"filter":{"field":{"path":"status"},"op":"$eq","type":"condition","value":":status"},"stage":"$match"}`,
+		`"bonus":{"expr":{"literal":":bonus"},"op":"$sum"}`,
+		`"facets":{"count":[{"field":"n","stage":"$count"}],"top":[{"limit":":top","stage":"$limit"}]}`,
+	} {
+		if !strings.Contains(result.JSON, want) {
+			t.Errorf("expected %s in %s", want, result.JSON)
+		}
+	}
+}
+
+func TestRender_InvalidAST(t *testing.T) {
+	_, err := New().Render(&types.DocumentAST{Operation: types.OpFind})
+	var invalid *types.InvalidASTError
+	if !errors.As(err, &invalid) {
+		t.Errorf("expected *InvalidASTError, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package debug
+
+// OutputSchema returns a JSON Schema (draft 2020-12) describing the JSON that
+// Render produces. The top-level keys and field references are exact;
+// filters, expressions, and stages are typed by their discriminating key,
+// with their remaining keys following the AST node they render.
+func OutputSchema() string {
+	return outputSchema
+}
+
+const outputSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "docql debug AST",
+  "$ref": "#/$defs/ast",
+  "$defs": {
+    "ast": {
+      "type": "object",
+      "properties": {
+        "operation": {"enum": ["FIND", "FIND_ONE", "INSERT", "INSERT_MANY", "UPDATE", "UPDATE_MANY", "DELETE", "DELETE_MANY", "AGGREGATE", "COUNT", "DISTINCT", "WATCH", "TRANSACTION"]},
+        "target": {"type": "string"},
+        "targetParam": {"$ref": "#/$defs/placeholder"},
+        "database": {"type": "string"},
+        "filter": {"$ref": "#/$defs/filter"},
+        "projection": {"$ref": "#/$defs/projection"},
+        "sort": {"$ref": "#/$defs/sort"},
+        "skip": {"$ref": "#/$defs/pagination"},
+        "limit": {"$ref": "#/$defs/pagination"},
+        "documents": {"type": "array", "items": {"$ref": "#/$defs/fields"}},
+        "update": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "properties": {
+              "op": {"type": "string"},
+              "fields": {"$ref": "#/$defs/fields"}
+            },
+            "required": ["op", "fields"],
+            "additionalProperties": false
+          }
+        },
+        "upsert": {"const": true},
+        "ifNotExists": {"const": true},
+        "pipeline": {"$ref": "#/$defs/pipeline"},
+        "distinctField": {"$ref": "#/$defs/field"},
+        "maxTimeMS": {"type": "integer"},
+        "allowDiskUse": {"const": true},
+        "hint": {"type": "string"},
+        "collation": {
+          "type": "object",
+          "properties": {
+            "locale": {"type": "string"},
+            "strength": {"type": "integer"}
+          },
+          "required": ["locale", "strength"],
+          "additionalProperties": false
+        },
+        "readConcern": {"type": "string"},
+        "writeConcern": {"type": "string"},
+        "fullDocument": {"type": "string"},
+        "resumeAfter": {"$ref": "#/$defs/placeholder"},
+        "rawEvent": {"const": true},
+        "requireFilter": {"const": true},
+        "operations": {"type": "array", "items": {"$ref": "#/$defs/ast"}}
+      },
+      "required": ["operation", "target"],
+      "additionalProperties": false
+    },
+    "field": {
+      "type": "object",
+      "properties": {
+        "path": {"type": "string"},
+        "collection": {"type": "string"}
+      },
+      "required": ["path"],
+      "additionalProperties": false
+    },
+    "fields": {
+      "type": "array",
+      "description": "Field-to-param entries in path order; value is null for operators that take none.",
+      "items": {
+        "type": "object",
+        "properties": {
+          "field": {"$ref": "#/$defs/field"},
+          "value": {"type": ["string", "null"]}
+        },
+        "required": ["field", "value"],
+        "additionalProperties": false
+      }
+    },
+    "filter": {
+      "type": "object",
+      "properties": {
+        "type": {"enum": ["condition", "group", "range", "regex", "text", "geo", "array", "values", "mod", "typeIs", "elemMatch", "exists", "null"]},
+        "field": {"$ref": "#/$defs/field"},
+        "conditions": {"type": "array", "items": {"$ref": "#/$defs/filter"}}
+      },
+      "required": ["type"]
+    },
+    "projection": {
+      "type": "object",
+      "properties": {
+        "exclude": {"type": "boolean"},
+        "fields": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "properties": {
+              "field": {"$ref": "#/$defs/field"},
+              "include": {"type": "boolean"},
+              "alias": {"type": "string"},
+              "slice": {
+                "type": "object",
+                "properties": {
+                  "count": {"$ref": "#/$defs/placeholder"},
+                  "skip": {"type": ["string", "null"]}
+                },
+                "required": ["count", "skip"],
+                "additionalProperties": false
+              },
+              "elemMatch": {"type": "array", "items": {"$ref": "#/$defs/filter"}}
+            },
+            "required": ["field", "include"],
+            "additionalProperties": false
+          }
+        }
+      },
+      "required": ["exclude", "fields"],
+      "additionalProperties": false
+    },
+    "sort": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "field": {"$ref": "#/$defs/field"},
+          "order": {"enum": [1, -1]},
+          "caseInsensitive": {"const": true},
+          "numeric": {"const": true}
+        },
+        "required": ["field", "order"],
+        "additionalProperties": false
+      }
+    },
+    "pipeline": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "stage": {"type": "string", "description": "The MongoDB stage name, such as $match."},
+          "pipeline": {"$ref": "#/$defs/pipeline"}
+        },
+        "required": ["stage"]
+      }
+    },
+    "placeholder": {"type": "string", "description": "A parameter reference, :name."},
+    "pagination": {"type": ["integer", "string"], "description": "A static count, or a :param placeholder."}
+  }
+}`
@@ -4,6 +4,10 @@ package dynamodb
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/zoobzio/docql/internal/types"
 )
@@ -14,8 +18,35 @@ type Renderer struct {
 	PartitionKey string
 	// SortKey specifies the sort key attribute name (optional).
 	SortKey string
+	// ReturnValues is added to put, update, and delete requests when set.
+	ReturnValues string
+	// ReturnConsumedCapacity is added to write requests when set.
+	ReturnConsumedCapacity string
 }
 
+// provider names DynamoDB in UnsupportedError values.
+const provider = "DynamoDB"
+
+// maxTransactItems is the most actions TransactWriteItems accepts.
+const maxTransactItems = 100
+
+// transactActions names the TransactWriteItems action for each write.
+var transactActions = map[types.Operation]string{
+	types.OpInsert: "Put",
+	types.OpUpdate: "Update",
+	types.OpDelete: "Delete",
+}
+
+// returnValues lists the ReturnValues each write request accepts.
+var returnValues = map[types.Operation][]string{
+	types.OpInsert: {"NONE", "ALL_OLD"},
+	types.OpUpdate: {"NONE", "ALL_OLD", "UPDATED_OLD", "ALL_NEW", "UPDATED_NEW"},
+	types.OpDelete: {"NONE", "ALL_OLD"},
+}
+
+// consumedCapacity lists the accepted ReturnConsumedCapacity values.
+var consumedCapacity = []string{"INDEXES", "TOTAL", "NONE"}
+
 // New creates a new DynamoDB renderer.
 func New() *Renderer {
 	return &Renderer{
@@ -35,30 +66,77 @@ func (r *Renderer) WithSortKey(sk string) *Renderer {
 	return r
 }
 
+// WithReturnValues sets the ReturnValues of put, update, and delete
+// requests, such as "ALL_NEW". PutItem and DeleteItem accept only "NONE"
+// and "ALL_OLD"; a value a request does not accept fails at render.
+func (r *Renderer) WithReturnValues(rv string) *Renderer {
+	r.ReturnValues = rv
+	return r
+}
+
+// WithReturnConsumedCapacity sets the ReturnConsumedCapacity of write
+// requests: "INDEXES", "TOTAL", or "NONE". A transaction reports it once,
+// for the whole TransactWriteItems request.
+func (r *Renderer) WithReturnConsumedCapacity(rcc string) *Renderer {
+	r.ReturnConsumedCapacity = rcc
+	return r
+}
+
+// CacheKey identifies the renderer's options, so a registry caches its
+// results apart from those of differently configured renderers.
+func (r *Renderer) CacheKey() (string, bool) {
+	return fmt.Sprintf("%q %q %q %q", r.PartitionKey, r.SortKey, r.ReturnValues, r.ReturnConsumedCapacity), true
+}
+
 // Render converts a DocumentAST to DynamoDB query format.
 func (r *Renderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
 	if err := ast.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid AST: %w", err)
+		return nil, &types.InvalidASTError{Err: err}
+	}
+
+	if ast.ParameterizedTarget() {
+		return nil, types.UnsupportedFeature(provider, "parameterized collection")
 	}
 
 	if !r.SupportsOperation(ast.Operation) {
-		return nil, fmt.Errorf("DynamoDB does not support operation: %s", ast.Operation)
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
 	}
 
 	var params []string
+	var result *types.QueryResult
+	var err error
 
 	switch ast.Operation {
 	case types.OpFind, types.OpFindOne:
-		return r.renderQuery(ast, &params)
+		result, err = r.renderQuery(ast, &params)
 	case types.OpInsert:
-		return r.renderPutItem(ast, &params)
+		result, err = r.renderPutItem(ast, &params)
 	case types.OpUpdate:
-		return r.renderUpdateItem(ast, &params)
+		result, err = r.renderUpdateItem(ast, &params)
 	case types.OpDelete:
-		return r.renderDeleteItem(ast, &params)
+		result, err = r.renderDeleteItem(ast, &params)
+	case types.OpTransaction:
+		result, err = r.renderTransactWriteItems(ast)
 	default:
-		return nil, fmt.Errorf("unsupported operation: %s", ast.Operation)
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
 	}
+	if err != nil {
+		return nil, types.WithOperation(err, ast.Operation)
+	}
+	result.Operation = ast.Operation
+	result.Single = ast.Operation == types.OpFindOne
+	result.Collection = ast.Target.Name
+	result.Database = ast.Target.Database
+	return result, nil
+}
+
+// RenderTransaction renders writes as one TransactWriteItems request, as
+// Render does for a transaction AST. Each action keeps its own
+// ExpressionAttributeNames and ExpressionAttributeValues, so the #n0 and :0
+// placeholders of one action never collide with another's; a param used by
+// several actions is bound once.
+func (r *Renderer) RenderTransaction(asts ...*types.DocumentAST) (*types.QueryResult, error) {
+	return r.Render(&types.DocumentAST{Operation: types.OpTransaction, Operations: asts})
 }
 
 func (r *Renderer) renderQuery(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
@@ -78,29 +156,59 @@ func (r *Renderer) renderQuery(ast *types.DocumentAST, params *[]string) (*types
 	}
 
 	getValue := func(param string) string {
-		key := fmt.Sprintf(":v%d", valueCounter)
+		key := valueKey(valueCounter)
 		valueCounter++
 		attrValues[key] = fmt.Sprintf(":%s", param)
 		*params = append(*params, param)
 		return key
 	}
 
-	if ast.FilterClause != nil {
-		expr, err := r.buildFilterExpression(ast.FilterClause, getName, getValue)
-		if err != nil {
-			return nil, err
+	literals := make(map[string]string)
+	getLiteral := func(value string) string {
+		if key, ok := literals[value]; ok {
+			return key
 		}
-		query["FilterExpression"] = expr
+		key := valueKey(valueCounter)
+		valueCounter++
+		literals[value] = key
+		attrValues[key] = value
+		return key
 	}
 
-	if len(attrNames) > 0 {
-		query["ExpressionAttributeNames"] = attrNames
-	}
-	if len(attrValues) > 0 {
-		query["ExpressionAttributeValues"] = attrValues
+	if ast.FilterClause != nil {
+		keys, rest := r.splitKeyConditions(ast.FilterClause)
+		if len(keys) > 0 {
+			exprs := make([]string, len(keys))
+			for i, k := range keys {
+				expr, err := r.buildFilterExpression(k, getName, getValue, getLiteral)
+				if err != nil {
+					return nil, err
+				}
+				exprs[i] = expr
+			}
+			query["KeyConditionExpression"] = strings.Join(exprs, " AND ")
+			// A Query's FilterExpression cannot reference key attributes.
+			if key := r.keyAttribute(rest); key != "" {
+				return nil, &types.UnsupportedError{
+					Provider: provider,
+					Features: []string{"key attribute '" + key + "' outside the key condition"},
+					Reason:   "a Query matches key attributes only in KeyConditionExpression: an equality on the partition key and one comparison on the sort key, ANDed at the top level of the filter",
+				}
+			}
+		}
+		if rest != nil {
+			expr, err := r.buildFilterExpression(rest, getName, getValue, getLiteral)
+			if err != nil {
+				return nil, err
+			}
+			query["FilterExpression"] = expr
+		}
 	}
 
-	if ast.Limit != nil {
+	// FindOne returns at most one document, so its limit is fixed at 1.
+	if ast.Operation == types.OpFindOne {
+		query["Limit"] = 1
+	} else if ast.Limit != nil {
 		if ast.Limit.Static != nil {
 			query["Limit"] = *ast.Limit.Static
 		} else if ast.Limit.Param != nil {
@@ -110,10 +218,20 @@ func (r *Renderer) renderQuery(ast *types.DocumentAST, params *[]string) (*types
 	}
 
 	if ast.Projection != nil {
+		if ast.Projection.Exclude || ast.Projection.ExcludesOnly() {
+			return nil, &types.UnsupportedError{
+				Provider: provider,
+				Features: []string{"projection exclusions: " + ast.Projection.ExcludedPaths()},
+				Reason:   "ProjectionExpression can only list attributes to include",
+			}
+		}
 		projExpr := ""
-		for i, f := range ast.Projection.Fields {
+		for _, f := range ast.Projection.Fields {
+			if f.Slice != nil || f.ElemMatch != nil {
+				return nil, types.UnsupportedFeature(provider, "array projection operators on field: "+f.Field.Path)
+			}
 			if f.Include {
-				if i > 0 {
+				if projExpr != "" {
 					projExpr += ", "
 				}
 				projExpr += getName(f.Field.Path)
@@ -124,7 +242,33 @@ func (r *Renderer) renderQuery(ast *types.DocumentAST, params *[]string) (*types
 		}
 	}
 
-	return toResult(query, *params)
+	if len(attrNames) > 0 {
+		query["ExpressionAttributeNames"] = attrNames
+	}
+	if len(attrValues) > 0 {
+		query["ExpressionAttributeValues"] = attrValues
+	}
+
+	var warnings []string
+	if ast.MaxTimeMS != nil {
+		warnings = append(warnings, "DynamoDB does not support server-side query timeouts: maxTimeMS ignored")
+	}
+	if _, ok := ast.SortOpts(); ok {
+		warnings = append(warnings, "DynamoDB has no collation: case-insensitive and numeric sort options ignored")
+	}
+	if _, filtered := query["FilterExpression"]; filtered && ast.Operation == types.OpFindOne {
+		warnings = append(warnings, "DynamoDB applies Limit before FilterExpression: a page of 1 may hold no match, follow LastEvaluatedKey until an item is returned")
+	}
+
+	result, err := toResult(query, *params)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = warnings
+	if ast.Projection != nil {
+		result.FieldAliases = ast.Projection.Aliases()
+	}
+	return result, nil
 }
 
 func (r *Renderer) renderPutItem(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
@@ -133,13 +277,21 @@ func (r *Renderer) renderPutItem(ast *types.DocumentAST, params *[]string) (*typ
 
 	if len(ast.Documents) > 0 {
 		item := make(map[string]interface{})
-		for field, value := range ast.Documents[0].Fields {
+		for _, field := range sortedFields(ast.Documents[0].Fields) {
+			value := ast.Documents[0].Fields[field]
 			*params = append(*params, value.Name)
 			item[field.Path] = fmt.Sprintf(":%s", value.Name)
 		}
 		query["Item"] = item
 	}
+	if ast.IfNotExists {
+		query["ConditionExpression"] = "attribute_not_exists(#pk)"
+		query["ExpressionAttributeNames"] = map[string]string{"#pk": r.PartitionKey}
+	}
 
+	if err := r.setWriteOptions(query, ast.Operation); err != nil {
+		return nil, err
+	}
 	return toResult(query, *params)
 }
 
@@ -160,7 +312,7 @@ func (r *Renderer) renderUpdateItem(ast *types.DocumentAST, params *[]string) (*
 	}
 
 	getValue := func(param string) string {
-		key := fmt.Sprintf(":v%d", valueCounter)
+		key := valueKey(valueCounter)
 		valueCounter++
 		attrValues[key] = fmt.Sprintf(":%s", param)
 		*params = append(*params, param)
@@ -173,7 +325,8 @@ func (r *Renderer) renderUpdateItem(ast *types.DocumentAST, params *[]string) (*
 	for _, op := range ast.UpdateOps {
 		switch op.Operator {
 		case types.Set, types.Inc:
-			for field, value := range op.Fields {
+			for _, field := range sortedFields(op.Fields) {
+				value := op.Fields[field]
 				nameKey := getName(field.Path)
 				valueKey := getValue(value.Name)
 				if op.Operator == types.Inc {
@@ -183,12 +336,12 @@ func (r *Renderer) renderUpdateItem(ast *types.DocumentAST, params *[]string) (*
 				}
 			}
 		case types.Unset:
-			for field := range op.Fields {
+			for _, field := range sortedFields(op.Fields) {
 				nameKey := getName(field.Path)
 				removeExprs = append(removeExprs, nameKey)
 			}
 		default:
-			return nil, fmt.Errorf("DynamoDB does not support update operator: %s", op.Operator)
+			return nil, types.UnsupportedFeature(provider, "update operator: "+string(op.Operator))
 		}
 	}
 
@@ -224,6 +377,9 @@ func (r *Renderer) renderUpdateItem(ast *types.DocumentAST, params *[]string) (*
 		query["ExpressionAttributeValues"] = attrValues
 	}
 
+	if err := r.setWriteOptions(query, ast.Operation); err != nil {
+		return nil, err
+	}
 	return toResult(query, *params)
 }
 
@@ -231,27 +387,123 @@ func (r *Renderer) renderDeleteItem(ast *types.DocumentAST, params *[]string) (*
 	query := make(map[string]interface{})
 	query["TableName"] = ast.Target.Name
 
+	if err := r.setWriteOptions(query, ast.Operation); err != nil {
+		return nil, err
+	}
 	return toResult(query, *params)
 }
 
-func (r *Renderer) buildFilterExpression(f types.FilterItem, getName func(string) string, getValue func(string) string) (string, error) {
+// setWriteOptions adds the configured ReturnValues and
+// ReturnConsumedCapacity to the request for a write operation.
+func (r *Renderer) setWriteOptions(query map[string]interface{}, op types.Operation) error {
+	if r.ReturnValues != "" {
+		if !slices.Contains(returnValues[op], r.ReturnValues) {
+			return &types.UnsupportedError{
+				Provider: provider,
+				Features: []string{"ReturnValues " + r.ReturnValues},
+				Reason:   fmt.Sprintf("%sItem accepts %s", transactActions[op], strings.Join(returnValues[op], ", ")),
+			}
+		}
+		query["ReturnValues"] = r.ReturnValues
+	}
+	return r.setConsumedCapacity(query)
+}
+
+func (r *Renderer) setConsumedCapacity(query map[string]interface{}) error {
+	if r.ReturnConsumedCapacity == "" {
+		return nil
+	}
+	if !slices.Contains(consumedCapacity, r.ReturnConsumedCapacity) {
+		return &types.UnsupportedError{
+			Provider: provider,
+			Features: []string{"ReturnConsumedCapacity " + r.ReturnConsumedCapacity},
+			Reason:   "accepts " + strings.Join(consumedCapacity, ", "),
+		}
+	}
+	query["ReturnConsumedCapacity"] = r.ReturnConsumedCapacity
+	return nil
+}
+
+// renderTransactWriteItems renders a transaction as a TransactWriteItems
+// request with one Put, Update, or Delete action per operation in order.
+func (r *Renderer) renderTransactWriteItems(ast *types.DocumentAST) (*types.QueryResult, error) {
+	if len(ast.Operations) > maxTransactItems {
+		return nil, &types.LimitExceededError{Limit: "TransactWriteItems", Value: len(ast.Operations), Max: maxTransactItems}
+	}
+	// Transaction items take neither option; capacity is reported for the
+	// request as a whole.
+	inner := *r
+	inner.ReturnValues = ""
+	inner.ReturnConsumedCapacity = ""
+	results, params, warnings, err := types.RenderOperations(ast, inner.Render)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]map[string]json.RawMessage, len(results))
+	for i, result := range results {
+		items[i] = map[string]json.RawMessage{transactActions[result.Operation]: json.RawMessage(result.JSON)}
+	}
+	query := map[string]interface{}{
+		"TransactItems": items,
+	}
+	if err := r.setConsumedCapacity(query); err != nil {
+		return nil, err
+	}
+	if r.ReturnValues != "" {
+		warnings = append(warnings, "DynamoDB TransactWriteItems does not return item values: ReturnValues ignored")
+	}
+
+	result, err := toResult(query, params)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = warnings
+	return result, nil
+}
+
+// buildFilterExpression renders f as a condition expression. getName and
+// getValue register attribute names and param values; getLiteral registers a
+// constant value, such as an attribute type, under its own placeholder.
+func (r *Renderer) buildFilterExpression(f types.FilterItem, getName, getValue, getLiteral func(string) string) (string, error) {
 	switch filter := f.(type) {
 	case types.FilterCondition:
 		nameKey := getName(filter.Field.Path)
 		valueKey := getValue(filter.Value.Name)
 		op := mapOperator(filter.Operator)
 		if op == "" {
-			return "", fmt.Errorf("DynamoDB does not support filter operator: %s", filter.Operator)
+			return "", types.UnsupportedFilter(provider, string(filter.Operator))
 		}
 		return fmt.Sprintf("%s %s %s", nameKey, op, valueKey), nil
 
+	case types.ValuesFilter:
+		nameKey := getName(filter.Field.Path)
+		valueKeys := make([]string, len(filter.Values))
+		for i, v := range filter.Values {
+			valueKeys[i] = getValue(v.Name)
+		}
+		expr := fmt.Sprintf("%s IN (%s)", nameKey, strings.Join(valueKeys, ", "))
+		if filter.Operator == types.NotIn {
+			expr = "NOT (" + expr + ")"
+		}
+		return expr, nil
+
 	case types.FilterGroup:
 		if len(filter.Conditions) == 0 {
 			return "", nil
 		}
+		if filter.Logic == types.OR {
+			if key := r.keyAttribute(filter); key != "" {
+				return "", &types.UnsupportedError{
+					Provider: provider,
+					Filters:  []string{string(filter.Logic)},
+					Reason:   fmt.Sprintf("key attribute '%s' cannot be used under $or; key conditions only support AND", key),
+				}
+			}
+		}
 		exprs := make([]string, 0, len(filter.Conditions))
 		for _, c := range filter.Conditions {
-			expr, err := r.buildFilterExpression(c, getName, getValue)
+			expr, err := r.buildFilterExpression(c, getName, getValue, getLiteral)
 			if err != nil {
 				return "", err
 			}
@@ -267,6 +519,37 @@ func (r *Renderer) buildFilterExpression(f types.FilterItem, getName func(string
 		}
 		return result, nil
 
+	case types.RangeFilter:
+		if filter.Negated {
+			positive := filter
+			positive.Negated = false
+			expr, err := r.buildFilterExpression(positive, getName, getValue, getLiteral)
+			if err != nil {
+				return "", err
+			}
+			return "NOT (" + expr + ")", nil
+		}
+		nameKey := getName(filter.Field.Path)
+		if filter.Min != nil && filter.Max != nil && !filter.MinExclusive && !filter.MaxExclusive {
+			return fmt.Sprintf("%s BETWEEN %s AND %s", nameKey, getValue(filter.Min.Name), getValue(filter.Max.Name)), nil
+		}
+		var exprs []string
+		if filter.Min != nil {
+			op := ">="
+			if filter.MinExclusive {
+				op = ">"
+			}
+			exprs = append(exprs, fmt.Sprintf("%s %s %s", nameKey, op, getValue(filter.Min.Name)))
+		}
+		if filter.Max != nil {
+			op := "<="
+			if filter.MaxExclusive {
+				op = "<"
+			}
+			exprs = append(exprs, fmt.Sprintf("%s %s %s", nameKey, op, getValue(filter.Max.Name)))
+		}
+		return strings.Join(exprs, " AND "), nil
+
 	case types.ExistsFilter:
 		nameKey := getName(filter.Field.Path)
 		if filter.Exists {
@@ -274,9 +557,103 @@ func (r *Renderer) buildFilterExpression(f types.FilterItem, getName func(string
 		}
 		return fmt.Sprintf("attribute_not_exists(%s)", nameKey), nil
 
+	case types.NullFilter:
+		// attribute_type is false for a missing attribute, so its negation
+		// matches one; a strict not-null match adds attribute_exists.
+		nameKey := getName(filter.Field.Path)
+		expr := fmt.Sprintf("attribute_type(%s, %s)", nameKey, getLiteral("NULL"))
+		switch {
+		case filter.Negated && filter.Strict:
+			return fmt.Sprintf("attribute_exists(%s) AND NOT %s", nameKey, expr), nil
+		case filter.Negated:
+			return "NOT " + expr, nil
+		default:
+			return expr, nil
+		}
+
 	default:
-		return "", fmt.Errorf("DynamoDB does not support filter type: %T", f)
+		return "", types.UnsupportedFilter(provider, types.FilterName(f))
+	}
+}
+
+// splitKeyConditions separates the conditions ANDed at the top level of f
+// that form a Query's key condition, an equality on the partition key and at
+// most one comparison on the sort key, from the rest of the filter. Without a
+// partition key equality it returns no key conditions, and the request is a
+// Scan.
+func (r *Renderer) splitKeyConditions(f types.FilterItem) (keys []types.FilterItem, rest types.FilterItem) {
+	conditions := []types.FilterItem{f}
+	if group, ok := f.(types.FilterGroup); ok && group.Logic == types.AND {
+		conditions = group.Conditions
+	}
+
+	pk := slices.IndexFunc(conditions, func(c types.FilterItem) bool {
+		cond, ok := c.(types.FilterCondition)
+		return ok && cond.Operator == types.EQ && cond.Field.Path == r.PartitionKey
+	})
+	if pk < 0 {
+		return nil, f
+	}
+	sk := -1
+	if r.SortKey != "" {
+		sk = slices.IndexFunc(conditions, r.isSortKeyCondition)
+	}
+
+	keys = []types.FilterItem{conditions[pk]}
+	if sk >= 0 {
+		keys = append(keys, conditions[sk])
+	}
+	var others []types.FilterItem
+	for i, c := range conditions {
+		if i != pk && i != sk {
+			others = append(others, c)
+		}
+	}
+	switch len(others) {
+	case 0:
+		return keys, nil
+	case 1:
+		return keys, others[0]
+	default:
+		return keys, types.FilterGroup{Logic: types.AND, Conditions: others}
+	}
+}
+
+// isSortKeyCondition reports whether c compares the sort key in a form
+// KeyConditionExpression accepts: =, <, <=, >, >=, or an inclusive BETWEEN.
+func (r *Renderer) isSortKeyCondition(c types.FilterItem) bool {
+	switch cond := c.(type) {
+	case types.FilterCondition:
+		return cond.Field.Path == r.SortKey && cond.Operator != types.NE && mapOperator(cond.Operator) != ""
+	case types.RangeFilter:
+		if cond.Field.Path != r.SortKey || cond.Negated {
+			return false
+		}
+		if cond.Min != nil && cond.Max != nil {
+			return !cond.MinExclusive && !cond.MaxExclusive
+		}
+		return true
 	}
+	return false
+}
+
+// keyAttribute returns the partition or sort key attribute f references, or
+// "" if it references neither. Conditions inside $elemMatch are relative to
+// the array element, so only the array field itself can be a key attribute.
+func (r *Renderer) keyAttribute(f types.FilterItem) string {
+	if group, ok := f.(types.FilterGroup); ok {
+		for _, c := range group.Conditions {
+			if key := r.keyAttribute(c); key != "" {
+				return key
+			}
+		}
+		return ""
+	}
+	field, ok := types.FilterField(f)
+	if ok && field.Path != "" && (field.Path == r.PartitionKey || field.Path == r.SortKey) {
+		return field.Path
+	}
+	return ""
 }
 
 func mapOperator(op types.FilterOperator) string {
@@ -301,7 +678,7 @@ func mapOperator(op types.FilterOperator) string {
 // SupportsOperation indicates if DynamoDB supports an operation.
 func (r *Renderer) SupportsOperation(op types.Operation) bool {
 	switch op {
-	case types.OpFind, types.OpFindOne, types.OpInsert, types.OpUpdate, types.OpDelete:
+	case types.OpFind, types.OpFindOne, types.OpInsert, types.OpUpdate, types.OpDelete, types.OpTransaction:
 		return true
 	default:
 		return false
@@ -333,6 +710,13 @@ func (r *Renderer) SupportsPipelineStage(stage string) bool {
 	return false
 }
 
+// valueKey returns the nth ExpressionAttributeValues placeholder, such as
+// ":0". Params render as ":" and their name, and a param name cannot begin
+// with a digit, so a generated placeholder never reads as a param.
+func valueKey(n int) string {
+	return ":" + strconv.Itoa(n)
+}
+
 func toResult(query map[string]interface{}, params []string) (*types.QueryResult, error) {
 	jsonBytes, err := json.Marshal(query)
 	if err != nil {
@@ -343,3 +727,9 @@ func toResult(query map[string]interface{}, params []string) (*types.QueryResult
 		RequiredParams: params,
 	}, nil
 }
+
+func sortedFields(fields map[types.Field]types.Param) []types.Field {
+	return slices.SortedFunc(maps.Keys(fields), func(a, b types.Field) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+}
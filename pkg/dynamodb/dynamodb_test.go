@@ -1,9 +1,12 @@
 package dynamodb
 
 import (
-	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 
+	"encoding/json"
 	"github.com/zoobzio/docql/internal/types"
 )
 
@@ -218,6 +221,9 @@ func TestSupportsFilter(t *testing.T) {
 			t.Errorf("expected DynamoDB to support filter %v", op)
 		}
 	}
+	if renderer.SupportsFilter(types.Mod) {
+		t.Error("expected DynamoDB to not support filter $mod")
+	}
 }
 
 func TestSupportsPipelineStage(t *testing.T) {
@@ -250,3 +256,728 @@ func TestRenderAggregate_NotSupported(t *testing.T) {
 		t.Error("expected error for unsupported Aggregate operation")
 	}
 }
+
+func TestRenderFind_WithMaxTimeMS(t *testing.T) {
+	maxTime := 1000
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		MaxTimeMS: &maxTime,
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Warnings) != 1 {
+		t.Errorf("expected 1 warning, got %v", result.Warnings)
+	}
+}
+
+func TestRenderFind_ProjectionWithoutID(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: types.IDField}, Include: false},
+			{Field: types.Field{Path: "email"}, Include: true},
+		}},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	if query["ProjectionExpression"] != "#n0" {
+		t.Errorf("expected ProjectionExpression #n0, got %v", query["ProjectionExpression"])
+	}
+}
+
+func TestRenderFind_RejectsExcludeProjection(t *testing.T) {
+	tests := []struct {
+		name       string
+		projection *types.Projection
+		want       string
+	}{
+		{"exclude", &types.Projection{Exclude: true, Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "password"}},
+			{Field: types.Field{Path: "token"}},
+		}}, "projection exclusions: password, token"},
+		{"only _id excluded", &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: types.IDField}, Include: false},
+		}}, "projection exclusions: _id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:  types.OpFind,
+				Target:     types.Collection{Name: "users"},
+				Projection: tt.projection,
+			}
+			_, err := New().Render(ast)
+			var unsupported *types.UnsupportedError
+			if !errors.As(err, &unsupported) {
+				t.Fatalf("expected *UnsupportedError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error should name the excluded fields, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestRenderFind_InValues(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.ValuesFilter{
+			Field:    types.Field{Path: "status"},
+			Operator: types.NotIn,
+			Values:   []types.Param{{Name: "a"}, {Name: "b"}},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if query["FilterExpression"] != "NOT (#n0 IN (:0, :1))" {
+		t.Errorf("unexpected FilterExpression: %v", query["FilterExpression"])
+	}
+	values := query["ExpressionAttributeValues"].(map[string]interface{})
+	if values[":0"] != ":a" || values[":1"] != ":b" {
+		t.Errorf("unexpected ExpressionAttributeValues: %v", values)
+	}
+	if len(result.RequiredParams) != 2 || result.RequiredParams[0] != "a" || result.RequiredParams[1] != "b" {
+		t.Errorf("expected params [a b], got %v", result.RequiredParams)
+	}
+}
+
+func TestRenderFind_KeyCondition(t *testing.T) {
+	pk := types.FilterCondition{Field: types.Field{Path: "pk"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+	sk := types.FilterCondition{Field: types.Field{Path: "sk"}, Operator: types.GT, Value: types.Param{Name: "after"}}
+	status := types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "status"}}
+
+	result, err := New().WithSortKey("sk").Render(&types.DocumentAST{
+		Operation:    types.OpFind,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{status, sk, pk}},
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if query["KeyConditionExpression"] != "#n0 = :0 AND #n1 > :1" || query["FilterExpression"] != "#n2 = :2" {
+		t.Errorf("unexpected key and filter expressions: %v", query)
+	}
+	names := query["ExpressionAttributeNames"].(map[string]interface{})
+	if names["#n0"] != "pk" || names["#n1"] != "sk" || names["#n2"] != "status" {
+		t.Errorf("unexpected ExpressionAttributeNames: %v", names)
+	}
+
+	result, err = New().Render(&types.DocumentAST{Operation: types.OpFind, Target: types.Collection{Name: "users"}, FilterClause: pk})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if strings.Contains(result.JSON, "FilterExpression") || !strings.Contains(result.JSON, "KeyConditionExpression") {
+		t.Errorf("expected only a key condition, got %s", result.JSON)
+	}
+}
+
+func TestRenderFind_KeyAttributeOutsideKeyCondition(t *testing.T) {
+	renderer := New().WithSortKey("sk")
+	pk := types.FilterCondition{Field: types.Field{Path: "pk"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+	status := types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "status"}}
+
+	tests := []struct {
+		name   string
+		key    string
+		filter types.FilterItem
+	}{
+		{"sort key under $or", "sk", types.FilterGroup{Logic: types.OR, Conditions: []types.FilterItem{
+			types.FilterCondition{Field: types.Field{Path: "sk"}, Operator: types.EQ, Value: types.Param{Name: "a"}},
+			status,
+		}}},
+		{"sort key $ne", "sk", types.FilterCondition{Field: types.Field{Path: "sk"}, Operator: types.NE, Value: types.Param{Name: "a"}}},
+		{"sort key $regex", "sk", types.RegexFilter{Field: types.Field{Path: "sk"}, Pattern: types.Param{Name: "p"}}},
+		{"partition key $in", "pk", types.ValuesFilter{Field: types.Field{Path: "pk"}, Operator: types.IN, Values: []types.Param{{Name: "ids"}}}},
+		{"sort key $elemMatch", "sk", types.ElemMatchFilter{Field: types.Field{Path: "sk"}, Conditions: []types.FilterItem{status}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := renderer.Render(&types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{pk, tt.filter}},
+			})
+			var unsupported *types.UnsupportedError
+			if !errors.As(err, &unsupported) {
+				t.Fatalf("expected unsupported error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "key attribute '"+tt.key+"'") {
+				t.Errorf("error should mention the key restriction, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestRenderFind_OrOnKeyAttribute(t *testing.T) {
+	for _, key := range []string{"pk", "sk"} {
+		ast := &types.DocumentAST{
+			Operation: types.OpFind,
+			Target:    types.Collection{Name: "users"},
+			FilterClause: types.FilterGroup{
+				Logic: types.OR,
+				Conditions: []types.FilterItem{
+					types.FilterCondition{Field: types.Field{Path: key}, Operator: types.EQ, Value: types.Param{Name: "key"}},
+					types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "status"}},
+				},
+			},
+		}
+
+		_, err := New().WithSortKey("sk").Render(ast)
+		if !errors.Is(err, types.ErrUnsupportedFilter) {
+			t.Fatalf("%s: expected unsupported filter error, got %v", key, err)
+		}
+		if !strings.Contains(err.Error(), "key attribute '"+key+"'") || !strings.Contains(err.Error(), "key conditions only support AND") {
+			t.Errorf("%s: expected error to mention the key restriction, got %v", key, err)
+		}
+	}
+}
+
+func TestRenderFind_OrOnNonKeyAttributes(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{
+			Logic: types.OR,
+			Conditions: []types.FilterItem{
+				types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "a"}},
+				types.FilterCondition{Field: types.Field{Path: "role"}, Operator: types.EQ, Value: types.Param{Name: "b"}},
+			},
+		},
+	}
+
+	if _, err := New().Render(ast); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRenderFind_Range(t *testing.T) {
+	lo, hi := types.Param{Name: "lo"}, types.Param{Name: "hi"}
+	tests := []struct {
+		name   string
+		filter types.RangeFilter
+		want   string
+	}{
+		{"inclusive", types.RangeFilter{Field: types.Field{Path: "age"}, Min: &lo, Max: &hi}, "#n0 BETWEEN :0 AND :1"},
+		{"exclusive", types.RangeFilter{Field: types.Field{Path: "age"}, Min: &lo, MinExclusive: true, Max: &hi}, "#n0 > :0 AND #n0 <= :1"},
+		{"upper only", types.RangeFilter{Field: types.Field{Path: "age"}, Max: &hi, MaxExclusive: true}, "#n0 < :0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := New().Render(&types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: tt.filter,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var query map[string]interface{}
+			if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if query["FilterExpression"] != tt.want {
+				t.Errorf("FilterExpression = %v, want %s", query["FilterExpression"], tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderFind_ProjectionAliases(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "name"}, Include: true},
+			{Field: types.Field{Path: "createdAt"}, Include: true, Alias: "created_at"},
+		}},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.FieldAliases) != 1 || result.FieldAliases["createdAt"] != "created_at" {
+		t.Errorf("expected createdAt -> created_at alias, got %v", result.FieldAliases)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	names := query["ExpressionAttributeNames"].(map[string]interface{})
+	if query["ProjectionExpression"] != "#n0, #n1" || names["#n1"] != "createdAt" {
+		t.Errorf("expected source fields in projection, got %v %v", query["ProjectionExpression"], names)
+	}
+}
+
+func TestRenderFind_RejectsArrayProjectionOperators(t *testing.T) {
+	tests := []struct {
+		name  string
+		field types.ProjectionField
+	}{
+		{
+			name: "slice",
+			field: types.ProjectionField{
+				Field:   types.Field{Path: "comments"},
+				Include: true,
+				Slice:   &types.SliceOp{Count: types.Param{Name: "n"}},
+			},
+		},
+		{
+			name: "elemMatch",
+			field: types.ProjectionField{
+				Field:   types.Field{Path: "scores"},
+				Include: true,
+				ElemMatch: &types.ElemMatchProjection{Conditions: []types.FilterItem{
+					types.FilterCondition{Field: types.Field{Path: "value"}, Operator: types.GT, Value: types.Param{Name: "min"}},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:  types.OpFind,
+				Target:     types.Collection{Name: "users"},
+				Projection: &types.Projection{Fields: []types.ProjectionField{tt.field}},
+			}
+
+			_, err := New().Render(ast)
+			if !errors.Is(err, types.ErrUnsupportedOperation) {
+				t.Fatalf("expected unsupported error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "array projection operators on field: "+tt.field.Field.Path) {
+				t.Errorf("error should name the field, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestRenderInsert_IfNotExists(t *testing.T) {
+	insert := func(ifNotExists bool) *types.DocumentAST {
+		return &types.DocumentAST{
+			Operation:   types.OpInsert,
+			Target:      types.Collection{Name: "users"},
+			Documents:   []types.Document{{Fields: map[types.Field]types.Param{{Path: "userId"}: {Name: "id"}}}},
+			IfNotExists: ifNotExists,
+		}
+	}
+
+	result, err := New().Render(insert(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result.JSON, "ConditionExpression") {
+		t.Errorf("expected no condition by default, got %s", result.JSON)
+	}
+
+	result, err = New().WithPartitionKey("userId").Render(insert(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"ConditionExpression":"attribute_not_exists(#pk)","ExpressionAttributeNames":{"#pk":"userId"},"Item":{"userId":":id"},"TableName":"users"}`
+	if result.JSON != expected {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, expected)
+	}
+
+	update := &types.DocumentAST{Operation: types.OpUpdate, Target: types.Collection{Name: "users"}, IfNotExists: true}
+	if _, err := New().Render(update); !errors.Is(err, types.ErrInvalidAST) {
+		t.Errorf("expected IfNotExists on an update to be rejected, got %v", err)
+	}
+}
+
+func TestRender_ParamNamesCannotCollide(t *testing.T) {
+	// Params named like generated placeholders stay distinct from them.
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+			types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "v0"}},
+			types.FilterCondition{Field: types.Field{Path: "name"}, Operator: types.EQ, Value: types.Param{Name: "n1"}},
+			types.NullFilter{Field: types.Field{Path: "deletedAt"}},
+		}},
+	}
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var query struct {
+		FilterExpression          string
+		ExpressionAttributeValues map[string]string
+	}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if query.FilterExpression != "(#n0 = :0) AND (#n1 = :1) AND (attribute_type(#n2, :2))" {
+		t.Errorf("unexpected FilterExpression: %s", query.FilterExpression)
+	}
+	for key, value := range query.ExpressionAttributeValues {
+		if _, ok := query.ExpressionAttributeValues[value]; ok {
+			t.Errorf("value %s of %s is also a placeholder", value, key)
+		}
+	}
+	if !slices.Equal(result.RequiredParams, []string{"v0", "n1"}) {
+		t.Errorf("unexpected params: %v", result.RequiredParams)
+	}
+
+	// A param name in the placeholder form is not an identifier.
+	ast.FilterClause = types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "0"}}
+	if _, err := New().Render(ast); !errors.Is(err, types.ErrInvalidAST) {
+		t.Errorf("expected param name '0' to be rejected, got %v", err)
+	}
+}
+
+func TestRenderTransaction(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpTransaction,
+		Operations: []*types.DocumentAST{
+			{
+				Operation: types.OpInsert,
+				Target:    types.Collection{Name: "orders"},
+				Documents: []types.Document{{Fields: map[types.Field]types.Param{{Path: "userId"}: {Name: "id"}}}},
+			},
+			{
+				Operation: types.OpUpdate,
+				Target:    types.Collection{Name: "users"},
+				UpdateOps: []types.UpdateOperation{{Operator: types.Inc, Fields: map[types.Field]types.Param{{Path: "orders"}: {Name: "one"}}}},
+			},
+			{Operation: types.OpDelete, Target: types.Collection{Name: "carts"}},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"TransactItems":[` +
+		`{"Put":{"Item":{"userId":":id"},"TableName":"orders"}},` +
+		`{"Update":{"ExpressionAttributeNames":{"#n0":"orders"},"ExpressionAttributeValues":{":0":":one"},"TableName":"users","UpdateExpression":"SET #n0 = #n0 + :0"}},` +
+		`{"Delete":{"TableName":"carts"}}]}`
+	if result.JSON != expected {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, expected)
+	}
+	if len(result.RequiredParams) != 2 || result.RequiredParams[0] != "id" || result.RequiredParams[1] != "one" {
+		t.Errorf("unexpected params: %v", result.RequiredParams)
+	}
+}
+
+func TestRenderTransaction_Writes(t *testing.T) {
+	put := &types.DocumentAST{
+		Operation: types.OpInsert,
+		Target:    types.Collection{Name: "orders"},
+		Documents: []types.Document{{Fields: map[types.Field]types.Param{{Path: "status"}: {Name: "status"}}}},
+	}
+	update := func(table string) *types.DocumentAST {
+		return &types.DocumentAST{
+			Operation: types.OpUpdate,
+			Target:    types.Collection{Name: table},
+			UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{{Path: "status"}: {Name: "status"}}}},
+		}
+	}
+
+	result, err := New().RenderTransaction(put, update("users"), update("carts"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Operation != types.OpTransaction {
+		t.Errorf("expected TRANSACTION, got %s", result.Operation)
+	}
+
+	var query struct {
+		TransactItems []map[string]struct {
+			TableName                 string
+			ExpressionAttributeNames  map[string]string
+			ExpressionAttributeValues map[string]string
+		}
+	}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if len(query.TransactItems) != 3 {
+		t.Fatalf("expected 3 items, got %s", result.JSON)
+	}
+	if _, ok := query.TransactItems[0]["Put"]; !ok {
+		t.Errorf("expected a Put first, got %s", result.JSON)
+	}
+	// Each action scopes its own placeholders, and both bind the one param.
+	for i, table := range []string{"users", "carts"} {
+		item, ok := query.TransactItems[i+1]["Update"]
+		if !ok || item.TableName != table {
+			t.Fatalf("expected an Update of %s, got %s", table, result.JSON)
+		}
+		if item.ExpressionAttributeNames["#n0"] != "status" || item.ExpressionAttributeValues[":0"] != ":status" {
+			t.Errorf("unexpected placeholders in %s: %+v", table, item)
+		}
+	}
+	if !slices.Equal(result.RequiredParams, []string{"status"}) {
+		t.Errorf("expected the shared param once, got %v", result.RequiredParams)
+	}
+
+	var many []*types.DocumentAST
+	for range maxTransactItems + 1 {
+		many = append(many, update("users"))
+	}
+	if _, err := New().RenderTransaction(many...); !errors.Is(err, types.ErrLimitExceeded) {
+		t.Errorf("expected limit exceeded error, got %v", err)
+	}
+	if _, err := New().RenderTransaction(&types.DocumentAST{Operation: types.OpFind, Target: types.Collection{Name: "users"}}); err == nil {
+		t.Error("expected a read in a transaction to be rejected")
+	}
+}
+
+func TestRender_WriteOptions(t *testing.T) {
+	update := &types.DocumentAST{
+		Operation: types.OpUpdate,
+		Target:    types.Collection{Name: "users"},
+		UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{{Path: "status"}: {Name: "status"}}}},
+	}
+
+	result, err := New().Render(update)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result.JSON, "ReturnValues") || strings.Contains(result.JSON, "ReturnConsumedCapacity") {
+		t.Errorf("expected no return options by default, got %s", result.JSON)
+	}
+
+	r := New().WithReturnValues("ALL_NEW").WithReturnConsumedCapacity("TOTAL")
+	result, err = r.Render(update)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if query["ReturnValues"] != "ALL_NEW" || query["ReturnConsumedCapacity"] != "TOTAL" {
+		t.Errorf("expected return options, got %s", result.JSON)
+	}
+
+	// PutItem and DeleteItem return only the old item.
+	del := &types.DocumentAST{Operation: types.OpDelete, Target: types.Collection{Name: "users"}}
+	if _, err := r.Render(del); !errors.Is(err, types.ErrUnsupportedOperation) {
+		t.Errorf("expected ALL_NEW on delete to be rejected, got %v", err)
+	}
+	result, err = New().WithReturnValues("ALL_OLD").Render(del)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result.JSON, `"ReturnValues":"ALL_OLD"`) {
+		t.Errorf("expected ReturnValues on delete, got %s", result.JSON)
+	}
+
+	if _, err := New().WithReturnConsumedCapacity("ALL").Render(update); err == nil {
+		t.Error("expected unknown ReturnConsumedCapacity to be rejected")
+	}
+}
+
+func TestRender_WriteOptionsTransaction(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpTransaction,
+		Operations: []*types.DocumentAST{
+			{Operation: types.OpDelete, Target: types.Collection{Name: "carts"}},
+		},
+	}
+
+	result, err := New().WithReturnValues("ALL_OLD").WithReturnConsumedCapacity("INDEXES").Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"ReturnConsumedCapacity":"INDEXES","TransactItems":[{"Delete":{"TableName":"carts"}}]}`
+	if result.JSON != expected {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, expected)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "ReturnValues ignored") {
+		t.Errorf("expected ReturnValues warning, got %v", result.Warnings)
+	}
+}
+
+func TestRenderTransaction_TooManyItems(t *testing.T) {
+	ast := &types.DocumentAST{Operation: types.OpTransaction}
+	for range maxTransactItems + 1 {
+		ast.Operations = append(ast.Operations, &types.DocumentAST{Operation: types.OpDelete, Target: types.Collection{Name: "carts"}})
+	}
+
+	_, err := New().Render(ast)
+	if !errors.Is(err, types.ErrLimitExceeded) {
+		t.Errorf("expected limit exceeded error, got %v", err)
+	}
+}
+
+func TestRenderFind_Exists(t *testing.T) {
+	tests := []struct {
+		exists bool
+		want   string
+	}{
+		{true, "attribute_exists(#n0)"},
+		{false, "attribute_not_exists(#n0)"},
+	}
+
+	for _, tt := range tests {
+		ast := &types.DocumentAST{
+			Operation:    types.OpFind,
+			Target:       types.Collection{Name: "users"},
+			FilterClause: types.ExistsFilter{Field: types.Field{Path: "email"}, Exists: tt.exists},
+		}
+
+		result, err := New().Render(ast)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var query map[string]interface{}
+		if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		if query["FilterExpression"] != tt.want {
+			t.Errorf("expected FilterExpression %s, got %v", tt.want, query["FilterExpression"])
+		}
+	}
+}
+
+func TestRenderFindOne_LimitsToOne(t *testing.T) {
+	for op, want := range map[types.Operation]interface{}{
+		types.OpFindOne: float64(1),
+		types.OpFind:    nil,
+	} {
+		ast := &types.DocumentAST{
+			Operation: op,
+			Target:    types.Collection{Name: "users"},
+		}
+
+		result, err := New().Render(ast)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", op, err)
+		}
+		if result.Single != (op == types.OpFindOne) {
+			t.Errorf("%s: unexpected Single %v", op, result.Single)
+		}
+
+		var query map[string]interface{}
+		if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+			t.Fatalf("%s: failed to parse JSON: %v", op, err)
+		}
+		if query["Limit"] != want {
+			t.Errorf("%s: expected Limit %v, got %v", op, want, query["Limit"])
+		}
+	}
+}
+
+func TestRender_EmptyParams(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	email := types.Field{Path: "email"}
+	age := types.Field{Path: "age"}
+	byKey := types.FilterCondition{Field: types.Field{Path: "pk"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+
+	tests := []struct {
+		name    string
+		ast     *types.DocumentAST
+		context string
+	}{
+		{
+			name:    "filter",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.FilterCondition{Field: email, Operator: types.EQ}},
+			context: "$eq on field 'email'",
+		},
+		{
+			name:    "range bound",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.RangeFilter{Field: age, Min: &types.Param{}}},
+			context: "range on field 'age'",
+		},
+		{
+			name:    "document",
+			ast:     &types.DocumentAST{Operation: types.OpInsert, Target: users, Documents: []types.Document{{Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "document field 'email'",
+		},
+		{
+			name: "update",
+			ast: &types.DocumentAST{Operation: types.OpUpdate, Target: users, FilterClause: byKey,
+				UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "$set field 'email'",
+		},
+		{
+			name:    "pagination",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: byKey, Limit: &types.PaginationValue{Param: &types.Param{}}},
+			context: "limit",
+		},
+		{
+			name: "pipeline expression",
+			ast: &types.DocumentAST{Operation: types.OpAggregate, Target: users,
+				Pipeline: []types.PipelineStage{types.AddFieldsStage{Fields: map[string]types.Expression{"flag": types.LiteralExpression{}}}}},
+			context: "$addFields field 'flag'",
+		},
+	}
+
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Render(tt.ast)
+			if !errors.Is(err, types.ErrInvalidIdentifier) {
+				t.Fatalf("expected invalid identifier error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.context) {
+				t.Errorf("expected error naming %q, got %v", tt.context, err)
+			}
+		})
+	}
+}
+
+func TestRender_ValuelessUpdateOperators(t *testing.T) {
+	r := New()
+	for _, op := range []types.UpdateOperator{types.Unset, types.CurrentDate} {
+		t.Run(string(op), func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:    types.OpUpdate,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: types.FilterCondition{Field: types.Field{Path: "pk"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+				UpdateOps:    []types.UpdateOperation{{Operator: op, Fields: map[types.Field]types.Param{{Path: "deletedAt"}: {}}}},
+			}
+			result, err := r.Render(ast)
+			if !r.SupportsUpdate(op) {
+				var unsupported *types.UnsupportedError
+				if !errors.As(err, &unsupported) {
+					t.Errorf("expected unsupported operator error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if slices.Contains(result.RequiredParams, "") {
+				t.Errorf("unexpected empty param in %v", result.RequiredParams)
+			}
+		})
+	}
+}
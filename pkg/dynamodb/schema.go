@@ -0,0 +1,111 @@
+package dynamodb
+
+// OutputSchema returns a JSON Schema (draft 2020-12) describing the JSON that
+// Render produces: the parameters of a Query, Scan, PutItem, UpdateItem,
+// DeleteItem, or TransactWriteItems request. Requests carry no operation key;
+// the QueryResult's Operation says which API to call, and a FIND or FIND_ONE
+// is a Query when it has a KeyConditionExpression and a Scan otherwise.
+func OutputSchema() string {
+	return outputSchema
+}
+
+const outputSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "docql DynamoDB request",
+  "anyOf": [
+    {"$ref": "#/$defs/read"},
+    {"$ref": "#/$defs/put"},
+    {"$ref": "#/$defs/update"},
+    {"$ref": "#/$defs/delete"},
+    {"$ref": "#/$defs/transactWrite"}
+  ],
+  "$defs": {
+    "read": {
+      "description": "FIND and FIND_ONE: a Query with KeyConditionExpression, otherwise a Scan.",
+      "type": "object",
+      "properties": {
+        "TableName": {"type": "string"},
+        "KeyConditionExpression": {"type": "string"},
+        "FilterExpression": {"type": "string"},
+        "ProjectionExpression": {"type": "string"},
+        "Limit": {"type": ["integer", "string"], "description": "A static count, or a :param placeholder."},
+        "ExpressionAttributeNames": {"$ref": "#/$defs/names"},
+        "ExpressionAttributeValues": {"$ref": "#/$defs/values"}
+      },
+      "required": ["TableName"],
+      "additionalProperties": false
+    },
+    "put": {
+      "description": "INSERT.",
+      "type": "object",
+      "properties": {
+        "TableName": {"type": "string"},
+        "Item": {"type": "object", "additionalProperties": {"type": "string"}},
+        "ConditionExpression": {"const": "attribute_not_exists(#pk)"},
+        "ExpressionAttributeNames": {"$ref": "#/$defs/names"},
+        "ReturnValues": {"type": "string"},
+        "ReturnConsumedCapacity": {"type": "string"}
+      },
+      "required": ["TableName", "Item"],
+      "additionalProperties": false
+    },
+    "update": {
+      "description": "UPDATE.",
+      "type": "object",
+      "properties": {
+        "TableName": {"type": "string"},
+        "UpdateExpression": {"type": "string"},
+        "ExpressionAttributeNames": {"$ref": "#/$defs/names"},
+        "ExpressionAttributeValues": {"$ref": "#/$defs/values"},
+        "ReturnValues": {"type": "string"},
+        "ReturnConsumedCapacity": {"type": "string"}
+      },
+      "required": ["TableName", "UpdateExpression"],
+      "additionalProperties": false
+    },
+    "delete": {
+      "description": "DELETE.",
+      "type": "object",
+      "properties": {
+        "TableName": {"type": "string"},
+        "ReturnValues": {"type": "string"},
+        "ReturnConsumedCapacity": {"type": "string"}
+      },
+      "required": ["TableName"],
+      "additionalProperties": false
+    },
+    "transactWrite": {
+      "description": "TRANSACTION: one Put, Update, or Delete action per write, in order.",
+      "type": "object",
+      "properties": {
+        "TransactItems": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "properties": {
+              "Put": {"$ref": "#/$defs/put"},
+              "Update": {"$ref": "#/$defs/update"},
+              "Delete": {"$ref": "#/$defs/delete"}
+            },
+            "minProperties": 1,
+            "maxProperties": 1,
+            "additionalProperties": false
+          }
+        },
+        "ReturnConsumedCapacity": {"type": "string"}
+      },
+      "required": ["TransactItems"],
+      "additionalProperties": false
+    },
+    "names": {
+      "type": "object",
+      "description": "#n placeholders to attribute paths.",
+      "additionalProperties": {"type": "string"}
+    },
+    "values": {
+      "type": "object",
+      "description": ":0, :1, ... placeholders to :param references or literal attribute types.",
+      "additionalProperties": {"type": "string"}
+    }
+  }
+}`
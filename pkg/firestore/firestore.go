@@ -4,6 +4,8 @@ package firestore
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/zoobzio/docql/internal/types"
 )
@@ -11,6 +13,9 @@ import (
 // Renderer renders DocumentAST to Firestore query format.
 type Renderer struct{}
 
+// provider names Firestore in UnsupportedError values.
+const provider = "firestore"
+
 // New creates a new Firestore renderer.
 func New() *Renderer {
 	return &Renderer{}
@@ -19,43 +24,82 @@ func New() *Renderer {
 // Render converts a DocumentAST to Firestore query format.
 func (r *Renderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
 	if err := ast.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid AST: %w", err)
+		return nil, &types.InvalidASTError{Err: err}
+	}
+
+	if ast.ParameterizedTarget() {
+		return nil, types.UnsupportedFeature(provider, "parameterized collection")
 	}
 
 	if !r.SupportsOperation(ast.Operation) {
-		return nil, fmt.Errorf("firestore does not support operation: %s", ast.Operation)
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
 	}
 
 	var params []string
+	var result *types.QueryResult
+	var err error
 
 	switch ast.Operation {
 	case types.OpFind, types.OpFindOne:
-		return r.renderQuery(ast, &params)
+		result, err = r.renderQuery(ast, &params)
 	case types.OpInsert:
-		return r.renderAdd(ast, &params)
+		result, err = r.renderAdd(ast, &params)
 	case types.OpUpdate:
-		return r.renderUpdate(ast, &params)
+		result, err = r.renderUpdate(ast, &params)
 	case types.OpDelete:
-		return r.renderDelete(ast, &params)
+		result, err = r.renderDelete(ast, &params)
+	case types.OpTransaction:
+		result, err = r.renderBatch(ast)
 	default:
-		return nil, fmt.Errorf("unsupported operation: %s", ast.Operation)
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
+	}
+	if err != nil {
+		return nil, types.WithOperation(err, ast.Operation)
 	}
+	result.Operation = ast.Operation
+	result.Single = ast.Operation == types.OpFindOne
+	result.Collection = ast.Target.Name
+	result.Database = ast.Target.Database
+	return result, nil
+}
+
+// newQuery returns a query map naming the target collection and, when the
+// schema names one, its database.
+func newQuery(ast *types.DocumentAST) map[string]interface{} {
+	query := map[string]interface{}{"collection": ast.Target.Name}
+	if ast.Target.Database != "" {
+		query["database"] = ast.Target.Database
+	}
+	return query
 }
 
 func (r *Renderer) renderQuery(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
-	query["collection"] = ast.Target.Name
+	query := newQuery(ast)
 	query["operation"] = string(ast.Operation)
 
+	var warnings []string
+	var inequalityField string
+
 	if ast.FilterClause != nil {
 		wheres, err := r.buildWheres(ast.FilterClause, params)
 		if err != nil {
 			return nil, err
 		}
+		inequalityField, err = singleInequalityField(wheres)
+		if err != nil {
+			return nil, err
+		}
 		query["where"] = wheres
 	}
 
 	if len(ast.SortClauses) > 0 {
+		if first := ast.SortClauses[0].Field.Path; inequalityField != "" && first != inequalityField {
+			return nil, &types.UnsupportedError{
+				Provider: provider,
+				Features: []string{fmt.Sprintf("orderBy %s before inequality field %s", first, inequalityField)},
+				Reason:   "the first orderBy must be the field with range or inequality filters",
+			}
+		}
 		orderBy := make([]map[string]interface{}, len(ast.SortClauses))
 		for i, s := range ast.SortClauses {
 			direction := "asc"
@@ -68,9 +112,17 @@ func (r *Renderer) renderQuery(ast *types.DocumentAST, params *[]string) (*types
 			}
 		}
 		query["orderBy"] = orderBy
+	} else if inequalityField != "" {
+		query["orderBy"] = []map[string]interface{}{
+			{"field": inequalityField, "direction": "asc"},
+		}
+		warnings = append(warnings, fmt.Sprintf("firestore requires ordering by the inequality field: added orderBy %s asc", inequalityField))
 	}
 
-	if ast.Limit != nil {
+	// FindOne returns at most one document, so its limit is fixed at 1.
+	if ast.Operation == types.OpFindOne {
+		query["limit"] = 1
+	} else if ast.Limit != nil {
 		if ast.Limit.Static != nil {
 			query["limit"] = *ast.Limit.Static
 		} else if ast.Limit.Param != nil {
@@ -89,8 +141,18 @@ func (r *Renderer) renderQuery(ast *types.DocumentAST, params *[]string) (*types
 	}
 
 	if ast.Projection != nil {
+		if ast.Projection.Exclude {
+			return nil, &types.UnsupportedError{
+				Provider: provider,
+				Features: []string{"projection exclusions: " + ast.Projection.ExcludedPaths()},
+				Reason:   "select can only list fields to include",
+			}
+		}
 		fields := make([]string, 0)
 		for _, f := range ast.Projection.Fields {
+			if f.Slice != nil || f.ElemMatch != nil {
+				return nil, types.UnsupportedFeature(provider, "array projection operators on field: "+f.Field.Path)
+			}
 			if f.Include {
 				fields = append(fields, f.Field.Path)
 			}
@@ -100,12 +162,26 @@ func (r *Renderer) renderQuery(ast *types.DocumentAST, params *[]string) (*types
 		}
 	}
 
-	return toResult(query, *params)
+	if ast.MaxTimeMS != nil {
+		warnings = append(warnings, "firestore does not support server-side query timeouts: maxTimeMS ignored")
+	}
+	if _, ok := ast.SortOpts(); ok {
+		warnings = append(warnings, "firestore has no collation: case-insensitive and numeric sort options ignored")
+	}
+
+	result, err := toResult(query, *params)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = warnings
+	if ast.Projection != nil {
+		result.FieldAliases = ast.Projection.Aliases()
+	}
+	return result, nil
 }
 
 func (r *Renderer) renderAdd(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
-	query["collection"] = ast.Target.Name
+	query := newQuery(ast)
 	query["operation"] = string(ast.Operation)
 
 	if len(ast.Documents) > 0 {
@@ -121,14 +197,13 @@ func (r *Renderer) renderAdd(ast *types.DocumentAST, params *[]string) (*types.Q
 }
 
 func (r *Renderer) renderUpdate(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
-	query["collection"] = ast.Target.Name
+	query := newQuery(ast)
 	query["operation"] = string(ast.Operation)
 
 	data := make(map[string]interface{})
 	for _, op := range ast.UpdateOps {
 		if op.Operator != types.Set && op.Operator != types.Unset {
-			return nil, fmt.Errorf("firestore does not support update operator: %s", op.Operator)
+			return nil, types.UnsupportedFeature(provider, "update operator: "+string(op.Operator))
 		}
 		for field, value := range op.Fields {
 			if op.Operator == types.Unset {
@@ -145,13 +220,50 @@ func (r *Renderer) renderUpdate(ast *types.DocumentAST, params *[]string) (*type
 }
 
 func (r *Renderer) renderDelete(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
-	query["collection"] = ast.Target.Name
+	query := newQuery(ast)
 	query["operation"] = string(ast.Operation)
 
 	return toResult(query, *params)
 }
 
+// batchWrites names the batch entry for each write Firestore renders.
+var batchWrites = map[types.Operation]string{
+	types.OpInsert: "set",
+	types.OpUpdate: "update",
+	types.OpDelete: "delete",
+}
+
+// renderBatch renders a transaction as a batched write, with one set,
+// update, or delete entry per operation in order.
+func (r *Renderer) renderBatch(ast *types.DocumentAST) (*types.QueryResult, error) {
+	results, params, warnings, err := types.RenderOperations(ast, r.Render)
+	if err != nil {
+		return nil, err
+	}
+
+	writes := make([]map[string]interface{}, len(results))
+	for i, result := range results {
+		write := batchWrites[result.Operation]
+		// A batched create fails when the document exists, where set
+		// overwrites it.
+		if ast.Operations[i].IfNotExists {
+			write = "create"
+		}
+		writes[i] = map[string]interface{}{write: json.RawMessage(result.JSON)}
+	}
+	query := map[string]interface{}{
+		"operation": string(ast.Operation),
+		"writes":    writes,
+	}
+
+	result, err := toResult(query, params)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = warnings
+	return result, nil
+}
+
 func (r *Renderer) buildWheres(f types.FilterItem, params *[]string) ([]map[string]interface{}, error) {
 	var wheres []map[string]interface{}
 
@@ -168,9 +280,29 @@ func (r *Renderer) buildWheres(f types.FilterItem, params *[]string) ([]map[stri
 			"value":    fmt.Sprintf(":%s", filter.Value.Name),
 		})
 
+	case types.ValuesFilter:
+		op, err := mapOperator(filter.Operator)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, len(filter.Values))
+		for i, v := range filter.Values {
+			*params = append(*params, v.Name)
+			values[i] = fmt.Sprintf(":%s", v.Name)
+		}
+		wheres = append(wheres, map[string]interface{}{
+			"field":    filter.Field.Path,
+			"operator": op,
+			"value":    values,
+		})
+
 	case types.FilterGroup:
 		if filter.Logic != types.AND {
-			return nil, fmt.Errorf("firestore only supports AND logic in compound queries")
+			return nil, &types.UnsupportedError{
+				Provider: provider,
+				Filters:  []string{string(filter.Logic)},
+				Reason:   "only AND logic is supported in compound queries",
+			}
 		}
 		for _, c := range filter.Conditions {
 			childWheres, err := r.buildWheres(c, params)
@@ -181,6 +313,9 @@ func (r *Renderer) buildWheres(f types.FilterItem, params *[]string) ([]map[stri
 		}
 
 	case types.RangeFilter:
+		if filter.Negated {
+			return nil, types.UnsupportedFilter(provider, "negated range")
+		}
 		if filter.Min != nil {
 			*params = append(*params, filter.Min.Name)
 			op := ">="
@@ -206,13 +341,82 @@ func (r *Renderer) buildWheres(f types.FilterItem, params *[]string) ([]map[stri
 			})
 		}
 
+	case types.ExistsFilter:
+		// Firestore has no existence operator. "!= null" matches documents
+		// where the field is present, except those that store an explicit
+		// null, and counts as an inequality. Absence cannot be queried at all.
+		if !filter.Exists {
+			return nil, &types.UnsupportedError{
+				Provider: provider,
+				Filters:  []string{types.FilterName(f)},
+				Reason:   "firestore cannot match documents missing a field",
+			}
+		}
+		wheres = append(wheres, map[string]interface{}{
+			"field":    filter.Field.Path,
+			"operator": "!=",
+			"value":    nil,
+		})
+
+	case types.NullFilter:
+		// Firestore skips documents missing the field for both operators,
+		// so both forms are already strict. "!= null" is an inequality.
+		op := "=="
+		if filter.Negated {
+			op = "!="
+		}
+		wheres = append(wheres, map[string]interface{}{
+			"field":    filter.Field.Path,
+			"operator": op,
+			"value":    nil,
+		})
+
 	default:
-		return nil, fmt.Errorf("firestore does not support filter type: %T", f)
+		return nil, types.UnsupportedFilter(provider, types.FilterName(f))
 	}
 
 	return wheres, nil
 }
 
+// singleInequalityField returns the one field carrying range or inequality
+// filters (>, >=, <, <=, !=, not-in), or an error listing every such field
+// with its operators when there is more than one.
+func singleInequalityField(wheres []map[string]interface{}) (string, error) {
+	var fields []string
+	ops := make(map[string][]string)
+	for _, w := range wheres {
+		op, _ := w["operator"].(string)
+		switch op {
+		case ">", ">=", "<", "<=", "!=", "not-in":
+		default:
+			continue
+		}
+		field, _ := w["field"].(string)
+		if _, ok := ops[field]; !ok {
+			fields = append(fields, field)
+		}
+		if !slices.Contains(ops[field], op) {
+			ops[field] = append(ops[field], op)
+		}
+	}
+	switch len(fields) {
+	case 0:
+		return "", nil
+	case 1:
+		return fields[0], nil
+	default:
+		found := make([]string, len(fields))
+		for i, field := range fields {
+			found[i] = fmt.Sprintf("%s (%s)", field, strings.Join(ops[field], " "))
+		}
+		return "", &types.UnsupportedError{
+			Provider: provider,
+			Features: []string{"inequality filters on multiple fields: " + strings.Join(found, ", ")},
+			Reason:   "range and inequality filters must all apply to a single field",
+		}
+	}
+}
+
 func mapOperator(op types.FilterOperator) (string, error) {
 	switch op {
 	case types.EQ:
@@ -234,21 +438,23 @@ func mapOperator(op types.FilterOperator) (string, error) {
 	case types.All:
 		return "array-contains", nil
 	default:
-		return "", fmt.Errorf("firestore does not support filter operator: %s", op)
+		return "", types.UnsupportedFilter(provider, string(op))
 	}
 }
 
 // SupportsOperation indicates if Firestore supports an operation.
 func (r *Renderer) SupportsOperation(op types.Operation) bool {
 	switch op {
-	case types.OpFind, types.OpFindOne, types.OpInsert, types.OpUpdate, types.OpDelete:
+	case types.OpFind, types.OpFindOne, types.OpInsert, types.OpUpdate, types.OpDelete, types.OpTransaction:
 		return true
 	default:
 		return false
 	}
 }
 
-// SupportsFilter indicates if Firestore supports a filter operator.
+// SupportsFilter indicates if Firestore supports a filter operator. Exists is
+// reported unsupported: Exists(field) renders as a "!= null" approximation,
+// but NotExists cannot be rendered.
 func (r *Renderer) SupportsFilter(op types.FilterOperator) bool {
 	switch op {
 	case types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE, types.IN, types.NotIn, types.All:
@@ -1,9 +1,12 @@
 package firestore
 
 import (
-	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 
+	"encoding/json"
 	"github.com/zoobzio/docql/internal/types"
 )
 
@@ -309,7 +312,7 @@ func TestSupportsFilter(t *testing.T) {
 	}
 
 	unsupported := []types.FilterOperator{
-		types.Regex, types.Text,
+		types.Regex, types.Text, types.Mod,
 	}
 
 	for _, op := range unsupported {
@@ -386,3 +389,515 @@ func TestRenderFind_WithANDFilter(t *testing.T) {
 		t.Errorf("expected 2 params, got %d", len(result.RequiredParams))
 	}
 }
+
+func TestRenderFind_WithMaxTimeMS(t *testing.T) {
+	maxTime := 1000
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		MaxTimeMS: &maxTime,
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Warnings) != 1 {
+		t.Errorf("expected 1 warning, got %v", result.Warnings)
+	}
+}
+
+func TestRenderQuery_MultipleInequalityFields(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{
+			Logic: types.AND,
+			Conditions: []types.FilterItem{
+				types.FilterCondition{Field: types.Field{Path: "age"}, Operator: types.GT, Value: types.Param{Name: "minAge"}},
+				types.FilterCondition{Field: types.Field{Path: "score"}, Operator: types.LT, Value: types.Param{Name: "maxScore"}},
+			},
+		},
+	}
+
+	_, err := New().Render(ast)
+	if err == nil {
+		t.Fatal("expected error for inequality filters on two fields")
+	}
+	if !strings.Contains(err.Error(), "age (>), score (<)") {
+		t.Errorf("expected error to list conflicting fields, got: %v", err)
+	}
+	var unsupported *types.UnsupportedError
+	if !errors.As(err, &unsupported) {
+		t.Errorf("expected UnsupportedError, got %T", err)
+	}
+}
+
+func TestRenderQuery_InequalityAcrossFilterKinds(t *testing.T) {
+	minAge := types.Param{Name: "minAge"}
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{
+			Logic: types.AND,
+			Conditions: []types.FilterItem{
+				types.RangeFilter{Field: types.Field{Path: "age"}, Min: &minAge},
+				types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.NE, Value: types.Param{Name: "status"}},
+			},
+		},
+	}
+
+	_, err := New().Render(ast)
+	if err == nil || !strings.Contains(err.Error(), "age (>=), status (!=)") {
+		t.Errorf("expected range and != on different fields to be rejected, got: %v", err)
+	}
+}
+
+func TestRenderQuery_RangeOnSingleField(t *testing.T) {
+	minAge := types.Param{Name: "minAge"}
+	maxAge := types.Param{Name: "maxAge"}
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{
+			Logic: types.AND,
+			Conditions: []types.FilterItem{
+				types.RangeFilter{Field: types.Field{Path: "age"}, Min: &minAge, Max: &maxAge},
+				types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "status"}},
+			},
+		},
+		SortClauses: []types.SortClause{{Field: types.Field{Path: "age"}, Order: types.Descending}},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", result.Warnings)
+	}
+}
+
+func TestRenderQuery_InequalityOrderByMismatch(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{
+			Field: types.Field{Path: "age"}, Operator: types.GTE, Value: types.Param{Name: "minAge"},
+		},
+		SortClauses: []types.SortClause{{Field: types.Field{Path: "name"}, Order: types.Ascending}},
+	}
+
+	_, err := New().Render(ast)
+	if err == nil {
+		t.Fatal("expected error when first orderBy is not the inequality field")
+	}
+	if !strings.Contains(err.Error(), "orderBy name before inequality field age") {
+		t.Errorf("expected error naming both fields, got: %v", err)
+	}
+
+	// The inequality field must come first, not merely appear.
+	ast.SortClauses = append(ast.SortClauses, types.SortClause{Field: types.Field{Path: "age"}, Order: types.Ascending})
+	if _, err := New().Render(ast); err == nil {
+		t.Error("expected error when the inequality field is not the first orderBy")
+	}
+
+	ast.SortClauses = []types.SortClause{
+		{Field: types.Field{Path: "age"}, Order: types.Ascending},
+		{Field: types.Field{Path: "name"}, Order: types.Ascending},
+	}
+	if _, err := New().Render(ast); err != nil {
+		t.Errorf("unexpected error with the inequality field ordered first: %v", err)
+	}
+}
+
+func TestRenderQuery_InequalityInjectsOrderBy(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{
+			Field: types.Field{Path: "status"}, Operator: types.NE, Value: types.Param{Name: "status"},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	orderBy, ok := query["orderBy"].([]interface{})
+	if !ok || len(orderBy) != 1 {
+		t.Fatalf("expected injected orderBy, got %v", query["orderBy"])
+	}
+	if orderBy[0].(map[string]interface{})["field"] != "status" {
+		t.Errorf("expected orderBy on status, got %v", orderBy[0])
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("expected 1 warning, got %v", result.Warnings)
+	}
+}
+
+func TestRenderFind_InValues(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.ValuesFilter{
+			Field:    types.Field{Path: "status"},
+			Operator: types.IN,
+			Values:   []types.Param{{Name: "a"}, {Name: "b"}},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query struct {
+		Where []struct {
+			Field    string   `json:"field"`
+			Operator string   `json:"operator"`
+			Value    []string `json:"value"`
+		} `json:"where"`
+	}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	w := query.Where[0]
+	if w.Field != "status" || w.Operator != "in" || strings.Join(w.Value, ",") != ":a,:b" {
+		t.Errorf("unexpected where: %+v", w)
+	}
+	if strings.Join(result.RequiredParams, ",") != "a,b" {
+		t.Errorf("expected params [a b], got %v", result.RequiredParams)
+	}
+}
+
+func TestRenderFind_ProjectionAliases(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "name"}, Include: true},
+			{Field: types.Field{Path: "createdAt"}, Include: true, Alias: "created_at"},
+		}},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.FieldAliases) != 1 || result.FieldAliases["createdAt"] != "created_at" {
+		t.Errorf("expected createdAt -> created_at alias, got %v", result.FieldAliases)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	fields, ok := query["select"].([]interface{})
+	if !ok || len(fields) != 2 || fields[0] != "name" || fields[1] != "createdAt" {
+		t.Errorf("expected source fields [name createdAt], got %v", query["select"])
+	}
+}
+
+func TestRenderFind_RejectsExcludeProjection(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Exclude: true, Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "password"}},
+			{Field: types.Field{Path: "token"}},
+		}},
+	}
+
+	_, err := New().Render(ast)
+	var unsupported *types.UnsupportedError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("expected *UnsupportedError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "projection exclusions: password, token") {
+		t.Errorf("error should name the excluded fields, got %q", err.Error())
+	}
+}
+
+func TestRenderFind_RejectsArrayProjectionOperators(t *testing.T) {
+	tests := []struct {
+		name  string
+		field types.ProjectionField
+	}{
+		{
+			name: "slice",
+			field: types.ProjectionField{
+				Field:   types.Field{Path: "comments"},
+				Include: true,
+				Slice:   &types.SliceOp{Count: types.Param{Name: "n"}},
+			},
+		},
+		{
+			name: "elemMatch",
+			field: types.ProjectionField{
+				Field:   types.Field{Path: "scores"},
+				Include: true,
+				ElemMatch: &types.ElemMatchProjection{Conditions: []types.FilterItem{
+					types.FilterCondition{Field: types.Field{Path: "value"}, Operator: types.GT, Value: types.Param{Name: "min"}},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:  types.OpFind,
+				Target:     types.Collection{Name: "users"},
+				Projection: &types.Projection{Fields: []types.ProjectionField{tt.field}},
+			}
+
+			_, err := New().Render(ast)
+			if !errors.Is(err, types.ErrUnsupportedOperation) {
+				t.Fatalf("expected unsupported error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "array projection operators on field: "+tt.field.Field.Path) {
+				t.Errorf("error should name the field, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestRenderTransaction(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpTransaction,
+		Operations: []*types.DocumentAST{
+			{
+				Operation: types.OpInsert,
+				Target:    types.Collection{Name: "orders"},
+				Documents: []types.Document{{Fields: map[types.Field]types.Param{{Path: "userId"}: {Name: "id"}}}},
+			},
+			{
+				Operation: types.OpUpdate,
+				Target:    types.Collection{Name: "users"},
+				UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{{Path: "status"}: {Name: "status"}}}},
+			},
+			{Operation: types.OpDelete, Target: types.Collection{Name: "carts"}},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query struct {
+		Operation string                       `json:"operation"`
+		Writes    []map[string]json.RawMessage `json:"writes"`
+	}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if query.Operation != "TRANSACTION" || len(query.Writes) != 3 {
+		t.Fatalf("unexpected batch: %s", result.JSON)
+	}
+	for i, name := range []string{"set", "update", "delete"} {
+		if _, ok := query.Writes[i][name]; !ok {
+			t.Errorf("write %d: expected %s entry, got %v", i, name, query.Writes[i])
+		}
+	}
+	if !strings.Contains(result.JSON, `"set":{"collection":"orders","data":{"userId":":id"},"operation":"INSERT"}`) {
+		t.Errorf("expected set entry rendered through the insert path, got %s", result.JSON)
+	}
+}
+
+func TestRenderTransaction_IfNotExists(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpTransaction,
+		Operations: []*types.DocumentAST{{
+			Operation:   types.OpInsert,
+			Target:      types.Collection{Name: "orders"},
+			Documents:   []types.Document{{Fields: map[types.Field]types.Param{{Path: "userId"}: {Name: "id"}}}},
+			IfNotExists: true,
+		}},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result.JSON, `"writes":[{"create":{`) {
+		t.Errorf("expected a create entry, got %s", result.JSON)
+	}
+}
+
+func TestRenderTransaction_UnsupportedOperation(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpTransaction,
+		Operations: []*types.DocumentAST{{
+			Operation:    types.OpDeleteMany,
+			Target:       types.Collection{Name: "users"},
+			FilterClause: types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "status"}},
+		}},
+	}
+
+	_, err := New().Render(ast)
+	if !errors.Is(err, types.ErrUnsupportedOperation) {
+		t.Errorf("expected unsupported operation error, got %v", err)
+	}
+}
+
+func TestRenderFind_Exists(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation:    types.OpFind,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.ExistsFilter{Field: types.Field{Path: "email"}, Exists: true},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	// Exists approximates to "!= null", an inequality that forces an orderBy.
+	want := `{"collection":"users","operation":"FIND","orderBy":[{"direction":"asc","field":"email"}],"where":[{"field":"email","operator":"!=","value":null}]}`
+	if result.JSON != want {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, want)
+	}
+	if len(result.RequiredParams) != 0 {
+		t.Errorf("expected no params, got %v", result.RequiredParams)
+	}
+}
+
+func TestRenderFind_NotExists_NotSupported(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation:    types.OpFind,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.ExistsFilter{Field: types.Field{Path: "deletedAt"}, Exists: false},
+	}
+
+	_, err := New().Render(ast)
+	if !errors.Is(err, types.ErrUnsupportedFilter) {
+		t.Fatalf("expected unsupported filter error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "missing a field") {
+		t.Errorf("expected error to explain the limitation, got: %v", err)
+	}
+}
+
+func TestRenderFindOne_LimitsToOne(t *testing.T) {
+	for op, want := range map[types.Operation]interface{}{
+		types.OpFindOne: float64(1),
+		types.OpFind:    nil,
+	} {
+		ast := &types.DocumentAST{
+			Operation: op,
+			Target:    types.Collection{Name: "users"},
+		}
+
+		result, err := New().Render(ast)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", op, err)
+		}
+		if result.Single != (op == types.OpFindOne) {
+			t.Errorf("%s: unexpected Single %v", op, result.Single)
+		}
+
+		var query map[string]interface{}
+		if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+			t.Fatalf("%s: failed to parse JSON: %v", op, err)
+		}
+		if query["limit"] != want {
+			t.Errorf("%s: expected limit %v, got %v", op, want, query["limit"])
+		}
+	}
+}
+
+func TestRender_EmptyParams(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	email := types.Field{Path: "email"}
+	age := types.Field{Path: "age"}
+	byKey := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+
+	tests := []struct {
+		name    string
+		ast     *types.DocumentAST
+		context string
+	}{
+		{
+			name:    "filter",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.FilterCondition{Field: email, Operator: types.EQ}},
+			context: "$eq on field 'email'",
+		},
+		{
+			name:    "range bound",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.RangeFilter{Field: age, Min: &types.Param{}}},
+			context: "range on field 'age'",
+		},
+		{
+			name:    "document",
+			ast:     &types.DocumentAST{Operation: types.OpInsert, Target: users, Documents: []types.Document{{Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "document field 'email'",
+		},
+		{
+			name: "update",
+			ast: &types.DocumentAST{Operation: types.OpUpdate, Target: users, FilterClause: byKey,
+				UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "$set field 'email'",
+		},
+		{
+			name:    "pagination",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: byKey, Limit: &types.PaginationValue{Param: &types.Param{}}},
+			context: "limit",
+		},
+		{
+			name: "pipeline expression",
+			ast: &types.DocumentAST{Operation: types.OpAggregate, Target: users,
+				Pipeline: []types.PipelineStage{types.AddFieldsStage{Fields: map[string]types.Expression{"flag": types.LiteralExpression{}}}}},
+			context: "$addFields field 'flag'",
+		},
+	}
+
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Render(tt.ast)
+			if !errors.Is(err, types.ErrInvalidIdentifier) {
+				t.Fatalf("expected invalid identifier error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.context) {
+				t.Errorf("expected error naming %q, got %v", tt.context, err)
+			}
+		})
+	}
+}
+
+func TestRender_ValuelessUpdateOperators(t *testing.T) {
+	r := New()
+	for _, op := range []types.UpdateOperator{types.Unset, types.CurrentDate} {
+		t.Run(string(op), func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:    types.OpUpdate,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+				UpdateOps:    []types.UpdateOperation{{Operator: op, Fields: map[types.Field]types.Param{{Path: "deletedAt"}: {}}}},
+			}
+			result, err := r.Render(ast)
+			if !r.SupportsUpdate(op) {
+				var unsupported *types.UnsupportedError
+				if !errors.As(err, &unsupported) {
+					t.Errorf("expected unsupported operator error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if slices.Contains(result.RequiredParams, "") {
+				t.Errorf("unexpected empty param in %v", result.RequiredParams)
+			}
+		})
+	}
+}
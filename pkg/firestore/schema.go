@@ -0,0 +1,96 @@
+package firestore
+
+// OutputSchema returns a JSON Schema (draft 2020-12) describing the JSON that
+// Render produces. Every query names its operation; the keys each operation
+// requires and allows are listed in the oneOf branches.
+func OutputSchema() string {
+	return outputSchema
+}
+
+const outputSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "docql Firestore query",
+  "type": "object",
+  "properties": {
+    "operation": {"enum": ["FIND", "FIND_ONE", "INSERT", "UPDATE", "DELETE", "TRANSACTION"]},
+    "collection": {"type": "string"},
+    "database": {"type": "string"},
+    "where": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "field": {"type": "string"},
+          "operator": {"type": "string"},
+          "value": {
+            "type": ["string", "array", "null"],
+            "items": {"type": "string"},
+            "description": "A :param placeholder, a list of them for in and array operators, or null."
+          }
+        },
+        "required": ["field", "operator", "value"],
+        "additionalProperties": false
+      }
+    },
+    "orderBy": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "field": {"type": "string"},
+          "direction": {"enum": ["asc", "desc"]}
+        },
+        "required": ["field", "direction"],
+        "additionalProperties": false
+      }
+    },
+    "limit": {"$ref": "#/$defs/pagination"},
+    "offset": {"$ref": "#/$defs/pagination"},
+    "select": {"type": "array", "items": {"type": "string"}},
+    "data": {
+      "type": "object",
+      "description": "Field paths to :param placeholders, or FieldValue.delete() for $unset.",
+      "additionalProperties": {"type": "string"}
+    },
+    "writes": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "set": {"$ref": "#"},
+          "create": {"$ref": "#"},
+          "update": {"$ref": "#"},
+          "delete": {"$ref": "#"}
+        },
+        "minProperties": 1,
+        "maxProperties": 1,
+        "additionalProperties": false
+      }
+    }
+  },
+  "oneOf": [
+    {
+      "properties": {"operation": {"enum": ["FIND", "FIND_ONE"]}},
+      "required": ["operation", "collection"],
+      "propertyNames": {"enum": ["operation", "collection", "database", "where", "orderBy", "limit", "offset", "select"]}
+    },
+    {
+      "properties": {"operation": {"enum": ["INSERT", "UPDATE"]}},
+      "required": ["operation", "collection", "data"],
+      "propertyNames": {"enum": ["operation", "collection", "database", "data"]}
+    },
+    {
+      "properties": {"operation": {"const": "DELETE"}},
+      "required": ["operation", "collection"],
+      "propertyNames": {"enum": ["operation", "collection", "database"]}
+    },
+    {
+      "properties": {"operation": {"const": "TRANSACTION"}},
+      "required": ["operation", "writes"],
+      "propertyNames": {"enum": ["operation", "writes"]}
+    }
+  ],
+  "$defs": {
+    "pagination": {"type": ["integer", "string"], "description": "A static count, or a :param placeholder."}
+  }
+}`
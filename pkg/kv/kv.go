@@ -0,0 +1,252 @@
+// Package kv provides a key-value store renderer for DOCQL.
+//
+// Key-value stores address documents only by key, so the renderer accepts a
+// narrow slice of the AST: Find or FindOne with a single equality filter on
+// _id renders a get descriptor, and Insert renders a put descriptor keyed by
+// the document's _id. Everything else fails with a *types.UnsupportedError
+// listing every unsupported feature of the query, not just the first.
+package kv
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// provider names the key-value renderer in UnsupportedError values.
+const provider = "kv"
+
+// Renderer renders DocumentAST to key-value get/put descriptors.
+type Renderer struct{}
+
+// New creates a new key-value renderer.
+func New() *Renderer {
+	return &Renderer{}
+}
+
+// unsupported accumulates the AST features a query uses that a key-value
+// store cannot express, in first-seen order without duplicates.
+type unsupported struct {
+	filters  []string
+	features []string
+}
+
+func (u *unsupported) filter(name string) {
+	if !slices.Contains(u.filters, name) {
+		u.filters = append(u.filters, name)
+	}
+}
+
+func (u *unsupported) feature(name string) {
+	if !slices.Contains(u.features, name) {
+		u.features = append(u.features, name)
+	}
+}
+
+func (u *unsupported) err(op types.Operation) error {
+	if len(u.filters) == 0 && len(u.features) == 0 {
+		return nil
+	}
+	return &types.UnsupportedError{
+		Provider:  provider,
+		Operation: op,
+		Filters:   u.filters,
+		Features:  u.features,
+		Reason:    "only get by _id and put are supported",
+	}
+}
+
+// Render converts a DocumentAST to a key-value descriptor.
+func (r *Renderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
+	if err := ast.Validate(); err != nil {
+		return nil, &types.InvalidASTError{Err: err}
+	}
+
+	if ast.ParameterizedTarget() {
+		return nil, types.UnsupportedFeature(provider, "parameterized collection")
+	}
+
+	if !r.SupportsOperation(ast.Operation) {
+		return nil, &types.UnsupportedError{
+			Provider:  provider,
+			Operation: ast.Operation,
+			Reason:    "only get by _id and put are supported",
+		}
+	}
+
+	var result *types.QueryResult
+	var err error
+
+	switch ast.Operation {
+	case types.OpFind, types.OpFindOne:
+		result, err = r.renderGet(ast)
+	case types.OpInsert:
+		result, err = r.renderPut(ast)
+	default:
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Operation = ast.Operation
+	result.Single = ast.Operation == types.OpFindOne
+	result.Collection = ast.Target.Name
+	result.Database = ast.Target.Database
+	return result, nil
+}
+
+func (r *Renderer) renderGet(ast *types.DocumentAST) (*types.QueryResult, error) {
+	u := &unsupported{}
+	var key *types.Param
+
+	switch filter := ast.FilterClause.(type) {
+	case nil:
+		u.feature("find without _id lookup")
+	case types.FilterCondition:
+		if filter.Operator == types.EQ && filter.Field.Path == types.IDField {
+			key = &filter.Value
+		} else {
+			collectFilter(filter, u)
+		}
+	default:
+		collectFilter(filter, u)
+	}
+
+	if ast.Projection != nil && len(ast.Projection.Fields) > 0 {
+		u.feature("projection")
+	}
+	if len(ast.SortClauses) > 0 {
+		u.feature("sort")
+	}
+	if ast.Skip != nil {
+		u.feature("skip")
+	}
+	if err := u.err(ast.Operation); err != nil {
+		return nil, err
+	}
+
+	cmd := map[string]interface{}{
+		"op":         "get",
+		"collection": ast.Target.Name,
+		"key":        ":" + key.Name,
+	}
+	result, err := toResult(cmd, []string{key.Name})
+	if err != nil {
+		return nil, err
+	}
+	if ast.MaxTimeMS != nil {
+		result.Warnings = append(result.Warnings, "kv does not support per-request timeouts: maxTimeMS ignored")
+	}
+	return result, nil
+}
+
+// collectFilter records every part of f that prevents a key lookup: operators
+// other than equality, equality on fields other than _id, and logic groups.
+func collectFilter(f types.FilterItem, u *unsupported) {
+	switch filter := f.(type) {
+	case types.FilterCondition:
+		if filter.Operator != types.EQ {
+			u.filter(string(filter.Operator))
+		} else if filter.Field.Path != types.IDField {
+			u.feature("lookup on non-key field: " + filter.Field.Path)
+		}
+	case types.FilterGroup:
+		u.filter(string(filter.Logic))
+		for _, cond := range filter.Conditions {
+			collectFilter(cond, u)
+		}
+	default:
+		u.filter(types.FilterName(f))
+	}
+}
+
+func (r *Renderer) renderPut(ast *types.DocumentAST) (*types.QueryResult, error) {
+	if len(ast.Documents) == 0 {
+		return nil, fmt.Errorf("insert requires a document")
+	}
+	// A put always overwrites, so it cannot honor IfNotExists.
+	if ast.IfNotExists {
+		return nil, types.UnsupportedFeature(provider, "conditional put")
+	}
+	doc := ast.Documents[0]
+
+	fields := make([]types.Field, 0, len(doc.Fields))
+	for field := range doc.Fields {
+		fields = append(fields, field)
+	}
+	slices.SortFunc(fields, func(a, b types.Field) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	idx := slices.IndexFunc(fields, func(f types.Field) bool {
+		return f.Path == types.IDField
+	})
+	if idx < 0 {
+		return nil, &types.UnsupportedError{
+			Provider:  provider,
+			Operation: ast.Operation,
+			Features:  []string{"put without _id field"},
+			Reason:    "documents are stored under their _id",
+		}
+	}
+
+	key := doc.Fields[fields[idx]]
+
+	params := []string{key.Name}
+	value := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		p := doc.Fields[field]
+		if field.Path != types.IDField {
+			params = append(params, p.Name)
+		}
+		value[field.Path] = ":" + p.Name
+	}
+
+	cmd := map[string]interface{}{
+		"op":         "put",
+		"collection": ast.Target.Name,
+		"key":        ":" + key.Name,
+		"value":      value,
+	}
+	return toResult(cmd, params)
+}
+
+// SupportsOperation indicates if the key-value renderer supports an operation.
+func (r *Renderer) SupportsOperation(op types.Operation) bool {
+	switch op {
+	case types.OpFind, types.OpFindOne, types.OpInsert:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsFilter indicates if the key-value renderer supports a filter operator.
+// Only equality on _id is renderable.
+func (r *Renderer) SupportsFilter(op types.FilterOperator) bool {
+	return op == types.EQ
+}
+
+// SupportsUpdate indicates if the key-value renderer supports an update operator.
+func (r *Renderer) SupportsUpdate(op types.UpdateOperator) bool {
+	return false
+}
+
+// SupportsPipelineStage indicates if the key-value renderer supports a pipeline stage.
+func (r *Renderer) SupportsPipelineStage(stage string) bool {
+	return false
+}
+
+func toResult(cmd map[string]interface{}, params []string) (*types.QueryResult, error) {
+	jsonBytes, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize query: %w", err)
+	}
+	return &types.QueryResult{
+		JSON:           string(jsonBytes),
+		RequiredParams: params,
+	}, nil
+}
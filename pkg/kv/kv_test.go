@@ -0,0 +1,235 @@
+package kv
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"encoding/json"
+	"github.com/zoobzio/docql/internal/types"
+)
+
+func intPtr(i int) *int { return &i }
+
+func render(t *testing.T, ast *types.DocumentAST) (map[string]interface{}, *types.QueryResult) {
+	t.Helper()
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var cmd map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &cmd); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	return cmd, result
+}
+
+func unsupportedErr(t *testing.T, ast *types.DocumentAST) *types.UnsupportedError {
+	t.Helper()
+	_, err := New().Render(ast)
+	var unsupported *types.UnsupportedError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("expected *UnsupportedError, got %T: %v", err, err)
+	}
+	return unsupported
+}
+
+func TestRenderGet(t *testing.T) {
+	for _, op := range []types.Operation{types.OpFind, types.OpFindOne} {
+		ast := &types.DocumentAST{
+			Operation:    op,
+			Target:       types.Collection{Name: "users"},
+			FilterClause: types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+		}
+		cmd, result := render(t, ast)
+		if cmd["op"] != "get" || cmd["collection"] != "users" || cmd["key"] != ":id" {
+			t.Errorf("%s: unexpected command: %v", op, cmd)
+		}
+		if !slices.Equal(result.RequiredParams, []string{"id"}) {
+			t.Errorf("%s: RequiredParams = %v", op, result.RequiredParams)
+		}
+	}
+}
+
+func TestRenderGet_MaxTimeMSWarning(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation:    types.OpFindOne,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+		MaxTimeMS:    intPtr(100),
+	}
+	_, result := render(t, ast)
+	if len(result.Warnings) != 1 {
+		t.Errorf("expected maxTimeMS warning, got %v", result.Warnings)
+	}
+}
+
+func TestRenderPut(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpInsert,
+		Target:    types.Collection{Name: "users"},
+		Documents: []types.Document{{Fields: map[types.Field]types.Param{
+			{Path: "_id"}:   {Name: "id"},
+			{Path: "name"}:  {Name: "name"},
+			{Path: "email"}: {Name: "email"},
+		}}},
+	}
+	cmd, result := render(t, ast)
+	if cmd["op"] != "put" || cmd["collection"] != "users" || cmd["key"] != ":id" {
+		t.Errorf("unexpected command: %v", cmd)
+	}
+	value := cmd["value"].(map[string]interface{})
+	if value["_id"] != ":id" || value["name"] != ":name" || value["email"] != ":email" {
+		t.Errorf("unexpected value: %v", value)
+	}
+	if !slices.Equal(result.RequiredParams, []string{"id", "email", "name"}) {
+		t.Errorf("RequiredParams = %v", result.RequiredParams)
+	}
+}
+
+func TestRenderPut_MissingID(t *testing.T) {
+	err := unsupportedErr(t, &types.DocumentAST{
+		Operation: types.OpInsert,
+		Target:    types.Collection{Name: "users"},
+		Documents: []types.Document{{Fields: map[types.Field]types.Param{
+			{Path: "name"}: {Name: "name"},
+		}}},
+	})
+	if !slices.Equal(err.Features, []string{"put without _id field"}) {
+		t.Errorf("Features = %v", err.Features)
+	}
+}
+
+func TestRenderPut_IfNotExists(t *testing.T) {
+	err := unsupportedErr(t, &types.DocumentAST{
+		Operation:   types.OpInsert,
+		Target:      types.Collection{Name: "users"},
+		Documents:   []types.Document{{Fields: map[types.Field]types.Param{{Path: "_id"}: {Name: "id"}}}},
+		IfNotExists: true,
+	})
+	if !slices.Equal(err.Features, []string{"conditional put"}) {
+		t.Errorf("Features = %v", err.Features)
+	}
+}
+
+func TestRender_UnsupportedOperation(t *testing.T) {
+	err := unsupportedErr(t, &types.DocumentAST{
+		Operation:    types.OpDelete,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+	})
+	if err.Provider != "kv" || err.Operation != types.OpDelete {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
+func TestRender_ListsAllUnsupportedFeatures(t *testing.T) {
+	err := unsupportedErr(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+			types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+			types.FilterCondition{Field: types.Field{Path: "age"}, Operator: types.GT, Value: types.Param{Name: "age"}},
+			types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "status"}},
+			types.RegexFilter{Field: types.Field{Path: "name"}, Pattern: types.Param{Name: "pattern"}},
+			types.FilterCondition{Field: types.Field{Path: "score"}, Operator: types.GT, Value: types.Param{Name: "score"}},
+		}},
+		SortClauses: []types.SortClause{{Field: types.Field{Path: "age"}, Order: types.Ascending}},
+		Skip:        &types.PaginationValue{Static: intPtr(10)},
+	})
+
+	if err.Operation != types.OpFind {
+		t.Errorf("Operation = %q", err.Operation)
+	}
+	if want := []string{"$and", "$gt", "$regex"}; !slices.Equal(err.Filters, want) {
+		t.Errorf("Filters = %v, want %v", err.Filters, want)
+	}
+	if want := []string{"lookup on non-key field: status", "sort", "skip"}; !slices.Equal(err.Features, want) {
+		t.Errorf("Features = %v, want %v", err.Features, want)
+	}
+}
+
+func TestRender_NoFilter(t *testing.T) {
+	err := unsupportedErr(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+	})
+	if !slices.Equal(err.Features, []string{"find without _id lookup"}) {
+		t.Errorf("Features = %v", err.Features)
+	}
+}
+
+func TestRender_InValuesUnsupported(t *testing.T) {
+	err := unsupportedErr(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.ValuesFilter{
+			Field:    types.Field{Path: "_id"},
+			Operator: types.IN,
+			Values:   []types.Param{{Name: "a"}, {Name: "b"}},
+		},
+	})
+	if !slices.Equal(err.Filters, []string{"$in"}) {
+		t.Errorf("Filters = %v", err.Filters)
+	}
+}
+
+func TestRender_EmptyParams(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	email := types.Field{Path: "email"}
+	age := types.Field{Path: "age"}
+	byKey := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+
+	tests := []struct {
+		name    string
+		ast     *types.DocumentAST
+		context string
+	}{
+		{
+			name:    "filter",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.FilterCondition{Field: email, Operator: types.EQ}},
+			context: "$eq on field 'email'",
+		},
+		{
+			name:    "range bound",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.RangeFilter{Field: age, Min: &types.Param{}}},
+			context: "range on field 'age'",
+		},
+		{
+			name:    "document",
+			ast:     &types.DocumentAST{Operation: types.OpInsert, Target: users, Documents: []types.Document{{Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "document field 'email'",
+		},
+		{
+			name: "update",
+			ast: &types.DocumentAST{Operation: types.OpUpdate, Target: users, FilterClause: byKey,
+				UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "$set field 'email'",
+		},
+		{
+			name:    "pagination",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: byKey, Limit: &types.PaginationValue{Param: &types.Param{}}},
+			context: "limit",
+		},
+		{
+			name: "pipeline expression",
+			ast: &types.DocumentAST{Operation: types.OpAggregate, Target: users,
+				Pipeline: []types.PipelineStage{types.AddFieldsStage{Fields: map[string]types.Expression{"flag": types.LiteralExpression{}}}}},
+			context: "$addFields field 'flag'",
+		},
+	}
+
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Render(tt.ast)
+			if !errors.Is(err, types.ErrInvalidIdentifier) {
+				t.Fatalf("expected invalid identifier error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.context) {
+				t.Errorf("expected error naming %q, got %v", tt.context, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package kv
+
+// OutputSchema returns a JSON Schema (draft 2020-12) describing the JSON that
+// Render produces: a get or put command on one key of a collection's
+// namespace.
+func OutputSchema() string {
+	return outputSchema
+}
+
+const outputSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "docql key-value command",
+  "type": "object",
+  "properties": {
+    "op": {"enum": ["get", "put"]},
+    "collection": {"type": "string"},
+    "key": {"type": "string", "description": "The :param placeholder bound to the document's _id."},
+    "value": {
+      "type": "object",
+      "description": "Field paths to :param placeholders.",
+      "additionalProperties": {"type": "string"}
+    }
+  },
+  "required": ["op", "collection", "key"],
+  "oneOf": [
+    {"properties": {"op": {"const": "get"}}, "propertyNames": {"enum": ["op", "collection", "key"]}},
+    {"properties": {"op": {"const": "put"}}, "required": ["value"]}
+  ],
+  "additionalProperties": false
+}`
@@ -0,0 +1,164 @@
+package mongodb
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// encoder serializes rendered queries without reflection for the value shapes
+// the renderer produces. Output matches encoding/json: object keys are sorted
+// and strings needing escapes are delegated to json.Marshal. Any other type
+// also falls back to json.Marshal. Sort documents are the one exception, and
+// keep their keys in clause order.
+type encoder struct {
+	buf  []byte
+	keys []string
+}
+
+func (e *encoder) encode(v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		e.buf = append(e.buf, "null"...)
+	case string:
+		return e.encodeString(val)
+	case int:
+		e.buf = strconv.AppendInt(e.buf, int64(val), 10)
+	case bool:
+		e.buf = strconv.AppendBool(e.buf, val)
+	case map[string]interface{}:
+		return encodeObject(e, val, (*encoder).encode)
+	case map[string]string:
+		return encodeObject(e, val, (*encoder).encodeString)
+	case map[string]map[string]string:
+		return encodeObject(e, val, encodeStringMap)
+	case map[string][]interface{}:
+		return encodeObject(e, val, encodeAnySlice)
+	case []interface{}:
+		return encodeAnySlice(e, val)
+	case []string:
+		return encodeArray(e, val, (*encoder).encodeString)
+	case []map[string]interface{}:
+		return encodeArray(e, val, encodeAnyMap)
+	case sortDocument:
+		return encodeSort(e, val)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		e.buf = append(e.buf, b...)
+	}
+	return nil
+}
+
+func (e *encoder) encodeString(s string) error {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= 0x80 || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			b, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			e.buf = append(e.buf, b...)
+			return nil
+		}
+	}
+	e.buf = append(e.buf, '"')
+	e.buf = append(e.buf, s...)
+	e.buf = append(e.buf, '"')
+	return nil
+}
+
+// sortDocument is a sort specification. MongoDB sorts by its keys in order,
+// so unlike other objects it is encoded in clause order rather than with
+// sorted keys.
+type sortDocument []types.SortClause
+
+// MarshalJSON encodes d in clause order.
+func (d sortDocument) MarshalJSON() ([]byte, error) {
+	var e encoder
+	err := encodeSort(&e, d)
+	return e.buf, err
+}
+
+func encodeSort(e *encoder, d sortDocument) error {
+	e.buf = append(e.buf, '{')
+	for i, s := range d {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		if err := e.encodeString(s.Field.Path); err != nil {
+			return err
+		}
+		e.buf = append(e.buf, ':')
+		e.buf = strconv.AppendInt(e.buf, int64(s.Order), 10)
+	}
+	e.buf = append(e.buf, '}')
+	return nil
+}
+
+func encodeAnyMap(e *encoder, m map[string]interface{}) error {
+	return encodeObject(e, m, (*encoder).encode)
+}
+
+func encodeStringMap(e *encoder, m map[string]string) error {
+	return encodeObject(e, m, (*encoder).encodeString)
+}
+
+func encodeAnySlice(e *encoder, s []interface{}) error {
+	return encodeArray(e, s, (*encoder).encode)
+}
+
+// encodeObject writes a map with sorted keys, using e.keys as scratch space
+// shared across nesting levels. Values are written by encodeValue so typed
+// maps never box their values into interfaces.
+func encodeObject[V any](e *encoder, m map[string]V, encodeValue func(*encoder, V) error) error {
+	if m == nil {
+		e.buf = append(e.buf, "null"...)
+		return nil
+	}
+	start := len(e.keys)
+	for k := range m {
+		e.keys = append(e.keys, k)
+	}
+	end := len(e.keys)
+	slices.Sort(e.keys[start:end])
+
+	e.buf = append(e.buf, '{')
+	for i := start; i < end; i++ {
+		if i > start {
+			e.buf = append(e.buf, ',')
+		}
+		k := e.keys[i]
+		if err := e.encodeString(k); err != nil {
+			return err
+		}
+		e.buf = append(e.buf, ':')
+		if err := encodeValue(e, m[k]); err != nil {
+			return err
+		}
+	}
+	e.buf = append(e.buf, '}')
+	e.keys = e.keys[:start]
+	return nil
+}
+
+func encodeArray[V any](e *encoder, s []V, encodeValue func(*encoder, V) error) error {
+	if s == nil {
+		e.buf = append(e.buf, "null"...)
+		return nil
+	}
+	e.buf = append(e.buf, '[')
+	for i, v := range s {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		if err := encodeValue(e, v); err != nil {
+			return err
+		}
+	}
+	e.buf = append(e.buf, ']')
+	return nil
+}
@@ -0,0 +1,41 @@
+package mongodb
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEncoder_MatchesEncodingJSON(t *testing.T) {
+	values := []interface{}{
+		nil,
+		"plain",
+		"quote\" backslash\\ <html> &   tab\t é \xff",
+		42,
+		-7,
+		true,
+		map[string]interface{}{"b": 1, "a": []interface{}{"x", nil, false}, "c": map[string]interface{}{}},
+		map[string]string{"$regex": ":p", "$options": ":o"},
+		map[string]map[string]string{"age": {"$gt": ":min"}},
+		map[string][]interface{}{"$and": {map[string]interface{}{"z": 1}}},
+		[]string{"a", "<b>"},
+		[]map[string]interface{}{{"$match": map[string]interface{}{}}},
+		map[string]interface{}(nil),
+		[]interface{}(nil),
+		3.5,
+		[]int{1, 2},
+	}
+
+	for _, v := range values {
+		want, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("json.Marshal(%#v): %v", v, err)
+		}
+		e := &encoder{}
+		if err := e.encode(v); err != nil {
+			t.Fatalf("encode(%#v): %v", v, err)
+		}
+		if string(e.buf) != string(want) {
+			t.Errorf("encode(%#v) = %s, want %s", v, e.buf, want)
+		}
+	}
+}
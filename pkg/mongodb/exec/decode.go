@@ -0,0 +1,81 @@
+package exec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// decode parses rendered JSON into BSON. Objects become bson.D in the order
+// their keys appear, so sort documents keep their precedence, and arrays
+// become bson.A. Integral numbers decode as int64 and others as float64.
+//
+// Plain JSON is read rather than extended JSON: a rendered filter such as
+// {"$regex": ":pattern", "$options": "i"} must stay a document whose
+// placeholder can be bound, not become a BSON regex.
+func decode(data []byte) (bson.D, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query JSON: %w", err)
+	}
+	doc, ok := v.(bson.D)
+	if !ok {
+		return nil, errors.New("invalid query JSON: not an object")
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid query JSON: trailing data")
+	}
+	return doc, nil
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			doc := bson.D{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				doc = append(doc, bson.E{Key: key.(string), Value: value})
+			}
+			_, err := dec.Token()
+			return doc, err
+		case '[':
+			arr := bson.A{}
+			for dec.More() {
+				value, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, value)
+			}
+			_, err := dec.Token()
+			return arr, err
+		}
+		return nil, fmt.Errorf("unexpected %v", t)
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n, nil
+		}
+		return t.Float64()
+	default:
+		return t, nil
+	}
+}
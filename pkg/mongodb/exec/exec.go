@@ -0,0 +1,468 @@
+// Package exec runs rendered MongoDB queries with the official Go driver.
+//
+// It is optional: the renderer in pkg/mongodb has no driver dependency, and
+// only programs that import this package link the driver.
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zoobzio/docql/internal/types"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
+)
+
+// Executor runs queries rendered by the MongoDB renderer against a database.
+type Executor struct {
+	db *mongo.Database
+}
+
+// New creates an Executor for db. Queries run against db whatever database
+// the schema names.
+func New(db *mongo.Database) *Executor {
+	return &Executor{db: db}
+}
+
+// Result holds what a query returned. Only the fields the operation produces
+// are set.
+type Result struct {
+	// Documents holds the documents a FIND, FIND_ONE, or AGGREGATE
+	// returned. FIND_ONE returns none when nothing matches.
+	Documents []bson.M
+
+	// Values holds the distinct values a DISTINCT returned.
+	Values []interface{}
+
+	// Count is the number of documents a COUNT matched.
+	Count int64
+
+	// InsertedIDs holds the _id of each inserted document.
+	InsertedIDs []interface{}
+
+	MatchedCount  int64
+	ModifiedCount int64
+	UpsertedCount int64
+	DeletedCount  int64
+}
+
+// Run substitutes params into result and executes it. Every required param
+// must be given; values are encoded with the driver's BSON rules, so
+// time.Time becomes a date and bson.ObjectID an ObjectId. A TRANSACTION runs
+// its writes in one session transaction, which needs a replica set.
+func (e *Executor) Run(ctx context.Context, result *types.QueryResult, params map[string]interface{}) (*Result, error) {
+	if result == nil {
+		return nil, errors.New("nil query result")
+	}
+	query, err := bind(result, params)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	if result.Operation == types.OpTransaction {
+		return res, e.runTransaction(ctx, query, res)
+	}
+	return res, e.run(ctx, query, res)
+}
+
+// bind decodes result's JSON into a BSON document, keeping key order, and
+// replaces each placeholder for a required param with its value. It fails if
+// a required param is missing.
+func bind(result *types.QueryResult, params map[string]interface{}) (bson.D, error) {
+	var missing []string
+	required := make(map[string]bool, len(result.RequiredParams))
+	for _, name := range result.RequiredParams {
+		if _, ok := params[name]; !ok {
+			missing = append(missing, name)
+		}
+		required[name] = true
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing params: %s", strings.Join(missing, ", "))
+	}
+
+	query, err := decode([]byte(result.JSON))
+	if err != nil {
+		return nil, err
+	}
+	return substitute(query, required, params).(bson.D), nil
+}
+
+// substitute returns v with every string ":name" naming a required param
+// replaced by the param's value.
+func substitute(v interface{}, required map[string]bool, params map[string]interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		if name, ok := strings.CutPrefix(value, ":"); ok && required[name] {
+			return params[name]
+		}
+	case bson.D:
+		for i := range value {
+			value[i].Value = substitute(value[i].Value, required, params)
+		}
+	case bson.A:
+		for i := range value {
+			value[i] = substitute(value[i], required, params)
+		}
+	}
+	return v
+}
+
+func (e *Executor) run(ctx context.Context, query bson.D, res *Result) error {
+	op, _ := lookup(query, "operation").(string)
+	name, ok := lookup(query, "collection").(string)
+	if !ok || name == "" {
+		return fmt.Errorf("%s: no collection", op)
+	}
+	coll := e.db.Collection(name, collectionOptions(query)...)
+
+	if ms, ok := lookup(query, "maxTimeMS").(int64); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+		defer cancel()
+	}
+
+	filter := lookup(query, "filter")
+	if filter == nil {
+		filter = bson.D{}
+	}
+
+	var err error
+	switch types.Operation(op) {
+	case types.OpFind:
+		err = e.find(ctx, coll, filter, query, res)
+	case types.OpFindOne:
+		err = e.findOne(ctx, coll, filter, query, res)
+	case types.OpInsert:
+		var r *mongo.InsertOneResult
+		if r, err = coll.InsertOne(ctx, lookup(query, "document")); err == nil {
+			res.InsertedIDs = append(res.InsertedIDs, r.InsertedID)
+		}
+	case types.OpInsertMany:
+		docs, _ := lookup(query, "documents").(bson.A)
+		var r *mongo.InsertManyResult
+		if r, err = coll.InsertMany(ctx, []interface{}(docs)); err == nil {
+			res.InsertedIDs = append(res.InsertedIDs, r.InsertedIDs...)
+		}
+	case types.OpUpdate, types.OpUpdateMany:
+		err = e.update(ctx, coll, types.Operation(op), filter, query, res)
+	case types.OpDelete, types.OpDeleteMany:
+		err = e.delete(ctx, coll, types.Operation(op), filter, query, res)
+	case types.OpAggregate:
+		err = e.aggregate(ctx, coll, query, res)
+	case types.OpCount:
+		opts := options.Count()
+		if hint := lookup(query, "hint"); hint != nil {
+			opts.SetHint(hint)
+		}
+		if c := collation(query); c != nil {
+			opts.SetCollation(c)
+		}
+		res.Count, err = coll.CountDocuments(ctx, filter, opts)
+	case types.OpDistinct:
+		if lookup(query, "pipeline") != nil {
+			err = e.distinctPipeline(ctx, coll, query, res)
+			break
+		}
+		field, _ := lookup(query, "field").(string)
+		opts := options.Distinct()
+		if c := collation(query); c != nil {
+			opts.SetCollation(c)
+		}
+		err = coll.Distinct(ctx, field, filter, opts).Decode(&res.Values)
+	default:
+		return fmt.Errorf("operation %q cannot be executed", op)
+	}
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", op, name, err)
+	}
+	return nil
+}
+
+func (e *Executor) find(ctx context.Context, coll *mongo.Collection, filter interface{}, query bson.D, res *Result) error {
+	opts := options.Find()
+	if projection := lookup(query, "projection"); projection != nil {
+		opts.SetProjection(projection)
+	}
+	if sort := lookup(query, "sort"); sort != nil {
+		opts.SetSort(sort)
+	}
+	if n, err := integer(query, "skip"); err != nil {
+		return err
+	} else if n != nil {
+		opts.SetSkip(*n)
+	}
+	if n, err := integer(query, "limit"); err != nil {
+		return err
+	} else if n != nil {
+		opts.SetLimit(*n)
+	}
+	if hint := lookup(query, "hint"); hint != nil {
+		opts.SetHint(hint)
+	}
+	if c := collation(query); c != nil {
+		opts.SetCollation(c)
+	}
+
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, &res.Documents)
+}
+
+func (e *Executor) findOne(ctx context.Context, coll *mongo.Collection, filter interface{}, query bson.D, res *Result) error {
+	opts := options.FindOne()
+	if projection := lookup(query, "projection"); projection != nil {
+		opts.SetProjection(projection)
+	}
+	if sort := lookup(query, "sort"); sort != nil {
+		opts.SetSort(sort)
+	}
+	if n, err := integer(query, "skip"); err != nil {
+		return err
+	} else if n != nil {
+		opts.SetSkip(*n)
+	}
+	if hint := lookup(query, "hint"); hint != nil {
+		opts.SetHint(hint)
+	}
+	if c := collation(query); c != nil {
+		opts.SetCollation(c)
+	}
+
+	var doc bson.M
+	err := coll.FindOne(ctx, filter, opts).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	res.Documents = append(res.Documents, doc)
+	return nil
+}
+
+func (e *Executor) update(ctx context.Context, coll *mongo.Collection, op types.Operation, filter interface{}, query bson.D, res *Result) error {
+	upsert, _ := lookup(query, "upsert").(bool)
+	hint := lookup(query, "hint")
+	c := collation(query)
+	update := lookup(query, "update")
+
+	var r *mongo.UpdateResult
+	var err error
+	if op == types.OpUpdate {
+		opts := options.UpdateOne().SetUpsert(upsert)
+		if hint != nil {
+			opts.SetHint(hint)
+		}
+		if c != nil {
+			opts.SetCollation(c)
+		}
+		r, err = coll.UpdateOne(ctx, filter, update, opts)
+	} else {
+		opts := options.UpdateMany().SetUpsert(upsert)
+		if hint != nil {
+			opts.SetHint(hint)
+		}
+		if c != nil {
+			opts.SetCollation(c)
+		}
+		r, err = coll.UpdateMany(ctx, filter, update, opts)
+	}
+	if err != nil {
+		return err
+	}
+	res.MatchedCount += r.MatchedCount
+	res.ModifiedCount += r.ModifiedCount
+	res.UpsertedCount += r.UpsertedCount
+	return nil
+}
+
+func (e *Executor) delete(ctx context.Context, coll *mongo.Collection, op types.Operation, filter interface{}, query bson.D, res *Result) error {
+	hint := lookup(query, "hint")
+	c := collation(query)
+
+	var r *mongo.DeleteResult
+	var err error
+	if op == types.OpDelete {
+		opts := options.DeleteOne()
+		if hint != nil {
+			opts.SetHint(hint)
+		}
+		if c != nil {
+			opts.SetCollation(c)
+		}
+		r, err = coll.DeleteOne(ctx, filter, opts)
+	} else {
+		opts := options.DeleteMany()
+		if hint != nil {
+			opts.SetHint(hint)
+		}
+		if c != nil {
+			opts.SetCollation(c)
+		}
+		r, err = coll.DeleteMany(ctx, filter, opts)
+	}
+	if err != nil {
+		return err
+	}
+	res.DeletedCount += r.DeletedCount
+	return nil
+}
+
+func (e *Executor) aggregate(ctx context.Context, coll *mongo.Collection, query bson.D, res *Result) error {
+	opts := options.Aggregate()
+	if allow, ok := lookup(query, "allowDiskUse").(bool); ok {
+		opts.SetAllowDiskUse(allow)
+	}
+	if hint := lookup(query, "hint"); hint != nil {
+		opts.SetHint(hint)
+	}
+	if c := collation(query); c != nil {
+		opts.SetCollation(c)
+	}
+
+	pipeline, _ := lookup(query, "pipeline").(bson.A)
+	if pipeline == nil {
+		pipeline = bson.A{}
+	}
+	cursor, err := coll.Aggregate(ctx, pipeline, opts)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, &res.Documents)
+}
+
+// distinctPipeline runs a sorted or limited DISTINCT, which renders as a
+// pipeline grouping by the field, and collects the _id of each group.
+func (e *Executor) distinctPipeline(ctx context.Context, coll *mongo.Collection, query bson.D, res *Result) error {
+	var groups Result
+	if err := e.aggregate(ctx, coll, query, &groups); err != nil {
+		return err
+	}
+	res.Values = make([]interface{}, len(groups.Documents))
+	for i, doc := range groups.Documents {
+		res.Values[i] = doc["_id"]
+	}
+	return nil
+}
+
+// runTransaction runs each write of a TRANSACTION in one session transaction,
+// adding up their results.
+func (e *Executor) runTransaction(ctx context.Context, query bson.D, res *Result) error {
+	operations, _ := lookup(query, "operations").(bson.A)
+
+	opts := options.Transaction()
+	if rc := readConcern(query); rc != nil {
+		opts.SetReadConcern(rc)
+	}
+	if wc := writeConcern(query); wc != nil {
+		opts.SetWriteConcern(wc)
+	}
+
+	session, err := e.db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (interface{}, error) {
+		*res = Result{}
+		for i, op := range operations {
+			doc, ok := op.(bson.D)
+			if !ok {
+				return nil, fmt.Errorf("operation %d: not a document", i)
+			}
+			if err := e.run(ctx, doc, res); err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+		}
+		return nil, nil
+	}, opts)
+	return err
+}
+
+// collectionOptions returns the read and write concerns a query names.
+func collectionOptions(query bson.D) []options.Lister[options.CollectionOptions] {
+	opts := options.Collection()
+	if rc := readConcern(query); rc != nil {
+		opts.SetReadConcern(rc)
+	}
+	if wc := writeConcern(query); wc != nil {
+		opts.SetWriteConcern(wc)
+	}
+	return []options.Lister[options.CollectionOptions]{opts}
+}
+
+func readConcern(query bson.D) *readconcern.ReadConcern {
+	doc, ok := lookup(query, "readConcern").(bson.D)
+	if !ok {
+		return nil
+	}
+	level, _ := lookup(doc, "level").(string)
+	return &readconcern.ReadConcern{Level: level}
+}
+
+func writeConcern(query bson.D) *writeconcern.WriteConcern {
+	doc, ok := lookup(query, "writeConcern").(bson.D)
+	if !ok {
+		return nil
+	}
+	return &writeconcern.WriteConcern{W: lookup(doc, "w")}
+}
+
+// collation returns the collation a query names, or nil.
+func collation(query bson.D) *options.Collation {
+	doc, ok := lookup(query, "collation").(bson.D)
+	if !ok {
+		return nil
+	}
+	c := &options.Collation{}
+	c.Locale, _ = lookup(doc, "locale").(string)
+	if strength, ok := lookup(doc, "strength").(int64); ok {
+		c.Strength = int(strength)
+	}
+	c.NumericOrdering, _ = lookup(doc, "numericOrdering").(bool)
+	return c
+}
+
+// integer returns the integer under key, which a param may have bound to any
+// Go integer type, or nil when the key is absent.
+func integer(query bson.D, key string) (*int64, error) {
+	var n int64
+	switch v := lookup(query, key).(type) {
+	case nil:
+		return nil, nil
+	case int:
+		n = int64(v)
+	case int32:
+		n = int64(v)
+	case int64:
+		n = v
+	case float64:
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("%s: %v is not an integer", key, v)
+		}
+		n = int64(v)
+	default:
+		return nil, fmt.Errorf("%s: %T is not an integer", key, v)
+	}
+	return &n, nil
+}
+
+// lookup returns the value under key in doc, or nil.
+func lookup(doc bson.D, key string) interface{} {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package exec
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zoobzio/docql/internal/types"
+	"github.com/zoobzio/docql/pkg/mongodb"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestBind(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{
+			Logic: types.AND,
+			Conditions: []types.FilterItem{
+				types.FilterCondition{Field: types.Field{Path: "createdAt"}, Operator: types.GTE, Value: types.Param{Name: "since"}},
+				types.RegexFilter{Field: types.Field{Path: "email"}, Pattern: types.Param{Name: "pattern"}},
+			},
+		},
+		SortClauses: []types.SortClause{
+			{Field: types.Field{Path: "username"}, Order: types.Ascending},
+			{Field: types.Field{Path: "createdAt"}, Order: types.Descending},
+		},
+		Limit: &types.PaginationValue{Param: &types.Param{Name: "n"}},
+	}
+	result, err := mongodb.New().Render(ast)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	query, err := bind(result, map[string]interface{}{"since": since, "pattern": "^a", "n": 10})
+	if err != nil {
+		t.Fatalf("bind failed: %v", err)
+	}
+
+	want := bson.D{
+		{Key: "$and", Value: bson.A{
+			bson.D{{Key: "createdAt", Value: bson.D{{Key: "$gte", Value: since}}}},
+			bson.D{{Key: "email", Value: bson.D{{Key: "$regex", Value: "^a"}}}},
+		}},
+	}
+	if got := lookup(query, "filter"); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected filter:\n got: %#v\nwant: %#v", got, want)
+	}
+	sort := bson.D{{Key: "username", Value: int64(1)}, {Key: "createdAt", Value: int64(-1)}}
+	if got := lookup(query, "sort"); !reflect.DeepEqual(got, sort) {
+		t.Errorf("sort lost its key order: %#v", got)
+	}
+	if n, err := integer(query, "limit"); err != nil || *n != 10 {
+		t.Errorf("expected limit 10, got %v (%v)", n, err)
+	}
+}
+
+func TestBind_MissingParams(t *testing.T) {
+	result := &types.QueryResult{
+		JSON:           `{"collection":"users","filter":{"a":{"$eq":":a"},"b":{"$eq":":b"}},"operation":"FIND"}`,
+		RequiredParams: []string{"a", "b"},
+	}
+	_, err := bind(result, map[string]interface{}{"a": 1})
+	if err == nil || !strings.Contains(err.Error(), "missing params: b") {
+		t.Errorf("expected missing param b, got %v", err)
+	}
+}
+
+func TestBind_OnlyRequiredParams(t *testing.T) {
+	// A string that looks like a placeholder but names no required param is
+	// data, not a param.
+	result := &types.QueryResult{
+		JSON:           `{"collection":"users","document":{"name":":name","note":":literal"},"operation":"INSERT"}`,
+		RequiredParams: []string{"name"},
+	}
+	query, err := bind(result, map[string]interface{}{"name": "alice", "literal": "x"})
+	if err != nil {
+		t.Fatalf("bind failed: %v", err)
+	}
+	want := bson.D{{Key: "name", Value: "alice"}, {Key: "note", Value: ":literal"}}
+	if got := lookup(query, "document"); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected document: %#v", got)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	for _, data := range []string{`[]`, `{"a":1} {}`, `{"a":`} {
+		if _, err := decode([]byte(data)); err == nil {
+			t.Errorf("expected %s to be rejected", data)
+		}
+	}
+}
+
+func TestInteger(t *testing.T) {
+	query := bson.D{{Key: "a", Value: int32(3)}, {Key: "b", Value: 2.5}, {Key: "c", Value: "x"}}
+	if n, err := integer(query, "a"); err != nil || *n != 3 {
+		t.Errorf("expected 3, got %v (%v)", n, err)
+	}
+	if _, err := integer(query, "b"); err == nil {
+		t.Error("expected 2.5 to be rejected")
+	}
+	if _, err := integer(query, "c"); err == nil {
+		t.Error("expected a string to be rejected")
+	}
+	if n, err := integer(query, "missing"); n != nil || err != nil {
+		t.Errorf("expected nil for a missing key, got %v (%v)", n, err)
+	}
+}
@@ -0,0 +1,22 @@
+module github.com/zoobzio/docql/pkg/mongodb/exec
+
+go 1.25.5
+
+replace github.com/zoobzio/docql => ../../..
+
+require (
+	github.com/zoobzio/docql v0.0.0-00010101000000-000000000000
+	go.mongodb.org/mongo-driver/v2 v2.4.1
+)
+
+require (
+	github.com/golang/snappy v1.0.0 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
+	github.com/xdg-go/scram v1.1.2 // indirect
+	github.com/xdg-go/stringprep v1.0.4 // indirect
+	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
+	golang.org/x/crypto v0.43.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
+	golang.org/x/text v0.32.0 // indirect
+)
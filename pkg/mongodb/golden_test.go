@@ -0,0 +1,127 @@
+package mongodb
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// TestRender_Golden pins the rendered JSON of representative queries, key
+// order included, against testdata/*.json. Regenerate with
+// go test ./pkg/mongodb -run TestRender_Golden -update.
+func TestRender_Golden(t *testing.T) {
+	field := func(path string) types.Field { return types.Field{Path: path} }
+	param := func(name string) types.Param { return types.Param{Name: name} }
+	eq := func(path, name string) types.FilterCondition {
+		return types.FilterCondition{Field: field(path), Operator: types.EQ, Value: param(name)}
+	}
+	limit, majority := 20, "majority"
+
+	tests := []struct {
+		name string
+		ast  *types.DocumentAST
+	}{
+		{
+			name: "find_complex_filter",
+			ast: &types.DocumentAST{
+				Operation: types.OpFind,
+				Target:    types.Collection{Name: "users"},
+				FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+					eq("status", "status"),
+					types.FilterGroup{Logic: types.OR, Conditions: []types.FilterItem{
+						types.RangeFilter{Field: field("age"), Min: &types.Param{Name: "minAge"}, Max: &types.Param{Name: "maxAge"}, MaxExclusive: true},
+						types.RegexFilter{Field: field("email"), Pattern: param("domain"), Options: &types.Param{Name: "flags"}},
+					}},
+					types.ValuesFilter{Field: field("role"), Operator: types.IN, Values: []types.Param{param("r1"), param("r2")}},
+					types.ElemMatchFilter{Field: field("orders"), Conditions: []types.FilterItem{
+						eq("sku", "sku"),
+						types.FilterCondition{Field: field("qty"), Operator: types.GTE, Value: param("minQty")},
+					}},
+					types.FilterGroup{Logic: types.NOR, Conditions: []types.FilterItem{
+						types.ExistsFilter{Field: field("deletedAt"), Exists: true},
+					}},
+				}},
+				Projection:  &types.Projection{Fields: []types.ProjectionField{{Field: field("name"), Include: true}, {Field: field("email"), Include: true}}},
+				SortClauses: []types.SortClause{{Field: field("age"), Order: types.Descending}, {Field: field("name"), Order: types.Ascending}},
+				Skip:        &types.PaginationValue{Param: &types.Param{Name: "offset"}},
+				Limit:       &types.PaginationValue{Static: &limit},
+			},
+		},
+		{
+			name: "update_many",
+			ast: &types.DocumentAST{
+				Operation:    types.OpUpdateMany,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: eq("status", "status"),
+				UpdateOps: []types.UpdateOperation{
+					{Operator: types.Set, Fields: map[types.Field]types.Param{field("status"): param("newStatus"), field("profile.updatedBy"): param("actor")}},
+					{Operator: types.Inc, Fields: map[types.Field]types.Param{field("version"): param("one")}},
+					{Operator: types.Unset, Fields: map[types.Field]types.Param{field("legacy"): {}}},
+					{Operator: types.Push, Fields: map[types.Field]types.Param{field("history"): param("entry")}},
+				},
+				Upsert:       true,
+				WriteConcern: &majority,
+			},
+		},
+		{
+			name: "aggregate",
+			ast: &types.DocumentAST{
+				Operation: types.OpAggregate,
+				Target:    types.Collection{Name: "orders"},
+				Pipeline: []types.PipelineStage{
+					types.MatchStage{Filter: eq("status", "status")},
+					types.LookupStage{From: "users", LocalField: field("userId"), ForeignField: field("_id"), As: "user"},
+					types.GroupStage{
+						ID: types.CompositeExpression{Fields: map[string]types.Expression{
+							"region": types.FieldExpression{Field: field("region")},
+							"day":    types.FieldExpression{Field: field("day")},
+						}},
+						Accumulators: map[string]types.Accumulator{
+							"total": {Operator: types.AccSum, Expr: types.FieldExpression{Field: field("amount")}},
+							"skus":  {Operator: types.AccAddToSet, Expr: types.FieldExpression{Field: field("sku")}},
+						},
+					},
+					types.SortStage{Sorts: []types.SortClause{{Field: field("total"), Order: types.Descending}}},
+					types.LimitStage{Limit: types.PaginationValue{Param: &types.Param{Name: "top"}}},
+				},
+				AllowDiskUse: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := New().Render(tt.ast)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got bytes.Buffer
+			if err := json.Indent(&got, []byte(result.JSON), "", "  "); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			got.WriteByte('\n')
+
+			path := filepath.Join("testdata", tt.name+".json")
+			if *update {
+				if err := os.WriteFile(path, got.Bytes(), 0o644); err != nil {
+					t.Fatalf("writing golden file: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading golden file: %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("%s does not match the rendered query:\n got: %s\nwant: %s", path, got.Bytes(), want)
+			}
+		})
+	}
+}
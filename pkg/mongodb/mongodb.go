@@ -4,6 +4,10 @@ package mongodb
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
 
 	"github.com/zoobzio/docql/internal/types"
 )
@@ -11,6 +15,9 @@ import (
 // Renderer renders DocumentAST to MongoDB query format.
 type Renderer struct{}
 
+// provider names MongoDB in UnsupportedError values.
+const provider = "MongoDB"
+
 // New creates a new MongoDB renderer.
 func New() *Renderer {
 	return &Renderer{}
@@ -19,41 +26,60 @@ func New() *Renderer {
 // Render converts a DocumentAST to MongoDB query format.
 func (r *Renderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
 	if err := ast.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid AST: %w", err)
+		return nil, &types.InvalidASTError{Err: err}
 	}
 
 	var params []string
+	if n := countParams(ast); n > 0 {
+		params = make([]string, 0, n)
+	}
+	if ast.Target.Param != nil {
+		params = append(params, ast.Target.Param.Name)
+	}
+
+	var result *types.QueryResult
+	var err error
 
 	switch ast.Operation {
 	case types.OpFind, types.OpFindOne:
-		return r.renderFind(ast, &params)
+		result, err = r.renderFind(ast, &params)
 	case types.OpInsert:
-		return r.renderInsert(ast, &params)
+		result, err = r.renderInsert(ast, &params)
 	case types.OpInsertMany:
-		return r.renderInsertMany(ast, &params)
+		result, err = r.renderInsertMany(ast, &params)
 	case types.OpUpdate:
-		return r.renderUpdate(ast, &params)
+		result, err = r.renderUpdate(ast, &params)
 	case types.OpUpdateMany:
-		return r.renderUpdateMany(ast, &params)
+		result, err = r.renderUpdateMany(ast, &params)
 	case types.OpDelete:
-		return r.renderDelete(ast, &params)
+		result, err = r.renderDelete(ast, &params)
 	case types.OpDeleteMany:
-		return r.renderDeleteMany(ast, &params)
+		result, err = r.renderDeleteMany(ast, &params)
 	case types.OpAggregate:
-		return r.renderAggregate(ast, &params)
+		result, err = r.renderAggregate(ast, &params)
 	case types.OpCount:
-		return r.renderCount(ast, &params)
+		result, err = r.renderCount(ast, &params)
 	case types.OpDistinct:
-		return r.renderDistinct(ast, &params)
+		result, err = r.renderDistinct(ast, &params)
+	case types.OpWatch:
+		result, err = r.renderWatch(ast, &params)
+	case types.OpTransaction:
+		result, err = r.renderTransaction(ast)
 	default:
-		return nil, fmt.Errorf("unsupported operation: %s", ast.Operation)
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
+	}
+	if err != nil {
+		return nil, types.WithOperation(err, ast.Operation)
 	}
+	result.Operation = ast.Operation
+	result.Single = ast.Operation == types.OpFindOne
+	result.Collection = ast.Target.Name
+	result.Database = ast.Target.Database
+	return result, nil
 }
 
 func (r *Renderer) renderFind(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
-	query["collection"] = ast.Target.Name
-	query["operation"] = string(ast.Operation)
+	query := newQuery(ast)
 
 	if ast.FilterClause != nil {
 		filter, err := r.renderFilter(ast.FilterClause, params)
@@ -70,11 +96,7 @@ func (r *Renderer) renderFind(ast *types.DocumentAST, params *[]string) (*types.
 	}
 
 	if len(ast.SortClauses) > 0 {
-		sort := make(map[string]interface{})
-		for _, s := range ast.SortClauses {
-			sort[s.Field.Path] = int(s.Order)
-		}
-		query["sort"] = sort
+		query["sort"] = sortDocument(ast.SortClauses)
 	}
 
 	if ast.Skip != nil {
@@ -82,26 +104,31 @@ func (r *Renderer) renderFind(ast *types.DocumentAST, params *[]string) (*types.
 			query["skip"] = *ast.Skip.Static
 		} else if ast.Skip.Param != nil {
 			*params = append(*params, ast.Skip.Param.Name)
-			query["skip"] = fmt.Sprintf(":%s", ast.Skip.Param.Name)
+			query["skip"] = placeholder(ast.Skip.Param.Name)
 		}
 	}
 
-	if ast.Limit != nil {
+	// FindOne returns at most one document, so its limit is fixed at 1.
+	if ast.Operation == types.OpFindOne {
+		query["limit"] = 1
+	} else if ast.Limit != nil {
 		if ast.Limit.Static != nil {
 			query["limit"] = *ast.Limit.Static
 		} else if ast.Limit.Param != nil {
 			*params = append(*params, ast.Limit.Param.Name)
-			query["limit"] = fmt.Sprintf(":%s", ast.Limit.Param.Name)
+			query["limit"] = placeholder(ast.Limit.Param.Name)
 		}
 	}
 
+	if ast.MaxTimeMS != nil {
+		query["maxTimeMS"] = *ast.MaxTimeMS
+	}
+
 	return toResult(query, *params)
 }
 
 func (r *Renderer) renderInsert(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
-	query["collection"] = ast.Target.Name
-	query["operation"] = string(ast.Operation)
+	query := newQuery(ast)
 
 	if len(ast.Documents) > 0 {
 		doc := r.renderDocument(ast.Documents[0], params)
@@ -112,9 +139,7 @@ func (r *Renderer) renderInsert(ast *types.DocumentAST, params *[]string) (*type
 }
 
 func (r *Renderer) renderInsertMany(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
-	query["collection"] = ast.Target.Name
-	query["operation"] = string(ast.Operation)
+	query := newQuery(ast)
 
 	docs := make([]map[string]interface{}, len(ast.Documents))
 	for i, doc := range ast.Documents {
@@ -126,9 +151,7 @@ func (r *Renderer) renderInsertMany(ast *types.DocumentAST, params *[]string) (*
 }
 
 func (r *Renderer) renderUpdate(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
-	query["collection"] = ast.Target.Name
-	query["operation"] = string(ast.Operation)
+	query := newQuery(ast)
 
 	if ast.FilterClause != nil {
 		filter, err := r.renderFilter(ast.FilterClause, params)
@@ -154,9 +177,7 @@ func (r *Renderer) renderUpdateMany(ast *types.DocumentAST, params *[]string) (*
 }
 
 func (r *Renderer) renderDelete(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
-	query["collection"] = ast.Target.Name
-	query["operation"] = string(ast.Operation)
+	query := newQuery(ast)
 
 	if ast.FilterClause != nil {
 		filter, err := r.renderFilter(ast.FilterClause, params)
@@ -175,10 +196,33 @@ func (r *Renderer) renderDeleteMany(ast *types.DocumentAST, params *[]string) (*
 	return r.renderDelete(ast, params)
 }
 
-func (r *Renderer) renderAggregate(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
-	query["collection"] = ast.Target.Name
+// renderTransaction renders each write through its ordinary path, for the
+// caller to run inside a session with startTransaction/commitTransaction.
+func (r *Renderer) renderTransaction(ast *types.DocumentAST) (*types.QueryResult, error) {
+	results, params, warnings, err := types.RenderOperations(ast, r.Render)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := make([]interface{}, len(results))
+	for i, result := range results {
+		operations[i] = json.RawMessage(result.JSON)
+	}
+	query := queryPool.Get().(map[string]interface{})
 	query["operation"] = string(ast.Operation)
+	query["operations"] = operations
+	addConcerns(query, ast)
+
+	result, err := toResult(query, params)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = warnings
+	return result, nil
+}
+
+func (r *Renderer) renderAggregate(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
+	query := newQuery(ast)
 
 	pipeline := make([]map[string]interface{}, 0, len(ast.Pipeline))
 	for _, stage := range ast.Pipeline {
@@ -190,13 +234,18 @@ func (r *Renderer) renderAggregate(ast *types.DocumentAST, params *[]string) (*t
 	}
 	query["pipeline"] = pipeline
 
+	if ast.MaxTimeMS != nil {
+		query["maxTimeMS"] = *ast.MaxTimeMS
+	}
+	if ast.AllowDiskUse {
+		query["allowDiskUse"] = true
+	}
+
 	return toResult(query, *params)
 }
 
 func (r *Renderer) renderCount(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
-	query["collection"] = ast.Target.Name
-	query["operation"] = string(ast.Operation)
+	query := newQuery(ast)
 
 	if ast.FilterClause != nil {
 		filter, err := r.renderFilter(ast.FilterClause, params)
@@ -208,23 +257,60 @@ func (r *Renderer) renderCount(ast *types.DocumentAST, params *[]string) (*types
 		query["filter"] = map[string]interface{}{}
 	}
 
+	if ast.MaxTimeMS != nil {
+		query["maxTimeMS"] = *ast.MaxTimeMS
+	}
+
 	return toResult(query, *params)
 }
 
 func (r *Renderer) renderDistinct(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
-	query := make(map[string]interface{})
-	query["collection"] = ast.Target.Name
-	query["operation"] = string(ast.Operation)
+	query := newQuery(ast)
 	query["field"] = ast.DistinctField.Path
 
+	var filter interface{}
 	if ast.FilterClause != nil {
-		filter, err := r.renderFilter(ast.FilterClause, params)
-		if err != nil {
+		var err error
+		if filter, err = r.renderFilter(ast.FilterClause, params); err != nil {
 			return nil, err
 		}
+	}
+
+	// The distinct command can neither sort nor limit, so a sorted or
+	// limited distinct groups by the field in a pipeline instead. The
+	// values come back as the _id of each group.
+	if len(ast.SortClauses) > 0 || ast.Limit != nil {
+		pipeline := make([]map[string]interface{}, 0, 4)
+		if filter != nil {
+			pipeline = append(pipeline, map[string]interface{}{"$match": filter})
+		}
+		pipeline = append(pipeline, map[string]interface{}{
+			"$group": map[string]interface{}{"_id": "$" + ast.DistinctField.Path},
+		})
+		for _, sc := range ast.SortClauses {
+			pipeline = append(pipeline, map[string]interface{}{
+				"$sort": map[string]interface{}{"_id": int(sc.Order)},
+			})
+		}
+		if ast.Limit != nil {
+			var limit interface{}
+			if ast.Limit.Static != nil {
+				limit = *ast.Limit.Static
+			} else if ast.Limit.Param != nil {
+				*params = append(*params, ast.Limit.Param.Name)
+				limit = placeholder(ast.Limit.Param.Name)
+			}
+			pipeline = append(pipeline, map[string]interface{}{"$limit": limit})
+		}
+		query["pipeline"] = pipeline
+	} else if filter != nil {
 		query["filter"] = filter
 	}
 
+	if ast.MaxTimeMS != nil {
+		query["maxTimeMS"] = *ast.MaxTimeMS
+	}
+
 	return toResult(query, *params)
 }
 
@@ -234,10 +320,8 @@ func (r *Renderer) renderFilter(f types.FilterItem, params *[]string) (interface
 		if filter.Value.Name != "" {
 			*params = append(*params, filter.Value.Name)
 		}
-		return map[string]interface{}{
-			filter.Field.Path: map[string]interface{}{
-				string(filter.Operator): fmt.Sprintf(":%s", filter.Value.Name),
-			},
+		return map[string]map[string]string{
+			filter.Field.Path: {string(filter.Operator): placeholder(filter.Value.Name)},
 		}, nil
 
 	case types.FilterGroup:
@@ -249,19 +333,19 @@ func (r *Renderer) renderFilter(f types.FilterItem, params *[]string) (interface
 			}
 			conditions = append(conditions, rendered)
 		}
-		return map[string]interface{}{
+		return map[string][]interface{}{
 			string(filter.Logic): conditions,
 		}, nil
 
 	case types.RangeFilter:
-		rangeFilter := make(map[string]interface{})
+		rangeFilter := make(map[string]string, 2)
 		if filter.Min != nil {
 			*params = append(*params, filter.Min.Name)
 			op := "$gte"
 			if filter.MinExclusive {
 				op = "$gt"
 			}
-			rangeFilter[op] = fmt.Sprintf(":%s", filter.Min.Name)
+			rangeFilter[op] = placeholder(filter.Min.Name)
 		}
 		if filter.Max != nil {
 			*params = append(*params, filter.Max.Name)
@@ -269,22 +353,27 @@ func (r *Renderer) renderFilter(f types.FilterItem, params *[]string) (interface
 			if filter.MaxExclusive {
 				op = "$lt"
 			}
-			rangeFilter[op] = fmt.Sprintf(":%s", filter.Max.Name)
+			rangeFilter[op] = placeholder(filter.Max.Name)
 		}
-		return map[string]interface{}{
+		if filter.Negated {
+			return map[string]map[string]map[string]string{
+				filter.Field.Path: {"$not": rangeFilter},
+			}, nil
+		}
+		return map[string]map[string]string{
 			filter.Field.Path: rangeFilter,
 		}, nil
 
 	case types.RegexFilter:
 		*params = append(*params, filter.Pattern.Name)
-		regexFilter := map[string]interface{}{
-			"$regex": fmt.Sprintf(":%s", filter.Pattern.Name),
+		regexFilter := map[string]string{
+			"$regex": placeholder(filter.Pattern.Name),
 		}
 		if filter.Options != nil {
 			*params = append(*params, filter.Options.Name)
-			regexFilter["$options"] = fmt.Sprintf(":%s", filter.Options.Name)
+			regexFilter["$options"] = placeholder(filter.Options.Name)
 		}
-		return map[string]interface{}{
+		return map[string]map[string]string{
 			filter.Field.Path: regexFilter,
 		}, nil
 
@@ -295,6 +384,18 @@ func (r *Renderer) renderFilter(f types.FilterItem, params *[]string) (interface
 			},
 		}, nil
 
+	case types.NullFilter:
+		// Null equality also matches missing fields, so a strict match adds
+		// $exists. $ne: null already excludes them.
+		switch {
+		case filter.Negated:
+			return map[string]interface{}{filter.Field.Path: map[string]interface{}{"$ne": nil}}, nil
+		case filter.Strict:
+			return map[string]interface{}{filter.Field.Path: map[string]interface{}{"$eq": nil, "$exists": true}}, nil
+		default:
+			return map[string]interface{}{filter.Field.Path: nil}, nil
+		}
+
 	case types.GeoFilter:
 		*params = append(*params, filter.Center.Lon.Name)
 		*params = append(*params, filter.Center.Lat.Name)
@@ -302,14 +403,14 @@ func (r *Renderer) renderFilter(f types.FilterItem, params *[]string) (interface
 			"$geometry": map[string]interface{}{
 				"type": "Point",
 				"coordinates": []string{
-					fmt.Sprintf(":%s", filter.Center.Lon.Name),
-					fmt.Sprintf(":%s", filter.Center.Lat.Name),
+					placeholder(filter.Center.Lon.Name),
+					placeholder(filter.Center.Lat.Name),
 				},
 			},
 		}
 		if filter.Radius != nil {
 			*params = append(*params, filter.Radius.Name)
-			geoQuery["$maxDistance"] = fmt.Sprintf(":%s", filter.Radius.Name)
+			geoQuery["$maxDistance"] = placeholder(filter.Radius.Name)
 		}
 		return map[string]interface{}{
 			filter.Field.Path: map[string]interface{}{
@@ -318,11 +419,35 @@ func (r *Renderer) renderFilter(f types.FilterItem, params *[]string) (interface
 		}, nil
 
 	case types.ArrayFilter:
+		if filter.Literal != nil {
+			return map[string]map[string]int{
+				filter.Field.Path: {string(filter.Operator): *filter.Literal},
+			}, nil
+		}
 		*params = append(*params, filter.Value.Name)
-		return map[string]interface{}{
-			filter.Field.Path: map[string]interface{}{
-				string(filter.Operator): fmt.Sprintf(":%s", filter.Value.Name),
-			},
+		return map[string]map[string]string{
+			filter.Field.Path: {string(filter.Operator): placeholder(filter.Value.Name)},
+		}, nil
+
+	case types.ValuesFilter:
+		values := make([]string, len(filter.Values))
+		for i, v := range filter.Values {
+			*params = append(*params, v.Name)
+			values[i] = placeholder(v.Name)
+		}
+		return map[string]map[string][]string{
+			filter.Field.Path: {string(filter.Operator): values},
+		}, nil
+
+	case types.TypeFilter:
+		return map[string]map[string]string{
+			filter.Field.Path: {"$type": filter.TypeName},
+		}, nil
+
+	case types.ModFilter:
+		*params = append(*params, filter.Divisor.Name, filter.Remainder.Name)
+		return map[string]map[string][]string{
+			filter.Field.Path: {"$mod": {placeholder(filter.Divisor.Name), placeholder(filter.Remainder.Name)}},
 		}, nil
 
 	case types.ElemMatchFilter:
@@ -332,11 +457,7 @@ func (r *Renderer) renderFilter(f types.FilterItem, params *[]string) (interface
 			if err != nil {
 				return nil, err
 			}
-			if m, ok := rendered.(map[string]interface{}); ok {
-				for k, v := range m {
-					conditions[k] = v
-				}
-			}
+			mergeFilter(conditions, rendered)
 		}
 		return map[string]interface{}{
 			filter.Field.Path: map[string]interface{}{
@@ -347,11 +468,11 @@ func (r *Renderer) renderFilter(f types.FilterItem, params *[]string) (interface
 	case types.TextSearchFilter:
 		*params = append(*params, filter.Search.Name)
 		textQuery := map[string]interface{}{
-			"$search": fmt.Sprintf(":%s", filter.Search.Name),
+			"$search": placeholder(filter.Search.Name),
 		}
 		if filter.Language != nil {
 			*params = append(*params, filter.Language.Name)
-			textQuery["$language"] = fmt.Sprintf(":%s", filter.Language.Name)
+			textQuery["$language"] = placeholder(filter.Language.Name)
 		}
 		if filter.CaseSensitive {
 			textQuery["$caseSensitive"] = true
@@ -364,40 +485,72 @@ func (r *Renderer) renderFilter(f types.FilterItem, params *[]string) (interface
 		}, nil
 
 	default:
-		return nil, fmt.Errorf("unsupported filter type: %T", f)
+		return nil, types.UnsupportedFilter(provider, types.FilterName(f))
 	}
 }
 
+// mergeFilter copies the keys of a rendered filter into dst.
+func mergeFilter(dst map[string]interface{}, rendered interface{}) {
+	switch m := rendered.(type) {
+	case map[string]interface{}:
+		for k, v := range m {
+			dst[k] = v
+		}
+	case map[string]map[string]string:
+		for k, v := range m {
+			dst[k] = v
+		}
+	case map[string][]interface{}:
+		for k, v := range m {
+			dst[k] = v
+		}
+	}
+}
+
+// renderProjection renders included fields as 1 and excluded fields as 0.
+// An aliased field becomes a computed entry, {"alias": "$path"}; aliasing _id
+// also excludes _id unless the projection names it explicitly.
 func (r *Renderer) renderProjection(p *types.Projection) map[string]interface{} {
-	proj := make(map[string]interface{})
+	proj := make(map[string]interface{}, len(p.Fields))
+	aliasedID := false
 	for _, f := range p.Fields {
-		if f.Include {
+		switch {
+		case f.Alias != "":
+			proj[f.Alias] = "$" + f.Field.Path
+			aliasedID = aliasedID || f.Field.Path == types.IDField
+		case f.Include:
 			proj[f.Field.Path] = 1
-		} else {
+		default:
 			proj[f.Field.Path] = 0
 		}
 	}
+	if _, ok := proj[types.IDField]; aliasedID && !ok {
+		proj[types.IDField] = 0
+	}
 	return proj
 }
 
 func (r *Renderer) renderDocument(doc types.Document, params *[]string) map[string]interface{} {
-	result := make(map[string]interface{})
+	result := make(map[string]interface{}, len(doc.Fields))
 	for field, value := range doc.Fields {
 		*params = append(*params, value.Name)
-		result[field.Path] = fmt.Sprintf(":%s", value.Name)
+		result[field.Path] = placeholder(value.Name)
 	}
 	return result
 }
 
 func (r *Renderer) renderUpdateOps(ops []types.UpdateOperation, params *[]string) map[string]interface{} {
-	result := make(map[string]interface{})
+	result := make(map[string]interface{}, len(ops))
 	for _, op := range ops {
-		fields := make(map[string]interface{})
+		fields := make(map[string]interface{}, len(op.Fields))
 		for field, value := range op.Fields {
-			if value.Name != "" {
+			switch {
+			case value.Name != "":
 				*params = append(*params, value.Name)
-				fields[field.Path] = fmt.Sprintf(":%s", value.Name)
-			} else {
+				fields[field.Path] = placeholder(value.Name)
+			case op.Operator == types.CurrentDate:
+				fields[field.Path] = true
+			default:
 				fields[field.Path] = ""
 			}
 		}
@@ -418,14 +571,28 @@ func (r *Renderer) renderPipelineStage(stage types.PipelineStage, params *[]stri
 		}, nil
 
 	case types.ProjectStage:
+		project := r.renderProjection(&s.Projection)
+		for _, name := range slices.Sorted(maps.Keys(s.Computed)) {
+			project[name] = r.renderExpression(s.Computed[name], params)
+		}
 		return map[string]interface{}{
-			"$project": r.renderProjection(&s.Projection),
+			"$project": project,
 		}, nil
 
 	case types.GroupStage:
-		group := make(map[string]interface{})
+		group := make(map[string]interface{}, len(s.Accumulators)+1)
+		if composite, ok := s.ID.(types.CompositeExpression); ok {
+			for name := range composite.Fields {
+				if !isValidKey(name) {
+					return nil, &types.InvalidIdentifierError{Kind: "$group _id key", Value: name}
+				}
+			}
+		}
 		group["_id"] = r.renderExpression(s.ID, params)
 		for name, acc := range s.Accumulators {
+			if !isValidKey(name) {
+				return nil, &types.InvalidIdentifierError{Kind: "$group output name", Value: name}
+			}
 			group[name] = map[string]interface{}{
 				acc.Operator: r.renderExpression(acc.Expr, params),
 			}
@@ -435,12 +602,8 @@ func (r *Renderer) renderPipelineStage(stage types.PipelineStage, params *[]stri
 		}, nil
 
 	case types.SortStage:
-		sort := make(map[string]interface{})
-		for _, sc := range s.Sorts {
-			sort[sc.Field.Path] = int(sc.Order)
-		}
 		return map[string]interface{}{
-			"$sort": sort,
+			"$sort": sortDocument(s.Sorts),
 		}, nil
 
 	case types.LimitStage:
@@ -449,7 +612,7 @@ func (r *Renderer) renderPipelineStage(stage types.PipelineStage, params *[]stri
 			limit = *s.Limit.Static
 		} else if s.Limit.Param != nil {
 			*params = append(*params, s.Limit.Param.Name)
-			limit = fmt.Sprintf(":%s", s.Limit.Param.Name)
+			limit = placeholder(s.Limit.Param.Name)
 		}
 		return map[string]interface{}{
 			"$limit": limit,
@@ -461,7 +624,7 @@ func (r *Renderer) renderPipelineStage(stage types.PipelineStage, params *[]stri
 			skip = *s.Skip.Static
 		} else if s.Skip.Param != nil {
 			*params = append(*params, s.Skip.Param.Name)
-			skip = fmt.Sprintf(":%s", s.Skip.Param.Name)
+			skip = placeholder(s.Skip.Param.Name)
 		}
 		return map[string]interface{}{
 			"$skip": skip,
@@ -492,9 +655,42 @@ func (r *Renderer) renderPipelineStage(stage types.PipelineStage, params *[]stri
 			"$lookup": lookup,
 		}, nil
 
+	case types.GraphLookupStage:
+		graph := map[string]interface{}{
+			"from":             s.From,
+			"startWith":        r.renderExpression(s.StartWith, params),
+			"connectFromField": s.ConnectFromField.Path,
+			"connectToField":   s.ConnectToField.Path,
+			"as":               s.As,
+		}
+		if s.MaxDepth != nil {
+			if s.MaxDepth.Static != nil {
+				graph["maxDepth"] = *s.MaxDepth.Static
+			} else if s.MaxDepth.Param != nil {
+				*params = append(*params, s.MaxDepth.Param.Name)
+				graph["maxDepth"] = placeholder(s.MaxDepth.Param.Name)
+			}
+		}
+		if s.DepthField != nil {
+			graph["depthField"] = *s.DepthField
+		}
+		if s.RestrictSearchWithMatch != nil {
+			filter, err := r.renderFilter(s.RestrictSearchWithMatch, params)
+			if err != nil {
+				return nil, err
+			}
+			graph["restrictSearchWithMatch"] = filter
+		}
+		return map[string]interface{}{
+			"$graphLookup": graph,
+		}, nil
+
 	case types.AddFieldsStage:
 		fields := make(map[string]interface{})
 		for name, expr := range s.Fields {
+			if !isValidKey(name) {
+				return nil, &types.InvalidIdentifierError{Kind: "$addFields key", Value: name}
+			}
 			fields[name] = r.renderExpression(expr, params)
 		}
 		return map[string]interface{}{
@@ -502,12 +698,66 @@ func (r *Renderer) renderPipelineStage(stage types.PipelineStage, params *[]stri
 		}, nil
 
 	case types.CountStage:
+		if !isValidKey(s.FieldName) {
+			return nil, &types.InvalidIdentifierError{Kind: "$count field name", Value: s.FieldName}
+		}
 		return map[string]interface{}{
 			"$count": s.FieldName,
 		}, nil
 
+	case types.FacetStage:
+		facets := make(map[string]interface{}, len(s.Facets))
+		for _, name := range slices.Sorted(maps.Keys(s.Facets)) {
+			if !isValidKey(name) {
+				return nil, &types.InvalidIdentifierError{Kind: "$facet name", Value: name}
+			}
+			pipeline := make([]map[string]interface{}, 0, len(s.Facets[name]))
+			for _, sub := range s.Facets[name] {
+				rendered, err := r.renderPipelineStage(sub, params)
+				if err != nil {
+					return nil, err
+				}
+				pipeline = append(pipeline, rendered)
+			}
+			facets[name] = pipeline
+		}
+		return map[string]interface{}{
+			"$facet": facets,
+		}, nil
+
+	case types.SortByCountStage:
+		return map[string]interface{}{
+			"$sortByCount": r.renderExpression(s.Expr, params),
+		}, nil
+
+	case types.SampleStage:
+		var size interface{}
+		if s.Size.Static != nil {
+			size = *s.Size.Static
+		} else if s.Size.Param != nil {
+			*params = append(*params, s.Size.Param.Name)
+			size = placeholder(s.Size.Param.Name)
+		}
+		return map[string]interface{}{
+			"$sample": map[string]interface{}{"size": size},
+		}, nil
+
+	case types.MergeStage:
+		merge := map[string]interface{}{
+			"into": s.Into,
+		}
+		if s.WhenMatched != "" {
+			merge["whenMatched"] = s.WhenMatched
+		}
+		if s.WhenNotMatched != "" {
+			merge["whenNotMatched"] = s.WhenNotMatched
+		}
+		return map[string]interface{}{
+			"$merge": merge,
+		}, nil
+
 	default:
-		return nil, fmt.Errorf("unsupported pipeline stage: %T", stage)
+		return nil, types.UnsupportedFeature(provider, fmt.Sprintf("pipeline stage: %T", stage))
 	}
 }
 
@@ -522,7 +772,7 @@ func (r *Renderer) renderExpression(expr types.Expression, params *[]string) int
 
 	case types.LiteralExpression:
 		*params = append(*params, e.Value.Name)
-		return fmt.Sprintf(":%s", e.Value.Name)
+		return placeholder(e.Value.Name)
 
 	case types.OperatorExpression:
 		args := make([]interface{}, len(e.Args))
@@ -542,6 +792,13 @@ func (r *Renderer) renderExpression(expr types.Expression, params *[]string) int
 			},
 		}
 
+	case types.CompositeExpression:
+		fields := make(map[string]interface{}, len(e.Fields))
+		for _, name := range slices.Sorted(maps.Keys(e.Fields)) {
+			fields[name] = r.renderExpression(e.Fields[name], params)
+		}
+		return fields
+
 	default:
 		return nil
 	}
@@ -567,13 +824,171 @@ func (r *Renderer) SupportsPipelineStage(stage string) bool {
 	return true
 }
 
+// isValidKey reports whether a user-supplied stage key is safe to emit as a JSON
+// object key. This duplicates the builder's identifier check as a second layer
+// of defense for hand-built ASTs.
+func isValidKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if i == 0 {
+			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && r != '_' {
+				return false
+			}
+		} else {
+			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// queryPool recycles top-level query maps, which never outlive toResult.
+var queryPool = sync.Pool{
+	New: func() any { return make(map[string]interface{}, 8) },
+}
+
+// encoderPool recycles serialization buffers.
+var encoderPool = sync.Pool{
+	New: func() any { return &encoder{buf: make([]byte, 0, 512)} },
+}
+
+// newQuery returns an empty query map carrying the collection, its database
+// when the schema names one, and the operation.
+func newQuery(ast *types.DocumentAST) map[string]interface{} {
+	query := queryPool.Get().(map[string]interface{})
+	query["collection"] = ast.Target.Name
+	if ast.Target.Param != nil {
+		query["collection"] = placeholder(ast.Target.Param.Name)
+	}
+	if ast.Target.Database != "" {
+		query["database"] = ast.Target.Database
+	}
+	query["operation"] = string(ast.Operation)
+	if ast.Hint != nil {
+		query["hint"] = *ast.Hint
+	}
+	if collation := queryCollation(ast); collation != nil {
+		query["collation"] = collation
+	}
+	addConcerns(query, ast)
+	return query
+}
+
+// queryCollation merges the query's collation with the options of its sort
+// clauses, which validation has checked agree. Sort options without a
+// collation use the "en" locale; case-insensitive sorting lowers the
+// strength to 2, which ignores case but not accents.
+func queryCollation(ast *types.DocumentAST) map[string]interface{} {
+	opts, ok := ast.SortOpts()
+	if ast.Collation == nil && !ok {
+		return nil
+	}
+	collation := map[string]interface{}{"locale": "en"}
+	if ast.Collation != nil {
+		collation["locale"] = ast.Collation.Locale
+		collation["strength"] = ast.Collation.Strength
+	}
+	if opts.CaseInsensitive && ast.Collation == nil {
+		collation["strength"] = 2
+	}
+	if opts.Numeric {
+		collation["numericOrdering"] = true
+	}
+	return collation
+}
+
+// addConcerns sets readConcern and writeConcern on a query. A numeric write
+// concern is emitted as a number, as MongoDB requires for acknowledgement
+// counts; anything else, such as "majority" or a tag set name, as a string.
+func addConcerns(query map[string]interface{}, ast *types.DocumentAST) {
+	if ast.ReadConcern != nil {
+		query["readConcern"] = map[string]interface{}{"level": *ast.ReadConcern}
+	}
+	if ast.WriteConcern != nil {
+		var w interface{} = *ast.WriteConcern
+		if n, err := strconv.Atoi(*ast.WriteConcern); err == nil && n >= 0 {
+			w = n
+		}
+		query["writeConcern"] = map[string]interface{}{"w": w}
+	}
+}
+
+// placeholder renders a parameter reference.
+func placeholder(name string) string {
+	return ":" + name
+}
+
+// countParams estimates the number of parameters an AST will collect so the
+// params slice is allocated once.
+func countParams(ast *types.DocumentAST) int {
+	n := countFilterParams(ast.FilterClause)
+	if ast.Target.Param != nil {
+		n++
+	}
+	if ast.Skip != nil && ast.Skip.Param != nil {
+		n++
+	}
+	if ast.Limit != nil && ast.Limit.Param != nil {
+		n++
+	}
+	for _, doc := range ast.Documents {
+		n += len(doc.Fields)
+	}
+	for _, op := range ast.UpdateOps {
+		n += len(op.Fields)
+	}
+	for _, stage := range ast.Pipeline {
+		if match, ok := stage.(types.MatchStage); ok {
+			n += countFilterParams(match.Filter)
+		}
+	}
+	return n
+}
+
+func countFilterParams(f types.FilterItem) int {
+	switch filter := f.(type) {
+	case types.FilterCondition, types.ArrayFilter:
+		return 1
+	case types.RangeFilter, types.RegexFilter, types.TextSearchFilter:
+		return 2
+	case types.GeoFilter:
+		return 3
+	case types.ValuesFilter:
+		return len(filter.Values)
+	case types.FilterGroup:
+		n := 0
+		for _, c := range filter.Conditions {
+			n += countFilterParams(c)
+		}
+		return n
+	case types.ElemMatchFilter:
+		n := 0
+		for _, c := range filter.Conditions {
+			n += countFilterParams(c)
+		}
+		return n
+	default:
+		return 0
+	}
+}
+
 func toResult(query map[string]interface{}, params []string) (*types.QueryResult, error) {
-	jsonBytes, err := json.Marshal(query)
+	e := encoderPool.Get().(*encoder)
+	defer encoderPool.Put(e)
+	e.buf = e.buf[:0]
+
+	err := e.encode(query)
+	clear(query)
+	queryPool.Put(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to serialize query: %w", err)
 	}
+
 	return &types.QueryResult{
-		JSON:           string(jsonBytes),
+		JSON:           string(e.buf),
 		RequiredParams: params,
 	}, nil
 }
@@ -1,9 +1,12 @@
 package mongodb
 
 import (
-	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 
+	"encoding/json"
 	"github.com/zoobzio/docql/internal/types"
 )
 
@@ -33,6 +36,34 @@ func TestRenderFind(t *testing.T) {
 	}
 }
 
+func TestRenderFindOne_LimitsToOne(t *testing.T) {
+	for op, want := range map[types.Operation]interface{}{
+		types.OpFindOne: float64(1),
+		types.OpFind:    nil,
+	} {
+		ast := &types.DocumentAST{
+			Operation: op,
+			Target:    types.Collection{Name: "users"},
+		}
+
+		result, err := New().Render(ast)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", op, err)
+		}
+		if result.Single != (op == types.OpFindOne) {
+			t.Errorf("%s: unexpected Single %v", op, result.Single)
+		}
+
+		var query map[string]interface{}
+		if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+			t.Fatalf("%s: failed to parse JSON: %v", op, err)
+		}
+		if query["limit"] != want {
+			t.Errorf("%s: expected limit %v, got %v", op, want, query["limit"])
+		}
+	}
+}
+
 func TestRenderFind_WithFilter(t *testing.T) {
 	ast := &types.DocumentAST{
 		Operation: types.OpFind,
@@ -239,6 +270,251 @@ func TestRenderAggregate(t *testing.T) {
 	}
 }
 
+func TestRenderAggregate_ProjectComputed(t *testing.T) {
+	total := types.OperatorExpression{Operator: "$multiply", Args: []types.Expression{
+		types.FieldExpression{Field: types.Field{Path: "price"}},
+		types.FieldExpression{Field: types.Field{Path: "qty"}},
+	}}
+	taxed := types.OperatorExpression{Operator: "$multiply", Args: []types.Expression{
+		types.FieldExpression{Field: types.Field{Path: "price"}},
+		types.LiteralExpression{Value: types.Param{Name: "rate"}},
+	}}
+	includeName := types.Projection{Fields: []types.ProjectionField{{Field: types.Field{Path: "name"}, Include: true}}}
+
+	tests := []struct {
+		name   string
+		stage  types.ProjectStage
+		want   string
+		params []string
+	}{
+		{
+			name:   "computed only",
+			stage:  types.ProjectStage{Computed: map[string]types.Expression{"total": total, "taxed": taxed}},
+			want:   `{"$project":{"taxed":{"$multiply":["$price",":rate"]},"total":{"$multiply":["$price","$qty"]}}}`,
+			params: []string{"rate"},
+		},
+		{
+			name:  "includes only",
+			stage: types.ProjectStage{Projection: includeName},
+			want:  `{"$project":{"name":1}}`,
+		},
+		{
+			name:  "mixed",
+			stage: types.ProjectStage{Projection: includeName, Computed: map[string]types.Expression{"total": total}},
+			want:  `{"$project":{"name":1,"total":{"$multiply":["$price","$qty"]}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation: types.OpAggregate,
+				Target:    types.Collection{Name: "orders"},
+				Pipeline:  []types.PipelineStage{tt.stage},
+			}
+			result, err := New().Render(ast)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(result.JSON, tt.want) {
+				t.Errorf("expected %s in %s", tt.want, result.JSON)
+			}
+			if !slices.Equal(result.RequiredParams, tt.params) {
+				t.Errorf("expected params %v, got %v", tt.params, result.RequiredParams)
+			}
+		})
+	}
+}
+
+func TestRenderAggregate_CompositeGroupID(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "orders"},
+		Pipeline: []types.PipelineStage{
+			types.GroupStage{
+				ID: types.CompositeExpression{Fields: map[string]types.Expression{
+					"status": types.FieldExpression{Field: types.Field{Path: "status"}},
+					"region": types.FieldExpression{Field: types.Field{Path: "shipping.region"}},
+				}},
+				Accumulators: map[string]types.Accumulator{
+					"total": {Operator: types.AccSum, Expr: types.FieldExpression{Field: types.Field{Path: "amount"}}},
+				},
+			},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query struct {
+		Pipeline []struct {
+			Group struct {
+				ID    map[string]string `json:"_id"`
+				Total map[string]string `json:"total"`
+			} `json:"$group"`
+		} `json:"pipeline"`
+	}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	id := query.Pipeline[0].Group.ID
+	if len(id) != 2 || id["status"] != "$status" || id["region"] != "$shipping.region" {
+		t.Errorf("unexpected _id: %v", id)
+	}
+	if query.Pipeline[0].Group.Total["$sum"] != "$amount" {
+		t.Errorf("unexpected total: %v", query.Pipeline[0].Group.Total)
+	}
+
+	ast.Pipeline[0] = types.GroupStage{ID: types.CompositeExpression{Fields: map[string]types.Expression{
+		"$where": types.FieldExpression{Field: types.Field{Path: "status"}},
+	}}}
+	if _, err := New().Render(ast); err == nil {
+		t.Error("expected error for malicious _id key")
+	}
+}
+
+func TestRenderAggregate_ArrayAccumulators(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "orders"},
+		Pipeline: []types.PipelineStage{
+			types.GroupStage{
+				ID: types.FieldExpression{Field: types.Field{Path: "userId"}},
+				Accumulators: map[string]types.Accumulator{
+					"items": {Operator: types.AccPush, Expr: types.LiteralExpression{Value: types.Param{Name: "item"}}},
+					"tags":  {Operator: types.AccAddToSet, Expr: types.FieldExpression{Field: types.Field{Path: "tag"}}},
+				},
+			},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `"pipeline":[{"$group":{"_id":"$userId","items":{"$push":":item"},"tags":{"$addToSet":"$tag"}}}]`
+	if !strings.Contains(result.JSON, want) {
+		t.Errorf("expected %s in %s", want, result.JSON)
+	}
+	if len(result.RequiredParams) != 1 || result.RequiredParams[0] != "item" {
+		t.Errorf("unexpected params: %v", result.RequiredParams)
+	}
+}
+
+func TestRenderAggregate_Facet(t *testing.T) {
+	limit := 5
+	ast := &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "orders"},
+		Pipeline: []types.PipelineStage{
+			types.FacetStage{
+				Facets: map[string][]types.PipelineStage{
+					"recent": {types.LimitStage{Limit: types.PaginationValue{Static: &limit}}},
+					"total":  {types.CountStage{FieldName: "count"}},
+				},
+			},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	pipeline := query["pipeline"].([]interface{})
+	facet, ok := pipeline[0].(map[string]interface{})["$facet"].(map[string]interface{})
+	if !ok {
+		t.Fatal("expected $facet stage")
+	}
+	if len(facet) != 2 {
+		t.Errorf("expected 2 facets, got %d", len(facet))
+	}
+}
+
+func TestRenderAggregate_Count(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "orders"},
+		Pipeline:  []types.PipelineStage{types.CountStage{FieldName: "total"}},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query struct {
+		Pipeline []map[string]interface{} `json:"pipeline"`
+	}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if query.Pipeline[0]["$count"] != "total" {
+		t.Errorf("expected $count total, got %v", query.Pipeline[0])
+	}
+}
+
+func TestRenderAggregate_RejectsEmptyCountField(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "orders"},
+		Pipeline:  []types.PipelineStage{types.CountStage{}},
+	}
+	if _, err := New().Render(ast); err == nil {
+		t.Error("expected error for empty $count field name")
+	}
+}
+
+func TestRenderAggregate_RejectsMaliciousKeys(t *testing.T) {
+	malicious := []string{
+		`x", "$where": "sleep(1000)`,
+		"$where",
+		"a.b",
+		"",
+		"bad key",
+	}
+
+	for _, key := range malicious {
+		stages := map[string]types.PipelineStage{
+			"group": types.GroupStage{
+				ID: types.FieldExpression{Field: types.Field{Path: "status"}},
+				Accumulators: map[string]types.Accumulator{
+					key: {Operator: types.AccSum, Expr: types.FieldExpression{Field: types.Field{Path: "total"}}},
+				},
+			},
+			"addFields": types.AddFieldsStage{
+				Fields: map[string]types.Expression{
+					key: types.FieldExpression{Field: types.Field{Path: "total"}},
+				},
+			},
+			"facet": types.FacetStage{
+				Facets: map[string][]types.PipelineStage{
+					key: {types.CountStage{FieldName: "count"}},
+				},
+			},
+			"count": types.CountStage{FieldName: key},
+		}
+
+		for kind, stage := range stages {
+			ast := &types.DocumentAST{
+				Operation: types.OpAggregate,
+				Target:    types.Collection{Name: "orders"},
+				Pipeline:  []types.PipelineStage{stage},
+			}
+			if _, err := New().Render(ast); err == nil {
+				t.Errorf("expected error for %s key %q", kind, key)
+			}
+		}
+	}
+}
+
 func TestSupportsOperation(t *testing.T) {
 	renderer := New()
 
@@ -249,3 +525,910 @@ func TestSupportsOperation(t *testing.T) {
 		t.Error("expected MongoDB to support OpAggregate")
 	}
 }
+
+func TestRenderFind_WithMaxTimeMS(t *testing.T) {
+	maxTime := 2500
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		MaxTimeMS: &maxTime,
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	if query["maxTimeMS"] != float64(2500) {
+		t.Errorf("expected maxTimeMS 2500, got %v", query["maxTimeMS"])
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", result.Warnings)
+	}
+}
+
+func TestRenderFind_WithoutMaxTimeMS(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	if _, ok := query["maxTimeMS"]; ok {
+		t.Error("expected maxTimeMS to be omitted")
+	}
+}
+
+func TestRenderUpdateMany_Upsert(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpUpdateMany,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{
+			Field:    types.Field{Path: "status"},
+			Operator: types.EQ,
+			Value:    types.Param{Name: "oldStatus"},
+		},
+		UpdateOps: []types.UpdateOperation{
+			{
+				Operator: types.Set,
+				Fields: map[types.Field]types.Param{
+					{Path: "status"}: {Name: "newStatus"},
+				},
+			},
+		},
+		Upsert: true,
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	if query["upsert"] != true {
+		t.Errorf("expected upsert true, got %v", query["upsert"])
+	}
+	if query["operation"] != "UPDATE_MANY" {
+		t.Errorf("expected operation UPDATE_MANY, got %v", query["operation"])
+	}
+	if len(result.RequiredParams) != 2 {
+		t.Errorf("expected 2 required params, got %d", len(result.RequiredParams))
+	}
+}
+
+func TestRenderAggregate_LimitStage(t *testing.T) {
+	limit := 10
+	ast := &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "orders"},
+		Pipeline: []types.PipelineStage{
+			types.SkipStage{Skip: types.PaginationValue{Param: &types.Param{Name: "offset"}}},
+			types.LimitStage{Limit: types.PaginationValue{Static: &limit}},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	pipeline := query["pipeline"].([]interface{})
+	if pipeline[0].(map[string]interface{})["$skip"] != ":offset" {
+		t.Errorf("expected $skip :offset, got %v", pipeline[0])
+	}
+	if pipeline[1].(map[string]interface{})["$limit"] != float64(10) {
+		t.Errorf("expected $limit 10, got %v", pipeline[1])
+	}
+}
+
+func TestRenderFind_ProjectionWithoutID(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: types.IDField}, Include: false},
+			{Field: types.Field{Path: "email"}, Include: true},
+		}},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	proj := query["projection"].(map[string]interface{})
+	if proj["_id"] != float64(0) {
+		t.Errorf("expected _id: 0, got %v", proj["_id"])
+	}
+	if proj["email"] != float64(1) {
+		t.Errorf("expected email: 1, got %v", proj["email"])
+	}
+}
+
+func TestRenderFind_ProjectionAliases(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []types.ProjectionField
+		want   map[string]interface{}
+	}{
+		{
+			name: "aliased and plain includes",
+			fields: []types.ProjectionField{
+				{Field: types.Field{Path: "name"}, Include: true},
+				{Field: types.Field{Path: "createdAt"}, Include: true, Alias: "created_at"},
+			},
+			want: map[string]interface{}{"name": float64(1), "created_at": "$createdAt"},
+		},
+		{
+			name: "aliased _id is excluded",
+			fields: []types.ProjectionField{
+				{Field: types.Field{Path: types.IDField}, Include: true, Alias: "id"},
+				{Field: types.Field{Path: "name"}, Include: true},
+			},
+			want: map[string]interface{}{"id": "$_id", "_id": float64(0), "name": float64(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:  types.OpFind,
+				Target:     types.Collection{Name: "users"},
+				Projection: &types.Projection{Fields: tt.fields},
+			}
+			result, err := New().Render(ast)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.FieldAliases != nil {
+				t.Errorf("MongoDB renames server-side, got FieldAliases %v", result.FieldAliases)
+			}
+
+			var query map[string]interface{}
+			if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			proj := query["projection"].(map[string]interface{})
+			if len(proj) != len(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, proj)
+			}
+			for k, v := range tt.want {
+				if proj[k] != v {
+					t.Errorf("projection[%s] = %v, want %v", k, proj[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderFind_RejectsMixedProjection(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "name"}, Include: false},
+			{Field: types.Field{Path: "email"}, Include: true},
+		}},
+	}
+
+	if _, err := New().Render(ast); err == nil {
+		t.Error("expected error for mixed include/exclude projection")
+	}
+}
+
+func TestRender_WriteOperationStrings(t *testing.T) {
+	filter := types.FilterCondition{
+		Field:    types.Field{Path: "status"},
+		Operator: types.EQ,
+		Value:    types.Param{Name: "status"},
+	}
+	update := []types.UpdateOperation{{
+		Operator: types.Set,
+		Fields:   map[types.Field]types.Param{{Path: "status"}: {Name: "newStatus"}},
+	}}
+
+	tests := []struct {
+		ast  *types.DocumentAST
+		want string
+	}{
+		{&types.DocumentAST{Operation: types.OpUpdate, Target: types.Collection{Name: "users"}, FilterClause: filter, UpdateOps: update}, "UPDATE"},
+		{&types.DocumentAST{Operation: types.OpUpdateMany, Target: types.Collection{Name: "users"}, FilterClause: filter, UpdateOps: update}, "UPDATE_MANY"},
+		{&types.DocumentAST{Operation: types.OpDelete, Target: types.Collection{Name: "users"}, FilterClause: filter}, "DELETE"},
+		{&types.DocumentAST{Operation: types.OpDeleteMany, Target: types.Collection{Name: "users"}, FilterClause: filter}, "DELETE_MANY"},
+	}
+
+	for _, tt := range tests {
+		result, err := New().Render(tt.ast)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", tt.want, err)
+		}
+
+		var query map[string]interface{}
+		if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		if query["operation"] != tt.want {
+			t.Errorf("expected operation %s, got %v", tt.want, query["operation"])
+		}
+	}
+}
+
+func TestRender_RequireFilter(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation:     types.OpDelete,
+		Target:        types.Collection{Name: "users"},
+		RequireFilter: true,
+	}
+
+	if _, err := New().Render(ast); err == nil {
+		t.Error("expected error for unfiltered delete with RequireFilter")
+	}
+}
+
+func TestRenderFind_InValues(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+			types.ValuesFilter{Field: types.Field{Path: "status"}, Operator: types.IN, Values: []types.Param{
+				{Name: "a"}, {Name: "b"}, {Name: "c"},
+			}},
+			types.ValuesFilter{Field: types.Field{Path: "role"}, Operator: types.NotIn, Values: []types.Param{
+				{Name: "banned"},
+			}},
+		}},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query struct {
+		Filter struct {
+			And []map[string]map[string][]string `json:"$and"`
+		} `json:"filter"`
+	}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	in := query.Filter.And[0]["status"]["$in"]
+	if len(in) != 3 || in[0] != ":a" || in[1] != ":b" || in[2] != ":c" {
+		t.Errorf("expected $in [:a :b :c], got %v", in)
+	}
+	nin := query.Filter.And[1]["role"]["$nin"]
+	if len(nin) != 1 || nin[0] != ":banned" {
+		t.Errorf("expected $nin [:banned], got %v", nin)
+	}
+
+	want := []string{"a", "b", "c", "banned"}
+	if len(result.RequiredParams) != len(want) {
+		t.Fatalf("expected params %v, got %v", want, result.RequiredParams)
+	}
+	for i, p := range want {
+		if result.RequiredParams[i] != p {
+			t.Errorf("param %d: expected %s, got %s", i, p, result.RequiredParams[i])
+		}
+	}
+}
+
+func TestRenderFind_Mod(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.ModFilter{
+			Field:     types.Field{Path: "userId"},
+			Divisor:   types.Param{Name: "shards"},
+			Remainder: types.Param{Name: "bucket"},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"collection":"users","filter":{"userId":{"$mod":[":shards",":bucket"]}},"operation":"FIND"}`
+	if result.JSON != want {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, want)
+	}
+	if len(result.RequiredParams) != 2 || result.RequiredParams[0] != "shards" || result.RequiredParams[1] != "bucket" {
+		t.Errorf("unexpected params: %v", result.RequiredParams)
+	}
+}
+
+func TestRenderAggregate_AnalyticsStages(t *testing.T) {
+	size := 100
+	ast := &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "orders"},
+		Pipeline: []types.PipelineStage{
+			types.SampleStage{Size: types.PaginationValue{Static: &size}},
+			types.SortByCountStage{Expr: types.FieldExpression{Field: types.Field{Path: "status"}}},
+			types.MergeStage{Into: "status_counts", WhenMatched: types.MergeReplace},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query struct {
+		Pipeline []map[string]interface{} `json:"pipeline"`
+	}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	sample := query.Pipeline[0]["$sample"].(map[string]interface{})
+	if sample["size"] != float64(100) {
+		t.Errorf("expected $sample size 100, got %v", sample)
+	}
+	if query.Pipeline[1]["$sortByCount"] != "$status" {
+		t.Errorf("expected $sortByCount $status, got %v", query.Pipeline[1])
+	}
+	merge := query.Pipeline[2]["$merge"].(map[string]interface{})
+	if merge["into"] != "status_counts" || merge["whenMatched"] != "replace" {
+		t.Errorf("unexpected $merge: %v", merge)
+	}
+	if _, ok := merge["whenNotMatched"]; ok {
+		t.Errorf("expected whenNotMatched to be omitted, got %v", merge)
+	}
+}
+
+func TestRenderAggregate_SampleParam(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "orders"},
+		Pipeline: []types.PipelineStage{
+			types.SampleStage{Size: types.PaginationValue{Param: &types.Param{Name: "n"}}},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.RequiredParams) != 1 || result.RequiredParams[0] != "n" {
+		t.Errorf("expected params [n], got %v", result.RequiredParams)
+	}
+}
+
+func TestRenderAggregate_GraphLookup(t *testing.T) {
+	depthField := "level"
+	ast := &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "employees"},
+		Pipeline: []types.PipelineStage{
+			types.GraphLookupStage{
+				From:             "employees",
+				StartWith:        types.FieldExpression{Field: types.Field{Path: "managerId"}},
+				ConnectFromField: types.Field{Path: "managerId"},
+				ConnectToField:   types.Field{Path: "_id"},
+				As:               "chain",
+				MaxDepth:         &types.PaginationValue{Param: &types.Param{Name: "depth"}},
+				DepthField:       &depthField,
+				RestrictSearchWithMatch: types.FilterCondition{
+					Field: types.Field{Path: "active"}, Operator: types.EQ, Value: types.Param{Name: "active"},
+				},
+			},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query struct {
+		Pipeline []map[string]map[string]interface{} `json:"pipeline"`
+	}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	graph := query.Pipeline[0]["$graphLookup"]
+	checks := map[string]interface{}{
+		"from":             "employees",
+		"startWith":        "$managerId",
+		"connectFromField": "managerId",
+		"connectToField":   "_id",
+		"as":               "chain",
+		"maxDepth":         ":depth",
+		"depthField":       "level",
+	}
+	for key, want := range checks {
+		if graph[key] != want {
+			t.Errorf("%s: expected %v, got %v", key, want, graph[key])
+		}
+	}
+	restrict := graph["restrictSearchWithMatch"].(map[string]interface{})
+	if restrict["active"].(map[string]interface{})["$eq"] != ":active" {
+		t.Errorf("unexpected restrictSearchWithMatch: %v", restrict)
+	}
+
+	want := []string{"depth", "active"}
+	if len(result.RequiredParams) != 2 || result.RequiredParams[0] != want[0] || result.RequiredParams[1] != want[1] {
+		t.Errorf("expected params %v, got %v", want, result.RequiredParams)
+	}
+}
+
+func TestRenderAggregate_UnwindOptions(t *testing.T) {
+	idx := "idx"
+	ast := &types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "orders"},
+		Pipeline: []types.PipelineStage{
+			types.UnwindStage{Path: types.Field{Path: "items"}, IncludeArrayIndex: &idx, PreserveNullAndEmptyArrays: true},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var query struct {
+		Pipeline []map[string]map[string]interface{} `json:"pipeline"`
+	}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	unwind := query.Pipeline[0]["$unwind"]
+	if unwind["path"] != "$items" || unwind["includeArrayIndex"] != "idx" || unwind["preserveNullAndEmptyArrays"] != true {
+		t.Errorf("unexpected $unwind: %v", unwind)
+	}
+}
+
+func TestRenderTransaction(t *testing.T) {
+	id := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+	ast := &types.DocumentAST{
+		Operation: types.OpTransaction,
+		Operations: []*types.DocumentAST{
+			{
+				Operation: types.OpInsert,
+				Target:    types.Collection{Name: "orders"},
+				Documents: []types.Document{{Fields: map[types.Field]types.Param{{Path: "userId"}: {Name: "id"}}}},
+			},
+			{
+				Operation:    types.OpUpdate,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: id,
+				UpdateOps:    []types.UpdateOperation{{Operator: types.Inc, Fields: map[types.Field]types.Param{{Path: "orders"}: {Name: "one"}}}},
+			},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"operation":"TRANSACTION","operations":[` +
+		`{"collection":"orders","document":{"userId":":id"},"operation":"INSERT"},` +
+		`{"collection":"users","filter":{"_id":{"$eq":":id"}},"operation":"UPDATE","update":{"$inc":{"orders":":one"}}}]}`
+	if result.JSON != expected {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, expected)
+	}
+	if len(result.RequiredParams) != 2 || result.RequiredParams[0] != "id" || result.RequiredParams[1] != "one" {
+		t.Errorf("expected deduplicated params [id one], got %v", result.RequiredParams)
+	}
+	if result.Operation != types.OpTransaction || result.Collection != "" {
+		t.Errorf("unexpected result metadata: %s %q", result.Operation, result.Collection)
+	}
+}
+
+func TestRender_Hint(t *testing.T) {
+	filter := types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "status"}}
+	hint := "status_1"
+
+	for _, op := range []types.Operation{types.OpFind, types.OpCount, types.OpDeleteMany} {
+		t.Run(string(op), func(t *testing.T) {
+			result, err := New().Render(&types.DocumentAST{
+				Operation:    op,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: filter,
+				Hint:         &hint,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var query map[string]interface{}
+			if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if query["hint"] != "status_1" {
+				t.Errorf("expected hint status_1, got %v", query["hint"])
+			}
+		})
+	}
+}
+
+func TestRender_Collation(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation:   types.OpFind,
+		Target:      types.Collection{Name: "users"},
+		SortClauses: []types.SortClause{{Field: types.Field{Path: "name"}, Order: types.Ascending}},
+		Collation:   &types.Collation{Locale: "en", Strength: 2},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"collation":{"locale":"en","strength":2},"collection":"users","filter":{},"operation":"FIND","sort":{"name":1}}`
+	if result.JSON != expected {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, expected)
+	}
+
+	ast.Collation = nil
+	result, err = New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if _, ok := query["collation"]; ok {
+		t.Errorf("expected no collation, got %s", result.JSON)
+	}
+}
+
+func TestRender_SortOptsCollation(t *testing.T) {
+	name := types.Field{Path: "name"}
+	sku := types.Field{Path: "sku"}
+	tests := []struct {
+		name string
+		ast  *types.DocumentAST
+		want string
+	}{
+		{
+			name: "numeric sort keeps collation strength",
+			ast: &types.DocumentAST{
+				Operation:   types.OpFind,
+				Target:      types.Collection{Name: "products"},
+				SortClauses: []types.SortClause{{Field: sku, Order: types.Ascending, Opts: types.SortOpts{Numeric: true}}},
+				Collation:   &types.Collation{Locale: "fr", Strength: 3},
+			},
+			want: `{"collation":{"locale":"fr","numericOrdering":true,"strength":3},"collection":"products","filter":{},"operation":"FIND","sort":{"sku":1}}`,
+		},
+		{
+			name: "case-insensitive sort lowers strength",
+			ast: &types.DocumentAST{
+				Operation: types.OpFind,
+				Target:    types.Collection{Name: "users"},
+				SortClauses: []types.SortClause{
+					{Field: name, Order: types.Ascending, Opts: types.SortOpts{CaseInsensitive: true}},
+					{Field: sku, Order: types.Descending},
+				},
+			},
+			want: `{"collation":{"locale":"en","strength":2},"collection":"users","filter":{},"operation":"FIND","sort":{"name":1,"sku":-1}}`,
+		},
+		{
+			name: "pipeline sort stage",
+			ast: &types.DocumentAST{
+				Operation: types.OpAggregate,
+				Target:    types.Collection{Name: "products"},
+				Pipeline: []types.PipelineStage{
+					types.SortStage{Sorts: []types.SortClause{{Field: sku, Order: types.Ascending, Opts: types.SortOpts{Numeric: true}}}},
+				},
+			},
+			want: `{"collation":{"locale":"en","numericOrdering":true},"collection":"products","operation":"AGGREGATE","pipeline":[{"$sort":{"sku":1}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := New().Render(tt.ast)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.JSON != tt.want {
+				t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender_Concerns(t *testing.T) {
+	filter := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+	majority, one, snapshot := "majority", "1", "snapshot"
+
+	tests := []struct {
+		name    string
+		ast     *types.DocumentAST
+		want    string
+		without string
+	}{
+		{
+			name:    "read",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: types.Collection{Name: "users"}, ReadConcern: &majority},
+			want:    `"readConcern":{"level":"majority"}`,
+			without: `"writeConcern"`,
+		},
+		{
+			name:    "numeric write",
+			ast:     &types.DocumentAST{Operation: types.OpDelete, Target: types.Collection{Name: "users"}, FilterClause: filter, WriteConcern: &one},
+			want:    `"writeConcern":{"w":1}`,
+			without: `"readConcern"`,
+		},
+		{
+			name: "transaction",
+			ast: &types.DocumentAST{
+				Operation:    types.OpTransaction,
+				Operations:   []*types.DocumentAST{{Operation: types.OpDelete, Target: types.Collection{Name: "users"}, FilterClause: filter}},
+				ReadConcern:  &snapshot,
+				WriteConcern: &majority,
+			},
+			want: `"readConcern":{"level":"snapshot"},"writeConcern":{"w":"majority"}`,
+		},
+		{
+			name:    "none",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: types.Collection{Name: "users"}},
+			want:    `"operation":"FIND"`,
+			without: "Concern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := New().Render(tt.ast)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(result.JSON, tt.want) {
+				t.Errorf("expected %s in %s", tt.want, result.JSON)
+			}
+			if tt.without != "" && strings.Contains(result.JSON, tt.without) {
+				t.Errorf("unexpected %s in %s", tt.without, result.JSON)
+			}
+		})
+	}
+}
+
+func TestRenderAggregate_Options(t *testing.T) {
+	maxTime := 5000
+	ast := &types.DocumentAST{
+		Operation:    types.OpAggregate,
+		Target:       types.Collection{Name: "orders"},
+		Pipeline:     []types.PipelineStage{types.CountStage{FieldName: "n"}},
+		MaxTimeMS:    &maxTime,
+		AllowDiskUse: true,
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"allowDiskUse":true,"collection":"orders","maxTimeMS":5000,"operation":"AGGREGATE","pipeline":[{"$count":"n"}]}`
+	if result.JSON != expected {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, expected)
+	}
+
+	ast.MaxTimeMS, ast.AllowDiskUse = nil, false
+	result, err = New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result.JSON, "allowDiskUse") || strings.Contains(result.JSON, "maxTimeMS") {
+		t.Errorf("expected no options, got %s", result.JSON)
+	}
+}
+
+func TestRenderFind_TypeIs(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation:    types.OpFind,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.TypeFilter{Field: types.Field{Path: "age"}, TypeName: "long"},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"collection":"users","filter":{"age":{"$type":"long"}},"operation":"FIND"}`
+	if result.JSON != want {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, want)
+	}
+	if len(result.RequiredParams) != 0 {
+		t.Errorf("expected no params, got %v", result.RequiredParams)
+	}
+}
+
+func TestRender_ParamCollection(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "orders", Param: &types.Param{Name: "tenantOrders"}},
+		FilterClause: types.FilterCondition{
+			Field:    types.Field{Path: "status"},
+			Operator: types.EQ,
+			Value:    types.Param{Name: "status"},
+		},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"collection":":tenantOrders","filter":{"status":{"$eq":":status"}},"operation":"FIND"}`
+	if result.JSON != want {
+		t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, want)
+	}
+	if !slices.Equal(result.RequiredParams, []string{"tenantOrders", "status"}) {
+		t.Errorf("unexpected params: %v", result.RequiredParams)
+	}
+	if result.Collection != "orders" {
+		t.Errorf("expected base collection name, got %s", result.Collection)
+	}
+
+	ast.Target.Name = "orders; drop"
+	if _, err := New().Render(ast); !errors.Is(err, types.ErrInvalidIdentifier) {
+		t.Errorf("expected invalid base name to be rejected, got %v", err)
+	}
+}
+
+func TestRender_Fingerprint(t *testing.T) {
+	find := func(filter types.FilterItem, order types.SortOrder) *types.DocumentAST {
+		return &types.DocumentAST{
+			Operation:    types.OpFind,
+			Target:       types.Collection{Name: "users"},
+			FilterClause: filter,
+			SortClauses:  []types.SortClause{{Field: types.Field{Path: "age"}, Order: order}},
+		}
+	}
+	filter := func() types.FilterItem {
+		return types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+			types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "status"}},
+			types.RangeFilter{Field: types.Field{Path: "age"}, Min: &types.Param{Name: "min"}},
+		}}
+	}
+
+	fingerprint := func(ast *types.DocumentAST) string {
+		t.Helper()
+		result, err := New().Render(ast)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.Fingerprint()
+	}
+
+	a := fingerprint(find(filter(), types.Ascending))
+	for range 10 {
+		if b := fingerprint(find(filter(), types.Ascending)); b != a {
+			t.Fatalf("identical queries produced different fingerprints: %s, %s", a, b)
+		}
+	}
+	if desc := fingerprint(find(filter(), types.Descending)); desc == a {
+		t.Error("expected sort order to change the fingerprint")
+	}
+
+	sortBy := func(paths ...string) *types.DocumentAST {
+		ast := find(filter(), types.Ascending)
+		ast.SortClauses = nil
+		for _, path := range paths {
+			ast.SortClauses = append(ast.SortClauses, types.SortClause{Field: types.Field{Path: path}, Order: types.Ascending})
+		}
+		return ast
+	}
+	if fingerprint(sortBy("age", "name")) == fingerprint(sortBy("name", "age")) {
+		t.Error("expected the order of sort fields to change the fingerprint")
+	}
+}
+
+func TestRender_EmptyParams(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	email := types.Field{Path: "email"}
+	age := types.Field{Path: "age"}
+	byKey := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+
+	tests := []struct {
+		name    string
+		ast     *types.DocumentAST
+		context string
+	}{
+		{
+			name:    "filter",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.FilterCondition{Field: email, Operator: types.EQ}},
+			context: "$eq on field 'email'",
+		},
+		{
+			name:    "range bound",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.RangeFilter{Field: age, Min: &types.Param{}}},
+			context: "range on field 'age'",
+		},
+		{
+			name:    "document",
+			ast:     &types.DocumentAST{Operation: types.OpInsert, Target: users, Documents: []types.Document{{Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "document field 'email'",
+		},
+		{
+			name: "update",
+			ast: &types.DocumentAST{Operation: types.OpUpdate, Target: users, FilterClause: byKey,
+				UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "$set field 'email'",
+		},
+		{
+			name:    "pagination",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: byKey, Limit: &types.PaginationValue{Param: &types.Param{}}},
+			context: "limit",
+		},
+		{
+			name: "pipeline expression",
+			ast: &types.DocumentAST{Operation: types.OpAggregate, Target: users,
+				Pipeline: []types.PipelineStage{types.AddFieldsStage{Fields: map[string]types.Expression{"flag": types.LiteralExpression{}}}}},
+			context: "$addFields field 'flag'",
+		},
+	}
+
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Render(tt.ast)
+			if !errors.Is(err, types.ErrInvalidIdentifier) {
+				t.Fatalf("expected invalid identifier error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.context) {
+				t.Errorf("expected error naming %q, got %v", tt.context, err)
+			}
+		})
+	}
+}
+
+func TestRender_ValuelessUpdateOperators(t *testing.T) {
+	r := New()
+	for _, op := range []types.UpdateOperator{types.Unset, types.CurrentDate} {
+		t.Run(string(op), func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:    types.OpUpdate,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+				UpdateOps:    []types.UpdateOperation{{Operator: op, Fields: map[types.Field]types.Param{{Path: "deletedAt"}: {}}}},
+			}
+			result, err := r.Render(ast)
+			if !r.SupportsUpdate(op) {
+				var unsupported *types.UnsupportedError
+				if !errors.As(err, &unsupported) {
+					t.Errorf("expected unsupported operator error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if slices.Contains(result.RequiredParams, "") {
+				t.Errorf("unexpected empty param in %v", result.RequiredParams)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package mongodb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// Parse lifts a MongoDB filter document into a docql filter so existing
+// queries can be re-rendered for other providers. Concrete values become
+// generated params named p0, p1, ... in document order (keys sorted), and the
+// returned map holds each param's value: numbers decode as int64 when
+// integral and float64 otherwise. Fields are attributed to collection.
+//
+// Recognized: $and, $or, $nor, $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin,
+// $exists, $regex with $options, $elemMatch, $all, and $size. A plain value
+// is an equality match; several keys in one document are combined with AND.
+// An empty document returns a nil filter. Unknown operators fail with the
+// JSON path where they appear.
+func Parse(filterJSON []byte, collection string) (types.FilterItem, map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(filterJSON))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid filter JSON: %w", err)
+	}
+
+	p := &filterParser{collection: collection, values: make(map[string]interface{})}
+	f, err := p.document(doc, "$")
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, p.values, nil
+}
+
+type filterParser struct {
+	collection string
+	values     map[string]interface{}
+}
+
+// param records value under the next generated param name.
+func (p *filterParser) param(value interface{}) types.Param {
+	name := fmt.Sprintf("p%d", len(p.values))
+	p.values[name] = literal(value)
+	return types.Param{Name: name}
+}
+
+// document parses a filter document; path locates it for error messages.
+func (p *filterParser) document(doc map[string]interface{}, path string) (types.FilterItem, error) {
+	var items []types.FilterItem
+	for _, key := range slices.Sorted(maps.Keys(doc)) {
+		keyPath := path + "." + key
+		value := doc[key]
+
+		var item types.FilterItem
+		var err error
+		switch key {
+		case "$and", "$or", "$nor":
+			item, err = p.group(types.LogicOperator(key), value, keyPath)
+		default:
+			if strings.HasPrefix(key, "$") {
+				return nil, fmt.Errorf("%s: unsupported top-level operator %s", keyPath, key)
+			}
+			item, err = p.field(key, value, keyPath)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return combine(items), nil
+}
+
+func (p *filterParser) group(logic types.LogicOperator, value interface{}, path string) (types.FilterItem, error) {
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%s: %s requires a non-empty array", path, logic)
+	}
+	conditions := make([]types.FilterItem, 0, len(list))
+	for i, entry := range list {
+		entryPath := fmt.Sprintf("%s[%d]", path, i)
+		doc, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a filter document", entryPath)
+		}
+		f, err := p.document(doc, entryPath)
+		if err != nil {
+			return nil, err
+		}
+		if f == nil {
+			return nil, fmt.Errorf("%s: empty filter document", entryPath)
+		}
+		conditions = append(conditions, f)
+	}
+	return types.FilterGroup{Logic: logic, Conditions: conditions}, nil
+}
+
+func (p *filterParser) field(name string, value interface{}, path string) (types.FilterItem, error) {
+	field := types.Field{Path: name, Collection: p.collection}
+
+	ops, ok := value.(map[string]interface{})
+	if !ok || !isOperatorDoc(ops) {
+		return types.FilterCondition{Field: field, Operator: types.EQ, Value: p.param(value)}, nil
+	}
+
+	if _, ok := ops["$options"]; ok {
+		if _, ok := ops["$regex"]; !ok {
+			return nil, fmt.Errorf("%s.$options: $options requires $regex", path)
+		}
+	}
+
+	var items []types.FilterItem
+	for _, op := range slices.Sorted(maps.Keys(ops)) {
+		opPath := path + "." + op
+		arg := ops[op]
+
+		switch op {
+		case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte":
+			items = append(items, types.FilterCondition{Field: field, Operator: types.FilterOperator(op), Value: p.param(arg)})
+
+		case "$in", "$nin":
+			list, ok := arg.([]interface{})
+			if !ok || len(list) == 0 {
+				return nil, fmt.Errorf("%s: %s requires a non-empty array", opPath, op)
+			}
+			values := make([]types.Param, len(list))
+			for i, v := range list {
+				values[i] = p.param(v)
+			}
+			items = append(items, types.ValuesFilter{Field: field, Operator: types.FilterOperator(op), Values: values})
+
+		case "$exists":
+			exists, ok := arg.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%s: $exists requires a boolean", opPath)
+			}
+			items = append(items, types.ExistsFilter{Field: field, Exists: exists})
+
+		case "$regex":
+			pattern, ok := arg.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: $regex requires a string", opPath)
+			}
+			regex := types.RegexFilter{Field: field, Pattern: p.param(pattern)}
+			if opts, ok := ops["$options"]; ok {
+				s, ok := opts.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s.$options: $options requires a string", path)
+				}
+				options := p.param(s)
+				regex.Options = &options
+			}
+			items = append(items, regex)
+
+		case "$options":
+			// Consumed with $regex.
+
+		case "$all":
+			if _, ok := arg.([]interface{}); !ok {
+				return nil, fmt.Errorf("%s: $all requires an array", opPath)
+			}
+			items = append(items, types.ArrayFilter{Field: field, Operator: types.All, Value: p.param(arg)})
+
+		case "$size":
+			if _, ok := arg.(json.Number); !ok {
+				return nil, fmt.Errorf("%s: $size requires a number", opPath)
+			}
+			items = append(items, types.ArrayFilter{Field: field, Operator: types.Size, Value: p.param(arg)})
+
+		case "$elemMatch":
+			doc, ok := arg.(map[string]interface{})
+			if !ok || len(doc) == 0 {
+				return nil, fmt.Errorf("%s: $elemMatch requires a non-empty document", opPath)
+			}
+			for key := range doc {
+				if strings.HasPrefix(key, "$") && key != "$and" && key != "$or" && key != "$nor" {
+					return nil, fmt.Errorf("%s: $elemMatch on scalar elements is not supported", opPath)
+				}
+			}
+			inner, err := p.document(doc, opPath)
+			if err != nil {
+				return nil, err
+			}
+			conditions := []types.FilterItem{inner}
+			if group, ok := inner.(types.FilterGroup); ok && group.Logic == types.AND {
+				conditions = group.Conditions
+			}
+			items = append(items, types.ElemMatchFilter{Field: field, Conditions: conditions})
+
+		default:
+			return nil, fmt.Errorf("%s: unsupported operator %s", opPath, op)
+		}
+	}
+	return combine(items), nil
+}
+
+// isOperatorDoc reports whether every key of doc is an operator, which makes
+// it a set of conditions rather than an embedded document to match exactly.
+func isOperatorDoc(doc map[string]interface{}) bool {
+	if len(doc) == 0 {
+		return false
+	}
+	for key := range doc {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// combine joins items with AND, collapsing a single item and returning nil
+// for none.
+func combine(items []types.FilterItem) types.FilterItem {
+	switch len(items) {
+	case 0:
+		return nil
+	case 1:
+		return items[0]
+	default:
+		return types.FilterGroup{Logic: types.AND, Conditions: items}
+	}
+}
+
+// literal converts decoded JSON numbers to int64 or float64, recursively.
+func literal(v interface{}) interface{} {
+	switch value := v.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, item := range value {
+			out[i] = literal(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			out[k] = literal(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,234 @@
+package mongodb
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+func TestParse_RoundTrip(t *testing.T) {
+	filters := []string{
+		`{"status": "active"}`,
+		`{"age": {"$gte": 18, "$lt": 65}, "name": "alice"}`,
+		`{"score": {"$gt": 1.5}, "deleted": {"$exists": false}}`,
+		`{"status": {"$in": ["a", "b"]}, "role": {"$nin": ["admin"]}}`,
+		`{"name": {"$regex": "^al", "$options": "i"}}`,
+		`{"email": {"$regex": "@example\\.com$"}}`,
+		`{"tags": {"$all": ["go", "db"]}, "items": {"$size": 3}}`,
+		`{"address": {"city": "Paris", "zip": "75001"}}`,
+		`{"$or": [{"status": "active"}, {"age": {"$ne": 0}}]}`,
+		`{"$and": [{"a": 1}, {"$nor": [{"b": 2}, {"c": {"$lte": 3}}]}]}`,
+		`{"results": {"$elemMatch": {"product": "xyz", "score": {"$gte": 8}}}}`,
+		`{"results": {"$elemMatch": {"$or": [{"score": 1}, {"score": 2}]}}}`,
+		`{"$or": [{"a": {"$eq": null}}, {"b": true}], "c": {"$in": [1, 2.5, "x"]}}`,
+	}
+
+	renderer := New()
+	for _, input := range filters {
+		t.Run(input, func(t *testing.T) {
+			filter, values, err := Parse([]byte(input), "users")
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			result, err := renderer.Render(&types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: filter,
+			})
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			for _, p := range result.RequiredParams {
+				if _, ok := values[p]; !ok {
+					t.Errorf("rendered param %q has no value", p)
+				}
+			}
+
+			var query map[string]interface{}
+			if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			rendered := substituteParams(t, query["filter"], values)
+
+			var original interface{}
+			if err := json.Unmarshal([]byte(input), &original); err != nil {
+				t.Fatalf("failed to parse input: %v", err)
+			}
+
+			want, got := normalizeFilter(t, original), normalizeFilter(t, rendered)
+			if !slices.Equal(want, got) {
+				t.Errorf("round trip mismatch\nwant: %v\n got: %v\nJSON: %s", want, got, result.JSON)
+			}
+		})
+	}
+}
+
+func TestParse_Values(t *testing.T) {
+	filter, values, err := Parse([]byte(`{"age": {"$gt": 18}, "score": 2.5, "tags": {"$all": [1, "x"]}}`), "users")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if values["p0"] != int64(18) || values["p1"] != 2.5 {
+		t.Errorf("unexpected numeric values: %v", values)
+	}
+	if all, ok := values["p2"].([]interface{}); !ok || all[0] != int64(1) || all[1] != "x" {
+		t.Errorf("unexpected $all value: %v", values["p2"])
+	}
+
+	group, ok := filter.(types.FilterGroup)
+	if !ok || group.Logic != types.AND || len(group.Conditions) != 3 {
+		t.Fatalf("expected AND group of 3, got %#v", filter)
+	}
+	cond, ok := group.Conditions[0].(types.FilterCondition)
+	if !ok || cond.Field.Path != "age" || cond.Field.Collection != "users" || cond.Operator != types.GT {
+		t.Errorf("unexpected first condition: %#v", group.Conditions[0])
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	filter, values, err := Parse([]byte(`{}`), "users")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if filter != nil || len(values) != 0 {
+		t.Errorf("expected nil filter and no values, got %#v %v", filter, values)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"age": {"$gtx": 1}}`, "$.age.$gtx: unsupported operator $gtx"},
+		{`{"$or": [{"a": 1}, {"b": {"$near": [0, 0]}}]}`, "$.$or[1].b.$near: unsupported operator $near"},
+		{`{"$where": "this.a > 1"}`, "$.$where: unsupported top-level operator $where"},
+		{`{"r": {"$elemMatch": {"s": {"$mod": [2, 0]}}}}`, "$.r.$elemMatch.s.$mod: unsupported operator $mod"},
+		{`{"$and": []}`, "$.$and: $and requires a non-empty array"},
+		{`{"$or": [1]}`, "$.$or[0]: expected a filter document"},
+		{`{"$nor": [{}]}`, "$.$nor[0]: empty filter document"},
+		{`{"a": {"$in": "x"}}`, "$.a.$in: $in requires a non-empty array"},
+		{`{"a": {"$exists": 1}}`, "$.a.$exists: $exists requires a boolean"},
+		{`{"a": {"$regex": 1}}`, "$.a.$regex: $regex requires a string"},
+		{`{"a": {"$options": "i"}}`, "$.a.$options: $options requires $regex"},
+		{`{"a": {"$size": "3"}}`, "$.a.$size: $size requires a number"},
+		{`{"a": {"$elemMatch": {"$gt": 1}}}`, "$.a.$elemMatch: $elemMatch on scalar elements is not supported"},
+		{`[1, 2]`, "invalid filter JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, _, err := Parse([]byte(tt.input), "users")
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+// substituteParams replaces ":name" placeholders with their values and
+// round-trips the result through JSON so numbers compare as float64.
+func substituteParams(t *testing.T, v interface{}, values map[string]interface{}) interface{} {
+	t.Helper()
+
+	var walk func(v interface{}) interface{}
+	walk = func(v interface{}) interface{} {
+		switch value := v.(type) {
+		case string:
+			if name, ok := strings.CutPrefix(value, ":"); ok {
+				if param, ok := values[name]; ok {
+					return param
+				}
+			}
+			return value
+		case []interface{}:
+			out := make([]interface{}, len(value))
+			for i, item := range value {
+				out[i] = walk(item)
+			}
+			return out
+		case map[string]interface{}:
+			out := make(map[string]interface{}, len(value))
+			for k, item := range value {
+				out[k] = walk(item)
+			}
+			return out
+		default:
+			return v
+		}
+	}
+
+	data, err := json.Marshal(walk(v))
+	if err != nil {
+		t.Fatalf("failed to marshal substituted filter: %v", err)
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("failed to unmarshal substituted filter: %v", err)
+	}
+	return out
+}
+
+// normalizeFilter reduces a filter document to a sorted list of canonical
+// conjuncts: $and is flattened, multi-key documents and operator documents are
+// split, and plain values become explicit $eq.
+func normalizeFilter(t *testing.T, v interface{}) []string {
+	t.Helper()
+
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to marshal atom: %v", err)
+		}
+		return string(data)
+	}
+
+	doc, ok := v.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected filter document, got %T", v)
+	}
+
+	var atoms []string
+	for key, value := range doc {
+		switch key {
+		case "$and":
+			for _, entry := range value.([]interface{}) {
+				atoms = append(atoms, normalizeFilter(t, entry)...)
+			}
+		case "$or", "$nor":
+			var branches []string
+			for _, entry := range value.([]interface{}) {
+				branches = append(branches, encode(normalizeFilter(t, entry)))
+			}
+			slices.Sort(branches)
+			atoms = append(atoms, encode(map[string]interface{}{key: branches}))
+		default:
+			ops, ok := value.(map[string]interface{})
+			if !ok || !isOperatorDoc(ops) {
+				atoms = append(atoms, encode(map[string]interface{}{key: map[string]interface{}{"$eq": value}}))
+				continue
+			}
+			for op, arg := range ops {
+				switch op {
+				case "$options":
+					continue
+				case "$regex":
+					arg = []interface{}{arg, ops["$options"]}
+				case "$elemMatch":
+					arg = normalizeFilter(t, arg)
+				}
+				atoms = append(atoms, encode(map[string]interface{}{key: map[string]interface{}{op: arg}}))
+			}
+		}
+	}
+	slices.Sort(atoms)
+	return atoms
+}
@@ -0,0 +1,122 @@
+package mongodb
+
+// OutputSchema returns a JSON Schema (draft 2020-12) describing the JSON that
+// Render produces. Every query names its operation; the keys each operation
+// requires and allows are listed in the oneOf branches. Filter, projection,
+// update, and pipeline documents are MongoDB query language and are only
+// typed as objects.
+func OutputSchema() string {
+	return outputSchema
+}
+
+const outputSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "docql MongoDB query",
+  "type": "object",
+  "properties": {
+    "operation": {"enum": ["FIND", "FIND_ONE", "INSERT", "INSERT_MANY", "UPDATE", "UPDATE_MANY", "DELETE", "DELETE_MANY", "AGGREGATE", "COUNT", "DISTINCT", "WATCH", "TRANSACTION"]},
+    "collection": {"type": "string", "description": "Collection name, or a :param placeholder for a parameterized collection."},
+    "database": {"type": "string"},
+    "filter": {"type": "object"},
+    "projection": {"type": "object"},
+    "sort": {"type": "object", "description": "Field to 1 or -1, in clause order."},
+    "skip": {"$ref": "#/$defs/pagination"},
+    "limit": {"$ref": "#/$defs/pagination"},
+    "field": {"type": "string"},
+    "document": {"type": "object"},
+    "documents": {"type": "array", "items": {"type": "object"}},
+    "update": {"type": "object"},
+    "upsert": {"const": true},
+    "pipeline": {"type": "array", "items": {"type": "object"}},
+    "allowDiskUse": {"const": true},
+    "options": {
+      "type": "object",
+      "properties": {
+        "fullDocument": {"type": "string"},
+        "resumeAfter": {"$ref": "#/$defs/placeholder"}
+      },
+      "additionalProperties": false
+    },
+    "operations": {"type": "array", "items": {"$ref": "#"}},
+    "maxTimeMS": {"type": "integer"},
+    "hint": {"type": "string"},
+    "collation": {
+      "type": "object",
+      "properties": {
+        "locale": {"type": "string"},
+        "strength": {"type": "integer"},
+        "numericOrdering": {"const": true}
+      },
+      "required": ["locale"],
+      "additionalProperties": false
+    },
+    "readConcern": {
+      "type": "object",
+      "properties": {"level": {"type": "string"}},
+      "required": ["level"],
+      "additionalProperties": false
+    },
+    "writeConcern": {
+      "type": "object",
+      "properties": {"w": {"type": ["string", "integer"]}},
+      "required": ["w"],
+      "additionalProperties": false
+    }
+  },
+  "oneOf": [
+    {
+      "properties": {"operation": {"enum": ["FIND", "FIND_ONE"]}},
+      "required": ["operation", "collection", "filter"],
+      "propertyNames": {"enum": ["operation", "collection", "database", "filter", "projection", "sort", "skip", "limit", "maxTimeMS", "hint", "collation", "readConcern"]}
+    },
+    {
+      "properties": {"operation": {"const": "INSERT"}},
+      "required": ["operation", "collection", "document"],
+      "propertyNames": {"enum": ["operation", "collection", "database", "document", "writeConcern"]}
+    },
+    {
+      "properties": {"operation": {"const": "INSERT_MANY"}},
+      "required": ["operation", "collection", "documents"],
+      "propertyNames": {"enum": ["operation", "collection", "database", "documents", "writeConcern"]}
+    },
+    {
+      "properties": {"operation": {"enum": ["UPDATE", "UPDATE_MANY"]}},
+      "required": ["operation", "collection", "filter", "update"],
+      "propertyNames": {"enum": ["operation", "collection", "database", "filter", "update", "upsert", "hint", "collation", "writeConcern"]}
+    },
+    {
+      "properties": {"operation": {"enum": ["DELETE", "DELETE_MANY"]}},
+      "required": ["operation", "collection", "filter"],
+      "propertyNames": {"enum": ["operation", "collection", "database", "filter", "hint", "collation", "writeConcern"]}
+    },
+    {
+      "properties": {"operation": {"const": "AGGREGATE"}},
+      "required": ["operation", "collection", "pipeline"],
+      "propertyNames": {"enum": ["operation", "collection", "database", "pipeline", "maxTimeMS", "allowDiskUse", "hint", "collation", "readConcern"]}
+    },
+    {
+      "properties": {"operation": {"const": "COUNT"}},
+      "required": ["operation", "collection", "filter"],
+      "propertyNames": {"enum": ["operation", "collection", "database", "filter", "maxTimeMS", "hint", "collation", "readConcern"]}
+    },
+    {
+      "properties": {"operation": {"const": "DISTINCT"}},
+      "required": ["operation", "collection", "field"],
+      "propertyNames": {"enum": ["operation", "collection", "database", "field", "filter", "pipeline", "maxTimeMS", "hint", "collation", "readConcern"]}
+    },
+    {
+      "properties": {"operation": {"const": "WATCH"}},
+      "required": ["operation", "collection", "pipeline"],
+      "propertyNames": {"enum": ["operation", "collection", "database", "pipeline", "options", "hint", "collation", "readConcern"]}
+    },
+    {
+      "properties": {"operation": {"const": "TRANSACTION"}},
+      "required": ["operation", "operations"],
+      "propertyNames": {"enum": ["operation", "operations", "readConcern", "writeConcern"]}
+    }
+  ],
+  "$defs": {
+    "placeholder": {"type": "string", "description": "A parameter reference, :name."},
+    "pagination": {"type": ["integer", "string"], "description": "A static count, or a :param placeholder."}
+  }
+}`
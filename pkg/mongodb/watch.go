@@ -0,0 +1,95 @@
+package mongodb
+
+import "github.com/zoobzio/docql/internal/types"
+
+// fullDocumentPrefix is where a change event carries the changed document.
+const fullDocumentPrefix = "fullDocument."
+
+// renderWatch renders a change stream: the filter becomes a $match stage on
+// the change events, under "fullDocument." unless the AST addresses raw
+// event fields.
+func (r *Renderer) renderWatch(ast *types.DocumentAST, params *[]string) (*types.QueryResult, error) {
+	query := newQuery(ast)
+
+	pipeline := make([]map[string]interface{}, 0, 1)
+	if ast.FilterClause != nil {
+		filter := ast.FilterClause
+		if !ast.RawEvent {
+			filter = prefixFilter(filter, fullDocumentPrefix)
+		}
+		match, err := r.renderFilter(filter, params)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, map[string]interface{}{"$match": match})
+	}
+	query["pipeline"] = pipeline
+
+	options := make(map[string]interface{}, 2)
+	if ast.FullDocument != nil {
+		options["fullDocument"] = *ast.FullDocument
+	}
+	if ast.ResumeAfter != nil {
+		options["resumeAfter"] = placeholder(ast.ResumeAfter.Name)
+		*params = append(*params, ast.ResumeAfter.Name)
+	}
+	if len(options) > 0 {
+		query["options"] = options
+	}
+
+	return toResult(query, *params)
+}
+
+// prefixFilter returns a copy of f with every field path prefixed. Conditions
+// inside $elemMatch are relative to the array element and keep their paths.
+func prefixFilter(f types.FilterItem, prefix string) types.FilterItem {
+	prefixed := func(field types.Field) types.Field {
+		field.Path = prefix + field.Path
+		return field
+	}
+
+	switch filter := f.(type) {
+	case types.FilterCondition:
+		filter.Field = prefixed(filter.Field)
+		return filter
+	case types.FilterGroup:
+		conditions := make([]types.FilterItem, len(filter.Conditions))
+		for i, c := range filter.Conditions {
+			conditions[i] = prefixFilter(c, prefix)
+		}
+		filter.Conditions = conditions
+		return filter
+	case types.RangeFilter:
+		filter.Field = prefixed(filter.Field)
+		return filter
+	case types.RegexFilter:
+		filter.Field = prefixed(filter.Field)
+		return filter
+	case types.GeoFilter:
+		filter.Field = prefixed(filter.Field)
+		return filter
+	case types.ArrayFilter:
+		filter.Field = prefixed(filter.Field)
+		return filter
+	case types.ValuesFilter:
+		filter.Field = prefixed(filter.Field)
+		return filter
+	case types.ModFilter:
+		filter.Field = prefixed(filter.Field)
+		return filter
+	case types.TypeFilter:
+		filter.Field = prefixed(filter.Field)
+		return filter
+	case types.ElemMatchFilter:
+		filter.Field = prefixed(filter.Field)
+		return filter
+	case types.ExistsFilter:
+		filter.Field = prefixed(filter.Field)
+		return filter
+	case types.NullFilter:
+		filter.Field = prefixed(filter.Field)
+		return filter
+	default:
+		return f
+	}
+}
@@ -0,0 +1,119 @@
+package mongodb
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+func TestRenderWatch(t *testing.T) {
+	eq := func(path, param string) types.FilterCondition {
+		return types.FilterCondition{Field: types.Field{Path: path}, Operator: types.EQ, Value: types.Param{Name: param}}
+	}
+	updateLookup := "updateLookup"
+
+	tests := []struct {
+		name   string
+		ast    *types.DocumentAST
+		want   string
+		params []string
+	}{
+		{
+			name: "no filter",
+			ast:  &types.DocumentAST{Operation: types.OpWatch, Target: types.Collection{Name: "orders"}},
+			want: `{"collection":"orders","operation":"WATCH","pipeline":[]}`,
+		},
+		{
+			name: "prefixed field",
+			ast: &types.DocumentAST{
+				Operation:    types.OpWatch,
+				Target:       types.Collection{Name: "orders"},
+				FilterClause: eq("status", "status"),
+			},
+			want:   `{"collection":"orders","operation":"WATCH","pipeline":[{"$match":{"fullDocument.status":{"$eq":":status"}}}]}`,
+			params: []string{"status"},
+		},
+		{
+			name: "nested paths in groups",
+			ast: &types.DocumentAST{
+				Operation: types.OpWatch,
+				Target:    types.Collection{Name: "orders"},
+				FilterClause: types.FilterGroup{Logic: types.OR, Conditions: []types.FilterItem{
+					eq("shipping.address.city", "city"),
+					types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+						types.RangeFilter{Field: types.Field{Path: "total"}, Min: &types.Param{Name: "min"}},
+						types.ExistsFilter{Field: types.Field{Path: "customer.email"}, Exists: true},
+					}},
+				}},
+			},
+			want:   `{"collection":"orders","operation":"WATCH","pipeline":[{"$match":{"$or":[{"fullDocument.shipping.address.city":{"$eq":":city"}},{"$and":[{"fullDocument.total":{"$gte":":min"}},{"fullDocument.customer.email":{"$exists":true}}]}]}}]}`,
+			params: []string{"city", "min"},
+		},
+		{
+			name: "elemMatch conditions stay relative",
+			ast: &types.DocumentAST{
+				Operation: types.OpWatch,
+				Target:    types.Collection{Name: "orders"},
+				FilterClause: types.ElemMatchFilter{
+					Field:      types.Field{Path: "items"},
+					Conditions: []types.FilterItem{eq("sku", "sku")},
+				},
+			},
+			want:   `{"collection":"orders","operation":"WATCH","pipeline":[{"$match":{"fullDocument.items":{"$elemMatch":{"sku":{"$eq":":sku"}}}}}]}`,
+			params: []string{"sku"},
+		},
+		{
+			name: "raw event fields",
+			ast: &types.DocumentAST{
+				Operation:    types.OpWatch,
+				Target:       types.Collection{Name: "orders"},
+				FilterClause: eq("operationType", "op"),
+				RawEvent:     true,
+			},
+			want:   `{"collection":"orders","operation":"WATCH","pipeline":[{"$match":{"operationType":{"$eq":":op"}}}]}`,
+			params: []string{"op"},
+		},
+		{
+			name: "options",
+			ast: &types.DocumentAST{
+				Operation:    types.OpWatch,
+				Target:       types.Collection{Name: "orders"},
+				FilterClause: eq("status", "status"),
+				FullDocument: &updateLookup,
+				ResumeAfter:  &types.Param{Name: "token"},
+			},
+			want:   `{"collection":"orders","operation":"WATCH","options":{"fullDocument":"updateLookup","resumeAfter":":token"},"pipeline":[{"$match":{"fullDocument.status":{"$eq":":status"}}}]}`,
+			params: []string{"status", "token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := New().Render(tt.ast)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.JSON != tt.want {
+				t.Errorf("unexpected JSON:\n got: %s\nwant: %s", result.JSON, tt.want)
+			}
+			if !slices.Equal(result.RequiredParams, tt.params) {
+				t.Errorf("expected params %v, got %v", tt.params, result.RequiredParams)
+			}
+		})
+	}
+}
+
+func TestRenderWatch_DoesNotModifyFilter(t *testing.T) {
+	filter := types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+		types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "status"}},
+	}}
+	ast := &types.DocumentAST{Operation: types.OpWatch, Target: types.Collection{Name: "orders"}, FilterClause: filter}
+
+	if _, err := New().Render(ast); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path := filter.Conditions[0].(types.FilterCondition).Field.Path; path != "status" {
+		t.Errorf("filter was modified: %s", path)
+	}
+}
@@ -0,0 +1,834 @@
+// Package postgres provides a renderer that targets documents stored in a
+// PostgreSQL jsonb column.
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// FieldTypes resolves the schema type of a field ("int", "float", "bool",
+// "date", "string", ...), reporting false when the field is unknown. It lets
+// the renderer cast jsonb text to the right SQL type without importing the
+// schema; DOCQL.GetFieldType can be adapted to it directly.
+type FieldTypes func(collection, fieldPath string) (string, bool)
+
+// reservedWords lists SQL keywords that must be double-quoted as identifiers.
+var reservedWords = map[string]bool{
+	"all": true, "and": true, "any": true, "array": true, "as": true, "asc": true,
+	"case": true, "check": true, "column": true, "constraint": true, "create": true,
+	"default": true, "desc": true, "distinct": true, "do": true, "else": true,
+	"end": true, "false": true, "for": true, "foreign": true, "from": true,
+	"grant": true, "group": true, "having": true, "in": true, "into": true,
+	"is": true, "join": true, "limit": true, "not": true, "null": true,
+	"offset": true, "on": true, "or": true, "order": true, "primary": true,
+	"references": true, "select": true, "table": true, "then": true, "to": true,
+	"true": true, "union": true, "unique": true, "user": true, "using": true,
+	"when": true, "where": true, "with": true,
+}
+
+// Renderer renders DocumentAST to parameterized SQL over a jsonb column.
+type Renderer struct {
+	// Table overrides the table name; by default the collection name is used.
+	Table string
+	// Column names the jsonb column holding the documents.
+	Column string
+	// Types optionally resolves field types for casts.
+	Types FieldTypes
+}
+
+// provider names PostgreSQL in UnsupportedError values.
+const provider = "postgres"
+
+// New creates a new PostgreSQL renderer using the "data" column.
+func New() *Renderer {
+	return &Renderer{
+		Column: "data",
+	}
+}
+
+// WithTable sets the table holding the documents.
+func (r *Renderer) WithTable(table string) *Renderer {
+	r.Table = table
+	return r
+}
+
+// WithColumn sets the jsonb column holding the documents.
+func (r *Renderer) WithColumn(column string) *Renderer {
+	r.Column = column
+	return r
+}
+
+// WithFieldTypes sets the hook used to look up field types for casts.
+func (r *Renderer) WithFieldTypes(fn FieldTypes) *Renderer {
+	r.Types = fn
+	return r
+}
+
+// CacheKey identifies the renderer's options, so a registry caches its
+// results apart from those of differently configured renderers. A renderer
+// with a FieldTypes hook is not cached, since hooks cannot be compared.
+func (r *Renderer) CacheKey() (string, bool) {
+	if r.Types != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%q %q", r.Table, r.Column), true
+}
+
+// scope is the jsonb value filters are evaluated against: the document column,
+// or an array element inside an $elemMatch subquery.
+type scope struct {
+	root   string
+	prefix string
+	depth  int
+}
+
+// statement carries per-render state.
+type statement struct {
+	collection string
+	params     []string
+}
+
+func (s *statement) placeholder(p types.Param) (string, error) {
+	if !types.IsValidIdentifier(p.Name) {
+		return "", &types.InvalidIdentifierError{Kind: "param name", Value: p.Name}
+	}
+	s.params = append(s.params, p.Name)
+	return ":" + p.Name, nil
+}
+
+// Render converts a DocumentAST to PostgreSQL.
+func (r *Renderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
+	if err := ast.Validate(); err != nil {
+		return nil, &types.InvalidASTError{Err: err}
+	}
+
+	if ast.ParameterizedTarget() {
+		return nil, types.UnsupportedFeature(provider, "parameterized collection")
+	}
+
+	if !r.SupportsOperation(ast.Operation) {
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
+	}
+
+	table := r.Table
+	if table == "" {
+		table = ast.Target.Name
+	}
+	if !types.IsValidIdentifier(table) {
+		return nil, &types.InvalidIdentifierError{Kind: "table name", Value: table}
+	}
+	if !types.IsValidIdentifier(r.Column) {
+		return nil, &types.InvalidIdentifierError{Kind: "column name", Value: r.Column}
+	}
+	table = quoteIdent(table)
+
+	s := &statement{collection: ast.Target.Name}
+	var sql string
+	var err error
+
+	switch ast.Operation {
+	case types.OpFind, types.OpFindOne:
+		sql, err = r.renderSelect(ast, table, s)
+	case types.OpCount:
+		sql, err = r.renderCount(ast, table, s)
+	case types.OpDistinct:
+		sql, err = r.renderDistinct(ast, table, s)
+	case types.OpInsert, types.OpInsertMany:
+		sql, err = r.renderInsert(ast, table, s)
+	case types.OpUpdate, types.OpUpdateMany:
+		sql, err = r.renderUpdate(ast, table, s)
+	case types.OpDelete, types.OpDeleteMany:
+		sql, err = r.renderDelete(ast, table, s)
+	default:
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
+	}
+	if err != nil {
+		return nil, types.WithOperation(err, ast.Operation)
+	}
+
+	var warnings []string
+	if ast.MaxTimeMS != nil {
+		warnings = append(warnings, "postgres sets timeouts through statement_timeout: maxTimeMS ignored")
+	}
+	if _, ok := ast.SortOpts(); ok {
+		warnings = append(warnings, "postgres orders by the column collation: case-insensitive and numeric sort options ignored")
+	}
+
+	result, err := toResult(sql, s.params)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = warnings
+	result.Operation = ast.Operation
+	result.Single = ast.Operation == types.OpFindOne
+	result.Collection = ast.Target.Name
+	result.Database = ast.Target.Database
+	return result, nil
+}
+
+func (r *Renderer) column() scope {
+	return scope{root: quoteIdent(r.Column)}
+}
+
+func (r *Renderer) renderSelect(ast *types.DocumentAST, table string, s *statement) (string, error) {
+	selectList, err := r.buildSelectList(ast.Projection)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + selectList + " FROM " + table)
+	if err := r.writeWhere(&sb, ast.FilterClause, s); err != nil {
+		return "", err
+	}
+
+	if len(ast.SortClauses) > 0 {
+		parts := make([]string, len(ast.SortClauses))
+		for i, sc := range ast.SortClauses {
+			expr, err := r.textValue(r.column(), sc.Field.Path, s, false)
+			if err != nil {
+				return "", err
+			}
+			dir := "ASC"
+			if sc.Order == types.Descending {
+				dir = "DESC"
+			}
+			parts[i] = expr + " " + dir
+		}
+		sb.WriteString(" ORDER BY " + strings.Join(parts, ", "))
+	}
+
+	limit := ast.Limit
+	if ast.Operation == types.OpFindOne {
+		one := 1
+		limit = &types.PaginationValue{Static: &one}
+	}
+	if limit != nil {
+		v, err := pagination(*limit, s)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(" LIMIT " + v)
+	}
+	if ast.Skip != nil {
+		v, err := pagination(*ast.Skip, s)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(" OFFSET " + v)
+	}
+
+	return sb.String(), nil
+}
+
+func (r *Renderer) renderCount(ast *types.DocumentAST, table string, s *statement) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("SELECT COUNT(*) FROM " + table)
+	if err := r.writeWhere(&sb, ast.FilterClause, s); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (r *Renderer) renderDistinct(ast *types.DocumentAST, table string, s *statement) (string, error) {
+	expr, err := jsonValue(r.column().root, ast.DistinctField.Path)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString("SELECT DISTINCT " + expr + " FROM " + table)
+	if err := r.writeWhere(&sb, ast.FilterClause, s); err != nil {
+		return "", err
+	}
+	// Validation limits the sort to the distinct field, which SELECT
+	// DISTINCT requires of ORDER BY expressions anyway.
+	for _, sc := range ast.SortClauses {
+		dir := "ASC"
+		if sc.Order == types.Descending {
+			dir = "DESC"
+		}
+		sb.WriteString(" ORDER BY " + expr + " " + dir)
+	}
+	if ast.Limit != nil {
+		v, err := pagination(*ast.Limit, s)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(" LIMIT " + v)
+	}
+	return sb.String(), nil
+}
+
+func (r *Renderer) renderInsert(ast *types.DocumentAST, table string, s *statement) (string, error) {
+	rows := make([]string, len(ast.Documents))
+	for i, doc := range ast.Documents {
+		obj := newObject()
+		for _, field := range sortedFields(doc.Fields) {
+			p, err := s.placeholder(doc.Fields[field])
+			if err != nil {
+				return "", err
+			}
+			if err := obj.set(field.Path, p); err != nil {
+				return "", err
+			}
+		}
+		rows[i] = "(" + obj.String() + ")"
+	}
+	return "INSERT INTO " + table + " (" + quoteIdent(r.Column) + ") VALUES " + strings.Join(rows, ", "), nil
+}
+
+func (r *Renderer) renderUpdate(ast *types.DocumentAST, table string, s *statement) (string, error) {
+	if ast.Upsert {
+		return "", types.UnsupportedFeature(provider, "upsert updates")
+	}
+
+	col := quoteIdent(r.Column)
+	expr := col
+	for _, op := range ast.UpdateOps {
+		for _, field := range sortedFields(op.Fields) {
+			path, err := pathLiteral(field.Path)
+			if err != nil {
+				return "", err
+			}
+			switch op.Operator {
+			case types.Set:
+				p, err := s.placeholder(op.Fields[field])
+				if err != nil {
+					return "", err
+				}
+				cast := r.cast(s, field.Path, false)
+				if cast == "" {
+					cast = "text"
+				}
+				expr = "jsonb_set(" + expr + ", " + path + ", to_jsonb(" + p + "::" + cast + "))"
+			case types.Unset:
+				expr = expr + " #- " + path
+			case types.Inc:
+				p, err := s.placeholder(op.Fields[field])
+				if err != nil {
+					return "", err
+				}
+				current, err := textPath(col, field.Path)
+				if err != nil {
+					return "", err
+				}
+				expr = "jsonb_set(" + expr + ", " + path + ", to_jsonb(COALESCE((" + current + ")::numeric, 0) + " + p + "))"
+			default:
+				return "", types.UnsupportedFeature(provider, "update operator: "+string(op.Operator))
+			}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("UPDATE " + table + " SET " + col + " = " + expr)
+	if err := r.writeTarget(&sb, ast, table, s); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (r *Renderer) renderDelete(ast *types.DocumentAST, table string, s *statement) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("DELETE FROM " + table)
+	if err := r.writeTarget(&sb, ast, table, s); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// writeTarget writes the WHERE clause for writes. PostgreSQL has no LIMIT on
+// UPDATE or DELETE, so single-document writes select one row by ctid.
+func (r *Renderer) writeTarget(sb *strings.Builder, ast *types.DocumentAST, table string, s *statement) error {
+	if ast.Operation != types.OpUpdate && ast.Operation != types.OpDelete {
+		return r.writeWhere(sb, ast.FilterClause, s)
+	}
+	sb.WriteString(" WHERE ctid = (SELECT ctid FROM " + table)
+	if err := r.writeWhere(sb, ast.FilterClause, s); err != nil {
+		return err
+	}
+	sb.WriteString(" LIMIT 1)")
+	return nil
+}
+
+func (r *Renderer) writeWhere(sb *strings.Builder, f types.FilterItem, s *statement) error {
+	if f == nil {
+		return nil
+	}
+	expr, err := r.buildFilter(f, r.column(), s)
+	if err != nil {
+		return err
+	}
+	if expr != "" {
+		sb.WriteString(" WHERE " + expr)
+	}
+	return nil
+}
+
+// buildSelectList renders the projection: jsonb_build_object for inclusions,
+// or the column with - / #- removing excluded paths.
+func (r *Renderer) buildSelectList(p *types.Projection) (string, error) {
+	col := quoteIdent(r.Column)
+	if p == nil {
+		return col, nil
+	}
+
+	obj := newObject()
+	var excluded []string
+	for _, f := range p.Fields {
+		if f.Slice != nil || f.ElemMatch != nil {
+			return "", types.UnsupportedFeature(provider, "array projection operators on field: "+f.Field.Path)
+		}
+		if f.Include {
+			expr, err := jsonValue(col, f.Field.Path)
+			if err != nil {
+				return "", err
+			}
+			if err := obj.set(f.OutputName(), expr); err != nil {
+				return "", err
+			}
+			continue
+		}
+		path, err := pathLiteral(f.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		excluded = append(excluded, path)
+	}
+
+	if !obj.empty() {
+		// Included fields already define the result; a lone _id exclusion
+		// simply leaves it out of the object.
+		return obj.String() + " AS " + col, nil
+	}
+	expr := col
+	for _, path := range excluded {
+		expr += " #- " + path
+	}
+	return expr + " AS " + col, nil
+}
+
+func (r *Renderer) buildFilter(f types.FilterItem, sc scope, s *statement) (string, error) {
+	switch filter := f.(type) {
+	case types.ValuesFilter:
+		lhs, err := r.textValue(sc, filter.Field.Path, s, false)
+		if err != nil {
+			return "", err
+		}
+		refs := make([]string, len(filter.Values))
+		for i, v := range filter.Values {
+			if refs[i], err = s.placeholder(v); err != nil {
+				return "", err
+			}
+		}
+		op := " IN ("
+		if filter.Operator == types.NotIn {
+			op = " NOT IN ("
+		}
+		return lhs + op + strings.Join(refs, ", ") + ")", nil
+
+	case types.FilterCondition:
+		switch filter.Operator {
+		case types.IN, types.NotIn:
+			lhs, err := r.textValue(sc, filter.Field.Path, s, false)
+			if err != nil {
+				return "", err
+			}
+			p, err := s.placeholder(filter.Value)
+			if err != nil {
+				return "", err
+			}
+			expr := lhs + " = ANY(" + p + ")"
+			if filter.Operator == types.NotIn {
+				expr = "NOT (" + expr + ")"
+			}
+			return expr, nil
+		}
+		op := mapOperator(filter.Operator)
+		if op == "" {
+			return "", types.UnsupportedFilter(provider, string(filter.Operator))
+		}
+		ordered := filter.Operator != types.EQ && filter.Operator != types.NE
+		lhs, err := r.textValue(sc, filter.Field.Path, s, ordered)
+		if err != nil {
+			return "", err
+		}
+		p, err := s.placeholder(filter.Value)
+		if err != nil {
+			return "", err
+		}
+		return lhs + " " + op + " " + p, nil
+
+	case types.FilterGroup:
+		exprs := make([]string, 0, len(filter.Conditions))
+		for _, c := range filter.Conditions {
+			expr, err := r.buildFilter(c, sc, s)
+			if err != nil {
+				return "", err
+			}
+			if expr != "" {
+				exprs = append(exprs, "("+expr+")")
+			}
+		}
+		if len(exprs) == 0 {
+			return "", nil
+		}
+		switch filter.Logic {
+		case types.AND:
+			return strings.Join(exprs, " AND "), nil
+		case types.OR:
+			return strings.Join(exprs, " OR "), nil
+		case types.NOR:
+			return "NOT (" + strings.Join(exprs, " OR ") + ")", nil
+		case types.NOT:
+			return "NOT (" + strings.Join(exprs, " AND ") + ")", nil
+		default:
+			return "", types.UnsupportedFilter(provider, string(filter.Logic))
+		}
+
+	case types.RangeFilter:
+		lhs, err := r.textValue(sc, filter.Field.Path, s, true)
+		if err != nil {
+			return "", err
+		}
+		var parts []string
+		if filter.Min != nil {
+			p, err := s.placeholder(*filter.Min)
+			if err != nil {
+				return "", err
+			}
+			op := ">="
+			if filter.MinExclusive {
+				op = ">"
+			}
+			parts = append(parts, lhs+" "+op+" "+p)
+		}
+		if filter.Max != nil {
+			p, err := s.placeholder(*filter.Max)
+			if err != nil {
+				return "", err
+			}
+			op := "<="
+			if filter.MaxExclusive {
+				op = "<"
+			}
+			parts = append(parts, lhs+" "+op+" "+p)
+		}
+		if filter.Negated {
+			return "NOT (" + strings.Join(parts, " AND ") + ")", nil
+		}
+		return strings.Join(parts, " AND "), nil
+
+	case types.RegexFilter:
+		lhs, err := textPath(sc.root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		pattern, err := s.placeholder(filter.Pattern)
+		if err != nil {
+			return "", err
+		}
+		if filter.Options != nil {
+			opts, err := s.placeholder(*filter.Options)
+			if err != nil {
+				return "", err
+			}
+			return "regexp_like(" + lhs + ", " + pattern + ", " + opts + ")", nil
+		}
+		return lhs + " ~ " + pattern, nil
+
+	case types.ArrayFilter:
+		if filter.Operator != types.Size {
+			return "", types.UnsupportedFilter(provider, string(filter.Operator))
+		}
+		arr, err := jsonValue(sc.root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		if filter.Literal != nil {
+			return "jsonb_array_length(" + arr + ") = " + strconv.Itoa(*filter.Literal), nil
+		}
+		p, err := s.placeholder(filter.Value)
+		if err != nil {
+			return "", err
+		}
+		return "jsonb_array_length(" + arr + ") = " + p, nil
+
+	case types.ElemMatchFilter:
+		arr, err := jsonValue(sc.root, filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		elem := scope{root: "e" + strconv.Itoa(sc.depth), prefix: sc.prefix + filter.Field.Path + ".", depth: sc.depth + 1}
+		conds := make([]string, 0, len(filter.Conditions))
+		for _, c := range filter.Conditions {
+			expr, err := r.buildFilter(c, elem, s)
+			if err != nil {
+				return "", err
+			}
+			conds = append(conds, "("+expr+")")
+		}
+		return "EXISTS (SELECT 1 FROM jsonb_array_elements(" + arr + ") AS " + elem.root +
+			" WHERE " + strings.Join(conds, " AND ") + ")", nil
+
+	case types.ExistsFilter:
+		if !types.IsValidFieldPath(filter.Field.Path) {
+			return "", &types.InvalidIdentifierError{Kind: "field path", Value: filter.Field.Path}
+		}
+		parent := sc.root
+		key := filter.Field.Path
+		if i := strings.LastIndex(key, "."); i >= 0 {
+			p, err := jsonValue(sc.root, key[:i])
+			if err != nil {
+				return "", err
+			}
+			parent, key = p, key[i+1:]
+		}
+		expr := parent + " ? " + quoteLiteral(key)
+		if !filter.Exists {
+			expr = "NOT (" + expr + ")"
+		}
+		return expr, nil
+
+	default:
+		return "", types.UnsupportedFilter(provider, types.FilterName(f))
+	}
+}
+
+// textValue extracts a field as text, cast to its schema type when known.
+// Ordered comparisons fall back to numeric when the type is unknown.
+func (r *Renderer) textValue(sc scope, path string, s *statement, ordered bool) (string, error) {
+	expr, err := textPath(sc.root, path)
+	if err != nil {
+		return "", err
+	}
+	if cast := r.cast(s, sc.prefix+path, ordered); cast != "" {
+		return "(" + expr + ")::" + cast, nil
+	}
+	return expr, nil
+}
+
+// cast maps the field's schema type to a SQL type, or "" for plain text.
+func (r *Renderer) cast(s *statement, path string, ordered bool) string {
+	if r.Types != nil {
+		if typ, ok := r.Types(s.collection, path); ok {
+			return sqlType(typ)
+		}
+	}
+	if ordered {
+		return "numeric"
+	}
+	return ""
+}
+
+func sqlType(typ string) string {
+	switch typ {
+	case "int", "float":
+		return "numeric"
+	case "bool":
+		return "boolean"
+	case "date":
+		return "timestamptz"
+	default:
+		return ""
+	}
+}
+
+func mapOperator(op types.FilterOperator) string {
+	switch op {
+	case types.EQ:
+		return "="
+	case types.NE:
+		return "<>"
+	case types.GT:
+		return ">"
+	case types.GTE:
+		return ">="
+	case types.LT:
+		return "<"
+	case types.LTE:
+		return "<="
+	default:
+		return ""
+	}
+}
+
+func pagination(p types.PaginationValue, s *statement) (string, error) {
+	if p.Static != nil {
+		return strconv.Itoa(*p.Static), nil
+	}
+	if p.Param != nil {
+		return s.placeholder(*p.Param)
+	}
+	return "", fmt.Errorf("pagination value has neither a static value nor a param")
+}
+
+// textPath renders a text extraction: data->>'status' for top-level fields
+// and data#>>'{address,city}' for nested ones.
+func textPath(root, path string) (string, error) {
+	return extract(root, path, "->>", "#>>")
+}
+
+// jsonValue renders a jsonb extraction: data->'status' or data#>'{address,city}'.
+func jsonValue(root, path string) (string, error) {
+	return extract(root, path, "->", "#>")
+}
+
+func extract(root, path, keyOp, pathOp string) (string, error) {
+	if !types.IsValidFieldPath(path) {
+		return "", &types.InvalidIdentifierError{Kind: "field path", Value: path}
+	}
+	if !strings.Contains(path, ".") {
+		return root + keyOp + quoteLiteral(path), nil
+	}
+	lit, err := pathLiteral(path)
+	if err != nil {
+		return "", err
+	}
+	return root + pathOp + lit, nil
+}
+
+// pathLiteral renders a dot-notation path as a text[] literal, e.g. '{address,city}'.
+func pathLiteral(path string) (string, error) {
+	if !types.IsValidFieldPath(path) {
+		return "", &types.InvalidIdentifierError{Kind: "field path", Value: path}
+	}
+	return quoteLiteral("{" + strings.ReplaceAll(path, ".", ",") + "}"), nil
+}
+
+// quoteLiteral single-quotes s, doubling embedded quotes. Field paths are
+// validated before they get here; the escaping is defense-in-depth.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// quoteIdent double-quotes an already-validated identifier when it is a
+// reserved word or not all lowercase, which PostgreSQL would otherwise fold.
+func quoteIdent(name string) string {
+	if reservedWords[strings.ToLower(name)] || strings.ToLower(name) != name {
+		return `"` + name + `"`
+	}
+	return name
+}
+
+// object builds a jsonb_build_object call from dot-notation paths, nesting
+// sub-objects so address.city becomes jsonb_build_object('address', jsonb_build_object('city', ...)).
+type object struct {
+	values   map[string]string
+	children map[string]*object
+}
+
+func newObject() *object {
+	return &object{values: map[string]string{}, children: map[string]*object{}}
+}
+
+func (o *object) empty() bool {
+	return len(o.values) == 0 && len(o.children) == 0
+}
+
+func (o *object) set(path, expr string) error {
+	if !types.IsValidFieldPath(path) {
+		return &types.InvalidIdentifierError{Kind: "field path", Value: path}
+	}
+	segs := strings.Split(path, ".")
+	node := o
+	for _, seg := range segs[:len(segs)-1] {
+		if _, ok := node.values[seg]; ok {
+			return fmt.Errorf("conflicting field paths at: %s", path)
+		}
+		child, ok := node.children[seg]
+		if !ok {
+			child = newObject()
+			node.children[seg] = child
+		}
+		node = child
+	}
+	last := segs[len(segs)-1]
+	if _, ok := node.children[last]; ok {
+		return fmt.Errorf("conflicting field paths at: %s", path)
+	}
+	if _, ok := node.values[last]; ok {
+		return fmt.Errorf("conflicting field paths at: %s", path)
+	}
+	node.values[last] = expr
+	return nil
+}
+
+func (o *object) String() string {
+	keys := slices.Collect(maps.Keys(o.values))
+	for k := range o.children {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		if child, ok := o.children[k]; ok {
+			parts[i] = quoteLiteral(k) + ", " + child.String()
+		} else {
+			parts[i] = quoteLiteral(k) + ", " + o.values[k]
+		}
+	}
+	return "jsonb_build_object(" + strings.Join(parts, ", ") + ")"
+}
+
+func sortedFields(fields map[types.Field]types.Param) []types.Field {
+	return slices.SortedFunc(maps.Keys(fields), func(a, b types.Field) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+}
+
+// SupportsOperation indicates if PostgreSQL supports an operation.
+func (r *Renderer) SupportsOperation(op types.Operation) bool {
+	switch op {
+	case types.OpFind, types.OpFindOne, types.OpCount, types.OpDistinct,
+		types.OpInsert, types.OpInsertMany, types.OpUpdate, types.OpUpdateMany,
+		types.OpDelete, types.OpDeleteMany:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsFilter indicates if PostgreSQL supports a filter operator.
+func (r *Renderer) SupportsFilter(op types.FilterOperator) bool {
+	switch op {
+	case types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE,
+		types.IN, types.NotIn, types.Exists, types.Regex, types.Size, types.ElemMatch:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsUpdate indicates if PostgreSQL supports an update operator.
+func (r *Renderer) SupportsUpdate(op types.UpdateOperator) bool {
+	switch op {
+	case types.Set, types.Unset, types.Inc:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsPipelineStage indicates if PostgreSQL supports a pipeline stage.
+func (r *Renderer) SupportsPipelineStage(stage string) bool {
+	return false
+}
+
+func toResult(sql string, params []string) (*types.QueryResult, error) {
+	jsonBytes, err := json.Marshal(map[string]interface{}{"query": sql})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize query: %w", err)
+	}
+	return &types.QueryResult{
+		JSON:           string(jsonBytes),
+		RequiredParams: params,
+	}, nil
+}
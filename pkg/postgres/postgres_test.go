@@ -0,0 +1,481 @@
+package postgres
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"encoding/json"
+	"github.com/zoobzio/docql/internal/types"
+)
+
+func intPtr(i int) *int { return &i }
+
+func renderWith(t *testing.T, r *Renderer, ast *types.DocumentAST) (string, *types.QueryResult) {
+	t.Helper()
+	result, err := r.Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out map[string]string
+	if err := json.Unmarshal([]byte(result.JSON), &out); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	return out["query"], result
+}
+
+func render(t *testing.T, ast *types.DocumentAST) string {
+	t.Helper()
+	sql, _ := renderWith(t, New().WithTable("docs"), ast)
+	return sql
+}
+
+func cond(path string, op types.FilterOperator, param string) types.FilterCondition {
+	return types.FilterCondition{Field: types.Field{Path: path}, Operator: op, Value: types.Param{Name: param}}
+}
+
+func TestRenderFind(t *testing.T) {
+	sql, result := renderWith(t, New().WithTable("docs").WithColumn("data"), &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+			cond("status", types.EQ, "status"),
+			cond("age", types.GT, "minAge"),
+		}},
+		SortClauses: []types.SortClause{{Field: types.Field{Path: "age"}, Order: types.Descending}},
+		Skip:        &types.PaginationValue{Static: intPtr(20)},
+		Limit:       &types.PaginationValue{Static: intPtr(10)},
+	})
+
+	expected := "SELECT data FROM docs WHERE (data->>'status' = :status) AND ((data->>'age')::numeric > :minAge)" +
+		" ORDER BY data->>'age' DESC LIMIT 10 OFFSET 20"
+	if sql != expected {
+		t.Errorf("expected %q, got %q", expected, sql)
+	}
+	if len(result.RequiredParams) != 2 || result.RequiredParams[0] != "status" || result.RequiredParams[1] != "minAge" {
+		t.Errorf("expected [status minAge], got %v", result.RequiredParams)
+	}
+}
+
+func TestRenderFind_DefaultsToCollectionTable(t *testing.T) {
+	sql, _ := renderWith(t, New(), &types.DocumentAST{
+		Operation: types.OpFindOne,
+		Target:    types.Collection{Name: "users"},
+	})
+	if sql != "SELECT data FROM users LIMIT 1" {
+		t.Errorf("unexpected query: %s", sql)
+	}
+}
+
+func TestRenderFind_NestedPaths(t *testing.T) {
+	sql := render(t, &types.DocumentAST{
+		Operation:    types.OpFind,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: cond("address.city", types.EQ, "city"),
+	})
+	if sql != "SELECT data FROM docs WHERE data#>>'{address,city}' = :city" {
+		t.Errorf("unexpected query: %s", sql)
+	}
+}
+
+func TestRenderFind_FieldTypes(t *testing.T) {
+	fieldTypes := func(collection, path string) (string, bool) {
+		if collection != "users" {
+			return "", false
+		}
+		switch path {
+		case "age":
+			return "int", true
+		case "active":
+			return "bool", true
+		case "name":
+			return "string", true
+		case "items.qty":
+			return "int", true
+		}
+		return "", false
+	}
+
+	sql, _ := renderWith(t, New().WithFieldTypes(fieldTypes), &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+			cond("active", types.EQ, "active"),
+			cond("name", types.GTE, "from"),
+			types.ElemMatchFilter{Field: types.Field{Path: "items"}, Conditions: []types.FilterItem{
+				cond("qty", types.LT, "qty"),
+			}},
+		}},
+		SortClauses: []types.SortClause{{Field: types.Field{Path: "age"}, Order: types.Ascending}},
+	})
+
+	expected := "SELECT data FROM users WHERE ((data->>'active')::boolean = :active) AND (data->>'name' >= :from)" +
+		" AND (EXISTS (SELECT 1 FROM jsonb_array_elements(data->'items') AS e0 WHERE ((e0->>'qty')::numeric < :qty)))" +
+		" ORDER BY (data->>'age')::numeric ASC"
+	if sql != expected {
+		t.Errorf("expected %q, got %q", expected, sql)
+	}
+}
+
+func TestRenderFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   types.FilterItem
+		expected string
+	}{
+		{"ne", cond("status", types.NE, "s"), "data->>'status' <> :s"},
+		{"in", cond("status", types.IN, "s"), "data->>'status' = ANY(:s)"},
+		{"not in", cond("status", types.NotIn, "s"), "NOT (data->>'status' = ANY(:s))"},
+		{
+			"range",
+			types.RangeFilter{Field: types.Field{Path: "age"}, Min: &types.Param{Name: "lo"}, Max: &types.Param{Name: "hi"}, MaxExclusive: true},
+			"(data->>'age')::numeric >= :lo AND (data->>'age')::numeric < :hi",
+		},
+		{"exists", types.ExistsFilter{Field: types.Field{Path: "email"}, Exists: true}, "data ? 'email'"},
+		{"nested exists", types.ExistsFilter{Field: types.Field{Path: "address.city"}, Exists: true}, "data->'address' ? 'city'"},
+		{"not exists", types.ExistsFilter{Field: types.Field{Path: "email"}, Exists: false}, "NOT (data ? 'email')"},
+		{"regex", types.RegexFilter{Field: types.Field{Path: "name"}, Pattern: types.Param{Name: "p"}}, "data->>'name' ~ :p"},
+		{
+			"regex options",
+			types.RegexFilter{Field: types.Field{Path: "name"}, Pattern: types.Param{Name: "p"}, Options: &types.Param{Name: "o"}},
+			"regexp_like(data->>'name', :p, :o)",
+		},
+		{
+			"size",
+			types.ArrayFilter{Field: types.Field{Path: "tags"}, Operator: types.Size, Value: types.Param{Name: "n"}},
+			"jsonb_array_length(data->'tags') = :n",
+		},
+		{
+			"or",
+			types.FilterGroup{Logic: types.OR, Conditions: []types.FilterItem{cond("a", types.EQ, "a"), cond("b", types.EQ, "b")}},
+			"(data->>'a' = :a) OR (data->>'b' = :b)",
+		},
+		{
+			"nor",
+			types.FilterGroup{Logic: types.NOR, Conditions: []types.FilterItem{cond("a", types.EQ, "a")}},
+			"NOT ((data->>'a' = :a))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql := render(t, &types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: tt.filter,
+			})
+			expected := "SELECT data FROM docs WHERE " + tt.expected
+			if sql != expected {
+				t.Errorf("expected %q, got %q", expected, sql)
+			}
+		})
+	}
+}
+
+func TestRenderFind_Projection(t *testing.T) {
+	sql := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: types.IDField}, Include: false},
+			{Field: types.Field{Path: "username"}, Include: true},
+			{Field: types.Field{Path: "address.city"}, Include: true},
+		}},
+	})
+	expected := "SELECT jsonb_build_object('address', jsonb_build_object('city', data#>'{address,city}'), 'username', data->'username') AS data FROM docs"
+	if sql != expected {
+		t.Errorf("expected %q, got %q", expected, sql)
+	}
+
+	sql = render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Exclude: true, Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "password"}, Include: false},
+			{Field: types.Field{Path: "auth.token"}, Include: false},
+		}},
+	})
+	if sql != "SELECT data #- '{password}' #- '{auth,token}' AS data FROM docs" {
+		t.Errorf("unexpected query: %s", sql)
+	}
+}
+
+func TestRenderCountAndDistinct(t *testing.T) {
+	sql := render(t, &types.DocumentAST{
+		Operation:    types.OpCount,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: cond("status", types.EQ, "status"),
+	})
+	if sql != "SELECT COUNT(*) FROM docs WHERE data->>'status' = :status" {
+		t.Errorf("unexpected count query: %s", sql)
+	}
+
+	sql = render(t, &types.DocumentAST{
+		Operation:     types.OpDistinct,
+		Target:        types.Collection{Name: "users"},
+		DistinctField: &types.Field{Path: "address.city"},
+	})
+	if sql != "SELECT DISTINCT data#>'{address,city}' FROM docs" {
+		t.Errorf("unexpected distinct query: %s", sql)
+	}
+}
+
+func TestRenderInsert(t *testing.T) {
+	sql, result := renderWith(t, New().WithTable("docs"), &types.DocumentAST{
+		Operation: types.OpInsertMany,
+		Target:    types.Collection{Name: "users"},
+		Documents: []types.Document{
+			{Fields: map[types.Field]types.Param{
+				{Path: "username"}:     {Name: "u1"},
+				{Path: "address.city"}: {Name: "c1"},
+			}},
+			{Fields: map[types.Field]types.Param{
+				{Path: "username"}: {Name: "u2"},
+			}},
+		},
+	})
+
+	expected := "INSERT INTO docs (data) VALUES" +
+		" (jsonb_build_object('address', jsonb_build_object('city', :c1), 'username', :u1))," +
+		" (jsonb_build_object('username', :u2))"
+	if sql != expected {
+		t.Errorf("expected %q, got %q", expected, sql)
+	}
+	if len(result.RequiredParams) != 3 {
+		t.Errorf("expected 3 params, got %v", result.RequiredParams)
+	}
+}
+
+func TestRenderUpdate(t *testing.T) {
+	sql := render(t, &types.DocumentAST{
+		Operation:    types.OpUpdateMany,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: cond("status", types.EQ, "status"),
+		UpdateOps: []types.UpdateOperation{
+			{Operator: types.Set, Fields: map[types.Field]types.Param{
+				{Path: "email"}:        {Name: "email"},
+				{Path: "address.city"}: {Name: "city"},
+			}},
+			{Operator: types.Inc, Fields: map[types.Field]types.Param{{Path: "logins"}: {Name: "n"}}},
+			{Operator: types.Unset, Fields: map[types.Field]types.Param{{Path: "token"}: {Name: "token"}}},
+		},
+	})
+
+	expected := "UPDATE docs SET data = jsonb_set(jsonb_set(jsonb_set(data, '{address,city}', to_jsonb(:city::text))," +
+		" '{email}', to_jsonb(:email::text)), '{logins}', to_jsonb(COALESCE((data->>'logins')::numeric, 0) + :n)) #- '{token}'" +
+		" WHERE data->>'status' = :status"
+	if sql != expected {
+		t.Errorf("expected %q, got %q", expected, sql)
+	}
+}
+
+func TestRenderUpdateOne_UsesCtid(t *testing.T) {
+	sql := render(t, &types.DocumentAST{
+		Operation:    types.OpUpdate,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: cond("email", types.EQ, "email"),
+		UpdateOps: []types.UpdateOperation{
+			{Operator: types.Set, Fields: map[types.Field]types.Param{{Path: "active"}: {Name: "active"}}},
+		},
+	})
+	expected := "UPDATE docs SET data = jsonb_set(data, '{active}', to_jsonb(:active::text))" +
+		" WHERE ctid = (SELECT ctid FROM docs WHERE data->>'email' = :email LIMIT 1)"
+	if sql != expected {
+		t.Errorf("expected %q, got %q", expected, sql)
+	}
+}
+
+func TestRenderDelete(t *testing.T) {
+	sql := render(t, &types.DocumentAST{
+		Operation:    types.OpDeleteMany,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: cond("active", types.EQ, "active"),
+	})
+	if sql != "DELETE FROM docs WHERE data->>'active' = :active" {
+		t.Errorf("unexpected query: %s", sql)
+	}
+}
+
+func TestRender_IdentifierQuoting(t *testing.T) {
+	sql, _ := renderWith(t, New().WithTable("user").WithColumn("Body"), &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+	})
+	if sql != `SELECT "Body" FROM "user"` {
+		t.Errorf("unexpected query: %s", sql)
+	}
+}
+
+func TestRender_RejectsUnsafeIdentifiers(t *testing.T) {
+	find := func(filter types.FilterItem) *types.DocumentAST {
+		return &types.DocumentAST{Operation: types.OpFind, Target: types.Collection{Name: "users"}, FilterClause: filter}
+	}
+
+	tests := []struct {
+		name     string
+		renderer *Renderer
+		ast      *types.DocumentAST
+	}{
+		{"table", New().WithTable("docs; DROP TABLE docs"), find(nil)},
+		{"column", New().WithColumn(`data"--`), find(nil)},
+		{"collection", New(), &types.DocumentAST{Operation: types.OpFind, Target: types.Collection{Name: "users x"}}},
+		{"field quote", New(), find(cond("a'b", types.EQ, "x"))},
+		{"field comment", New(), find(cond("a--", types.EQ, "x"))},
+		{"exists field", New(), find(types.ExistsFilter{Field: types.Field{Path: "a') OR ('1"}, Exists: true})},
+		{"param", New(), find(cond("a", types.EQ, "x; DROP"))},
+		{"sort", New(), &types.DocumentAST{
+			Operation: types.OpFind, Target: types.Collection{Name: "users"},
+			SortClauses: []types.SortClause{{Field: types.Field{Path: "age DESC, (SELECT 1)"}, Order: types.Ascending}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.renderer.Render(tt.ast); err == nil {
+				t.Error("expected error for unsafe identifier")
+			}
+		})
+	}
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	if got := quoteLiteral("it's"); got != "'it''s'" {
+		t.Errorf("expected doubled quote, got %s", got)
+	}
+}
+
+func TestRender_Unsupported(t *testing.T) {
+	r := New()
+	if r.SupportsOperation(types.OpAggregate) {
+		t.Error("expected Aggregate to be unsupported")
+	}
+	_, err := r.Render(&types.DocumentAST{
+		Operation: types.OpAggregate,
+		Target:    types.Collection{Name: "users"},
+		Pipeline:  []types.PipelineStage{types.LimitStage{Limit: types.PaginationValue{Static: intPtr(1)}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "does not support operation") {
+		t.Errorf("expected unsupported operation error, got %v", err)
+	}
+}
+
+func TestRenderFind_InValues(t *testing.T) {
+	sql, result := renderWith(t, New().WithTable("docs"), &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+			types.ValuesFilter{Field: types.Field{Path: "status"}, Operator: types.IN, Values: []types.Param{{Name: "a"}, {Name: "b"}}},
+			types.ValuesFilter{Field: types.Field{Path: "role"}, Operator: types.NotIn, Values: []types.Param{{Name: "banned"}}},
+		}},
+	})
+	want := "SELECT data FROM docs WHERE (data->>'status' IN (:a, :b)) AND (data->>'role' NOT IN (:banned))"
+	if sql != want {
+		t.Errorf("sql = %q, want %q", sql, want)
+	}
+	if strings.Join(result.RequiredParams, ",") != "a,b,banned" {
+		t.Errorf("RequiredParams = %v", result.RequiredParams)
+	}
+}
+
+func TestRenderFind_ProjectionAliases(t *testing.T) {
+	sql := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: types.IDField}, Include: true, Alias: "id"},
+			{Field: types.Field{Path: "username"}, Include: true},
+		}},
+	})
+	expected := "SELECT jsonb_build_object('id', data->'_id', 'username', data->'username') AS data FROM docs"
+	if sql != expected {
+		t.Errorf("expected %q, got %q", expected, sql)
+	}
+}
+
+func TestRender_EmptyParams(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	email := types.Field{Path: "email"}
+	age := types.Field{Path: "age"}
+	byKey := types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+
+	tests := []struct {
+		name    string
+		ast     *types.DocumentAST
+		context string
+	}{
+		{
+			name:    "filter",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.FilterCondition{Field: email, Operator: types.EQ}},
+			context: "$eq on field 'email'",
+		},
+		{
+			name:    "range bound",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.RangeFilter{Field: age, Min: &types.Param{}}},
+			context: "range on field 'age'",
+		},
+		{
+			name:    "document",
+			ast:     &types.DocumentAST{Operation: types.OpInsert, Target: users, Documents: []types.Document{{Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "document field 'email'",
+		},
+		{
+			name: "update",
+			ast: &types.DocumentAST{Operation: types.OpUpdate, Target: users, FilterClause: byKey,
+				UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "$set field 'email'",
+		},
+		{
+			name:    "pagination",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: byKey, Limit: &types.PaginationValue{Param: &types.Param{}}},
+			context: "limit",
+		},
+		{
+			name: "pipeline expression",
+			ast: &types.DocumentAST{Operation: types.OpAggregate, Target: users,
+				Pipeline: []types.PipelineStage{types.AddFieldsStage{Fields: map[string]types.Expression{"flag": types.LiteralExpression{}}}}},
+			context: "$addFields field 'flag'",
+		},
+	}
+
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Render(tt.ast)
+			if !errors.Is(err, types.ErrInvalidIdentifier) {
+				t.Fatalf("expected invalid identifier error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.context) {
+				t.Errorf("expected error naming %q, got %v", tt.context, err)
+			}
+		})
+	}
+}
+
+func TestRender_ValuelessUpdateOperators(t *testing.T) {
+	r := New()
+	for _, op := range []types.UpdateOperator{types.Unset, types.CurrentDate} {
+		t.Run(string(op), func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:    types.OpUpdate,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: types.FilterCondition{Field: types.Field{Path: "_id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+				UpdateOps:    []types.UpdateOperation{{Operator: op, Fields: map[types.Field]types.Param{{Path: "deletedAt"}: {}}}},
+			}
+			result, err := r.Render(ast)
+			if !r.SupportsUpdate(op) {
+				var unsupported *types.UnsupportedError
+				if !errors.As(err, &unsupported) {
+					t.Errorf("expected unsupported operator error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if slices.Contains(result.RequiredParams, "") {
+				t.Errorf("unexpected empty param in %v", result.RequiredParams)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package postgres
+
+// OutputSchema returns a JSON Schema (draft 2020-12) describing the JSON that
+// Render produces: a single SQL statement with :param placeholders.
+func OutputSchema() string {
+	return outputSchema
+}
+
+const outputSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "docql PostgreSQL query",
+  "type": "object",
+  "properties": {
+    "query": {"type": "string"}
+  },
+  "required": ["query"],
+  "additionalProperties": false
+}`
@@ -0,0 +1,534 @@
+// Package redisearch provides a RediSearch/RedisJSON renderer for DOCQL.
+//
+// Queries render as command descriptors rather than raw command strings. Param
+// placeholders (:name) are left in place for the executor to substitute; tag and
+// text values must be passed through Escape first, numeric values as-is.
+package redisearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// specialChars lists the characters RediSearch treats as query syntax.
+const specialChars = ",.<>{}[]\"':;!@#$%^&*()-+=~|/\\ "
+
+// Escape backslash-escapes RediSearch special characters so s matches
+// literally inside a tag or text query.
+func Escape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(specialChars, r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// Renderer renders DocumentAST to RediSearch command descriptors.
+type Renderer struct {
+	// KeyField names the document field whose value completes the Redis key
+	// (<collection>:<value>) for JSON.SET, JSON.MERGE, and DEL.
+	KeyField string
+}
+
+// provider names RediSearch in UnsupportedError values.
+const provider = "redisearch"
+
+// New creates a new RediSearch renderer.
+func New() *Renderer {
+	return &Renderer{
+		KeyField: "id",
+	}
+}
+
+// WithKeyField sets the document field used to build Redis keys.
+func (r *Renderer) WithKeyField(field string) *Renderer {
+	r.KeyField = field
+	return r
+}
+
+// CacheKey identifies the renderer's options, so a registry caches its
+// results apart from those of differently configured renderers.
+func (r *Renderer) CacheKey() (string, bool) {
+	return r.KeyField, true
+}
+
+// search collects state that filter rendering contributes to the descriptor.
+type search struct {
+	params   []string
+	seen     map[string]bool
+	language *string
+	warnings []string
+}
+
+// placeholder returns the placeholder for a param, registering it on first
+// use.
+func (s *search) placeholder(p types.Param) string {
+	if !s.seen[p.Name] {
+		if s.seen == nil {
+			s.seen = make(map[string]bool)
+		}
+		s.seen[p.Name] = true
+		s.params = append(s.params, p.Name)
+	}
+	return ":" + p.Name
+}
+
+// Render converts a DocumentAST to a RediSearch command descriptor.
+func (r *Renderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
+	if err := ast.Validate(); err != nil {
+		return nil, &types.InvalidASTError{Err: err}
+	}
+
+	if ast.ParameterizedTarget() {
+		return nil, types.UnsupportedFeature(provider, "parameterized collection")
+	}
+
+	if !r.SupportsOperation(ast.Operation) {
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
+	}
+
+	s := &search{}
+	var cmd map[string]interface{}
+	var err error
+
+	switch ast.Operation {
+	case types.OpFind, types.OpFindOne, types.OpCount:
+		cmd, err = r.renderSearch(ast, s)
+	case types.OpInsert:
+		cmd, err = r.renderSet(ast, s)
+	case types.OpUpdate:
+		cmd, err = r.renderMerge(ast, s)
+	case types.OpDelete:
+		cmd, err = r.renderDel(ast, s)
+	default:
+		return nil, types.UnsupportedOperation(provider, ast.Operation)
+	}
+	if err != nil {
+		return nil, types.WithOperation(err, ast.Operation)
+	}
+
+	if _, ok := ast.SortOpts(); ok {
+		s.warnings = append(s.warnings, "redisearch sorts by raw value: case-insensitive and numeric sort options ignored")
+	}
+	if ast.MaxTimeMS != nil {
+		cmd["timeout"] = *ast.MaxTimeMS
+	}
+
+	result, err := toResult(cmd, s.params)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = s.warnings
+	if ast.Projection != nil {
+		result.FieldAliases = ast.Projection.Aliases()
+	}
+	result.Operation = ast.Operation
+	result.Single = ast.Operation == types.OpFindOne
+	result.Collection = ast.Target.Name
+	result.Database = ast.Target.Database
+	return result, nil
+}
+
+func (r *Renderer) renderSearch(ast *types.DocumentAST, s *search) (map[string]interface{}, error) {
+	cmd := map[string]interface{}{
+		"command": "FT.SEARCH",
+		"index":   ast.Target.Name,
+	}
+
+	q := "*"
+	if ast.FilterClause != nil {
+		expr, err := r.buildQuery(ast.FilterClause, s)
+		if err != nil {
+			return nil, err
+		}
+		if expr != "" {
+			q = expr
+		}
+	}
+	cmd["query"] = q
+	if s.language != nil {
+		cmd["language"] = *s.language
+	}
+
+	if ast.Operation == types.OpCount {
+		cmd["limit"] = []interface{}{0, 0}
+		return cmd, nil
+	}
+
+	if ast.Projection != nil {
+		fields := make([]string, 0, len(ast.Projection.Fields))
+		for _, f := range ast.Projection.Fields {
+			if f.Slice != nil || f.ElemMatch != nil {
+				return nil, types.UnsupportedFeature(provider, "array projection operators on field: "+f.Field.Path)
+			}
+			if !f.Include {
+				if f.Field.Path == types.IDField {
+					continue
+				}
+				return nil, types.UnsupportedFeature(provider, "projection exclusions: "+f.Field.Path)
+			}
+			fields = append(fields, f.Field.Path)
+		}
+		if len(fields) > 0 {
+			cmd["return"] = fields
+		}
+	}
+
+	if len(ast.SortClauses) > 1 {
+		return nil, &types.UnsupportedError{
+			Provider: provider,
+			Features: []string{"multiple sort fields"},
+			Reason:   fmt.Sprintf("sorting is limited to a single field, got %d", len(ast.SortClauses)),
+		}
+	}
+	if len(ast.SortClauses) == 1 {
+		dir := "ASC"
+		if ast.SortClauses[0].Order == types.Descending {
+			dir = "DESC"
+		}
+		cmd["sortby"] = []string{ast.SortClauses[0].Field.Path, dir}
+	}
+
+	limit := ast.Limit
+	if ast.Operation == types.OpFindOne {
+		one := 1
+		limit = &types.PaginationValue{Static: &one}
+	}
+	if ast.Skip != nil || limit != nil {
+		// LIMIT takes offset and count together.
+		if limit == nil {
+			return nil, &types.UnsupportedError{
+				Provider: provider,
+				Features: []string{"skip without limit"},
+				Reason:   "skip must be combined with a limit",
+			}
+		}
+		var offset interface{} = 0
+		if ast.Skip != nil {
+			offset = s.pagination(*ast.Skip)
+		}
+		cmd["limit"] = []interface{}{offset, s.pagination(*limit)}
+	}
+
+	return cmd, nil
+}
+
+func (s *search) pagination(p types.PaginationValue) interface{} {
+	if p.Param != nil {
+		return s.placeholder(*p.Param)
+	}
+	return *p.Static
+}
+
+func (r *Renderer) renderSet(ast *types.DocumentAST, s *search) (map[string]interface{}, error) {
+	doc := ast.Documents[0]
+	var key *types.Param
+	for field, value := range doc.Fields {
+		if field.Path == r.KeyField {
+			key = &value
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("redisearch insert requires key field: %s", r.KeyField)
+	}
+
+	value, err := r.buildObject(doc.Fields, s, func(p types.Param) interface{} { return s.placeholder(p) })
+	if err != nil {
+		return nil, err
+	}
+	cmd := map[string]interface{}{
+		"command": "JSON.SET",
+		"key":     ast.Target.Name + ":" + s.placeholder(*key),
+		"path":    "$",
+		"value":   value,
+	}
+	// NX sets the key only if it does not already exist.
+	if ast.IfNotExists {
+		cmd["condition"] = "NX"
+	}
+	return cmd, nil
+}
+
+func (r *Renderer) renderMerge(ast *types.DocumentAST, s *search) (map[string]interface{}, error) {
+	if ast.Upsert {
+		return nil, types.UnsupportedFeature(provider, "upsert updates")
+	}
+	key, err := r.key(ast, s)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := make(map[types.Field]types.Param)
+	var unset []types.Field
+	for _, op := range ast.UpdateOps {
+		switch op.Operator {
+		case types.Set:
+			maps.Copy(patch, op.Fields)
+		case types.Unset:
+			for field := range op.Fields {
+				unset = append(unset, field)
+			}
+		default:
+			return nil, types.UnsupportedFeature(provider, "update operator: "+string(op.Operator))
+		}
+	}
+	for _, field := range unset {
+		// JSON.MERGE deletes fields whose patch value is null.
+		patch[field] = types.Param{}
+	}
+
+	value, err := r.buildObject(patch, s, func(p types.Param) interface{} {
+		if p.Name == "" {
+			return nil
+		}
+		return s.placeholder(p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"command": "JSON.MERGE",
+		"key":     key,
+		"path":    "$",
+		"value":   value,
+	}, nil
+}
+
+func (r *Renderer) renderDel(ast *types.DocumentAST, s *search) (map[string]interface{}, error) {
+	key, err := r.key(ast, s)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"command": "DEL",
+		"key":     key,
+	}, nil
+}
+
+// key builds the Redis key template for single-document writes, which must be
+// addressed by an equality filter on KeyField.
+func (r *Renderer) key(ast *types.DocumentAST, s *search) (string, error) {
+	cond, ok := ast.FilterClause.(types.FilterCondition)
+	if !ok || cond.Operator != types.EQ || cond.Field.Path != r.KeyField {
+		return "", &types.UnsupportedError{
+			Provider:  provider,
+			Operation: ast.Operation,
+			Features:  []string{"filter other than key field equality"},
+			Reason:    "single-document writes require a single equality filter on key field: " + r.KeyField,
+		}
+	}
+	return ast.Target.Name + ":" + s.placeholder(cond.Value), nil
+}
+
+// buildObject nests dot-notation fields into a JSON object, visiting fields in
+// path order so RequiredParams is deterministic.
+func (r *Renderer) buildObject(fields map[types.Field]types.Param, s *search, value func(types.Param) interface{}) (map[string]interface{}, error) {
+	paths := make(map[string]types.Param, len(fields))
+	for field, p := range fields {
+		paths[field.Path] = p
+	}
+
+	obj := make(map[string]interface{})
+	for _, path := range slices.Sorted(maps.Keys(paths)) {
+		segs := strings.Split(path, ".")
+		node := obj
+		for _, seg := range segs[:len(segs)-1] {
+			child, exists := node[seg]
+			if !exists {
+				next := make(map[string]interface{})
+				node[seg] = next
+				node = next
+				continue
+			}
+			next, ok := child.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("conflicting field paths at: %s", path)
+			}
+			node = next
+		}
+		last := segs[len(segs)-1]
+		if _, exists := node[last]; exists {
+			return nil, fmt.Errorf("conflicting field paths at: %s", path)
+		}
+		node[last] = value(paths[path])
+	}
+	return obj, nil
+}
+
+func (r *Renderer) buildQuery(f types.FilterItem, s *search) (string, error) {
+	switch filter := f.(type) {
+	case types.FilterCondition:
+		attr, err := attribute(filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		p := s.placeholder(filter.Value)
+		switch filter.Operator {
+		case types.EQ:
+			return attr + ":{" + p + "}", nil
+		case types.NE:
+			return "-" + attr + ":{" + p + "}", nil
+		case types.GT:
+			return attr + ":[(" + p + " +inf]", nil
+		case types.GTE:
+			return attr + ":[" + p + " +inf]", nil
+		case types.LT:
+			return attr + ":[-inf (" + p + "]", nil
+		case types.LTE:
+			return attr + ":[-inf " + p + "]", nil
+		default:
+			return "", types.UnsupportedFilter(provider, string(filter.Operator))
+		}
+
+	case types.ValuesFilter:
+		attr, err := attribute(filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		refs := make([]string, len(filter.Values))
+		for i, v := range filter.Values {
+			refs[i] = s.placeholder(v)
+		}
+		expr := attr + ":{" + strings.Join(refs, " | ") + "}"
+		if filter.Operator == types.NotIn {
+			expr = "-" + expr
+		}
+		return expr, nil
+
+	case types.FilterGroup:
+		exprs := make([]string, 0, len(filter.Conditions))
+		for _, c := range filter.Conditions {
+			expr, err := r.buildQuery(c, s)
+			if err != nil {
+				return "", err
+			}
+			if expr == "" {
+				continue
+			}
+			if _, nested := c.(types.FilterGroup); nested {
+				expr = "(" + expr + ")"
+			}
+			exprs = append(exprs, expr)
+		}
+		if len(exprs) == 0 {
+			return "", nil
+		}
+		switch filter.Logic {
+		case types.AND:
+			return strings.Join(exprs, " "), nil
+		case types.OR:
+			return strings.Join(exprs, " | "), nil
+		case types.NOR:
+			return "-(" + strings.Join(exprs, " | ") + ")", nil
+		case types.NOT:
+			return "-(" + strings.Join(exprs, " ") + ")", nil
+		default:
+			return "", types.UnsupportedFilter(provider, string(filter.Logic))
+		}
+
+	case types.RangeFilter:
+		attr, err := attribute(filter.Field.Path)
+		if err != nil {
+			return "", err
+		}
+		lo, hi := "-inf", "+inf"
+		if filter.Min != nil {
+			lo = s.placeholder(*filter.Min)
+			if filter.MinExclusive {
+				lo = "(" + lo
+			}
+		}
+		if filter.Max != nil {
+			hi = s.placeholder(*filter.Max)
+			if filter.MaxExclusive {
+				hi = "(" + hi
+			}
+		}
+		expr := attr + ":[" + lo + " " + hi + "]"
+		if filter.Negated {
+			return "-" + expr, nil
+		}
+		return expr, nil
+
+	case types.TextSearchFilter:
+		term := s.placeholder(filter.Search)
+		if filter.Language != nil {
+			lang := s.placeholder(*filter.Language)
+			s.language = &lang
+		}
+		if filter.CaseSensitive {
+			s.warnings = append(s.warnings, "redisearch text search is case-insensitive: caseSensitive ignored")
+		}
+		if filter.DiacriticSensitive {
+			s.warnings = append(s.warnings, "redisearch text search is diacritic-insensitive: diacriticSensitive ignored")
+		}
+		return term, nil
+
+	default:
+		return "", types.UnsupportedFilter(provider, types.FilterName(f))
+	}
+}
+
+// attribute renders a field path as an @-prefixed index attribute name,
+// escaping the dots of nested paths. Escaping keeps the query well-formed,
+// but a path that is not a valid field path cannot name an attribute.
+func attribute(path string) (string, error) {
+	if !types.IsValidFieldPath(path) {
+		return "", &types.InvalidIdentifierError{Kind: "field path", Value: path}
+	}
+	return "@" + Escape(path), nil
+}
+
+// SupportsOperation indicates if RediSearch supports an operation.
+func (r *Renderer) SupportsOperation(op types.Operation) bool {
+	switch op {
+	case types.OpFind, types.OpFindOne, types.OpCount, types.OpInsert, types.OpUpdate, types.OpDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsFilter indicates if RediSearch supports a filter operator.
+func (r *Renderer) SupportsFilter(op types.FilterOperator) bool {
+	switch op {
+	case types.EQ, types.NE, types.GT, types.GTE, types.LT, types.LTE, types.Text:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsUpdate indicates if RediSearch supports an update operator.
+func (r *Renderer) SupportsUpdate(op types.UpdateOperator) bool {
+	switch op {
+	case types.Set, types.Unset:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsPipelineStage indicates if RediSearch supports a pipeline stage.
+func (r *Renderer) SupportsPipelineStage(stage string) bool {
+	return false
+}
+
+func toResult(cmd map[string]interface{}, params []string) (*types.QueryResult, error) {
+	jsonBytes, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize query: %w", err)
+	}
+	return &types.QueryResult{
+		JSON:           string(jsonBytes),
+		RequiredParams: params,
+	}, nil
+}
@@ -0,0 +1,502 @@
+package redisearch
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"encoding/json"
+	"github.com/zoobzio/docql/internal/types"
+)
+
+func intPtr(i int) *int { return &i }
+
+func render(t *testing.T, ast *types.DocumentAST) (map[string]interface{}, *types.QueryResult) {
+	t.Helper()
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var cmd map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &cmd); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	return cmd, result
+}
+
+func TestRenderFind(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+			types.FilterCondition{Field: types.Field{Path: "active"}, Operator: types.EQ, Value: types.Param{Name: "active"}},
+			types.FilterCondition{Field: types.Field{Path: "age"}, Operator: types.GT, Value: types.Param{Name: "minAge"}},
+		}},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "username"}, Include: true},
+			{Field: types.Field{Path: "email"}, Include: true},
+		}},
+		SortClauses: []types.SortClause{{Field: types.Field{Path: "age"}, Order: types.Descending}},
+		Skip:        &types.PaginationValue{Static: intPtr(20)},
+		Limit:       &types.PaginationValue{Static: intPtr(10)},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"command":"FT.SEARCH","index":"users","limit":[20,10],"query":"@active:{:active} @age:[(:minAge +inf]","return":["username","email"],"sortby":["age","DESC"]}`
+	if result.JSON != expected {
+		t.Errorf("expected %s, got %s", expected, result.JSON)
+	}
+	if len(result.RequiredParams) != 2 || result.RequiredParams[0] != "active" || result.RequiredParams[1] != "minAge" {
+		t.Errorf("expected [active minAge], got %v", result.RequiredParams)
+	}
+}
+
+func TestRenderFind_MatchAll(t *testing.T) {
+	cmd, _ := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+	})
+	if cmd["query"] != "*" {
+		t.Errorf("expected *, got %v", cmd["query"])
+	}
+	if _, ok := cmd["limit"]; ok {
+		t.Error("expected no limit")
+	}
+}
+
+func TestRenderQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   types.FilterItem
+		expected string
+	}{
+		{
+			name:     "ne",
+			filter:   types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.NE, Value: types.Param{Name: "s"}},
+			expected: "-@status:{:s}",
+		},
+		{
+			name:     "lte",
+			filter:   types.FilterCondition{Field: types.Field{Path: "age"}, Operator: types.LTE, Value: types.Param{Name: "max"}},
+			expected: "@age:[-inf :max]",
+		},
+		{
+			name: "range exclusive",
+			filter: types.RangeFilter{
+				Field: types.Field{Path: "price"},
+				Min:   &types.Param{Name: "lo"}, Max: &types.Param{Name: "hi"},
+				MinExclusive: true, MaxExclusive: true,
+			},
+			expected: "@price:[(:lo (:hi]",
+		},
+		{
+			name:     "range open",
+			filter:   types.RangeFilter{Field: types.Field{Path: "price"}, Max: &types.Param{Name: "hi"}},
+			expected: "@price:[-inf :hi]",
+		},
+		{
+			name:     "text",
+			filter:   types.TextSearchFilter{Search: types.Param{Name: "q"}},
+			expected: ":q",
+		},
+		{
+			name: "or",
+			filter: types.FilterGroup{Logic: types.OR, Conditions: []types.FilterItem{
+				types.FilterCondition{Field: types.Field{Path: "a"}, Operator: types.EQ, Value: types.Param{Name: "a"}},
+				types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+					types.FilterCondition{Field: types.Field{Path: "b"}, Operator: types.EQ, Value: types.Param{Name: "b"}},
+					types.FilterCondition{Field: types.Field{Path: "c"}, Operator: types.EQ, Value: types.Param{Name: "c"}},
+				}},
+			}},
+			expected: "@a:{:a} | (@b:{:b} @c:{:c})",
+		},
+		{
+			name: "not",
+			filter: types.FilterGroup{Logic: types.NOT, Conditions: []types.FilterItem{
+				types.FilterCondition{Field: types.Field{Path: "a"}, Operator: types.EQ, Value: types.Param{Name: "a"}},
+			}},
+			expected: "-(@a:{:a})",
+		},
+		{
+			name:     "nested attribute escaped",
+			filter:   types.FilterCondition{Field: types.Field{Path: "address.city"}, Operator: types.EQ, Value: types.Param{Name: "city"}},
+			expected: `@address\.city:{:city}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, _ := render(t, &types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: tt.filter,
+			})
+			if cmd["query"] != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, cmd["query"])
+			}
+		})
+	}
+}
+
+func TestRenderQuery_TextLanguage(t *testing.T) {
+	cmd, result := render(t, &types.DocumentAST{
+		Operation:    types.OpFind,
+		Target:       types.Collection{Name: "posts"},
+		FilterClause: types.TextSearchFilter{Search: types.Param{Name: "q"}, Language: &types.Param{Name: "lang"}, CaseSensitive: true},
+	})
+	if cmd["language"] != ":lang" {
+		t.Errorf("expected language :lang, got %v", cmd["language"])
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("expected caseSensitive warning, got %v", result.Warnings)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"user@example.com", `user\@example\.com`},
+		{"new york", `new\ york`},
+		{"a-b|c", `a\-b\|c`},
+		{"{tag}", `\{tag\}`},
+		{`back\slash`, `back\\slash`},
+		{"(1+2)*3", `\(1\+2\)\*3`},
+		{"café", "café"},
+	}
+
+	for _, tt := range tests {
+		if got := Escape(tt.in); got != tt.expected {
+			t.Errorf("Escape(%q) = %q, expected %q", tt.in, got, tt.expected)
+		}
+	}
+}
+
+func TestRenderFindOne(t *testing.T) {
+	cmd, _ := render(t, &types.DocumentAST{
+		Operation: types.OpFindOne,
+		Target:    types.Collection{Name: "users"},
+	})
+	limit, _ := cmd["limit"].([]interface{})
+	if len(limit) != 2 || limit[0] != float64(0) || limit[1] != float64(1) {
+		t.Errorf("expected limit [0 1], got %v", cmd["limit"])
+	}
+}
+
+func TestRenderCount(t *testing.T) {
+	cmd, _ := render(t, &types.DocumentAST{
+		Operation: types.OpCount,
+		Target:    types.Collection{Name: "users"},
+	})
+	limit, _ := cmd["limit"].([]interface{})
+	if len(limit) != 2 || limit[0] != float64(0) || limit[1] != float64(0) {
+		t.Errorf("expected limit [0 0], got %v", cmd["limit"])
+	}
+}
+
+func TestRenderFind_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		ast  *types.DocumentAST
+	}{
+		{"multiple sorts", &types.DocumentAST{
+			Operation: types.OpFind, Target: types.Collection{Name: "users"},
+			SortClauses: []types.SortClause{
+				{Field: types.Field{Path: "a"}, Order: types.Ascending},
+				{Field: types.Field{Path: "b"}, Order: types.Ascending},
+			},
+		}},
+		{"skip without limit", &types.DocumentAST{
+			Operation: types.OpFind, Target: types.Collection{Name: "users"},
+			Skip: &types.PaginationValue{Static: intPtr(5)},
+		}},
+		{"regex", &types.DocumentAST{
+			Operation: types.OpFind, Target: types.Collection{Name: "users"},
+			FilterClause: types.RegexFilter{Field: types.Field{Path: "a"}, Pattern: types.Param{Name: "p"}},
+		}},
+		{"exclusion", &types.DocumentAST{
+			Operation: types.OpFind, Target: types.Collection{Name: "users"},
+			Projection: &types.Projection{Exclude: true, Fields: []types.ProjectionField{
+				{Field: types.Field{Path: "password"}, Include: false},
+			}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New().Render(tt.ast); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestRenderFind_InvalidPaths(t *testing.T) {
+	bad := types.Field{Path: "a|b"}
+	filters := map[string]types.FilterItem{
+		"condition": types.FilterCondition{Field: bad, Operator: types.EQ, Value: types.Param{Name: "v"}},
+		"values":    types.ValuesFilter{Field: bad, Operator: types.IN, Values: []types.Param{{Name: "v"}}},
+		"range":     types.RangeFilter{Field: bad, Min: &types.Param{Name: "v"}},
+	}
+	for name, f := range filters {
+		t.Run(name, func(t *testing.T) {
+			_, err := New().Render(&types.DocumentAST{Operation: types.OpFind, Target: types.Collection{Name: "users"}, FilterClause: f})
+			var invalid *types.InvalidIdentifierError
+			if !errors.As(err, &invalid) || invalid.Value != "a|b" {
+				t.Errorf("expected invalid field path error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRenderInsert(t *testing.T) {
+	cmd, result := render(t, &types.DocumentAST{
+		Operation: types.OpInsert,
+		Target:    types.Collection{Name: "users"},
+		Documents: []types.Document{{Fields: map[types.Field]types.Param{
+			{Path: "id"}:           {Name: "id"},
+			{Path: "username"}:     {Name: "username"},
+			{Path: "address.city"}: {Name: "city"},
+		}}},
+	})
+
+	if cmd["command"] != "JSON.SET" || cmd["key"] != "users::id" || cmd["path"] != "$" {
+		t.Errorf("unexpected descriptor: %v", cmd)
+	}
+	value, _ := cmd["value"].(map[string]interface{})
+	address, _ := value["address"].(map[string]interface{})
+	if value["username"] != ":username" || address["city"] != ":city" {
+		t.Errorf("unexpected value: %v", cmd["value"])
+	}
+	if want := []string{"city", "id", "username"}; !slices.Equal(result.RequiredParams, want) {
+		t.Errorf("expected the key param once, got %v", result.RequiredParams)
+	}
+}
+
+func TestRenderInsert_IfNotExists(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpInsert,
+		Target:    types.Collection{Name: "users"},
+		Documents: []types.Document{{Fields: map[types.Field]types.Param{{Path: "id"}: {Name: "id"}}}},
+	}
+	if cmd, _ := render(t, ast); cmd["condition"] != nil {
+		t.Errorf("expected no condition by default, got %v", cmd)
+	}
+	ast.IfNotExists = true
+	if cmd, _ := render(t, ast); cmd["condition"] != "NX" {
+		t.Errorf("expected NX condition, got %v", cmd)
+	}
+}
+
+func TestRenderInsert_MissingKey(t *testing.T) {
+	_, err := New().WithKeyField("sku").Render(&types.DocumentAST{
+		Operation: types.OpInsert,
+		Target:    types.Collection{Name: "products"},
+		Documents: []types.Document{{Fields: map[types.Field]types.Param{
+			{Path: "name"}: {Name: "name"},
+		}}},
+	})
+	if err == nil {
+		t.Error("expected error for missing key field")
+	}
+}
+
+func TestRenderUpdate(t *testing.T) {
+	cmd, _ := render(t, &types.DocumentAST{
+		Operation:    types.OpUpdate,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{Field: types.Field{Path: "id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+		UpdateOps: []types.UpdateOperation{
+			{Operator: types.Set, Fields: map[types.Field]types.Param{{Path: "active"}: {Name: "active"}}},
+			{Operator: types.Unset, Fields: map[types.Field]types.Param{{Path: "token"}: {Name: "token"}}},
+		},
+	})
+
+	if cmd["command"] != "JSON.MERGE" || cmd["key"] != "users::id" {
+		t.Errorf("unexpected descriptor: %v", cmd)
+	}
+	value, _ := cmd["value"].(map[string]interface{})
+	if value["active"] != ":active" {
+		t.Errorf("expected active placeholder, got %v", value["active"])
+	}
+	if v, ok := value["token"]; !ok || v != nil {
+		t.Errorf("expected token to be null, got %v", v)
+	}
+}
+
+func TestRenderDelete(t *testing.T) {
+	cmd, result := render(t, &types.DocumentAST{
+		Operation:    types.OpDelete,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{Field: types.Field{Path: "id"}, Operator: types.EQ, Value: types.Param{Name: "userId"}},
+	})
+	if cmd["command"] != "DEL" || cmd["key"] != "users::userId" {
+		t.Errorf("unexpected descriptor: %v", cmd)
+	}
+	if len(result.RequiredParams) != 1 || result.RequiredParams[0] != "userId" {
+		t.Errorf("expected [userId], got %v", result.RequiredParams)
+	}
+
+	_, err := New().Render(&types.DocumentAST{
+		Operation:    types.OpDelete,
+		Target:       types.Collection{Name: "users"},
+		FilterClause: types.FilterCondition{Field: types.Field{Path: "status"}, Operator: types.EQ, Value: types.Param{Name: "s"}},
+	})
+	if err == nil {
+		t.Error("expected error when filter does not address the key field")
+	}
+}
+
+func TestSupports(t *testing.T) {
+	r := New()
+	for _, op := range []types.Operation{types.OpAggregate, types.OpDistinct, types.OpInsertMany, types.OpUpdateMany, types.OpDeleteMany} {
+		if r.SupportsOperation(op) {
+			t.Errorf("expected %s to be unsupported", op)
+		}
+	}
+	if r.SupportsPipelineStage("$match") {
+		t.Error("expected no pipeline support")
+	}
+	if r.SupportsFilter(types.Regex) || !r.SupportsFilter(types.Text) {
+		t.Error("unexpected filter support")
+	}
+}
+
+func TestRenderFind_InValues(t *testing.T) {
+	cmd, result := render(t, &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+			types.ValuesFilter{Field: types.Field{Path: "status"}, Operator: types.IN, Values: []types.Param{{Name: "a"}, {Name: "b"}}},
+			types.ValuesFilter{Field: types.Field{Path: "role"}, Operator: types.NotIn, Values: []types.Param{{Name: "banned"}}},
+		}},
+	})
+	if want := "@status:{:a | :b} -@role:{:banned}"; cmd["query"] != want {
+		t.Errorf("query = %v, want %q", cmd["query"], want)
+	}
+	if len(result.RequiredParams) != 3 || result.RequiredParams[0] != "a" || result.RequiredParams[2] != "banned" {
+		t.Errorf("RequiredParams = %v", result.RequiredParams)
+	}
+}
+
+func TestRenderFind_ProjectionAliases(t *testing.T) {
+	ast := &types.DocumentAST{
+		Operation: types.OpFind,
+		Target:    types.Collection{Name: "users"},
+		Projection: &types.Projection{Fields: []types.ProjectionField{
+			{Field: types.Field{Path: "name"}, Include: true},
+			{Field: types.Field{Path: "createdAt"}, Include: true, Alias: "created_at"},
+		}},
+	}
+
+	result, err := New().Render(ast)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.FieldAliases) != 1 || result.FieldAliases["createdAt"] != "created_at" {
+		t.Errorf("expected createdAt -> created_at alias, got %v", result.FieldAliases)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	fields, ok := query["return"].([]interface{})
+	if !ok || len(fields) != 2 || fields[0] != "name" || fields[1] != "createdAt" {
+		t.Errorf("expected source fields [name createdAt], got %v", query["return"])
+	}
+}
+
+func TestRender_EmptyParams(t *testing.T) {
+	users := types.Collection{Name: "users"}
+	email := types.Field{Path: "email"}
+	age := types.Field{Path: "age"}
+	byKey := types.FilterCondition{Field: types.Field{Path: "id"}, Operator: types.EQ, Value: types.Param{Name: "id"}}
+
+	tests := []struct {
+		name    string
+		ast     *types.DocumentAST
+		context string
+	}{
+		{
+			name:    "filter",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.FilterCondition{Field: email, Operator: types.EQ}},
+			context: "$eq on field 'email'",
+		},
+		{
+			name:    "range bound",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: types.RangeFilter{Field: age, Min: &types.Param{}}},
+			context: "range on field 'age'",
+		},
+		{
+			name:    "document",
+			ast:     &types.DocumentAST{Operation: types.OpInsert, Target: users, Documents: []types.Document{{Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "document field 'email'",
+		},
+		{
+			name: "update",
+			ast: &types.DocumentAST{Operation: types.OpUpdate, Target: users, FilterClause: byKey,
+				UpdateOps: []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{email: {}}}}},
+			context: "$set field 'email'",
+		},
+		{
+			name:    "pagination",
+			ast:     &types.DocumentAST{Operation: types.OpFind, Target: users, FilterClause: byKey, Limit: &types.PaginationValue{Param: &types.Param{}}},
+			context: "limit",
+		},
+		{
+			name: "pipeline expression",
+			ast: &types.DocumentAST{Operation: types.OpAggregate, Target: users,
+				Pipeline: []types.PipelineStage{types.AddFieldsStage{Fields: map[string]types.Expression{"flag": types.LiteralExpression{}}}}},
+			context: "$addFields field 'flag'",
+		},
+	}
+
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Render(tt.ast)
+			if !errors.Is(err, types.ErrInvalidIdentifier) {
+				t.Fatalf("expected invalid identifier error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.context) {
+				t.Errorf("expected error naming %q, got %v", tt.context, err)
+			}
+		})
+	}
+}
+
+func TestRender_ValuelessUpdateOperators(t *testing.T) {
+	r := New()
+	for _, op := range []types.UpdateOperator{types.Unset, types.CurrentDate} {
+		t.Run(string(op), func(t *testing.T) {
+			ast := &types.DocumentAST{
+				Operation:    types.OpUpdate,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: types.FilterCondition{Field: types.Field{Path: "id"}, Operator: types.EQ, Value: types.Param{Name: "id"}},
+				UpdateOps:    []types.UpdateOperation{{Operator: op, Fields: map[types.Field]types.Param{{Path: "deletedAt"}: {}}}},
+			}
+			result, err := r.Render(ast)
+			if !r.SupportsUpdate(op) {
+				var unsupported *types.UnsupportedError
+				if !errors.As(err, &unsupported) {
+					t.Errorf("expected unsupported operator error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if slices.Contains(result.RequiredParams, "") {
+				t.Errorf("unexpected empty param in %v", result.RequiredParams)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package redisearch
+
+// OutputSchema returns a JSON Schema (draft 2020-12) describing the JSON that
+// Render produces: an FT.SEARCH for reads, or a JSON.SET, JSON.MERGE, or DEL
+// on one document key for writes. The command's arguments are named rather
+// than positional.
+func OutputSchema() string {
+	return outputSchema
+}
+
+const outputSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "docql RediSearch command",
+  "type": "object",
+  "properties": {
+    "command": {"enum": ["FT.SEARCH", "JSON.SET", "JSON.MERGE", "DEL"]},
+    "index": {"type": "string"},
+    "query": {"type": "string", "description": "RediSearch query syntax with :param placeholders."},
+    "language": {"type": "string"},
+    "return": {"type": "array", "items": {"type": "string"}},
+    "sortby": {
+      "type": "array",
+      "items": {"type": "string"},
+      "minItems": 2,
+      "maxItems": 2,
+      "description": "The sort field followed by ASC or DESC."
+    },
+    "limit": {
+      "type": "array",
+      "items": {"type": ["integer", "string"]},
+      "minItems": 2,
+      "maxItems": 2,
+      "description": "Offset and count, each static or a :param placeholder."
+    },
+    "key": {"type": "string", "description": "The collection name and : followed by the key placeholder."},
+    "path": {"const": "$"},
+    "value": {"type": "object"},
+    "condition": {"const": "NX"},
+    "timeout": {"type": "integer"}
+  },
+  "required": ["command"],
+  "oneOf": [
+    {
+      "properties": {"command": {"const": "FT.SEARCH"}},
+      "required": ["index", "query"],
+      "propertyNames": {"enum": ["command", "index", "query", "language", "return", "sortby", "limit", "timeout"]}
+    },
+    {
+      "properties": {"command": {"const": "JSON.SET"}},
+      "required": ["key", "path", "value"],
+      "propertyNames": {"enum": ["command", "key", "path", "value", "condition", "timeout"]}
+    },
+    {
+      "properties": {"command": {"const": "JSON.MERGE"}},
+      "required": ["key", "path", "value"],
+      "propertyNames": {"enum": ["command", "key", "path", "value", "timeout"]}
+    },
+    {
+      "properties": {"command": {"const": "DEL"}},
+      "required": ["key"],
+      "propertyNames": {"enum": ["command", "key", "timeout"]}
+    }
+  ],
+  "additionalProperties": false
+}`
@@ -0,0 +1,408 @@
+package docql
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// Policy restricts the queries a PolicyRenderer will render. The zero Policy
+// allows every operation, collection, and field but rejects unfiltered
+// multi-document reads.
+type Policy struct {
+	// Operations lists the allowed operations; empty allows all. A
+	// transaction needs TRANSACTION and the operation of each write listed.
+	// An aggregation with $merge writes, so it also needs INSERT_MANY when
+	// unmatched documents are inserted and UPDATE_MANY when matched ones
+	// are merged or replaced.
+	Operations []types.Operation
+
+	// Collections lists the collections a query may target, look up, or
+	// merge into; empty allows all.
+	Collections []string
+
+	// AllowParameterizedCollections permits targets named by a param, whose
+	// collection is chosen at bind time and so cannot be checked against
+	// Collections.
+	AllowParameterizedCollections bool
+
+	// DeniedFields lists field paths that may not appear anywhere in a
+	// query, in any collection. A denied path covers its descendants, so
+	// "address" also denies "address.city".
+	DeniedFields []string
+
+	// MaxLimit caps static limits, including $limit stages; 0 sets no cap.
+	// With a cap, param-bound limits are rejected, since their value is not
+	// known until execution.
+	MaxLimit int
+
+	// AllowUnfiltered permits FIND, COUNT, DISTINCT, WATCH, and AGGREGATE
+	// without a filter. An aggregation is filtered when its first stage is $match.
+	AllowUnfiltered bool
+}
+
+// PolicyRenderer checks each query against a Policy before delegating to
+// another renderer. Violations are returned together as a *PolicyError.
+type PolicyRenderer struct {
+	inner  Renderer
+	policy Policy
+}
+
+// NewPolicyRenderer wraps inner so that only queries allowed by policy are
+// rendered.
+func NewPolicyRenderer(inner Renderer, policy Policy) *PolicyRenderer {
+	return &PolicyRenderer{inner: inner, policy: policy}
+}
+
+// Render checks ast against the policy and renders it with the inner
+// renderer when it complies.
+func (r *PolicyRenderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
+	if violations := r.policy.Check(ast); len(violations) > 0 {
+		return nil, &types.PolicyError{Violations: violations}
+	}
+	return r.inner.Render(ast)
+}
+
+// SupportsOperation reports whether the policy allows op and the inner
+// renderer supports it.
+func (r *PolicyRenderer) SupportsOperation(op types.Operation) bool {
+	return r.policy.allowsOperation(op) && r.inner.SupportsOperation(op)
+}
+
+// SupportsFilter delegates to the inner renderer.
+func (r *PolicyRenderer) SupportsFilter(op types.FilterOperator) bool {
+	return r.inner.SupportsFilter(op)
+}
+
+// SupportsUpdate delegates to the inner renderer.
+func (r *PolicyRenderer) SupportsUpdate(op types.UpdateOperator) bool {
+	return r.inner.SupportsUpdate(op)
+}
+
+// SupportsPipelineStage delegates to the inner renderer.
+func (r *PolicyRenderer) SupportsPipelineStage(stage string) bool {
+	return r.inner.SupportsPipelineStage(stage)
+}
+
+// CacheKey reports false, so a registry checks the policy on every render
+// rather than reusing a result another policy allowed.
+func (r *PolicyRenderer) CacheKey() (string, bool) {
+	return "", false
+}
+
+// Check returns every way ast violates the policy, or nil. Each violation is
+// reported once.
+func (p Policy) Check(ast *types.DocumentAST) []types.PolicyViolation {
+	c := &policyChecker{policy: p, seen: make(map[types.PolicyViolation]bool)}
+	c.check(ast)
+	return c.violations
+}
+
+func (p Policy) allowsOperation(op types.Operation) bool {
+	return len(p.Operations) == 0 || slices.Contains(p.Operations, op)
+}
+
+// policyChecker accumulates the violations of one query.
+type policyChecker struct {
+	policy     Policy
+	seen       map[types.PolicyViolation]bool
+	violations []types.PolicyViolation
+}
+
+func (c *policyChecker) violate(rule, value, reason string) {
+	v := types.PolicyViolation{Rule: rule, Value: value, Reason: reason}
+	if !c.seen[v] {
+		c.seen[v] = true
+		c.violations = append(c.violations, v)
+	}
+}
+
+func (c *policyChecker) check(ast *types.DocumentAST) {
+	if !c.policy.allowsOperation(ast.Operation) {
+		c.violate("operation", string(ast.Operation), "is not allowed")
+	}
+	if ast.Operation == types.OpTransaction {
+		for _, op := range ast.Operations {
+			c.check(op)
+		}
+		return
+	}
+
+	c.collection(ast.Target.Name)
+	if ast.Target.Param != nil && !c.policy.AllowParameterizedCollections {
+		c.violate("collection", ":"+ast.Target.Param.Name, "is parameterized and cannot be checked")
+	}
+	c.limit(ast.Limit)
+	if !c.policy.AllowUnfiltered && !filtered(ast) {
+		c.violate("filter", string(ast.Operation), "requires a filter")
+	}
+	if len(c.policy.DeniedFields) > 0 {
+		visitFields(ast, c.field)
+	}
+	for _, stage := range ast.Pipeline {
+		c.stage(stage)
+	}
+}
+
+// stage checks the collections and limits a pipeline stage names.
+func (c *policyChecker) stage(s types.PipelineStage) {
+	switch stage := s.(type) {
+	case types.LimitStage:
+		c.limit(&stage.Limit)
+	case types.LookupStage:
+		c.collection(stage.From)
+		for _, sub := range stage.Pipeline {
+			c.stage(sub)
+		}
+	case types.GraphLookupStage:
+		c.collection(stage.From)
+	case types.MergeStage:
+		c.collection(stage.Into)
+		c.merge(stage)
+	case types.FacetStage:
+		for _, pipeline := range stage.Facets {
+			for _, sub := range pipeline {
+				c.stage(sub)
+			}
+		}
+	}
+}
+
+// merge checks the writes a $merge stage performs against the allowed
+// operations. Empty actions are the server defaults, merge and insert.
+func (c *policyChecker) merge(stage types.MergeStage) {
+	reason := "is not allowed: $merge writes into " + stage.Into
+	switch stage.WhenNotMatched {
+	case "", types.MergeInsert:
+		if !c.policy.allowsOperation(types.OpInsertMany) {
+			c.violate("operation", string(types.OpInsertMany), reason)
+		}
+	}
+	switch stage.WhenMatched {
+	case types.MergeKeepExisting, types.MergeFail:
+	default:
+		if !c.policy.allowsOperation(types.OpUpdateMany) {
+			c.violate("operation", string(types.OpUpdateMany), reason)
+		}
+	}
+}
+
+func (c *policyChecker) collection(name string) {
+	if len(c.policy.Collections) > 0 && !slices.Contains(c.policy.Collections, name) {
+		c.violate("collection", name, "is not allowed")
+	}
+}
+
+func (c *policyChecker) limit(limit *types.PaginationValue) {
+	if c.policy.MaxLimit <= 0 || limit == nil {
+		return
+	}
+	switch {
+	case limit.Param != nil:
+		c.violate("limit", ":"+limit.Param.Name, "cannot be checked against MaxLimit "+strconv.Itoa(c.policy.MaxLimit))
+	case limit.Static != nil && *limit.Static > c.policy.MaxLimit:
+		c.violate("limit", strconv.Itoa(*limit.Static), "exceeds MaxLimit "+strconv.Itoa(c.policy.MaxLimit))
+	}
+}
+
+func (c *policyChecker) field(path string) {
+	for _, denied := range c.policy.DeniedFields {
+		if path == denied || strings.HasPrefix(path, denied+".") {
+			c.violate("field", path, "is denied")
+			return
+		}
+	}
+}
+
+// filtered reports whether a multi-document read restricts the documents it
+// reads. Other operations always count as filtered; writes that need a
+// filter are checked by validation.
+func filtered(ast *types.DocumentAST) bool {
+	switch ast.Operation {
+	case types.OpFind, types.OpCount, types.OpDistinct, types.OpWatch:
+		return ast.FilterClause != nil
+	case types.OpAggregate:
+		if len(ast.Pipeline) == 0 {
+			return false
+		}
+		_, ok := ast.Pipeline[0].(types.MatchStage)
+		return ok
+	default:
+		return true
+	}
+}
+
+// visitFields calls visit with the path of every field a single-collection
+// AST references: in filters, projections, sorts, documents, updates, the
+// distinct field, and pipeline stages and expressions, including lookup
+// fields in other collections. Paths inside $elemMatch are joined to the
+// array field's path.
+func visitFields(ast *types.DocumentAST, visit func(path string)) {
+	var walkFilter func(f types.FilterItem, parent string)
+	var walkExpr func(e types.Expression)
+	var walkStage func(s types.PipelineStage)
+
+	field := func(f types.Field, parent string) {
+		if parent != "" {
+			visit(parent + "." + f.Path)
+			return
+		}
+		visit(f.Path)
+	}
+
+	walkFilter = func(f types.FilterItem, parent string) {
+		switch filter := f.(type) {
+		case types.FilterCondition:
+			field(filter.Field, parent)
+		case types.FilterGroup:
+			for _, c := range filter.Conditions {
+				walkFilter(c, parent)
+			}
+		case types.RangeFilter:
+			field(filter.Field, parent)
+		case types.RegexFilter:
+			field(filter.Field, parent)
+		case types.GeoFilter:
+			field(filter.Field, parent)
+		case types.ArrayFilter:
+			field(filter.Field, parent)
+		case types.ValuesFilter:
+			field(filter.Field, parent)
+		case types.ModFilter:
+			field(filter.Field, parent)
+		case types.TypeFilter:
+			field(filter.Field, parent)
+		case types.ExistsFilter:
+			field(filter.Field, parent)
+		case types.NullFilter:
+			field(filter.Field, parent)
+		case types.ElemMatchFilter:
+			field(filter.Field, parent)
+			path := filter.Field.Path
+			if parent != "" {
+				path = parent + "." + path
+			}
+			for _, c := range filter.Conditions {
+				walkFilter(c, path)
+			}
+		}
+	}
+
+	walkExpr = func(e types.Expression) {
+		switch expr := e.(type) {
+		case types.FieldExpression:
+			visit(expr.Field.Path)
+		case types.OperatorExpression:
+			for _, arg := range expr.Args {
+				walkExpr(arg)
+			}
+		case types.ConditionalExpression:
+			walkExpr(expr.If)
+			walkExpr(expr.Then)
+			walkExpr(expr.Else)
+		case types.CompositeExpression:
+			for _, sub := range expr.Fields {
+				walkExpr(sub)
+			}
+		}
+	}
+
+	walkProjection := func(p types.Projection) {
+		for _, f := range p.Fields {
+			visit(f.Field.Path)
+			if f.ElemMatch != nil {
+				for _, c := range f.ElemMatch.Conditions {
+					walkFilter(c, f.Field.Path)
+				}
+			}
+		}
+	}
+
+	walkAccumulators := func(accs map[string]types.Accumulator) {
+		for _, acc := range accs {
+			walkExpr(acc.Expr)
+		}
+	}
+
+	walkStage = func(s types.PipelineStage) {
+		switch stage := s.(type) {
+		case types.MatchStage:
+			walkFilter(stage.Filter, "")
+		case types.ProjectStage:
+			walkProjection(stage.Projection)
+			for _, e := range stage.Computed {
+				walkExpr(e)
+			}
+		case types.GroupStage:
+			walkExpr(stage.ID)
+			walkAccumulators(stage.Accumulators)
+		case types.SortStage:
+			for _, s := range stage.Sorts {
+				visit(s.Field.Path)
+			}
+		case types.UnwindStage:
+			visit(stage.Path.Path)
+		case types.LookupStage:
+			visit(stage.LocalField.Path)
+			visit(stage.ForeignField.Path)
+			for _, e := range stage.Let {
+				walkExpr(e)
+			}
+			for _, sub := range stage.Pipeline {
+				walkStage(sub)
+			}
+		case types.GraphLookupStage:
+			walkExpr(stage.StartWith)
+			visit(stage.ConnectFromField.Path)
+			visit(stage.ConnectToField.Path)
+			if stage.RestrictSearchWithMatch != nil {
+				walkFilter(stage.RestrictSearchWithMatch, "")
+			}
+		case types.AddFieldsStage:
+			for _, e := range stage.Fields {
+				walkExpr(e)
+			}
+		case types.ReplaceRootStage:
+			walkExpr(stage.NewRoot)
+		case types.FacetStage:
+			for _, pipeline := range stage.Facets {
+				for _, sub := range pipeline {
+					walkStage(sub)
+				}
+			}
+		case types.BucketStage:
+			walkExpr(stage.GroupBy)
+			walkAccumulators(stage.Output)
+		case types.SortByCountStage:
+			walkExpr(stage.Expr)
+		}
+	}
+
+	if ast.FilterClause != nil {
+		walkFilter(ast.FilterClause, "")
+	}
+	if ast.Projection != nil {
+		walkProjection(*ast.Projection)
+	}
+	for _, s := range ast.SortClauses {
+		visit(s.Field.Path)
+	}
+	for _, doc := range ast.Documents {
+		for f := range doc.Fields {
+			visit(f.Path)
+		}
+	}
+	for _, op := range ast.UpdateOps {
+		for f := range op.Fields {
+			visit(f.Path)
+		}
+	}
+	if ast.DistinctField != nil {
+		visit(ast.DistinctField.Path)
+	}
+	for _, stage := range ast.Pipeline {
+		walkStage(stage)
+	}
+}
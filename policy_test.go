@@ -0,0 +1,218 @@
+package docql_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/zoobzio/docql"
+	"github.com/zoobzio/docql/internal/types"
+	"github.com/zoobzio/docql/pkg/couchdb"
+	"github.com/zoobzio/docql/pkg/mongodb"
+)
+
+func policyRenderers() map[string]docql.Renderer {
+	return map[string]docql.Renderer{
+		"mongodb": mongodb.New(),
+		"couchdb": couchdb.New(),
+	}
+}
+
+// assertPolicy renders b under policy with each renderer and checks the rules
+// of the violations reported, or that rendering succeeds when rules is empty.
+func assertPolicy(t *testing.T, b *docql.Builder, policy docql.Policy, rules ...string) {
+	t.Helper()
+	for name, inner := range policyRenderers() {
+		r := docql.NewPolicyRenderer(inner, policy)
+		result, err := b.Render(r)
+		if len(rules) == 0 {
+			if err != nil {
+				t.Errorf("%s: Render failed: %v", name, err)
+			} else if want, _ := b.Render(inner); result.JSON != want.JSON {
+				t.Errorf("%s: policy changed output:\n got: %s\nwant: %s", name, result.JSON, want.JSON)
+			}
+			continue
+		}
+		var perr *docql.PolicyError
+		if !errors.As(err, &perr) || !errors.Is(err, docql.ErrPolicyViolation) {
+			t.Errorf("%s: expected policy error, got %v", name, err)
+			continue
+		}
+		got := make([]string, len(perr.Violations))
+		for i, v := range perr.Violations {
+			got[i] = v.Rule
+		}
+		if !slices.Equal(got, rules) {
+			t.Errorf("%s: expected rules %v, got %v (%v)", name, rules, got, err)
+		}
+	}
+}
+
+func TestPolicy_Operations(t *testing.T) {
+	instance := createTestInstance(t)
+	policy := docql.Policy{Operations: []docql.Operation{docql.OpFind}}
+	filter := instance.Eq(instance.F("users", "status"), instance.P("status"))
+
+	assertPolicy(t, docql.Find(instance.C("users")).Filter(filter), policy)
+	assertPolicy(t, docql.Update(instance.C("users")).Filter(filter).Set(instance.F("users", "active"), instance.P("active")), policy, "operation")
+
+	r := docql.NewPolicyRenderer(mongodb.New(), policy)
+	if !r.SupportsOperation(docql.OpFind) || r.SupportsOperation(docql.OpUpdate) {
+		t.Error("SupportsOperation should reflect the allowed operations")
+	}
+}
+
+func TestPolicy_Collections(t *testing.T) {
+	instance := createTestInstance(t)
+	policy := docql.Policy{Collections: []string{"users"}}
+
+	assertPolicy(t, docql.Find(instance.C("users")).Filter(instance.Eq(instance.F("users", "status"), instance.P("status"))), policy)
+	assertPolicy(t, docql.Find(instance.C("posts")).Filter(instance.Eq(instance.F("posts", "title"), instance.P("title"))), policy, "collection")
+
+	// Lookups name a second collection and are checked too.
+	lookup := docql.Aggregate(instance.C("users")).
+		Match(instance.Eq(instance.F("users", "status"), instance.P("status"))).
+		Lookup("posts", instance.F("users", "_id"), instance.F("posts", "userId"), "posts")
+	r := docql.NewPolicyRenderer(mongodb.New(), policy)
+	if _, err := lookup.Render(r); !errors.Is(err, docql.ErrPolicyViolation) {
+		t.Errorf("expected lookup into posts to be denied, got %v", err)
+	}
+}
+
+func TestPolicy_ParameterizedCollection(t *testing.T) {
+	instance := createTestInstance(t)
+	filter := instance.Eq(instance.F("users", "status"), instance.P("status"))
+	b := docql.Find(docql.ParamCollection(instance.C("users"), instance.P("coll"))).Filter(filter)
+
+	checkViolations(t, docql.Policy{Collections: []string{"users"}}, b, ":coll")
+	checkViolations(t, docql.Policy{}, b, ":coll")
+	checkViolations(t, docql.Policy{AllowParameterizedCollections: true}, b)
+}
+
+func TestPolicy_MergeIsAWrite(t *testing.T) {
+	instance := createTestInstance(t)
+	match := instance.Eq(instance.F("users", "status"), instance.P("status"))
+	readOnly := docql.Policy{Operations: []docql.Operation{docql.OpFind, docql.OpAggregate}}
+
+	merge := docql.Aggregate(instance.C("users")).Match(match).MergeInto("archive", "", "")
+	checkViolations(t, readOnly, merge, "INSERT_MANY", "UPDATE_MANY")
+
+	insertOnly := docql.Aggregate(instance.C("users")).Match(match).MergeInto("archive", docql.MergeKeepExisting, docql.MergeInsert)
+	checkViolations(t, readOnly, insertOnly, "INSERT_MANY")
+	checkViolations(t, docql.Policy{Operations: []docql.Operation{docql.OpAggregate, docql.OpInsertMany}}, insertOnly)
+
+	checkViolations(t, readOnly, docql.Aggregate(instance.C("users")).Match(match))
+}
+
+func TestPolicy_DeniedFields(t *testing.T) {
+	instance := createTestInstance(t)
+	policy := docql.Policy{DeniedFields: []string{"email"}}
+	username := instance.F("users", "username")
+	email := instance.F("users", "email")
+
+	assertPolicy(t, docql.Find(instance.C("users")).Filter(instance.Eq(username, instance.P("name"))).Select(username), policy)
+	assertPolicy(t, docql.Find(instance.C("users")).Filter(instance.Eq(email, instance.P("email"))), policy, "field")
+	assertPolicy(t, docql.Find(instance.C("users")).Filter(instance.Eq(username, instance.P("name"))).Select(email), policy, "field")
+	assertPolicy(t, docql.Find(instance.C("users")).Filter(instance.Eq(username, instance.P("name"))).SortAsc(email), policy, "field")
+	assertPolicy(t, docql.Update(instance.C("users")).Filter(instance.Eq(username, instance.P("name"))).Set(email, instance.P("email")), policy, "field")
+	assertPolicy(t, docql.Insert(instance.C("users")).Document(docql.Doc().Set(email, instance.P("email")).Build()), policy, "field")
+
+	// Fields inside $elemMatch, pipeline expressions, and lookups are joined
+	// and matched by full path; a denied path covers its descendants.
+	nested := docql.Policy{DeniedFields: []string{"username.secret", "items.secret", "userId"}}
+	elem := docql.Find(instance.C("users")).Filter(docql.ElemMatch(instance.F("users", "username"),
+		instance.Eq(types.Field{Path: "secret"}, instance.P("s"))))
+	checkViolations(t, nested, elem, "username.secret")
+
+	group := docql.Aggregate(instance.C("users")).
+		Match(instance.Eq(username, instance.P("name"))).
+		Group(docql.FieldExpr(types.Field{Path: "items.secret.code"}), map[string]docql.Accumulator{"n": docql.CountAcc()})
+	checkViolations(t, nested, group, "items.secret.code")
+
+	lookup := docql.Aggregate(instance.C("users")).
+		Match(instance.Eq(username, instance.P("name"))).
+		Lookup("posts", instance.F("users", "_id"), instance.F("posts", "userId"), "posts")
+	checkViolations(t, nested, lookup, "userId")
+}
+
+func TestPolicy_MaxLimit(t *testing.T) {
+	instance := createTestInstance(t)
+	policy := docql.Policy{MaxLimit: 100}
+	filter := instance.Eq(instance.F("users", "status"), instance.P("status"))
+
+	assertPolicy(t, docql.Find(instance.C("users")).Filter(filter).Limit(100), policy)
+	assertPolicy(t, docql.Find(instance.C("users")).Filter(filter).Limit(101), policy, "limit")
+	assertPolicy(t, docql.Find(instance.C("users")).Filter(filter).LimitParam(instance.P("n")), policy, "limit")
+	assertPolicy(t, docql.Find(instance.C("users")).Filter(filter).LimitParam(instance.P("n")), docql.Policy{})
+}
+
+func TestPolicy_Unfiltered(t *testing.T) {
+	instance := createTestInstance(t)
+
+	assertPolicy(t, docql.Find(instance.C("users")), docql.Policy{}, "filter")
+	assertPolicy(t, docql.Find(instance.C("users")), docql.Policy{AllowUnfiltered: true})
+	assertPolicy(t, docql.Count(instance.C("users")), docql.Policy{}, "filter")
+	checkViolations(t, docql.Policy{}, docql.Watch(instance.C("users")), "WATCH")
+	checkViolations(t, docql.Policy{AllowUnfiltered: true}, docql.Watch(instance.C("users")))
+
+	r := docql.NewPolicyRenderer(mongodb.New(), docql.Policy{})
+	unmatched := docql.Aggregate(instance.C("users")).Unwind(instance.F("users", "status"))
+	if _, err := unmatched.Render(r); !errors.Is(err, docql.ErrPolicyViolation) {
+		t.Errorf("expected aggregation without leading $match to be denied, got %v", err)
+	}
+	matched := docql.Aggregate(instance.C("users")).Match(instance.Eq(instance.F("users", "status"), instance.P("status")))
+	if _, err := matched.Render(r); err != nil {
+		t.Errorf("Render failed: %v", err)
+	}
+}
+
+func TestPolicy_ListsEveryViolation(t *testing.T) {
+	instance := createTestInstance(t)
+	policy := docql.Policy{
+		Operations:   []docql.Operation{docql.OpCount},
+		Collections:  []string{"posts"},
+		DeniedFields: []string{"email", "status"},
+		MaxLimit:     10,
+	}
+	b := docql.Find(instance.C("users")).
+		Filter(instance.Eq(instance.F("users", "email"), instance.P("email"))).
+		SortAsc(instance.F("users", "status")).
+		Limit(50)
+	assertPolicy(t, b, policy, "operation", "collection", "limit", "field", "field")
+
+	_, err := b.Render(docql.NewPolicyRenderer(mongodb.New(), policy))
+	want := "policy violation: operation 'FIND' is not allowed; collection 'users' is not allowed; " +
+		"limit '50' exceeds MaxLimit 10; field 'email' is denied; field 'status' is denied"
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error:\n got: %v\nwant: %s", err, want)
+	}
+}
+
+func TestPolicy_Transaction(t *testing.T) {
+	instance := createTestInstance(t)
+	insert := docql.Insert(instance.C("users")).Document(docql.Doc().Set(instance.F("users", "email"), instance.P("email")).Build())
+	tx := docql.Transaction().Add(insert)
+
+	policy := docql.Policy{Operations: []docql.Operation{docql.OpTransaction}, DeniedFields: []string{"email"}}
+	r := docql.NewPolicyRenderer(mongodb.New(), policy)
+	_, err := tx.Render(r)
+	var perr *docql.PolicyError
+	if !errors.As(err, &perr) || len(perr.Violations) != 2 {
+		t.Fatalf("expected operation and field violations, got %v", err)
+	}
+}
+
+func checkViolations(t *testing.T, policy docql.Policy, b *docql.Builder, values ...string) {
+	t.Helper()
+	ast, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	var got []string
+	for _, v := range policy.Check(ast) {
+		got = append(got, v.Value)
+	}
+	if !slices.Equal(got, values) {
+		t.Errorf("expected violations for %v, got %v", values, got)
+	}
+}
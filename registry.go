@@ -0,0 +1,321 @@
+package docql
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// QueryInfo describes a registered query.
+type QueryInfo struct {
+	Name           string
+	Operation      types.Operation
+	Collection     string
+	RequiredParams []string
+}
+
+// Registry holds a fixed set of named, pre-validated queries. Registered ASTs
+// are deep copies, so later changes to the originating Builder never reach
+// them. Rendered results are cached per renderer type and, for renderers
+// implementing CacheKeyer, per CacheKey. A Registry is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]*registryEntry
+}
+
+type registryEntry struct {
+	ast    *types.DocumentAST
+	info   QueryInfo
+	mu     sync.Mutex
+	render map[renderKey]*types.QueryResult
+}
+
+// renderKey identifies the renderer configuration a cached result came from.
+type renderKey struct {
+	renderer reflect.Type
+	options  string
+}
+
+// NewRegistry creates an empty query registry.
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]*registryEntry)}
+}
+
+// Register builds and validates b and stores the result under name. It fails
+// if the name is empty or already registered, or if the builder has an error.
+func (r *Registry) Register(name string, b *Builder) error {
+	if name == "" {
+		return fmt.Errorf("query name is required")
+	}
+	ast, err := b.Build()
+	if err != nil {
+		return fmt.Errorf("query '%s': %w", name, err)
+	}
+	ast = ast.Clone()
+
+	entry := &registryEntry{
+		ast: ast,
+		info: QueryInfo{
+			Name:           name,
+			Operation:      ast.Operation,
+			Collection:     ast.Target.Name,
+			RequiredParams: requiredParams(ast),
+		},
+		render: make(map[renderKey]*types.QueryResult),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.entries[name]; exists {
+		return fmt.Errorf("query '%s' already registered", name)
+	}
+	r.entries[name] = entry
+	return nil
+}
+
+// Render renders the named query, reusing the cached result for the
+// renderer's type and CacheKey when there is one. Callers receive their own
+// copy.
+func (r *Registry) Render(name string, renderer Renderer) (*types.QueryResult, error) {
+	r.mu.RLock()
+	entry, ok := r.entries[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("query '%s' not registered", name)
+	}
+
+	key := renderKey{renderer: reflect.TypeOf(renderer)}
+	cached := true
+	if k, ok := renderer.(CacheKeyer); ok {
+		key.options, cached = k.CacheKey()
+	}
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	result, ok := entry.render[key]
+	if !ok || !cached {
+		var err error
+		result, err = renderer.Render(entry.ast)
+		if err != nil {
+			return nil, fmt.Errorf("query '%s': %w", name, err)
+		}
+		result.ParamMinimums = paramMinimums(entry.ast)
+		if cached {
+			entry.render[key] = result
+		}
+	}
+	return copyResult(result), nil
+}
+
+// List returns every registered query, sorted by name.
+func (r *Registry) List() []QueryInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]QueryInfo, 0, len(r.entries))
+	for _, name := range slices.Sorted(maps.Keys(r.entries)) {
+		info := r.entries[name].info
+		info.RequiredParams = slices.Clone(info.RequiredParams)
+		out = append(out, info)
+	}
+	return out
+}
+
+func copyResult(result *types.QueryResult) *types.QueryResult {
+	out := *result
+	out.RequiredParams = slices.Clone(result.RequiredParams)
+	out.Warnings = slices.Clone(result.Warnings)
+	out.FieldAliases = maps.Clone(result.FieldAliases)
+	out.ParamMinimums = maps.Clone(result.ParamMinimums)
+	if result.EnumParams != nil {
+		out.EnumParams = make(map[string][]string, len(result.EnumParams))
+		for k, v := range result.EnumParams {
+			out.EnumParams[k] = slices.Clone(v)
+		}
+	}
+	return &out
+}
+
+// requiredParams collects every param name the AST references, sorted and
+// deduplicated, independent of any renderer.
+func requiredParams(ast *types.DocumentAST) []string {
+	seen := make(map[string]bool)
+	add := func(p *types.Param) {
+		if p != nil && p.Name != "" {
+			seen[p.Name] = true
+		}
+	}
+	addPagination := func(p *types.PaginationValue) {
+		if p != nil {
+			add(p.Param)
+		}
+	}
+
+	var walkFilter func(f types.FilterItem)
+	var walkExpr func(e types.Expression)
+	var walkStage func(s types.PipelineStage)
+
+	walkFilter = func(f types.FilterItem) {
+		switch filter := f.(type) {
+		case types.FilterCondition:
+			add(&filter.Value)
+		case types.FilterGroup:
+			for _, c := range filter.Conditions {
+				walkFilter(c)
+			}
+		case types.RangeFilter:
+			add(filter.Min)
+			add(filter.Max)
+		case types.RegexFilter:
+			add(&filter.Pattern)
+			add(filter.Options)
+		case types.TextSearchFilter:
+			add(&filter.Search)
+			add(filter.Language)
+		case types.GeoFilter:
+			add(&filter.Center.Lon)
+			add(&filter.Center.Lat)
+			add(filter.Radius)
+			add(filter.MaxDistance)
+			add(filter.MinDistance)
+		case types.ArrayFilter:
+			add(&filter.Value)
+		case types.ValuesFilter:
+			for i := range filter.Values {
+				add(&filter.Values[i])
+			}
+		case types.ModFilter:
+			add(&filter.Divisor)
+			add(&filter.Remainder)
+		case types.ElemMatchFilter:
+			for _, c := range filter.Conditions {
+				walkFilter(c)
+			}
+		}
+	}
+
+	walkExpr = func(e types.Expression) {
+		switch expr := e.(type) {
+		case types.LiteralExpression:
+			add(&expr.Value)
+		case types.OperatorExpression:
+			for _, arg := range expr.Args {
+				walkExpr(arg)
+			}
+		case types.ConditionalExpression:
+			walkExpr(expr.If)
+			walkExpr(expr.Then)
+			walkExpr(expr.Else)
+		case types.CompositeExpression:
+			for _, field := range expr.Fields {
+				walkExpr(field)
+			}
+		}
+	}
+
+	walkProjection := func(p types.Projection) {
+		for _, f := range p.Fields {
+			if f.Slice != nil {
+				add(&f.Slice.Count)
+				add(f.Slice.Skip)
+			}
+			if f.ElemMatch != nil {
+				for _, c := range f.ElemMatch.Conditions {
+					walkFilter(c)
+				}
+			}
+		}
+	}
+
+	walkStage = func(s types.PipelineStage) {
+		switch stage := s.(type) {
+		case types.MatchStage:
+			walkFilter(stage.Filter)
+		case types.ProjectStage:
+			walkProjection(stage.Projection)
+			for _, e := range stage.Computed {
+				walkExpr(e)
+			}
+		case types.GroupStage:
+			walkExpr(stage.ID)
+			for _, acc := range stage.Accumulators {
+				walkExpr(acc.Expr)
+			}
+		case types.LimitStage:
+			addPagination(&stage.Limit)
+		case types.SkipStage:
+			addPagination(&stage.Skip)
+		case types.LookupStage:
+			for _, e := range stage.Let {
+				walkExpr(e)
+			}
+			for _, sub := range stage.Pipeline {
+				walkStage(sub)
+			}
+		case types.GraphLookupStage:
+			walkExpr(stage.StartWith)
+			addPagination(stage.MaxDepth)
+			if stage.RestrictSearchWithMatch != nil {
+				walkFilter(stage.RestrictSearchWithMatch)
+			}
+		case types.AddFieldsStage:
+			for _, e := range stage.Fields {
+				walkExpr(e)
+			}
+		case types.ReplaceRootStage:
+			walkExpr(stage.NewRoot)
+		case types.FacetStage:
+			for _, pipeline := range stage.Facets {
+				for _, sub := range pipeline {
+					walkStage(sub)
+				}
+			}
+		case types.BucketStage:
+			walkExpr(stage.GroupBy)
+			for i := range stage.Boundaries {
+				add(&stage.Boundaries[i])
+			}
+			add(stage.Default)
+			for _, acc := range stage.Output {
+				walkExpr(acc.Expr)
+			}
+		case types.SortByCountStage:
+			walkExpr(stage.Expr)
+		case types.SampleStage:
+			addPagination(&stage.Size)
+		}
+	}
+
+	add(ast.Target.Param)
+	add(ast.ResumeAfter)
+	if ast.FilterClause != nil {
+		walkFilter(ast.FilterClause)
+	}
+	if ast.Projection != nil {
+		walkProjection(*ast.Projection)
+	}
+	addPagination(ast.Skip)
+	addPagination(ast.Limit)
+	for _, doc := range ast.Documents {
+		for _, p := range doc.Fields {
+			add(&p)
+		}
+	}
+	for _, op := range ast.UpdateOps {
+		for _, p := range op.Fields {
+			add(&p)
+		}
+	}
+	for _, stage := range ast.Pipeline {
+		walkStage(stage)
+	}
+	for _, op := range ast.Operations {
+		for _, name := range requiredParams(op) {
+			seen[name] = true
+		}
+	}
+
+	return slices.Sorted(maps.Keys(seen))
+}
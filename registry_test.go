@@ -0,0 +1,247 @@
+package docql
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zoobzio/docql/internal/types"
+	"github.com/zoobzio/docql/pkg/dynamodb"
+)
+
+// countingRenderer counts Render calls so tests can observe caching.
+type countingRenderer struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (r *countingRenderer) Render(ast *types.DocumentAST) (*types.QueryResult, error) {
+	r.mu.Lock()
+	r.calls++
+	r.mu.Unlock()
+	return &types.QueryResult{JSON: fmt.Sprintf(`{"collection":%q}`, ast.Target.Name)}, nil
+}
+
+func (r *countingRenderer) SupportsOperation(types.Operation) bool   { return true }
+func (r *countingRenderer) SupportsFilter(types.FilterOperator) bool { return true }
+func (r *countingRenderer) SupportsUpdate(types.UpdateOperator) bool { return true }
+func (r *countingRenderer) SupportsPipelineStage(stage string) bool  { return true }
+
+// keyedRenderer is a countingRenderer that reports a CacheKey.
+type keyedRenderer struct {
+	countingRenderer
+	key    string
+	cached bool
+}
+
+func (r *keyedRenderer) CacheKey() (string, bool) { return r.key, r.cached }
+
+func registryQuery() *Builder {
+	return Find(types.Collection{Name: "users"}).
+		Filter(Eq(types.Field{Path: "status"}, types.Param{Name: "status"})).
+		LimitParam(types.Param{Name: "limit"})
+}
+
+func TestRegistry_RegisterAndList(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("users.active", registryQuery()); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := r.Register("orders.delete", DeleteMany(types.Collection{Name: "orders"}).
+		Filter(Eq(types.Field{Path: "_id"}, types.Param{Name: "id"}))); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	list := r.List()
+	if len(list) != 2 || list[0].Name != "orders.delete" || list[1].Name != "users.active" {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+	users := list[1]
+	if users.Operation != types.OpFind || users.Collection != "users" {
+		t.Errorf("unexpected info: %+v", users)
+	}
+	if !slices.Equal(users.RequiredParams, []string{"limit", "status"}) {
+		t.Errorf("RequiredParams = %v", users.RequiredParams)
+	}
+}
+
+func TestRegistry_RejectsDuplicatesAndErrors(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("q", registryQuery()); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := r.Register("q", registryQuery()); err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+	if err := r.Register("bad", Insert(types.Collection{Name: "users"}).Sort(types.Field{Path: "a"}, Ascending)); err == nil {
+		t.Error("expected builder error to fail registration")
+	}
+	if err := r.Register("", registryQuery()); err == nil {
+		t.Error("expected empty name to fail registration")
+	}
+	if len(r.List()) != 1 {
+		t.Errorf("failed registrations should not be stored: %+v", r.List())
+	}
+}
+
+func TestRegistry_BuilderMutationAfterRegister(t *testing.T) {
+	b := Update(types.Collection{Name: "users"}).
+		Filter(Eq(types.Field{Path: "_id"}, types.Param{Name: "id"})).
+		Set(types.Field{Path: "status"}, types.Param{Name: "status"})
+
+	r := NewRegistry()
+	if err := r.Register("q", b); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	// Set merges into the existing $set map in place; the registered copy must not see it.
+	b.Set(types.Field{Path: "name"}, types.Param{Name: "name"}).
+		Filter(Eq(types.Field{Path: "tenant"}, types.Param{Name: "tenant"}))
+
+	info := r.List()[0]
+	if !slices.Equal(info.RequiredParams, []string{"id", "status"}) {
+		t.Errorf("registered query changed: RequiredParams = %v", info.RequiredParams)
+	}
+	entry := r.entries["q"]
+	if len(entry.ast.UpdateOps[0].Fields) != 1 {
+		t.Errorf("registered update ops changed: %v", entry.ast.UpdateOps)
+	}
+	if _, ok := entry.ast.FilterClause.(types.FilterCondition); !ok {
+		t.Errorf("registered filter changed: %T", entry.ast.FilterClause)
+	}
+}
+
+func TestRegistry_RenderCachesPerRendererType(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("q", registryQuery()); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	first := &countingRenderer{}
+	result, err := r.Render("q", first)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	result.Warnings = append(result.Warnings, "caller mutation")
+
+	second := &countingRenderer{}
+	again, err := r.Render("q", second)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if first.calls != 1 || second.calls != 0 {
+		t.Errorf("expected one render per renderer type, got %d and %d", first.calls, second.calls)
+	}
+	if again.JSON != result.JSON || len(again.Warnings) != 0 {
+		t.Errorf("cached result was not isolated from caller: %+v", again)
+	}
+
+	if _, err := r.Render("missing", first); err == nil {
+		t.Error("expected error for unregistered query")
+	}
+}
+
+func TestRegistry_RenderCachesPerCacheKey(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("q", registryQuery()); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	a := &keyedRenderer{key: "a", cached: true}
+	sameA := &keyedRenderer{key: "a", cached: true}
+	b := &keyedRenderer{key: "b", cached: true}
+	uncached := &keyedRenderer{}
+	for _, renderer := range []*keyedRenderer{a, sameA, b, uncached, uncached} {
+		if _, err := r.Render("q", renderer); err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+	}
+	if a.calls != 1 || sameA.calls != 0 {
+		t.Errorf("expected renderers with the same key to share a result, got %d and %d", a.calls, sameA.calls)
+	}
+	if b.calls != 1 {
+		t.Errorf("expected a different key to render again, got %d", b.calls)
+	}
+	if uncached.calls != 2 {
+		t.Errorf("expected an uncacheable renderer to render every call, got %d", uncached.calls)
+	}
+
+	if err := r.Register("update", Update(types.Collection{Name: "users"}).
+		Filter(Eq(types.Field{Path: "pk"}, types.Param{Name: "id"})).
+		Set(types.Field{Path: "status"}, types.Param{Name: "status"})); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	plain, err := r.Render("update", dynamodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	withReturn, err := r.Render("update", dynamodb.New().WithReturnValues("ALL_NEW"))
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if strings.Contains(plain.JSON, "ALL_NEW") || !strings.Contains(withReturn.JSON, "ALL_NEW") {
+		t.Errorf("expected renderer options to be cached apart:\n%s\n%s", plain.JSON, withReturn.JSON)
+	}
+
+	policy := NewPolicyRenderer(&countingRenderer{}, Policy{AllowUnfiltered: true})
+	if _, err := r.Render("q", policy); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	strict := NewPolicyRenderer(&countingRenderer{}, Policy{DeniedFields: []string{"status"}})
+	if _, err := r.Render("q", strict); err == nil {
+		t.Error("expected the second policy to be checked rather than served from the cache")
+	}
+}
+
+func TestRegistry_RenderParamMinimums(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("q", registryQuery()); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	renderer := &countingRenderer{}
+	for range 2 {
+		result, err := r.Render("q", renderer)
+		if err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		if result.ParamMinimums["limit"] != 1 || len(result.ParamMinimums) != 1 {
+			t.Errorf("unexpected minimums: %v", result.ParamMinimums)
+		}
+		result.ParamMinimums["limit"] = 0
+	}
+}
+
+func TestRegistry_Concurrent(t *testing.T) {
+	r := NewRegistry()
+	renderer := &countingRenderer{}
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := r.Register(fmt.Sprintf("q%d", i), registryQuery()); err != nil {
+				t.Errorf("Register failed: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			_ = r.Register("shared", registryQuery())
+			if _, err := r.Render("shared", renderer); err != nil && !strings.Contains(err.Error(), "not registered") {
+				t.Errorf("Render failed: %v", err)
+			}
+			_ = r.List()
+		}()
+	}
+	wg.Wait()
+
+	if got := len(r.List()); got != 51 {
+		t.Errorf("expected 51 queries, got %d", got)
+	}
+	if renderer.calls != 1 {
+		t.Errorf("expected shared query rendered once, got %d", renderer.calls)
+	}
+}
@@ -19,3 +19,12 @@ type Renderer interface {
 	// SupportsPipelineStage indicates if the provider supports a pipeline stage.
 	SupportsPipelineStage(stage string) bool
 }
+
+// CacheKeyer is implemented by renderers whose output depends on their
+// options. A Registry caches results per renderer type and CacheKey, so
+// renderers configured differently never share a result; when CacheKey
+// reports false, as for options that cannot be compared, the query is rendered
+// on every call.
+type CacheKeyer interface {
+	CacheKey() (string, bool)
+}
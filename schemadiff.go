@@ -0,0 +1,151 @@
+package docql
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/zoobzio/ddml"
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// SchemaDiff reports the changes between two schemas that can break queries
+// built against the older one. A renamed field shows up as a removed path and
+// an added one; DDML carries no rename history to pair them.
+type SchemaDiff struct {
+	RemovedCollections []string
+	AddedCollections   []string
+
+	// Fields of collections present in both schemas, sorted by collection
+	// and path. Fields of removed or added collections are not listed.
+	RemovedFields []FieldRef
+	AddedFields   []FieldRef
+	TypeChanges   []FieldTypeChange
+}
+
+// FieldRef names a field path in a collection.
+type FieldRef struct {
+	Collection string
+	Path       string
+}
+
+// FieldTypeChange reports a field path whose DDML type differs between schemas.
+type FieldTypeChange struct {
+	Collection string
+	Path       string
+	OldType    ddml.FieldType
+	NewType    ddml.FieldType
+}
+
+// Breaking reports whether the diff removes collections or fields or changes
+// field types. Additions alone never break existing queries.
+func (s SchemaDiff) Breaking() bool {
+	return len(s.RemovedCollections) > 0 || len(s.RemovedFields) > 0 || len(s.TypeChanges) > 0
+}
+
+// DiffSchemas compares the collections and indexed field paths of two
+// instances, as F resolves them, including paths inside array elements.
+func DiffSchemas(before, after *DOCQL) SchemaDiff {
+	var diff SchemaDiff
+	for _, name := range slices.Sorted(maps.Keys(before.fields)) {
+		newFields, ok := after.fields[name]
+		if !ok {
+			diff.RemovedCollections = append(diff.RemovedCollections, name)
+			continue
+		}
+		oldFields := before.fields[name]
+		for _, path := range slices.Sorted(maps.Keys(oldFields)) {
+			nf, ok := newFields[path]
+			if !ok {
+				diff.RemovedFields = append(diff.RemovedFields, FieldRef{Collection: name, Path: path})
+				continue
+			}
+			if of := oldFields[path]; of.Type != nf.Type {
+				diff.TypeChanges = append(diff.TypeChanges, FieldTypeChange{
+					Collection: name,
+					Path:       path,
+					OldType:    of.Type,
+					NewType:    nf.Type,
+				})
+			}
+		}
+		for _, path := range slices.Sorted(maps.Keys(newFields)) {
+			if _, ok := oldFields[path]; !ok {
+				diff.AddedFields = append(diff.AddedFields, FieldRef{Collection: name, Path: path})
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(after.fields)) {
+		if _, ok := before.fields[name]; !ok {
+			diff.AddedCollections = append(diff.AddedCollections, name)
+		}
+	}
+	return diff
+}
+
+// ValidationIssue reports a collection or field reference in a query that is
+// missing from a schema. Field is empty for unknown collections. Err is an
+// *UnknownCollectionError or an *UnknownFieldError naming the single field.
+type ValidationIssue struct {
+	Collection string
+	Field      string
+	Err        error
+}
+
+func (i ValidationIssue) Error() string {
+	return i.Err.Error()
+}
+
+// Unwrap returns the underlying error, so errors.Is matches
+// ErrUnknownCollection and ErrUnknownField.
+func (i ValidationIssue) Unwrap() error {
+	return i.Err
+}
+
+// ValidateAgainst checks every collection and field reference in a built AST
+// against d and returns all issues, in reference order, or nil. It covers
+// the same clauses as BuildChecked, and each operation of a transaction is
+// checked against its own collection.
+func ValidateAgainst(ast *types.DocumentAST, d *DOCQL) []ValidationIssue {
+	if ast.Operation == types.OpTransaction {
+		var issues []ValidationIssue
+		for _, op := range ast.Operations {
+			issues = append(issues, validateAgainst(op, d)...)
+		}
+		return issues
+	}
+	return validateAgainst(ast, d)
+}
+
+func validateAgainst(ast *types.DocumentAST, d *DOCQL) []ValidationIssue {
+	name := ast.Target.Name
+	if _, ok := d.fields[name]; !ok {
+		return []ValidationIssue{{Collection: name, Err: &types.UnknownCollectionError{Collection: name}}}
+	}
+
+	unknown := unknownFields(ast, d.fields)
+	if len(unknown) == 0 {
+		return nil
+	}
+	issues := make([]ValidationIssue, len(unknown))
+	for i, ref := range unknown {
+		issues[i] = ValidationIssue{Collection: ref.Collection, Field: ref.Path}
+		if ref.Path == "" {
+			issues[i].Err = &types.UnknownCollectionError{Collection: ref.Collection}
+		} else {
+			issues[i].Err = &types.UnknownFieldError{Collection: ref.Collection, Fields: []string{ref.Path}}
+		}
+	}
+	return issues
+}
+
+// ValidateAll runs ValidateAgainst over a set of stored queries keyed by name
+// and returns the issues of each query that has any.
+func ValidateAll(asts map[string]*types.DocumentAST, d *DOCQL) map[string][]ValidationIssue {
+	out := make(map[string][]ValidationIssue)
+	for name, ast := range asts {
+		if issues := ValidateAgainst(ast, d); len(issues) > 0 {
+			out[name] = issues
+		}
+	}
+	return out
+}
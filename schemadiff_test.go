@@ -0,0 +1,190 @@
+package docql_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/zoobzio/ddml"
+	"github.com/zoobzio/docql"
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// createEvolvedInstance returns the test schema after a migration: users drops
+// status, retypes active as a string, and adds displayName; posts is removed
+// and comments is added.
+func createEvolvedInstance(t *testing.T) *docql.DOCQL {
+	t.Helper()
+
+	schema := ddml.NewSchema("test_db")
+
+	users := ddml.NewCollection("users")
+	users.AddField(ddml.NewField("_id", ddml.TypeObjectID))
+	users.AddField(ddml.NewField("username", ddml.TypeString))
+	users.AddField(ddml.NewField("email", ddml.TypeString))
+	users.AddField(ddml.NewField("active", ddml.TypeString))
+	users.AddField(ddml.NewField("displayName", ddml.TypeString))
+	schema.AddCollection(users)
+
+	comments := ddml.NewCollection("comments")
+	comments.AddField(ddml.NewField("_id", ddml.TypeObjectID))
+	comments.AddField(ddml.NewField("body", ddml.TypeString))
+	schema.AddCollection(comments)
+
+	instance, err := docql.NewFromDDML(schema)
+	if err != nil {
+		t.Fatalf("Failed to create evolved instance: %v", err)
+	}
+	return instance
+}
+
+func TestDiffSchemas(t *testing.T) {
+	before := createTestInstance(t)
+	after := createEvolvedInstance(t)
+
+	diff := docql.DiffSchemas(before, after)
+	want := docql.SchemaDiff{
+		RemovedCollections: []string{"posts"},
+		AddedCollections:   []string{"comments"},
+		RemovedFields:      []docql.FieldRef{{Collection: "users", Path: "status"}},
+		AddedFields:        []docql.FieldRef{{Collection: "users", Path: "displayName"}},
+		TypeChanges: []docql.FieldTypeChange{
+			{Collection: "users", Path: "active", OldType: ddml.TypeBool, NewType: ddml.TypeString},
+		},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("DiffSchemas() =\n%+v\nwant\n%+v", diff, want)
+	}
+	if !diff.Breaking() {
+		t.Error("expected diff to be breaking")
+	}
+
+	if same := docql.DiffSchemas(before, createTestInstance(t)); !reflect.DeepEqual(same, docql.SchemaDiff{}) || same.Breaking() {
+		t.Errorf("expected empty diff for identical schemas, got %+v", same)
+	}
+}
+
+func TestValidateAgainst(t *testing.T) {
+	before := createTestInstance(t)
+	after := createEvolvedInstance(t)
+
+	ast, err := before.Find("users").
+		Filter(before.And(
+			before.Eq(before.F("users", "status"), before.P("status")),
+			before.Eq(before.F("users", "active"), before.P("active")),
+		)).
+		SortAsc(before.F("users", "status")).
+		Select(before.F("users", "email")).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if issues := docql.ValidateAgainst(ast, before); issues != nil {
+		t.Errorf("expected no issues against the original schema, got %v", issues)
+	}
+
+	issues := docql.ValidateAgainst(ast, after)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %v", issues)
+	}
+	if issues[0].Collection != "users" || issues[0].Field != "status" {
+		t.Errorf("unexpected issue: %+v", issues[0])
+	}
+	if !errors.Is(issues[0], docql.ErrUnknownField) {
+		t.Errorf("expected ErrUnknownField, got %v", issues[0].Err)
+	}
+	if got := issues[0].Error(); got != "field 'status' not found in collection 'users'" {
+		t.Errorf("unexpected message: %s", got)
+	}
+}
+
+func TestValidateAgainst_Transaction(t *testing.T) {
+	before := createTestInstance(t)
+	after := createEvolvedInstance(t)
+
+	ast, err := docql.Transaction().
+		Add(before.Update("users").
+			Filter(before.Eq(before.F("users", "_id"), before.P("id"))).
+			Set(before.F("users", "status"), before.P("status"))).
+		Add(docql.Insert(before.C("posts")).Document(types.Document{Fields: map[types.Field]types.Param{
+			before.F("posts", "title"): before.P("title"),
+		}})).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	issues := docql.ValidateAgainst(ast, after)
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %v", issues)
+	}
+	if issues[0].Field != "status" || !errors.Is(issues[0], docql.ErrUnknownField) {
+		t.Errorf("unexpected first issue: %+v", issues[0])
+	}
+	if issues[1].Collection != "posts" || issues[1].Field != "" || !errors.Is(issues[1], docql.ErrUnknownCollection) {
+		t.Errorf("unexpected second issue: %+v", issues[1])
+	}
+}
+
+func TestValidateAgainst_Pipeline(t *testing.T) {
+	before := createTestInstance(t)
+	after := createEvolvedInstance(t)
+
+	ast, err := docql.Aggregate(before.C("users")).
+		Match(before.Eq(before.F("users", "status"), before.P("status"))).
+		Lookup("posts", before.F("users", "_id"), before.F("posts", "userId"), "posts").
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if issues := docql.ValidateAgainst(ast, before); issues != nil {
+		t.Errorf("expected no issues against the original schema, got %v", issues)
+	}
+	issues := docql.ValidateAgainst(ast, after)
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %v", issues)
+	}
+	if issues[0].Collection != "users" || issues[0].Field != "status" {
+		t.Errorf("unexpected $match issue: %+v", issues[0])
+	}
+	if issues[1].Collection != "posts" || issues[1].Field != "" || !errors.Is(issues[1], docql.ErrUnknownCollection) {
+		t.Errorf("unexpected $lookup issue: %+v", issues[1])
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	before := createTestInstance(t)
+	after := createEvolvedInstance(t)
+
+	build := func(b *docql.Builder) *types.DocumentAST {
+		t.Helper()
+		ast, err := b.Build()
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		return ast
+	}
+
+	stored := map[string]*types.DocumentAST{
+		"byEmail":  build(before.Find("users").Filter(before.Eq(before.F("users", "email"), before.P("email")))),
+		"byStatus": build(before.Find("users").Filter(before.Eq(before.F("users", "status"), before.P("status")))),
+		"byActive": build(before.Count("users").Filter(before.Eq(before.F("users", "active"), before.P("active")))),
+		"posts":    build(before.Find("posts").Filter(before.Eq(before.F("posts", "title"), before.P("title")))),
+	}
+
+	got := docql.ValidateAll(stored, after)
+	if len(got) != 2 {
+		t.Fatalf("expected issues for 2 queries, got %v", got)
+	}
+	if issues := got["byStatus"]; len(issues) != 1 || issues[0].Field != "status" {
+		t.Errorf("unexpected byStatus issues: %v", issues)
+	}
+	if issues := got["posts"]; len(issues) != 1 || !errors.Is(issues[0], docql.ErrUnknownCollection) {
+		t.Errorf("unexpected posts issues: %v", issues)
+	}
+	if len(docql.ValidateAll(stored, before)) != 0 {
+		t.Error("expected no issues against the original schema")
+	}
+}
@@ -0,0 +1,115 @@
+package docql
+
+import (
+	"fmt"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// WithSoftDelete marks documents as deleted by the presence of fieldPath.
+// Builders created through the instance (Find, FindOne, Count, Distinct,
+// Update, UpdateMany, Delete, DeleteMany) exclude deleted documents from
+// reads, UpdateMany, and DeleteMany, and can rewrite deletes with
+// AsSoftDelete. With no collections it applies to every collection whose
+// schema defines fieldPath; named collections must define it.
+func (d *DOCQL) WithSoftDelete(fieldPath string, collections ...string) *DOCQL {
+	if d.softDelete == nil {
+		d.softDelete = make(map[string]types.Field)
+	}
+	if len(collections) == 0 {
+		for name := range d.collections {
+			if f, err := d.TryF(name, fieldPath); err == nil {
+				d.softDelete[name] = f
+			}
+		}
+		return d
+	}
+	for _, name := range collections {
+		f, err := d.TryF(name, fieldPath)
+		if err != nil {
+			d.fail(err)
+			continue
+		}
+		d.softDelete[f.Collection] = f
+	}
+	return d
+}
+
+// withSoftDelete attaches the collection's soft-delete field, if any, to b.
+func (d *DOCQL) withSoftDelete(b *Builder) *Builder {
+	if f, ok := d.softDelete[b.ast.Target.Name]; ok {
+		b.softDelete = &f
+	}
+	return b
+}
+
+// IncludeDeleted opts out of the soft-delete filter, so the query also
+// matches documents marked deleted.
+func (b *Builder) IncludeDeleted() *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.includeDeleted = true
+	return b
+}
+
+// AsSoftDelete renders a Delete or DeleteMany as an Update or UpdateMany that
+// sets the soft-delete field with $currentDate. Documents already marked
+// deleted keep their original timestamp unless IncludeDeleted is set.
+func (b *Builder) AsSoftDelete() *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.ast.Operation != types.OpDelete && b.ast.Operation != types.OpDeleteMany {
+		b.err = fmt.Errorf("AsSoftDelete() can only be used with delete operations")
+		return b
+	}
+	if b.softDelete == nil {
+		b.err = fmt.Errorf("AsSoftDelete() requires a collection configured with WithSoftDelete")
+		return b
+	}
+	b.asSoftDelete = true
+	return b
+}
+
+// resolved returns the AST renderers see: base with any soft-delete rewrite
+// and filter applied. base itself is left untouched so Build can be repeated.
+func (b *Builder) resolved(base *types.DocumentAST) *types.DocumentAST {
+	if b.softDelete == nil {
+		return base
+	}
+
+	ast := *base
+	filtered := false
+	switch ast.Operation {
+	case types.OpFind, types.OpFindOne, types.OpCount, types.OpDistinct,
+		types.OpUpdateMany, types.OpDeleteMany:
+		filtered = true
+	}
+
+	if b.asSoftDelete {
+		if ast.Operation == types.OpDelete {
+			ast.Operation = types.OpUpdate
+		} else {
+			ast.Operation = types.OpUpdateMany
+		}
+		ast.UpdateOps = []types.UpdateOperation{{
+			Operator: types.CurrentDate,
+			Fields:   map[types.Field]types.Param{*b.softDelete: {}},
+		}}
+		filtered = true
+	}
+
+	if filtered && !b.includeDeleted {
+		notDeleted := types.ExistsFilter{Field: *b.softDelete, Exists: false}
+		if ast.FilterClause == nil {
+			ast.FilterClause = notDeleted
+		} else {
+			ast.FilterClause = types.FilterGroup{
+				Logic:      types.AND,
+				Conditions: []types.FilterItem{ast.FilterClause, notDeleted},
+			}
+		}
+	}
+	return &ast
+}
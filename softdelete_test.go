@@ -0,0 +1,318 @@
+package docql_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zoobzio/ddml"
+	"github.com/zoobzio/docql"
+	"github.com/zoobzio/docql/internal/types"
+	"github.com/zoobzio/docql/pkg/arangodb"
+	"github.com/zoobzio/docql/pkg/cosmosdb"
+	"github.com/zoobzio/docql/pkg/couchdb"
+	"github.com/zoobzio/docql/pkg/dynamodb"
+	"github.com/zoobzio/docql/pkg/firestore"
+	"github.com/zoobzio/docql/pkg/mongodb"
+	"github.com/zoobzio/docql/pkg/postgres"
+	"github.com/zoobzio/docql/pkg/redisearch"
+)
+
+func softDeleteInstance(t *testing.T, opts ...docql.Option) *docql.DOCQL {
+	t.Helper()
+
+	schema := ddml.NewSchema("test_db")
+
+	users := ddml.NewCollection("users")
+	users.AddField(ddml.NewField("_id", ddml.TypeObjectID))
+	users.AddField(ddml.NewField("status", ddml.TypeString))
+	users.AddField(ddml.NewField("deletedAt", ddml.TypeDate))
+	schema.AddCollection(users)
+
+	logs := ddml.NewCollection("logs")
+	logs.AddField(ddml.NewField("_id", ddml.TypeObjectID))
+	logs.AddField(ddml.NewField("level", ddml.TypeString))
+	schema.AddCollection(logs)
+
+	instance, err := docql.NewFromDDML(schema, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	return instance.WithSoftDelete("deletedAt")
+}
+
+// isNotDeleted reports whether f is the injected soft-delete condition.
+func isNotDeleted(f types.FilterItem) bool {
+	exists, ok := f.(types.ExistsFilter)
+	return ok && exists.Field.Path == "deletedAt" && !exists.Exists
+}
+
+func TestSoftDelete_PerOperation(t *testing.T) {
+	instance := softDeleteInstance(t)
+	status := instance.Eq(instance.F("users", "status"), instance.P("status"))
+	set := func(b *docql.Builder) *docql.Builder {
+		return b.Set(instance.F("users", "status"), instance.P("newStatus"))
+	}
+
+	tests := []struct {
+		name     string
+		builder  *docql.Builder
+		filtered bool
+	}{
+		{"find", instance.Find("users"), true},
+		{"find one", instance.FindOne("users"), true},
+		{"count", instance.Count("users"), true},
+		{"distinct", instance.Distinct("users", "status"), true},
+		{"update many", set(instance.UpdateMany("users").Filter(status)), true},
+		{"delete many", instance.DeleteMany("users").Filter(status), true},
+		{"update", set(instance.Update("users").Filter(status)), false},
+		{"delete", instance.Delete("users").Filter(status), false},
+		{"package constructor", docql.Find(instance.C("users")), false},
+		{"collection without field", instance.Find("logs"), false},
+		{"include deleted", instance.Find("users").IncludeDeleted(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := tt.builder.Build()
+			if err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+
+			var injected bool
+			switch f := ast.FilterClause.(type) {
+			case types.ExistsFilter:
+				injected = isNotDeleted(f)
+			case types.FilterGroup:
+				injected = f.Logic == types.AND && len(f.Conditions) == 2 && isNotDeleted(f.Conditions[1])
+			}
+			if injected != tt.filtered {
+				t.Errorf("soft-delete filter injected = %v, want %v (filter %#v)", injected, tt.filtered, ast.FilterClause)
+			}
+		})
+	}
+}
+
+func TestSoftDelete_ComposesWithFilters(t *testing.T) {
+	instance := softDeleteInstance(t)
+	status := instance.F("users", "status")
+
+	b := instance.Find("users").
+		Filter(instance.Eq(status, instance.P("a"))).
+		OrFilter(instance.Eq(status, instance.P("b")))
+
+	result, err := b.Render(mongodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := `"filter":{"$and":[{"$or":[{"status":{"$eq":":a"}},{"status":{"$eq":":b"}}]},{"deletedAt":{"$exists":false}}]}`
+	if !strings.Contains(result.JSON, want) {
+		t.Errorf("expected %s in %s", want, result.JSON)
+	}
+
+	// Building again must not stack a second filter onto the builder.
+	again, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if group := again.FilterClause.(types.FilterGroup); len(group.Conditions) != 2 || group.Logic != types.AND {
+		t.Errorf("repeated Build changed the filter: %#v", again.FilterClause)
+	}
+	if clone, _ := b.Clone().Build(); !isNotDeleted(clone.FilterClause.(types.FilterGroup).Conditions[1]) {
+		t.Error("Clone should keep the soft-delete configuration")
+	}
+}
+
+func TestSoftDelete_StillRequiresUserFilter(t *testing.T) {
+	instance := softDeleteInstance(t)
+
+	_, err := instance.DeleteMany("users").Build()
+	if !errors.Is(err, docql.ErrMissingFilter) {
+		t.Errorf("expected missing filter error, got %v", err)
+	}
+	_, err = instance.UpdateMany("users").Set(instance.F("users", "status"), instance.P("s")).Build()
+	if !errors.Is(err, docql.ErrMissingFilter) {
+		t.Errorf("expected missing filter error, got %v", err)
+	}
+}
+
+func TestSoftDelete_AsSoftDelete(t *testing.T) {
+	instance := softDeleteInstance(t)
+	status := instance.Eq(instance.F("users", "status"), instance.P("status"))
+
+	tests := []struct {
+		name    string
+		builder *docql.Builder
+		want    []string
+	}{
+		{
+			name:    "delete many",
+			builder: instance.DeleteMany("users").Filter(status).AsSoftDelete(),
+			want: []string{
+				`"operation":"UPDATE_MANY"`,
+				`"update":{"$currentDate":{"deletedAt":true}}`,
+				`"filter":{"$and":[{"status":{"$eq":":status"}},{"deletedAt":{"$exists":false}}]}`,
+			},
+		},
+		{
+			name:    "delete",
+			builder: instance.Delete("users").Filter(status).AsSoftDelete(),
+			want: []string{
+				`"operation":"UPDATE"`,
+				`"update":{"$currentDate":{"deletedAt":true}}`,
+				`{"deletedAt":{"$exists":false}}`,
+			},
+		},
+		{
+			name:    "include deleted",
+			builder: instance.Delete("users").Filter(status).AsSoftDelete().IncludeDeleted(),
+			want:    []string{`"filter":{"status":{"$eq":":status"}}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.builder.Render(mongodb.New())
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result.JSON, want) {
+					t.Errorf("expected %s in %s", want, result.JSON)
+				}
+			}
+		})
+	}
+
+	if _, err := instance.Find("users").AsSoftDelete().Build(); err == nil {
+		t.Error("expected error for AsSoftDelete on a find")
+	}
+	if _, err := instance.DeleteMany("logs").Filter(instance.Eq(instance.F("logs", "level"), instance.P("l"))).AsSoftDelete().Build(); err == nil {
+		t.Error("expected error for AsSoftDelete without a soft-delete field")
+	}
+}
+
+func TestSoftDelete_AsSoftDeleteArangoDB(t *testing.T) {
+	instance := softDeleteInstance(t)
+	b := instance.Delete("users").Filter(instance.Eq(instance.F("users", "_id"), instance.P("id"))).AsSoftDelete()
+
+	result, err := b.Render(arangodb.New())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(result.JSON, "DATE_ISO8601(DATE_NOW())} IN users") {
+		t.Errorf("expected soft-delete update in %s", result.JSON)
+	}
+	if !strings.Contains(result.JSON, "(d.deletedAt == null)") {
+		t.Errorf("expected soft-delete filter in %s", result.JSON)
+	}
+}
+
+func TestSoftDelete_ParseQuery(t *testing.T) {
+	instance := softDeleteInstance(t)
+
+	tests := []struct {
+		name string
+		def  string
+		want []string
+		skip []string
+	}{
+		{
+			name: "find",
+			def:  "{collection: users, operation: find, filter: {field: status, op: eq, param: status}}",
+			want: []string{`{"deletedAt":{"$exists":false}}`},
+		},
+		{
+			name: "include deleted",
+			def:  "{collection: users, operation: find, includeDeleted: true}",
+			skip: []string{"deletedAt"},
+		},
+		{
+			name: "soft delete",
+			def:  "{collection: users, operation: deleteMany, softDelete: true, filter: {field: status, op: eq, param: status}}",
+			want: []string{`"operation":"UPDATE_MANY"`, `"update":{"$currentDate":{"deletedAt":true}}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := docql.ParseQuery([]byte(tt.def), instance)
+			if err != nil {
+				t.Fatalf("ParseQuery failed: %v", err)
+			}
+			result, err := b.Render(mongodb.New())
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result.JSON, want) {
+					t.Errorf("expected %s in %s", want, result.JSON)
+				}
+			}
+			for _, skip := range tt.skip {
+				if strings.Contains(result.JSON, skip) {
+					t.Errorf("unexpected %s in %s", skip, result.JSON)
+				}
+			}
+		})
+	}
+
+	var parseErr *docql.ParseError
+	_, err := docql.ParseQuery([]byte("{collection: logs, operation: delete, softDelete: true}"), instance)
+	if !errors.As(err, &parseErr) || parseErr.Path != "softDelete" {
+		t.Errorf("expected parse error at softDelete, got %v", err)
+	}
+}
+
+func TestSoftDelete_PerCollection(t *testing.T) {
+	instance := softDeleteInstance(t, docql.WithStrictMode())
+	instance.WithSoftDelete("level", "logs")
+
+	ast, err := instance.Find("logs").Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if exists, ok := ast.FilterClause.(types.ExistsFilter); !ok || exists.Field.Path != "level" {
+		t.Errorf("expected level soft-delete filter on logs, got %#v", ast.FilterClause)
+	}
+
+	instance.WithSoftDelete("deletedAt", "logs")
+	var unknown *docql.UnknownFieldError
+	if !errors.As(instance.Err(), &unknown) {
+		t.Errorf("expected unknown field error for a collection without the field, got %v", instance.Err())
+	}
+}
+
+func TestSoftDelete_Renderers(t *testing.T) {
+	instance := softDeleteInstance(t)
+	b := instance.Find("users").Filter(instance.Eq(instance.F("users", "status"), instance.P("status")))
+
+	renderers := map[string]docql.Renderer{
+		"mongodb":    mongodb.New(),
+		"dynamodb":   dynamodb.New(),
+		"firestore":  firestore.New(),
+		"couchdb":    couchdb.New(),
+		"cosmosdb":   cosmosdb.New(),
+		"arangodb":   arangodb.New(),
+		"redisearch": redisearch.New(),
+		"postgres":   postgres.New(),
+	}
+
+	for name, r := range renderers {
+		t.Run(name, func(t *testing.T) {
+			result, err := b.Render(r)
+			if !r.SupportsFilter(types.Exists) {
+				if !errors.Is(err, docql.ErrUnsupportedFilter) {
+					t.Errorf("expected unsupported filter error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if !strings.Contains(result.JSON, "deletedAt") {
+				t.Errorf("expected soft-delete condition in %s", result.JSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package benchmarks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zoobzio/ddml"
+	"github.com/zoobzio/docql"
+)
+
+// createLargeSchemaInstance builds a schema of 300 collections with about 140
+// indexed paths each: 10 scalar fields and 11 objects of 11 fields.
+func createLargeSchemaInstance(b *testing.B, opts ...docql.Option) *docql.DOCQL {
+	b.Helper()
+
+	schema := ddml.NewSchema("large")
+	for c := range 300 {
+		coll := ddml.NewCollection(fmt.Sprintf("collection_%03d", c))
+		for f := range 10 {
+			coll.AddField(ddml.NewField(fmt.Sprintf("field_%02d", f), ddml.TypeString))
+		}
+		for o := range 11 {
+			obj := ddml.NewField(fmt.Sprintf("object_%02d", o), ddml.TypeObject)
+			for f := range 11 {
+				obj.Fields = append(obj.Fields, ddml.NewField(fmt.Sprintf("nested_%02d", f), ddml.TypeInt))
+			}
+			coll.AddField(obj)
+		}
+		schema.AddCollection(coll)
+	}
+
+	instance, err := docql.NewFromDDML(schema, opts...)
+	if err != nil {
+		b.Fatalf("Failed to create instance: %v", err)
+	}
+	return instance
+}
+
+// BenchmarkLargeSchemaLookup measures C, F, and TryF against a large schema.
+// "exact" names hit the schema index and skip validation; "folded" names
+// differ in case, so a case-insensitive instance validates them first, as
+// every lookup did before the exact-hit fast path.
+func BenchmarkLargeSchemaLookup(b *testing.B) {
+	instance := createLargeSchemaInstance(b, docql.WithCaseInsensitiveLookup())
+
+	cases := []struct {
+		name       string
+		collection string
+		path       string
+	}{
+		{"exact", "collection_150", "object_07.nested_09"},
+		{"folded", "Collection_150", "Object_07.Nested_09"},
+	}
+
+	for _, tc := range cases {
+		b.Run("C/"+tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = instance.C(tc.collection)
+			}
+		})
+		b.Run("F/"+tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = instance.F(tc.collection, tc.path)
+			}
+		})
+		b.Run("TryF/"+tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := instance.TryF(tc.collection, tc.path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	b.Run("TryF/unknown", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := instance.TryF("collection_150", "object_07.missing"); err == nil {
+				b.Fatal("expected unknown field error")
+			}
+		}
+	})
+}
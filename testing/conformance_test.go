@@ -0,0 +1,182 @@
+package testing
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zoobzio/docql"
+	"github.com/zoobzio/docql/pkg/arangodb"
+	"github.com/zoobzio/docql/pkg/cosmosdb"
+	"github.com/zoobzio/docql/pkg/couchdb"
+	"github.com/zoobzio/docql/pkg/debug"
+	"github.com/zoobzio/docql/pkg/dynamodb"
+	"github.com/zoobzio/docql/pkg/firestore"
+	"github.com/zoobzio/docql/pkg/kv"
+	"github.com/zoobzio/docql/pkg/mongodb"
+	"github.com/zoobzio/docql/pkg/postgres"
+	"github.com/zoobzio/docql/pkg/redisearch"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// conformanceRenderers are the renderers the corpus runs through, each with
+// its output schema. RediSearch keys documents by _id, as the corpus writes.
+var conformanceRenderers = []struct {
+	name     string
+	renderer docql.Renderer
+	schema   string
+}{
+	{"mongodb", mongodb.New(), mongodb.OutputSchema()},
+	{"dynamodb", dynamodb.New(), dynamodb.OutputSchema()},
+	{"couchdb", couchdb.New(), couchdb.OutputSchema()},
+	{"firestore", firestore.New(), firestore.OutputSchema()},
+	{"cosmosdb", cosmosdb.New(), cosmosdb.OutputSchema()},
+	{"arangodb", arangodb.New(), arangodb.OutputSchema()},
+	{"redisearch", redisearch.New().WithKeyField("_id"), redisearch.OutputSchema()},
+	{"postgres", postgres.New(), postgres.OutputSchema()},
+	{"kv", kv.New(), kv.OutputSchema()},
+	{"debug", debug.New(), debug.OutputSchema()},
+}
+
+// TestConformance renders the corpus through every renderer and checks each
+// query against the renderer's OutputSchema and against
+// testdata/conformance/<renderer>/<case>.json. The errors of cases a
+// renderer rejects are pinned in errors.txt alongside, so a renderer that
+// starts or stops supporting a case shows up too. Regenerate with
+// go test ./testing -run TestConformance -update.
+func TestConformance(t *testing.T) {
+	for _, r := range conformanceRenderers {
+		t.Run(r.name, func(t *testing.T) {
+			dir := filepath.Join("testdata", "conformance", r.name)
+			if *update {
+				if err := os.RemoveAll(dir); err != nil {
+					t.Fatalf("clearing golden files: %v", err)
+				}
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatalf("creating golden directory: %v", err)
+				}
+			}
+
+			var errs bytes.Buffer
+			for _, c := range Corpus() {
+				result, err := r.renderer.Render(c.AST)
+				if err != nil {
+					fmt.Fprintf(&errs, "%s: %v\n", c.Name, err)
+					continue
+				}
+				t.Run(c.Name, func(t *testing.T) {
+					AssertSchema(t, r.schema, result.JSON)
+
+					var got bytes.Buffer
+					if err := json.Indent(&got, []byte(result.JSON), "", "  "); err != nil {
+						t.Fatalf("invalid JSON: %v", err)
+					}
+					got.WriteByte('\n')
+					compareGolden(t, filepath.Join(dir, c.Name+".json"), got.Bytes())
+				})
+			}
+			compareGolden(t, filepath.Join(dir, "errors.txt"), errs.Bytes())
+		})
+	}
+}
+
+// TestConformance_SchemasRejectDrift checks that each schema is strict enough
+// to catch a renamed key: every rendered query fails once a top-level key is
+// renamed.
+func TestConformance_SchemasRejectDrift(t *testing.T) {
+	for _, r := range conformanceRenderers {
+		t.Run(r.name, func(t *testing.T) {
+			for _, c := range Corpus() {
+				result, err := r.renderer.Render(c.AST)
+				if err != nil {
+					continue
+				}
+				var query map[string]json.RawMessage
+				if err := json.Unmarshal([]byte(result.JSON), &query); err != nil {
+					t.Fatalf("%s: invalid JSON: %v", c.Name, err)
+				}
+				for key, value := range query {
+					renamed := maps.Clone(query)
+					delete(renamed, key)
+					renamed[key+"_renamed"] = value
+					doc, err := json.Marshal(renamed)
+					if err != nil {
+						t.Fatalf("%s: %v", c.Name, err)
+					}
+					if ValidateSchema(r.schema, string(doc)) == nil {
+						t.Errorf("%s: schema accepts %q renamed to %q", c.Name, key, key+"_renamed")
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestConformance_Redacted checks that Redacted replaces every placeholder
+// each renderer emits and lists every required param.
+func TestConformance_Redacted(t *testing.T) {
+	for _, r := range conformanceRenderers {
+		t.Run(r.name, func(t *testing.T) {
+			for _, c := range Corpus() {
+				result, err := r.renderer.Render(c.AST)
+				if err != nil {
+					continue
+				}
+				redacted, tokens := result.Redacted()
+				if distinct := slices.Compact(slices.Sorted(slices.Values(result.RequiredParams))); len(tokens) != len(distinct) {
+					t.Errorf("%s: expected a token for each of %v, got %v", c.Name, result.RequiredParams, tokens)
+				}
+				rest := redacted
+				for _, name := range result.RequiredParams {
+					token := "<redacted:" + name + ">"
+					if tokens[token] != name {
+						t.Errorf("%s: expected %s to stand for %s, got %q", c.Name, token, name, tokens[token])
+					}
+					if !strings.Contains(redacted, token) {
+						t.Errorf("%s: expected %s in %s", c.Name, token, redacted)
+					}
+					rest = strings.ReplaceAll(rest, token, "")
+				}
+				for _, name := range result.RequiredParams {
+					if regexp.MustCompile(`:` + name + `\b`).MatchString(rest) {
+						t.Errorf("%s: placeholder :%s left in %s", c.Name, name, redacted)
+					}
+				}
+			}
+		})
+	}
+}
+
+// compareGolden compares got with the golden file at path, or rewrites the
+// file under -update. An empty golden file is not written.
+func compareGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if len(got) == 0 {
+			return
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) && len(got) == 0 {
+		return
+	}
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match the rendered output:\n got: %s\nwant: %s", path, got, want)
+	}
+}
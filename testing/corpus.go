@@ -0,0 +1,260 @@
+package testing
+
+import (
+	"github.com/zoobzio/docql"
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// CorpusCase is one canonical query for conformance tests.
+type CorpusCase struct {
+	// Name identifies the case and names its golden files.
+	Name string
+
+	// AST is the query. Each call to Corpus builds fresh ASTs, so a
+	// renderer that mutates one cannot affect another test.
+	AST *docql.DocumentAST
+}
+
+// Corpus returns a canonical set of queries covering every operation with
+// representative filters, projections, sorts, pagination, updates, and
+// pipeline stages. New renderers can render it to check their output
+// against their OutputSchema and golden files. Cases are ordered and named
+// stably; add new cases rather than changing existing ones, so that golden
+// files only change when output does.
+func Corpus() []CorpusCase {
+	limit, skip := 20, 40
+	majority := "majority"
+
+	return []CorpusCase{
+		{
+			Name: "find_eq",
+			AST: &types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users", Database: "app"},
+				FilterClause: eq("status", "status"),
+			},
+		},
+		{
+			Name: "find_logical",
+			AST: &types.DocumentAST{
+				Operation: types.OpFind,
+				Target:    types.Collection{Name: "users"},
+				FilterClause: types.FilterGroup{Logic: types.AND, Conditions: []types.FilterItem{
+					eq("status", "status"),
+					types.FilterGroup{Logic: types.OR, Conditions: []types.FilterItem{
+						types.FilterCondition{Field: field("age"), Operator: types.GTE, Value: param("minAge")},
+						types.FilterCondition{Field: field("age"), Operator: types.LT, Value: param("maxAge")},
+					}},
+					types.FilterCondition{Field: field("role"), Operator: types.NE, Value: param("role")},
+				}},
+			},
+		},
+		{
+			Name: "find_values",
+			AST: &types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: types.ValuesFilter{Field: field("status"), Operator: types.IN, Values: []types.Param{param("s1"), param("s2")}},
+			},
+		},
+		{
+			Name: "find_range",
+			AST: &types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "orders"},
+				FilterClause: types.RangeFilter{Field: field("total"), Min: &types.Param{Name: "min"}, Max: &types.Param{Name: "max"}, MaxExclusive: true},
+			},
+		},
+		{
+			Name: "find_regex",
+			AST: &types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: types.RegexFilter{Field: field("email"), Pattern: param("pattern")},
+			},
+		},
+		{
+			Name: "find_exists",
+			AST: &types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: types.ExistsFilter{Field: field("deletedAt"), Exists: false},
+			},
+		},
+		{
+			Name: "find_paged",
+			AST: &types.DocumentAST{
+				Operation:    types.OpFind,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: eq("status", "status"),
+				Projection: &types.Projection{Fields: []types.ProjectionField{
+					{Field: field("username"), Include: true},
+					{Field: field("email"), Include: true},
+				}},
+				SortClauses: []types.SortClause{
+					{Field: field("age"), Order: types.Descending},
+					{Field: field("username"), Order: types.Ascending},
+				},
+				Skip:  &types.PaginationValue{Static: &skip},
+				Limit: &types.PaginationValue{Static: &limit},
+			},
+		},
+		{
+			Name: "find_one",
+			AST: &types.DocumentAST{
+				Operation:    types.OpFindOne,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: eq("_id", "id"),
+			},
+		},
+		{
+			Name: "count",
+			AST: &types.DocumentAST{
+				Operation:    types.OpCount,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: eq("status", "status"),
+			},
+		},
+		{
+			Name: "distinct",
+			AST: &types.DocumentAST{
+				Operation:     types.OpDistinct,
+				Target:        types.Collection{Name: "users"},
+				DistinctField: &types.Field{Path: "status"},
+				FilterClause:  types.FilterCondition{Field: field("age"), Operator: types.GT, Value: param("minAge")},
+			},
+		},
+		{
+			Name: "distinct_sorted",
+			AST: &types.DocumentAST{
+				Operation:     types.OpDistinct,
+				Target:        types.Collection{Name: "users"},
+				DistinctField: &types.Field{Path: "address.city"},
+				FilterClause:  eq("status", "status"),
+				SortClauses:   []types.SortClause{{Field: field("address.city"), Order: types.Ascending}},
+				Limit:         &types.PaginationValue{Param: &types.Param{Name: "limit"}},
+			},
+		},
+		{
+			Name: "insert",
+			AST: &types.DocumentAST{
+				Operation: types.OpInsert,
+				Target:    types.Collection{Name: "users"},
+				Documents: []types.Document{document("_id", "id", "username", "name", "status", "status")},
+			},
+		},
+		{
+			Name: "insert_many",
+			AST: &types.DocumentAST{
+				Operation: types.OpInsertMany,
+				Target:    types.Collection{Name: "users"},
+				Documents: []types.Document{
+					document("_id", "id1", "username", "name1"),
+					document("_id", "id2", "username", "name2"),
+				},
+			},
+		},
+		{
+			Name: "update",
+			AST: &types.DocumentAST{
+				Operation:    types.OpUpdate,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: eq("_id", "id"),
+				UpdateOps: []types.UpdateOperation{
+					{Operator: types.Set, Fields: map[types.Field]types.Param{field("status"): param("status"), field("profile.city"): param("city")}},
+				},
+			},
+		},
+		{
+			Name: "update_many",
+			AST: &types.DocumentAST{
+				Operation:    types.OpUpdateMany,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: eq("status", "status"),
+				UpdateOps: []types.UpdateOperation{
+					{Operator: types.Set, Fields: map[types.Field]types.Param{field("status"): param("newStatus")}},
+					{Operator: types.Inc, Fields: map[types.Field]types.Param{field("version"): param("one")}},
+				},
+			},
+		},
+		{
+			Name: "delete",
+			AST: &types.DocumentAST{
+				Operation:    types.OpDelete,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: eq("_id", "id"),
+			},
+		},
+		{
+			Name: "delete_many",
+			AST: &types.DocumentAST{
+				Operation:    types.OpDeleteMany,
+				Target:       types.Collection{Name: "users"},
+				FilterClause: eq("status", "status"),
+			},
+		},
+		{
+			Name: "aggregate",
+			AST: &types.DocumentAST{
+				Operation: types.OpAggregate,
+				Target:    types.Collection{Name: "orders"},
+				Pipeline: []types.PipelineStage{
+					types.MatchStage{Filter: eq("status", "status")},
+					types.GroupStage{
+						ID: types.FieldExpression{Field: field("userId")},
+						Accumulators: map[string]types.Accumulator{
+							"total": {Operator: types.AccSum, Expr: types.FieldExpression{Field: field("total")}},
+						},
+					},
+					types.SortStage{Sorts: []types.SortClause{{Field: field("total"), Order: types.Descending}}},
+					types.LimitStage{Limit: types.PaginationValue{Static: &limit}},
+				},
+			},
+		},
+		{
+			Name: "watch",
+			AST: &types.DocumentAST{
+				Operation:    types.OpWatch,
+				Target:       types.Collection{Name: "orders"},
+				FilterClause: eq("status", "status"),
+			},
+		},
+		{
+			Name: "transaction",
+			AST: &types.DocumentAST{
+				Operation:    types.OpTransaction,
+				WriteConcern: &majority,
+				Operations: []*types.DocumentAST{
+					{
+						Operation: types.OpInsert,
+						Target:    types.Collection{Name: "orders"},
+						Documents: []types.Document{document("_id", "orderId", "userId", "userId")},
+					},
+					{
+						Operation:    types.OpUpdate,
+						Target:       types.Collection{Name: "users"},
+						FilterClause: eq("_id", "userId"),
+						UpdateOps:    []types.UpdateOperation{{Operator: types.Set, Fields: map[types.Field]types.Param{field("lastOrder"): param("orderId")}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func field(path string) types.Field { return types.Field{Path: path} }
+
+func param(name string) types.Param { return types.Param{Name: name} }
+
+func eq(path, name string) types.FilterCondition {
+	return types.FilterCondition{Field: field(path), Operator: types.EQ, Value: param(name)}
+}
+
+// document builds a document from alternating field paths and param names.
+func document(pairs ...string) types.Document {
+	fields := make(map[types.Field]types.Param, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		fields[field(pairs[i])] = param(pairs[i+1])
+	}
+	return types.Document{Fields: fields}
+}
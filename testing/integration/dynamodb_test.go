@@ -79,11 +79,11 @@ func TestDynamoDB_Insert(t *testing.T) {
 	instance := createTestInstance(t)
 
 	result, err := docql.Insert(instance.C("users")).
-		Doc(
-			docql.Set(instance.F("users", "_id"), instance.P("id")),
-			docql.Set(instance.F("users", "username"), instance.P("username")),
-			docql.Set(instance.F("users", "email"), instance.P("email")),
-		).
+		Document(docql.Doc().
+			Set(instance.F("users", "_id"), instance.P("id")).
+			Set(instance.F("users", "username"), instance.P("username")).
+			Set(instance.F("users", "email"), instance.P("email")).
+			Build()).
 		Render(dynamodb.New())
 
 	if err != nil {
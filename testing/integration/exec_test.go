@@ -0,0 +1,164 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zoobzio/docql"
+	"github.com/zoobzio/docql/pkg/couchdb"
+	couchexec "github.com/zoobzio/docql/pkg/couchdb/exec"
+	"github.com/zoobzio/docql/pkg/mongodb"
+	mongoexec "github.com/zoobzio/docql/pkg/mongodb/exec"
+)
+
+func TestMongoDB_Exec(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	ctx := context.Background()
+	mc := getMongoContainer(t)
+	db := setupCollections(ctx, t, mc)
+	seedData(ctx, t, db)
+
+	instance := createTestInstance(t)
+	renderer := mongodb.New()
+	executor := mongoexec.New(db)
+	active := instance.Eq(instance.F("users", "active"), instance.P("active"))
+
+	run := func(b *docql.Builder, params map[string]any) *mongoexec.Result {
+		t.Helper()
+		result, err := b.Render(renderer)
+		if err != nil {
+			t.Fatalf("Failed to render query: %v", err)
+		}
+		res, err := executor.Run(ctx, result, params)
+		if err != nil {
+			t.Fatalf("Failed to execute query: %v", err)
+		}
+		return res
+	}
+
+	found := run(docql.Find(instance.C("users")).
+		Filter(active).
+		SortDesc(instance.F("users", "age")).
+		LimitParam(instance.P("n")), map[string]any{"active": true, "n": 2})
+	if len(found.Documents) != 2 || found.Documents[0]["username"] != "alice" || found.Documents[1]["username"] != "diana" {
+		t.Errorf("Expected alice then diana, got %v", found.Documents)
+	}
+
+	one := run(docql.FindOne(instance.C("users")).
+		Filter(instance.Eq(instance.F("users", "username"), instance.P("name"))), map[string]any{"name": "bob"})
+	if len(one.Documents) != 1 || one.Documents[0]["_id"] != "2" {
+		t.Errorf("Expected bob, got %v", one.Documents)
+	}
+
+	count := run(docql.Count(instance.C("users")).Filter(active), map[string]any{"active": true})
+	if count.Count != 3 {
+		t.Errorf("Expected 3 active users, got %d", count.Count)
+	}
+
+	distinct := run(docql.Distinct(instance.C("orders"), instance.F("orders", "status")).
+		Filter(instance.Gt(instance.F("orders", "total"), instance.P("min"))), map[string]any{"min": 40})
+	if len(distinct.Values) != 2 {
+		t.Errorf("Expected 2 distinct statuses, got %v", distinct.Values)
+	}
+
+	inserted := run(docql.Insert(instance.C("users")).Document(docql.Doc().
+		Set(instance.F("users", "_id"), instance.P("id")).
+		Set(instance.F("users", "username"), instance.P("name")).
+		Set(instance.F("users", "active"), instance.P("active")).
+		Build()), map[string]any{"id": "5", "name": "eve", "active": true})
+	if len(inserted.InsertedIDs) != 1 || inserted.InsertedIDs[0] != "5" {
+		t.Errorf("Expected inserted id 5, got %v", inserted.InsertedIDs)
+	}
+
+	updated := run(docql.UpdateMany(instance.C("users")).
+		Filter(active).
+		Set(instance.F("users", "active"), instance.P("inactive")), map[string]any{"active": true, "inactive": false})
+	if updated.MatchedCount != 4 || updated.ModifiedCount != 4 {
+		t.Errorf("Expected 4 users deactivated, got %+v", updated)
+	}
+
+	deleted := run(docql.Delete(instance.C("users")).
+		Filter(instance.Eq(instance.F("users", "_id"), instance.P("id"))), map[string]any{"id": "5"})
+	if deleted.DeletedCount != 1 {
+		t.Errorf("Expected 1 deleted, got %d", deleted.DeletedCount)
+	}
+
+	grouped := run(docql.Aggregate(instance.C("orders")).
+		Match(instance.Eq(instance.F("orders", "status"), instance.P("status"))).
+		Group(docql.FieldExpr(instance.F("orders", "userId")), map[string]docql.Accumulator{"n": docql.CountAcc()}), map[string]any{"status": "completed"})
+	if len(grouped.Documents) != 2 {
+		t.Errorf("Expected 2 groups, got %v", grouped.Documents)
+	}
+}
+
+func TestCouchDB_Exec(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	cc := getCouchDBContainer(t)
+	client := newCouchClient(cc)
+	_ = client.deleteDB("users")
+	if err := client.createDB("users"); err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = client.deleteDB("users") }()
+
+	ctx := context.Background()
+	instance := createCouchDBTestInstance(t)
+	renderer := couchdb.New()
+	executor := couchexec.New(cc.url).WithBasicAuth(cc.username, cc.password)
+
+	run := func(b *docql.Builder, params map[string]any) *couchexec.Result {
+		t.Helper()
+		result, err := b.Render(renderer)
+		if err != nil {
+			t.Fatalf("Failed to render query: %v", err)
+		}
+		res, err := executor.Run(ctx, result, params)
+		if err != nil {
+			t.Fatalf("Failed to execute query: %v", err)
+		}
+		return res
+	}
+
+	doc := func(id, name string, active bool) map[string]any {
+		return map[string]any{"id": id, "name": name, "active": active}
+	}
+	insert := docql.Insert(instance.C("users")).Document(docql.Doc().
+		Set(instance.F("users", "_id"), instance.P("id")).
+		Set(instance.F("users", "username"), instance.P("name")).
+		Set(instance.F("users", "active"), instance.P("active")).
+		Build())
+	for _, params := range []map[string]any{doc("user:1", "alice", true), doc("user:2", "bob", true), doc("user:3", "charlie", false)} {
+		if res := run(insert, params); len(res.InsertedIDs) != 1 {
+			t.Fatalf("Expected one inserted id, got %v", res.InsertedIDs)
+		}
+	}
+
+	active := instance.Eq(instance.F("users", "active"), instance.P("active"))
+	found := run(docql.Find(instance.C("users")).Filter(active), map[string]any{"active": true})
+	if len(found.Documents) != 2 {
+		t.Errorf("Expected 2 active users, got %v", found.Documents)
+	}
+
+	byID := instance.Eq(instance.F("users", "_id"), instance.P("id"))
+	updated := run(docql.Update(instance.C("users")).Filter(byID).
+		Set(instance.F("users", "active"), instance.P("active")), map[string]any{"id": "user:3", "active": true})
+	if updated.ModifiedCount != 1 {
+		t.Errorf("Expected 1 modified, got %d", updated.ModifiedCount)
+	}
+
+	deleted := run(docql.Delete(instance.C("users")).Filter(byID), map[string]any{"id": "user:1"})
+	if deleted.DeletedCount != 1 {
+		t.Errorf("Expected 1 deleted, got %d", deleted.DeletedCount)
+	}
+
+	found = run(docql.Find(instance.C("users")).Filter(active), map[string]any{"active": true})
+	if len(found.Documents) != 2 {
+		t.Errorf("Expected bob and charlie active, got %v", found.Documents)
+	}
+}
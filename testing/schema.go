@@ -0,0 +1,327 @@
+package testing
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// ValidateSchema checks a JSON document against a JSON Schema, such as a
+// renderer's OutputSchema. It implements the subset of draft 2020-12 the
+// renderer schemas use: type, const, enum, properties, required,
+// additionalProperties, propertyNames, minProperties, maxProperties, items,
+// minItems, maxItems, allOf, anyOf, oneOf, and $ref to the root or to $defs.
+// A schema using any other keyword is reported as an error rather than
+// silently accepted.
+func ValidateSchema(schema, document string) error {
+	root, err := decodeJSON(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	value, err := decodeJSON(document)
+	if err != nil {
+		return fmt.Errorf("invalid document: %w", err)
+	}
+	v := &schemaValidator{root: root}
+	return v.validate(root, value, "$")
+}
+
+// AssertSchema fails the test if document does not match schema.
+func AssertSchema(t *testing.T, schema, document string) {
+	t.Helper()
+	if err := ValidateSchema(schema, document); err != nil {
+		t.Errorf("document does not match schema: %v\ndocument: %s", err, document)
+	}
+}
+
+func decodeJSON(s string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("trailing data after JSON value")
+	}
+	return v, nil
+}
+
+// annotations are keywords that describe a schema without constraining it.
+var annotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$defs":       true,
+	"title":       true,
+	"description": true,
+}
+
+// combinators are the keywords that apply subschemas to the whole value.
+var combinators = map[string]int{"allOf": 1, "anyOf": 1, "oneOf": 1}
+
+type schemaValidator struct {
+	root interface{}
+}
+
+func (v *schemaValidator) validate(schema, value interface{}, path string) error {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			return fmt.Errorf("%s: not allowed", path)
+		}
+		return nil
+	case map[string]interface{}:
+		// Combinators go last, so a value that fails a plain keyword
+		// reports that rather than every branch it fails.
+		keywords := sortedKeys(s)
+		slices.SortStableFunc(keywords, func(a, b string) int {
+			return cmp.Compare(combinators[a], combinators[b])
+		})
+		for _, keyword := range keywords {
+			if annotations[keyword] {
+				continue
+			}
+			if err := v.keyword(keyword, s[keyword], s, value, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s: schema must be an object or boolean, got %T", path, schema)
+	}
+}
+
+// keyword checks value against one keyword of schema s.
+func (v *schemaValidator) keyword(keyword string, arg interface{}, s map[string]interface{}, value interface{}, path string) error {
+	switch keyword {
+	case "$ref":
+		ref, _ := arg.(string)
+		target, err := v.resolve(ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return v.validate(target, value, path)
+
+	case "type":
+		var names []string
+		switch t := arg.(type) {
+		case string:
+			names = []string{t}
+		case []interface{}:
+			for _, n := range t {
+				name, _ := n.(string)
+				names = append(names, name)
+			}
+		}
+		if got := jsonType(value); !slices.Contains(names, got) && !(got == "integer" && slices.Contains(names, "number")) {
+			return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(names, " or "), got)
+		}
+
+	case "const":
+		if !jsonEqual(arg, value) {
+			return fmt.Errorf("%s: expected %s, got %s", path, encode(arg), encode(value))
+		}
+
+	case "enum":
+		options, _ := arg.([]interface{})
+		if !slices.ContainsFunc(options, func(o interface{}) bool { return jsonEqual(o, value) }) {
+			return fmt.Errorf("%s: %s is not one of %s", path, encode(value), encode(arg))
+		}
+
+	case "properties":
+		obj, ok := value.(map[string]interface{})
+		props, _ := arg.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, name := range sortedKeys(obj) {
+			if sub, ok := props[name]; ok {
+				if err := v.validate(sub, obj[name], path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+
+	case "required":
+		obj, ok := value.(map[string]interface{})
+		names, _ := arg.([]interface{})
+		if !ok {
+			return nil
+		}
+		for _, n := range names {
+			name, _ := n.(string)
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required key %q", path, name)
+			}
+		}
+
+	case "additionalProperties":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		props, _ := s["properties"].(map[string]interface{})
+		for _, name := range sortedKeys(obj) {
+			if _, ok := props[name]; ok {
+				continue
+			}
+			if arg == false {
+				return fmt.Errorf("%s: unexpected key %q", path, name)
+			}
+			if err := v.validate(arg, obj[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+
+	case "propertyNames":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, name := range sortedKeys(obj) {
+			if err := v.validate(arg, name, path); err != nil {
+				return fmt.Errorf("%s: key %q not allowed: %w", path, name, err)
+			}
+		}
+
+	case "minProperties", "maxProperties":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		return checkCount(keyword, arg, len(obj), path)
+
+	case "items":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range arr {
+			if err := v.validate(arg, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case "minItems", "maxItems":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		return checkCount(keyword, arg, len(arr), path)
+
+	case "allOf", "anyOf", "oneOf":
+		branches, _ := arg.([]interface{})
+		var matched int
+		var failures []string
+		for _, branch := range branches {
+			if err := v.validate(branch, value, path); err != nil {
+				failures = append(failures, err.Error())
+				continue
+			}
+			matched++
+		}
+		switch {
+		case keyword == "allOf" && len(failures) > 0:
+			return fmt.Errorf("%s: %s", path, failures[0])
+		case keyword == "oneOf" && matched > 1:
+			return fmt.Errorf("%s: matches %d oneOf schemas, expected exactly one", path, matched)
+		case keyword != "allOf" && matched == 0:
+			return fmt.Errorf("%s: matches none of the %s schemas: %s", path, keyword, strings.Join(failures, "; "))
+		}
+
+	default:
+		return fmt.Errorf("%s: unsupported schema keyword %q", path, keyword)
+	}
+	return nil
+}
+
+// resolve finds the schema a $ref names: "#" for the root or a JSON pointer
+// into it, such as "#/$defs/filter".
+func (v *schemaValidator) resolve(ref string) (interface{}, error) {
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q: only references within the schema are supported", ref)
+	}
+	target := v.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		obj, ok := target.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if target, ok = obj[token]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return target, nil
+}
+
+// checkCount enforces a min* or max* keyword against a count.
+func checkCount(keyword string, arg interface{}, count int, path string) error {
+	n, ok := arg.(json.Number)
+	bound, err := n.Int64()
+	if !ok || err != nil {
+		return fmt.Errorf("%s: %s must be an integer", path, keyword)
+	}
+	if strings.HasPrefix(keyword, "min") && int64(count) < bound {
+		return fmt.Errorf("%s: expected at least %d %s, got %d", path, bound, strings.ToLower(keyword[3:]), count)
+	}
+	if strings.HasPrefix(keyword, "max") && int64(count) > bound {
+		return fmt.Errorf("%s: expected at most %d %s, got %d", path, bound, strings.ToLower(keyword[3:]), count)
+	}
+	return nil
+}
+
+// jsonType names the JSON Schema type of a decoded value. Numbers without a
+// fraction or exponent are integers.
+func jsonType(value interface{}) string {
+	switch x := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if strings.ContainsAny(x.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// jsonEqual compares decoded values, treating numbers as equal when their
+// values are, whatever their spelling.
+func jsonEqual(a, b interface{}) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok && bok {
+		af, aerr := an.Float64()
+		bf, berr := bn.Float64()
+		return aerr == nil && berr == nil && af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func encode(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return strings.TrimSpace(buf.String())
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	return slices.Sorted(maps.Keys(m))
+}
@@ -0,0 +1,72 @@
+package testing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zoobzio/docql/pkg/mongodb"
+)
+
+func TestValidateSchema(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"properties": {
+			"kind": {"enum": ["a", "b"]},
+			"n": {"type": "integer"},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+			"child": {"$ref": "#"}
+		},
+		"required": ["kind"],
+		"oneOf": [
+			{"properties": {"kind": {"const": "a"}}, "required": ["n"]},
+			{"properties": {"kind": {"const": "b"}}, "propertyNames": {"enum": ["kind", "tags"]}}
+		],
+		"additionalProperties": false
+	}`
+
+	tests := []struct {
+		name     string
+		document string
+		wantErr  string
+	}{
+		{"valid a", `{"kind": "a", "n": 3}`, ""},
+		{"valid b", `{"kind": "b", "tags": ["x", "y"]}`, ""},
+		{"nested", `{"kind": "a", "n": 1, "child": {"kind": "b"}}`, ""},
+		{"missing required", `{"n": 3}`, `missing required key "kind"`},
+		{"wrong type", `{"kind": "a", "n": 1.5}`, "$.n: expected integer, got number"},
+		{"enum", `{"kind": "c"}`, `"c" is not one of`},
+		{"unexpected key", `{"kind": "a", "n": 1, "extra": true}`, `unexpected key "extra"`},
+		{"no branch", `{"kind": "b", "n": 1}`, "matches none of the oneOf schemas"},
+		{"max items", `{"kind": "b", "tags": ["x", "y", "z"]}`, "expected at most 2 items"},
+		{"items", `{"kind": "b", "tags": [1]}`, "$.tags[0]: expected string"},
+		{"nested invalid", `{"kind": "a", "n": 1, "child": {"kind": "a"}}`, `$.child: matches none`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchema(schema, tt.document)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateSchema_UnsupportedKeyword(t *testing.T) {
+	err := ValidateSchema(`{"type": "string", "pattern": "^a"}`, `"abc"`)
+	if err == nil || !strings.Contains(err.Error(), `unsupported schema keyword "pattern"`) {
+		t.Errorf("expected unsupported keyword error, got %v", err)
+	}
+}
+
+func TestValidateSchema_OutputSchemaRejectsMissingKey(t *testing.T) {
+	err := ValidateSchema(mongodb.OutputSchema(), `{"collection": "users", "operation": "FIND"}`)
+	if err == nil || !strings.Contains(err.Error(), `missing required key "filter"`) {
+		t.Errorf("expected a find without a filter to be rejected, got %v", err)
+	}
+}
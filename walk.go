@@ -0,0 +1,184 @@
+package docql
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/zoobzio/docql/internal/types"
+)
+
+// Walk calls visitor for each node of ast in depth-first order, each node
+// before its children, and stops as soon as visitor returns false.
+//
+// Nodes are the *DocumentAST itself and each write of a transaction, every
+// FilterItem including those nested in groups, $elemMatch, and stages, the
+// Projection and each ProjectionField, SortClause, Document, and
+// UpdateOperation, and every PipelineStage, Expression, and Accumulator,
+// including those in lookup and facet sub-pipelines. Map-valued children,
+// such as computed fields or accumulators, are visited in key order. Nodes
+// other than the ASTs are passed by value.
+func Walk(ast *types.DocumentAST, visitor func(node interface{}) bool) {
+	if ast == nil {
+		return
+	}
+	w := &walker{visit: visitor}
+	w.ast(ast)
+}
+
+// walker carries the visitor and whether it has stopped the walk. With prune
+// set, a visitor returning false skips the node's children instead of
+// stopping the walk.
+type walker struct {
+	visit   func(node interface{}) bool
+	prune   bool
+	stopped bool
+}
+
+// node visits n and reports whether the walk should continue into its
+// children.
+func (w *walker) node(n interface{}) bool {
+	if w.stopped {
+		return false
+	}
+	if !w.visit(n) {
+		w.stopped = !w.prune
+		return false
+	}
+	return true
+}
+
+func (w *walker) ast(ast *types.DocumentAST) {
+	if !w.node(ast) {
+		return
+	}
+	if ast.FilterClause != nil {
+		w.filter(ast.FilterClause)
+	}
+	if ast.Projection != nil {
+		w.projection(*ast.Projection)
+	}
+	for _, s := range ast.SortClauses {
+		w.node(s)
+	}
+	for _, doc := range ast.Documents {
+		w.node(doc)
+	}
+	for _, op := range ast.UpdateOps {
+		w.node(op)
+	}
+	w.pipeline(ast.Pipeline)
+	for _, op := range ast.Operations {
+		w.ast(op)
+	}
+}
+
+func (w *walker) filter(f types.FilterItem) {
+	if f == nil || !w.node(f) {
+		return
+	}
+	switch filter := f.(type) {
+	case types.FilterGroup:
+		for _, c := range filter.Conditions {
+			w.filter(c)
+		}
+	case types.ElemMatchFilter:
+		for _, c := range filter.Conditions {
+			w.filter(c)
+		}
+	}
+}
+
+func (w *walker) projection(p types.Projection) {
+	if !w.node(p) {
+		return
+	}
+	for _, f := range p.Fields {
+		if !w.node(f) {
+			continue
+		}
+		if f.ElemMatch != nil {
+			for _, c := range f.ElemMatch.Conditions {
+				w.filter(c)
+			}
+		}
+	}
+}
+
+func (w *walker) pipeline(stages []types.PipelineStage) {
+	for _, stage := range stages {
+		w.stage(stage)
+	}
+}
+
+func (w *walker) stage(s types.PipelineStage) {
+	if !w.node(s) {
+		return
+	}
+	switch stage := s.(type) {
+	case types.MatchStage:
+		w.filter(stage.Filter)
+	case types.ProjectStage:
+		w.projection(stage.Projection)
+		w.expressions(stage.Computed)
+	case types.GroupStage:
+		w.expr(stage.ID)
+		w.accumulators(stage.Accumulators)
+	case types.SortStage:
+		for _, s := range stage.Sorts {
+			w.node(s)
+		}
+	case types.LookupStage:
+		w.expressions(stage.Let)
+		w.pipeline(stage.Pipeline)
+	case types.GraphLookupStage:
+		w.expr(stage.StartWith)
+		if stage.RestrictSearchWithMatch != nil {
+			w.filter(stage.RestrictSearchWithMatch)
+		}
+	case types.AddFieldsStage:
+		w.expressions(stage.Fields)
+	case types.ReplaceRootStage:
+		w.expr(stage.NewRoot)
+	case types.FacetStage:
+		for _, name := range slices.Sorted(maps.Keys(stage.Facets)) {
+			w.pipeline(stage.Facets[name])
+		}
+	case types.BucketStage:
+		w.expr(stage.GroupBy)
+		w.accumulators(stage.Output)
+	case types.SortByCountStage:
+		w.expr(stage.Expr)
+	}
+}
+
+func (w *walker) expr(e types.Expression) {
+	if e == nil || !w.node(e) {
+		return
+	}
+	switch expr := e.(type) {
+	case types.OperatorExpression:
+		for _, arg := range expr.Args {
+			w.expr(arg)
+		}
+	case types.ConditionalExpression:
+		w.expr(expr.If)
+		w.expr(expr.Then)
+		w.expr(expr.Else)
+	case types.CompositeExpression:
+		w.expressions(expr.Fields)
+	}
+}
+
+func (w *walker) expressions(exprs map[string]types.Expression) {
+	for _, name := range slices.Sorted(maps.Keys(exprs)) {
+		w.expr(exprs[name])
+	}
+}
+
+func (w *walker) accumulators(accs map[string]types.Accumulator) {
+	for _, name := range slices.Sorted(maps.Keys(accs)) {
+		if acc := accs[name]; w.node(acc) {
+			w.expr(acc.Expr)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package docql_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/zoobzio/docql"
+)
+
+func TestWalk_CountsNestedConditions(t *testing.T) {
+	instance := createTestInstance(t)
+	status := instance.F("users", "status")
+	active := instance.F("users", "active")
+	filter := docql.And(
+		instance.Eq(status, instance.P("s1")),
+		docql.Or(
+			instance.Eq(status, instance.P("s2")),
+			docql.And(instance.Eq(active, instance.P("a")), docql.IsNull(instance.F("users", "email"))),
+		),
+	)
+	ast, err := docql.Find(instance.C("users")).Filter(filter).Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	var conditions, groups int
+	docql.Walk(ast, func(node interface{}) bool {
+		switch node.(type) {
+		case docql.FilterCondition, docql.NullFilter:
+			conditions++
+		case docql.FilterGroup:
+			groups++
+		}
+		return true
+	})
+	if conditions != 4 || groups != 3 {
+		t.Errorf("expected 4 conditions in 3 groups, got %d in %d", conditions, groups)
+	}
+}
+
+func TestWalk_StopsEarly(t *testing.T) {
+	instance := createTestInstance(t)
+	status := instance.F("users", "status")
+	filter := docql.Or(instance.Eq(status, instance.P("a")), instance.Eq(status, instance.P("b")), instance.Eq(status, instance.P("c")))
+	ast, err := docql.Find(instance.C("users")).Filter(filter).SortAsc(status).Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	var seen []string
+	docql.Walk(ast, func(node interface{}) bool {
+		c, ok := node.(docql.FilterCondition)
+		if !ok {
+			return true
+		}
+		seen = append(seen, c.Value.Name)
+		return c.Value.Name != "b"
+	})
+	if !slices.Equal(seen, []string{"a", "b"}) {
+		t.Errorf("expected the walk to stop after b, got %v", seen)
+	}
+}
+
+func TestWalk_Pipeline(t *testing.T) {
+	instance := createTestInstance(t)
+	ast, err := docql.Aggregate(instance.C("users")).
+		Match(instance.Eq(instance.F("users", "status"), instance.P("status"))).
+		Group(docql.FieldExpr(instance.F("users", "status")), map[string]docql.Accumulator{"n": docql.CountAcc()}).
+		Lookup("posts", instance.F("users", "_id"), instance.F("posts", "userId"), "posts").
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	var kinds []string
+	docql.Walk(ast, func(node interface{}) bool {
+		switch node.(type) {
+		case *docql.DocumentAST:
+			kinds = append(kinds, "ast")
+		case docql.MatchStage, docql.GroupStage, docql.LookupStage:
+			kinds = append(kinds, "stage")
+		case docql.FilterCondition:
+			kinds = append(kinds, "condition")
+		case docql.FieldExpression:
+			kinds = append(kinds, "field")
+		case docql.Accumulator:
+			kinds = append(kinds, "accumulator")
+		}
+		return true
+	})
+	want := []string{"ast", "stage", "condition", "stage", "field", "accumulator", "stage"}
+	if !slices.Equal(kinds, want) {
+		t.Errorf("unexpected walk order:\n got: %v\nwant: %v", kinds, want)
+	}
+}
+
+func TestWalk_TransactionOperations(t *testing.T) {
+	instance := createTestInstance(t)
+	byID := instance.Eq(instance.F("users", "_id"), instance.P("id"))
+	ast, err := docql.Transaction().
+		Add(docql.Update(instance.C("users")).Filter(byID).Set(instance.F("users", "active"), instance.P("active"))).
+		Add(docql.Delete(instance.C("posts")).Filter(instance.Eq(instance.F("posts", "_id"), instance.P("post")))).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	var asts, updates, conditions int
+	docql.Walk(ast, func(node interface{}) bool {
+		switch node.(type) {
+		case *docql.DocumentAST:
+			asts++
+		case docql.UpdateOperation:
+			updates++
+		case docql.FilterCondition:
+			conditions++
+		}
+		return true
+	})
+	if asts != 3 || updates != 1 || conditions != 2 {
+		t.Errorf("expected 3 ASTs, 1 update, and 2 conditions, got %d, %d, %d", asts, updates, conditions)
+	}
+}